@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogs returns audit log entries with pagination
+func (a *API) GetAuditLogs(c *gin.Context) {
+	page, pageSize, ok := pageParams(c, 50)
+	if !ok {
+		return
+	}
+
+	filter := repository.AuditFilter{
+		Actor:      c.Query("actor"),
+		Resource:   c.Query("resource"),
+		ResourceID: c.Query("resource_id"),
+		Method:     c.Query("method"),
+		Since:      c.Query("since"),
+		Until:      c.Query("until"),
+	}
+
+	logs, total, err := a.repos.Audit.List(c.Request.Context(), filter, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(gin.H{"logs": logs}, page, pageSize, total))
+}
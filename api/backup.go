@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/backup"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportBackup streams a zip archive of rules, whitelist, results and scan history
+func (a *API) ExportBackup(c *gin.Context) {
+	filename := fmt.Sprintf("github-monitor-backup-%s.zip", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// The status is already sent once the archive starts streaming, so a failure can only be logged
+	if _, err := backup.Export(a.repos.DB.WithContext(c.Request.Context()), c.Writer); err != nil {
+		log.Printf("Backup export failed: %v", err)
+	}
+}
+
+// ImportBackup restores an archive uploaded as the multipart field "file"
+func (a *API) ImportBackup(c *gin.Context) {
+	header, err := c.FormFile("file")
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &sizeErr):
+		apierror.TooLarge(c, fmt.Sprintf("Backup is larger than %d bytes, raise server.max_bulk_body_mb to restore it", sizeErr.Limit))
+		return
+	case err != nil:
+		apierror.BadRequest(c, "file is required")
+		return
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	defer f.Close()
+
+	manifest, err := backup.Import(a.repos.DB.WithContext(c.Request.Context()), f, header.Size)
+	if errors.Is(err, backup.ErrInvalidArchive) {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Backup restored",
+		"manifest": manifest,
+	})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bulkRoutes take whole imports or webhook payloads in one request, their bodies
+// are limited by server.max_bulk_body_mb instead of server.max_body_kb
+var bulkRoutes = map[string]bool{
+	"/api/v1/backup/restore": true,
+	"/api/v1/results/ingest": true,
+	"/webhooks/github":       true,
+	"/webhooks/rules":        true,
+}
+
+// limitBodies turns away request bodies over the size limit of their route and JSON
+// bodies nested deeper than server.max_json_depth, before a handler reads them
+func limitBodies(server config.ServerConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		limit := int64(server.MaxBodyKB) << 10
+		if bulkRoutes[c.FullPath()] {
+			limit = int64(server.MaxBulkBodyMB) << 20
+		}
+		if c.Request.ContentLength > limit {
+			apierror.TooLarge(c, fmt.Sprintf("Request body is larger than %d bytes", limit))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+		// Forms are parsed by size, uploads are streamed to the handler
+		contentType := c.ContentType()
+		if server.MaxJSONDepth <= 0 || contentType == "multipart/form-data" || contentType == "application/x-www-form-urlencoded" {
+			c.Next()
+			return
+		}
+
+		// Deeply nested JSON costs far more to decode than its size suggests
+		body, err := io.ReadAll(c.Request.Body)
+		var sizeErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &sizeErr):
+			apierror.TooLarge(c, fmt.Sprintf("Request body is larger than %d bytes", limit))
+			return
+		case err != nil:
+			apierror.BadRequest(c, "Unreadable request body")
+			return
+		}
+		if jsonDepth(body) > server.MaxJSONDepth {
+			apierror.BadRequest(c, fmt.Sprintf("JSON body is nested deeper than %d levels", server.MaxJSONDepth))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// jsonDepth returns how deeply the arrays and objects of a JSON document nest,
+// without decoding it
+func jsonDepth(data []byte) int {
+	depth, deepest := 0, 0
+	inString, escaped := false, false
+	for _, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if b == '\\' {
+				escaped = true
+			} else if b == '"' {
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+			if depth > deepest {
+				deepest = depth
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return deepest
+}
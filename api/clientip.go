@@ -0,0 +1,24 @@
+package api
+
+import (
+	"log"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// warnUntrustedProxies logs once per peer that sends X-Forwarded-For without being
+// one of server.trusted_proxies. Its own address is taken as the client's, so a
+// reverse proxy missing from the setting shows up in every log line and audit
+// entry instead of the clients behind it.
+func warnUntrustedProxies() gin.HandlerFunc {
+	var warned sync.Map
+	return func(c *gin.Context) {
+		if c.GetHeader("X-Forwarded-For") != "" && c.ClientIP() == c.RemoteIP() {
+			if _, seen := warned.LoadOrStore(c.RemoteIP(), true); !seen {
+				log.Printf("%s sent X-Forwarded-For but isn't in server.trusted_proxies, its address is logged as the client's", c.RemoteIP())
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// compressResponses gzips the JSON, CSV and text responses of at least minBytes
+// for clients accepting it. Smaller responses aren't worth the overhead and are
+// sent as they are, 0 disables compression.
+func compressResponses(minBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if minBytes <= 0 || c.IsWebsocket() || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		w := &gzipWriter{ResponseWriter: c.Writer, minBytes: minBytes}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Header("Vary", "Accept-Encoding")
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	for _, coding := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+		// gzip;q=0 refuses it
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(key) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressible reports whether responses of a content type shrink when gzipped.
+// Archives and images already are compressed.
+func compressible(contentType string) bool {
+	switch {
+	case strings.HasPrefix(contentType, "application/json"),
+		strings.HasPrefix(contentType, "text/"):
+		return true
+	}
+	return false
+}
+
+// gzipWriter holds back the start of a body until it's known to reach the minimum
+// size, then gzips it and the rest
+type gzipWriter struct {
+	gin.ResponseWriter
+	minBytes int
+	pending  []byte
+	gz       *gzip.Writer // nil until compression starts
+	plain    bool         // the body is sent as it is
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	switch {
+	case w.gz != nil:
+		return w.gz.Write(data)
+	case w.plain:
+		return w.ResponseWriter.Write(data)
+	}
+
+	if len(w.pending) == 0 && !w.shouldCompress() {
+		w.plain = true
+		return w.ResponseWriter.Write(data)
+	}
+	w.pending = append(w.pending, data...)
+	if len(w.pending) >= w.minBytes {
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what was written so far, compressed when it may still grow past the
+// minimum size, as streamed exports do
+func (w *gzipWriter) Flush() {
+	if w.gz == nil && !w.plain && len(w.pending) > 0 {
+		if err := w.startGzip(); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// shouldCompress reports whether the response is one to gzip, judged by the
+// headers its handler set before writing the body
+func (w *gzipWriter) shouldCompress() bool {
+	header := w.Header()
+	status := w.Status()
+	return header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) &&
+		status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+func (w *gzipWriter) startGzip() error {
+	header := w.Header()
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	// The compressed body differs byte for byte from the one the tag was computed on
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+
+	w.gz = gzipWriters.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+	pending := w.pending
+	w.pending = nil
+	_, err := w.gz.Write(pending)
+	return err
+}
+
+// finish completes the body, sending a held back one that stayed too small as it is
+func (w *gzipWriter) finish() {
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+		return
+	}
+	if len(w.pending) > 0 {
+		w.ResponseWriter.Write(w.pending)
+		w.pending = nil
+	}
+}
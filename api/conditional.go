@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTrackedPages bounds how many pages conditional remembers the last body of,
+// forgetting them all makes every page new again
+const maxTrackedPages = 10000
+
+// servedPage is the body a page was last served with
+type servedPage struct {
+	etag     string
+	modified time.Time
+}
+
+// conditional answers repeated GETs of a list with 304 Not Modified while its
+// body is unchanged. Bodies are tagged with a hash of their content, and pages are
+// dated by when the instance first served their current body, since the rows of a
+// list can change or drop out of it without a newer timestamp on any row that's
+// left.
+func conditional() gin.HandlerFunc {
+	var mu sync.Mutex
+	pages := make(map[string]servedPage)
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		w := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.Status() != http.StatusOK {
+			w.ResponseWriter.Write(w.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(w.body.Bytes())
+		// Weak, the body may be sent compressed or as it is
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		now := time.Now().UTC().Truncate(time.Second)
+		page := c.Request.URL.RequestURI()
+		mu.Lock()
+		if len(pages) >= maxTrackedPages {
+			pages = make(map[string]servedPage)
+		}
+		served, ok := pages[page]
+		if !ok || served.etag != etag {
+			// A change dates the page later than any earlier body, even one of
+			// the same second, so If-Modified-Since never matches a stale copy
+			modified := now
+			if ok && !modified.After(served.modified) {
+				modified = served.modified.Add(time.Second)
+			}
+			served = servedPage{etag: etag, modified: modified}
+			pages[page] = served
+		}
+		modified := served.modified
+		mu.Unlock()
+
+		header := c.Writer.Header()
+		header.Set("ETag", etag)
+		header.Set("Last-Modified", modified.Format(http.TimeFormat))
+		// Cached copies are the user's own and are checked before each use
+		header.Set("Cache-Control", "private, no-cache")
+
+		if notModified(c.Request, etag, modified) {
+			header.Del("Content-Type")
+			c.Writer.WriteHeader(http.StatusNotModified)
+			c.Writer.WriteHeaderNow()
+			return
+		}
+		c.Writer.Write(w.body.Bytes())
+	}
+}
+
+// notModified reports whether the copy a request revalidates is current.
+// Tags compare weakly, and If-None-Match takes precedence over
+// If-Modified-Since, as in RFC 9110.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modified.After(since)
+}
+
+// bufferedWriter holds back a body so it can be tagged before it's sent
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
@@ -2,26 +2,47 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"strconv"
+	"strings"
+	"time"
 
+	"github-monitor/apierror"
 	"github-monitor/auth"
-	"github-monitor/db"
+	"github-monitor/cache"
+	"github-monitor/cluster"
+	"github-monitor/config"
 	"github-monitor/db/models"
+	"github-monitor/events"
 	"github-monitor/github"
+	"github-monitor/i18n"
 	"github-monitor/monitor"
+	"github-monitor/report"
+	"github-monitor/repository"
+	"github-monitor/rulesync"
+	"github-monitor/sla"
+	"github-monitor/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 type API struct {
-	tokenPool      *github.TokenPool
-	searchService  *github.SearchService
-	monitorService *monitor.MonitorService
+	repos           *repository.Repositories
+	tokenPool       *github.TokenPool
+	searchService   *github.SearchService
+	monitorService  *monitor.MonitorService
+	oidcProvider    *auth.OIDCProvider
+	reportScheduler *report.Scheduler
+	store           storage.Store    // nil when object storage isn't configured
+	shared          cache.Cache      // nil when Redis isn't configured
+	elector         *cluster.Elector // nil when running as a single instance
+	ruleSync        *rulesync.Syncer // nil when rules aren't synced from Git
+	sla             *sla.Policy      // nil when SLAs aren't tracked
 }
 
-func NewAPI(tokenPool *github.TokenPool, searchService *github.SearchService, monitorService *monitor.MonitorService) *API {
+func NewAPI(repos *repository.Repositories, tokenPool *github.TokenPool, searchService *github.SearchService, monitorService *monitor.MonitorService) *API {
 	return &API{
+		repos:          repos,
 		tokenPool:      tokenPool,
 		searchService:  searchService,
 		monitorService: monitorService,
@@ -30,9 +51,9 @@ func NewAPI(tokenPool *github.TokenPool, searchService *github.SearchService, mo
 
 // GetTokens returns all GitHub tokens
 func (a *API) GetTokens(c *gin.Context) {
-	var tokens []models.GitHubToken
-	if err := db.GetDB().Find(&tokens).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	tokens, err := a.repos.Tokens.List(c.Request.Context())
+	if err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -43,25 +64,82 @@ func (a *API) GetTokens(c *gin.Context) {
 func (a *API) CreateToken(c *gin.Context) {
 	var token models.GitHubToken
 	if err := c.ShouldBindJSON(&token); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Bind(c, err)
 		return
 	}
 
-	if err := db.GetDB().Create(&token).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := a.repos.Tokens.Create(c.Request.Context(), &token); err != nil {
+		apierror.Database(c, err)
 		return
 	}
+	a.tokenPool.AddTokens([]string{token.Token})
 
 	c.JSON(http.StatusCreated, token)
 }
 
+type tokenUpdate struct {
+	Name     *string `json:"name"`
+	IsActive *bool   `json:"is_active"`
+}
+
+// UpdateToken renames a token or enables and disables it. A disabled token keeps
+// its history but leaves the rotation right away, until it is enabled again.
+func (a *API) UpdateToken(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	token, err := a.repos.Tokens.Get(ctx, id)
+	if err != nil {
+		apierror.NotFound(c, "Token not found")
+		return
+	}
+
+	var req tokenUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	if req.Name != nil {
+		token.Name = *req.Name
+	}
+	if req.IsActive != nil {
+		token.IsActive = *req.IsActive
+	}
+
+	if err := a.repos.Tokens.Save(ctx, token); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	a.tokenPool.SetTokenActive(token.Token, token.IsActive)
+
+	c.JSON(http.StatusOK, token)
+}
+
 // DeleteToken deletes a token
 func (a *API) DeleteToken(c *gin.Context) {
-	id := c.Param("id")
-	if err := db.GetDB().Delete(&models.GitHubToken{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	id, ok := idParam(c)
+	if !ok {
 		return
 	}
+	ctx := c.Request.Context()
+	tokens, err := a.repos.Tokens.List(ctx)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	if err := a.repos.Tokens.Delete(ctx, id); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	for _, token := range tokens {
+		if token.ID == id {
+			// Without its stored flag the token is no longer held out of rotation
+			a.tokenPool.SetTokenActive(token.Token, true)
+			a.tokenPool.RemoveToken(token.Token)
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Token deleted successfully"})
 }
@@ -72,11 +150,22 @@ func (a *API) GetTokenStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetTokenForecast returns when the shared tokens run out at the current pace and
+// the scan interval that would make them last until their limit resets
+func (a *API) GetTokenForecast(c *gin.Context) {
+	forecast, ok := a.monitorService.UsageForecast(time.Now())
+	if !ok {
+		apierror.NotFound(c, "Token usage is not forecast")
+		return
+	}
+	c.JSON(http.StatusOK, forecast)
+}
+
 // GetMonitorRules returns all monitor rules
 func (a *API) GetMonitorRules(c *gin.Context) {
-	var rules []models.MonitorRule
-	if err := db.GetDB().Find(&rules).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	rules, err := a.repos.Rules.List(c.Request.Context())
+	if err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -85,10 +174,13 @@ func (a *API) GetMonitorRules(c *gin.Context) {
 
 // GetMonitorRule returns a single monitor rule
 func (a *API) GetMonitorRule(c *gin.Context) {
-	id := c.Param("id")
-	var rule models.MonitorRule
-	if err := db.GetDB().First(&rule, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	rule, err := a.repos.Rules.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Rule not found")
 		return
 	}
 
@@ -99,21 +191,49 @@ func (a *API) GetMonitorRule(c *gin.Context) {
 func (a *API) CreateMonitorRule(c *gin.Context) {
 	var rule models.MonitorRule
 	if err := c.ShouldBindJSON(&rule); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Bind(c, err)
 		return
 	}
 
+	// Raw query rules may match any content, they need no keywords
+	if rule.Keywords == "" && rule.RawQuery != "" {
+		rule.Keywords = "[]"
+	}
+
 	// Validate keywords JSON
 	if rule.Keywords != "" {
 		var keywords []string
 		if err := json.Unmarshal([]byte(rule.Keywords), &keywords); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid keywords JSON format"})
+			apierror.Validation(c, apierror.FieldError{Field: "keywords", Message: "must be a JSON array of strings"})
 			return
 		}
 	}
 
-	if err := db.GetDB().Create(&rule).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if rule.Severity == "" {
+		rule.Severity = "medium"
+	}
+	if rule.QueryDialect == "" {
+		rule.QueryDialect = github.DialectLegacy
+	}
+	if !models.ValidSeverities[rule.Severity] {
+		apierror.Validation(c, apierror.FieldError{Field: "severity", Message: "must be one of: critical high medium low info"})
+		return
+	}
+	if rule.TokenGroup != "" && !config.HasTokenGroup(rule.TokenGroup) {
+		apierror.Validation(c, apierror.FieldError{Field: "token_group", Message: "must be one of github.token_groups"})
+		return
+	}
+	if !validScoring(c, &rule) {
+		return
+	}
+	rule.ProjectID = projectOf(c)
+	if rule.Owner == "" {
+		// Whoever creates a rule answers for its noise unless they name someone else
+		rule.Owner = ownerOf(c)
+	}
+
+	if err := a.repos.Rules.Create(c.Request.Context(), &rule); err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -122,32 +242,135 @@ func (a *API) CreateMonitorRule(c *gin.Context) {
 
 // UpdateMonitorRule updates a monitor rule
 func (a *API) UpdateMonitorRule(c *gin.Context) {
-	id := c.Param("id")
-	var rule models.MonitorRule
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	rule, err := a.repos.Rules.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Rule not found")
+		return
+	}
 
-	if err := db.GetDB().First(&rule, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+	projectID := rule.ProjectID
+	if err := c.ShouldBindJSON(rule); err != nil {
+		apierror.Bind(c, err)
 		return
 	}
+	rule.ProjectID = projectID // rules don't move between projects
 
-	if err := c.ShouldBindJSON(&rule); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !models.ValidSeverities[rule.Severity] {
+		apierror.Validation(c, apierror.FieldError{Field: "severity", Message: "must be one of: critical high medium low info"})
+		return
+	}
+	if rule.TokenGroup != "" && !config.HasTokenGroup(rule.TokenGroup) {
+		apierror.Validation(c, apierror.FieldError{Field: "token_group", Message: "must be one of github.token_groups"})
+		return
+	}
+	if !validScoring(c, rule) {
 		return
 	}
 
-	if err := db.GetDB().Save(&rule).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := a.repos.Rules.Save(c.Request.Context(), rule); err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, rule)
 }
 
+// validScoring checks the query dialect, raw query, file size, keyword weights,
+// min score, notify threshold, delays, repository filters, excluded repositories
+// and owner of a rule, responding with a validation error when they can't be used
+func validScoring(c *gin.Context, rule *models.MonitorRule) bool {
+	if !models.ValidQueryDialects[rule.QueryDialect] {
+		apierror.Validation(c, apierror.FieldError{Field: "query_dialect", Message: "must be legacy or code_search"})
+		return false
+	}
+	if rule.QueryDialect == github.DialectCodeSearch {
+		keywords, err := github.ParseKeywords(rule.Keywords)
+		if err == nil {
+			err = github.CheckKeywords(keywords)
+		}
+		if err != nil {
+			apierror.Validation(c, apierror.FieldError{Field: "keywords", Message: err.Error()})
+			return false
+		}
+	}
+	if rule.RawQuery != "" {
+		if err := github.CheckRawQuery(rule.RawQuery); err != nil {
+			apierror.Validation(c, apierror.FieldError{Field: "raw_query", Message: err.Error()})
+			return false
+		}
+	}
+	if !models.ValidFileSize(rule.FileSize) {
+		apierror.Validation(c, apierror.FieldError{Field: "file_size", Message: "must be a number of bytes such as <50000 or a range such as 100..5000"})
+		return false
+	}
+	if rule.MinScore < 0 {
+		apierror.Validation(c, apierror.FieldError{Field: "min_score", Message: "must not be negative"})
+		return false
+	}
+
+	weights, err := github.ParseKeywordWeights(rule.KeywordWeights)
+	if err != nil {
+		apierror.Validation(c, apierror.FieldError{Field: "keyword_weights", Message: "must be a JSON object of non-negative numbers"})
+		return false
+	}
+	keywords, _ := github.ParseKeywords(rule.Keywords)
+	for keyword := range weights {
+		known := false
+		for _, candidate := range keywords {
+			known = known || strings.EqualFold(candidate, keyword)
+		}
+		if !known {
+			apierror.Validation(c, apierror.FieldError{Field: "keyword_weights", Message: fmt.Sprintf("%q is not one of the keywords", keyword)})
+			return false
+		}
+	}
+	if !models.ValidNotifyThreshold(rule.NotifyThreshold) {
+		apierror.Validation(c, apierror.FieldError{Field: "notify_threshold", Message: "must be a severity (critical high medium low info) or a non-negative score"})
+		return false
+	}
+	if !models.ValidDelay(rule.PageDelay) {
+		apierror.Validation(c, apierror.FieldError{Field: "page_delay", Message: "must be a duration between 0s and 1m, such as 500ms"})
+		return false
+	}
+	if !models.ValidDelay(rule.RuleDelay) {
+		apierror.Validation(c, apierror.FieldError{Field: "rule_delay", Message: "must be a duration between 0s and 1m, such as 500ms"})
+		return false
+	}
+	if !models.ValidRepoAge(rule.MinRepoAgeDays, rule.MaxRepoAgeDays) {
+		apierror.Validation(c, apierror.FieldError{Field: "min_repo_age_days", Message: "must not be negative, nor greater than max_repo_age_days"})
+		return false
+	}
+	if rule.MaxNewResults < 0 {
+		apierror.Validation(c, apierror.FieldError{Field: "max_new_results", Message: "must not be negative"})
+		return false
+	}
+	if !models.ValidOwner(rule.Owner) {
+		apierror.Validation(c, apierror.FieldError{Field: "owner", Message: "must be a user name or a plain email address such as alice@example.com"})
+		return false
+	}
+	if !models.ValidExcludeRepos(rule.ExcludeRepos) {
+		apierror.Validation(c, apierror.FieldError{Field: "exclude_repos", Message: fmt.Sprintf("must be a JSON array of at most %d owner/name repositories", models.MaxExcludeRepos)})
+		return false
+	}
+	if err := monitor.ValidProcessors(rule.Processors); err != nil {
+		apierror.Validation(c, apierror.FieldError{Field: "processors", Message: err.Error()})
+		return false
+	}
+	return true
+}
+
 // DeleteMonitorRule deletes a monitor rule
 func (a *API) DeleteMonitorRule(c *gin.Context) {
-	id := c.Param("id")
-	if err := db.GetDB().Delete(&models.MonitorRule{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	if err := a.repos.Rules.Delete(c.Request.Context(), id); err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -156,51 +379,155 @@ func (a *API) DeleteMonitorRule(c *gin.Context) {
 
 // GetSearchResults returns search results with pagination
 func (a *API) GetSearchResults(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	ruleID := c.Query("rule_id")
-	status := c.Query("status")
+	page, pageSize, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	// A saved view fills in the filters the request doesn't set
+	saved, ok := a.savedFilters(c)
+	if !ok {
+		return
+	}
+	query := func(name string) string {
+		if value := c.Query(name); value != "" {
+			return value
+		}
+		return saved[name]
+	}
+
+	ruleID, ok := uintValue(c, "rule_id", query("rule_id"))
+	if !ok {
+		return
+	}
+	incidentID, ok := uintValue(c, "incident_id", query("incident_id"))
+	if !ok {
+		return
+	}
+
+	filter := repository.ResultFilter{
+		RuleID:     ruleID,
+		IncidentID: incidentID,
+		Status:     query("status"),
+		Severity:   query("severity"),
+		Source:     query("source"),
+		Verdict:    query("verdict"),
+		Sort:       query("sort"),
+	}
+	if fileTypes := query("file_type"); fileTypes != "" {
+		filter.FileTypes = strings.Split(fileTypes, ",")
+		for _, fileType := range filter.FileTypes {
+			if !github.ValidFileTypes[fileType] {
+				apierror.Validation(c, apierror.FieldError{Field: "file_type", Message: fmt.Sprintf("%q is not a known file type", fileType)})
+				return
+			}
+		}
+	}
+	if filter.Sort != repository.SortNewest && filter.Sort != repository.SortVerdict {
+		apierror.Validation(c, apierror.FieldError{Field: "sort", Message: "must be empty or verdict"})
+		return
+	}
 
-	offset := (page - 1) * pageSize
+	// Snoozed results are left out of the pending queue unless asked for
+	switch snoozed := query("snoozed"); snoozed {
+	case "true", "false":
+		only := snoozed == "true"
+		filter.Snoozed = &only
+	case "":
+		if filter.Status == "pending" {
+			hide := false
+			filter.Snoozed = &hide
+		}
+	case "any":
+	default:
+		apierror.Validation(c, apierror.FieldError{Field: "snoozed", Message: "must be true, false or any"})
+		return
+	}
 
-	query := db.GetDB().Model(&models.SearchResult{})
+	after, useCursor, err := afterCursor(c)
+	if err != nil {
+		invalidCursor(c)
+		return
+	}
+	if useCursor && filter.Sort != repository.SortNewest {
+		// Cursors follow the id order, they can't page through another ordering
+		apierror.Validation(c, apierror.FieldError{Field: "sort", Message: "can't be combined with the after cursor, use page and page_size"})
+		return
+	}
 
-	if ruleID != "" {
-		query = query.Where("rule_id = ?", ruleID)
+	// Keyset pagination skips the count and offset scan, which get slow on large tables
+	if useCursor {
+		results, err := a.repos.Results.ListAfter(c.Request.Context(), filter, after, pageSize)
+		if err != nil {
+			apierror.Database(c, err)
+			return
+		}
+
+		a.annotateSLA(results)
+		redactResults(results)
+
+		var lastID uint
+		if len(results) > 0 {
+			lastID = results[len(results)-1].ID
+		}
+
+		next := nextCursor(lastID, len(results), pageSize)
+		c.JSON(http.StatusOK, gin.H{
+			"results":     results,
+			"page_size":   pageSize,
+			"next_cursor": next,
+			"has_next":    next != nil,
+		})
+		return
 	}
 
-	if status != "" {
-		query = query.Where("status = ?", status)
+	results, total, err := a.repos.Results.List(c.Request.Context(), filter, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apierror.Database(c, err)
+		return
 	}
+	a.annotateSLA(results)
+	redactResults(results)
 
-	var total int64
-	query.Count(&total)
+	c.JSON(http.StatusOK, paginated(gin.H{"results": results}, page, pageSize, total))
+}
 
-	var results []models.SearchResult
-	if err := query.Preload("Rule").
-		Order("created_at DESC").
-		Limit(pageSize).
-		Offset(offset).
-		Find(&results).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// GetResultsByRepo returns the repositories with results with pagination, those
+// with the most open results first
+func (a *API) GetResultsByRepo(c *gin.Context) {
+	page, pageSize, ok := pageParams(c, 20)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"results":   results,
-		"total":     total,
-		"page":      page,
-		"page_size": pageSize,
-	})
+	ruleID, ok := uintValue(c, "rule_id", c.Query("rule_id"))
+	if !ok {
+		return
+	}
+	filter := repository.ResultFilter{
+		RuleID:   ruleID,
+		Severity: c.Query("severity"),
+		Source:   c.Query("source"),
+	}
+
+	repos, total, err := a.repos.Results.ByRepo(c.Request.Context(), filter, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(gin.H{"repos": repos}, page, pageSize, total))
 }
 
 // UpdateSearchResult updates a search result status
 func (a *API) UpdateSearchResult(c *gin.Context) {
-	id := c.Param("id")
-	var result models.SearchResult
-
-	if err := db.GetDB().First(&result, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	result, err := a.repos.Results.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Result not found")
 		return
 	}
 
@@ -209,17 +536,19 @@ func (a *API) UpdateSearchResult(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Bind(c, err)
 		return
 	}
 
 	result.Status = input.Status
 
-	if err := db.GetDB().Save(&result).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := a.repos.Results.Save(c.Request.Context(), result); err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
+	events.PublishTo(result.ProjectID, events.TypeResultStatus, events.StatusChange{IDs: []uint{result.ID}, Status: result.Status})
+
 	c.JSON(http.StatusOK, result)
 }
 
@@ -231,35 +560,29 @@ func (a *API) BatchUpdateSearchResults(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Bind(c, err)
 		return
 	}
 
 	if len(input.IDs) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No IDs provided"})
+		apierror.BadRequest(c, "No IDs provided")
 		return
 	}
 
 	// Validate status
-	validStatuses := map[string]bool{
-		"pending":        true,
-		"confirmed":      true,
-		"false_positive": true,
-	}
-
-	if !validStatuses[input.Status] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+	if !models.ValidResultStatuses[input.Status] {
+		apierror.Validation(c, apierror.FieldError{Field: "status", Message: "must be one of: pending confirmed false_positive resolved"})
 		return
 	}
 
 	// Update all results
-	if err := db.GetDB().Model(&models.SearchResult{}).
-		Where("id IN ?", input.IDs).
-		Update("status", input.Status).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if _, err := a.repos.Results.UpdateStatus(c.Request.Context(), input.IDs, input.Status); err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
+	events.PublishTo(projectOf(c), events.TypeResultStatus, events.StatusChange{IDs: input.IDs, Status: input.Status})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Batch update successful",
 		"updated": len(input.IDs),
@@ -268,9 +591,9 @@ func (a *API) BatchUpdateSearchResults(c *gin.Context) {
 
 // GetWhitelist returns all whitelist entries
 func (a *API) GetWhitelist(c *gin.Context) {
-	var whitelist []models.Whitelist
-	if err := db.GetDB().Find(&whitelist).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	whitelist, err := a.repos.Whitelist.List(c.Request.Context())
+	if err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -281,12 +604,13 @@ func (a *API) GetWhitelist(c *gin.Context) {
 func (a *API) CreateWhitelist(c *gin.Context) {
 	var entry models.Whitelist
 	if err := c.ShouldBindJSON(&entry); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Bind(c, err)
 		return
 	}
+	entry.ProjectID = projectOf(c)
 
-	if err := db.GetDB().Create(&entry).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := a.repos.Whitelist.Create(c.Request.Context(), &entry); err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -295,9 +619,12 @@ func (a *API) CreateWhitelist(c *gin.Context) {
 
 // DeleteWhitelist deletes a whitelist entry
 func (a *API) DeleteWhitelist(c *gin.Context) {
-	id := c.Param("id")
-	if err := db.GetDB().Delete(&models.Whitelist{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	if err := a.repos.Whitelist.Delete(c.Request.Context(), id); err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -306,69 +633,109 @@ func (a *API) DeleteWhitelist(c *gin.Context) {
 
 // GetScanHistory returns scan history
 func (a *API) GetScanHistory(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	ruleID := c.Query("rule_id")
-
-	offset := (page - 1) * pageSize
+	page, pageSize, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+	ruleID, ok := uintQuery(c, "rule_id")
+	if !ok {
+		return
+	}
 
-	query := db.GetDB().Model(&models.ScanHistory{})
+	filter := repository.HistoryFilter{RuleID: ruleID}
 
-	if ruleID != "" {
-		query = query.Where("rule_id = ?", ruleID)
+	after, useCursor, err := afterCursor(c)
+	if err != nil {
+		invalidCursor(c)
+		return
 	}
 
-	var total int64
-	query.Count(&total)
+	if useCursor {
+		history, err := a.repos.History.ListAfter(c.Request.Context(), filter, after, pageSize)
+		if err != nil {
+			apierror.Database(c, err)
+			return
+		}
+
+		var lastID uint
+		if len(history) > 0 {
+			lastID = history[len(history)-1].ID
+		}
 
-	var history []models.ScanHistory
-	if err := query.Preload("Rule").
-		Order("created_at DESC").
-		Limit(pageSize).
-		Offset(offset).
-		Find(&history).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		next := nextCursor(lastID, len(history), pageSize)
+		c.JSON(http.StatusOK, gin.H{
+			"history":     history,
+			"page_size":   pageSize,
+			"next_cursor": next,
+			"has_next":    next != nil,
+		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"history":   history,
-		"total":     total,
-		"page":      page,
-		"page_size": pageSize,
-	})
+	history, total, err := a.repos.History.List(c.Request.Context(), filter, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(gin.H{"history": history}, page, pageSize, total))
 }
 
 // GetMonitorStatus returns monitor service status
 func (a *API) GetMonitorStatus(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"is_running": a.monitorService.IsRunning(),
-	})
+	status := gin.H{
+		"is_running":     a.monitorService.IsRunning(),
+		"last_heartbeat": a.monitorService.LastHeartbeat(),
+		"stalled":        a.monitorService.Stalled(),
+		"scan":           a.monitorService.ScanStatus(),
+		"dry_run":        a.monitorService.DryRun(),
+	}
+	if a.elector != nil {
+		leader, err := a.elector.Leader(c.Request.Context())
+		if err != nil {
+			apierror.Database(c, err)
+			return
+		}
+		status["cluster"] = gin.H{
+			"instance":  a.elector.ID(),
+			"is_leader": a.elector.IsLeader(),
+			"leader":    leader,
+		}
+	}
+	c.JSON(http.StatusOK, status)
 }
 
-// StartMonitor starts the monitoring service
+// StartMonitor starts the monitoring service. Starting a running monitor succeeds
+// without starting a second loop.
 func (a *API) StartMonitor(c *gin.Context) {
-	if a.monitorService.IsRunning() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Monitor is already running"})
+	// Followers would scan everything a second time
+	if a.elector != nil && !a.elector.IsLeader() {
+		apierror.Conflict(c, "This instance is not the cluster leader, start the monitor through the leader")
 		return
 	}
 
-	a.monitorService.Start()
-	c.JSON(http.StatusOK, gin.H{"message": "Monitor started successfully"})
+	message := "Monitor started successfully"
+	if !a.monitorService.Start() {
+		message = "Monitor is already running"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "is_running": true})
 }
 
-// StopMonitor stops the monitoring service
+// StopMonitor stops the monitoring service, canceling the scan in flight. Stopping
+// a stopped monitor succeeds.
 func (a *API) StopMonitor(c *gin.Context) {
-	if !a.monitorService.IsRunning() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Monitor is not running"})
-		return
+	message := "Monitor stopped successfully"
+	if !a.monitorService.Stop() {
+		message = "Monitor is not running"
 	}
-
-	a.monitorService.Stop()
-	c.JSON(http.StatusOK, gin.H{"message": "Monitor stopped successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": message, "is_running": false})
 }
 
-// GetDashboardStats returns dashboard statistics
+// dashboardTopRules is how many rules the dashboard breaks open results down by
+const dashboardTopRules = 10
+
+// GetDashboardStats returns dashboard statistics of the selected project, or of
+// every project for admins that didn't select one
 func (a *API) GetDashboardStats(c *gin.Context) {
 	var stats struct {
 		TotalRules       int64 `json:"total_rules"`
@@ -378,15 +745,34 @@ func (a *API) GetDashboardStats(c *gin.Context) {
 		ConfirmedResults int64 `json:"confirmed_results"`
 		TotalTokens      int64 `json:"total_tokens"`
 		ActiveTokens     int64 `json:"active_tokens"`
+
+		SLA *sla.Counts `json:"sla,omitempty"` // open results at risk of missing their SLA and overdue ones
+
+		OpenResults   *repository.OpenResultStats `json:"open_results,omitempty"` // by severity, age and rule, to pick what to work on next
+		OpenIncidents int64                       `json:"open_incidents"`
 	}
 
-	db.GetDB().Model(&models.MonitorRule{}).Count(&stats.TotalRules)
-	db.GetDB().Model(&models.MonitorRule{}).Where("is_active = ?", true).Count(&stats.ActiveRules)
-	db.GetDB().Model(&models.SearchResult{}).Count(&stats.TotalResults)
-	db.GetDB().Model(&models.SearchResult{}).Where("status = ?", "pending").Count(&stats.PendingResults)
-	db.GetDB().Model(&models.SearchResult{}).Where("status = ?", "confirmed").Count(&stats.ConfirmedResults)
-	db.GetDB().Model(&models.GitHubToken{}).Count(&stats.TotalTokens)
-	db.GetDB().Model(&models.GitHubToken{}).Where("is_active = ?", true).Count(&stats.ActiveTokens)
+	ctx := c.Request.Context()
+	stats.TotalRules, _ = a.repos.Rules.Count(ctx, false)
+	stats.ActiveRules, _ = a.repos.Rules.Count(ctx, true)
+	stats.TotalResults, _ = a.repos.Results.Count(ctx, repository.ResultFilter{})
+	stats.PendingResults, _ = a.repos.Results.Count(ctx, repository.ResultFilter{Status: "pending"})
+	stats.ConfirmedResults, _ = a.repos.Results.Count(ctx, repository.ResultFilter{Status: "confirmed"})
+	stats.TotalTokens, _ = a.repos.Tokens.Count(ctx, false)
+	stats.ActiveTokens, _ = a.repos.Tokens.Count(ctx, true)
+	now := time.Now()
+	if a.sla != nil {
+		if counts, err := a.sla.Count(ctx, a.repos.Results, now); err == nil {
+			stats.SLA = &counts
+		}
+	}
+	// Days start at midnight of the configured timezone, the week is today and the 6 days before
+	local := now.In(config.Location())
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	if open, err := a.repos.Results.OpenStats(ctx, today, today.AddDate(0, 0, -6), dashboardTopRules); err == nil {
+		stats.OpenResults = &open
+	}
+	stats.OpenIncidents, _ = a.repos.Incidents.CountOpen(ctx)
 
 	c.JSON(http.StatusOK, stats)
 }
@@ -395,9 +781,9 @@ func (a *API) GetDashboardStats(c *gin.Context) {
 
 // GetNotifications returns all notification configs
 func (a *API) GetNotifications(c *gin.Context) {
-	var notifications []models.NotificationConfig
-	if err := db.GetDB().Find(&notifications).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	notifications, err := a.repos.Notifications.List(c.Request.Context())
+	if err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -408,12 +794,17 @@ func (a *API) GetNotifications(c *gin.Context) {
 func (a *API) CreateNotification(c *gin.Context) {
 	var notification models.NotificationConfig
 	if err := c.ShouldBindJSON(&notification); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Bind(c, err)
+		return
+	}
+	notification.ProjectID = projectOf(c)
+	if !i18n.Valid(notification.Locale) {
+		apierror.Validation(c, apierror.FieldError{Field: "locale", Message: "must be en or zh"})
 		return
 	}
 
-	if err := db.GetDB().Create(&notification).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := a.repos.Notifications.Create(c.Request.Context(), &notification); err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -422,21 +813,29 @@ func (a *API) CreateNotification(c *gin.Context) {
 
 // UpdateNotification updates a notification config
 func (a *API) UpdateNotification(c *gin.Context) {
-	id := c.Param("id")
-	var notification models.NotificationConfig
-
-	if err := db.GetDB().First(&notification, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	notification, err := a.repos.Notifications.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Notification not found")
 		return
 	}
 
-	if err := c.ShouldBindJSON(&notification); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	projectID := notification.ProjectID
+	if err := c.ShouldBindJSON(notification); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	notification.ProjectID = projectID
+	if !i18n.Valid(notification.Locale) {
+		apierror.Validation(c, apierror.FieldError{Field: "locale", Message: "must be en or zh"})
 		return
 	}
 
-	if err := db.GetDB().Save(&notification).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := a.repos.Notifications.Save(c.Request.Context(), notification); err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -445,9 +844,12 @@ func (a *API) UpdateNotification(c *gin.Context) {
 
 // DeleteNotification deletes a notification config
 func (a *API) DeleteNotification(c *gin.Context) {
-	id := c.Param("id")
-	if err := db.GetDB().Delete(&models.NotificationConfig{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	if err := a.repos.Notifications.Delete(c.Request.Context(), id); err != nil {
+		apierror.Database(c, err)
 		return
 	}
 
@@ -456,11 +858,12 @@ func (a *API) DeleteNotification(c *gin.Context) {
 
 // TestNotification sends a test notification
 func (a *API) TestNotification(c *gin.Context) {
-	id := c.Param("id")
-	var notification models.NotificationConfig
-
-	if err := db.GetDB().First(&notification, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	if _, err := a.repos.Notifications.Get(c.Request.Context(), id); err != nil {
+		apierror.NotFound(c, "Notification not found")
 		return
 	}
 
@@ -475,32 +878,41 @@ func (a *API) Login(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
+		apierror.BadRequest(c, "Password is required")
 		return
 	}
 
 	// Verify password
 	if !auth.VerifyPassword(input.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		apierror.Unauthorized(c, "Invalid password")
 		return
 	}
 
-	// Generate token
-	token, err := auth.GenerateToken()
+	// Start a session, the password login always acts as admin
+	tokens, err := auth.CreateSession(c.Request.Context(), a.repos.Sessions, "admin", auth.RoleAdmin, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"message": "Login successful",
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.ExpiresAt,
+		"message":            "Login successful",
 	})
 }
 
 // GetAuthStatus returns the current authentication status
 func (a *API) GetAuthStatus(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	status := gin.H{
 		"authenticated": true,
-	})
+	}
+
+	if claims := auth.GetClaims(c); claims != nil {
+		status["user"] = claims.Subject
+		status["role"] = claims.Role
+	}
+
+	c.JSON(http.StatusOK, status)
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github-monitor/cache"
+	"github-monitor/cluster"
+	"github-monitor/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Health is the liveness probe, it only tells that the process is serving requests
+func (a *API) Health(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// SetElector makes monitor control and health checks cluster aware
+func (a *API) SetElector(elector *cluster.Elector) {
+	a.elector = elector
+}
+
+// SetCache adds the shared Redis cache to the readiness check
+func (a *API) SetCache(shared cache.Cache) {
+	a.shared = shared
+}
+
+// Ready is the readiness probe, it checks every component the service depends on
+// and returns 503 if any of them is unhealthy
+func (a *API) Ready(c *gin.Context) {
+	components := gin.H{
+		"database":      a.checkDatabase(c.Request.Context()),
+		"github_tokens": a.checkTokens(),
+		"monitor":       a.checkMonitor(),
+	}
+	if a.shared != nil {
+		components["redis"] = a.checkRedis(c.Request.Context())
+	}
+	if a.elector != nil {
+		components["cluster"] = gin.H{"status": "ok", "instance": a.elector.ID(), "is_leader": a.elector.IsLeader()}
+	}
+
+	healthy := true
+	for _, component := range components {
+		if component.(gin.H)["status"] == "fail" {
+			healthy = false
+		}
+	}
+
+	status := "ok"
+	code := http.StatusOK
+	if !healthy {
+		status = "fail"
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{
+		"status":     status,
+		"components": components,
+	})
+}
+
+func (a *API) checkDatabase(ctx context.Context) gin.H {
+	sqlDB, err := db.GetDB().DB()
+	if err != nil {
+		return gin.H{"status": "fail", "error": err.Error()}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return gin.H{"status": "fail", "error": err.Error()}
+	}
+
+	result := gin.H{"status": "ok", "latency_ms": time.Since(start).Milliseconds()}
+	if pool, err := db.Pool(); err == nil {
+		result["pool"] = pool
+	}
+
+	return result
+}
+
+func (a *API) checkRedis(ctx context.Context) gin.H {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := a.shared.Ping(ctx); err != nil {
+		return gin.H{"status": "fail", "error": err.Error()}
+	}
+	return gin.H{"status": "ok", "latency_ms": time.Since(start).Milliseconds()}
+}
+
+func (a *API) checkTokens() gin.H {
+	available := a.tokenPool.AvailableTokenCount()
+	result := gin.H{
+		"status":    "ok",
+		"available": available,
+		"total":     a.tokenPool.TokenCount(),
+	}
+
+	if available == 0 {
+		result["status"] = "fail"
+		result["error"] = "no GitHub token available"
+	}
+
+	return result
+}
+
+func (a *API) checkMonitor() gin.H {
+	if !a.monitorService.IsRunning() {
+		return gin.H{"status": "disabled", "running": false}
+	}
+
+	result := gin.H{
+		"status":         "ok",
+		"running":        true,
+		"last_heartbeat": a.monitorService.LastHeartbeat(),
+		"stalled":        false,
+	}
+
+	if a.monitorService.Stalled() {
+		result["status"] = "fail"
+		result["stalled"] = true
+		result["error"] = "monitor loop has not reported a heartbeat in over two scan intervals"
+	}
+
+	return result
+}
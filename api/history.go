@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/report"
+	"github-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultStatsDays is the scan history window of the stats without ?days=
+	defaultStatsDays = 30
+	// maxHistoryDays caps the scan history window of the stats and the export
+	maxHistoryDays = 365
+	// historyExportPageSize is how many rows the export reads per query
+	historyExportPageSize = 1000
+)
+
+// historyFilter reads ?rule_id= and ?days=, the number of days back from now.
+// Without days the window is defaultDays, 0 for the whole history.
+func historyFilter(c *gin.Context, defaultDays int) (repository.HistoryFilter, bool) {
+	ruleID, ok := uintQuery(c, "rule_id")
+	if !ok {
+		return repository.HistoryFilter{}, false
+	}
+
+	days := defaultDays
+	if value := c.Query("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxHistoryDays {
+			apierror.Validation(c, apierror.FieldError{Field: "days", Message: fmt.Sprintf("must be between 1 and %d", maxHistoryDays)})
+			return repository.HistoryFilter{}, false
+		}
+		days = n
+	}
+
+	filter := repository.HistoryFilter{RuleID: ruleID}
+	if days > 0 {
+		filter.Since = time.Now().AddDate(0, 0, -days)
+	}
+	return filter, true
+}
+
+// GetScanHistoryStats returns per rule success rates, durations, rate limit
+// frequency and daily results per scan, for tuning intervals and spotting rules
+// that keep failing
+func (a *API) GetScanHistoryStats(c *gin.Context) {
+	filter, ok := historyFilter(c, defaultStatsDays)
+	if !ok {
+		return
+	}
+
+	stats, err := report.ScanStats(c.Request.Context(), a.repos.History, filter)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"since": filter.Since,
+		"rules": stats,
+	})
+}
+
+// ExportScanHistory streams the scan history as CSV, newest first
+func (a *API) ExportScanHistory(c *gin.Context) {
+	filter, ok := historyFilter(c, 0)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("scan-history-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// The status is already sent once the rows start streaming, so a failure can only be logged
+	w := csv.NewWriter(c.Writer)
+	if err := w.Write([]string{"id", "rule_id", "rule_name", "status", "results_count", "new_results", "duration", "error_message", "created_at"}); err != nil {
+		log.Printf("Scan history export failed: %v", err)
+		return
+	}
+
+	var after uint64
+	for {
+		page, err := a.repos.History.ListAfter(c.Request.Context(), filter, after, historyExportPageSize)
+		if err != nil {
+			log.Printf("Scan history export failed: %v", err)
+			return
+		}
+
+		for _, scan := range page {
+			err := w.Write([]string{
+				strconv.FormatUint(uint64(scan.ID), 10),
+				strconv.FormatUint(uint64(scan.RuleID), 10),
+				report.CSVCell(scan.Rule.Name),
+				scan.Status,
+				strconv.Itoa(scan.ResultsCount),
+				strconv.Itoa(scan.NewResults),
+				strconv.Itoa(scan.Duration),
+				report.CSVCell(scan.ErrorMessage),
+				scan.CreatedAt.Format(time.RFC3339),
+			})
+			if err != nil {
+				log.Printf("Scan history export failed: %v", err)
+				return
+			}
+		}
+
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Printf("Scan history export failed: %v", err)
+			return
+		}
+		if len(page) < historyExportPageSize {
+			return
+		}
+		after = uint64(page[len(page)-1].ID)
+	}
+}
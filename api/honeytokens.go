@@ -0,0 +1,102 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/db/models"
+	"github-monitor/honeytoken"
+
+	"github.com/gin-gonic/gin"
+)
+
+type honeytokenRequest struct {
+	Name string `json:"name" binding:"required,max=200"`
+	Kind string `json:"kind" binding:"required"`
+	Host string `json:"host" binding:"max=255"` // database host of DSNs
+	Note string `json:"note"`                   // where it will be planted
+}
+
+// GetHoneytokens returns the honeytokens of the project, triggered or not
+func (a *API) GetHoneytokens(c *gin.Context) {
+	tokens, err := a.repos.Honeytokens.List(c.Request.Context())
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateHoneytoken generates a canary credential together with the critical rule
+// that searches for it
+func (a *API) CreateHoneytoken(c *gin.Context) {
+	var req honeytokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	if !honeytoken.ValidKinds[req.Kind] {
+		apierror.Validation(c, apierror.FieldError{Field: "kind", Message: "must be one of: aws_key database_dsn api_key"})
+		return
+	}
+
+	value, marker, err := honeytoken.Generate(req.Kind, req.Host)
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	token := models.Honeytoken{
+		Name:      req.Name,
+		Kind:      req.Kind,
+		Value:     value,
+		Marker:    marker,
+		Note:      req.Note,
+		ProjectID: projectOf(c),
+	}
+
+	rule, err := honeytoken.Rule(&token)
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	if err := a.repos.Rules.Create(c.Request.Context(), rule); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	token.RuleID = rule.ID
+	if err := a.repos.Honeytokens.Create(c.Request.Context(), &token); err != nil {
+		if deleteErr := a.repos.Rules.Delete(c.Request.Context(), rule.ID); deleteErr != nil {
+			log.Printf("Failed to delete the rule of honeytoken %q: %v", token.Name, deleteErr)
+		}
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// DeleteHoneytoken deletes a honeytoken and its rule. Results it triggered stay.
+func (a *API) DeleteHoneytoken(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	token, err := a.repos.Honeytokens.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Honeytoken not found")
+		return
+	}
+
+	if err := a.repos.Honeytokens.Delete(c.Request.Context(), token.ID); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	if err := a.repos.Rules.Delete(c.Request.Context(), token.RuleID); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Honeytoken deleted successfully"})
+}
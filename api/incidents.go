@@ -0,0 +1,295 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/db/models"
+	"github-monitor/events"
+	"github-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxIncidentResults caps the results returned with an incident, the results
+// endpoint pages through the rest with incident_id
+const maxIncidentResults = 100
+
+type incidentUpdate struct {
+	Title    *string `json:"title" binding:"omitempty,min=1,max=255"`
+	Status   *string `json:"status"`
+	Assignee *string `json:"assignee" binding:"omitempty,max=255"`
+}
+
+// GetIncidents returns a page of the incidents of the project, newest first
+func (a *API) GetIncidents(c *gin.Context) {
+	page, pageSize, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+	filter := repository.IncidentFilter{
+		Status:   c.Query("status"),
+		Severity: c.Query("severity"),
+		Assignee: c.Query("assignee"),
+	}
+
+	incidents, total, err := a.repos.Incidents.List(c.Request.Context(), filter, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(gin.H{"incidents": incidents}, page, pageSize, total))
+}
+
+// GetIncident returns an incident with its newest results and its timeline
+func (a *API) GetIncident(c *gin.Context) {
+	incident, ok := a.incident(c)
+	if !ok {
+		return
+	}
+
+	results, _, err := a.repos.Results.List(c.Request.Context(), repository.ResultFilter{IncidentID: incident.ID}, repository.Page{Size: maxIncidentResults})
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	a.annotateSLA(results)
+	redactResults(results)
+	timeline, err := a.repos.Incidents.Timeline(c.Request.Context(), incident.ID)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"incident": incident,
+		"results":  results,
+		"timeline": timeline,
+	})
+}
+
+// UpdateIncident renames, assigns or triages an incident. A status applies to
+// every result of the incident.
+func (a *API) UpdateIncident(c *gin.Context) {
+	incident, ok := a.incident(c)
+	if !ok {
+		return
+	}
+
+	var req incidentUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	if req.Status != nil && !models.ValidResultStatuses[*req.Status] {
+		apierror.Validation(c, apierror.FieldError{Field: "status", Message: "must be one of: pending confirmed false_positive resolved"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var changes []models.IncidentEvent
+	if req.Title != nil && *req.Title != incident.Title {
+		changes = append(changes, models.IncidentEvent{Type: models.IncidentRenamed, Detail: fmt.Sprintf("%q -> %q", incident.Title, *req.Title)})
+		incident.Title = *req.Title
+	}
+	if req.Assignee != nil && *req.Assignee != incident.Assignee {
+		detail := "Unassigned"
+		if *req.Assignee != "" {
+			detail = "Assigned to " + *req.Assignee
+		}
+		changes = append(changes, models.IncidentEvent{Type: models.IncidentAssigned, Detail: detail})
+		incident.Assignee = *req.Assignee
+	}
+	if req.Status != nil && *req.Status != incident.Status {
+		ids, err := a.repos.Incidents.ResultIDs(ctx, incident.ID)
+		if err != nil {
+			apierror.Database(c, err)
+			return
+		}
+		if len(ids) > 0 {
+			if _, err := a.repos.Results.UpdateStatus(ctx, ids, *req.Status); err != nil {
+				apierror.Database(c, err)
+				return
+			}
+			events.PublishTo(incident.ProjectID, events.TypeResultStatus, events.StatusChange{IDs: ids, Status: *req.Status})
+		}
+		changes = append(changes, models.IncidentEvent{Type: models.IncidentStatus, Detail: fmt.Sprintf("%s -> %s, %d results", incident.Status, *req.Status, len(ids))})
+		incident.Status = *req.Status
+	}
+
+	if err := a.repos.Incidents.Save(ctx, incident); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	for _, change := range changes {
+		a.incidentEvent(c, incident.ID, change.Type, change.Detail)
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// MergeIncidents moves the results of other incidents into an incident and deletes
+// the emptied incidents, for leaks that were grouped apart
+func (a *API) MergeIncidents(c *gin.Context) {
+	incident, ok := a.incident(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		IncidentIDs []uint `json:"incident_ids" binding:"required,min=1,max=100"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	for _, id := range req.IncidentIDs {
+		if id == incident.ID {
+			apierror.Validation(c, apierror.FieldError{Field: "incident_ids", Message: "can't contain the incident merged into"})
+			return
+		}
+		if _, err := a.repos.Incidents.Get(ctx, id); err != nil {
+			apierror.Validation(c, apierror.FieldError{Field: "incident_ids", Message: fmt.Sprintf("incident %d not found", id)})
+			return
+		}
+	}
+
+	for _, id := range req.IncidentIDs {
+		ids, err := a.repos.Incidents.ResultIDs(ctx, id)
+		if err != nil {
+			apierror.Database(c, err)
+			return
+		}
+		if len(ids) > 0 {
+			if err := a.repos.Incidents.MoveResults(ctx, incident.ID, ids); err != nil {
+				apierror.Database(c, err)
+				return
+			}
+		}
+		if err := a.repos.Incidents.Delete(ctx, id); err != nil {
+			apierror.Database(c, err)
+			return
+		}
+		a.incidentEvent(c, id, models.IncidentMerged, fmt.Sprintf("Merged into incident #%d", incident.ID))
+		a.incidentEvent(c, incident.ID, models.IncidentMerged, fmt.Sprintf("%d results of incident #%d merged in", len(ids), id))
+	}
+
+	a.respondIncident(c, http.StatusOK, incident.ID)
+}
+
+// SplitIncident moves some results of an incident into a new incident, for results
+// that were grouped together but belong to different leaks
+func (a *API) SplitIncident(c *gin.Context) {
+	incident, ok := a.incident(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ResultIDs []uint `json:"result_ids" binding:"required,min=1,max=1000"`
+		Title     string `json:"title" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	ids, err := a.repos.Incidents.ResultIDs(ctx, incident.ID)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	member := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		member[id] = true
+	}
+	split := make(map[uint]bool, len(req.ResultIDs))
+	for _, id := range req.ResultIDs {
+		if !member[id] {
+			apierror.Validation(c, apierror.FieldError{Field: "result_ids", Message: fmt.Sprintf("result %d is not part of the incident", id)})
+			return
+		}
+		split[id] = true
+	}
+	if len(split) == len(ids) {
+		apierror.Validation(c, apierror.FieldError{Field: "result_ids", Message: "must leave at least one result in the incident"})
+		return
+	}
+
+	title := req.Title
+	if title == "" {
+		title = fmt.Sprintf("Split from incident #%d: %s", incident.ID, incident.Title)
+	}
+	created := models.Incident{
+		Title:     title,
+		Status:    incident.Status,
+		Assignee:  incident.Assignee,
+		GroupBy:   models.GroupManual,
+		Severity:  incident.Severity,
+		ProjectID: incident.ProjectID,
+	}
+	if err := a.repos.Incidents.Create(ctx, &created); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	if err := a.repos.Incidents.MoveResults(ctx, created.ID, req.ResultIDs); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	a.incidentEvent(c, created.ID, models.IncidentCreated, fmt.Sprintf("Split from incident #%d", incident.ID))
+	a.incidentEvent(c, incident.ID, models.IncidentSplit, fmt.Sprintf("%d results split into incident #%d", len(split), created.ID))
+
+	a.respondIncident(c, http.StatusCreated, created.ID)
+}
+
+// incident loads the incident of the id parameter, responding with an error when
+// there is none in the projects of the caller
+func (a *API) incident(c *gin.Context) (*models.Incident, bool) {
+	id, ok := idParam(c)
+	if !ok {
+		return nil, false
+	}
+	incident, err := a.repos.Incidents.Get(c.Request.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.NotFound(c, "Incident not found")
+		return nil, false
+	}
+	if err != nil {
+		apierror.Database(c, err)
+		return nil, false
+	}
+	return incident, true
+}
+
+// respondIncident responds with the incident as stored, its result count and
+// severity follow the results moved
+func (a *API) respondIncident(c *gin.Context, status int, id uint) {
+	incident, err := a.repos.Incidents.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	c.JSON(status, incident)
+}
+
+// incidentEvent records a change made by the caller on the timeline of an incident,
+// a failure only loses the timeline entry
+func (a *API) incidentEvent(c *gin.Context, incidentID uint, eventType, detail string) {
+	event := models.IncidentEvent{
+		IncidentID: incidentID,
+		Type:       eventType,
+		Actor:      ownerOf(c),
+		Detail:     detail,
+	}
+	if err := a.repos.Incidents.AddEvent(c.Request.Context(), &event); err != nil {
+		log.Printf("Failed to record the timeline of incident %d: %v", incidentID, err)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/github"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ingestFinding struct {
+	RepoFullName    string   `json:"repo_full_name" binding:"required,max=255"`
+	RepoURL         string   `json:"repo_url" binding:"max=512"`
+	FilePath        string   `json:"file_path" binding:"max=1024"`
+	FileURL         string   `json:"file_url" binding:"max=1024"`
+	HTMLURL         string   `json:"html_url" binding:"max=1024"`
+	ContentSnippet  string   `json:"content_snippet"`
+	MatchedKeywords []string `json:"matched_keywords"`
+	Severity        string   `json:"severity"` // the rule severity of the source when empty
+}
+
+type ingestRequest struct {
+	Source   string          `json:"source" binding:"required"`
+	Findings []ingestFinding `json:"findings" binding:"required,dive"`
+}
+
+// requestKey returns the API key of a request, sent as X-API-Key or as bearer token
+func requestKey(c *gin.Context) string {
+	key := c.GetHeader("X-API-Key")
+	if key == "" {
+		key, _ = strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	return key
+}
+
+// ingestKey returns the configured key the request authenticates with
+func ingestKey(c *gin.Context) *config.IngestKey {
+	key := requestKey(c)
+	if key == "" {
+		return nil
+	}
+
+	var match *config.IngestKey
+	for i, configured := range config.AppConfig.Ingest.Keys {
+		// Every key is compared so the time taken doesn't tell which one came close
+		if subtle.ConstantTimeCompare([]byte(key), []byte(configured.Key)) == 1 {
+			match = &config.AppConfig.Ingest.Keys[i]
+		}
+	}
+	return match
+}
+
+// IngestResults records findings submitted by external scanners (trufflehog,
+// gitleaks, ...) as results of the project of their API key, labeled with their
+// source. They are deduplicated, whitelisted and notified like scan results.
+func (a *API) IngestResults(c *gin.Context) {
+	ingest := config.AppConfig.Ingest
+	if !ingest.Enabled {
+		apierror.NotFound(c, "Result ingestion is not enabled")
+		return
+	}
+	key := ingestKey(c)
+	if key == nil {
+		apierror.Unauthorized(c, "Invalid API key")
+		return
+	}
+
+	var req ingestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	if !models.ValidIngestSource(req.Source) {
+		apierror.Validation(c, apierror.FieldError{Field: "source", Message: "must be 1-32 lowercase letters, digits, - or _ and not a built-in source"})
+		return
+	}
+	if len(req.Findings) > ingest.MaxFindings {
+		apierror.Validation(c, apierror.FieldError{Field: "findings", Message: fmt.Sprintf("at most %d findings per request", ingest.MaxFindings)})
+		return
+	}
+
+	now := time.Now()
+	items := make([]*github.SearchResultItem, 0, len(req.Findings))
+	for i, finding := range req.Findings {
+		if finding.Severity != "" && !models.ValidSeverities[finding.Severity] {
+			apierror.Validation(c, apierror.FieldError{Field: fmt.Sprintf("findings[%d].severity", i), Message: "must be one of: critical high medium low info"})
+			return
+		}
+		items = append(items, &github.SearchResultItem{
+			RepoFullName:    finding.RepoFullName,
+			RepoURL:         finding.RepoURL,
+			FilePath:        finding.FilePath,
+			FileURL:         finding.FileURL,
+			HTMLURL:         finding.HTMLURL,
+			ContentSnippet:  finding.ContentSnippet,
+			MatchedKeywords: finding.MatchedKeywords,
+			CreatedAt:       now,
+			Severity:        finding.Severity,
+		})
+	}
+
+	projectID := key.ProjectID
+	if projectID == 0 {
+		projectID = models.DefaultProjectID
+	}
+	newResults, err := a.monitorService.Ingest(c.Request.Context(), projectID, req.Source, items)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	log.Printf("Ingested %d findings from %s (%s), %d new", len(items), key.Name, req.Source, len(newResults))
+
+	c.JSON(http.StatusOK, gin.H{
+		"received": len(items),
+		"new":      len(newResults),
+	})
+}
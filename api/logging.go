@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sensitiveQueryParams are query parameters that carry credentials, such as the
+// WebSocket ?token=, and must not end up in logs
+var sensitiveQueryParams = []string{"token"}
+
+// sensitivePaths are path prefixes followed by a credential, such as the token of
+// a share link, which must not end up in logs or error reports either
+var sensitivePaths = []string{"/api/v1/share/"}
+
+// requestLogger is gin.Logger with the values of sensitive query parameters and
+// path segments redacted
+func requestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			p.StatusCode,
+			p.Latency,
+			p.ClientIP,
+			p.Method,
+			redactQuery(redactPath(p.Path)),
+			p.ErrorMessage,
+		)
+	})
+}
+
+// hidePathCredential redacts the credential in the path of a request once it is
+// routed, so error reports of the request don't carry it
+func hidePathCredential() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.URL.Path = redactPath(c.Request.URL.Path)
+		c.Request.URL.RawPath = ""
+		c.Next()
+	}
+}
+
+// redactPath replaces the path segment after a sensitive prefix
+func redactPath(path string) string {
+	for _, prefix := range sensitivePaths {
+		rest, found := strings.CutPrefix(path, prefix)
+		if !found || rest == "" {
+			continue
+		}
+		end := strings.IndexAny(rest, "/?")
+		if end < 0 {
+			end = len(rest)
+		}
+		return prefix + "REDACTED" + rest[end:]
+	}
+	return path
+}
+
+// redactQuery replaces the values of sensitive query parameters in a path with a query
+func redactQuery(path string) string {
+	base, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Can't tell the parameters apart, leave the whole query out
+		return base + "?REDACTED"
+	}
+
+	redacted := false
+	for _, name := range sensitiveQueryParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+	"github-monitor/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcNonceCookie = "oidc_nonce"
+	oidcCookiePath  = "/api/v1/auth/oidc"
+)
+
+// SetOIDCProvider enables the SSO login endpoints
+func (a *API) SetOIDCProvider(provider *auth.OIDCProvider) {
+	a.oidcProvider = provider
+}
+
+// GetAuthProviders tells the login page which login methods are available
+func (a *API) GetAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"password": config.AppConfig.Auth.Password != "",
+		"oidc":     a.oidcProvider != nil,
+	})
+}
+
+// OIDCLogin redirects the browser to the identity provider
+func (a *API) OIDCLogin(c *gin.Context) {
+	if a.oidcProvider == nil {
+		apierror.NotFound(c, "SSO login is not enabled")
+		return
+	}
+
+	state, err := auth.RandomString(16)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to start SSO login")
+		return
+	}
+	nonce, err := auth.RandomString(16)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to start SSO login")
+		return
+	}
+
+	secure := c.Request.TLS != nil
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, 600, oidcCookiePath, "", secure, true)
+	c.SetCookie(oidcNonceCookie, nonce, 600, oidcCookiePath, "", secure, true)
+
+	c.Redirect(http.StatusFound, a.oidcProvider.AuthCodeURL(state, nonce))
+}
+
+// OIDCCallback completes the SSO login and hands our own JWT to the frontend
+func (a *API) OIDCCallback(c *gin.Context) {
+	if a.oidcProvider == nil {
+		apierror.NotFound(c, "SSO login is not enabled")
+		return
+	}
+
+	if errParam := c.Query("error"); errParam != "" {
+		apierror.Unauthorized(c, "SSO login failed: "+errParam)
+		return
+	}
+
+	state, err := c.Cookie(oidcStateCookie)
+	if err != nil || state == "" || state != c.Query("state") {
+		apierror.BadRequest(c, "Invalid SSO state")
+		return
+	}
+	nonce, _ := c.Cookie(oidcNonceCookie)
+
+	// The state and nonce are single use
+	c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath, "", false, true)
+	c.SetCookie(oidcNonceCookie, "", -1, oidcCookiePath, "", false, true)
+
+	identity, err := a.oidcProvider.Exchange(c.Request.Context(), c.Query("code"), nonce)
+	if err != nil {
+		log.Printf("SSO login failed: %v", err)
+		apierror.Unauthorized(c, "SSO login failed")
+		return
+	}
+
+	if identity.Role == "" {
+		log.Printf("SSO login denied for %s: no role mapped from groups %v", identity.Subject, identity.Groups)
+		apierror.Forbidden(c, "Your account is not allowed to access this application")
+		return
+	}
+
+	subject := identity.Email
+	if subject == "" {
+		subject = identity.Subject
+	}
+
+	tokens, err := auth.CreateSession(c.Request.Context(), a.repos.Sessions, subject, identity.Role, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
+		return
+	}
+
+	log.Printf("SSO login successful for %s with role %s", subject, identity.Role)
+
+	// Pass the tokens in the fragment so they never reach server logs
+	fragment := url.Values{
+		"token":         {tokens.AccessToken},
+		"refresh_token": {tokens.RefreshToken},
+	}
+	target := config.AppConfig.Auth.OIDC.FrontendURL + "#" + fragment.Encode()
+	c.Redirect(http.StatusFound, target)
+}
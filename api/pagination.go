@@ -0,0 +1,90 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	"github-monitor/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// afterCursor reads the ?after=<id> keyset cursor. ok is false when the request
+// uses the legacy page params instead. after=0 starts from the newest row.
+func afterCursor(c *gin.Context) (after uint64, ok bool, err error) {
+	value := c.Query("after")
+	if value == "" {
+		return 0, false, nil
+	}
+
+	after, err = strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return after, true, nil
+}
+
+// invalidCursor responds to a malformed ?after= value
+func invalidCursor(c *gin.Context) {
+	apierror.Validation(c, apierror.FieldError{Field: "after", Message: "must be a non-negative integer ID"})
+}
+
+// nextCursor returns the cursor for the following page, or nil when this was the last one
+func nextCursor(lastID uint, count, pageSize int) *uint {
+	if count == 0 || count < pageSize {
+		return nil
+	}
+	return &lastID
+}
+
+// maxPageSize caps page_size so a single request can't load a whole table
+const maxPageSize = 100
+
+// maxPageOffset is how many rows page may skip. Deeper offsets make the database
+// scan and drop every row before the page, the after cursor pages on instead.
+const maxPageOffset = 10000
+
+// pageParams reads ?page= and ?page_size=, falling back to the first page and
+// defaultSize for missing values and capping the size at maxPageSize. Values that
+// aren't positive integers and pages past maxPageOffset get a validation error
+// and ok false.
+func pageParams(c *gin.Context, defaultSize int) (page, pageSize int, ok bool) {
+	page, pageSize = 1, defaultSize
+	if value := c.Query("page"); value != "" {
+		var err error
+		if page, err = strconv.Atoi(value); err != nil || page < 1 {
+			apierror.Validation(c, apierror.FieldError{Field: "page", Message: "must be a positive integer"})
+			return 0, 0, false
+		}
+	}
+	if value := c.Query("page_size"); value != "" {
+		var err error
+		if pageSize, err = strconv.Atoi(value); err != nil || pageSize < 1 {
+			apierror.Validation(c, apierror.FieldError{Field: "page_size", Message: "must be a positive integer"})
+			return 0, 0, false
+		}
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	if page-1 > maxPageOffset/pageSize {
+		apierror.Validation(c, apierror.FieldError{
+			Field:   "page",
+			Message: fmt.Sprintf("must be at most %d at a page_size of %d, narrow the filters or use the after cursor to go further", maxPageOffset/pageSize+1, pageSize),
+		})
+		return 0, 0, false
+	}
+	return page, pageSize, true
+}
+
+// paginated adds the pagination of a page of total rows to a list response
+func paginated(body gin.H, page, pageSize int, total int64) gin.H {
+	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
+	body["total"] = total
+	body["page"] = page
+	body["page_size"] = pageSize
+	body["total_pages"] = totalPages
+	body["has_next"] = int64(page) < totalPages
+	return body
+}
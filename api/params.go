@@ -0,0 +1,38 @@
+package api
+
+import (
+	"strconv"
+
+	"github-monitor/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idParam parses the :id path parameter, responding with 400 when it isn't a valid ID
+func idParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.BadRequest(c, "Invalid ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// uintQuery parses an optional numeric query parameter, 0 when absent
+func uintQuery(c *gin.Context, name string) (uint, bool) {
+	return uintValue(c, name, c.Query(name))
+}
+
+// uintValue parses an optional numeric parameter value, 0 when empty
+func uintValue(c *gin.Context, name, value string) (uint, bool) {
+	if value == "" {
+		return 0, true
+	}
+
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		apierror.Validation(c, apierror.FieldError{Field: name, Message: "must be a positive integer"})
+		return 0, false
+	}
+	return uint(n), true
+}
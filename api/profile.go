@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+	"github-monitor/profile"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCompanyProfile returns the company profile and the rules it expands into
+func (a *API) GetCompanyProfile(c *gin.Context) {
+	p, err := profile.Load(c.Request.Context(), a.repos.DB)
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile":         p,
+		"recommendations": p.Recommend(),
+	})
+}
+
+// UpdateCompanyProfile replaces the company profile and syncs the generated rules
+func (a *API) UpdateCompanyProfile(c *gin.Context) {
+	var p profile.Profile
+	if err := c.ShouldBindJSON(&p); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	actor := ""
+	if claims := auth.GetClaims(c); claims != nil {
+		actor = claims.Subject
+	}
+
+	result, err := profile.Save(c.Request.Context(), a.repos.DB, &p, actor)
+	var fieldErr *profile.FieldError
+	if errors.As(err, &fieldErr) {
+		apierror.Validation(c, apierror.FieldError{Field: fieldErr.Field, Message: fieldErr.Message})
+		return
+	}
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile":         p,
+		"recommendations": p.Recommend(),
+		"sync":            result,
+	})
+}
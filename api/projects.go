@@ -0,0 +1,257 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// projectOf returns the project new records of the request are created in
+func projectOf(c *gin.Context) uint {
+	if access := auth.GetProjectAccess(c); access != nil {
+		return access.ProjectID
+	}
+	return models.DefaultProjectID
+}
+
+// deploymentWide only lets through callers that may see data spanning every
+// project. It must be used after auth.ProjectMiddleware.
+func deploymentWide() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if access := auth.GetProjectAccess(c); access == nil || !access.DeploymentWide() {
+			apierror.Forbidden(c, "Only available to users that aren't limited to projects")
+			return
+		}
+		c.Next()
+	}
+}
+
+// claimsOf returns the caller's claims, nil when auth is disabled
+func claimsOf(c *gin.Context) *auth.Claims {
+	if !config.AppConfig.Auth.Enabled {
+		return nil
+	}
+	return auth.GetClaims(c)
+}
+
+// projectAdmin checks that the caller administers the project, as an admin or as
+// one of its admins, responding with 404 or 403 when they don't
+func (a *API) projectAdmin(c *gin.Context, id uint) bool {
+	access, err := auth.ResolveProject(c.Request.Context(), a.repos.Projects, claimsOf(c), id)
+	switch {
+	case errors.Is(err, auth.ErrProjectNotFound):
+		apierror.NotFound(c, "Project not found")
+		return false
+	case errors.Is(err, auth.ErrProjectForbidden):
+		apierror.Forbidden(c, "No access to this project")
+		return false
+	case err != nil:
+		apierror.Database(c, err)
+		return false
+	case access.Role != auth.RoleAdmin:
+		apierror.Forbidden(c, "Insufficient permissions in this project")
+		return false
+	}
+	return true
+}
+
+// GetProjects returns the projects the caller may select
+func (a *API) GetProjects(c *gin.Context) {
+	ctx := c.Request.Context()
+	memberships, all, err := auth.AccessibleProjects(ctx, a.repos.Projects, claimsOf(c))
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	var ids []uint
+	roles := make(map[uint]string)
+	if !all {
+		ids = make([]uint, 0, len(memberships))
+		for _, member := range memberships {
+			ids = append(ids, member.ProjectID)
+			roles[member.ProjectID] = member.Role
+		}
+	}
+
+	projects, err := a.repos.Projects.List(ctx, ids)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	type projectView struct {
+		models.Project
+		Role string `json:"role"` // caller's role in the project
+	}
+	views := make([]projectView, 0, len(projects))
+	for _, project := range projects {
+		role := auth.RoleAdmin
+		if !all {
+			role = roles[project.ID]
+		}
+		views = append(views, projectView{Project: project, Role: role})
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+type projectRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description"`
+}
+
+// CreateProject creates a new project
+func (a *API) CreateProject(c *gin.Context) {
+	var req projectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	project := models.Project{Name: req.Name, Description: req.Description}
+	if err := a.repos.Projects.Create(c.Request.Context(), &project); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// UpdateProject renames a project or changes its description
+func (a *API) UpdateProject(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	project, err := a.repos.Projects.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Project not found")
+		return
+	}
+
+	var req projectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	project.Name = req.Name
+	project.Description = req.Description
+	if err := a.repos.Projects.Save(c.Request.Context(), project); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject deletes a project that no longer has rules
+func (a *API) DeleteProject(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	if id == models.DefaultProjectID {
+		apierror.BadRequest(c, "The default project can't be deleted")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := a.repos.Projects.Get(ctx, id); err != nil {
+		apierror.NotFound(c, "Project not found")
+		return
+	}
+
+	// Results and history hang off the rules, so an empty project leaves nothing behind
+	rules, err := a.repos.Rules.Count(repository.WithProjects(ctx, []uint{id}), false)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	if rules > 0 {
+		apierror.Conflict(c, "The project still has rules, delete them first")
+		return
+	}
+
+	if err := a.repos.Projects.Delete(ctx, id); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+// GetProjectMembers returns the members of a project, for admins and the project's admins
+func (a *API) GetProjectMembers(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	if !a.projectAdmin(c, id) {
+		return
+	}
+
+	members, err := a.repos.Projects.Members(c.Request.Context(), id)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+type memberRequest struct {
+	Subject string `json:"subject" binding:"required,max=255"`
+	Role    string `json:"role" binding:"required,oneof=admin analyst viewer"`
+}
+
+// SetProjectMember adds a user to a project or changes their role in it
+func (a *API) SetProjectMember(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	if !a.projectAdmin(c, id) {
+		return
+	}
+
+	var req memberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	member := models.ProjectMember{ProjectID: id, Subject: req.Subject, Role: req.Role}
+	if err := a.repos.Projects.SetMember(c.Request.Context(), &member); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveProjectMember removes a user from a project
+func (a *API) RemoveProjectMember(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	if !a.projectAdmin(c, id) {
+		return
+	}
+
+	if err := a.repos.Projects.RemoveMember(c.Request.Context(), id, c.Param("subject")); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/redact"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewRuleQuery returns the code search query a rule would be scanned with,
+// without saving the rule. With ?search=true the query is run for one page to show
+// what it finds, which costs a search API call.
+func (a *API) PreviewRuleQuery(c *gin.Context) {
+	var rule models.MonitorRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	if rule.Keywords == "" {
+		rule.Keywords = "[]"
+	}
+	if rule.QueryDialect == "" {
+		rule.QueryDialect = github.DialectLegacy
+	}
+	if _, err := github.ParseKeywords(rule.Keywords); err != nil {
+		apierror.Validation(c, apierror.FieldError{Field: "keywords", Message: "must be a JSON array of strings"})
+		return
+	}
+	if rule.TokenGroup != "" && !config.HasTokenGroup(rule.TokenGroup) {
+		apierror.Validation(c, apierror.FieldError{Field: "token_group", Message: "must be one of github.token_groups"})
+		return
+	}
+	if !validScoring(c, &rule) {
+		return
+	}
+
+	preview, err := a.monitorService.PreviewQuery(c.Request.Context(), rule, c.Query("search") == "true")
+	var invalid *github.InvalidQueryError
+	switch {
+	case errors.As(err, &invalid):
+		field := "keywords"
+		if rule.RawQuery != "" {
+			field = "raw_query"
+		}
+		apierror.Validation(c, apierror.FieldError{Field: field, Message: "GitHub rejected the query: " + invalid.Details})
+		return
+	case err != nil:
+		apierror.Respond(c, http.StatusBadGateway, apierror.CodeUnavailable, "Code search failed: "+err.Error())
+		return
+	}
+	if config.AppConfig.Redaction.Enabled {
+		for _, result := range preview.Results {
+			result.ContentSnippet = redact.Secrets(result.ContentSnippet)
+		}
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
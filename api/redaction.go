@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/events"
+	"github-monitor/redact"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactResults masks the secret-looking values of listed results, the full values
+// are only returned by RevealSearchResult
+func redactResults(results []models.SearchResult) {
+	if !config.AppConfig.Redaction.Enabled {
+		return
+	}
+	for i := range results {
+		redactResult(&results[i])
+	}
+}
+
+func redactResult(result *models.SearchResult) {
+	result.ContentSnippet = redact.Secrets(result.ContentSnippet)
+	result.Matches = redact.Secrets(result.Matches)
+	result.MatchContext = redact.Secrets(result.MatchContext)
+	result.VerdictReason = redact.Secrets(result.VerdictReason)
+}
+
+// redactEvent masks the result carried by a live event, the events are shared with
+// the monitor so the result is copied
+func redactEvent(event events.Event) events.Event {
+	if !config.AppConfig.Redaction.Enabled {
+		return event
+	}
+	switch result := event.Data.(type) {
+	case models.SearchResult:
+		redactResult(&result)
+		event.Data = result
+	case *models.SearchResult:
+		masked := *result
+		redactResult(&masked)
+		event.Data = &masked
+	}
+	return event
+}
+
+// RevealSearchResult returns a result with its secrets unmasked. It is a POST so the
+// audit log records who revealed what, and only the redaction.reveal_roles may use it.
+func (a *API) RevealSearchResult(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	result, err := a.repos.Results.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Result not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/report"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetReportScheduler enables sending reports on demand
+func (a *API) SetReportScheduler(scheduler *report.Scheduler) {
+	a.reportScheduler = scheduler
+}
+
+// GetReports returns generated summary reports, newest first
+func (a *API) GetReports(c *gin.Context) {
+	reports, err := a.repos.Reports.List(c.Request.Context(), 100)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetReportHTML returns the rendered HTML of a report
+func (a *API) GetReportHTML(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	rep, err := a.repos.Reports.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Report not found")
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rep.HTML))
+}
+
+// SendReport generates and delivers a summary report for the last 7 days immediately
+func (a *API) SendReport(c *gin.Context) {
+	if a.reportScheduler == nil {
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeUnavailable, "Reports are not available")
+		return
+	}
+
+	end := time.Now()
+	rep, err := a.reportScheduler.Send(end.AddDate(0, 0, -7), end)
+	if err != nil {
+		if rep != nil {
+			// Generated and stored, but not delivered everywhere
+			c.JSON(http.StatusBadGateway, gin.H{"code": apierror.CodeDeliveryFailed, "error": err.Error(), "report": rep})
+			return
+		}
+		apierror.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rep)
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/redact"
+	"github-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordActor names the caller on the request context, so rule changes are
+// attributed in the rule history. It must be used after auth.AuthMiddleware.
+func recordActor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims := auth.GetClaims(c); claims != nil {
+			c.Request = c.Request.WithContext(repository.WithActor(c.Request.Context(), claims.Subject))
+		}
+		c.Next()
+	}
+}
+
+// timeQuery parses an optional RFC 3339 query parameter, zero when absent
+func timeQuery(c *gin.Context, name string) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		apierror.Validation(c, apierror.FieldError{Field: name, Message: "must be an RFC 3339 timestamp"})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// GetRuleRevisions returns the change history of every rule, or of one rule when
+// called on /rules/:id/revisions
+func (a *API) GetRuleRevisions(c *gin.Context) {
+	page, pageSize, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	var filter repository.RevisionFilter
+	if c.Param("id") != "" {
+		if filter.RuleID, ok = idParam(c); !ok {
+			return
+		}
+	} else if filter.RuleID, ok = uintQuery(c, "rule_id"); !ok {
+		return
+	}
+	if filter.Since, ok = timeQuery(c, "since"); !ok {
+		return
+	}
+	if filter.Until, ok = timeQuery(c, "until"); !ok {
+		return
+	}
+
+	revisions, total, err := a.repos.Revisions.List(c.Request.Context(), filter, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(gin.H{"revisions": revisions}, page, pageSize, total))
+}
+
+// RollbackRule restores a rule to the state it had after the given revision
+func (a *API) RollbackRule(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	revisionID, err := strconv.ParseUint(c.Param("revision"), 10, 64)
+	if err != nil {
+		apierror.BadRequest(c, "Invalid revision ID")
+		return
+	}
+
+	ctx := c.Request.Context()
+	revision, err := a.repos.Revisions.Get(ctx, uint(revisionID))
+	if err != nil || revision.RuleID != id {
+		apierror.NotFound(c, "Revision not found")
+		return
+	}
+	if revision.After == "" {
+		apierror.BadRequest(c, "The revision deleted the rule, roll back to an earlier one")
+		return
+	}
+
+	rule, err := a.repos.Rules.Get(ctx, id)
+	if err != nil {
+		apierror.NotFound(c, "Rule not found")
+		return
+	}
+
+	var snapshot repository.RuleSnapshot
+	if err := json.Unmarshal([]byte(revision.After), &snapshot); err != nil {
+		apierror.Internal(c, fmt.Errorf("invalid snapshot in revision %d: %w", revision.ID, err))
+		return
+	}
+	snapshot.ApplyTo(rule)
+
+	ctx = repository.WithNote(ctx, fmt.Sprintf("rollback to revision %d", revision.ID))
+	if err := a.repos.Rules.Save(ctx, rule); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+type cloneRuleRequest struct {
+	Name string `json:"name"` // defaults to "<name> (copy)"
+}
+
+// CloneRule copies a rule into a new, inactive rule, so keywords can be tuned
+// without touching the original
+func (a *API) CloneRule(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	var req cloneRuleRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Bind(c, err)
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	source, err := a.repos.Rules.Get(ctx, id)
+	if err != nil {
+		apierror.NotFound(c, "Rule not found")
+		return
+	}
+
+	clone := &models.MonitorRule{}
+	repository.SnapshotOf(source).ApplyTo(clone)
+	clone.ProjectID = source.ProjectID
+	clone.Name = source.Name + " (copy)"
+	if req.Name != "" {
+		clone.Name = req.Name
+	}
+
+	ctx = repository.WithNote(ctx, fmt.Sprintf("cloned from rule %d", source.ID))
+	if err := a.repos.Rules.Create(ctx, clone); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	// is_active has a database default of true, which Create applies to false
+	if clone.IsActive {
+		clone.IsActive = false
+		if err := a.repos.Rules.Save(ctx, clone); err != nil {
+			apierror.Database(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, clone)
+}
+
+// GetResultRevisions returns the changes scans found in the file of a result,
+// newest first, with secrets masked like in the result listings
+func (a *API) GetResultRevisions(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err := a.repos.Results.Get(ctx, id); err != nil {
+		apierror.NotFound(c, "Result not found")
+		return
+	}
+	revisions, err := a.repos.Results.Revisions(ctx, id)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	if config.AppConfig.Redaction.Enabled {
+		for i := range revisions {
+			revisions[i].PreviousSnippet = redact.Secrets(revisions[i].PreviousSnippet)
+			revisions[i].ContentSnippet = redact.Secrets(revisions[i].ContentSnippet)
+		}
+	}
+	c.JSON(http.StatusOK, revisions)
+}
@@ -1,100 +1,276 @@
 package api
 
 import (
-	"net/http"
+	"log"
 	"path/filepath"
 
+	"github-monitor/apierror"
+	"github-monitor/audit"
 	"github-monitor/auth"
+	"github-monitor/config"
+	"github-monitor/ratelimit"
+	"github-monitor/reporting"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRouter(api *API) *gin.Engine {
-	r := gin.Default()
+	r := gin.New()
+	// Without trusted proxies anyone could pick their rate limiting key with X-Forwarded-For
+	if err := r.SetTrustedProxies(config.AppConfig.Server.TrustedProxies); err != nil {
+		log.Printf("Invalid server.trusted_proxies, trusting none: %v", err)
+		r.SetTrustedProxies(nil)
+	}
+	r.Use(warnUntrustedProxies(), requestLogger(), reporting.Middleware(), limitBodies(config.AppConfig.Server), compressResponses(config.AppConfig.Server.CompressMinBytes))
+
+	// Report validation errors with JSON field names
+	apierror.RegisterJSONFieldNames()
 
 	// CORS middleware
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{"http://localhost:3000", "http://localhost:5173"}
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
-	r.Use(cors.New(config))
-
-	// Health check
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = []string{"http://localhost:3000", "http://localhost:5173"}
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", auth.ProjectHeader}
+	r.Use(cors.New(corsConfig))
+
+	// Rate limiting
+	rateLimit := config.AppConfig.Server.RateLimit
+	limit := func(perMinute int, keyFunc ratelimit.KeyFunc) gin.HandlerFunc {
+		if !rateLimit.Enabled || perMinute <= 0 {
+			return func(c *gin.Context) { c.Next() }
+		}
+		burst := rateLimit.Burst
+		if burst > perMinute {
+			burst = perMinute
+		}
+		return ratelimit.Middleware(ratelimit.NewLimiter(perMinute, burst), keyFunc)
+	}
+	loginLimit := limit(rateLimit.LoginPerMinute, ratelimit.ByIP)
+	expensiveLimit := limit(rateLimit.ExpensivePerMinute, ratelimit.BySession)
+
+	// Lists the dashboard polls answer 304 while they're unchanged
+	cached := conditional()
+
+	// Health checks, /health for liveness and /health/ready for readiness probes
+	r.GET("/health", api.Health)
+	r.GET("/health/ready", api.Ready)
+
+	// Live event stream, authenticates itself since browsers can't send headers on upgrade
+	r.GET("/api/v1/ws", api.WebSocket)
+
+	// GitHub push webhooks, authenticated by their HMAC signature
+	r.POST("/webhooks/github", limit(rateLimit.RequestsPerMinute, ratelimit.ByIP), api.GitHubWebhook)
+
+	// Pushes to the rules repository, authenticated by their HMAC signature
+	r.POST("/webhooks/rules", limit(rateLimit.RequestsPerMinute, ratelimit.ByIP), api.RulesWebhook)
+
+	// Findings of external scanners, authenticated by API key. Not audited, the
+	// bodies carry the leaked secrets.
+	r.POST("/api/v1/results/ingest", limit(rateLimit.RequestsPerMinute, ratelimit.ByIP), api.IngestResults)
+
+	// Read-only status and metrics for monitoring systems, authenticated by scrape key
+	scrape := r.Group("/api/v1/scrape")
+	scrape.Use(limit(rateLimit.RequestsPerMinute, ratelimit.ByIP), requireScrapeKey())
+	{
+		scrape.GET("/monitor/status", api.GetMonitorStatus)
+		scrape.GET("/metrics", api.Metrics)
+	}
+
+	// Triage actions from chat notifications, authenticated by their signatures
+	callbacks := r.Group("/api/v1/callbacks")
+	callbacks.Use(limit(rateLimit.RequestsPerMinute, ratelimit.ByIP))
+	{
+		callbacks.GET("/triage", api.TriageLink)
+		callbacks.POST("/triage", api.TriageLinkSubmit)
+		callbacks.POST("/slack", api.SlackInteraction)
+	}
 
 	// Public routes (no authentication required)
 	public := r.Group("/api/v1")
+	public.Use(limit(rateLimit.RequestsPerMinute, ratelimit.ByIP), audit.Middleware(api.repos))
 	{
-		public.POST("/login", api.Login)
+		public.POST("/login", loginLimit, api.Login)
+		public.POST("/auth/refresh", loginLimit, api.RefreshToken)
+		public.GET("/auth/providers", api.GetAuthProviders)
+		public.GET("/auth/oidc/login", api.OIDCLogin)
+		public.GET("/auth/oidc/callback", api.OIDCCallback)
+		public.GET("/share/:token", hidePathCredential(), api.SharedResult)
 	}
 
 	// Protected API routes (require authentication)
 	v1 := r.Group("/api/v1")
-	v1.Use(auth.AuthMiddleware())
+	v1.Use(auth.AuthMiddleware(api.repos.Sessions), limit(rateLimit.RequestsPerMinute, ratelimit.BySession), auth.ProjectMiddleware(api.repos.Projects), recordActor(), audit.Middleware(api.repos))
+	// admin guards deployment settings, the project guards use the role in the selected project
+	admin := auth.RequireAdmin()
+	projectAdmin := auth.RequireProjectRole(auth.RoleAdmin)
+	analyst := auth.RequireProjectRole(auth.RoleAdmin, auth.RoleAnalyst)
+	revealer := auth.RequireProjectRole(config.AppConfig.Redaction.RevealRoles...)
 	{
 		// Auth
 		v1.GET("/auth/status", api.GetAuthStatus)
+		v1.POST("/auth/logout", api.Logout)
+		v1.GET("/auth/sessions", admin, api.GetSessions)
+		v1.DELETE("/auth/sessions/:id", admin, api.RevokeSession)
 
-		// Dashboard
+		// Dashboard, for the selected project
 		v1.GET("/dashboard/stats", api.GetDashboardStats)
 
+		// Projects
+		projects := v1.Group("/projects")
+		{
+			projects.GET("", api.GetProjects)
+			projects.POST("", admin, api.CreateProject)
+			projects.PUT("/:id", admin, api.UpdateProject)
+			projects.DELETE("/:id", admin, api.DeleteProject)
+			projects.GET("/:id/members", api.GetProjectMembers)
+			projects.PUT("/:id/members", api.SetProjectMember)
+			projects.DELETE("/:id/members/:subject", api.RemoveProjectMember)
+		}
+
 		// Tokens
 		tokens := v1.Group("/tokens")
 		{
-			tokens.GET("", api.GetTokens)
-			tokens.POST("", api.CreateToken)
-			tokens.DELETE("/:id", api.DeleteToken)
+			tokens.GET("", admin, api.GetTokens)
+			tokens.POST("", admin, api.CreateToken)
+			tokens.POST("/import", admin, expensiveLimit, api.ImportTokens)
+			tokens.PUT("/:id", admin, api.UpdateToken)
+			tokens.DELETE("/:id", admin, api.DeleteToken)
 			tokens.GET("/stats", api.GetTokenStats)
+			tokens.GET("/forecast", api.GetTokenForecast)
 		}
 
 		// Monitor rules
 		rules := v1.Group("/rules")
 		{
 			rules.GET("", api.GetMonitorRules)
+			rules.GET("/templates", api.GetRuleTemplates)
+			rules.GET("/revisions", api.GetRuleRevisions)
+			rules.GET("/sync", api.GetRuleSyncStatus)
+			rules.POST("/sync", admin, api.SyncRules)
+			rules.POST("/from-template/:name", analyst, api.CreateRuleFromTemplate)
+			rules.POST("/preview", analyst, expensiveLimit, api.PreviewRuleQuery)
 			rules.GET("/:id", api.GetMonitorRule)
-			rules.POST("", api.CreateMonitorRule)
-			rules.PUT("/:id", api.UpdateMonitorRule)
-			rules.DELETE("/:id", api.DeleteMonitorRule)
+			rules.POST("", analyst, api.CreateMonitorRule)
+			rules.PUT("/:id", analyst, api.UpdateMonitorRule)
+			rules.DELETE("/:id", analyst, api.DeleteMonitorRule)
+			rules.POST("/:id/clone", analyst, api.CloneRule)
+			rules.GET("/:id/revisions", api.GetRuleRevisions)
+			rules.POST("/:id/revisions/:revision/rollback", analyst, api.RollbackRule)
+			rules.GET("/:id/webhooks", api.GetRuleWebhooks)
+			rules.POST("/:id/webhooks", analyst, api.CreateRuleWebhook)
+			rules.PUT("/:id/webhooks/:webhook", analyst, api.UpdateRuleWebhook)
+			rules.DELETE("/:id/webhooks/:webhook", analyst, api.DeleteRuleWebhook)
+			rules.GET("/:id/webhooks/:webhook/deliveries", api.GetWebhookDeliveries)
+			rules.POST("/:id/webhooks/:webhook/deliveries/:delivery/retry", analyst, api.RetryWebhookDelivery)
+			rules.GET("/:id/samples", api.GetRuleSamples)
+			rules.DELETE("/:id/samples", analyst, api.ClearRuleSamples)
+		}
+
+		// Company profile, expanded into generated rules of the default project
+		profile := v1.Group("/profile")
+		profile.Use(deploymentWide())
+		{
+			profile.GET("", api.GetCompanyProfile)
+			profile.PUT("", auth.RequireRole(auth.RoleAdmin, auth.RoleAnalyst), api.UpdateCompanyProfile)
 		}
 
 		// Search results
 		results := v1.Group("/results")
 		{
-			results.GET("", api.GetSearchResults)
-			results.PUT("/:id", api.UpdateSearchResult)
-			results.POST("/batch", api.BatchUpdateSearchResults)
+			results.GET("", expensiveLimit, cached, api.GetSearchResults)
+			results.GET("/by-repo", expensiveLimit, cached, api.GetResultsByRepo)
+			results.GET("/staged", cached, api.GetStagedResults)
+			results.DELETE("/staged", analyst, api.ClearStagedResults)
+			results.PUT("/:id", analyst, api.UpdateSearchResult)
+			results.POST("/batch", analyst, api.BatchUpdateSearchResults)
+			results.POST("/:id/snooze", analyst, api.SnoozeSearchResult)
+			results.DELETE("/:id/snooze", analyst, api.UnsnoozeSearchResult)
+			results.GET("/:id/evidence", revealer, api.GetResultEvidence)
+			results.GET("/:id/revisions", cached, api.GetResultRevisions)
+			results.POST("/:id/reveal", revealer, api.RevealSearchResult)
+			results.GET("/:id/shares", api.GetShareLinks)
+			results.POST("/:id/share", analyst, api.ShareSearchResult)
+			results.DELETE("/:id/shares/:link", analyst, api.RevokeShareLink)
+		}
+
+		// Incidents, related results triaged together
+		incidents := v1.Group("/incidents")
+		{
+			incidents.GET("", api.GetIncidents)
+			incidents.GET("/:id", api.GetIncident)
+			incidents.PUT("/:id", analyst, api.UpdateIncident)
+			incidents.POST("/:id/merge", analyst, api.MergeIncidents)
+			incidents.POST("/:id/split", analyst, api.SplitIncident)
+		}
+
+		// Saved result views, personal or shared with the project
+		views := v1.Group("/views")
+		{
+			views.GET("", api.GetViews)
+			views.POST("", api.CreateView)
+			views.PUT("/:id", api.UpdateView)
+			views.DELETE("/:id", api.DeleteView)
+		}
+
+		// Honeytokens, canary credentials with a rule that searches for them
+		honeytokens := v1.Group("/honeytokens")
+		{
+			honeytokens.GET("", api.GetHoneytokens)
+			honeytokens.POST("", analyst, api.CreateHoneytoken)
+			honeytokens.DELETE("/:id", analyst, api.DeleteHoneytoken)
 		}
 
 		// Whitelist
 		whitelist := v1.Group("/whitelist")
 		{
 			whitelist.GET("", api.GetWhitelist)
-			whitelist.POST("", api.CreateWhitelist)
-			whitelist.DELETE("/:id", api.DeleteWhitelist)
+			whitelist.POST("", analyst, api.CreateWhitelist)
+			whitelist.DELETE("/:id", analyst, api.DeleteWhitelist)
 		}
 
 		// Scan history
-		v1.GET("/history", api.GetScanHistory)
+		v1.GET("/history", expensiveLimit, cached, api.GetScanHistory)
+		v1.GET("/history/stats", expensiveLimit, cached, api.GetScanHistoryStats)
+		v1.GET("/history/export", expensiveLimit, api.ExportScanHistory)
+
+		// Summary reports, which cover every project
+		reports := v1.Group("/reports")
+		reports.Use(deploymentWide())
+		{
+			reports.GET("", api.GetReports)
+			reports.GET("/:id/html", api.GetReportHTML)
+			reports.GET("/:id/download", api.GetReportDownload)
+			reports.POST("/send", admin, api.SendReport)
+		}
+
+		// Runtime configuration
+		v1.GET("/config", admin, api.GetSettings)
+		v1.PUT("/config", admin, api.UpdateSettings)
+
+		// Audit log
+		v1.GET("/audit", admin, expensiveLimit, api.GetAuditLogs)
+
+		// Backup and restore
+		v1.GET("/backup", admin, expensiveLimit, api.ExportBackup)
+		v1.POST("/backup/restore", admin, expensiveLimit, api.ImportBackup)
 
 		// Monitor control
 		monitor := v1.Group("/monitor")
 		{
 			monitor.GET("/status", api.GetMonitorStatus)
-			monitor.POST("/start", api.StartMonitor)
-			monitor.POST("/stop", api.StopMonitor)
+			monitor.POST("/start", admin, api.StartMonitor)
+			monitor.POST("/stop", admin, api.StopMonitor)
 		}
 
 		// Notifications
 		notifications := v1.Group("/notifications")
 		{
-			notifications.GET("", api.GetNotifications)
-			notifications.POST("", api.CreateNotification)
-			notifications.PUT("/:id", api.UpdateNotification)
-			notifications.DELETE("/:id", api.DeleteNotification)
-			notifications.POST("/:id/test", api.TestNotification)
+			notifications.GET("", projectAdmin, api.GetNotifications)
+			notifications.POST("", projectAdmin, api.CreateNotification)
+			notifications.PUT("/:id", projectAdmin, api.UpdateNotification)
+			notifications.DELETE("/:id", projectAdmin, api.DeleteNotification)
+			notifications.POST("/:id/test", projectAdmin, api.TestNotification)
 		}
 	}
 
@@ -106,7 +282,7 @@ func SetupRouter(api *API) *gin.Engine {
 	r.NoRoute(func(c *gin.Context) {
 		// Don't serve index.html for API routes
 		if len(c.Request.URL.Path) >= 4 && c.Request.URL.Path[:4] == "/api" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "API endpoint not found"})
+			apierror.NotFound(c, "API endpoint not found")
 			return
 		}
 		c.File(filepath.Join(distPath, "index.html"))
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+	"github-monitor/db/models"
+	"github-monitor/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxListedDeliveries caps the deliveries returned for a webhook
+const maxListedDeliveries = 100
+
+type ruleWebhookRequest struct {
+	Name    *string `json:"name" binding:"omitempty,max=255"`
+	URL     *string `json:"url" binding:"omitempty,max=512"`
+	Secret  string  `json:"secret" binding:"max=255"` // generated when empty, only on creation
+	Enabled *bool   `json:"enabled"`
+}
+
+// GetRuleWebhooks returns the outbound webhooks of a rule
+func (a *API) GetRuleWebhooks(c *gin.Context) {
+	rule, ok := a.webhookRule(c)
+	if !ok {
+		return
+	}
+	hooks, err := a.repos.Webhooks.ListByRule(c.Request.Context(), rule.ID)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+// CreateRuleWebhook registers a URL that receives every new result of the rule as
+// JSON, signed with the secret. The secret is only returned here.
+func (a *API) CreateRuleWebhook(c *gin.Context) {
+	var req ruleWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	if req.URL == nil {
+		apierror.Validation(c, apierror.FieldError{Field: "url", Message: "is required"})
+		return
+	}
+	if !validWebhookURL(*req.URL) {
+		apierror.Validation(c, apierror.FieldError{Field: "url", Message: "must be an http or https URL"})
+		return
+	}
+	rule, ok := a.webhookRule(c)
+	if !ok {
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = auth.RandomString(16); err != nil {
+			apierror.Internal(c, err)
+			return
+		}
+	}
+	hook := models.RuleWebhook{
+		RuleID:    rule.ID,
+		ProjectID: rule.ProjectID,
+		URL:       *req.URL,
+		Secret:    secret,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		CreatedBy: ownerOf(c),
+	}
+	if req.Name != nil {
+		hook.Name = *req.Name
+	}
+	if err := a.repos.Webhooks.Create(c.Request.Context(), &hook); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	// enabled has a database default of true, which Create applies to false
+	if !hook.Enabled {
+		if err := a.repos.Webhooks.Save(c.Request.Context(), &hook); err != nil {
+			apierror.Database(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": hook, "secret": secret})
+}
+
+// UpdateRuleWebhook changes the name, URL or enabled state of a webhook. Disabling
+// it gives up its pending deliveries.
+func (a *API) UpdateRuleWebhook(c *gin.Context) {
+	var req ruleWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	if req.URL != nil && !validWebhookURL(*req.URL) {
+		apierror.Validation(c, apierror.FieldError{Field: "url", Message: "must be an http or https URL"})
+		return
+	}
+	hook, ok := a.ruleWebhook(c)
+	if !ok {
+		return
+	}
+
+	if req.Name != nil {
+		hook.Name = *req.Name
+	}
+	if req.URL != nil {
+		hook.URL = *req.URL
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	if err := a.repos.Webhooks.Save(c.Request.Context(), hook); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, hook)
+}
+
+// DeleteRuleWebhook deletes a webhook together with its deliveries
+func (a *API) DeleteRuleWebhook(c *gin.Context) {
+	hook, ok := a.ruleWebhook(c)
+	if !ok {
+		return
+	}
+	if err := a.repos.Webhooks.Delete(c.Request.Context(), hook.ID); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook with their
+// attempts and last response
+func (a *API) GetWebhookDeliveries(c *gin.Context) {
+	hook, ok := a.ruleWebhook(c)
+	if !ok {
+		return
+	}
+	deliveries, err := a.repos.Webhooks.ListDeliveries(c.Request.Context(), hook.ID, maxListedDeliveries)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// RetryWebhookDelivery queues a failed delivery again with a fresh set of attempts
+func (a *API) RetryWebhookDelivery(c *gin.Context) {
+	hook, ok := a.ruleWebhook(c)
+	if !ok {
+		return
+	}
+	deliveryID, ok := uintValue(c, "delivery", c.Param("delivery"))
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	delivery, err := a.repos.Webhooks.GetDelivery(ctx, deliveryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && delivery.WebhookID != hook.ID {
+		apierror.NotFound(c, "Delivery not found")
+		return
+	}
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	if delivery.Status != webhooks.StatusFailed {
+		apierror.Conflict(c, "Only failed deliveries can be retried")
+		return
+	}
+
+	delivery.Status = webhooks.StatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := a.repos.Webhooks.SaveDelivery(ctx, delivery); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// webhookRule returns the rule of the :id path parameter, responding with 404 when
+// the caller can't see it
+func (a *API) webhookRule(c *gin.Context) (*models.MonitorRule, bool) {
+	id, ok := idParam(c)
+	if !ok {
+		return nil, false
+	}
+	rule, err := a.repos.Rules.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Rule not found")
+		return nil, false
+	}
+	return rule, true
+}
+
+// ruleWebhook returns the :webhook of the rule of the :id path parameter
+func (a *API) ruleWebhook(c *gin.Context) (*models.RuleWebhook, bool) {
+	id, ok := idParam(c)
+	if !ok {
+		return nil, false
+	}
+	hookID, ok := uintValue(c, "webhook", c.Param("webhook"))
+	if !ok {
+		return nil, false
+	}
+
+	hook, err := a.repos.Webhooks.Get(c.Request.Context(), hookID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && hook.RuleID != id {
+		apierror.NotFound(c, "Webhook not found")
+		return nil, false
+	}
+	if err != nil {
+		apierror.Database(c, err)
+		return nil, false
+	}
+	return hook, true
+}
+
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+	"github-monitor/reporting"
+	"github-monitor/rulesync"
+
+	"github.com/gin-gonic/gin"
+	gogithub "github.com/google/go-github/v57/github"
+)
+
+// ruleSyncTimeout bounds a sync started through the API or a webhook
+const ruleSyncTimeout = 5 * time.Minute
+
+// SetRuleSync enables the rules repository endpoints
+func (a *API) SetRuleSync(syncer *rulesync.Syncer) {
+	a.ruleSync = syncer
+}
+
+// GetRuleSyncStatus returns the outcome of the last sync with the rules repository
+func (a *API) GetRuleSyncStatus(c *gin.Context) {
+	if a.ruleSync == nil {
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeUnavailable, "Rule sync is not configured")
+		return
+	}
+
+	c.JSON(http.StatusOK, a.ruleSync.Status())
+}
+
+// SyncRules pulls the rules repository and applies it right away
+func (a *API) SyncRules(c *gin.Context) {
+	if a.ruleSync == nil {
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeUnavailable, "Rule sync is not configured")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ruleSyncTimeout)
+	defer cancel()
+	status, err := a.ruleSync.Sync(ctx)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"code": apierror.CodeSyncFailed, "error": err.Error(), "status": status})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// RulesWebhook syncs the rules repository when it receives a push. Requests are
+// authenticated by their HMAC signature, as sent by GitHub and Gitea.
+func (a *API) RulesWebhook(c *gin.Context) {
+	secret := config.AppConfig.RuleSync.WebhookSecret
+	if a.ruleSync == nil || secret == "" {
+		apierror.NotFound(c, "Rules webhook is not configured")
+		return
+	}
+
+	if _, err := gogithub.ValidatePayload(c.Request, []byte(secret)); err != nil {
+		apierror.Unauthorized(c, "Invalid webhook signature")
+		return
+	}
+
+	// Deliveries time out long before a clone finishes, so the sync runs in the background
+	go func() {
+		tags := reporting.Tags{"component": "rulesync"}
+		defer reporting.Recover(tags)
+
+		ctx, cancel := context.WithTimeout(context.Background(), ruleSyncTimeout)
+		defer cancel()
+		if _, err := a.ruleSync.Sync(ctx); err != nil {
+			log.Printf("Rule sync from webhook failed: %v", err)
+			reporting.CaptureError(err, tags)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Rule sync queued"})
+}
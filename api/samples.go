@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRuleSamples returns what the scans of a sample_only rule found, newest
+// first, with the totals over every sample kept
+func (a *API) GetRuleSamples(c *gin.Context) {
+	page, pageSize, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+	rule, ok := a.webhookRule(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	samples, total, err := a.repos.Samples.List(ctx, rule.ID, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	summary, err := a.repos.Samples.Summary(ctx, rule.ID)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(gin.H{"samples": samples, "summary": summary}, page, pageSize, total))
+}
+
+// ClearRuleSamples deletes the samples of a rule, to measure it again after its
+// keywords changed
+func (a *API) ClearRuleSamples(c *gin.Context) {
+	rule, ok := a.webhookRule(c)
+	if !ok {
+		return
+	}
+	deleted, err := a.repos.Samples.Clear(c.Request.Context(), rule.ID)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Samples deleted successfully", "deleted": deleted})
+}
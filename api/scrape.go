@@ -0,0 +1,117 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireScrapeKey lets monitoring systems in with one of the scrape.keys. The
+// keys only reach the scrape endpoints, so a scraper can't change anything.
+func requireScrapeKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := config.AppConfig.Scrape.Keys
+		if len(keys) == 0 {
+			apierror.NotFound(c, "Scraping is not enabled")
+			return
+		}
+
+		key := requestKey(c)
+		scraper := ""
+		for _, configured := range keys {
+			// Every key is compared so the time taken doesn't tell which one came close
+			if subtle.ConstantTimeCompare([]byte(key), []byte(configured.Key)) == 1 {
+				scraper = configured.Name
+			}
+		}
+		if key == "" || scraper == "" {
+			apierror.Unauthorized(c, "Invalid API key")
+			return
+		}
+		c.Next()
+		log.Printf("Scraped %s for %s, status %d", c.Request.URL.Path, scraper, c.Writer.Status())
+	}
+}
+
+// Metrics returns gauges of the monitor, the tokens and the open results of every
+// project in the Prometheus text format
+func (a *API) Metrics(c *gin.Context) {
+	ctx := c.Request.Context()
+	var b strings.Builder
+
+	heartbeat := a.monitorService.LastHeartbeat()
+	scan := a.monitorService.ScanStatus()
+	writeGauge(&b, "github_monitor_running", "Whether the monitoring loop is running", map[string]float64{"": boolGauge(a.monitorService.IsRunning())})
+	writeGauge(&b, "github_monitor_stalled", "Whether the monitoring loop stopped reporting heartbeats", map[string]float64{"": boolGauge(a.monitorService.Stalled())})
+	writeGauge(&b, "github_monitor_dry_run", "Whether new results are staged instead of recorded", map[string]float64{"": boolGauge(a.monitorService.DryRun())})
+	if !heartbeat.IsZero() {
+		writeGauge(&b, "github_monitor_last_heartbeat_seconds", "Unix time of the last heartbeat of the monitoring loop", map[string]float64{"": float64(heartbeat.Unix())})
+	}
+	writeGauge(&b, "github_monitor_scanning_rules", "Rules being scanned", map[string]float64{"": float64(len(scan.Scanning))})
+	writeGauge(&b, "github_monitor_queued_rules", "Rules waiting for a free worker", map[string]float64{"": float64(scan.Queued)})
+
+	if active, err := a.repos.Rules.Count(ctx, true); err == nil {
+		writeGauge(&b, "github_monitor_active_rules", "Active monitor rules", map[string]float64{"": float64(active)})
+	}
+	if active, err := a.repos.Tokens.Count(ctx, true); err == nil {
+		writeGauge(&b, "github_monitor_active_tokens", "Active GitHub tokens", map[string]float64{"": float64(active)})
+	}
+
+	now := time.Now()
+	local := now.In(config.Location())
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	if open, err := a.repos.Results.OpenStats(ctx, today, today.AddDate(0, 0, -6), 0); err == nil {
+		bySeverity := make(map[string]float64, len(open.BySeverity))
+		for severity, count := range open.BySeverity {
+			bySeverity[fmt.Sprintf("severity=%q", severity)] = float64(count)
+		}
+		writeGauge(&b, "github_monitor_open_results", "Pending and confirmed results", bySeverity)
+	}
+	if incidents, err := a.repos.Incidents.CountOpen(ctx); err == nil {
+		writeGauge(&b, "github_monitor_open_incidents", "Pending and confirmed incidents", map[string]float64{"": float64(incidents)})
+	}
+	if a.sla != nil {
+		if counts, err := a.sla.Count(ctx, a.repos.Results, now); err == nil {
+			writeGauge(&b, "github_monitor_sla_results", "Open results at risk of missing their SLA and overdue ones", map[string]float64{
+				`state="at_risk"`: float64(counts.AtRisk),
+				`state="overdue"`: float64(counts.Overdue),
+			})
+		}
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// writeGauge writes a gauge with its samples by label set, "" for none
+func writeGauge(b *strings.Builder, name, help string, samples map[string]float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	labels := make([]string, 0, len(samples))
+	for label := range samples {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		if label == "" {
+			fmt.Fprintf(b, "%s %s\n", name, strconv.FormatFloat(samples[label], 'f', -1, 64))
+		} else {
+			fmt.Fprintf(b, "%s{%s} %s\n", name, label, strconv.FormatFloat(samples[label], 'f', -1, 64))
+		}
+	}
+}
+
+func boolGauge(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshToken exchanges a refresh token for a new access and refresh token
+func (a *API) RefreshToken(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.BadRequest(c, "Refresh token is required")
+		return
+	}
+
+	tokens, err := auth.RefreshSession(c.Request.Context(), a.repos.Sessions, input.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			apierror.Unauthorized(c, err.Error())
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to refresh token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.ExpiresAt,
+	})
+}
+
+// Logout revokes the current session
+func (a *API) Logout(c *gin.Context) {
+	claims := auth.GetClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+		return
+	}
+
+	if err := auth.RevokeSession(c.Request.Context(), a.repos.Sessions, claims.ID); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to revoke session")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// GetSessions returns login sessions, active ones only unless all=true
+func (a *API) GetSessions(c *gin.Context) {
+	all, _ := strconv.ParseBool(c.DefaultQuery("all", "false"))
+
+	sessions, err := a.repos.Sessions.List(c.Request.Context(), !all)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession revokes any session by its session ID
+func (a *API) RevokeSession(c *gin.Context) {
+	sessionID := c.Param("id")
+
+	if _, err := a.repos.Sessions.GetBySessionID(c.Request.Context(), sessionID); err != nil {
+		apierror.NotFound(c, "Session not found")
+		return
+	}
+
+	if err := auth.RevokeSession(c.Request.Context(), a.repos.Sessions, sessionID); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
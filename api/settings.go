@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+	"github-monitor/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSettings returns the runtime configuration
+func (a *API) GetSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, settings.Current().Masked())
+}
+
+// UpdateSettings changes runtime configuration values and applies them without a restart
+func (a *API) UpdateSettings(c *gin.Context) {
+	var changes map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&changes); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	// The masked password from GET must not overwrite the real one
+	if value, ok := changes["proxy_password"]; ok {
+		var password string
+		if json.Unmarshal(value, &password) == nil && password == "******" {
+			delete(changes, "proxy_password")
+		}
+	}
+
+	if len(changes) == 0 {
+		c.JSON(http.StatusOK, settings.Current().Masked())
+		return
+	}
+
+	actor := ""
+	if claims := auth.GetClaims(c); claims != nil {
+		actor = claims.Subject
+	}
+
+	updated, err := settings.Update(changes, actor)
+	if errors.Is(err, settings.ErrInvalid) {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated.Masked())
+}
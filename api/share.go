@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/redact"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultShareExpiry = 72 * time.Hour
+	maxShareExpiry     = 30 * 24 * time.Hour
+)
+
+// sharePage shows a shared result read-only. It carries no triage actions, the
+// person it was shared with has no account.
+var sharePage = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>GitHub Monitor</title>
+<style>body{font-family:sans-serif;max-width:48em;margin:3em auto;padding:0 1em}pre{background:#f4f4f4;padding:1em;overflow-x:auto}th{text-align:left;padding-right:1em}</style>
+</head><body>
+{{if .Error}}<p>{{.Error}}</p>
+{{else}}<h2>Result #{{.Result.ID}}</h2>
+<table>
+<tr><th>File</th><td><a href="{{.Result.HTMLURL}}" rel="noreferrer">{{.Result.RepoFullName}}/{{.Result.FilePath}}</a></td></tr>
+<tr><th>Severity</th><td>{{.Result.Severity}}</td></tr>
+<tr><th>Status</th><td>{{.Result.Status}}</td></tr>
+<tr><th>Found</th><td>{{.Result.CreatedAt.UTC.Format "2006-01-02 15:04 UTC"}}</td></tr>
+</table>
+{{if .Result.ContentSnippet}}<pre>{{.Result.ContentSnippet}}</pre>{{end}}
+<p><small>Shared read-only until {{.Link.ExpiresAt.UTC.Format "2006-01-02 15:04 UTC"}}.</small></p>{{end}}
+</body></html>`))
+
+type shareView struct {
+	Link   *models.ShareLink
+	Result *models.SearchResult
+	Error  string
+}
+
+// ShareSearchResult creates a link that shows a result read-only without a login,
+// for looping in the repository owner or a partner. The token is only returned
+// here.
+func (a *API) ShareSearchResult(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		ExpiresIn string `json:"expires_in"` // duration such as 72h
+		Note      string `json:"note" binding:"max=255"`
+	}
+	// The body is optional, the defaults apply without one
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		apierror.Bind(c, err)
+		return
+	}
+	expiry := defaultShareExpiry
+	if req.ExpiresIn != "" {
+		var err error
+		if expiry, err = time.ParseDuration(req.ExpiresIn); err != nil || expiry <= 0 || expiry > maxShareExpiry {
+			apierror.Validation(c, apierror.FieldError{Field: "expires_in", Message: "must be a duration between 1s and 720h"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	result, err := a.repos.Results.Get(ctx, id)
+	if err != nil {
+		apierror.NotFound(c, "Result not found")
+		return
+	}
+
+	token, err := auth.RandomString(12)
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	link := models.ShareLink{
+		TokenHash: hashShareToken(token),
+		ResultID:  result.ID,
+		ProjectID: result.ProjectID,
+		CreatedBy: ownerOf(c),
+		Note:      req.Note,
+		ExpiresAt: time.Now().Add(expiry),
+	}
+	if err := a.repos.ShareLinks.Create(ctx, &link); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"link":  link,
+		"token": token,
+		"url":   publicBaseURL(c) + "/api/v1/share/" + token,
+	})
+}
+
+// GetShareLinks lists the share links of a result, revoked and expired ones included
+func (a *API) GetShareLinks(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	links, err := a.repos.ShareLinks.ListByResult(c.Request.Context(), id)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, links)
+}
+
+// RevokeShareLink stops a share link from working before it expires
+func (a *API) RevokeShareLink(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	linkID, ok := uintValue(c, "link", c.Param("link"))
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	link, err := a.repos.ShareLinks.Get(ctx, linkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && link.ResultID != id {
+		apierror.NotFound(c, "Share link not found")
+		return
+	}
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	if link.RevokedAt == nil {
+		now := time.Now()
+		link.RevokedAt = &now
+		if err := a.repos.ShareLinks.Save(ctx, link); err != nil {
+			apierror.Database(c, err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// SharedResult shows the result of a share link. Secrets are masked as in the
+// result listings.
+func (a *API) SharedResult(c *gin.Context) {
+	ctx := c.Request.Context()
+	link, err := a.repos.ShareLinks.GetByTokenHash(ctx, hashShareToken(c.Param("token")))
+	if err != nil || link.RevokedAt != nil || time.Now().After(link.ExpiresAt) {
+		a.renderShare(c, http.StatusNotFound, &shareView{Error: "This link is not valid or has expired."})
+		return
+	}
+	result, err := a.repos.Results.Get(ctx, link.ResultID)
+	if err != nil {
+		a.renderShare(c, http.StatusNotFound, &shareView{Error: "The result no longer exists."})
+		return
+	}
+
+	now := time.Now()
+	link.Views++
+	link.LastViewedAt = &now
+	if err := a.repos.ShareLinks.Save(ctx, link); err != nil {
+		log.Printf("Failed to count the view of share link %d: %v", link.ID, err)
+	}
+
+	if config.AppConfig.Redaction.Enabled {
+		result.ContentSnippet = redact.Secrets(result.ContentSnippet)
+	}
+	a.renderShare(c, http.StatusOK, &shareView{Link: link, Result: result})
+}
+
+func (a *API) renderShare(c *gin.Context, status int, view *shareView) {
+	var page bytes.Buffer
+	if err := sharePage.Execute(&page, view); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
+
+// hashShareToken hashes share tokens so a database leak doesn't leak usable links
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// publicBaseURL is the URL links to this server are built on, the configured one of
+// the notification actions or else the one the request came in on
+func publicBaseURL(c *gin.Context) string {
+	if baseURL := config.AppConfig.Notify.Actions.BaseURL; baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
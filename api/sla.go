@@ -0,0 +1,19 @@
+package api
+
+import (
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/sla"
+)
+
+// SetSLA enables the SLA state of results and the SLA counts of the dashboard
+func (a *API) SetSLA(policy *sla.Policy) {
+	a.sla = policy
+}
+
+func (a *API) annotateSLA(results []models.SearchResult) {
+	if a.sla != nil {
+		a.sla.Annotate(results, time.Now())
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSnooze caps how far ahead a result can be snoozed
+const maxSnooze = 365 * 24 * time.Hour
+
+// SnoozeSearchResult hides a result from the pending queue until the given time,
+// when the monitor resurfaces it with a reminder notification
+func (a *API) SnoozeSearchResult(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Until time.Time `json:"until" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	if !input.Until.After(time.Now()) {
+		apierror.Validation(c, apierror.FieldError{Field: "until", Message: "must be in the future"})
+		return
+	}
+	if input.Until.After(time.Now().Add(maxSnooze)) {
+		apierror.Validation(c, apierror.FieldError{Field: "until", Message: "must be within a year"})
+		return
+	}
+
+	a.setSnooze(c, id, &input.Until)
+}
+
+// UnsnoozeSearchResult ends the snooze of a result right away, without a reminder
+func (a *API) UnsnoozeSearchResult(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	a.setSnooze(c, id, nil)
+}
+
+func (a *API) setSnooze(c *gin.Context, id uint, until *time.Time) {
+	result, err := a.repos.Results.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Result not found")
+		return
+	}
+
+	result.SnoozedUntil = until
+	if err := a.repos.Results.Save(c.Request.Context(), result); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	events.PublishTo(result.ProjectID, events.TypeResultSnoozed, result)
+
+	c.JSON(http.StatusOK, result)
+}
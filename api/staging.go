@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+	"github-monitor/redact"
+	"github-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStagedResults returns the results dry run scans staged with pagination,
+// newest first
+func (a *API) GetStagedResults(c *gin.Context) {
+	page, pageSize, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+	ruleID, ok := uintQuery(c, "rule_id")
+	if !ok {
+		return
+	}
+
+	results, total, err := a.repos.Staging.List(c.Request.Context(), ruleID, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	if config.AppConfig.Redaction.Enabled {
+		for i := range results {
+			results[i].ContentSnippet = redact.Secrets(results[i].ContentSnippet)
+		}
+	}
+
+	c.JSON(http.StatusOK, paginated(gin.H{
+		"results": results,
+		"dry_run": a.monitorService.DryRun(),
+	}, page, pageSize, total))
+}
+
+// ClearStagedResults removes the staged results, before another dry run or once
+// the monitor went live
+func (a *API) ClearStagedResults(c *gin.Context) {
+	cleared, err := a.repos.Staging.Clear(c.Request.Context())
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Staged results cleared", "cleared": cleared})
+}
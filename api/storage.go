@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+	"github-monitor/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetStore enables downloads of evidence and reports kept in object storage
+func (a *API) SetStore(store storage.Store) {
+	a.store = store
+}
+
+// GetResultEvidence returns a signed download URL for the evidence of a result. The
+// evidence holds the file unmasked, so the route is limited to the reveal roles.
+func (a *API) GetResultEvidence(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	result, err := a.repos.Results.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Result not found")
+		return
+	}
+	if result.EvidenceKey == "" {
+		apierror.NotFound(c, "No evidence was stored for this result")
+		return
+	}
+
+	a.signedURL(c, result.EvidenceKey)
+}
+
+// GetReportDownload returns a signed download URL for the stored copy of a report
+func (a *API) GetReportDownload(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	rep, err := a.repos.Reports.Get(c.Request.Context(), id)
+	if err != nil {
+		apierror.NotFound(c, "Report not found")
+		return
+	}
+	if rep.StorageKey == "" {
+		apierror.NotFound(c, "Report is not in object storage, use /html instead")
+		return
+	}
+
+	a.signedURL(c, rep.StorageKey)
+}
+
+func (a *API) signedURL(c *gin.Context, key string) {
+	if a.store == nil {
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeUnavailable, "Object storage is not configured")
+		return
+	}
+
+	expiry, err := time.ParseDuration(config.AppConfig.Storage.SignedURLExpiry)
+	if err != nil || expiry <= 0 {
+		expiry = 15 * time.Minute
+	}
+
+	url, err := a.store.SignedURL(c.Request.Context(), key, expiry)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadGateway, apierror.CodeUnavailable, "Failed to sign the download URL")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        url,
+		"expires_at": time.Now().Add(expiry),
+	})
+}
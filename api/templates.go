@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github-monitor/apierror"
+	"github-monitor/db/models"
+	"github-monitor/ruletemplate"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRuleTemplates lists the built-in rule templates
+func (a *API) GetRuleTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, ruletemplate.List())
+}
+
+type fromTemplateRequest struct {
+	Name      string            `json:"name"`     // replaces the rule name derived from the template
+	Severity  string            `json:"severity"` // replaces the template's severity
+	Variables map[string]string `json:"variables"`
+}
+
+// CreateRuleFromTemplate creates an active rule from a template with the given variables
+func (a *API) CreateRuleFromTemplate(c *gin.Context) {
+	template, ok := ruletemplate.Get(c.Param("name"))
+	if !ok {
+		apierror.NotFound(c, "Template not found")
+		return
+	}
+
+	var req fromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	rule, err := template.Instantiate(req.Variables)
+	var varErr *ruletemplate.VariableError
+	if errors.As(err, &varErr) {
+		apierror.Validation(c, apierror.FieldError{Field: "variables." + varErr.Name, Message: varErr.Message})
+		return
+	}
+
+	if req.Name != "" {
+		rule.Name = req.Name
+	}
+	if req.Severity != "" {
+		if !models.ValidSeverities[req.Severity] {
+			apierror.Validation(c, apierror.FieldError{Field: "severity", Message: "must be one of: critical high medium low info"})
+			return
+		}
+		rule.Severity = req.Severity
+	}
+
+	rule.ProjectID = projectOf(c)
+	rule.Owner = ownerOf(c)
+
+	if err := a.repos.Rules.Create(c.Request.Context(), rule); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/db/models"
+	"github-monitor/github"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxTokenImport caps the tokens of one import
+	maxTokenImport = 100
+	// tokenCheckWorkers is how many imported tokens are checked against GitHub at once
+	tokenCheckWorkers = 5
+	// tokenCheckTimeout bounds the check of one token
+	tokenCheckTimeout = 10 * time.Second
+)
+
+// Statuses of imported tokens
+const (
+	tokenValid     = "valid"
+	tokenInvalid   = "invalid"
+	tokenDuplicate = "duplicate"
+)
+
+type tokenImportResult struct {
+	Token         string `json:"token"` // masked
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"` // why GitHub rejected an invalid token
+	ID            uint   `json:"id,omitempty"`    // of the stored token
+	RateRemaining int    `json:"rate_remaining,omitempty"`
+}
+
+// ImportTokens stores many GitHub tokens at once, sent as a JSON array of strings
+// or as plain text with one token per line. Each one is checked against GitHub,
+// the valid ones are stored and join the pool right away. The optional ?name=
+// labels them.
+func (a *API) ImportTokens(c *gin.Context) {
+	var tokens []string
+	if c.ContentType() == "application/json" {
+		if err := c.ShouldBindJSON(&tokens); err != nil {
+			apierror.Bind(c, err)
+			return
+		}
+	} else {
+		body, err := c.GetRawData()
+		if err != nil {
+			apierror.Bind(c, err)
+			return
+		}
+		scanner := bufio.NewScanner(strings.NewReader(string(body)))
+		for scanner.Scan() {
+			tokens = append(tokens, scanner.Text())
+		}
+	}
+
+	// Blank lines and comments are left out
+	candidates := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if token = strings.TrimSpace(token); token != "" && !strings.HasPrefix(token, "#") {
+			candidates = append(candidates, token)
+		}
+	}
+	if len(candidates) == 0 {
+		apierror.BadRequest(c, "No tokens provided")
+		return
+	}
+	if len(candidates) > maxTokenImport {
+		apierror.Validation(c, apierror.FieldError{Field: "tokens", Message: fmt.Sprintf("at most %d tokens per import", maxTokenImport)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	stored, err := a.repos.Tokens.List(ctx)
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+	known := make(map[string]bool, len(stored)+len(candidates))
+	for _, token := range stored {
+		known[token.Token] = true
+	}
+
+	results := make([]tokenImportResult, len(candidates))
+	records := make([]models.GitHubToken, len(candidates))
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, tokenCheckWorkers)
+	for i, token := range candidates {
+		results[i] = tokenImportResult{Token: github.MaskToken(token)}
+		if known[token] {
+			results[i].Status = tokenDuplicate
+			continue
+		}
+		known[token] = true
+
+		wg.Add(1)
+		go func(i int, token string) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			checkCtx, cancel := context.WithTimeout(ctx, tokenCheckTimeout)
+			defer cancel()
+			rate, err := a.tokenPool.CheckToken(checkCtx, token)
+			if err != nil {
+				results[i].Status = tokenInvalid
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Status = tokenValid
+			results[i].RateRemaining = rate.Remaining
+			records[i] = models.GitHubToken{
+				Token:         token,
+				RateLimit:     rate.Limit,
+				RateRemaining: rate.Remaining,
+				RateReset:     rate.Reset.Time,
+				IsActive:      true,
+			}
+		}(i, token)
+	}
+	wg.Wait()
+
+	var valid []string
+	counts := map[string]int{tokenValid: 0, tokenInvalid: 0, tokenDuplicate: 0}
+	for i, token := range candidates {
+		counts[results[i].Status]++
+		if results[i].Status != tokenValid {
+			continue
+		}
+		record := records[i]
+		record.Name = c.Query("name")
+		if err := a.repos.Tokens.Create(ctx, &record); err != nil {
+			// The tokens stored so far are used already
+			a.tokenPool.AddTokens(valid)
+			apierror.Database(c, err)
+			return
+		}
+		results[i].ID = record.ID
+		valid = append(valid, token)
+	}
+	a.tokenPool.AddTokens(valid)
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":   results,
+		"valid":     counts[tokenValid],
+		"invalid":   counts[tokenInvalid],
+		"duplicate": counts[tokenDuplicate],
+	})
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/events"
+	"github-monitor/notify"
+	"github-monitor/reporting"
+	"github-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// triagePage is shown for action links opened from chat notifications. Opening a
+// link only shows the form, so link previews can't trigger the action.
+var triagePage = template.Must(template.New("triage").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>GitHub Monitor</title>
+<style>body{font-family:sans-serif;max-width:32em;margin:3em auto;padding:0 1em}button{font-size:1em;padding:.5em 1.5em}</style>
+</head><body>
+{{if .Error}}<p>{{.Error}}</p>
+{{else if .Done}}<p>{{.Done}}</p>
+{{else}}<p>{{.Label}} result #{{.ID}}?</p>
+<p><a href="{{.Result.HTMLURL}}">{{.Result.RepoFullName}}/{{.Result.FilePath}}</a></p>
+<form method="post">
+<input type="hidden" name="result" value="{{.ID}}">
+<input type="hidden" name="action" value="{{.Action}}">
+<input type="hidden" name="expires" value="{{.Expires}}">
+<input type="hidden" name="sig" value="{{.Sig}}">
+<button type="submit">{{.Label}}</button>
+</form>{{end}}
+</body></html>`))
+
+type triageView struct {
+	ID      uint
+	Action  string
+	Label   string
+	Expires int64
+	Sig     string
+	Result  *models.SearchResult
+	Done    string
+	Error   string
+}
+
+// TriageLink shows the confirmation form of a signed action link
+func (a *API) TriageLink(c *gin.Context) {
+	view, ok := a.verifyTriageLink(c, c.Query)
+	if !ok {
+		return
+	}
+	a.renderTriage(c, http.StatusOK, view)
+}
+
+// TriageLinkSubmit performs the action of a signed action link
+func (a *API) TriageLinkSubmit(c *gin.Context) {
+	view, ok := a.verifyTriageLink(c, c.PostForm)
+	if !ok {
+		return
+	}
+
+	done, err := a.triage(c.Request.Context(), view.ID, view.Action, "chat-link", c.ClientIP())
+	if err != nil {
+		view.Error = err.Error()
+		a.renderTriage(c, http.StatusInternalServerError, view)
+		return
+	}
+	view.Done = done
+	a.renderTriage(c, http.StatusOK, view)
+}
+
+func (a *API) verifyTriageLink(c *gin.Context, param func(string) string) (*triageView, bool) {
+	id, _ := strconv.ParseUint(param("result"), 10, 64)
+	expires, _ := strconv.ParseInt(param("expires"), 10, 64)
+	view := &triageView{
+		ID:      uint(id),
+		Action:  param("action"),
+		Label:   actionLabel(param("action")),
+		Expires: expires,
+		Sig:     param("sig"),
+	}
+
+	if err := notify.VerifyAction(view.ID, view.Action, view.Expires, view.Sig); err != nil {
+		a.renderTriage(c, http.StatusForbidden, &triageView{Error: "This action link is not valid: " + err.Error()})
+		return nil, false
+	}
+
+	result, err := a.repos.Results.Get(c.Request.Context(), view.ID)
+	if err != nil {
+		a.renderTriage(c, http.StatusNotFound, &triageView{Error: "The result no longer exists."})
+		return nil, false
+	}
+	view.Result = result
+	return view, true
+}
+
+func (a *API) renderTriage(c *gin.Context, status int, view *triageView) {
+	var page bytes.Buffer
+	if err := triagePage.Execute(&page, view); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
+
+// slackInteraction is the part of a Slack block_actions payload we use
+type slackInteraction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	ResponseURL string `json:"response_url"`
+}
+
+// SlackInteraction performs triage actions for buttons clicked in Slack. Requests
+// are verified with the app's signing secret.
+func (a *API) SlackInteraction(c *gin.Context) {
+	secret := config.AppConfig.Notify.Actions.SlackSigningSecret
+	if secret == "" {
+		apierror.NotFound(c, "Slack interactivity is not configured")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil || !verifySlackSignature(secret, c.GetHeader("X-Slack-Request-Timestamp"), c.GetHeader("X-Slack-Signature"), body) {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	var interaction slackInteraction
+	if err := json.Unmarshal([]byte(form.Get("payload")), &interaction); err != nil || interaction.Type != "block_actions" {
+		c.Status(http.StatusOK) // other interaction types are not used
+		return
+	}
+
+	// Slack expects an answer within 3 seconds, the outcome is posted to the thread
+	actor, ip := "slack:"+interaction.User.Username, c.ClientIP()
+	for _, action := range interaction.Actions {
+		name, idText, ok := strings.Cut(action.Value, ":")
+		id, err := strconv.ParseUint(idText, 10, 64)
+		if !ok || err != nil {
+			continue
+		}
+		go func() {
+			defer reporting.Recover(reporting.Tags{"component": "slack", "result_id": idText})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			text, err := a.triage(ctx, uint(id), name, actor, ip)
+			if err != nil {
+				text = fmt.Sprintf("Result #%d: %v", id, err)
+			} else {
+				text = fmt.Sprintf("<@%s>: %s", interaction.User.ID, text)
+			}
+			respondToSlack(ctx, interaction.ResponseURL, text)
+		}()
+	}
+	c.Status(http.StatusOK)
+}
+
+// triage applies an action to a result and returns a description of what was done
+func (a *API) triage(ctx context.Context, id uint, action, actor, ip string) (string, error) {
+	result, err := a.repos.Results.Get(ctx, id)
+	if err != nil {
+		return "", errors.New("result not found")
+	}
+	before := result.Status
+
+	var status, done string
+	switch action {
+	case notify.ActionConfirm:
+		status, done = "confirmed", fmt.Sprintf("Result #%d confirmed", id)
+	case notify.ActionFalsePositive:
+		status, done = "false_positive", fmt.Sprintf("Result #%d marked as false positive", id)
+	case notify.ActionWhitelist:
+		if err := a.whitelistRepo(ctx, result.ProjectID, result.RepoFullName, actor); err != nil {
+			return "", err
+		}
+		status, done = "false_positive", fmt.Sprintf("%s whitelisted, result #%d marked as false positive", result.RepoFullName, id)
+	default:
+		return "", fmt.Errorf("unknown action %q", action)
+	}
+
+	if _, err := a.repos.Results.UpdateStatus(ctx, []uint{id}, status); err != nil {
+		return "", err
+	}
+	events.PublishTo(result.ProjectID, events.TypeResultStatus, events.StatusChange{IDs: []uint{id}, Status: status})
+
+	// Chat actions bypass the authenticated API, so they are audited here
+	entry := models.AuditLog{
+		Actor:      actor,
+		Method:     http.MethodPost,
+		Path:       "/api/v1/callbacks/" + action,
+		Resource:   "results",
+		ResourceID: strconv.FormatUint(uint64(id), 10),
+		StatusCode: http.StatusOK,
+		Before:     fmt.Sprintf(`{"status":%q}`, before),
+		After:      fmt.Sprintf(`{"status":%q}`, status),
+		IPAddress:  ip,
+		CreatedAt:  time.Now(),
+	}
+	if err := a.repos.Audit.Create(ctx, &entry); err != nil {
+		log.Printf("Failed to write audit log: %v", err)
+	}
+
+	log.Printf("%s by %s", done, actor)
+	return done, nil
+}
+
+// whitelistRepo adds a repository to the whitelist of a project unless it is already there
+func (a *API) whitelistRepo(ctx context.Context, projectID uint, repo, actor string) error {
+	ctx = repository.WithProjects(ctx, []uint{projectID})
+	entries, err := a.repos.Whitelist.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Value == repo {
+			return nil
+		}
+	}
+	return a.repos.Whitelist.Create(ctx, &models.Whitelist{
+		Type:        "repo",
+		Value:       repo,
+		Description: "Whitelisted from a notification by " + actor,
+		ProjectID:   projectID,
+	})
+}
+
+func actionLabel(action string) string {
+	for _, a := range notify.Actions {
+		if a.Name == action {
+			return a.Label
+		}
+	}
+	return action
+}
+
+// verifySlackSignature checks the v0 request signature and rejects requests older
+// than 5 minutes to prevent replays
+func verifySlackSignature(secret, timestamp, signature string, body []byte) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > 5*time.Minute || age < -5*time.Minute {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func respondToSlack(ctx context.Context, responseURL, text string) {
+	if !strings.HasPrefix(responseURL, "https://hooks.slack.com/") {
+		return
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"response_type":    "in_channel",
+		"replace_original": false,
+		"text":             text,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to answer Slack interaction: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+	"github-monitor/db/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// viewFilters are the results query parameters a view can save
+var viewFilters = map[string]bool{
+	"rule_id":     true,
+	"incident_id": true,
+	"status":      true,
+	"severity":    true,
+	"source":      true,
+	"verdict":     true,
+	"file_type":   true,
+	"snoozed":     true,
+	"sort":        true,
+}
+
+type viewRequest struct {
+	Name    string `json:"name" binding:"required,max=255"`
+	Shared  bool   `json:"shared"`
+	Filters string `json:"filters"` // JSON object of results query parameters
+}
+
+// ownerOf returns the subject views of the caller are saved under, empty when auth
+// is disabled
+func ownerOf(c *gin.Context) string {
+	if claims := claimsOf(c); claims != nil {
+		return claims.Subject
+	}
+	return ""
+}
+
+// GetViews returns the caller's saved views and the ones shared in the project
+func (a *API) GetViews(c *gin.Context) {
+	views, err := a.repos.Views.List(c.Request.Context(), ownerOf(c))
+	if err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+// CreateView saves a named set of result filters
+func (a *API) CreateView(c *gin.Context) {
+	var req viewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	if !validViewFilters(c, req.Filters) {
+		return
+	}
+
+	view := models.SavedView{
+		Name:      req.Name,
+		Owner:     ownerOf(c),
+		Shared:    req.Shared,
+		Filters:   req.Filters,
+		ProjectID: projectOf(c),
+	}
+	if err := a.repos.Views.Create(c.Request.Context(), &view); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, view)
+}
+
+// UpdateView replaces the name, sharing and filters of a saved view
+func (a *API) UpdateView(c *gin.Context) {
+	view, ok := a.ownedView(c)
+	if !ok {
+		return
+	}
+
+	var req viewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	if !validViewFilters(c, req.Filters) {
+		return
+	}
+
+	view.Name = req.Name
+	view.Shared = req.Shared
+	view.Filters = req.Filters
+	if err := a.repos.Views.Save(c.Request.Context(), view); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// DeleteView deletes a saved view
+func (a *API) DeleteView(c *gin.Context) {
+	view, ok := a.ownedView(c)
+	if !ok {
+		return
+	}
+	if err := a.repos.Views.Delete(c.Request.Context(), view.ID); err != nil {
+		apierror.Database(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "View deleted successfully"})
+}
+
+// ownedView loads the view of the :id parameter for a change. Views are changed by
+// their owner, shared views also by admins of the project.
+func (a *API) ownedView(c *gin.Context) (*models.SavedView, bool) {
+	id, ok := idParam(c)
+	if !ok {
+		return nil, false
+	}
+	view, err := a.repos.Views.Get(c.Request.Context(), id)
+	if err != nil || (view.Owner != ownerOf(c) && !view.Shared) {
+		apierror.NotFound(c, "View not found")
+		return nil, false
+	}
+	if view.Owner != ownerOf(c) {
+		if access := auth.GetProjectAccess(c); access == nil || access.Role != auth.RoleAdmin {
+			apierror.Forbidden(c, "Only the owner and project admins can change a shared view")
+			return nil, false
+		}
+	}
+	return view, true
+}
+
+// savedFilters returns the filters of the view selected with ?view=, nil without
+// one. It responds with an error when the view can't be used.
+func (a *API) savedFilters(c *gin.Context) (map[string]string, bool) {
+	id, ok := uintQuery(c, "view")
+	if !ok || id == 0 {
+		return nil, ok
+	}
+	view, err := a.repos.Views.Get(c.Request.Context(), id)
+	if err != nil || (view.Owner != ownerOf(c) && !view.Shared) {
+		apierror.NotFound(c, "View not found")
+		return nil, false
+	}
+
+	var filters map[string]string
+	if view.Filters != "" {
+		if err := json.Unmarshal([]byte(view.Filters), &filters); err != nil {
+			apierror.Internal(c, fmt.Errorf("filters of view %d: %w", view.ID, err))
+			return nil, false
+		}
+	}
+	return filters, true
+}
+
+// validViewFilters checks that filters is a JSON object of results query
+// parameters, responding with a validation error when it isn't
+func validViewFilters(c *gin.Context, filters string) bool {
+	if filters == "" {
+		return true
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal([]byte(filters), &values); err != nil {
+		apierror.Validation(c, apierror.FieldError{Field: "filters", Message: "must be a JSON object of string values"})
+		return false
+	}
+	for name := range values {
+		if !viewFilters[name] {
+			names := make([]string, 0, len(viewFilters))
+			for known := range viewFilters {
+				names = append(names, known)
+			}
+			sort.Strings(names)
+			apierror.Validation(c, apierror.FieldError{Field: "filters", Message: fmt.Sprintf("%q is not a results filter, use %s", name, strings.Join(names, ", "))})
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+	"github-monitor/monitor"
+	"github-monitor/reporting"
+
+	"github.com/gin-gonic/gin"
+	gogithub "github.com/google/go-github/v57/github"
+)
+
+// pushScanTimeout bounds the background scan started for one push
+const pushScanTimeout = 5 * time.Minute
+
+// GitHubWebhook receives push events from repositories we control and scans the
+// changed files right away. Requests are authenticated by their HMAC signature.
+func (a *API) GitHubWebhook(c *gin.Context) {
+	secret := config.AppConfig.GitHub.WebhookSecret
+	if secret == "" {
+		apierror.NotFound(c, "GitHub webhook is not configured")
+		return
+	}
+
+	payload, err := gogithub.ValidatePayload(c.Request, []byte(secret))
+	if err != nil {
+		apierror.Unauthorized(c, "Invalid webhook signature")
+		return
+	}
+
+	event, err := gogithub.ParseWebHook(gogithub.WebHookType(c.Request), payload)
+	if err != nil {
+		log.Printf("Invalid GitHub webhook payload: %v", err)
+		apierror.BadRequest(c, "Invalid webhook payload")
+		return
+	}
+
+	switch e := event.(type) {
+	case *gogithub.PingEvent:
+		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+	case *gogithub.PushEvent:
+		a.handlePush(c, e)
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"message": "Event ignored"})
+	}
+}
+
+func (a *API) handlePush(c *gin.Context, e *gogithub.PushEvent) {
+	fullName := e.GetRepo().GetFullName()
+	owner, _, _ := strings.Cut(fullName, "/")
+	if !webhookOrgAllowed(owner) {
+		apierror.Forbidden(c, "Pushes from "+owner+" are not monitored")
+		return
+	}
+
+	if e.GetDeleted() {
+		c.JSON(http.StatusAccepted, gin.H{"message": "Branch deletion ignored"})
+		return
+	}
+
+	paths := changedFiles(e.Commits)
+	if len(paths) == 0 {
+		c.JSON(http.StatusAccepted, gin.H{"message": "No changed files"})
+		return
+	}
+
+	push := monitor.Push{
+		RepoFullName: fullName,
+		RepoURL:      e.GetRepo().GetHTMLURL(),
+		Ref:          e.GetAfter(),
+		Paths:        paths,
+	}
+
+	// GitHub gives up on deliveries after 10 seconds, so the scan runs in the background
+	go func() {
+		tags := reporting.Tags{"component": "webhook", "repo": push.RepoFullName}
+		defer reporting.Recover(tags)
+
+		ctx, cancel := context.WithTimeout(context.Background(), pushScanTimeout)
+		defer cancel()
+		if err := a.monitorService.ScanPush(ctx, push); err != nil {
+			log.Printf("Push scan of %s failed: %v", push.RepoFullName, err)
+			reporting.CaptureError(err, tags)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Push queued for scanning",
+		"files":   len(paths),
+	})
+}
+
+// changedFiles returns the files added or modified by the commits of a push that
+// still exist after it, in the order they were first changed
+func changedFiles(commits []*gogithub.HeadCommit) []string {
+	var paths []string
+	exists := make(map[string]bool)
+
+	changed := func(path string) {
+		if _, seen := exists[path]; !seen {
+			paths = append(paths, path)
+		}
+		exists[path] = true
+	}
+
+	for _, commit := range commits {
+		for _, path := range commit.Added {
+			changed(path)
+		}
+		for _, path := range commit.Modified {
+			changed(path)
+		}
+		for _, path := range commit.Removed {
+			if _, seen := exists[path]; seen {
+				exists[path] = false
+			}
+		}
+	}
+
+	current := paths[:0]
+	for _, path := range paths {
+		if exists[path] {
+			current = append(current, path)
+		}
+	}
+	return current
+}
+
+func webhookOrgAllowed(owner string) bool {
+	orgs := config.AppConfig.GitHub.WebhookOrgs
+	if len(orgs) == 0 {
+		return true
+	}
+	for _, org := range orgs {
+		if strings.EqualFold(org, owner) {
+			return true
+		}
+	}
+	return false
+}
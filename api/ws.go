@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+	"github-monitor/config"
+	"github-monitor/events"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocket streams monitor events to the dashboard.
+// Browsers can't set headers on WebSocket requests, so the token may also be passed as ?token=
+// and the project as ?project_id=. Events of other projects are left out.
+func (a *API) WebSocket(c *gin.Context) {
+	var claims *auth.Claims
+	if config.AppConfig.Auth.Enabled {
+		tokenString := c.Query("token")
+		if tokenString != "" {
+			// Keep the token out of error reports of the request
+			query := c.Request.URL.Query()
+			query.Del("token")
+			c.Request.URL.RawQuery = query.Encode()
+		} else {
+			tokenString = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+
+		var err error
+		claims, err = auth.ValidateToken(tokenString)
+		if err != nil || !auth.IsSessionActive(c.Request.Context(), a.repos.Sessions, claims.ID) {
+			apierror.Unauthorized(c, "Invalid or expired token")
+			return
+		}
+	}
+
+	requested, ok := uintQuery(c, "project_id")
+	if !ok {
+		return
+	}
+	access, err := auth.ResolveProject(c.Request.Context(), a.repos.Projects, claims, requested)
+	switch {
+	case errors.Is(err, auth.ErrProjectNotFound):
+		apierror.NotFound(c, "Project not found")
+		return
+	case errors.Is(err, auth.ErrProjectForbidden):
+		apierror.Forbidden(c, "No access to this project")
+		return
+	case err != nil:
+		apierror.Database(c, err)
+		return
+	}
+
+	websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		eventsCh, unsubscribe := events.Subscribe()
+		defer unsubscribe()
+
+		// Detect the client going away, we don't expect any messages from it
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			var msg string
+			for {
+				if err := websocket.Message.Receive(ws, &msg); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case event, ok := <-eventsCh:
+				if !ok {
+					return
+				}
+				if !access.Covers(event.ProjectID) {
+					continue
+				}
+				if err := websocket.JSON.Send(ws, redactEvent(event)); err != nil {
+					log.Printf("WebSocket send failed: %v", err)
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}).ServeHTTP(c.Writer, c.Request)
+}
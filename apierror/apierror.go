@@ -0,0 +1,190 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github-monitor/i18n"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// Error codes returned in the "code" field
+const (
+	CodeBadRequest     = "bad_request"
+	CodeValidation     = "validation_error"
+	CodeUnauthorized   = "unauthorized"
+	CodeForbidden      = "forbidden"
+	CodeNotFound       = "not_found"
+	CodeConflict       = "conflict"
+	CodeTooLarge       = "too_large"
+	CodeRateLimited    = "rate_limited"
+	CodeUnavailable    = "unavailable"
+	CodeInternal       = "internal_error"
+	CodeDeliveryFailed = "delivery_failed"
+	CodeSyncFailed     = "sync_failed"
+)
+
+// FieldError describes a problem with a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Response is the error envelope returned by every endpoint.
+// "error" stays a plain message so existing clients keep working.
+type Response struct {
+	Code   string       `json:"code"`
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// Respond aborts the request with the given status, code and message, the message
+// translated into the configured locale
+func Respond(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, Response{Code: code, Error: i18n.Translate("", message)})
+}
+
+// BadRequest responds with 400
+func BadRequest(c *gin.Context, message string) {
+	Respond(c, http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// Unauthorized responds with 401
+func Unauthorized(c *gin.Context, message string) {
+	Respond(c, http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden responds with 403
+func Forbidden(c *gin.Context, message string) {
+	Respond(c, http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound responds with 404
+func NotFound(c *gin.Context, message string) {
+	Respond(c, http.StatusNotFound, CodeNotFound, message)
+}
+
+// Conflict responds with 409
+func Conflict(c *gin.Context, message string) {
+	Respond(c, http.StatusConflict, CodeConflict, message)
+}
+
+// TooLarge responds with 413
+func TooLarge(c *gin.Context, message string) {
+	Respond(c, http.StatusRequestEntityTooLarge, CodeTooLarge, message)
+}
+
+// Validation responds with 400 and a list of field problems
+func Validation(c *gin.Context, fields ...FieldError) {
+	translated := make([]FieldError, 0, len(fields))
+	for _, field := range fields {
+		translated = append(translated, FieldError{Field: field.Field, Message: i18n.Translate("", field.Message)})
+	}
+	c.AbortWithStatusJSON(http.StatusBadRequest, Response{
+		Code:   CodeValidation,
+		Error:  i18n.Translate("", "Validation failed"),
+		Fields: translated,
+	})
+}
+
+// Internal logs the error and responds with 500 without leaking internals to the client.
+// The error is attached to the request for error reporting.
+func Internal(c *gin.Context, err error) {
+	c.Error(err)
+	log.Printf("Internal error on %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	Respond(c, http.StatusInternalServerError, CodeInternal, "Internal server error")
+}
+
+// Database maps a database error to 404, 409 or 500
+func Database(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		NotFound(c, "Record not found")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		Conflict(c, "A record with the same unique value already exists")
+	default:
+		Internal(c, err)
+	}
+}
+
+// Bind translates a request binding error into field level validation details
+func Bind(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   fe.Field(),
+				Message: validationMessage(fe),
+			})
+		}
+		Validation(c, fields...)
+		return
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		Validation(c, FieldError{
+			Field:   typeErr.Field,
+			Message: i18n.Sprintf("", "must be of type %s", typeErr.Type.String()),
+		})
+		return
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		BadRequest(c, "Malformed JSON body")
+		return
+	}
+
+	var sizeErr *http.MaxBytesError
+	if errors.As(err, &sizeErr) {
+		TooLarge(c, i18n.Sprintf("", "Request body is larger than %d bytes", sizeErr.Limit))
+		return
+	}
+
+	BadRequest(c, "Invalid request body")
+}
+
+// RegisterJSONFieldNames makes validation errors report JSON field names instead of Go struct fields
+func RegisterJSONFieldNames() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return i18n.Sprintf("", "must be at least %s", fe.Param())
+	case "max":
+		return i18n.Sprintf("", "must be at most %s", fe.Param())
+	case "oneof":
+		return i18n.Sprintf("", "must be one of: %s", fe.Param())
+	case "url":
+		return "must be a valid URL"
+	case "email":
+		return "must be a valid email address"
+	default:
+		return i18n.Sprintf("", "failed the %q check", fe.Tag())
+	}
+}
@@ -0,0 +1,212 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github-monitor/auth"
+	"github-monitor/db/models"
+	"github-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSnapshotSize caps how much of a request or before/after value is stored per entry
+const maxSnapshotSize = 60 * 1024
+
+// sensitiveFields are replaced before anything is written to the audit log
+var sensitiveFields = map[string]bool{
+	"token":          true,
+	"refresh_token":  true,
+	"password":       true,
+	"secret":         true,
+	"client_secret":  true,
+	"proxy_password": true,
+	"webhook_url":    true,
+}
+
+// secretLists are resources whose requests may send secrets as bare lists, such as
+// token imports, every string in a list of theirs is replaced
+var secretLists = map[string]bool{
+	"tokens": true,
+}
+
+// resources maps the first path segment after /api/v1 to the repository holding its
+// state, so the middleware can capture before/after snapshots for routes with an :id param
+var resources = map[string]func(ctx context.Context, repos *repository.Repositories, id uint) (interface{}, error){
+	"tokens": func(ctx context.Context, repos *repository.Repositories, id uint) (interface{}, error) {
+		return repos.Tokens.Get(ctx, id)
+	},
+	"rules": func(ctx context.Context, repos *repository.Repositories, id uint) (interface{}, error) {
+		return repos.Rules.Get(ctx, id)
+	},
+	"results": func(ctx context.Context, repos *repository.Repositories, id uint) (interface{}, error) {
+		return repos.Results.Get(ctx, id)
+	},
+	"whitelist": func(ctx context.Context, repos *repository.Repositories, id uint) (interface{}, error) {
+		return repos.Whitelist.Get(ctx, id)
+	},
+	"notifications": func(ctx context.Context, repos *repository.Repositories, id uint) (interface{}, error) {
+		return repos.Notifications.Get(ctx, id)
+	},
+}
+
+// bodyWriter keeps a copy of the response body so creates can be audited
+type bodyWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bodyWriter) Write(b []byte) (int, error) {
+	if w.body.Len() < maxSnapshotSize {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Middleware records every mutating request into the audit log.
+// It must be used after auth.AuthMiddleware so the actor is known.
+func Middleware(repos *repository.Repositories) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		resource := resourceName(c.FullPath())
+		resourceID := c.Param("id")
+
+		before := snapshot(c.Request.Context(), repos, resource, resourceID)
+
+		var requestBody string
+		if c.Request.Body != nil {
+			data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSnapshotSize))
+			if err == nil {
+				requestBody = redact(data, secretLists[resource])
+				c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), c.Request.Body))
+			}
+		}
+
+		writer := &bodyWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+
+		c.Next()
+
+		after := snapshot(c.Request.Context(), repos, resource, resourceID)
+		if after == "" && resourceID == "" && c.Writer.Status() < 300 {
+			// Creates have no id in the path, the response carries the new object
+			after = redact(writer.body.Bytes(), false)
+		}
+
+		entry := models.AuditLog{
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Resource:   resource,
+			ResourceID: resourceID,
+			StatusCode: c.Writer.Status(),
+			Request:    requestBody,
+			Before:     before,
+			After:      after,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  truncate(c.Request.UserAgent(), 512),
+		}
+
+		if claims := auth.GetClaims(c); claims != nil {
+			entry.Actor = claims.Subject
+			entry.Role = claims.Role
+		}
+
+		if err := repos.Audit.Create(c.Request.Context(), &entry); err != nil {
+			log.Printf("Failed to write audit log: %v", err)
+		}
+	}
+}
+
+// resourceName extracts the resource from a route pattern like /api/v1/rules/:id
+func resourceName(fullPath string) string {
+	path := strings.TrimPrefix(fullPath, "/api/v1/")
+	if i := strings.Index(path, "/"); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+// snapshot returns the redacted JSON state of a resource, or "" if it can't be loaded
+func snapshot(ctx context.Context, repos *repository.Repositories, resource, id string) string {
+	load, ok := resources[resource]
+	if !ok || id == "" {
+		return ""
+	}
+	parsed, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return ""
+	}
+
+	model, err := load(ctx, repos, uint(parsed))
+	if err != nil {
+		return ""
+	}
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		return ""
+	}
+
+	return redact(data, false)
+}
+
+// redact masks sensitive fields in a JSON document, and the strings in its lists
+// when maskLists is set
+func redact(data []byte, maskLists bool) string {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return ""
+	}
+
+	redacted, err := json.Marshal(redactValue(value, maskLists))
+	if err != nil {
+		return ""
+	}
+
+	return truncate(string(redacted), maxSnapshotSize)
+}
+
+func redactValue(value interface{}, maskLists bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if sensitiveFields[key] {
+				if s, ok := inner.(string); ok && s != "" {
+					v[key] = "******"
+				}
+				continue
+			}
+			v[key] = redactValue(inner, maskLists)
+		}
+		return v
+	case []interface{}:
+		for i, inner := range v {
+			if s, ok := inner.(string); ok && maskLists && s != "" {
+				v[i] = "******"
+				continue
+			}
+			v[i] = redactValue(inner, maskLists)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
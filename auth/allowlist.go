@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"log"
+	"net"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAddressAllowed reports whether admin endpoints may be called from ip, which
+// server.admin_allowlist decides. An empty allowlist allows every address.
+func AdminAddressAllowed(ip net.IP) bool {
+	allowlist := config.AppConfig.Server.AdminAllowlist
+	if len(allowlist) == 0 {
+		return true
+	}
+	for _, entry := range allowlist {
+		if allowed := net.ParseIP(entry); allowed != nil {
+			if allowed.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAdmin is RequireRole(RoleAdmin) that also turns away clients outside
+// server.admin_allowlist, whether or not auth is enabled
+func RequireAdmin() gin.HandlerFunc {
+	requireRole := RequireRole(RoleAdmin)
+	return func(c *gin.Context) {
+		if !AdminAddressAllowed(net.ParseIP(c.ClientIP())) {
+			log.Printf("Refused admin request %s %s from %s, outside server.admin_allowlist", c.Request.Method, c.Request.URL.Path, c.ClientIP())
+			apierror.Forbidden(c, "Admin endpoints can't be reached from this address")
+			return
+		}
+		requireRole(c)
+	}
+}
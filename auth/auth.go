@@ -2,23 +2,33 @@ package auth
 
 import (
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
+	"github-monitor/apierror"
 	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Roles understood by RequireRole
+const (
+	RoleAdmin   = "admin"
+	RoleAnalyst = "analyst"
+	RoleViewer  = "viewer"
+)
+
 type Claims struct {
-	Authenticated bool `json:"authenticated"`
+	Authenticated bool   `json:"authenticated"`
+	Role          string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token
-func GenerateToken() (string, error) {
+// GenerateToken generates a JWT access token bound to the given session
+func GenerateToken(session *models.Session) (string, error) {
 	expiry, err := time.ParseDuration(config.AppConfig.Auth.TokenExpiry)
 	if err != nil {
 		expiry = 24 * time.Hour // Default to 24 hours
@@ -26,7 +36,10 @@ func GenerateToken() (string, error) {
 
 	claims := Claims{
 		Authenticated: true,
+		Role:          session.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        session.SessionID,
+			Subject:   session.Subject,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "github-monitor",
@@ -61,11 +74,16 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, fmt.Errorf("invalid token")
 	}
 
+	// Every token must belong to a session so it can be revoked
+	if claims.ID == "" {
+		return nil, fmt.Errorf("token is not bound to a session")
+	}
+
 	return claims, nil
 }
 
 // AuthMiddleware is a middleware that checks for valid JWT token
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(sessions repository.SessionRepo) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// If auth is disabled, allow all requests
 		if !config.AppConfig.Auth.Enabled {
@@ -76,16 +94,14 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			apierror.Unauthorized(c, "Authorization header required")
 			return
 		}
 
 		// Extract token from "Bearer <token>"
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+			apierror.Unauthorized(c, "Invalid authorization header format")
 			return
 		}
 
@@ -94,8 +110,13 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Validate token
 		claims, err := ValidateToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			apierror.Unauthorized(c, "Invalid or expired token")
+			return
+		}
+
+		// Reject tokens whose session was logged out or revoked
+		if !IsSessionActive(c.Request.Context(), sessions, claims.ID) {
+			apierror.Unauthorized(c, "Session has been revoked")
 			return
 		}
 
@@ -105,6 +126,42 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// RequireRole is a middleware that only lets through users holding one of the given roles.
+// It must be used after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.AppConfig.Auth.Enabled {
+			c.Next()
+			return
+		}
+
+		claims := GetClaims(c)
+		if claims == nil {
+			apierror.Unauthorized(c, "Authentication required")
+			return
+		}
+
+		for _, role := range roles {
+			if claims.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		apierror.Forbidden(c, "Insufficient permissions")
+	}
+}
+
+// GetClaims returns the claims set by AuthMiddleware, or nil if there are none
+func GetClaims(c *gin.Context) *Claims {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil
+	}
+	claims, _ := value.(*Claims)
+	return claims
+}
+
 // VerifyPassword checks if the provided password matches the configured password
 func VerifyPassword(password string) bool {
 	return password == config.AppConfig.Auth.Password
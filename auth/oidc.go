@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github-monitor/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProvider implements the OpenID Connect authorization code flow
+type OIDCProvider struct {
+	cfg         *config.OIDCConfig
+	oauth2      *oauth2.Config
+	issuer      string
+	jwksURI     string
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+	httpClient  *http.Client
+	mu          sync.RWMutex
+}
+
+// OIDCIdentity is the user identity extracted from a verified ID token
+type OIDCIdentity struct {
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+	Role    string
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// NewOIDCProvider discovers the issuer's endpoints and creates a provider
+func NewOIDCProvider(ctx context.Context, cfg *config.OIDCConfig) (*OIDCProvider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" {
+		return nil, fmt.Errorf("oidc issuer_url and client_id are required")
+	}
+
+	p := &OIDCProvider{
+		cfg:        cfg,
+		keys:       make(map[string]*rsa.PublicKey),
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+
+	discoveryURL := strings.TrimSuffix(cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	var doc discoveryDocument
+	if err := p.getJSON(ctx, discoveryURL, &doc); err != nil {
+		return nil, fmt.Errorf("failed to fetch oidc discovery document: %w", err)
+	}
+
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery document is missing required endpoints")
+	}
+
+	p.issuer = doc.Issuer
+	p.jwksURI = doc.JWKSURI
+	p.oauth2 = &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  doc.AuthorizationEndpoint,
+			TokenURL: doc.TokenEndpoint,
+		},
+	}
+
+	return p, nil
+}
+
+// AuthCodeURL returns the URL to redirect the browser to for the given state
+func (p *OIDCProvider) AuthCodeURL(state, nonce string) string {
+	return p.oauth2.AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce))
+}
+
+// Exchange trades an authorization code for a verified identity
+func (p *OIDCProvider) Exchange(ctx context.Context, code, nonce string) (*OIDCIdentity, error) {
+	token, err := p.oauth2.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("token response did not contain an id_token")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.getKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("id_token nonce mismatch")
+	}
+
+	identity := &OIDCIdentity{
+		Groups: groupsFromClaim(claims[p.cfg.GroupsClaim]),
+	}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	identity.Role = p.mapRole(identity.Groups)
+
+	return identity, nil
+}
+
+// mapRole picks the most privileged role granted by the user's groups
+func (p *OIDCProvider) mapRole(groups []string) string {
+	rank := map[string]int{RoleViewer: 1, RoleAnalyst: 2, RoleAdmin: 3}
+
+	role := p.cfg.DefaultRole
+	for _, group := range groups {
+		mapped, ok := p.cfg.RoleMapping[group]
+		if !ok {
+			// viper lowercases map keys
+			mapped, ok = p.cfg.RoleMapping[strings.ToLower(group)]
+		}
+		if ok && rank[mapped] > rank[role] {
+			role = mapped
+		}
+	}
+
+	return role
+}
+
+// getKey returns the signing key with the given key ID, refetching the JWKS on a miss
+func (p *OIDCProvider) getKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.RLock()
+	key, ok := p.keys[kid]
+	fetched := p.keysFetched
+	p.mu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+
+	// Avoid hammering the IdP with unknown key IDs
+	if time.Since(fetched) < time.Minute {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	key, ok = p.keys[kid]
+	if !ok {
+		// Providers with a single key may omit kid
+		if kid == "" && len(p.keys) == 1 {
+			for _, k := range p.keys {
+				return k, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	return key, nil
+}
+
+// refreshKeys downloads the issuer's JSON Web Key Set
+func (p *OIDCProvider) refreshKeys(ctx context.Context) error {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.jwksURI, &jwks); err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetched = time.Now()
+	p.mu.Unlock()
+
+	return nil
+}
+
+// getJSON performs a GET request and decodes the JSON response
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// groupsFromClaim normalizes a groups claim which may be a list or a single string
+func groupsFromClaim(value interface{}) []string {
+	switch v := value.(type) {
+	case []interface{}:
+		groups := make([]string, 0, len(v))
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+		return groups
+	case string:
+		return []string{v}
+	default:
+		return nil
+	}
+}
+
+// RandomString returns a random hex string of n bytes, used for state and nonce values
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Errors returned by ResolveProject
+var (
+	ErrProjectNotFound  = errors.New("project not found")
+	ErrProjectForbidden = errors.New("no access to the project")
+)
+
+// ProjectHeader selects the project of a request, the project_id query parameter
+// may be used instead
+const ProjectHeader = "X-Project-ID"
+
+// ProjectAccess is what a caller may see and do in the project of a request
+type ProjectAccess struct {
+	ProjectID  uint   // project new rules, whitelist entries and channels are created in
+	Role       string // role in that project
+	All        bool   // admins that didn't select a project see every project
+	Unassigned bool   // not a member of any project, acting in the default project
+}
+
+// DeploymentWide reports whether the caller may see data that spans every project,
+// such as summary reports. That is admins and users that aren't in any project.
+func (a *ProjectAccess) DeploymentWide() bool {
+	return a.All || a.Unassigned
+}
+
+// Scope restricts ctx to the projects the access covers
+func (a *ProjectAccess) Scope(ctx context.Context) context.Context {
+	if a.All {
+		return ctx
+	}
+	return repository.WithProjects(ctx, []uint{a.ProjectID})
+}
+
+// Covers reports whether something in a project is visible with the access.
+// projectID 0 stands for things that don't belong to a project.
+func (a *ProjectAccess) Covers(projectID uint) bool {
+	return a.All || projectID == 0 || projectID == a.ProjectID
+}
+
+// AccessibleProjects returns the projects a caller may select, all is true for
+// admins, who may select any project. Users that aren't a member of any project keep
+// their login role in the default project, so deployments without projects work as before.
+// claims is nil when auth is disabled.
+func AccessibleProjects(ctx context.Context, projects repository.ProjectRepo, claims *Claims) (memberships []models.ProjectMember, all bool, err error) {
+	if claims == nil || claims.Role == RoleAdmin {
+		return nil, true, nil
+	}
+
+	memberships, err = projects.MembershipsOf(ctx, claims.Subject)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(memberships) == 0 {
+		memberships = []models.ProjectMember{{ProjectID: models.DefaultProjectID, Subject: claims.Subject, Role: claims.Role}}
+	}
+	return memberships, false, nil
+}
+
+// ResolveProject works out the project of a request and the caller's role in it.
+// requested is 0 when the caller didn't select a project: admins then see every
+// project and create in the default one, other users get their first project.
+func ResolveProject(ctx context.Context, projects repository.ProjectRepo, claims *Claims, requested uint) (*ProjectAccess, error) {
+	if requested != 0 {
+		if _, err := projects.Get(ctx, requested); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrProjectNotFound
+			}
+			return nil, err
+		}
+	}
+
+	memberships, all, err := AccessibleProjects(ctx, projects, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if all {
+		if requested == 0 {
+			return &ProjectAccess{ProjectID: models.DefaultProjectID, Role: RoleAdmin, All: true}, nil
+		}
+		return &ProjectAccess{ProjectID: requested, Role: RoleAdmin}, nil
+	}
+
+	if requested == 0 {
+		requested = memberships[0].ProjectID
+	}
+	for _, member := range memberships {
+		if member.ProjectID == requested {
+			// Memberships that aren't stored stand for the default project of unassigned users
+			return &ProjectAccess{ProjectID: requested, Role: member.Role, Unassigned: member.ID == 0}, nil
+		}
+	}
+	return nil, ErrProjectForbidden
+}
+
+// ProjectMiddleware resolves the project selected with ProjectHeader or ?project_id=
+// and restricts the request context to it. It must be used after AuthMiddleware.
+func ProjectMiddleware(projects repository.ProjectRepo) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(ProjectHeader)
+		if value == "" {
+			value = c.Query("project_id")
+		}
+
+		var requested uint
+		if value != "" {
+			id, err := strconv.ParseUint(value, 10, 0)
+			if err != nil || id == 0 {
+				apierror.Validation(c, apierror.FieldError{Field: "project_id", Message: "must be a positive integer"})
+				return
+			}
+			requested = uint(id)
+		}
+
+		var claims *Claims
+		if config.AppConfig.Auth.Enabled {
+			claims = GetClaims(c)
+		}
+
+		access, err := ResolveProject(c.Request.Context(), projects, claims, requested)
+		switch {
+		case errors.Is(err, ErrProjectNotFound):
+			apierror.NotFound(c, "Project not found")
+			return
+		case errors.Is(err, ErrProjectForbidden):
+			apierror.Forbidden(c, "No access to this project")
+			return
+		case err != nil:
+			apierror.Database(c, err)
+			return
+		}
+
+		c.Set("project", access)
+		c.Request = c.Request.WithContext(access.Scope(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// RequireProjectRole is RequireRole for project resources, it checks the caller's
+// role in the project of the request. It must be used after ProjectMiddleware.
+func RequireProjectRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		access := GetProjectAccess(c)
+		if access == nil {
+			apierror.Forbidden(c, "No project selected")
+			return
+		}
+
+		for _, role := range roles {
+			if access.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		apierror.Forbidden(c, "Insufficient permissions in this project")
+	}
+}
+
+// GetProjectAccess returns the access set by ProjectMiddleware, or nil if there is none
+func GetProjectAccess(c *gin.Context) *ProjectAccess {
+	value, exists := c.Get("project")
+	if !exists {
+		return nil
+	}
+	access, _ := value.(*ProjectAccess)
+	return access
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/repository"
+)
+
+// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired or revoked
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// TokenPair is returned to clients on login and refresh
+type TokenPair struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"refresh_expires_at"`
+}
+
+// CreateSession starts a new session for the subject and issues its tokens
+func CreateSession(ctx context.Context, sessions repository.SessionRepo, subject, role, ipAddress, userAgent string) (*TokenPair, error) {
+	sessionID, err := RandomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		SessionID:        sessionID,
+		Subject:          subject,
+		Role:             role,
+		RefreshTokenHash: hashToken(refreshToken),
+		IPAddress:        ipAddress,
+		UserAgent:        truncate(userAgent, 512),
+		ExpiresAt:        time.Now().Add(refreshTokenExpiry()),
+	}
+
+	if err := sessions.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	accessToken, err := GenerateToken(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    session.ExpiresAt,
+	}, nil
+}
+
+// RefreshSession rotates the refresh token of a session and issues a new access token
+func RefreshSession(ctx context.Context, sessions repository.SessionRepo, refreshToken string) (*TokenPair, error) {
+	session, err := sessions.GetByRefreshTokenHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, err := RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session.RefreshTokenHash = hashToken(newRefreshToken)
+	session.LastUsedAt = &now
+
+	// Rotate atomically so a refresh token can only be redeemed once
+	rotated, err := sessions.RotateRefreshToken(ctx, session.ID, hashToken(refreshToken), session.RefreshTokenHash, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	accessToken, err := GenerateToken(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		ExpiresAt:    session.ExpiresAt,
+	}, nil
+}
+
+// RevokeSession revokes a session so its access and refresh tokens stop working
+func RevokeSession(ctx context.Context, sessions repository.SessionRepo, sessionID string) error {
+	return sessions.Revoke(ctx, sessionID)
+}
+
+// IsSessionActive reports whether the session exists and has not been revoked
+func IsSessionActive(ctx context.Context, sessions repository.SessionRepo, sessionID string) bool {
+	active, err := sessions.Active(ctx, sessionID)
+	return err == nil && active
+}
+
+func refreshTokenExpiry() time.Duration {
+	expiry, err := time.ParseDuration(config.AppConfig.Auth.RefreshTokenExpiry)
+	if err != nil {
+		return 7 * 24 * time.Hour // Default to 7 days
+	}
+	return expiry
+}
+
+// hashToken hashes refresh tokens so a database leak doesn't leak usable tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
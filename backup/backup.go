@@ -0,0 +1,260 @@
+// Package backup exports and restores the monitor data as a portable zip archive
+// holding one JSON Lines file per table, independent of the database driver.
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github-monitor/db/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FormatVersion is written to the manifest so future versions can convert old archives
+const FormatVersion = 1
+
+const (
+	manifestFile = "manifest.json"
+	batchSize    = 500
+)
+
+// ErrInvalidArchive is wrapped by the errors of Import caused by the archive
+// itself, as opposed to failures writing to the database
+var ErrInvalidArchive = errors.New("invalid archive")
+
+// Manifest describes an archive
+type Manifest struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"created_at"`
+	Counts    map[string]int64 `json:"counts"`
+}
+
+// Tables in restore order, referenced rows come first.
+// Tokens and notification channels hold secrets and are left out on purpose.
+var tables = []string{"projects", "members", "rules", "whitelist", "results", "history"}
+
+// Export writes every project, project member, rule, whitelist entry, result and
+// scan history entry to w
+func Export(database *gorm.DB, w io.Writer) (*Manifest, error) {
+	zw := zip.NewWriter(w)
+	manifest := &Manifest{
+		Version:   FormatVersion,
+		CreatedAt: time.Now(),
+		Counts:    make(map[string]int64),
+	}
+
+	for _, name := range tables {
+		var (
+			count int64
+			err   error
+		)
+		switch name {
+		case "projects":
+			count, err = exportTable[models.Project](database, zw, name)
+		case "members":
+			count, err = exportTable[models.ProjectMember](database, zw, name)
+		case "rules":
+			count, err = exportTable[models.MonitorRule](database, zw, name)
+		case "whitelist":
+			count, err = exportTable[models.Whitelist](database, zw, name)
+		case "results":
+			count, err = exportTable[models.SearchResult](database, zw, name)
+		case "history":
+			count, err = exportTable[models.ScanHistory](database, zw, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", name, err)
+		}
+		manifest.Counts[name] = count
+	}
+
+	f, err := zw.Create(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(f).Encode(manifest); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// exportTable streams a table in batches so large result tables don't have to fit in memory
+func exportTable[T any](database *gorm.DB, zw *zip.Writer, name string) (int64, error) {
+	f, err := zw.Create(name + ".jsonl")
+	if err != nil {
+		return 0, err
+	}
+
+	encoder := json.NewEncoder(f)
+	var count int64
+	var batch []T
+
+	err = database.Model(new(T)).Order("id").FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := encoder.Encode(&batch[i]); err != nil {
+				return err
+			}
+		}
+		count += int64(len(batch))
+		return nil
+	}).Error
+
+	return count, err
+}
+
+// Import restores an archive created by Export. Rows are matched by ID, so existing
+// rows are overwritten and missing ones created. Everything runs in one transaction.
+func Import(database *gorm.DB, r io.ReaderAt, size int64) (*Manifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	manifestEntry, ok := files[manifestFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidArchive, manifestFile)
+	}
+	var manifest Manifest
+	if err := readJSON(manifestEntry, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: bad manifest: %v", ErrInvalidArchive, err)
+	}
+	if manifest.Version != FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidArchive, manifest.Version)
+	}
+
+	imported := &Manifest{
+		Version:   manifest.Version,
+		CreatedAt: manifest.CreatedAt,
+		Counts:    make(map[string]int64),
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		for _, name := range tables {
+			f, ok := files[name+".jsonl"]
+			if !ok {
+				continue
+			}
+
+			var (
+				count int64
+				err   error
+			)
+			switch name {
+			case "projects":
+				count, err = importTable[models.Project](tx, f)
+			case "members":
+				count, err = importTable[models.ProjectMember](tx, f)
+			case "rules":
+				count, err = importTable[models.MonitorRule](tx, f)
+			case "whitelist":
+				count, err = importTable[models.Whitelist](tx, f)
+			case "results":
+				count, err = importTable[models.SearchResult](tx, f)
+			case "history":
+				count, err = importTable[models.ScanHistory](tx, f)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to import %s: %w", name, err)
+			}
+			imported.Counts[name] = count
+		}
+
+		return resetSequences(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return imported, nil
+}
+
+// importTable upserts the rows of one JSON Lines file in batches
+func importTable[T any](tx *gorm.DB, f *zip.File) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer rc.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(rc))
+	var count int64
+	batch := make([]T, 0, batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		// Associations such as a result's rule are restored from their own file
+		err := tx.Omit(clause.Associations).
+			Clauses(clause.OnConflict{UpdateAll: true}).
+			Create(&batch).Error
+		count += int64(len(batch))
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		var row T
+		if err := decoder.Decode(&row); err == io.EOF {
+			break
+		} else if err != nil {
+			return count, fmt.Errorf("%w: row %d: %v", ErrInvalidArchive, count+int64(len(batch))+1, err)
+		}
+
+		batch = append(batch, row)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+
+	return count, flush()
+}
+
+// resetSequences moves Postgres id sequences past the restored ids.
+// MySQL and SQLite adjust their auto increment counters on their own.
+func resetSequences(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	for _, model := range []interface{}{&models.Project{}, &models.ProjectMember{}, &models.MonitorRule{}, &models.Whitelist{}, &models.SearchResult{}, &models.ScanHistory{}} {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+		sql := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %s", table, table)
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readJSON(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(rc).Decode(v)
+}
@@ -0,0 +1,106 @@
+// Package cache keeps state shared between monitor instances in Redis: GitHub
+// responses for conditional requests, claims that stop two instances from scanning
+// the same rule, and notification dedup windows.
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github-monitor/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrMiss is returned by Get for keys that don't exist
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores shared values by key
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Claim takes key for ttl unless another caller holds it. The returned release
+	// function gives it up early, it does nothing once the claim has expired.
+	Claim(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool, err error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// releaseScript deletes a claim only while it still holds our token
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+type redisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis connects to the configured Redis server
+func NewRedis(cfg *config.RedisConfig) (Cache, error) {
+	options := &redis.Options{
+		Addr:     cfg.Addr,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	}
+	if cfg.TLS {
+		options.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	c := &redisCache{client: redis.NewClient(options), prefix: cfg.KeyPrefix}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Ping(ctx); err != nil {
+		c.client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", cfg.Addr, err)
+	}
+	return c, nil
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	return value, err
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, c.prefix+key, value, ttl).Err()
+}
+
+func (c *redisCache) Claim(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, false, err
+	}
+	value := hex.EncodeToString(token)
+
+	ok, err := c.client.SetNX(ctx, c.prefix+key, value, ttl).Result()
+	if err != nil || !ok {
+		return func() {}, false, err
+	}
+
+	release := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		releaseScript.Run(ctx, c.client, []string{c.prefix + key}, value)
+	}
+	return release, true, nil
+}
+
+func (c *redisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+func (c *redisCache) Close() error {
+	return c.client.Close()
+}
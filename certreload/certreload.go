@@ -0,0 +1,114 @@
+package certreload
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkInterval throttles how often the certificate files are checked for changes
+const checkInterval = 30 * time.Second
+
+// Reloader serves a TLS certificate from disk and picks up rotated files without a restart
+type Reloader struct {
+	certFile    string
+	keyFile     string
+	cert        *tls.Certificate
+	modTime     time.Time
+	lastChecked time.Time
+	mu          sync.RWMutex
+}
+
+// New loads the certificate and key and returns a reloader for them
+func New(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// maybeReload reloads the certificate if the files changed since they were last loaded
+func (r *Reloader) maybeReload() {
+	r.mu.RLock()
+	due := time.Since(r.lastChecked) >= checkInterval
+	r.mu.RUnlock()
+
+	if !due {
+		return
+	}
+
+	modTime, err := r.latestModTime()
+
+	r.mu.Lock()
+	r.lastChecked = time.Now()
+	changed := err == nil && modTime.After(r.modTime)
+	r.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if err := r.reload(); err != nil {
+		// Keep serving the old certificate
+		log.Printf("Failed to reload TLS certificate: %v", err)
+		return
+	}
+
+	log.Printf("Reloaded TLS certificate from %s", r.certFile)
+}
+
+func (r *Reloader) reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.lastChecked = time.Now()
+	r.mu.Unlock()
+
+	return nil
+}
+
+// latestModTime returns the newer modification time of the certificate and key files
+func (r *Reloader) latestModTime() (time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to stat certificate: %w", err)
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to stat key: %w", err)
+	}
+
+	if keyInfo.ModTime().After(certInfo.ModTime()) {
+		return keyInfo.ModTime(), nil
+	}
+	return certInfo.ModTime(), nil
+}
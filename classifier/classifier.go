@@ -0,0 +1,239 @@
+// Package classifier pre-screens new results with an LLM, which suggests whether
+// a match is a real secret or noise such as samples and documentation. The verdict
+// is only a hint for analysts, it never changes the triage status.
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/reporting"
+
+	"gorm.io/gorm"
+)
+
+// maxSnippet caps the snippet bytes sent to the model
+const maxSnippet = 4000
+
+// maxAttempts is how many failed requests a result gets before it's stored as unknown
+const maxAttempts = 3
+
+const systemPrompt = `You triage results of a secret leak scanner that searches public code for an organization's keywords.
+Decide whether the match exposes a real, usable secret or credential of the organization ("secret"), or is noise such as
+placeholders, examples, documentation, test fixtures, revoked or redacted values, or an unrelated use of the keyword ("noise").
+Answer with a single JSON object and nothing else: {"verdict": "secret" or "noise", "confidence": number between 0 and 1, "reason": "one short sentence"}`
+
+// Classifier periodically classifies pending results that have no verdict yet
+type Classifier struct {
+	db        *gorm.DB
+	client    *Client
+	interval  time.Duration
+	batchSize int
+	stopChan  chan struct{} // nil while stopped
+	failures  map[uint]int  // failed requests per result, only touched by ClassifyPending
+}
+
+// NewClassifier creates a classifier for the configured endpoint
+func NewClassifier(cfg *config.ClassifierConfig, database *gorm.DB) (*Classifier, error) {
+	interval, err := time.ParseDuration(cfg.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval: %w", err)
+	}
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout: %w", err)
+	}
+	return &Classifier{
+		db:        database,
+		client:    NewClient(cfg.BaseURL, cfg.APIKey, cfg.Model, timeout),
+		interval:  interval,
+		batchSize: cfg.BatchSize,
+		failures:  make(map[uint]int),
+	}, nil
+}
+
+// Start runs the classifier in the background, it can be started again after Stop
+func (c *Classifier) Start() {
+	if c.stopChan != nil {
+		return
+	}
+	c.stopChan = make(chan struct{})
+	go c.run(c.stopChan)
+	log.Printf("Result classifier started, every %s", c.interval)
+}
+
+// Stop stops the classifier, a request in flight is cancelled
+func (c *Classifier) Stop() {
+	if c.stopChan == nil {
+		return
+	}
+	close(c.stopChan)
+	c.stopChan = nil
+}
+
+func (c *Classifier) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	for {
+		c.classifyOnce(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (c *Classifier) classifyOnce(ctx context.Context) {
+	defer reporting.Recover(reporting.Tags{"component": "classifier"})
+
+	if err := c.ClassifyPending(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("Result classification failed: %v", err)
+		reporting.CaptureError(err, reporting.Tags{"component": "classifier"})
+	}
+}
+
+// ClassifyPending classifies up to one batch of pending results without a verdict.
+// Results whose request fails are retried on the next runs and stored as unknown
+// after maxAttempts failures, so one result can't hold up the others. An error is
+// only returned when nothing could be classified.
+func (c *Classifier) ClassifyPending(ctx context.Context) error {
+	var results []models.SearchResult
+	err := c.db.WithContext(ctx).Preload("Rule").
+		Where("status = ?", "pending").
+		Where("verdict = '' OR verdict IS NULL").
+		Order("id").
+		Limit(c.batchSize).
+		Find(&results).Error
+	if err != nil || len(results) == 0 {
+		return err
+	}
+
+	// Forget failures of results that were triaged or classified elsewhere meanwhile
+	batch := make(map[uint]bool, len(results))
+	for _, result := range results {
+		batch[result.ID] = true
+	}
+	for id := range c.failures {
+		if !batch[id] {
+			delete(c.failures, id)
+		}
+	}
+
+	classified, failed := 0, 0
+	var lastErr error
+	for i := range results {
+		if ctx.Err() != nil {
+			break
+		}
+
+		verdict, err := c.Classify(ctx, &results[i])
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			failed++
+			lastErr = fmt.Errorf("result %d: %w", results[i].ID, err)
+			c.failures[results[i].ID]++
+			if c.failures[results[i].ID] < maxAttempts {
+				log.Printf("Failed to classify result %d, retrying on the next run: %v", results[i].ID, err)
+				continue
+			}
+			log.Printf("Failed to classify result %d %d times, giving up: %v", results[i].ID, maxAttempts, err)
+			verdict = Verdict{Verdict: models.VerdictUnknown, Reason: fmt.Sprintf("Classification failed %d times: %v", maxAttempts, err)}
+		}
+
+		err = c.db.WithContext(ctx).Model(&results[i]).UpdateColumns(map[string]interface{}{
+			"verdict":            verdict.Verdict,
+			"verdict_confidence": verdict.Confidence,
+			"verdict_reason":     verdict.Reason,
+		}).Error
+		if err != nil {
+			return err
+		}
+		delete(c.failures, results[i].ID)
+		classified++
+	}
+
+	log.Printf("Classified %d results, %d failed", classified, failed)
+	if classified == 0 && lastErr != nil {
+		return lastErr
+	}
+	return nil
+}
+
+// Verdict is the model's assessment of a result
+type Verdict struct {
+	Verdict    string  `json:"verdict"`
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason"`
+}
+
+// Classify asks the model for a verdict on a result. An answer that can't be used
+// yields models.VerdictUnknown rather than an error, so the result isn't retried.
+func (c *Classifier) Classify(ctx context.Context, result *models.SearchResult) (Verdict, error) {
+	answer, err := c.client.Complete(ctx, systemPrompt, describe(result))
+	if err != nil {
+		return Verdict{}, err
+	}
+	return parseVerdict(answer), nil
+}
+
+// describe renders the context of a result for the model
+func describe(result *models.SearchResult) string {
+	snippet := result.ContentSnippet
+	if len(snippet) > maxSnippet {
+		snippet = snippet[:maxSnippet]
+	}
+
+	var keywords []string
+	_ = json.Unmarshal([]byte(result.MatchedKeywords), &keywords)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rule: %s\n", result.Rule.Name)
+	fmt.Fprintf(&b, "Source: %s\n", result.Source)
+	fmt.Fprintf(&b, "Repository: %s\n", result.RepoFullName)
+	fmt.Fprintf(&b, "File: %s\n", result.FilePath)
+	fmt.Fprintf(&b, "Matched keywords: %s\n", strings.Join(keywords, ", "))
+	fmt.Fprintf(&b, "Snippet:\n%s\n", snippet)
+	return b.String()
+}
+
+// parseVerdict extracts the JSON object from an answer, models sometimes wrap it
+// in prose or code fences
+func parseVerdict(answer string) Verdict {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return Verdict{Verdict: models.VerdictUnknown, Reason: "The model did not answer with JSON"}
+	}
+
+	var verdict Verdict
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &verdict); err != nil {
+		return Verdict{Verdict: models.VerdictUnknown, Reason: "The model did not answer with valid JSON"}
+	}
+	verdict.Verdict = strings.ToLower(strings.TrimSpace(verdict.Verdict))
+	if verdict.Verdict != models.VerdictSecret && verdict.Verdict != models.VerdictNoise {
+		return Verdict{Verdict: models.VerdictUnknown, Reason: fmt.Sprintf("The model answered with verdict %q", verdict.Verdict)}
+	}
+
+	verdict.Confidence = min(max(verdict.Confidence, 0), 1)
+	if len(verdict.Reason) > 500 {
+		verdict.Reason = verdict.Reason[:500]
+	}
+	return verdict
+}
@@ -0,0 +1,206 @@
+// Package client calls the REST API of a GitHub Monitoring deployment, for Go
+// services that manage rules, triage results or control the monitor.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProjectHeader selects the project requests act on
+const ProjectHeader = "X-Project-ID"
+
+// FieldError describes a problem with a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int
+	Code       string       `json:"code"` // e.g. validation_error, not_found, rate_limited
+	Message    string       `json:"error"`
+	Fields     []FieldError `json:"fields,omitempty"`
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("github monitor API returned %d %s: %s", e.StatusCode, e.Code, e.Message)
+	for _, field := range e.Fields {
+		message += fmt.Sprintf("; %s %s", field.Field, field.Message)
+	}
+	return message
+}
+
+// IsNotFound reports whether err is a 404 response of the API
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client calls the API of the deployment at its base URL. It is safe for
+// concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu           sync.RWMutex
+	token        string
+	refreshToken string
+	projectID    uint // 0 for the default project of the session
+}
+
+// NewClient creates a client for the deployment at baseURL, e.g.
+// https://monitor.example.com. Call Login or SetToken before other methods unless
+// the deployment has authentication disabled.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/") + "/api/v1",
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetHTTPClient replaces the HTTP client requests are sent with, e.g. to change
+// the timeout or trust a private CA
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// SetToken authenticates requests with an access token, refresh is the refresh
+// token used to renew it when it expires, empty to not renew it
+func (c *Client) SetToken(token, refresh string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.refreshToken = refresh
+}
+
+// SetProject selects the project rules and results are read from and created in,
+// 0 for the default project of the session
+func (c *Client) SetProject(projectID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.projectID = projectID
+}
+
+type tokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Login starts a session with the admin password. The session is renewed with
+// its refresh token when the access token expires.
+func (c *Client) Login(ctx context.Context, password string) error {
+	var session tokens
+	if err := c.send(ctx, http.MethodPost, "/login", "", 0, map[string]string{"password": password}, &session); err != nil {
+		return err
+	}
+	c.SetToken(session.Token, session.RefreshToken)
+	return nil
+}
+
+// Logout revokes the session of the client
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.do(ctx, http.MethodPost, "/auth/logout", nil, nil); err != nil {
+		return err
+	}
+	c.SetToken("", "")
+	return nil
+}
+
+// refresh renews the expired access token, reporting whether there is a new one.
+// A concurrent request may have renewed it already.
+func (c *Client) refresh(ctx context.Context, expired string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != expired {
+		return true, nil
+	}
+	if c.refreshToken == "" {
+		return false, nil
+	}
+
+	var session tokens
+	if err := c.send(ctx, http.MethodPost, "/auth/refresh", "", 0, map[string]string{"refresh_token": c.refreshToken}, &session); err != nil {
+		return false, err
+	}
+	c.token = session.Token
+	c.refreshToken = session.RefreshToken
+	return true, nil
+}
+
+// do sends an authenticated request, renewing the session once when the access
+// token expired
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	c.mu.RLock()
+	token, projectID := c.token, c.projectID
+	c.mu.RUnlock()
+
+	err := c.send(ctx, method, path, token, projectID, in, out)
+	var apiErr *Error
+	if token == "" || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		return err
+	}
+	if renewed, refreshErr := c.refresh(ctx, token); refreshErr != nil || !renewed {
+		return err
+	}
+
+	c.mu.RLock()
+	token = c.token
+	c.mu.RUnlock()
+	return c.send(ctx, method, path, token, projectID, in, out)
+}
+
+func (c *Client) send(ctx context.Context, method, path, token string, projectID uint, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if projectID != 0 {
+		req.Header.Set(ProjectHeader, strconv.FormatUint(uint64(projectID), 10))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("github monitor request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
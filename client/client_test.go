@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRenewsExpiredToken(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		var input map[string]string
+		json.NewDecoder(r.Body).Decode(&input)
+		if input["refresh_token"] != "refresh-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "access-2", "refresh_token": "refresh-2"})
+	})
+	mux.HandleFunc("/api/v1/monitor/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"code": "unauthorized", "error": "Token expired"})
+			return
+		}
+		if r.Header.Get(ProjectHeader) != "3" {
+			t.Errorf("project header is %q, want 3", r.Header.Get(ProjectHeader))
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"is_running": true})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := NewClient(server.URL + "/")
+	c.SetToken("access-1", "refresh-1")
+	c.SetProject(3)
+
+	status, err := c.MonitorStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.IsRunning {
+		t.Error("monitor status is not running")
+	}
+	if c.token != "access-2" || c.refreshToken != "refresh-2" {
+		t.Errorf("tokens are %q and %q after the renewal", c.token, c.refreshToken)
+	}
+}
+
+func TestDecodesErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":   "validation_error",
+			"error":  "Validation failed",
+			"fields": []FieldError{{Field: "severity", Message: "must be one of: critical high medium low info"}},
+		})
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL).UpdateResultStatus(context.Background(), 1, "done")
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("error is %v, want an API error", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != "validation_error" || len(apiErr.Fields) != 1 {
+		t.Errorf("decoded %+v", apiErr)
+	}
+	if IsNotFound(err) {
+		t.Error("a validation error counts as not found")
+	}
+}
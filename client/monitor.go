@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// MonitorStatus tells whether the monitor is scanning
+type MonitorStatus struct {
+	IsRunning bool           `json:"is_running"`
+	Cluster   *ClusterStatus `json:"cluster,omitempty"` // nil for a single instance
+}
+
+// ClusterStatus is the leader election state of the instance that answered
+type ClusterStatus struct {
+	Instance string `json:"instance"`
+	IsLeader bool   `json:"is_leader"`
+	Leader   string `json:"leader"`
+}
+
+// MonitorStatus returns the state of the monitor
+func (c *Client) MonitorStatus(ctx context.Context) (*MonitorStatus, error) {
+	var status MonitorStatus
+	if err := c.do(ctx, http.MethodGet, "/monitor/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// StartMonitor starts the scheduled scans, admins only. In a cluster it has to
+// reach the leader.
+func (c *Client) StartMonitor(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/monitor/start", nil, nil)
+}
+
+// StopMonitor stops the scheduled scans, admins only
+func (c *Client) StopMonitor(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/monitor/stop", nil, nil)
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github-monitor/db/models"
+)
+
+// ResultQuery filters and pages the search results, zero values don't filter
+type ResultQuery struct {
+	RuleID    uint
+	Status    string   // pending, confirmed, false_positive or resolved
+	Severity  string   // critical, high, medium, low or info
+	Source    string   // github, dockerhub, postman, npm, pypi or an ingest source
+	Verdict   string   // secret, noise or unknown
+	FileTypes []string // e.g. env, key
+	Snoozed   string   // true, false or any, pending results exclude snoozed ones by default
+	Sort      string   // empty for newest first, verdict for likely secrets first
+	View      uint     // saved view whose filters fill in the ones not set
+
+	// Page and PageSize page through the results by offset, After by ID instead,
+	// which stays fast on large tables but can't be combined with Sort
+	Page     int
+	PageSize int     // at most 100
+	After    *uint64 // 0 starts from the newest result
+}
+
+func (q ResultQuery) values() url.Values {
+	values := url.Values{}
+	set := func(name, value string) {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	setUint := func(name string, value uint) {
+		if value != 0 {
+			values.Set(name, strconv.FormatUint(uint64(value), 10))
+		}
+	}
+
+	setUint("rule_id", q.RuleID)
+	set("status", q.Status)
+	set("severity", q.Severity)
+	set("source", q.Source)
+	set("verdict", q.Verdict)
+	set("file_type", strings.Join(q.FileTypes, ","))
+	set("snoozed", q.Snoozed)
+	set("sort", q.Sort)
+	setUint("view", q.View)
+	if q.Page > 0 {
+		values.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PageSize > 0 {
+		values.Set("page_size", strconv.Itoa(q.PageSize))
+	}
+	if q.After != nil {
+		values.Set("after", strconv.FormatUint(*q.After, 10))
+	}
+	return values
+}
+
+// ResultPage is a page of search results. Offset pages carry the total and page
+// number, cursor pages the cursor of the next page, nil after the last one.
+type ResultPage struct {
+	Results    []models.SearchResult `json:"results"`
+	Total      int64                 `json:"total"`
+	Page       int                   `json:"page"`
+	PageSize   int                   `json:"page_size"`
+	NextCursor *uint64               `json:"next_cursor"`
+}
+
+// Results returns a page of the search results matching query
+func (c *Client) Results(ctx context.Context, query ResultQuery) (*ResultPage, error) {
+	var page ResultPage
+	if err := c.do(ctx, http.MethodGet, "/results?"+query.values().Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// UpdateResultStatus sets the triage status of a result and returns it
+func (c *Client) UpdateResultStatus(ctx context.Context, id uint, status string) (*models.SearchResult, error) {
+	var result models.SearchResult
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/results/%d", id), map[string]string{"status": status}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// BatchUpdateResultStatus sets the triage status of several results at once
+func (c *Client) BatchUpdateResultStatus(ctx context.Context, ids []uint, status string) error {
+	return c.do(ctx, http.MethodPost, "/results/batch", map[string]interface{}{"ids": ids, "status": status}, nil)
+}
+
+// SnoozeResult hides a result from the pending queue until the given time, at
+// most a year ahead
+func (c *Client) SnoozeResult(ctx context.Context, id uint, until time.Time) (*models.SearchResult, error) {
+	var result models.SearchResult
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/results/%d/snooze", id), map[string]time.Time{"until": until}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UnsnoozeResult ends the snooze of a result right away
+func (c *Client) UnsnoozeResult(ctx context.Context, id uint) (*models.SearchResult, error) {
+	var result models.SearchResult
+	if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/results/%d/snooze", id), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github-monitor/db/models"
+)
+
+// Rules returns the monitor rules of the project
+func (c *Client) Rules(ctx context.Context) ([]models.MonitorRule, error) {
+	var rules []models.MonitorRule
+	err := c.do(ctx, http.MethodGet, "/rules", nil, &rules)
+	return rules, err
+}
+
+// Rule returns a monitor rule
+func (c *Client) Rule(ctx context.Context, id uint) (*models.MonitorRule, error) {
+	var rule models.MonitorRule
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/rules/%d", id), nil, &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateRule creates a monitor rule in the project and returns it as stored
+func (c *Client) CreateRule(ctx context.Context, rule *models.MonitorRule) (*models.MonitorRule, error) {
+	var created models.MonitorRule
+	if err := c.do(ctx, http.MethodPost, "/rules", rule, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateRule replaces the monitor rule with the ID of rule and returns it as stored
+func (c *Client) UpdateRule(ctx context.Context, rule *models.MonitorRule) (*models.MonitorRule, error) {
+	var updated models.MonitorRule
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/rules/%d", rule.ID), rule, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteRule deletes a monitor rule
+func (c *Client) DeleteRule(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/rules/%d", id), nil, nil)
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github-monitor/db/models"
+)
+
+// TokenStats is the state of a GitHub token in the search pool
+type TokenStats struct {
+	Index         int       `json:"index"`
+	Group         string    `json:"group,omitempty"` // github.token_groups entry, empty for the shared tokens
+	IsAvailable   bool      `json:"is_available"`
+	LastChecked   time.Time `json:"last_checked"`
+	RateLimit     int       `json:"rate_limit"`
+	RateRemaining int       `json:"rate_remaining"`
+	RateReset     time.Time `json:"rate_reset"`
+}
+
+// Tokens returns the GitHub tokens, admins only
+func (c *Client) Tokens(ctx context.Context) ([]models.GitHubToken, error) {
+	var tokens []models.GitHubToken
+	err := c.do(ctx, http.MethodGet, "/tokens", nil, &tokens)
+	return tokens, err
+}
+
+// CreateToken adds a GitHub token to the pool, admins only
+func (c *Client) CreateToken(ctx context.Context, token *models.GitHubToken) (*models.GitHubToken, error) {
+	var created models.GitHubToken
+	if err := c.do(ctx, http.MethodPost, "/tokens", token, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// DeleteToken removes a GitHub token, admins only
+func (c *Client) DeleteToken(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/tokens/%d", id), nil, nil)
+}
+
+// TokenStats returns the rate limits and availability of the pooled tokens
+func (c *Client) TokenStats(ctx context.Context) ([]TokenStats, error) {
+	var stats []TokenStats
+	err := c.do(ctx, http.MethodGet, "/tokens/stats", nil, &stats)
+	return stats, err
+}
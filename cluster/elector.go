@@ -0,0 +1,189 @@
+// Package cluster elects a leader among the instances sharing a database. Only the
+// leader runs scheduled scans and background jobs, so replicas behind a load
+// balancer don't scan or notify twice; every instance serves the API.
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// leaseName is the row the leader holds
+const leaseName = "leader"
+
+// Elector takes and renews the leader lease, running onElected when this instance
+// becomes leader and onDeposed when it loses the lease
+type Elector struct {
+	db        *gorm.DB
+	id        string
+	ttl       time.Duration
+	onElected func()
+	onDeposed func()
+	leader    bool
+	mu        sync.RWMutex
+	stopChan  chan struct{}
+	done      chan struct{}
+}
+
+// NewElector creates an elector for the instances sharing database
+func NewElector(database *gorm.DB, cfg *config.ClusterConfig, onElected, onDeposed func()) (*Elector, error) {
+	ttl, err := time.ParseDuration(cfg.LeaseTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lease ttl: %w", err)
+	}
+
+	id := cfg.InstanceID
+	if id == "" {
+		id = defaultInstanceID()
+	}
+
+	return &Elector{
+		db:        database,
+		id:        id,
+		ttl:       ttl,
+		onElected: onElected,
+		onDeposed: onDeposed,
+		stopChan:  make(chan struct{}),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// defaultInstanceID is unique per process even when containers share a hostname
+func defaultInstanceID() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// ID returns the id this instance holds the lease under
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// IsLeader reports whether this instance currently holds the lease
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Leader returns the id of the instance holding the lease, "" when it has expired
+func (e *Elector) Leader(ctx context.Context) (string, error) {
+	var lease models.Lease
+	err := e.db.WithContext(ctx).Where("name = ? AND expires_at > ?", leaseName, time.Now()).Limit(1).Find(&lease).Error
+	return lease.Holder, err
+}
+
+// Start campaigns for the lease in the background
+func (e *Elector) Start() {
+	go e.run()
+	log.Printf("Cluster member %s started, lease ttl %s", e.id, e.ttl)
+}
+
+// Stop steps down, running onDeposed if this instance was leader, and hands the
+// lease over right away instead of letting it expire
+func (e *Elector) Stop() {
+	close(e.stopChan)
+	<-e.done
+}
+
+func (e *Elector) run() {
+	defer close(e.done)
+
+	// Renew well within the ttl so a slow database doesn't cost the lease
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		e.campaign()
+
+		select {
+		case <-ticker.C:
+		case <-e.stopChan:
+			if e.IsLeader() {
+				e.setLeader(false)
+				e.release()
+			}
+			return
+		}
+	}
+}
+
+// campaign takes or renews the lease and applies the outcome
+func (e *Elector) campaign() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.ttl/3)
+	defer cancel()
+
+	held, err := e.acquire(ctx)
+	if err != nil {
+		// Without a renewal the lease may expire and be taken, so stop leading now
+		log.Printf("Failed to renew leader lease: %v", err)
+		held = false
+	}
+	if held != e.IsLeader() {
+		e.setLeader(held)
+	}
+}
+
+// acquire renews the lease if this instance holds it, takes it if it expired, or
+// creates it if no instance ever did, and reports whether the lease is ours
+func (e *Elector) acquire(ctx context.Context) (bool, error) {
+	now := time.Now()
+	expires := now.Add(e.ttl)
+
+	update := e.db.WithContext(ctx).Model(&models.Lease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", leaseName, e.id, now).
+		Updates(map[string]interface{}{"holder": e.id, "expires_at": expires})
+	if update.Error != nil {
+		return false, update.Error
+	}
+	if update.RowsAffected > 0 {
+		return true, nil
+	}
+
+	create := e.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Lease{Name: leaseName, Holder: e.id, ExpiresAt: expires})
+	if create.Error != nil {
+		return false, create.Error
+	}
+	return create.RowsAffected > 0, nil
+}
+
+// release expires the lease so another instance takes over on its next campaign
+func (e *Elector) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := e.db.WithContext(ctx).Model(&models.Lease{}).
+		Where("name = ? AND holder = ?", leaseName, e.id).
+		Update("expires_at", time.Now().Add(-time.Second)).Error
+	if err != nil {
+		log.Printf("Failed to release leader lease: %v", err)
+	}
+}
+
+func (e *Elector) setLeader(leader bool) {
+	e.mu.Lock()
+	e.leader = leader
+	e.mu.Unlock()
+
+	if leader {
+		log.Printf("Instance %s is now the cluster leader", e.id)
+		e.onElected()
+	} else {
+		log.Printf("Instance %s is no longer the cluster leader", e.id)
+		e.onDeposed()
+	}
+}
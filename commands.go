@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github-monitor/backup"
+	"github-monitor/config"
+	"github-monitor/db"
+	"github-monitor/db/models"
+	"github-monitor/dockerhub"
+	"github-monitor/github"
+	"github-monitor/monitor"
+	"github-monitor/postman"
+	"github-monitor/registry"
+	"github-monitor/report"
+	"github-monitor/repository"
+	"github-monitor/settings"
+	"github-monitor/storage"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm/logger"
+)
+
+// exportPageSize is how many results are read per query when exporting
+const exportPageSize = 500
+
+func newRootCmd() *cobra.Command {
+	var configPath string
+
+	root := &cobra.Command{
+		Use:          "github-monitor",
+		Short:        "Monitor GitHub code search for leaked secrets",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		// Without a subcommand the server starts, as before the CLI existed
+		Run: func(cmd *cobra.Command, args []string) {
+			serve(configPath)
+		},
+	}
+	root.PersistentFlags().StringVarP(&configPath, "config", "c", "config.yaml", "path to the config file")
+
+	root.AddCommand(
+		newServeCmd(&configPath),
+		newScanOnceCmd(&configPath),
+		newExportCmd(&configPath),
+		newImportCmd(&configPath),
+		newConfigCmd(&configPath),
+	)
+	return root
+}
+
+func newServeCmd(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Run the API server and the monitor",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			serve(*configPath)
+		},
+	}
+}
+
+func newScanOnceCmd(configPath *string) *cobra.Command {
+	var ruleID uint
+
+	cmd := &cobra.Command{
+		Use:   "scan-once",
+		Short: "Scan once and exit, for cron driven scans",
+		Long:  "Scans a single rule, or every active rule without --rule, records the results and sends notifications like the monitor does. Exits non-zero when a scan fails.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repos, closeDB, err := openDatabase(*configPath)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			if err := settings.Load(repos.Settings); err != nil {
+				return fmt.Errorf("failed to load runtime settings: %w", err)
+			}
+
+			tokenPool, err := newTokenPool()
+			if err != nil {
+				return fmt.Errorf("failed to initialize token pool: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			tokenPool.RefreshAllTokens(ctx)
+
+			monitorService := monitor.NewMonitorService(repos, github.NewSearchService(tokenPool), 0)
+			monitorService.SetConcurrency(config.AppConfig.Monitor.Concurrency)
+			monitorService.SetDelays(monitorDelays(config.AppConfig.Monitor))
+			monitorService.SetResultChanges(config.AppConfig.Monitor.ResultChanges)
+			monitorService.SetMaxNewResults(config.AppConfig.Monitor.MaxNewResults)
+			monitorService.SetDryRun(config.AppConfig.Monitor.DryRun)
+			monitorService.SetContextLines(config.AppConfig.Monitor.ContextLines)
+			monitorService.SetIncidentGrouping(config.AppConfig.Monitor.IncidentGroupBy)
+			github.SetSnippetLength(config.AppConfig.Monitor.SnippetLength)
+			github.SetMaxContentSize(int64(config.AppConfig.Monitor.MaxContentKB) << 10)
+			detector, err := github.NewInfraDetector(config.AppConfig.Monitor.InternalCIDRs, config.AppConfig.Monitor.InternalDomains)
+			if err != nil {
+				return err
+			}
+			monitorService.SetInfraDetector(detector)
+			if config.AppConfig.DockerHub.Enabled {
+				monitorService.SetDockerHub(dockerhub.NewClient(config.AppConfig.DockerHub.MaxPages))
+			}
+			if config.AppConfig.Postman.Enabled {
+				monitorService.SetPostman(postman.NewClient(config.AppConfig.Postman.MaxPages))
+			}
+			if config.AppConfig.Registry.Enabled {
+				monitorService.SetRegistryWatch(registry.NewClient(), registryWatch(config.AppConfig.Registry))
+			}
+			if config.AppConfig.Typosquat.Enabled {
+				monitorService.SetTyposquatWatch(typosquatWatch(config.AppConfig.Typosquat))
+			}
+			if config.AppConfig.ForkWatch.Enabled {
+				monitorService.SetForkWatch(forkWatch(config.AppConfig.ForkWatch))
+			}
+			if config.AppConfig.GistWatch.Enabled {
+				monitorService.SetGistWatch(gistWatch(config.AppConfig.GistWatch))
+			}
+			if config.AppConfig.Storage.Enabled {
+				store, err := storage.New(&config.AppConfig.Storage)
+				if err != nil {
+					return err
+				}
+				monitorService.SetStore(store)
+			}
+
+			return monitorService.ScanOnce(ctx, ruleID)
+		},
+	}
+	cmd.Flags().UintVar(&ruleID, "rule", 0, "ID of the rule to scan, all active rules when omitted")
+	return cmd
+}
+
+func newExportCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export data from the database",
+	}
+
+	var (
+		format string
+		output string
+		filter repository.ResultFilter
+	)
+	results := &cobra.Command{
+		Use:   "results",
+		Short: "Export search results as CSV or JSON, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "csv" && format != "json" {
+				return fmt.Errorf("--format must be csv or json")
+			}
+			if filter.Status != "" && !models.ValidResultStatuses[filter.Status] {
+				return fmt.Errorf("--status must be one of: pending confirmed false_positive resolved")
+			}
+			if filter.Severity != "" && !models.ValidSeverities[filter.Severity] {
+				return fmt.Errorf("--severity must be one of: critical high medium low info")
+			}
+			if filter.Source != "" && !models.ValidResultSources[filter.Source] && !models.ValidIngestSource(filter.Source) {
+				return fmt.Errorf("--source must be github, dockerhub, postman, npm, pypi or the source of ingested findings")
+			}
+			for _, fileType := range filter.FileTypes {
+				if !github.ValidFileTypes[fileType] {
+					return fmt.Errorf("--file-type %q is not a known file type", fileType)
+				}
+			}
+
+			repos, closeDB, err := openDatabase(*configPath)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			return writeOutput(output, func(w io.Writer) error {
+				return exportResults(cmd.Context(), repos.Results, filter, format, w)
+			})
+		},
+	}
+	results.Flags().StringVar(&format, "format", "csv", "output format, csv or json")
+	results.Flags().StringVarP(&output, "output", "o", "-", "file to write, - for stdout")
+	results.Flags().UintVar(&filter.RuleID, "rule", 0, "only results of this rule")
+	results.Flags().StringVar(&filter.Status, "status", "", "only results with this status")
+	results.Flags().StringVar(&filter.Severity, "severity", "", "only results with this severity")
+	results.Flags().StringVar(&filter.Source, "source", "", "only results from this source, github, dockerhub, postman, npm or pypi")
+	results.Flags().StringSliceVar(&filter.FileTypes, "file-type", nil, "only results with one of these file types, such as env,key,json")
+
+	archive := &cobra.Command{
+		Use:   "backup <file.zip>",
+		Short: "Export rules, whitelist, results and history to a backup archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repos, closeDB, err := openDatabase(*configPath)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			var manifest *backup.Manifest
+			err = writeOutput(args[0], func(w io.Writer) error {
+				manifest, err = backup.Export(repos.DB, w)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			log.Printf("Exported %v to %s", manifest.Counts, args[0])
+			return nil
+		},
+	}
+
+	cmd.AddCommand(results, archive)
+	return cmd
+}
+
+func newImportCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import data into the database",
+	}
+
+	archive := &cobra.Command{
+		Use:   "backup <file.zip>",
+		Short: "Restore a backup archive, rows with the same ID are overwritten",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repos, closeDB, err := openDatabase(*configPath)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			info, err := f.Stat()
+			if err != nil {
+				return err
+			}
+
+			manifest, err := backup.Import(repos.DB, f, info.Size())
+			if err != nil {
+				return err
+			}
+
+			log.Printf("Imported %v from %s", manifest.Counts, args[0])
+			return nil
+		},
+	}
+
+	cmd.AddCommand(archive)
+	return cmd
+}
+
+func newConfigCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	validate := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the config file and environment overrides without starting anything",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.LoadConfig(*configPath); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", *configPath)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(validate)
+	return cmd
+}
+
+// openDatabase loads the config, connects and migrates for the one-shot commands.
+// It returns repositories on the connection and a function closing it.
+func openDatabase(configPath string) (*repository.Repositories, func(), error) {
+	// stdout is reserved for command output such as exported results
+	logger.Default = logger.New(log.New(os.Stderr, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold: 200 * time.Millisecond,
+		LogLevel:      logger.Warn,
+	})
+
+	if err := config.LoadConfig(configPath); err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := db.InitDB(&config.AppConfig.Database); err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	if err := db.AutoMigrate(); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return repository.NewGormRepositories(db.GetDB()), func() { db.Close() }, nil
+}
+
+// writeOutput runs write against the named file, or stdout for "-". A partially
+// written file is removed when write fails.
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// exportResults pages through the results with the keyset cursor so large tables stream
+func exportResults(ctx context.Context, results repository.ResultRepo, filter repository.ResultFilter, format string, w io.Writer) error {
+	var (
+		csvWriter *csv.Writer
+		written   int
+	)
+
+	if format == "csv" {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write([]string{"id", "rule_id", "rule_name", "source", "severity", "status", "repo_full_name", "file_path", "html_url", "matched_keywords", "score", "created_at"}); err != nil {
+			return err
+		}
+	} else if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	var after uint64
+	for {
+		page, err := results.ListAfter(ctx, filter, after, exportPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range page {
+			if csvWriter != nil {
+				err := csvWriter.Write([]string{
+					strconv.FormatUint(uint64(result.ID), 10),
+					strconv.FormatUint(uint64(result.RuleID), 10),
+					report.CSVCell(result.Rule.Name),
+					result.Source,
+					result.Severity,
+					result.Status,
+					report.CSVCell(result.RepoFullName),
+					report.CSVCell(result.FilePath),
+					report.CSVCell(result.HTMLURL),
+					report.CSVCell(result.MatchedKeywords),
+					strconv.FormatFloat(result.Score, 'f', -1, 64),
+					result.CreatedAt.Format(time.RFC3339),
+				})
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Written as one array element at a time so the whole export never sits in memory
+			separator := "\n  "
+			if written > 0 {
+				separator = ",\n  "
+			}
+			if _, err := io.WriteString(w, separator); err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(result, "  ", "  ")
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			written++
+		}
+
+		if len(page) < exportPageSize {
+			break
+		}
+		after = uint64(page[len(page)-1].ID)
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
@@ -1,34 +1,108 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github-monitor/i18n"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
+	Timezone string         `mapstructure:"timezone"` // IANA name such as Europe/Berlin that schedules and report dates follow, empty for the server's
+	Locale   string         `mapstructure:"locale"`   // en or zh, the language of notifications and API errors, channels may set their own
 	Server   ServerConfig   `mapstructure:"server"`
 	Database DatabaseConfig `mapstructure:"database"`
 	GitHub   GitHubConfig   `mapstructure:"github"`
 	Monitor  MonitorConfig  `mapstructure:"monitor"`
 	Auth     AuthConfig     `mapstructure:"auth"`
+	Notify   NotifyConfig   `mapstructure:"notify"`
+	Report   ReportConfig   `mapstructure:"report"`
+	DockerHub DockerHubConfig `mapstructure:"dockerhub"`
+	Postman  PostmanConfig    `mapstructure:"postman"`
+	Registry RegistryConfig   `mapstructure:"registry"`
+	Typosquat TyposquatConfig `mapstructure:"typosquat"`
+	ForkWatch ForkWatchConfig `mapstructure:"fork_watch"`
+	GistWatch GistWatchConfig `mapstructure:"gist_watch"`
+	Elasticsearch ElasticsearchConfig `mapstructure:"elasticsearch"`
+	Storage  StorageConfig    `mapstructure:"storage"`
+	DefectDojo DefectDojoConfig `mapstructure:"defectdojo"`
+	Classifier ClassifierConfig `mapstructure:"classifier"`
+	Redis    RedisConfig      `mapstructure:"redis"`
+	Cluster  ClusterConfig    `mapstructure:"cluster"`
+	Sentry   SentryConfig     `mapstructure:"sentry"`
+	RuleSync RuleSyncConfig   `mapstructure:"rule_sync"`
+	SLA      SLAConfig        `mapstructure:"sla"`
+	Ingest   IngestConfig     `mapstructure:"ingest"`
+	Scrape   ScrapeConfig     `mapstructure:"scrape"`
+	Redaction RedactionConfig `mapstructure:"redaction"`
+	RuleWebhooks RuleWebhooksConfig `mapstructure:"rule_webhooks"`
+	Purge    PurgeConfig      `mapstructure:"purge"`
 }
 
 type ServerConfig struct {
-	Port int `mapstructure:"port"`
+	Port            int    `mapstructure:"port"`
+	ShutdownTimeout string          `mapstructure:"shutdown_timeout"` // how long to drain in-flight requests
+	RateLimit       RateLimitConfig `mapstructure:"rate_limit"`
+	TrustedProxies  []string        `mapstructure:"trusted_proxies"` // IPs or CIDRs whose X-Forwarded-For is believed, none by default
+	AdminAllowlist  []string        `mapstructure:"admin_allowlist"` // IPs or CIDRs allowed to call admin endpoints, empty allows any
+	MaxBodyKB       int             `mapstructure:"max_body_kb"`      // request body limit of the API
+	MaxBulkBodyMB   int             `mapstructure:"max_bulk_body_mb"` // request body limit of backup restores, result ingestion and webhooks
+	MaxJSONDepth    int             `mapstructure:"max_json_depth"`   // how deeply JSON bodies may nest, 0 disables the check
+	CompressMinBytes int            `mapstructure:"compress_min_bytes"` // responses at least this large are gzipped, 0 disables compression
+	TLS             TLSConfig       `mapstructure:"tls"`
+	GRPC            GRPCConfig      `mapstructure:"grpc"`
+}
+
+type GRPCConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Port    int  `mapstructure:"port"` // uses the server TLS certificate when TLS is enabled
+}
+
+type TLSConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	CertFile         string `mapstructure:"cert_file"` // reloaded automatically when the file changes
+	KeyFile          string `mapstructure:"key_file"`
+	RedirectHTTPPort int    `mapstructure:"redirect_http_port"` // plain HTTP port redirecting to HTTPS, 0 disables
+}
+
+type RateLimitConfig struct {
+	Enabled            bool `mapstructure:"enabled"`
+	RequestsPerMinute  int  `mapstructure:"requests_per_minute"`  // per IP on public routes, per session on the API
+	Burst              int  `mapstructure:"burst"`
+	LoginPerMinute     int  `mapstructure:"login_per_minute"`     // per IP on login and token refresh
+	ExpensivePerMinute int  `mapstructure:"expensive_per_minute"` // per session on large list endpoints
 }
 
 type DatabaseConfig struct {
+	Driver   string `mapstructure:"driver"` // mysql, postgres or sqlite
 	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
+	Port     int    `mapstructure:"port"` // defaults to 3306 for mysql and 5432 for postgres
 	User     string `mapstructure:"user"`
 	Password string `mapstructure:"password"`
 	Database string `mapstructure:"database"`
+	SSLMode  string `mapstructure:"ssl_mode"` // postgres only
+	Path     string `mapstructure:"path"`     // sqlite only, the database file
+
+	// Connection pool, ignored for sqlite which always uses a single connection
+	MaxOpenConns    int    `mapstructure:"max_open_conns"`
+	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime string `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime string `mapstructure:"conn_max_idle_time"`
+	PingInterval    string `mapstructure:"ping_interval"` // background health check, stale connections are dropped on failure
 }
 
 type GitHubConfig struct {
 	Tokens              []string `mapstructure:"tokens"`
+	TokenGroups         map[string][]string `mapstructure:"token_groups"` // dedicated tokens, used by the rules assigned to the group before the shared tokens
 	RateLimitThreshold  int      `mapstructure:"rate_limit_threshold"`
 	RequestInterval     string   `mapstructure:"request_interval"`
 	ProxyEnabled        bool     `mapstructure:"proxy_enabled"`
@@ -36,54 +110,544 @@ type GitHubConfig struct {
 	ProxyType           string   `mapstructure:"proxy_type"` // http, https, socks5
 	ProxyUsername       string   `mapstructure:"proxy_username"`
 	ProxyPassword       string   `mapstructure:"proxy_password"`
+	WebhookSecret       string   `mapstructure:"webhook_secret"` // enables /webhooks/github, HMAC key of the GitHub webhooks
+	WebhookOrgs         []string `mapstructure:"webhook_orgs"`   // owners allowed to send pushes, empty allows any signed payload
+	HTTP                GitHubHTTPConfig `mapstructure:"http"`
+}
+
+type GitHubHTTPConfig struct {
+	ConnectTimeout string `mapstructure:"connect_timeout"` // dialing, proxy included, and the TLS handshake
+	ReadTimeout    string `mapstructure:"read_timeout"`    // waiting for the response headers
+	MaxIdleConns   int    `mapstructure:"max_idle_conns"`  // per token
+	MaxRetries     int    `mapstructure:"max_retries"`     // retries of GET requests failing on the network or with 502, 503 or 504
+	RetryWait      string `mapstructure:"retry_wait"`      // before the first retry, doubled for each next one with jitter
 }
 
 type MonitorConfig struct {
 	Enabled      bool   `mapstructure:"enabled"`
 	ScanInterval string `mapstructure:"scan_interval"`
+	Concurrency  int    `mapstructure:"concurrency"` // number of rules scanned in parallel
+	PageDelay    string `mapstructure:"page_delay"`  // wait between the result pages of a search, rules may set their own
+	RuleDelay    string `mapstructure:"rule_delay"`  // wait after a rule's scan before the worker takes the next rule, rules may set their own
+	KnownCacheSize int  `mapstructure:"known_cache_size"` // files of recorded results kept in memory for dedup, 0 disables
+	SnippetLength  int  `mapstructure:"snippet_length"`   // bytes of each snippet and search match kept
+	ContextLines   int  `mapstructure:"context_lines"`    // lines fetched around each match of a new result, 0 disables
+	InternalCIDRs   []string `mapstructure:"internal_cidrs"`   // networks whose addresses in a new result raise it to high severity
+	InternalDomains []string `mapstructure:"internal_domains"` // DNS zones whose hostnames in a new result raise it to high severity
+	SearchBudget    SearchBudgetConfig `mapstructure:"search_budget"`
+	IncidentGroupBy []string `mapstructure:"incident_group_by"` // fingerprint, repository, owner: what new results are grouped into incidents by, in order, empty disables
+	RerunIncomplete bool     `mapstructure:"rerun_incomplete"`  // search pages GitHub answered with incomplete results again in the next scan cycles
+	ScanOnCreate    bool     `mapstructure:"scan_on_create"`    // scan new and re-activated rules right away instead of in the next scan cycle
+	AutoPause       AutoPauseConfig `mapstructure:"auto_pause"`
+	ResultChanges   string   `mapstructure:"result_changes"`    // off, record or reopen: what a scan finding the file of a result again with other content does
+	MaxNewResults   int      `mapstructure:"max_new_results"`   // new results a scan of a rule may record before the rule is held for review, rules may set their own, 0 disables
+	DryRun          bool     `mapstructure:"dry_run"`           // scans stage their new results instead of recording them and notify nobody
+	ExhaustionWarning string `mapstructure:"exhaustion_warning"` // warn when the tokens are forecast to run out within this, before their limit resets, 0 disables
+	MaxContentKB      int    `mapstructure:"max_content_kb"`     // files larger than this aren't fetched for context, verification or evidence, 0 disables
+}
+
+type AutoPauseConfig struct {
+	MaxFailures int `mapstructure:"max_failures"` // consecutive failed scans after which a rule is deactivated, 0 disables
+	IdleDays    int `mapstructure:"idle_days"`    // days without new results after which a rule is deactivated, 0 disables
+}
+
+type SearchBudgetConfig struct {
+	Enabled              bool `mapstructure:"enabled"`                 // split the hourly code search quota between rules by severity
+	RequestsPerTokenHour int  `mapstructure:"requests_per_token_hour"` // code searches each token is allowed per hour
+}
+
+type DockerHubConfig struct {
+	Enabled  bool `mapstructure:"enabled"`   // search Docker Hub with the keywords of every rule
+	MaxPages int  `mapstructure:"max_pages"` // pages of 100 repositories read per rule
+}
+
+type PostmanConfig struct {
+	Enabled  bool `mapstructure:"enabled"`   // search Postman public workspaces with the keywords of every rule
+	MaxPages int  `mapstructure:"max_pages"` // pages of 100 collections and requests read per rule
+}
+
+type RegistryConfig struct {
+	Enabled    bool     `mapstructure:"enabled"`    // look up internal package names on public registries
+	Packages   []string `mapstructure:"packages"`   // internal package names, e.g. @acme/billing or acme-utils
+	Registries []string `mapstructure:"registries"` // npm, pypi
+	Severity   string   `mapstructure:"severity"`   // severity of the results
+}
+
+type TyposquatConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`      // search repository names for lookalikes of our names
+	Names       []string `mapstructure:"names"`        // organization and product names, e.g. acme or acmepay
+	Owners      []string `mapstructure:"owners"`       // our own GitHub organizations and users, never flagged
+	MaxVariants int      `mapstructure:"max_variants"` // lookalikes searched per name
+	Severity    string   `mapstructure:"severity"`     // severity of the results
+	Interval    string   `mapstructure:"interval"`     // between searches
+}
+
+type ForkWatchConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`      // check forks of our repositories for secrets and suspicious changes
+	Repositories []string `mapstructure:"repositories"` // our public repositories, owner/name
+	MaxForks     int      `mapstructure:"max_forks"`    // newest forks checked per repository
+	Severity     string   `mapstructure:"severity"`     // severity of suspicious changes
+	Interval     string   `mapstructure:"interval"`     // between checks
+}
+
+type GistWatchConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`   // check the public gists of tracked users for secrets and rule keywords
+	Users    []string `mapstructure:"users"`     // GitHub logins, such as employees and contractors
+	MaxGists int      `mapstructure:"max_gists"` // most recently created gists checked per user
+	Severity string   `mapstructure:"severity"`  // severity of pasted secrets
+	Interval string   `mapstructure:"interval"`  // between checks
+}
+
+type ElasticsearchConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`        // index new and updated results
+	URL           string `mapstructure:"url"`            // Elasticsearch or OpenSearch endpoint
+	Username      string `mapstructure:"username"`
+	Password      string `mapstructure:"password"`
+	APIKey        string `mapstructure:"api_key"`        // used instead of username and password when set
+	Index         string `mapstructure:"index"`
+	TemplateFile  string `mapstructure:"template_file"`  // JSON index template replacing the built-in one
+	FlushInterval string `mapstructure:"flush_interval"` // how often pending results are sent
+}
+
+type StorageConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`           // keep evidence and reports in object storage
+	Provider        string `mapstructure:"provider"`          // s3, minio or oss
+	Endpoint        string `mapstructure:"endpoint"`          // host[:port], derived from the region for s3 and oss
+	Region          string `mapstructure:"region"`
+	Bucket          string `mapstructure:"bucket"`
+	AccessKey       string `mapstructure:"access_key"`
+	SecretKey       string `mapstructure:"secret_key"`
+	UseSSL          bool   `mapstructure:"use_ssl"`
+	Prefix          string `mapstructure:"prefix"`            // key prefix when the bucket is shared
+	SignedURLExpiry string `mapstructure:"signed_url_expiry"` // lifetime of download links handed out by the API
+}
+
+type DefectDojoConfig struct {
+	Enabled      bool                `mapstructure:"enabled"`       // push confirmed results and pull their remediation status
+	URL          string              `mapstructure:"url"`           // e.g. https://defectdojo.example.com
+	APIKey       string              `mapstructure:"api_key"`       // API v2 key
+	EngagementID int                 `mapstructure:"engagement_id"` // engagement for rules without a mapping
+	Engagements  []DefectDojoMapping `mapstructure:"engagements"`   // per rule engagements, which pick the product
+	TestType     string              `mapstructure:"test_type"`     // test type findings are reported under, created if missing
+	SyncInterval string              `mapstructure:"sync_interval"`
+}
+
+type ClusterConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`     // elect a leader that alone runs scheduled scans and jobs
+	InstanceID string `mapstructure:"instance_id"` // defaults to hostname-pid
+	LeaseTTL   string `mapstructure:"lease_ttl"`   // how long a leader that stopped renewing stays in charge
+}
+
+type SentryConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`     // report errors and recovered panics to Sentry
+	DSN         string  `mapstructure:"dsn"`
+	Environment string  `mapstructure:"environment"` // e.g. production, staging
+	Release     string  `mapstructure:"release"`
+	SampleRate  float64 `mapstructure:"sample_rate"` // share of errors sent, 0-1
+}
+
+type RuleSyncConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`        // manage rules and whitelist entries from YAML files in a Git repository
+	Repository    string `mapstructure:"repository"`     // clone URL, e.g. https://github.com/example/detections.git
+	Branch        string `mapstructure:"branch"`
+	Path          string `mapstructure:"path"`           // directory of the definitions in the repository
+	Token         string `mapstructure:"token"`          // HTTPS access token for private repositories
+	Interval      string `mapstructure:"interval"`       // how often the repository is polled
+	Drift         string `mapstructure:"drift"`          // revert or report changes made outside the repository
+	WebhookSecret string `mapstructure:"webhook_secret"` // enables /webhooks/rules, HMAC key of push webhooks
+	WorkDir       string `mapstructure:"work_dir"`       // local checkout
+}
+
+type RedisConfig struct {
+	Enabled           bool   `mapstructure:"enabled"`             // share caches, scan claims and notification dedup between instances
+	Addr              string `mapstructure:"addr"`                // host:port
+	Username          string `mapstructure:"username"`
+	Password          string `mapstructure:"password"`
+	DB                int    `mapstructure:"db"`
+	TLS               bool   `mapstructure:"tls"`
+	KeyPrefix         string `mapstructure:"key_prefix"`          // prepended to every key when the server is shared
+	ETagTTL           string `mapstructure:"etag_ttl"`            // how long GitHub responses are kept for conditional requests
+	NotifyDedupWindow string `mapstructure:"notify_dedup_window"` // a file is notified at most once per rule within this window
+}
+
+type ClassifierConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`    // suggest a verdict for new pending results with an LLM
+	BaseURL   string `mapstructure:"base_url"`   // OpenAI-compatible API, e.g. https://api.openai.com/v1
+	APIKey    string `mapstructure:"api_key"`    // sent as bearer token, optional for local servers
+	Model     string `mapstructure:"model"`
+	Interval  string `mapstructure:"interval"`   // how often unclassified results are picked up
+	BatchSize int    `mapstructure:"batch_size"` // results classified per run
+	Timeout   string `mapstructure:"timeout"`    // per completion request
+}
+
+type IngestConfig struct {
+	Enabled     bool        `mapstructure:"enabled"`      // accept findings of external tools on /api/v1/results/ingest
+	Keys        []IngestKey `mapstructure:"keys"`         // API keys of the tools
+	MaxFindings int         `mapstructure:"max_findings"` // findings accepted per request
+}
+
+type IngestKey struct {
+	Name      string `mapstructure:"name"`       // identifies the tool in logs
+	Key       string `mapstructure:"key"`        // sent as X-API-Key or bearer token
+	ProjectID uint   `mapstructure:"project_id"` // project the findings are recorded in, defaults to the default project
+}
+
+type ScrapeConfig struct {
+	Keys []ScrapeKey `mapstructure:"keys"` // keys of monitoring systems, none disables the scrape endpoints
+}
+
+type ScrapeKey struct {
+	Name string `mapstructure:"name"` // identifies the scraper in logs
+	Key  string `mapstructure:"key"`  // sent as X-API-Key or bearer token, only reads health and metrics
+}
+
+type RedactionConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`      // mask secret-looking values in result listings, notifications are always masked
+	RevealRoles []string `mapstructure:"reveal_roles"` // project roles that may reveal the full result
+}
+
+type RuleWebhooksConfig struct {
+	Timeout     string `mapstructure:"timeout"`      // per delivery attempt
+	MaxAttempts int    `mapstructure:"max_attempts"` // attempts before a delivery is given up, with backoff from 1m up to 6h between them
+}
+
+type PurgeConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`  // hard-delete soft-deleted records
+	MaxAge   string `mapstructure:"max_age"`  // how long deleted records are kept, e.g. 720h
+	Interval string `mapstructure:"interval"` // how often they are purged
+}
+
+type SLAConfig struct {
+	Enabled       bool                 `mapstructure:"enabled"`        // track triage and remediation deadlines of open results
+	Severities    map[string]SLATarget `mapstructure:"severities"`     // severities without an entry have no SLA
+	AtRisk        float64              `mapstructure:"at_risk"`        // share of the SLA elapsed after which a result is at risk, 0-1
+	CheckInterval string               `mapstructure:"check_interval"` // how often newly overdue results are notified
+}
+
+type SLATarget struct {
+	Triage    string `mapstructure:"triage"`    // pending results are triaged within this of being found, e.g. 4h, empty for none
+	Remediate string `mapstructure:"remediate"` // confirmed results are resolved within this of being found, empty for none
+}
+
+type DefectDojoMapping struct {
+	Rule         string `mapstructure:"rule"` // rule name
+	EngagementID int    `mapstructure:"engagement_id"`
+}
+
+type NotifyConfig struct {
+	Enabled      bool                `mapstructure:"enabled"`       // global switch for new-result notifications
+	DashboardURL string              `mapstructure:"dashboard_url"` // base URL used for links in notifications
+	Actions      NotifyActionsConfig `mapstructure:"actions"`
+	OwnerEmail   OwnerEmailConfig    `mapstructure:"owner_email"`
+	Translation  TranslationConfig   `mapstructure:"translation"`
+}
+
+// TranslationConfig is the service the snippets of notifications are translated with
+type TranslationConfig struct {
+	Provider       string `mapstructure:"provider"`        // deepl or libretranslate, empty disables translation
+	URL            string `mapstructure:"url"`             // API base URL, required for libretranslate, DeepL's free API by default
+	APIKey         string `mapstructure:"api_key"`         // required for deepl
+	TargetLanguage string `mapstructure:"target_language"` // language snippets are translated into
+	Timeout        string `mapstructure:"timeout"`         // notifications wait this long for a translation
+}
+
+// OwnerEmailConfig is the SMTP server rule owners are emailed through
+type OwnerEmailConfig struct {
+	SMTPHost string `mapstructure:"smtp_host"` // empty disables emailing rule owners
+	SMTPPort int    `mapstructure:"smtp_port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+type NotifyActionsConfig struct {
+	Enabled            bool   `mapstructure:"enabled"`              // triage buttons and links in notifications
+	BaseURL            string `mapstructure:"base_url"`             // public URL of this server, for the action links
+	Secret             string `mapstructure:"secret"`               // signs the action links
+	LinkExpiry         string `mapstructure:"link_expiry"`          // how long action links work
+	SlackSigningSecret string `mapstructure:"slack_signing_secret"` // verifies Slack button clicks, links are used without it
 }
 
 type AuthConfig struct {
 	Enabled    bool   `mapstructure:"enabled"`
 	Password   string `mapstructure:"password"`
 	JWTSecret  string `mapstructure:"jwt_secret"`
-	TokenExpiry string `mapstructure:"token_expiry"` // e.g., "24h", "7d"
+	TokenExpiry string `mapstructure:"token_expiry"` // e.g., "24h", "168h"
+	RefreshTokenExpiry string `mapstructure:"refresh_token_expiry"`
+	OIDC        OIDCConfig `mapstructure:"oidc"`
+}
+
+type OIDCConfig struct {
+	Enabled      bool              `mapstructure:"enabled"`
+	IssuerURL    string            `mapstructure:"issuer_url"`
+	ClientID     string            `mapstructure:"client_id"`
+	ClientSecret string            `mapstructure:"client_secret"`
+	RedirectURL  string            `mapstructure:"redirect_url"` // e.g., https://monitor.example.com/api/v1/auth/oidc/callback
+	Scopes       []string          `mapstructure:"scopes"`
+	GroupsClaim  string            `mapstructure:"groups_claim"`
+	RoleMapping  map[string]string `mapstructure:"role_mapping"` // IdP group -> role (admin, analyst, viewer)
+	DefaultRole  string            `mapstructure:"default_role"` // role for users without a mapped group, empty denies login
+	FrontendURL  string            `mapstructure:"frontend_url"` // where to send the browser after login
+}
+
+type ReportConfig struct {
+	Enabled    bool        `mapstructure:"enabled"`
+	Weekday    string      `mapstructure:"weekday"` // day the weekly report is sent, e.g. "monday"
+	Hour       int         `mapstructure:"hour"`    // hour of day (0-23) the report is sent
+	WebhookURL string      `mapstructure:"webhook_url"`
+	Email      EmailConfig `mapstructure:"email"`
+}
+
+type EmailConfig struct {
+	SMTPHost string   `mapstructure:"smtp_host"`
+	SMTPPort int      `mapstructure:"smtp_port"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
 }
 
 var AppConfig *Config
 
+// EnvPrefix prefixes the environment variables overriding config keys,
+// e.g. GHMON_DATABASE_PASSWORD for database.password
+const EnvPrefix = "GHMON"
+
 func LoadConfig(configPath string) error {
 	viper.SetConfigFile(configPath)
 	viper.SetConfigType("yaml")
 
+	viper.SetDefault("locale", "en")
 	viper.SetDefault("server.port", 8080)
-	viper.SetDefault("database.port", 3306)
+	viper.SetDefault("server.shutdown_timeout", "15s")
+	viper.SetDefault("server.max_body_kb", 1024)
+	viper.SetDefault("server.max_bulk_body_mb", 100)
+	viper.SetDefault("server.max_json_depth", 32)
+	viper.SetDefault("server.compress_min_bytes", 1024)
+	viper.SetDefault("server.rate_limit.enabled", true)
+	viper.SetDefault("server.rate_limit.requests_per_minute", 300)
+	viper.SetDefault("server.rate_limit.burst", 60)
+	viper.SetDefault("server.rate_limit.login_per_minute", 10)
+	viper.SetDefault("server.rate_limit.expensive_per_minute", 60)
+	viper.SetDefault("server.grpc.enabled", false)
+	viper.SetDefault("server.grpc.port", 9090)
+	viper.SetDefault("database.driver", "mysql")
+	viper.SetDefault("database.ssl_mode", "disable")
+	viper.SetDefault("database.path", "data/github_monitor.db")
+	viper.SetDefault("database.max_open_conns", 25)
+	viper.SetDefault("database.max_idle_conns", 10)
+	viper.SetDefault("database.conn_max_lifetime", "30m")
+	viper.SetDefault("database.conn_max_idle_time", "5m")
+	viper.SetDefault("database.ping_interval", "30s")
 	viper.SetDefault("github.rate_limit_threshold", 10)
 	viper.SetDefault("github.request_interval", "5s")
+	viper.SetDefault("github.http.connect_timeout", "10s")
+	viper.SetDefault("github.http.read_timeout", "60s")
+	viper.SetDefault("github.http.max_idle_conns", 100)
+	viper.SetDefault("github.http.max_retries", 2)
+	viper.SetDefault("github.http.retry_wait", "1s")
 	viper.SetDefault("monitor.enabled", true)
 	viper.SetDefault("monitor.scan_interval", "300s")
+	viper.SetDefault("monitor.concurrency", 1)
+	viper.SetDefault("monitor.known_cache_size", 500000)
+	viper.SetDefault("monitor.snippet_length", 500)
+	viper.SetDefault("monitor.context_lines", 0)
+	viper.SetDefault("monitor.incident_group_by", []string{"fingerprint", "repository"})
+	viper.SetDefault("monitor.rerun_incomplete", true)
+	viper.SetDefault("monitor.scan_on_create", true)
+	viper.SetDefault("monitor.result_changes", "record")
+	viper.SetDefault("monitor.exhaustion_warning", "1h")
+	viper.SetDefault("monitor.max_new_results", 0)
+	viper.SetDefault("monitor.dry_run", false)
+	viper.SetDefault("monitor.max_content_kb", 512)
+	viper.SetDefault("monitor.page_delay", "2s")
+	viper.SetDefault("monitor.rule_delay", "5s")
+	viper.SetDefault("monitor.auto_pause.max_failures", 0)
+	viper.SetDefault("monitor.auto_pause.idle_days", 0)
+	viper.SetDefault("monitor.search_budget.enabled", false)
+	viper.SetDefault("monitor.search_budget.requests_per_token_hour", 600)
+	viper.SetDefault("notify.enabled", false)
+	viper.SetDefault("notify.actions.enabled", false)
+	viper.SetDefault("notify.actions.link_expiry", "72h")
+	viper.SetDefault("notify.owner_email.smtp_port", 587)
+	viper.SetDefault("notify.translation.target_language", "en")
+	viper.SetDefault("notify.translation.timeout", "5s")
+	viper.SetDefault("dockerhub.enabled", false)
+	viper.SetDefault("dockerhub.max_pages", 1)
+	viper.SetDefault("postman.enabled", false)
+	viper.SetDefault("postman.max_pages", 1)
+	viper.SetDefault("elasticsearch.enabled", false)
+	viper.SetDefault("elasticsearch.index", "github-monitor-results")
+	viper.SetDefault("elasticsearch.flush_interval", "5s")
+	viper.SetDefault("storage.enabled", false)
+	viper.SetDefault("storage.provider", "s3")
+	viper.SetDefault("storage.use_ssl", true)
+	viper.SetDefault("storage.signed_url_expiry", "15m")
+	viper.SetDefault("defectdojo.enabled", false)
+	viper.SetDefault("defectdojo.test_type", "GitHub Monitor")
+	viper.SetDefault("defectdojo.sync_interval", "5m")
+	viper.SetDefault("cluster.enabled", false)
+	viper.SetDefault("cluster.lease_ttl", "15s")
+	viper.SetDefault("sentry.enabled", false)
+	viper.SetDefault("sentry.environment", "production")
+	viper.SetDefault("sentry.sample_rate", 1.0)
+	viper.SetDefault("rule_sync.enabled", false)
+	viper.SetDefault("rule_sync.branch", "main")
+	viper.SetDefault("rule_sync.path", "rules")
+	viper.SetDefault("rule_sync.interval", "5m")
+	viper.SetDefault("rule_sync.drift", "revert")
+	viper.SetDefault("rule_sync.work_dir", "data/rule-sync")
+	viper.SetDefault("redis.enabled", false)
+	viper.SetDefault("redis.addr", "localhost:6379")
+	viper.SetDefault("redis.key_prefix", "github-monitor:")
+	viper.SetDefault("redis.etag_ttl", "24h")
+	viper.SetDefault("redis.notify_dedup_window", "1h")
+	viper.SetDefault("ingest.enabled", false)
+	viper.SetDefault("ingest.max_findings", 500)
+	viper.SetDefault("redaction.enabled", true)
+	viper.SetDefault("redaction.reveal_roles", []string{"admin"})
+	viper.SetDefault("rule_webhooks.timeout", "10s")
+	viper.SetDefault("rule_webhooks.max_attempts", 8)
+	viper.SetDefault("purge.enabled", true)
+	viper.SetDefault("purge.max_age", "720h")
+	viper.SetDefault("purge.interval", "24h")
+	viper.SetDefault("sla.enabled", false)
+	viper.SetDefault("sla.at_risk", 0.75)
+	viper.SetDefault("sla.check_interval", "15m")
+	viper.SetDefault("classifier.enabled", false)
+	viper.SetDefault("classifier.base_url", "https://api.openai.com/v1")
+	viper.SetDefault("classifier.model", "gpt-4o-mini")
+	viper.SetDefault("classifier.interval", "1m")
+	viper.SetDefault("classifier.batch_size", 50)
+	viper.SetDefault("classifier.timeout", "30s")
+	viper.SetDefault("registry.enabled", false)
+	viper.SetDefault("registry.registries", []string{"npm", "pypi"})
+	viper.SetDefault("registry.severity", "critical")
+	viper.SetDefault("typosquat.enabled", false)
+	viper.SetDefault("typosquat.max_variants", 50)
+	viper.SetDefault("typosquat.severity", "high")
+	viper.SetDefault("typosquat.interval", "6h")
+	viper.SetDefault("fork_watch.enabled", false)
+	viper.SetDefault("fork_watch.max_forks", 100)
+	viper.SetDefault("fork_watch.severity", "high")
+	viper.SetDefault("fork_watch.interval", "1h")
+	viper.SetDefault("gist_watch.enabled", false)
+	viper.SetDefault("gist_watch.max_gists", 30)
+	viper.SetDefault("gist_watch.severity", "high")
+	viper.SetDefault("gist_watch.interval", "1h")
+	viper.SetDefault("report.enabled", false)
+	viper.SetDefault("report.weekday", "monday")
+	viper.SetDefault("report.hour", 9)
+	viper.SetDefault("report.email.smtp_port", 587)
 	viper.SetDefault("auth.enabled", false)
 	viper.SetDefault("auth.token_expiry", "24h")
+	viper.SetDefault("auth.refresh_token_expiry", "168h")
+	viper.SetDefault("auth.oidc.enabled", false)
+	viper.SetDefault("auth.oidc.scopes", []string{"openid", "profile", "email", "groups"})
+	viper.SetDefault("auth.oidc.groups_claim", "groups")
+	viper.SetDefault("auth.oidc.frontend_url", "/")
+
+	// Environment variables take precedence over the file. Lists are comma separated.
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	bindEnv(reflect.TypeOf(Config{}), "")
 
 	if err := viper.ReadInConfig(); err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		// Without a file everything comes from defaults and the environment, as in containers
+		if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		log.Printf("Config file %s not found, using defaults and environment variables", configPath)
 	}
 
-	AppConfig = &Config{}
-	if err := viper.Unmarshal(AppConfig); err != nil {
-		return fmt.Errorf("failed to unmarshal config: %w", err)
+	cfg, err := unmarshal()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
 	}
+	AppConfig = cfg
+	SetTimezone(cfg.Timezone)
+	i18n.SetDefault(cfg.Locale)
 
 	log.Println("Configuration loaded successfully")
 	return nil
 }
 
+// unmarshal builds a Config from the values viper currently holds
+func unmarshal() (*Config, error) {
+	cfg := &Config{}
+	if err := viper.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	if cfg.Database.Port == 0 {
+		cfg.Database.Port = 3306
+		if cfg.Database.Driver == "postgres" {
+			cfg.Database.Port = 5432
+		}
+	}
+
+	return cfg, nil
+}
+
+// bindEnv registers every config key with viper. AutomaticEnv only sees keys that
+// already have a default or a file value, so keys like database.password would be missed.
+func bindEnv(t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" {
+			continue
+		}
+
+		key := prefix + name
+		if field.Type.Kind() == reflect.Struct {
+			bindEnv(field.Type, key+".")
+			continue
+		}
+		viper.BindEnv(key)
+	}
+}
+
+// DSN returns the connection string for the application database
 func (c *DatabaseConfig) DSN() string {
+	return c.dsn(c.Database)
+}
+
+// ServerDSN returns a connection string that doesn't need the application database to exist,
+// used to create it on first start
+func (c *DatabaseConfig) ServerDSN() string {
+	if c.Driver == "postgres" {
+		return c.dsn("postgres")
+	}
+	return c.dsn("")
+}
+
+func (c *DatabaseConfig) dsn(database string) string {
+	if c.Driver == "sqlite" {
+		// WAL lets readers run alongside the single writer, busy_timeout makes writers wait instead of failing
+		return c.Path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	}
+
+	if c.Driver == "postgres" {
+		u := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.User, c.Password),
+			Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+			Path:     "/" + database,
+			RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+		}
+		return u.String()
+	}
+
 	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		c.User,
 		c.Password,
 		c.Host,
 		c.Port,
-		c.Database,
+		database,
 	)
 }
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"sync"
+	"time"
+	_ "time/tzdata" // containers often come without a zoneinfo database
+)
+
+var (
+	locationMu sync.RWMutex
+	location   = time.Local
+)
+
+// Location returns the configured timezone, which schedules and report dates follow.
+// It is the server's local time zone when timezone isn't set.
+func Location() *time.Location {
+	locationMu.RLock()
+	defer locationMu.RUnlock()
+	return location
+}
+
+// SetTimezone replaces the timezone after a reload, name was validated with the config
+func SetTimezone(name string) {
+	loc, err := loadLocation(name)
+	if err != nil {
+		return
+	}
+	locationMu.Lock()
+	location = loc
+	locationMu.Unlock()
+}
+
+// loadLocation loads an IANA time zone such as Europe/Berlin, the local one when
+// name is empty
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
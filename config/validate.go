@@ -0,0 +1,653 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github-monitor/i18n"
+)
+
+// githubLoginPattern matches GitHub user and organization names
+var githubLoginPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,38})$`)
+
+// ValidationError lists every problem found in a config so they can be fixed in one go
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the config for values that would only fail later at runtime
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if _, err := loadLocation(c.Timezone); err != nil {
+		v.add("timezone: %q is not a known time zone", c.Timezone)
+	}
+	if !i18n.Valid(c.Locale) {
+		v.add("locale: %q is not supported, use en or zh", c.Locale)
+	}
+	v.port("server.port", c.Server.Port)
+	v.duration("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	if c.Server.RateLimit.Enabled {
+		v.positive("server.rate_limit.requests_per_minute", c.Server.RateLimit.RequestsPerMinute)
+		v.positive("server.rate_limit.burst", c.Server.RateLimit.Burst)
+		v.positive("server.rate_limit.login_per_minute", c.Server.RateLimit.LoginPerMinute)
+		v.positive("server.rate_limit.expensive_per_minute", c.Server.RateLimit.ExpensivePerMinute)
+	}
+	v.networks("server.trusted_proxies", c.Server.TrustedProxies)
+	v.positive("server.max_body_kb", c.Server.MaxBodyKB)
+	v.positive("server.max_bulk_body_mb", c.Server.MaxBulkBodyMB)
+	if c.Server.MaxJSONDepth < 0 {
+		v.add("server.max_json_depth: must not be negative")
+	}
+	if c.Server.CompressMinBytes < 0 {
+		v.add("server.compress_min_bytes: must not be negative")
+	}
+	v.networks("server.admin_allowlist", c.Server.AdminAllowlist)
+	if c.Server.TLS.Enabled {
+		v.required("server.tls.cert_file", c.Server.TLS.CertFile)
+		v.required("server.tls.key_file", c.Server.TLS.KeyFile)
+		if c.Server.TLS.RedirectHTTPPort != 0 {
+			v.port("server.tls.redirect_http_port", c.Server.TLS.RedirectHTTPPort)
+		}
+	}
+	if c.Server.GRPC.Enabled {
+		v.port("server.grpc.port", c.Server.GRPC.Port)
+		if c.Server.GRPC.Port == c.Server.Port {
+			v.add("server.grpc.port: must differ from server.port (%d)", c.Server.Port)
+		}
+	}
+
+	switch c.Database.Driver {
+	case "mysql", "postgres":
+		v.required("database.host", c.Database.Host)
+		v.port("database.port", c.Database.Port)
+		v.required("database.database", c.Database.Database)
+		v.duration("database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+		v.duration("database.conn_max_idle_time", c.Database.ConnMaxIdleTime)
+		if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+			v.add("database.max_idle_conns: must not exceed database.max_open_conns (%d)", c.Database.MaxOpenConns)
+		}
+	case "sqlite":
+		v.required("database.path", c.Database.Path)
+	default:
+		v.add("database.driver: %q is not supported, use mysql, postgres or sqlite", c.Database.Driver)
+	}
+	v.duration("database.ping_interval", c.Database.PingInterval)
+
+	if c.Monitor.Enabled && !hasToken(c.GitHub.Tokens) {
+		v.add("github.tokens: at least one token is required when monitor.enabled is true")
+	}
+	shared := make(map[string]bool, len(c.GitHub.Tokens))
+	for _, token := range c.GitHub.Tokens {
+		shared[token] = true
+	}
+	groups := make([]string, 0, len(c.GitHub.TokenGroups))
+	for name := range c.GitHub.TokenGroups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	for _, name := range groups {
+		tokens := c.GitHub.TokenGroups[name]
+		if !hasToken(tokens) {
+			v.add("github.token_groups.%s: at least one token is required", name)
+		}
+		for _, token := range tokens {
+			if token != "" && shared[token] {
+				v.add("github.token_groups.%s: a token is also in github.tokens, dedicated tokens must not be shared", name)
+				break
+			}
+		}
+	}
+	v.duration("github.request_interval", c.GitHub.RequestInterval)
+	if c.GitHub.RateLimitThreshold < 0 {
+		v.add("github.rate_limit_threshold: must not be negative")
+	}
+	if d, ok := v.duration("github.http.connect_timeout", c.GitHub.HTTP.ConnectTimeout); ok && d < time.Second {
+		v.add("github.http.connect_timeout: must be at least 1s")
+	}
+	if d, ok := v.duration("github.http.read_timeout", c.GitHub.HTTP.ReadTimeout); ok && d < time.Second {
+		v.add("github.http.read_timeout: must be at least 1s")
+	}
+	if c.GitHub.HTTP.MaxIdleConns < 1 || c.GitHub.HTTP.MaxIdleConns > 1000 {
+		v.add("github.http.max_idle_conns: must be between 1 and 1000")
+	}
+	if c.GitHub.HTTP.MaxRetries < 0 || c.GitHub.HTTP.MaxRetries > 10 {
+		v.add("github.http.max_retries: must be between 0 and 10")
+	}
+	v.duration("github.http.retry_wait", c.GitHub.HTTP.RetryWait)
+	if c.GitHub.ProxyEnabled {
+		if u, err := url.Parse(c.GitHub.ProxyURL); err != nil || u.Host == "" {
+			v.add("github.proxy_url: %q is not a valid URL, e.g. http://proxy.example.com:8080", c.GitHub.ProxyURL)
+		}
+		switch c.GitHub.ProxyType {
+		case "http", "https", "socks5":
+		default:
+			v.add("github.proxy_type: %q is not supported, use http, https or socks5", c.GitHub.ProxyType)
+		}
+	}
+
+	if d, ok := v.duration("monitor.scan_interval", c.Monitor.ScanInterval); ok && d < time.Minute {
+		v.add("monitor.scan_interval: must be at least 1m")
+	}
+	if c.Monitor.Concurrency < 1 || c.Monitor.Concurrency > 32 {
+		v.add("monitor.concurrency: must be between 1 and 32")
+	}
+	if d, ok := v.duration("monitor.page_delay", c.Monitor.PageDelay); ok && d > time.Minute {
+		v.add("monitor.page_delay: must be at most 1m")
+	}
+	if d, ok := v.duration("monitor.rule_delay", c.Monitor.RuleDelay); ok && d > time.Minute {
+		v.add("monitor.rule_delay: must be at most 1m")
+	}
+	v.duration("monitor.exhaustion_warning", c.Monitor.ExhaustionWarning)
+	if c.Monitor.MaxNewResults < 0 {
+		v.add("monitor.max_new_results: must not be negative")
+	}
+	if c.Monitor.KnownCacheSize < 0 {
+		v.add("monitor.known_cache_size: must not be negative")
+	}
+	if c.Monitor.SnippetLength < 100 || c.Monitor.SnippetLength > 10000 {
+		v.add("monitor.snippet_length: must be between 100 and 10000")
+	}
+	if c.Monitor.ContextLines < 0 || c.Monitor.ContextLines > 50 {
+		v.add("monitor.context_lines: must be between 0 and 50")
+	}
+	if c.Monitor.MaxContentKB < 0 {
+		v.add("monitor.max_content_kb: must not be negative")
+	}
+	for _, groupBy := range c.Monitor.IncidentGroupBy {
+		if groupBy != "fingerprint" && groupBy != "repository" && groupBy != "owner" {
+			v.add("monitor.incident_group_by: %q is not supported, use fingerprint, repository or owner", groupBy)
+		}
+	}
+	for _, cidr := range c.Monitor.InternalCIDRs {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+			v.add("monitor.internal_cidrs: %q is not a CIDR, e.g. 10.0.0.0/8", cidr)
+		}
+	}
+	for _, domain := range c.Monitor.InternalDomains {
+		if strings.Trim(strings.TrimSpace(domain), ".") == "" {
+			v.add("monitor.internal_domains: must not contain empty zones")
+		}
+	}
+
+	switch c.Monitor.ResultChanges {
+	case "off", "record", "reopen":
+	default:
+		v.add("monitor.result_changes: %q is not supported, use off, record or reopen", c.Monitor.ResultChanges)
+	}
+	if c.Monitor.AutoPause.MaxFailures < 0 {
+		v.add("monitor.auto_pause.max_failures: must not be negative")
+	}
+	if c.Monitor.AutoPause.IdleDays < 0 {
+		v.add("monitor.auto_pause.idle_days: must not be negative")
+	}
+	if c.Monitor.SearchBudget.Enabled && (c.Monitor.SearchBudget.RequestsPerTokenHour < 1 || c.Monitor.SearchBudget.RequestsPerTokenHour > 5000) {
+		v.add("monitor.search_budget.requests_per_token_hour: must be between 1 and 5000")
+	}
+
+	if c.Notify.DashboardURL != "" {
+		if u, err := url.Parse(c.Notify.DashboardURL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add("notify.dashboard_url: %q must be an absolute URL", c.Notify.DashboardURL)
+		}
+	}
+	if c.Notify.Actions.Enabled {
+		if u, err := url.Parse(c.Notify.Actions.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add("notify.actions.base_url: %q must be the absolute URL the server is reachable at", c.Notify.Actions.BaseURL)
+		}
+		if len(c.Notify.Actions.Secret) < 16 {
+			v.add("notify.actions.secret: must be at least 16 characters")
+		}
+		if d, ok := v.duration("notify.actions.link_expiry", c.Notify.Actions.LinkExpiry); ok && d == 0 {
+			v.add("notify.actions.link_expiry: must be greater than 0")
+		}
+	}
+	if owner := c.Notify.OwnerEmail; owner.SMTPHost != "" {
+		if owner.SMTPPort < 1 || owner.SMTPPort > 65535 {
+			v.add("notify.owner_email.smtp_port: must be between 1 and 65535")
+		}
+		if _, err := mail.ParseAddress(owner.From); err != nil {
+			v.add("notify.owner_email.from: %q must be an email address", owner.From)
+		}
+	}
+	if translation := c.Notify.Translation; translation.Provider != "" {
+		switch translation.Provider {
+		case "deepl":
+			if translation.APIKey == "" {
+				v.add("notify.translation.api_key: required for deepl")
+			}
+		case "libretranslate":
+			if translation.URL == "" {
+				v.add("notify.translation.url: required for libretranslate")
+			}
+		default:
+			v.add("notify.translation.provider: %q is not supported, use deepl or libretranslate", translation.Provider)
+		}
+		if translation.URL != "" {
+			if u, err := url.Parse(translation.URL); err != nil || u.Scheme == "" || u.Host == "" {
+				v.add("notify.translation.url: %q must be an absolute URL", translation.URL)
+			}
+		}
+		if translation.TargetLanguage == "" {
+			v.add("notify.translation.target_language: must be set, such as en")
+		}
+		if d, ok := v.duration("notify.translation.timeout", translation.Timeout); ok && d == 0 {
+			v.add("notify.translation.timeout: must be greater than 0")
+		}
+	}
+
+	if c.DockerHub.Enabled && (c.DockerHub.MaxPages < 1 || c.DockerHub.MaxPages > 10) {
+		v.add("dockerhub.max_pages: must be between 1 and 10")
+	}
+	if c.Postman.Enabled && (c.Postman.MaxPages < 1 || c.Postman.MaxPages > 10) {
+		v.add("postman.max_pages: must be between 1 and 10")
+	}
+
+	if c.Registry.Enabled {
+		if len(c.Registry.Packages) == 0 {
+			v.add("registry.packages: at least one package name is required when registry.enabled is true")
+		}
+		if len(c.Registry.Registries) == 0 {
+			v.add("registry.registries: at least one registry is required when registry.enabled is true")
+		}
+		for _, name := range c.Registry.Registries {
+			if name != "npm" && name != "pypi" {
+				v.add("registry.registries: %q is not supported, use npm or pypi", name)
+			}
+		}
+		switch c.Registry.Severity {
+		case "critical", "high", "medium", "low", "info":
+		default:
+			v.add("registry.severity: %q is not a severity, use critical, high, medium, low or info", c.Registry.Severity)
+		}
+	}
+
+	if c.Typosquat.Enabled {
+		if len(c.Typosquat.Names) == 0 {
+			v.add("typosquat.names: at least one name is required when typosquat.enabled is true")
+		}
+		for _, name := range c.Typosquat.Names {
+			if len(strings.TrimSpace(name)) < 3 {
+				v.add("typosquat.names: %q is too short, names need at least 3 characters", name)
+			}
+		}
+		if c.Typosquat.MaxVariants < 1 || c.Typosquat.MaxVariants > 500 {
+			v.add("typosquat.max_variants: must be between 1 and 500")
+		}
+		switch c.Typosquat.Severity {
+		case "critical", "high", "medium", "low", "info":
+		default:
+			v.add("typosquat.severity: %q is not a severity, use critical, high, medium, low or info", c.Typosquat.Severity)
+		}
+		if d, ok := v.duration("typosquat.interval", c.Typosquat.Interval); ok && d < time.Hour {
+			v.add("typosquat.interval: must be at least 1h")
+		}
+	}
+
+	if c.ForkWatch.Enabled {
+		if len(c.ForkWatch.Repositories) == 0 {
+			v.add("fork_watch.repositories: at least one repository is required when fork_watch.enabled is true")
+		}
+		for _, repo := range c.ForkWatch.Repositories {
+			if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+				v.add("fork_watch.repositories: %q must be owner/name", repo)
+			}
+		}
+		if c.ForkWatch.MaxForks < 1 || c.ForkWatch.MaxForks > 1000 {
+			v.add("fork_watch.max_forks: must be between 1 and 1000")
+		}
+		switch c.ForkWatch.Severity {
+		case "critical", "high", "medium", "low", "info":
+		default:
+			v.add("fork_watch.severity: %q is not a severity, use critical, high, medium, low or info", c.ForkWatch.Severity)
+		}
+		if d, ok := v.duration("fork_watch.interval", c.ForkWatch.Interval); ok && d < 10*time.Minute {
+			v.add("fork_watch.interval: must be at least 10m")
+		}
+	}
+
+	if c.GistWatch.Enabled {
+		if len(c.GistWatch.Users) == 0 {
+			v.add("gist_watch.users: at least one user is required when gist_watch.enabled is true")
+		}
+		for _, user := range c.GistWatch.Users {
+			if !githubLoginPattern.MatchString(user) {
+				v.add("gist_watch.users: %q is not a GitHub login", user)
+			}
+		}
+		if c.GistWatch.MaxGists < 1 || c.GistWatch.MaxGists > 300 {
+			v.add("gist_watch.max_gists: must be between 1 and 300")
+		}
+		switch c.GistWatch.Severity {
+		case "critical", "high", "medium", "low", "info":
+		default:
+			v.add("gist_watch.severity: %q is not a severity, use critical, high, medium, low or info", c.GistWatch.Severity)
+		}
+		if d, ok := v.duration("gist_watch.interval", c.GistWatch.Interval); ok && d < 10*time.Minute {
+			v.add("gist_watch.interval: must be at least 10m")
+		}
+	}
+
+	if c.Elasticsearch.Enabled {
+		if u, err := url.Parse(c.Elasticsearch.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add("elasticsearch.url: %q must be an absolute URL, e.g. https://elastic.example.com:9200", c.Elasticsearch.URL)
+		}
+		if c.Elasticsearch.Index == "" || c.Elasticsearch.Index != strings.ToLower(c.Elasticsearch.Index) {
+			v.add("elasticsearch.index: %q must be a non-empty lowercase index name", c.Elasticsearch.Index)
+		}
+		if d, ok := v.duration("elasticsearch.flush_interval", c.Elasticsearch.FlushInterval); ok && d == 0 {
+			v.add("elasticsearch.flush_interval: must be greater than 0")
+		}
+	}
+
+	if c.Storage.Enabled {
+		switch c.Storage.Provider {
+		case "s3", "oss":
+			if c.Storage.Endpoint == "" {
+				v.required("storage.region", c.Storage.Region)
+			}
+		case "minio":
+			v.required("storage.endpoint", c.Storage.Endpoint)
+		default:
+			v.add("storage.provider: %q is not supported, use s3, minio or oss", c.Storage.Provider)
+		}
+		if strings.Contains(c.Storage.Endpoint, "://") {
+			v.add("storage.endpoint: %q must be host[:port] without a scheme, use storage.use_ssl instead", c.Storage.Endpoint)
+		}
+		v.required("storage.bucket", c.Storage.Bucket)
+		v.required("storage.access_key", c.Storage.AccessKey)
+		v.required("storage.secret_key", c.Storage.SecretKey)
+		// Signature V4 presigned URLs are valid for at most 7 days
+		if d, ok := v.duration("storage.signed_url_expiry", c.Storage.SignedURLExpiry); ok && (d < time.Second || d > 7*24*time.Hour) {
+			v.add("storage.signed_url_expiry: must be between 1s and 168h")
+		}
+	}
+
+	if c.DefectDojo.Enabled {
+		if u, err := url.Parse(c.DefectDojo.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add("defectdojo.url: %q must be an absolute URL", c.DefectDojo.URL)
+		}
+		v.required("defectdojo.api_key", c.DefectDojo.APIKey)
+		v.required("defectdojo.test_type", c.DefectDojo.TestType)
+		if c.DefectDojo.EngagementID <= 0 && len(c.DefectDojo.Engagements) == 0 {
+			v.add("defectdojo.engagement_id: is required unless defectdojo.engagements maps every rule")
+		}
+		for i, mapping := range c.DefectDojo.Engagements {
+			if mapping.Rule == "" || mapping.EngagementID <= 0 {
+				v.add("defectdojo.engagements[%d]: needs a rule and an engagement_id", i)
+			}
+		}
+		if d, ok := v.duration("defectdojo.sync_interval", c.DefectDojo.SyncInterval); ok && d < time.Minute {
+			v.add("defectdojo.sync_interval: must be at least 1m")
+		}
+	}
+
+	if c.Cluster.Enabled {
+		if d, ok := v.duration("cluster.lease_ttl", c.Cluster.LeaseTTL); ok && d < 3*time.Second {
+			v.add("cluster.lease_ttl: must be at least 3s")
+		}
+	}
+
+	if c.Sentry.Enabled {
+		v.required("sentry.dsn", c.Sentry.DSN)
+		if c.Sentry.SampleRate < 0 || c.Sentry.SampleRate > 1 {
+			v.add("sentry.sample_rate: must be between 0 and 1")
+		}
+	}
+
+	if c.RuleSync.Enabled {
+		v.required("rule_sync.repository", c.RuleSync.Repository)
+		v.required("rule_sync.branch", c.RuleSync.Branch)
+		v.required("rule_sync.work_dir", c.RuleSync.WorkDir)
+		if d, ok := v.duration("rule_sync.interval", c.RuleSync.Interval); ok && d < time.Minute {
+			v.add("rule_sync.interval: must be at least 1m")
+		}
+		switch c.RuleSync.Drift {
+		case "revert", "report":
+		default:
+			v.add("rule_sync.drift: %q is not supported, use revert or report", c.RuleSync.Drift)
+		}
+	}
+
+	if c.Redis.Enabled {
+		if _, _, err := net.SplitHostPort(c.Redis.Addr); err != nil {
+			v.add("redis.addr: %q must be host:port", c.Redis.Addr)
+		}
+		if c.Redis.DB < 0 {
+			v.add("redis.db: must not be negative")
+		}
+		if d, ok := v.duration("redis.etag_ttl", c.Redis.ETagTTL); ok && d == 0 {
+			v.add("redis.etag_ttl: must be greater than 0")
+		}
+		v.duration("redis.notify_dedup_window", c.Redis.NotifyDedupWindow)
+	}
+
+	if c.Classifier.Enabled {
+		if u, err := url.Parse(c.Classifier.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add("classifier.base_url: %q must be an absolute URL", c.Classifier.BaseURL)
+		}
+		v.required("classifier.model", c.Classifier.Model)
+		if d, ok := v.duration("classifier.interval", c.Classifier.Interval); ok && d < time.Second {
+			v.add("classifier.interval: must be at least 1s")
+		}
+		if c.Classifier.BatchSize < 1 || c.Classifier.BatchSize > 500 {
+			v.add("classifier.batch_size: must be between 1 and 500")
+		}
+		if d, ok := v.duration("classifier.timeout", c.Classifier.Timeout); ok && d == 0 {
+			v.add("classifier.timeout: must be greater than 0")
+		}
+	}
+
+	if c.Ingest.Enabled {
+		if len(c.Ingest.Keys) == 0 {
+			v.add("ingest.keys: at least one key is required when ingest.enabled is true")
+		}
+		names := make(map[string]bool, len(c.Ingest.Keys))
+		keys := make(map[string]bool, len(c.Ingest.Keys))
+		for i, key := range c.Ingest.Keys {
+			if key.Name == "" {
+				v.add("ingest.keys[%d].name: is required", i)
+			} else if names[key.Name] {
+				v.add("ingest.keys[%d].name: %q is used by another key", i, key.Name)
+			}
+			names[key.Name] = true
+			if len(key.Key) < 32 {
+				v.add("ingest.keys[%d].key: must be at least 32 characters", i)
+			} else if keys[key.Key] {
+				v.add("ingest.keys[%d].key: is used by another key", i)
+			}
+			keys[key.Key] = true
+		}
+		if c.Ingest.MaxFindings < 1 || c.Ingest.MaxFindings > 5000 {
+			v.add("ingest.max_findings: must be between 1 and 5000")
+		}
+	}
+
+	scrapeNames := make(map[string]bool, len(c.Scrape.Keys))
+	scrapeKeys := make(map[string]bool, len(c.Scrape.Keys))
+	for i, key := range c.Scrape.Keys {
+		if key.Name == "" {
+			v.add("scrape.keys[%d].name: is required", i)
+		} else if scrapeNames[key.Name] {
+			v.add("scrape.keys[%d].name: %q is used by another key", i, key.Name)
+		}
+		scrapeNames[key.Name] = true
+		if len(key.Key) < 32 {
+			v.add("scrape.keys[%d].key: must be at least 32 characters", i)
+		} else if scrapeKeys[key.Key] {
+			v.add("scrape.keys[%d].key: is used by another key", i)
+		}
+		scrapeKeys[key.Key] = true
+	}
+
+	for _, role := range c.Redaction.RevealRoles {
+		switch role {
+		case "admin", "analyst", "viewer":
+		default:
+			v.add("redaction.reveal_roles: %q is not a role, use admin, analyst or viewer", role)
+		}
+	}
+
+	if d, ok := v.duration("rule_webhooks.timeout", c.RuleWebhooks.Timeout); ok && d == 0 {
+		v.add("rule_webhooks.timeout: must be greater than 0")
+	}
+	if c.RuleWebhooks.MaxAttempts < 1 || c.RuleWebhooks.MaxAttempts > 50 {
+		v.add("rule_webhooks.max_attempts: must be between 1 and 50")
+	}
+
+	if c.Purge.Enabled {
+		if d, ok := v.duration("purge.max_age", c.Purge.MaxAge); ok && d < time.Hour {
+			v.add("purge.max_age: must be at least 1h")
+		}
+		if d, ok := v.duration("purge.interval", c.Purge.Interval); ok && d < time.Minute {
+			v.add("purge.interval: must be at least 1m")
+		}
+	}
+
+	if c.SLA.Enabled {
+		if len(c.SLA.Severities) == 0 {
+			v.add("sla.severities: at least one severity is required when sla.enabled is true")
+		}
+		severities := make([]string, 0, len(c.SLA.Severities))
+		for severity := range c.SLA.Severities {
+			severities = append(severities, severity)
+		}
+		sort.Strings(severities)
+		for _, severity := range severities {
+			switch severity {
+			case "critical", "high", "medium", "low", "info":
+			default:
+				v.add("sla.severities: %q is not a severity, use critical, high, medium, low or info", severity)
+				continue
+			}
+			target := c.SLA.Severities[severity]
+			if target.Triage == "" && target.Remediate == "" {
+				v.add("sla.severities.%s: set triage, remediate or both", severity)
+			}
+			if target.Triage != "" {
+				if d, ok := v.duration("sla.severities."+severity+".triage", target.Triage); ok && d == 0 {
+					v.add("sla.severities.%s.triage: must be greater than 0", severity)
+				}
+			}
+			if target.Remediate != "" {
+				if d, ok := v.duration("sla.severities."+severity+".remediate", target.Remediate); ok && d == 0 {
+					v.add("sla.severities.%s.remediate: must be greater than 0", severity)
+				}
+			}
+		}
+		if c.SLA.AtRisk <= 0 || c.SLA.AtRisk >= 1 {
+			v.add("sla.at_risk: must be between 0 and 1")
+		}
+		if d, ok := v.duration("sla.check_interval", c.SLA.CheckInterval); ok && d < time.Minute {
+			v.add("sla.check_interval: must be at least 1m")
+		}
+	}
+
+	if c.Auth.Enabled {
+		v.required("auth.jwt_secret", c.Auth.JWTSecret)
+		if c.Auth.Password == "" && !c.Auth.OIDC.Enabled {
+			v.add("auth.password: is required unless auth.oidc is enabled")
+		}
+		v.duration("auth.token_expiry", c.Auth.TokenExpiry)
+		v.duration("auth.refresh_token_expiry", c.Auth.RefreshTokenExpiry)
+		if c.Auth.OIDC.Enabled {
+			v.required("auth.oidc.issuer_url", c.Auth.OIDC.IssuerURL)
+			v.required("auth.oidc.client_id", c.Auth.OIDC.ClientID)
+			v.required("auth.oidc.redirect_url", c.Auth.OIDC.RedirectURL)
+		}
+	}
+
+	if c.Report.Enabled {
+		if !validWeekday(c.Report.Weekday) {
+			v.add("report.weekday: %q is not a day of the week", c.Report.Weekday)
+		}
+		if c.Report.Hour < 0 || c.Report.Hour > 23 {
+			v.add("report.hour: must be between 0 and 23")
+		}
+		if c.Report.WebhookURL == "" && c.Report.Email.SMTPHost == "" {
+			v.add("report: set report.webhook_url or report.email.smtp_host to deliver reports")
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validator collects problems instead of stopping at the first one
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(key, value string) {
+	if value == "" {
+		v.add("%s: is required", key)
+	}
+}
+
+func (v *validator) positive(key string, value int) {
+	if value <= 0 {
+		v.add("%s: must be greater than 0", key)
+	}
+}
+
+func (v *validator) port(key string, value int) {
+	if value < 1 || value > 65535 {
+		v.add("%s: %d is not a valid port (1-65535)", key, value)
+	}
+}
+
+func (v *validator) networks(key string, entries []string) {
+	for _, entry := range entries {
+		if net.ParseIP(entry) == nil {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				v.add("%s: %q is not an IP address or CIDR", key, entry)
+			}
+		}
+	}
+}
+
+func (v *validator) duration(key, value string) (time.Duration, bool) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		v.add("%s: %q is not a duration, use units like 30s, 5m or 24h", key, value)
+		return 0, false
+	}
+	if d < 0 {
+		v.add("%s: must not be negative", key)
+		return 0, false
+	}
+	return d, true
+}
+
+func hasToken(tokens []string) bool {
+	for _, token := range tokens {
+		if token != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func validWeekday(name string) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Editors often write a file in several steps, wait for them to settle before reloading
+const reloadDebounce = 500 * time.Millisecond
+
+// Watch re-reads the config file when it changes on disk or the process receives
+// SIGHUP and calls fn with the result. AppConfig itself is left alone, fn decides
+// which values can be applied while running.
+func Watch(fn func(*Config)) {
+	var (
+		mu    sync.Mutex
+		timer *time.Timer
+	)
+
+	reload := func(reason string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(reloadDebounce, func() {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err := viper.ReadInConfig(); err != nil {
+				log.Printf("Config reload (%s) failed, keeping current config: %v", reason, err)
+				return
+			}
+			cfg, err := unmarshal()
+			if err == nil {
+				err = cfg.Validate()
+			}
+			if err != nil {
+				log.Printf("Config reload (%s) failed, keeping current config: %v", reason, err)
+				return
+			}
+
+			log.Printf("Config reloaded (%s)", reason)
+			fn(cfg)
+		})
+	}
+
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		reload("file changed")
+	})
+	viper.WatchConfig()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reload("SIGHUP")
+		}
+	}()
+}
+
+// tokensMu guards GitHub.Tokens and GitHub.TokenGroups of AppConfig, the only
+// AppConfig values replaced by a reload outside the settings package
+var tokensMu sync.RWMutex
+
+// GitHubTokens returns the current shared tokens and token groups
+func GitHubTokens() (tokens []string, groups map[string][]string) {
+	tokensMu.RLock()
+	defer tokensMu.RUnlock()
+	return AppConfig.GitHub.Tokens, AppConfig.GitHub.TokenGroups
+}
+
+// SetGitHubTokens replaces the shared tokens and token groups after a reload.
+// The slices and map must not be modified afterwards.
+func SetGitHubTokens(tokens []string, groups map[string][]string) {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+	AppConfig.GitHub.Tokens = tokens
+	AppConfig.GitHub.TokenGroups = groups
+}
+
+// HasTokenGroup reports whether name is one of the configured token groups
+func HasTokenGroup(name string) bool {
+	tokensMu.RLock()
+	defer tokensMu.RUnlock()
+	_, ok := AppConfig.GitHub.TokenGroups[name]
+	return ok
+}
@@ -1,13 +1,19 @@
 package db
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 
 	"github-monitor/config"
 	"github-monitor/db/models"
 
+	"github.com/glebarez/sqlite"
+	"github.com/jackc/pgx/v5"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -16,21 +22,20 @@ var DB *gorm.DB
 
 // InitDB initializes the database connection
 func InitDB(cfg *config.DatabaseConfig) error {
-	var err error
+	switch cfg.Driver {
+	case "mysql", "postgres":
+	case "sqlite":
+		return initSQLite(cfg)
+	default:
+		return fmt.Errorf("unsupported database driver %q, use mysql, postgres or sqlite", cfg.Driver)
+	}
 
 	// First, connect without specifying the database to create it if it doesn't exist
-	dsnWithoutDB := fmt.Sprintf("%s:%s@tcp(%s:%d)/?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.User,
-		cfg.Password,
-		cfg.Host,
-		cfg.Port,
-	)
-
-	tempDB, err := gorm.Open(mysql.Open(dsnWithoutDB), &gorm.Config{
+	tempDB, err := gorm.Open(dialector(cfg.Driver, cfg.ServerDSN()), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to connect to MySQL server: %w", err)
+		return fmt.Errorf("failed to connect to database server: %w", err)
 	}
 
 	// Create database if it doesn't exist
@@ -39,8 +44,7 @@ func InitDB(cfg *config.DatabaseConfig) error {
 		return fmt.Errorf("failed to get database instance: %w", err)
 	}
 
-	createDBSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", cfg.Database)
-	_, err = sqlDB.Exec(createDBSQL)
+	err = createDatabase(sqlDB, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create database: %w", err)
 	}
@@ -52,38 +56,178 @@ func InitDB(cfg *config.DatabaseConfig) error {
 
 	// Now connect to the specific database
 	dsn := cfg.DSN()
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	DB, err = gorm.Open(dialector(cfg.Driver, dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Info),
+		TranslateError: true,
 	})
 
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	log.Println("Database connection established")
+	sqlDB, err = DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+	configurePool(sqlDB, cfg)
+
+	log.Printf("Database connection established (%s)", cfg.Driver)
 	return nil
 }
 
+// initSQLite opens the local database file, creating it and its directory if needed
+func initSQLite(cfg *config.DatabaseConfig) error {
+	if dir := filepath.Dir(cfg.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	var err error
+	DB, err = gorm.Open(sqlite.Open(cfg.DSN()), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Info),
+		TranslateError: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite allows a single writer, so serialize through one connection
+	// rather than have concurrent scans fail with "database is locked"
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	log.Printf("Database connection established (sqlite, %s)", cfg.Path)
+	return nil
+}
+
+// dialector returns the GORM dialector for the configured driver
+func dialector(driver, dsn string) gorm.Dialector {
+	if driver == "postgres" {
+		return postgres.Open(dsn)
+	}
+	return mysql.Open(dsn)
+}
+
+// createDatabase creates the application database if it doesn't exist yet
+func createDatabase(sqlDB *sql.DB, cfg *config.DatabaseConfig) error {
+	if cfg.Driver == "postgres" {
+		// Postgres has no CREATE DATABASE IF NOT EXISTS
+		var exists bool
+		if err := sqlDB.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", cfg.Database).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+		_, err := sqlDB.Exec("CREATE DATABASE " + pgx.Identifier{cfg.Database}.Sanitize())
+		return err
+	}
+
+	createDBSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", cfg.Database)
+	_, err := sqlDB.Exec(createDBSQL)
+	return err
+}
+
 // AutoMigrate runs database migrations
 func AutoMigrate() error {
 	err := DB.AutoMigrate(
+		&models.Project{},
+		&models.ProjectMember{},
 		&models.GitHubToken{},
 		&models.MonitorRule{},
 		&models.SearchResult{},
+		&models.StagedResult{},
+		&models.RuleSample{},
+		&models.SavedView{},
+		&models.Honeytoken{},
+		&models.Incident{},
+		&models.IncidentEvent{},
+		&models.ShareLink{},
+		&models.ResultRevision{},
+		&models.RuleWebhook{},
+		&models.WebhookDelivery{},
 		&models.Whitelist{},
 		&models.ScanHistory{},
 		&models.NotificationConfig{},
+		&models.Session{},
+		&models.AuditLog{},
+		&models.Setting{},
+		&models.Checkpoint{},
+		&models.Report{},
+		&models.DefectDojoFinding{},
+		&models.Lease{},
+		&models.CompanyProfile{},
+		&models.RuleRevision{},
+		&models.SyncedDefinition{},
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if err := createIndexes(); err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	// Rows from before projects default to the first one. It is created without an
+	// explicit id, so Postgres sequences stay in step.
+	var projects int64
+	if err := DB.Unscoped().Model(&models.Project{}).Count(&projects).Error; err != nil {
+		return fmt.Errorf("failed to count projects: %w", err)
+	}
+	if projects == 0 {
+		if err := DB.Create(&models.Project{Name: "Default", Description: "Created on first start"}).Error; err != nil {
+			return fmt.Errorf("failed to create the default project: %w", err)
+		}
+	}
+
 	log.Println("Database migrations completed successfully")
 	return nil
 }
 
+// createIndexes adds indexes that can't be declared portably with struct tags
+func createIndexes() error {
+	// Used by the monitor to skip files a rule already reported
+	if !DB.Migrator().HasIndex(&models.SearchResult{}, "idx_search_results_dedup") {
+		columns := "rule_id, repo_full_name, file_path"
+		if DB.Dialector.Name() == "mysql" {
+			// InnoDB keys are limited to 3072 bytes, too short for both columns in utf8mb4
+			columns = "rule_id, repo_full_name, file_path(255)"
+		}
+		if err := DB.Exec("CREATE INDEX idx_search_results_dedup ON search_results (" + columns + ")").Error; err != nil {
+			return err
+		}
+	}
+
+	// Whitelist values became unique per project
+	if DB.Migrator().HasIndex(&models.Whitelist{}, "idx_whitelists_value") {
+		if err := DB.Migrator().DropIndex(&models.Whitelist{}, "idx_whitelists_value"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// Close closes the database connection pool
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
+}
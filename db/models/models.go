@@ -1,6 +1,11 @@
 package models
 
 import (
+	"encoding/json"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -21,6 +26,31 @@ type GitHubToken struct {
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// DefaultProjectID is the project created on first start, which holds everything
+// that existed before projects
+const DefaultProjectID = 1
+
+// Project scopes rules, results, whitelist entries and notification channels, so
+// one deployment can serve several teams or subsidiaries
+type Project struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	Name        string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// ProjectMember grants a user a role in a project
+type ProjectMember struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ProjectID uint      `gorm:"not null;uniqueIndex:idx_project_members_subject,priority:1" json:"project_id"`
+	Subject   string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_project_members_subject,priority:2;index" json:"subject"`
+	Role      string    `gorm:"type:varchar(50);not null" json:"role"` // admin, analyst or viewer
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // MonitorRule represents a monitoring rule with keywords
 type MonitorRule struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
@@ -28,38 +58,463 @@ type MonitorRule struct {
 	Description string         `gorm:"type:text" json:"description"`
 	Keywords    string         `gorm:"type:text;not null" json:"keywords"` // JSON array of keywords
 	MatchType   string         `gorm:"type:varchar(50);default:'fuzzy'" json:"match_type"` // "precise" or "fuzzy"
+	QueryDialect string        `gorm:"type:varchar(20);default:'legacy'" json:"query_dialect"` // "legacy" searches keywords as text, "code_search" allows regular expressions, content: and NOT
+	RawQuery    string         `gorm:"type:text" json:"raw_query,omitempty"` // code search query sent verbatim instead of the one built from keywords, dialect and exclusions
 	IsActive    bool           `gorm:"default:true" json:"is_active"`
 	ExcludeExts string         `gorm:"type:text" json:"exclude_exts"` // JSON array of file extensions to exclude
+	ExcludeRepos string        `gorm:"type:text" json:"exclude_repos"` // JSON array of owner/name repositories the rule records no results in, other rules still do
+	FileSize    string         `gorm:"type:varchar(50)" json:"file_size,omitempty"` // size: qualifier in bytes such as <50000 or 100..5000, empty for any size
+	Severity    string         `gorm:"type:varchar(20);default:'medium'" json:"severity"` // critical, high, medium, low, info
+	ProfileKey  string         `gorm:"type:varchar(255);index" json:"profile_key,omitempty"` // set on rules generated from the company profile
+	TokenGroup  string         `gorm:"type:varchar(100)" json:"token_group"` // github.token_groups entry searched with before the shared tokens
+	SearchRepoMetadata bool    `json:"search_repo_metadata"` // also search repository descriptions and topics for the keywords
+	KeywordWeights string      `gorm:"type:text" json:"keyword_weights,omitempty"` // JSON object of keyword weights, other keywords weigh 1
+	MinScore    float64        `json:"min_score"` // results whose matched keywords weigh less aren't recorded, 0 records all
+	NotifyThreshold string     `gorm:"type:varchar(20)" json:"notify_threshold,omitempty"` // least severity (e.g. high) or score (e.g. 5) of the results notified about, empty notifies all
+	SkipArchived bool          `json:"skip_archived"` // code search results in archived repositories aren't recorded
+	MinRepoAgeDays int         `json:"min_repo_age_days"` // code search results in repositories created fewer days ago aren't recorded, 0 for no limit
+	MaxRepoAgeDays int         `json:"max_repo_age_days"` // code search results in repositories created more days ago aren't recorded, 0 for no limit
+	MaxNewResults int          `json:"max_new_results"`   // new results a scan may record before the rule is held for review, 0 for monitor.max_new_results
+	SampleOnly  bool           `json:"sample_only"` // scans count their hits and keep a random sample of them instead of recording results
+	Processors  string         `gorm:"type:text" json:"processors,omitempty"` // JSON array of the result processors the rule's hits go through in order, empty for the default pipeline
+	PageDelay   string         `gorm:"type:varchar(20)" json:"page_delay,omitempty"` // wait between the result pages of the rule's search, e.g. 500ms, empty for monitor.page_delay
+	RuleDelay   string         `gorm:"type:varchar(20)" json:"rule_delay,omitempty"` // wait after the rule's scan before the worker takes the next rule, empty for monitor.rule_delay
+	QueryError  string         `gorm:"type:text" json:"query_error,omitempty"` // why GitHub rejected the search query, the rule isn't scanned until its query changes
+	ScanPending bool           `gorm:"index" json:"scan_pending"` // created or re-activated and not scanned since, picked up by the scheduler with monitor.scan_on_create
+	ActiveSince *time.Time     `json:"active_since,omitempty"` // when the rule was created or last re-activated, nil for rules from before it was tracked
+	PausedReason string        `gorm:"type:text" json:"paused_reason,omitempty"` // why monitor.auto_pause deactivated the rule, cleared when it is re-activated
+	NeedsReview bool           `gorm:"index" json:"needs_review"` // deactivated because a scan found more new results than allowed, cleared when it is re-activated
+	Owner       string         `gorm:"type:varchar(255);index" json:"owner"` // user or email address accountable for the rule, emailed its notifications and scan failures
+	ProjectID   uint           `gorm:"index;not null;default:1" json:"project_id"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
-// SearchResult represents a search result from GitHub
+// ValidQueryDialects lists the query dialects a rule can be searched with
+var ValidQueryDialects = map[string]bool{
+	"legacy":      true,
+	"code_search": true,
+}
+
+// ValidSeverities lists the severities a rule can carry
+var ValidSeverities = map[string]bool{
+	"critical": true,
+	"high":     true,
+	"medium":   true,
+	"low":      true,
+	"info":     true,
+}
+
+// SeverityRank orders severities from info (0) to critical (4), -1 for unknown ones
+func SeverityRank(severity string) int {
+	switch severity {
+	case "critical":
+		return 4
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	case "info":
+		return 0
+	}
+	return -1
+}
+
+// ValidNotifyThreshold reports whether a notify threshold is empty, a severity or
+// a non-negative score
+func ValidNotifyThreshold(threshold string) bool {
+	if threshold == "" || ValidSeverities[threshold] {
+		return true
+	}
+	score, err := strconv.ParseFloat(threshold, 64)
+	return err == nil && score >= 0
+}
+
+// MaxDelay caps the delays between search requests
+const MaxDelay = time.Minute
+
+// ValidDelay reports whether a rule delay is empty or a duration between 0 and MaxDelay
+func ValidDelay(delay string) bool {
+	if delay == "" {
+		return true
+	}
+	d, err := time.ParseDuration(delay)
+	return err == nil && d >= 0 && d <= MaxDelay
+}
+
+// ValidOwner reports whether a rule owner is empty, a user name or a plain email
+// address, at most 255 characters long
+func ValidOwner(owner string) bool {
+	if len(owner) > 255 || strings.ContainsAny(owner, "\r\n") {
+		return false
+	}
+	if !strings.Contains(owner, "@") {
+		return true
+	}
+	address, err := mail.ParseAddress(owner)
+	return err == nil && address.Address == owner
+}
+
+// ValidRepoAge reports whether the repository age limits of a rule are non-negative
+// and, when both are set, the minimum is at most the maximum
+func ValidRepoAge(minDays, maxDays int) bool {
+	return minDays >= 0 && maxDays >= 0 && (maxDays == 0 || minDays <= maxDays)
+}
+
+// MaxExcludeRepos caps the repositories a rule excludes
+const MaxExcludeRepos = 100
+
+var repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// ValidExcludeRepos reports whether the excluded repositories of a rule are empty
+// or a JSON array of at most MaxExcludeRepos owner/name repositories
+func ValidExcludeRepos(reposJSON string) bool {
+	if reposJSON == "" {
+		return true
+	}
+	var repos []string
+	if err := json.Unmarshal([]byte(reposJSON), &repos); err != nil || len(repos) > MaxExcludeRepos {
+		return false
+	}
+	for _, repo := range repos {
+		if !repoNamePattern.MatchString(repo) {
+			return false
+		}
+	}
+	return true
+}
+
+var fileSizePattern = regexp.MustCompile(`^(?:(?:[<>]=?)?\d{1,9}|(\d{1,9})\.\.(\d{1,9}))$`)
+
+// ValidFileSize reports whether a rule's file size is empty or a size: qualifier
+// value, a number of bytes with an optional <, <=, > or >=, or an ascending min..max range
+func ValidFileSize(size string) bool {
+	if size == "" {
+		return true
+	}
+	bounds := fileSizePattern.FindStringSubmatch(size)
+	if bounds == nil {
+		return false
+	}
+	if bounds[1] == "" {
+		return true
+	}
+	min, _ := strconv.Atoi(bounds[1])
+	max, _ := strconv.Atoi(bounds[2])
+	return min <= max
+}
+
+// MeetsNotifyThreshold reports whether a result reaches the notify threshold of its
+// rule, by severity when the threshold is a severity and by score otherwise
+func MeetsNotifyThreshold(threshold string, result SearchResult) bool {
+	if threshold == "" {
+		return true
+	}
+	if ValidSeverities[threshold] {
+		return SeverityRank(result.Severity) >= SeverityRank(threshold)
+	}
+	score, err := strconv.ParseFloat(threshold, 64)
+	return err != nil || result.Score >= score
+}
+
+// ValidResultStatuses lists the triage states a search result can be set to
+var ValidResultStatuses = map[string]bool{
+	"pending":        true,
+	"confirmed":      true,
+	"false_positive": true,
+	"resolved":       true, // remediated, e.g. mitigated in DefectDojo
+}
+
+// Sources a search result can come from
+const (
+	SourceGitHub       = "github"
+	SourceDockerHub    = "dockerhub"
+	SourcePostman      = "postman"
+	SourceNPM          = "npm"
+	SourcePyPI         = "pypi"
+	SourceTyposquat    = "typosquat"     // lookalike repositories found by repository search
+	SourceForkWatch    = "fork_watch"    // changes pushed to forks of our repositories
+	SourceRepoMetadata = "repo_metadata" // repositories whose description or topics contain the keywords
+	SourceGist         = "gist"          // lines added to the public gists of tracked users
+)
+
+// Verdicts suggested by the classifier
+const (
+	VerdictSecret  = "secret"  // looks like a real, usable credential
+	VerdictNoise   = "noise"   // sample, placeholder, test fixture or documentation
+	VerdictUnknown = "unknown" // the model's answer couldn't be used
+)
+
+// ValidVerdicts are the verdicts results can be filtered by
+var ValidVerdicts = map[string]bool{
+	VerdictSecret:  true,
+	VerdictNoise:   true,
+	VerdictUnknown: true,
+}
+
+// ValidResultSources lists the built-in sources, findings of external tools carry
+// their own source names
+var ValidResultSources = map[string]bool{
+	SourceGitHub:       true,
+	SourceDockerHub:    true,
+	SourcePostman:      true,
+	SourceNPM:          true,
+	SourcePyPI:         true,
+	SourceTyposquat:    true,
+	SourceForkWatch:    true,
+	SourceRepoMetadata: true,
+	SourceGist:         true,
+}
+
+var ingestSourcePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ValidIngestSource reports whether findings submitted by external tools can be
+// labeled with source: lowercase letters, digits, - and _, and not a built-in source
+func ValidIngestSource(source string) bool {
+	return ingestSourcePattern.MatchString(source) && !ValidResultSources[source]
+}
+
+// SearchResult represents a search result from GitHub or one of the other sources
 type SearchResult struct {
 	ID           uint           `gorm:"primarykey" json:"id"`
-	RuleID       uint           `gorm:"index;not null" json:"rule_id"`
+	RuleID       uint           `gorm:"index;index:idx_search_results_rule_created,priority:1;not null" json:"rule_id"`
 	Rule         MonitorRule    `gorm:"foreignKey:RuleID" json:"rule,omitempty"`
+	ProjectID    uint           `gorm:"index;not null;default:1" json:"project_id"` // project of the rule
+	Source       string         `gorm:"type:varchar(32);default:'github';index" json:"source"` // github, dockerhub, postman, npm, pypi, typosquat, fork_watch, repo_metadata, gist or the tool that submitted the finding
 	RepoFullName string         `gorm:"type:varchar(255);index;not null" json:"repo_full_name"`
 	RepoURL      string         `gorm:"type:varchar(512)" json:"repo_url"`
 	FilePath     string         `gorm:"type:varchar(512)" json:"file_path"`
 	FileURL      string         `gorm:"type:varchar(512)" json:"file_url"`
+	FileType     string         `gorm:"type:varchar(32);index" json:"file_type,omitempty"` // such as env, key, json or markdown, empty without a file
 	MatchedKeywords string      `gorm:"type:text" json:"matched_keywords"` // JSON array
 	ContentSnippet  string      `gorm:"type:text" json:"content_snippet"`
+	Matches         string      `gorm:"type:text" json:"matches,omitempty"`       // JSON array of every code search match
+	MatchContext    string      `gorm:"type:text" json:"match_context,omitempty"` // numbered file lines around the matches
 	HTMLURL      string         `gorm:"type:varchar(512)" json:"html_url"`
+	EvidenceKey  string         `gorm:"type:varchar(512)" json:"evidence_key,omitempty"` // object storage key of the captured evidence
 	Score        float64        `json:"score"`
-	Status       string         `gorm:"type:varchar(50);default:'pending'" json:"status"` // pending, reviewed, false_positive, confirmed
-	CreatedAt    time.Time      `json:"created_at"`
+	VerifiedMatch bool          `json:"verified_match"` // the file was fetched and contains every keyword of the precise rule
+	InfraMatches string         `gorm:"type:text" json:"infra_matches,omitempty"` // JSON array of internal addresses and hostnames found in the file
+	CIFindings   string         `gorm:"type:text" json:"ci_findings,omitempty"`   // JSON array of hardcoded credentials and misused secrets when the file is a CI config
+	Status       string         `gorm:"type:varchar(50);default:'pending';index:idx_search_results_status_created,priority:1" json:"status"` // pending, confirmed, false_positive, resolved
+	Severity     string         `gorm:"type:varchar(20);default:'medium';index" json:"severity"` // inherited from the rule
+	SnoozedUntil *time.Time     `gorm:"index" json:"snoozed_until,omitempty"` // hidden from the pending queue until then, then resurfaced with a reminder
+	SLAState     string         `gorm:"-" json:"sla_state,omitempty"`    // ok, at_risk or overdue while an SLA runs, set by the API
+	SLADeadline  *time.Time     `gorm:"-" json:"sla_deadline,omitempty"` // when the running SLA ends
+	Verdict           string    `gorm:"type:varchar(20);index" json:"verdict,omitempty"` // suggested by the classifier: secret, noise or unknown
+	VerdictConfidence float64   `json:"verdict_confidence,omitempty"`                      // 0-1
+	VerdictReason     string    `gorm:"type:text" json:"verdict_reason,omitempty"`
+	Fingerprint  string         `gorm:"type:varchar(64);index" json:"fingerprint,omitempty"` // hash of the matched line, the same secret in other files shares it
+	ContentHash  string         `gorm:"type:varchar(64)" json:"content_hash,omitempty"`      // of the matched content when last found, a change is recorded as a revision
+	TokenUsed    string         `gorm:"type:varchar(100)" json:"token_used,omitempty"`       // masked token of the code search that found it
+	IncidentID   *uint          `gorm:"index" json:"incident_id,omitempty"`                  // incident the result is triaged with
+	CreatedAt    time.Time      `gorm:"index;index:idx_search_results_status_created,priority:2;index:idx_search_results_rule_created,priority:2" json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// RuleSample is what a scan of a sample_only rule found in one source, counted
+// instead of recorded as results, so the noise of a candidate keyword can be
+// measured without filling the triage queue
+type RuleSample struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	RuleID      uint      `gorm:"index;not null" json:"rule_id"`
+	ProjectID   uint      `gorm:"index;not null;default:1" json:"project_id"`
+	Source      string    `gorm:"type:varchar(32)" json:"source"`
+	Hits        int       `json:"hits"`                                    // files found after the whitelist and repository filters
+	Repos       int       `json:"repos"`                                   // distinct repositories among them
+	KeywordHits string    `gorm:"type:text" json:"keyword_hits,omitempty"` // JSON object of the hits each keyword matched
+	Sample      string    `gorm:"type:text" json:"sample"`                 // JSON array of SampleHit, chosen at random
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+// SampleHit is a hit kept in the sample of a RuleSample, its snippet masked
+type SampleHit struct {
+	RepoFullName    string   `json:"repo_full_name"`
+	FilePath        string   `json:"file_path"`
+	HTMLURL         string   `json:"html_url"`
+	MatchedKeywords []string `json:"matched_keywords"`
+	ContentSnippet  string   `json:"content_snippet"`
+}
+
+// StagedResult is a new result a scan found while monitor.dry_run was set. It is
+// kept apart from the results, so it notifies nobody and reaches no integration.
+type StagedResult struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	RuleID          uint      `gorm:"index;not null" json:"rule_id"`
+	ProjectID       uint      `gorm:"index;not null;default:1" json:"project_id"`
+	Source          string    `gorm:"type:varchar(32)" json:"source"`
+	RepoFullName    string    `gorm:"type:varchar(255);index;not null" json:"repo_full_name"`
+	FilePath        string    `gorm:"type:varchar(512)" json:"file_path"`
+	HTMLURL         string    `gorm:"type:varchar(512)" json:"html_url"`
+	MatchedKeywords string    `gorm:"type:text" json:"matched_keywords"` // JSON array
+	ContentSnippet  string    `gorm:"type:text" json:"content_snippet"`
+	Score           float64   `json:"score"`
+	Severity        string    `gorm:"type:varchar(20)" json:"severity"`
+	Fingerprint     string    `gorm:"type:varchar(64)" json:"fingerprint,omitempty"`
+	TokenUsed       string    `gorm:"type:varchar(100)" json:"token_used,omitempty"`
+	CreatedAt       time.Time `gorm:"index" json:"created_at"`
+}
+
+// SavedView is a named set of result filters, private to the user who saved it
+// unless shared with the project
+type SavedView struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	Name      string         `gorm:"type:varchar(255);not null" json:"name"`
+	Owner     string         `gorm:"type:varchar(255);index" json:"owner"` // subject of the user who saved it, empty without auth
+	Shared    bool           `json:"shared"`                               // listed for every member of the project
+	Filters   string         `gorm:"type:text" json:"filters"`             // JSON object of results query parameters, e.g. {"status":"pending","severity":"critical"}
+	ProjectID uint           `gorm:"index;not null;default:1" json:"project_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Honeytoken is a canary credential planted where only an attacker would pick it
+// up. Its rule searches for the marker, so any result means the credential leaked.
+type Honeytoken struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	Name        string         `gorm:"type:varchar(255);not null" json:"name"`
+	Kind        string         `gorm:"type:varchar(32);not null" json:"kind"`                // aws_key, database_dsn or api_key
+	Value       string         `gorm:"type:text;not null" json:"value"`                      // the credential to plant
+	Marker      string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"marker"` // unique part of the value the rule searches for
+	Note        string         `gorm:"type:text" json:"note"`                                // where it was planted
+	RuleID      uint           `gorm:"index" json:"rule_id"`
+	ProjectID   uint           `gorm:"index;not null;default:1" json:"project_id"`
+	TriggeredAt *time.Time     `json:"triggered_at,omitempty"` // first result
+	Triggers    int            `json:"triggers"`               // results found so far
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+
+// Incident groups related results, such as one secret copied into many files or
+// the files of one repository, so a leak is triaged once. Its status applies to
+// every result of it.
+type Incident struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	Title       string         `gorm:"type:varchar(255);not null" json:"title"`
+	Status      string         `gorm:"type:varchar(50);default:'pending';index" json:"status"` // pending, confirmed, false_positive, resolved
+	Assignee    string         `gorm:"type:varchar(255);index" json:"assignee"`
+	GroupBy     string         `gorm:"type:varchar(32)" json:"group_by"`                        // fingerprint, repository, owner or manual
+	GroupValue  string         `gorm:"type:varchar(255)" json:"group_value"`                    // what the results have in common
+	Severity    string         `gorm:"type:varchar(20);default:'medium';index" json:"severity"` // highest of the results
+	ResultCount int            `json:"result_count"`
+	ProjectID   uint           `gorm:"index;not null;default:1" json:"project_id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// What the results of an incident have in common
+const (
+	GroupFingerprint = "fingerprint" // the same matched line, usually the same secret
+	GroupRepository  = "repository"
+	GroupOwner       = "owner"  // the same leaker, across repositories
+	GroupManual      = "manual" // split off by hand
+)
+
+// ValidGroupings lists what results can be grouped into incidents by
+var ValidGroupings = map[string]bool{
+	GroupFingerprint: true,
+	GroupRepository:  true,
+	GroupOwner:       true,
+}
+
+// IncidentEvent is an entry of the timeline of an incident
+type IncidentEvent struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	IncidentID uint      `gorm:"index;not null" json:"incident_id"`
+	Type       string    `gorm:"type:varchar(32);not null" json:"type"` // created, results_added, status, assigned, renamed, merged, split
+	Actor      string    `gorm:"type:varchar(255)" json:"actor"`        // empty for the monitor
+	Detail     string    `gorm:"type:text" json:"detail"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// Types of incident timeline entries
+const (
+	IncidentCreated      = "created"
+	IncidentResultsAdded = "results_added"
+	IncidentStatus       = "status"
+	IncidentAssigned     = "assigned"
+	IncidentRenamed      = "renamed"
+	IncidentMerged       = "merged"
+	IncidentSplit        = "split"
+)
+// ResultRevision records that a scan found the file of a result again with other
+// content, the file may hold a new secret
+type ResultRevision struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	ResultID        uint      `gorm:"index;not null" json:"result_id"`
+	ProjectID       uint      `gorm:"index;not null;default:1" json:"project_id"`
+	PreviousHash    string    `gorm:"type:varchar(64)" json:"previous_hash"`
+	ContentHash     string    `gorm:"type:varchar(64)" json:"content_hash"`
+	PreviousSnippet string    `gorm:"type:text" json:"previous_snippet"`
+	ContentSnippet  string    `gorm:"type:text" json:"content_snippet"`
+	HTMLURL         string    `gorm:"type:varchar(512)" json:"html_url"`
+	Reopened        bool      `json:"reopened"` // the closed result went back to pending
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ShareLink grants read-only access to a single result without a login, for the
+// repository owner or a partner helping with remediation. Only the hash of its
+// token is stored.
+type ShareLink struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	TokenHash    string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ResultID     uint       `gorm:"index;not null" json:"result_id"`
+	ProjectID    uint       `gorm:"index;not null;default:1" json:"project_id"`
+	CreatedBy    string     `gorm:"type:varchar(255)" json:"created_by"`
+	Note         string     `gorm:"type:varchar(255)" json:"note"` // who the link was given to
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	Views        int        `json:"views"`
+	LastViewedAt *time.Time `json:"last_viewed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RuleWebhook is an outbound webhook that receives every new result of a rule as
+// JSON, for machines such as a SOAR rather than people
+type RuleWebhook struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	RuleID    uint      `gorm:"index;not null" json:"rule_id"`
+	ProjectID uint      `gorm:"index;not null;default:1" json:"project_id"`
+	Name      string    `gorm:"type:varchar(255)" json:"name"`
+	URL       string    `gorm:"type:varchar(512);not null" json:"url"`
+	Secret    string    `gorm:"type:varchar(255)" json:"-"` // signs the deliveries, only returned on creation
+	Enabled   bool      `gorm:"default:true" json:"enabled"`
+	CreatedBy string    `gorm:"type:varchar(255)" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is a new result queued for, or delivered to, a rule webhook
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	WebhookID     uint       `gorm:"index;not null" json:"webhook_id"`
+	ResultID      uint       `gorm:"index" json:"result_id"`
+	ProjectID     uint       `gorm:"index;not null;default:1" json:"project_id"`
+	Payload       string     `gorm:"type:text" json:"-"` // JSON body, as of when the result was found
+	Status        string     `gorm:"type:varchar(20);index" json:"status"` // pending, delivered or failed once the attempts ran out
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index" json:"next_attempt_at"`
+	ResponseCode  int        `json:"response_code,omitempty"` // of the last attempt
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
 // Whitelist represents whitelisted repositories or users
 type Whitelist struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
 	Type        string         `gorm:"type:varchar(50);not null" json:"type"` // "user" or "repo"
-	Value       string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"value"`
+	Value       string         `gorm:"type:varchar(255);uniqueIndex:idx_whitelists_project_value,priority:2;not null" json:"value"`
 	Description string         `gorm:"type:text" json:"description"`
+	ProjectID   uint           `gorm:"uniqueIndex:idx_whitelists_project_value,priority:1;not null;default:1" json:"project_id"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -73,9 +528,10 @@ type ScanHistory struct {
 	ResultsCount int       `json:"results_count"`
 	NewResults   int       `json:"new_results"`
 	TokenUsed    string    `gorm:"type:varchar(100)" json:"token_used"`
-	Status       string    `gorm:"type:varchar(50);default:'success'" json:"status"` // success, failed, rate_limited
+	Status       string    `gorm:"type:varchar(50);default:'success'" json:"status"` // success, failed, rate_limited, invalid_query
 	ErrorMessage string    `gorm:"type:text" json:"error_message"`
 	Duration     int       `json:"duration"` // in seconds
+	IncompletePages string `gorm:"type:varchar(64)" json:"incomplete_pages,omitempty"` // code search pages GitHub answered with incomplete results, such as "2,5"
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -89,7 +545,126 @@ type NotificationConfig struct {
 	Secret      string         `gorm:"type:varchar(255)" json:"secret,omitempty"`
 	NotifyOnNew bool           `gorm:"default:true" json:"notify_on_new"`     // Notify on new leaks
 	NotifyOnConfirmed bool    `gorm:"default:true" json:"notify_on_confirmed"` // Notify on confirmed leaks
+	ProjectID   uint           `gorm:"index;not null;default:1" json:"project_id"` // only notified of the project's results
+	Locale      string         `gorm:"type:varchar(10)" json:"locale"` // en or zh, empty for the global locale
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// Session represents a login session backing issued access and refresh tokens
+type Session struct {
+	ID               uint       `gorm:"primarykey" json:"id"`
+	SessionID        string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"session_id"`
+	Subject          string     `gorm:"type:varchar(255);index" json:"subject"`
+	Role             string     `gorm:"type:varchar(50)" json:"role"`
+	RefreshTokenHash string     `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	IPAddress        string     `gorm:"type:varchar(64)" json:"ip_address"`
+	UserAgent        string     `gorm:"type:varchar(512)" json:"user_agent"`
+	ExpiresAt        time.Time  `json:"expires_at"` // refresh token expiry
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// AuditLog records a mutating API request for compliance purposes
+type AuditLog struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	Actor      string    `gorm:"type:varchar(255);index" json:"actor"`
+	Role       string    `gorm:"type:varchar(50)" json:"role"`
+	Method     string    `gorm:"type:varchar(10)" json:"method"`
+	Path       string    `gorm:"type:varchar(512)" json:"path"`
+	Resource   string    `gorm:"type:varchar(100);index" json:"resource"`
+	ResourceID string    `gorm:"type:varchar(100)" json:"resource_id"`
+	StatusCode int       `json:"status_code"`
+	Request    string    `gorm:"type:text" json:"request"` // JSON, sensitive fields masked
+	Before     string    `gorm:"type:text" json:"before"`  // JSON, sensitive fields masked
+	After      string    `gorm:"type:text" json:"after"`   // JSON, sensitive fields masked
+	IPAddress  string    `gorm:"type:varchar(64)" json:"ip_address"`
+	UserAgent  string    `gorm:"type:varchar(512)" json:"user_agent"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// Setting is a runtime configuration value overriding config.yaml
+type Setting struct {
+	Key       string    `gorm:"primarykey;type:varchar(100)" json:"key"`
+	Value     string    `gorm:"type:text" json:"value"` // JSON encoded
+	UpdatedBy string    `gorm:"type:varchar(255)" json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DefectDojoFinding links a result to the DefectDojo finding it was pushed as
+type DefectDojoFinding struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	ResultID     uint      `gorm:"uniqueIndex;not null" json:"result_id"`
+	FindingID    int       `gorm:"index;not null" json:"finding_id"`
+	TestID       int       `gorm:"index" json:"test_id"`
+	SyncedStatus string    `gorm:"type:varchar(50)" json:"synced_status"` // result status at the last sync
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RuleRevision records a change to a monitor rule
+type RuleRevision struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	RuleID         uint      `gorm:"index;not null" json:"rule_id"`
+	Action         string    `gorm:"type:varchar(20)" json:"action"` // create, update or delete
+	Note           string    `gorm:"type:varchar(255)" json:"note,omitempty"` // e.g. the revision a rollback restored
+	KeywordsBefore string    `gorm:"type:text" json:"keywords_before"`
+	KeywordsAfter  string    `gorm:"type:text" json:"keywords_after"`
+	Before         string    `gorm:"type:text" json:"before"` // JSON snapshot, empty on create
+	After          string    `gorm:"type:text" json:"after"`  // JSON snapshot, empty on delete
+	Actor          string    `gorm:"type:varchar(255);index" json:"actor"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// CompanyProfile describes the company for generating rules, there is a single row
+type CompanyProfile struct {
+	ID              uint      `gorm:"primarykey" json:"-"`
+	Domains         string    `gorm:"type:text" json:"domains"`          // JSON array
+	BrandNames      string    `gorm:"type:text" json:"brand_names"`      // JSON array
+	InternalDomains string    `gorm:"type:text" json:"internal_domains"` // JSON array
+	EmailSuffixes   string    `gorm:"type:text" json:"email_suffixes"`   // JSON array
+	UpdatedBy       string    `gorm:"type:varchar(255)" json:"updated_by"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// SyncedDefinition links a rule or whitelist entry to its definition in the rules
+// repository, with the state last applied from it to detect changes made elsewhere
+type SyncedDefinition struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Kind      string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_synced_definition" json:"kind"`  // rule or whitelist
+	Name      string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_synced_definition" json:"name"` // rule name or whitelist value
+	File      string    `gorm:"type:varchar(500)" json:"file"` // path in the repository
+	TargetID  uint      `gorm:"not null" json:"target_id"`
+	Applied   string    `gorm:"type:text" json:"applied"` // JSON of the state written to the target
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Lease is held by the cluster leader and renewed until it steps down
+type Lease struct {
+	Name      string    `gorm:"primarykey;type:varchar(100)" json:"name"`
+	Holder    string    `gorm:"type:varchar(255);not null" json:"holder"` // instance id
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+}
+
+// Checkpoint records how far a background job got, so it resumes there after a restart
+type Checkpoint struct {
+	Name      string    `gorm:"primarykey;type:varchar(100)" json:"name"`
+	Value     string    `gorm:"type:text" json:"value"` // JSON encoded, up to the job
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Report represents a generated summary report
+type Report struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Summary     string    `gorm:"type:text" json:"summary"` // JSON encoded report data
+	HTML        string    `gorm:"size:16777215" json:"-"` // mediumtext on MySQL, text on Postgres
+	StorageKey  string    `gorm:"type:varchar(512)" json:"storage_key,omitempty"` // object storage copy of the HTML
+	Delivered   bool      `json:"delivered"`
+	Error       string    `gorm:"type:text" json:"error"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
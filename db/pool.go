@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github-monitor/config"
+)
+
+// pingState holds the result of the last background health check
+var pingState struct {
+	mu       sync.RWMutex
+	lastPing time.Time
+	lastErr  error
+	failures int
+}
+
+// configurePool applies the connection pool limits from the config
+func configurePool(sqlDB *sql.DB, cfg *config.DatabaseConfig) {
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if d, err := time.ParseDuration(cfg.ConnMaxLifetime); err == nil {
+		sqlDB.SetConnMaxLifetime(d)
+	}
+	if d, err := time.ParseDuration(cfg.ConnMaxIdleTime); err == nil {
+		sqlDB.SetConnMaxIdleTime(d)
+	}
+}
+
+// StartHealthCheck pings the database periodically. When a ping fails the idle
+// connections are dropped so the pool reconnects instead of handing out dead ones.
+// The returned function stops the check.
+func StartHealthCheck(cfg *config.DatabaseConfig) func() {
+	interval, err := time.ParseDuration(cfg.PingInterval)
+	if err != nil || interval <= 0 {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ping(cfg)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() { close(stop) }
+}
+
+func ping(cfg *config.DatabaseConfig) {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = sqlDB.PingContext(ctx)
+
+	pingState.mu.Lock()
+	pingState.lastPing = time.Now()
+	pingState.lastErr = err
+	if err != nil {
+		pingState.failures++
+	} else {
+		if pingState.failures > 0 {
+			log.Printf("Database connection recovered after %d failed pings", pingState.failures)
+		}
+		pingState.failures = 0
+	}
+	failures := pingState.failures
+	pingState.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Database ping failed (%d in a row): %v", failures, err)
+		// Close idle connections, new ones are dialed on the next query
+		idle := 2 // database/sql default
+		if cfg.Driver != "sqlite" && cfg.MaxIdleConns > 0 {
+			idle = cfg.MaxIdleConns
+		}
+		sqlDB.SetMaxIdleConns(-1)
+		sqlDB.SetMaxIdleConns(idle)
+	}
+}
+
+// PoolStatus describes the connection pool and the last background health check
+type PoolStatus struct {
+	MaxOpen          int        `json:"max_open"`
+	Open             int        `json:"open"`
+	InUse            int        `json:"in_use"`
+	Idle             int        `json:"idle"`
+	WaitCount        int64      `json:"wait_count"`
+	WaitDurationMs   int64      `json:"wait_duration_ms"`
+	MaxIdleClosed    int64      `json:"max_idle_closed"`
+	MaxLifeClosed    int64      `json:"max_lifetime_closed"`
+	LastPing         *time.Time `json:"last_ping,omitempty"`
+	LastPingError    string     `json:"last_ping_error,omitempty"`
+	FailedPingsInRow int        `json:"failed_pings_in_row"`
+}
+
+// Pool returns the current connection pool status
+func Pool() (*PoolStatus, error) {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := sqlDB.Stats()
+	status := &PoolStatus{
+		MaxOpen:        stats.MaxOpenConnections,
+		Open:           stats.OpenConnections,
+		InUse:          stats.InUse,
+		Idle:           stats.Idle,
+		WaitCount:      stats.WaitCount,
+		WaitDurationMs: stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:  stats.MaxIdleClosed + stats.MaxIdleTimeClosed,
+		MaxLifeClosed:  stats.MaxLifetimeClosed,
+	}
+
+	pingState.mu.RLock()
+	defer pingState.mu.RUnlock()
+	if !pingState.lastPing.IsZero() {
+		lastPing := pingState.lastPing
+		status.LastPing = &lastPing
+	}
+	if pingState.lastErr != nil {
+		status.LastPingError = pingState.lastErr.Error()
+	}
+	status.FailedPingsInRow = pingState.failures
+
+	return status, nil
+}
@@ -0,0 +1,172 @@
+package defectdojo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls the DefectDojo API v2
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the DefectDojo instance at baseURL
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/") + "/api/v2",
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Finding is the part of a DefectDojo finding the sync reads and writes
+type Finding struct {
+	ID                int      `json:"id,omitempty"`
+	Test              int      `json:"test"`
+	Title             string   `json:"title"`
+	Description       string   `json:"description"`
+	Severity          string   `json:"severity"`
+	NumericalSeverity string   `json:"numerical_severity"`
+	FilePath          string   `json:"file_path,omitempty"`
+	References        string   `json:"references,omitempty"`
+	UniqueIDFromTool  string   `json:"unique_id_from_tool,omitempty"`
+	FoundBy           []int    `json:"found_by"`
+	Tags              []string `json:"tags,omitempty"`
+	Active            bool     `json:"active"`
+	Verified          bool     `json:"verified"`
+	StaticFinding     bool     `json:"static_finding"`
+	FalsePositive     bool     `json:"false_p"`
+	IsMitigated       bool     `json:"is_mitigated"`
+	RiskAccepted      bool     `json:"risk_accepted"`
+	Date              string   `json:"date"`
+}
+
+type page[T any] struct {
+	Next    string `json:"next"`
+	Results []T    `json:"results"`
+}
+
+type named struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// TestTypeID returns the id of the test type with the given name, creating it if missing
+func (c *Client) TestTypeID(ctx context.Context, name string) (int, error) {
+	var found page[named]
+	if err := c.do(ctx, http.MethodGet, "/test_types/?"+url.Values{"name": {name}}.Encode(), nil, &found); err != nil {
+		return 0, err
+	}
+	for _, t := range found.Results {
+		if t.Name == name {
+			return t.ID, nil
+		}
+	}
+
+	var created named
+	if err := c.do(ctx, http.MethodPost, "/test_types/", map[string]interface{}{"name": name, "static_tool": true}, &created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// TestID returns the test of the given type in an engagement, creating it if missing
+func (c *Client) TestID(ctx context.Context, engagementID, testTypeID int, title string) (int, error) {
+	params := url.Values{
+		"engagement": {fmt.Sprint(engagementID)},
+		"test_type":  {fmt.Sprint(testTypeID)},
+		"title":      {title},
+	}
+	var found page[named]
+	if err := c.do(ctx, http.MethodGet, "/tests/?"+params.Encode(), nil, &found); err != nil {
+		return 0, err
+	}
+	if len(found.Results) > 0 {
+		return found.Results[0].ID, nil
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	var created named
+	err := c.do(ctx, http.MethodPost, "/tests/", map[string]interface{}{
+		"engagement":   engagementID,
+		"test_type":    testTypeID,
+		"title":        title,
+		"target_start": now,
+		"target_end":   now,
+	}, &created)
+	return created.ID, err
+}
+
+// CreateFinding creates the finding and returns its id
+func (c *Client) CreateFinding(ctx context.Context, finding *Finding) (int, error) {
+	var created Finding
+	if err := c.do(ctx, http.MethodPost, "/findings/", finding, &created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// Findings returns every finding of a test
+func (c *Client) Findings(ctx context.Context, testID int) ([]Finding, error) {
+	var findings []Finding
+	path := "/findings/?" + url.Values{"test": {fmt.Sprint(testID)}, "limit": {"500"}}.Encode()
+	for path != "" {
+		var p page[Finding]
+		if err := c.do(ctx, http.MethodGet, path, nil, &p); err != nil {
+			return nil, err
+		}
+		findings = append(findings, p.Results...)
+
+		// next is an absolute URL, which may name another host behind a proxy
+		path = ""
+		if _, rest, ok := strings.Cut(p.Next, "/api/v2"); ok {
+			path = rest
+		}
+	}
+	return findings, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("defectdojo request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("defectdojo %s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
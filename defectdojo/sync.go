@@ -0,0 +1,298 @@
+// Package defectdojo pushes confirmed results into DefectDojo and pulls their
+// remediation status back, so leaks are tracked in the same vulnerability
+// management pipeline as scanner findings.
+package defectdojo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/events"
+	"github-monitor/reporting"
+
+	"gorm.io/gorm"
+)
+
+// pushBatch caps the findings created per sync, the rest follow on the next one
+const pushBatch = 200
+
+// Syncer periodically exchanges findings with DefectDojo
+type Syncer struct {
+	cfg      *config.DefectDojoConfig
+	db       *gorm.DB
+	client   *Client
+	interval time.Duration
+	stopChan chan struct{} // nil while stopped
+}
+
+// NewSyncer creates a syncer for the configured DefectDojo instance
+func NewSyncer(cfg *config.DefectDojoConfig, database *gorm.DB) (*Syncer, error) {
+	interval, err := time.ParseDuration(cfg.SyncInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sync interval: %w", err)
+	}
+	return &Syncer{
+		cfg:      cfg,
+		db:       database,
+		client:   NewClient(cfg.URL, cfg.APIKey),
+		interval: interval,
+	}, nil
+}
+
+// Start runs the sync in the background, it can be started again after Stop
+func (s *Syncer) Start() {
+	if s.stopChan != nil {
+		return
+	}
+	s.stopChan = make(chan struct{})
+	go s.run(s.stopChan)
+	log.Printf("DefectDojo sync started, every %s", s.interval)
+}
+
+// Stop stops the sync
+func (s *Syncer) Stop() {
+	if s.stopChan == nil {
+		return
+	}
+	close(s.stopChan)
+	s.stopChan = nil
+}
+
+func (s *Syncer) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.syncOnce()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Syncer) syncOnce() {
+	defer reporting.Recover(reporting.Tags{"component": "defectdojo"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+	defer cancel()
+	if err := s.Sync(ctx); err != nil {
+		log.Printf("DefectDojo sync failed: %v", err)
+		reporting.CaptureError(err, reporting.Tags{"component": "defectdojo"})
+	}
+}
+
+// Sync pushes confirmed results that aren't in DefectDojo yet, then applies status
+// changes made in DefectDojo to the linked results
+func (s *Syncer) Sync(ctx context.Context) error {
+	pushErr := s.push(ctx)
+	pullErr := s.pull(ctx)
+	return errors.Join(pushErr, pullErr)
+}
+
+func (s *Syncer) push(ctx context.Context) error {
+	// Deleted rules are loaded too, so their results are mapped like the query maps them
+	query := s.db.WithContext(ctx).Preload("Rule", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Where("status = ?", "confirmed").
+		Where("id NOT IN (?)", s.db.Model(&models.DefectDojoFinding{}).Select("result_id"))
+
+	// Without a default engagement only results of mapped rules are pushed. They are
+	// picked in the query, otherwise unmapped results would fill every batch.
+	if s.cfg.EngagementID == 0 {
+		names := make([]string, 0, len(s.cfg.Engagements))
+		for _, mapping := range s.cfg.Engagements {
+			names = append(names, strings.ToLower(mapping.Rule))
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		query = query.Where("rule_id IN (?)", s.db.Unscoped().Model(&models.MonitorRule{}).Select("id").Where("LOWER(name) IN ?", names))
+	}
+
+	var results []models.SearchResult
+	err := query.Order("id").Limit(pushBatch).Find(&results).Error
+	if err != nil || len(results) == 0 {
+		return err
+	}
+
+	testTypeID, err := s.client.TestTypeID(ctx, s.cfg.TestType)
+	if err != nil {
+		return err
+	}
+
+	tests := make(map[int]int) // engagement id -> test id
+	pushed := 0
+	for _, result := range results {
+		engagementID := s.engagementFor(result.Rule.Name)
+		if engagementID == 0 {
+			continue
+		}
+
+		testID, ok := tests[engagementID]
+		if !ok {
+			testID, err = s.client.TestID(ctx, engagementID, testTypeID, s.cfg.TestType)
+			if err != nil {
+				return fmt.Errorf("engagement %d: %w", engagementID, err)
+			}
+			tests[engagementID] = testID
+		}
+
+		findingID, err := s.client.CreateFinding(ctx, newFinding(&result, testID, testTypeID))
+		if err != nil {
+			return fmt.Errorf("result %d: %w", result.ID, err)
+		}
+
+		link := models.DefectDojoFinding{
+			ResultID:     result.ID,
+			FindingID:    findingID,
+			TestID:       testID,
+			SyncedStatus: "confirmed",
+		}
+		if err := s.db.WithContext(ctx).Create(&link).Error; err != nil {
+			return fmt.Errorf("failed to link result %d to finding %d: %w", result.ID, findingID, err)
+		}
+		pushed++
+	}
+
+	if pushed > 0 {
+		log.Printf("Pushed %d confirmed results to DefectDojo", pushed)
+	}
+	return nil
+}
+
+// pull applies the status of linked findings. Only changes made in DefectDojo
+// since the last sync are applied, so local triage isn't overwritten each time.
+func (s *Syncer) pull(ctx context.Context) error {
+	var links []models.DefectDojoFinding
+	if err := s.db.WithContext(ctx).Find(&links).Error; err != nil {
+		return err
+	}
+
+	byTest := make(map[int][]*models.DefectDojoFinding)
+	for i := range links {
+		byTest[links[i].TestID] = append(byTest[links[i].TestID], &links[i])
+	}
+
+	changed := make(map[string][]uint) // status -> result ids
+	for testID, testLinks := range byTest {
+		findings, err := s.client.Findings(ctx, testID)
+		if err != nil {
+			return fmt.Errorf("test %d: %w", testID, err)
+		}
+		byID := make(map[int]*Finding, len(findings))
+		for i := range findings {
+			byID[findings[i].ID] = &findings[i]
+		}
+
+		for _, link := range testLinks {
+			finding, ok := byID[link.FindingID]
+			if !ok {
+				continue // deleted in DefectDojo, the result keeps its status
+			}
+			status := resultStatus(finding)
+			if status == link.SyncedStatus {
+				continue
+			}
+
+			link.SyncedStatus = status
+			if err := s.db.WithContext(ctx).Save(link).Error; err != nil {
+				return err
+			}
+			changed[status] = append(changed[status], link.ResultID)
+		}
+	}
+
+	for status, ids := range changed {
+		err := s.db.WithContext(ctx).Model(&models.SearchResult{}).Where("id IN ?", ids).Update("status", status).Error
+		if err != nil {
+			return err
+		}
+		events.Publish(events.TypeResultStatus, events.StatusChange{IDs: ids, Status: status})
+		log.Printf("DefectDojo set %d results to %s", len(ids), status)
+	}
+	return nil
+}
+
+// engagementFor returns the engagement of a rule, 0 when the rule isn't mapped and
+// there is no default
+func (s *Syncer) engagementFor(ruleName string) int {
+	for _, mapping := range s.cfg.Engagements {
+		if strings.EqualFold(mapping.Rule, ruleName) {
+			return mapping.EngagementID
+		}
+	}
+	return s.cfg.EngagementID
+}
+
+// resultStatus maps the state of a finding to a result status
+func resultStatus(finding *Finding) string {
+	switch {
+	case finding.FalsePositive:
+		return "false_positive"
+	case finding.IsMitigated:
+		return "resolved"
+	default:
+		return "confirmed"
+	}
+}
+
+var severities = map[string][2]string{
+	"critical": {"Critical", "S0"},
+	"high":     {"High", "S1"},
+	"medium":   {"Medium", "S2"},
+	"low":      {"Low", "S3"},
+	"info":     {"Info", "S4"},
+}
+
+func newFinding(result *models.SearchResult, testID, testTypeID int) *Finding {
+	severity, ok := severities[result.Severity]
+	if !ok {
+		severity = severities["medium"]
+	}
+
+	var keywords []string
+	_ = json.Unmarshal([]byte(result.MatchedKeywords), &keywords)
+
+	location := result.RepoFullName
+	if result.FilePath != "" {
+		location += "/" + result.FilePath
+	}
+
+	var desc strings.Builder
+	fmt.Fprintf(&desc, "**Rule:** %s\n\n", result.Rule.Name)
+	fmt.Fprintf(&desc, "**Source:** %s\n\n", result.Source)
+	fmt.Fprintf(&desc, "**Repository:** %s\n\n", result.RepoFullName)
+	if result.FilePath != "" {
+		fmt.Fprintf(&desc, "**File:** %s\n\n", result.FilePath)
+	}
+	fmt.Fprintf(&desc, "**Matched keywords:** %s\n\n", strings.Join(keywords, ", "))
+	if result.ContentSnippet != "" {
+		fmt.Fprintf(&desc, "```\n%s\n```\n", result.ContentSnippet)
+	}
+
+	return &Finding{
+		Test:              testID,
+		Title:             "Leak in " + location,
+		Description:       desc.String(),
+		Severity:          severity[0],
+		NumericalSeverity: severity[1],
+		FilePath:          result.FilePath,
+		References:        result.HTMLURL,
+		UniqueIDFromTool:  fmt.Sprintf("github-monitor-%d", result.ID),
+		FoundBy:           []int{testTypeID},
+		Tags:              []string{"github-monitor", result.Source},
+		Active:            true,
+		Verified:          true,
+		StaticFinding:     true,
+		Date:              result.CreatedAt.Format("2006-01-02"),
+	}
+}
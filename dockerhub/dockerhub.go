@@ -0,0 +1,113 @@
+// Package dockerhub searches public Docker Hub repositories, so leaked internal
+// images are found the same way as leaked code.
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://hub.docker.com"
+	pageSize       = 100
+)
+
+// Repository is a Docker Hub repository returned by the search
+type Repository struct {
+	Name        string `json:"repo_name"`
+	Description string `json:"short_description"`
+	Official    bool   `json:"is_official"`
+	Stars       int    `json:"star_count"`
+	Pulls       int64  `json:"pull_count"`
+}
+
+// URL returns the Docker Hub page of the repository
+func (r *Repository) URL() string {
+	if r.Official {
+		return defaultBaseURL + "/_/" + r.Name
+	}
+	return defaultBaseURL + "/r/" + r.Name
+}
+
+// FullName returns the name in namespace/repository form, official images live in "library"
+func (r *Repository) FullName() string {
+	if !strings.Contains(r.Name, "/") {
+		return "library/" + r.Name
+	}
+	return r.Name
+}
+
+// Client talks to the public Docker Hub search API, which needs no credentials
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxPages   int
+}
+
+// NewClient creates a client reading up to maxPages pages of 100 results per search
+func NewClient(maxPages int) *Client {
+	if maxPages < 1 {
+		maxPages = 1
+	}
+	return &Client{
+		baseURL:    defaultBaseURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		maxPages:   maxPages,
+	}
+}
+
+type searchResponse struct {
+	Count   int          `json:"count"`
+	Next    string       `json:"next"`
+	Results []Repository `json:"results"`
+}
+
+// Search returns the repositories Docker Hub finds for the query
+func (c *Client) Search(ctx context.Context, query string) ([]Repository, error) {
+	var repos []Repository
+
+	for page := 1; page <= c.maxPages; page++ {
+		params := url.Values{
+			"query":     {query},
+			"page":      {fmt.Sprint(page)},
+			"page_size": {fmt.Sprint(pageSize)},
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v2/search/repositories/?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("docker hub search failed: %w", err)
+		}
+
+		var body searchResponse
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			err = fmt.Errorf("docker hub rate limit exceeded")
+		case resp.StatusCode != http.StatusOK:
+			err = fmt.Errorf("docker hub search returned %s", resp.Status)
+		default:
+			err = json.NewDecoder(resp.Body).Decode(&body)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		repos = append(repos, body.Results...)
+		if body.Next == "" || len(body.Results) == 0 {
+			break
+		}
+	}
+
+	return repos, nil
+}
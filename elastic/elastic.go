@@ -0,0 +1,301 @@
+// Package elastic indexes search results into Elasticsearch or OpenSearch, so they
+// can be searched and charted next to other security data without querying the
+// monitor's database.
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/repository"
+)
+
+const (
+	// batchSize is the number of results sent in one bulk request
+	batchSize = 500
+	// checkpointName stores the position of the last indexed change
+	checkpointName = "elasticsearch"
+	// settleTime keeps changes of transactions that may still be committing with an
+	// earlier update time out of the current pass
+	settleTime = 5 * time.Second
+)
+
+// Sink indexes every new and updated result. It follows the update time of the
+// results from a checkpoint in the database, so nothing is missed while the cluster
+// is down, the sink falls behind or the server restarts.
+type Sink struct {
+	cfg        *config.ElasticsearchConfig
+	repos      *repository.Repositories
+	httpClient *http.Client
+	template   []byte
+	interval   time.Duration
+	stopChan   chan struct{}
+	done       chan struct{}
+}
+
+// NewSink creates a sink, reading the index template file if one is configured
+func NewSink(cfg *config.ElasticsearchConfig, repos *repository.Repositories) (*Sink, error) {
+	interval, err := time.ParseDuration(cfg.FlushInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flush interval: %w", err)
+	}
+
+	template := []byte(defaultTemplate(cfg.Index))
+	if cfg.TemplateFile != "" {
+		template, err = os.ReadFile(cfg.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read index template: %w", err)
+		}
+		if !json.Valid(template) {
+			return nil, fmt.Errorf("index template %s is not valid JSON", cfg.TemplateFile)
+		}
+	}
+
+	return &Sink{
+		cfg:        cfg,
+		repos:      repos,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		template:   template,
+		interval:   interval,
+		stopChan:   make(chan struct{}),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// Start installs the index template and starts indexing in the background
+func (s *Sink) Start() {
+	go func() {
+		defer close(s.done)
+		s.run()
+	}()
+	log.Printf("Indexing results into %s, index %s", s.cfg.URL, s.cfg.Index)
+}
+
+// Stop indexes the changes made so far and stops the sink
+func (s *Sink) Stop() {
+	close(s.stopChan)
+	<-s.done
+}
+
+func (s *Sink) run() {
+	ctx := context.Background()
+	templateInstalled := s.putTemplate(ctx) == nil
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if !templateInstalled {
+			templateInstalled = s.putTemplate(ctx) == nil
+		}
+		if err := s.flush(ctx); err != nil {
+			log.Printf("Failed to index results, retrying on the next flush: %v", err)
+		}
+	}
+
+	flush()
+	for {
+		select {
+		case <-ticker.C:
+			flush()
+		case <-s.stopChan:
+			flush()
+			return
+		}
+	}
+}
+
+// flush indexes the results changed since the checkpoint in batches, moving the
+// checkpoint past every batch that was sent. A failed batch is retried from there.
+func (s *Sink) flush(ctx context.Context) error {
+	var position repository.ChangePosition
+	if _, err := s.repos.Checkpoints.Get(ctx, checkpointName, &position); err != nil {
+		return err
+	}
+
+	until := time.Now().Add(-settleTime)
+	for {
+		results, err := s.repos.Results.ListChanged(ctx, position, until, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+
+		if err := s.indexBatch(ctx, results); err != nil {
+			return err
+		}
+
+		last := results[len(results)-1]
+		position = repository.ChangePosition{UpdatedAt: last.UpdatedAt, ID: last.ID}
+		if err := s.repos.Checkpoints.Set(ctx, checkpointName, position); err != nil {
+			return err
+		}
+		if len(results) < batchSize {
+			return nil
+		}
+	}
+}
+
+// document is the indexed form of a result
+type document struct {
+	ID              uint      `json:"id"`
+	RuleID          uint      `json:"rule_id"`
+	RuleName        string    `json:"rule_name"`
+	Source          string    `json:"source"`
+	RepoFullName    string    `json:"repo_full_name"`
+	RepoURL         string    `json:"repo_url"`
+	FilePath        string    `json:"file_path"`
+	FileURL         string    `json:"file_url"`
+	HTMLURL         string    `json:"html_url"`
+	MatchedKeywords []string  `json:"matched_keywords"`
+	ContentSnippet  string    `json:"content_snippet"`
+	Score           float64   `json:"score"`
+	Status          string    `json:"status"`
+	Severity        string    `json:"severity"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (s *Sink) indexBatch(ctx context.Context, results []models.SearchResult) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, result := range results {
+		var keywords []string
+		_ = json.Unmarshal([]byte(result.MatchedKeywords), &keywords)
+
+		action := map[string]map[string]interface{}{
+			"index": {"_index": s.cfg.Index, "_id": fmt.Sprint(result.ID)},
+		}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(document{
+			ID:              result.ID,
+			RuleID:          result.RuleID,
+			RuleName:        result.Rule.Name,
+			Source:          result.Source,
+			RepoFullName:    result.RepoFullName,
+			RepoURL:         result.RepoURL,
+			FilePath:        result.FilePath,
+			FileURL:         result.FileURL,
+			HTMLURL:         result.HTMLURL,
+			MatchedKeywords: keywords,
+			ContentSnippet:  result.ContentSnippet,
+			Score:           result.Score,
+			Status:          result.Status,
+			Severity:        result.Severity,
+			CreatedAt:       result.CreatedAt,
+			UpdatedAt:       result.UpdatedAt,
+		}); err != nil {
+			return err
+		}
+	}
+
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &body, &resp); err != nil {
+		return err
+	}
+	// Rejected documents usually don't fit the mapping, retrying them won't help
+	if resp.Errors {
+		for _, item := range resp.Items {
+			for _, op := range item {
+				if op.Status >= 300 {
+					log.Printf("Elasticsearch rejected a result: %s: %s", op.Error.Type, op.Error.Reason)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// putTemplate installs the index template, so the index is created with the right
+// mappings however it comes into existence
+func (s *Sink) putTemplate(ctx context.Context) error {
+	err := s.do(ctx, http.MethodPut, "/_index_template/"+s.cfg.Index, "application/json", bytes.NewReader(s.template), nil)
+	if err != nil {
+		log.Printf("Failed to install index template %s: %v", s.cfg.Index, err)
+	}
+	return err
+}
+
+func (s *Sink) do(ctx context.Context, method, path, contentType string, body io.Reader, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(s.cfg.URL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	switch {
+	case s.cfg.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+s.cfg.APIKey)
+	case s.cfg.Username != "":
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// defaultTemplate maps the result fields for filtering and aggregations in Kibana
+// or OpenSearch Dashboards
+func defaultTemplate(index string) string {
+	return `{
+  "index_patterns": ["` + index + `"],
+  "priority": 100,
+  "template": {
+    "mappings": {
+      "dynamic": false,
+      "properties": {
+        "id": {"type": "long"},
+        "rule_id": {"type": "long"},
+        "rule_name": {"type": "keyword"},
+        "source": {"type": "keyword"},
+        "repo_full_name": {"type": "keyword"},
+        "repo_url": {"type": "keyword", "index": false},
+        "file_path": {"type": "keyword"},
+        "file_url": {"type": "keyword", "index": false},
+        "html_url": {"type": "keyword", "index": false},
+        "matched_keywords": {"type": "keyword"},
+        "content_snippet": {"type": "text"},
+        "score": {"type": "float"},
+        "status": {"type": "keyword"},
+        "severity": {"type": "keyword"},
+        "created_at": {"type": "date"},
+        "updated_at": {"type": "date"}
+      }
+    }
+  }
+}`
+}
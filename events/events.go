@@ -0,0 +1,116 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types pushed to dashboard clients
+const (
+	TypeNewResult           = "result.new"
+	TypeResultStatus        = "result.status_changed"
+	TypeResultSnoozed       = "result.snoozed" // also when the snooze ends
+	TypeResultChanged       = "result.changed" // a scan found the file again with other content
+	TypeScanCompleted       = "scan.completed"
+	TypeTokenExhausted      = "token.exhausted"
+	TypeRuleDrift           = "rules.drift"
+	TypeHoneytokenTriggered = "honeytoken.triggered"
+)
+
+// StatusChange is the data of a TypeResultStatus event
+type StatusChange struct {
+	IDs    []uint `json:"ids"`
+	Status string `json:"status"`
+}
+
+// Event is a single notification about something that happened in the monitor
+type Event struct {
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	Time      time.Time   `json:"time"`
+	ProjectID uint        `json:"project_id,omitempty"` // 0 for events that don't belong to a project
+}
+
+// Hub fans out published events to all subscribers
+type Hub struct {
+	subscribers map[chan Event]struct{}
+	mu          sync.RWMutex
+}
+
+// DefaultHub is the process-wide hub used by Publish and Subscribe
+var DefaultHub = NewHub()
+
+// NewHub creates a new event hub
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber. The returned function must be called to unsubscribe.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 64)
+
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+		h.mu.Unlock()
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish sends an event to every subscriber. Slow subscribers miss events rather than block the caller.
+func (h *Hub) Publish(eventType string, data interface{}) {
+	h.PublishTo(0, eventType, data)
+}
+
+// PublishTo sends an event about something in a project, so subscribers can skip
+// the projects they have no access to
+func (h *Hub) PublishTo(projectID uint, eventType string, data interface{}) {
+	event := Event{
+		Type:      eventType,
+		Data:      data,
+		Time:      time.Now(),
+		ProjectID: projectID,
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// SubscriberCount returns the number of connected subscribers
+func (h *Hub) SubscriberCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+// Publish sends an event through the default hub
+func Publish(eventType string, data interface{}) {
+	DefaultHub.Publish(eventType, data)
+}
+
+// PublishTo sends a project event through the default hub
+func PublishTo(projectID uint, eventType string, data interface{}) {
+	DefaultHub.PublishTo(projectID, eventType, data)
+}
+
+// Subscribe subscribes to the default hub
+func Subscribe() (<-chan Event, func()) {
+	return DefaultHub.Subscribe()
+}
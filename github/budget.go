@@ -0,0 +1,122 @@
+package github
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxSearchPages is the most pages of 100 code search returns for a query, GitHub
+// stops at 1000 results
+const maxSearchPages = 10
+
+// PageDemand is a rule asking for code search pages. Higher priorities are served
+// first.
+type PageDemand struct {
+	RuleID   uint
+	Priority int
+}
+
+// SearchBudget tracks the code search requests of the last hour against the
+// hourly quota of the token pool and splits what is left between the rules of a
+// scan cycle, so that a tight quota defers the least important rules instead of
+// whichever rules happen to come last
+type SearchBudget struct {
+	pool *TokenPool
+
+	mu              sync.Mutex
+	requestsPerHour int         // per token
+	spent           []time.Time // requests of the last hour, oldest first
+}
+
+// NewSearchBudget creates a budget of requestsPerHour code searches per token
+func NewSearchBudget(pool *TokenPool, requestsPerHour int) *SearchBudget {
+	return &SearchBudget{pool: pool, requestsPerHour: requestsPerHour}
+}
+
+// SetRequestsPerHour changes the code searches each token is allowed per hour
+func (b *SearchBudget) SetRequestsPerHour(requestsPerHour int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requestsPerHour = requestsPerHour
+}
+
+// Spend records a code search request
+func (b *SearchBudget) Spend(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent = append(b.spent, now)
+}
+
+// Quota returns the code searches the token pool is allowed per hour
+func (b *SearchBudget) Quota() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pool.TokenCount() * b.requestsPerHour
+}
+
+// Remaining returns the code searches left of the quota in the hour before now
+func (b *SearchBudget) Remaining(now time.Time) int {
+	quota := b.Quota()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	expired := sort.Search(len(b.spent), func(i int) bool {
+		return b.spent[i].After(now.Add(-time.Hour))
+	})
+	b.spent = b.spent[expired:]
+
+	if remaining := quota - len(b.spent); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Allocate splits the pages a scan cycle of the given length may use between the
+// demands. A cycle gets its share of the hourly quota, capped at what is left of
+// it. Rules missing from the returned page counts are deferred to a later cycle.
+func (b *SearchBudget) Allocate(now time.Time, cycle time.Duration, demands []PageDemand) map[uint]int {
+	budget := b.Remaining(now)
+	if cycle < time.Hour {
+		if share := int(int64(b.Quota()) * int64(cycle) / int64(time.Hour)); share < budget {
+			budget = share
+		}
+	}
+	return allocatePages(budget, demands)
+}
+
+// allocatePages gives every rule one page in order of priority while the budget
+// lasts. What is left goes to the highest priority first, in turns between rules
+// of the same priority, up to maxSearchPages each.
+func allocatePages(budget int, demands []PageDemand) map[uint]int {
+	sorted := append([]PageDemand(nil), demands...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority > sorted[j].Priority })
+
+	pages := make(map[uint]int, len(sorted))
+	for _, demand := range sorted {
+		if budget == 0 {
+			return pages
+		}
+		pages[demand.RuleID] = 1
+		budget--
+	}
+
+	for start := 0; start < len(sorted) && budget > 0; {
+		end := start
+		for end < len(sorted) && sorted[end].Priority == sorted[start].Priority {
+			end++
+		}
+		tier := sorted[start:end]
+		for round := 1; round < maxSearchPages && budget > 0; round++ {
+			for _, demand := range tier {
+				if budget == 0 {
+					break
+				}
+				pages[demand.RuleID]++
+				budget--
+			}
+		}
+		start = end
+	}
+	return pages
+}
@@ -0,0 +1,57 @@
+package github
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllocatePages(t *testing.T) {
+	demands := []PageDemand{
+		{RuleID: 1, Priority: 1},
+		{RuleID: 2, Priority: 4},
+		{RuleID: 3, Priority: 4},
+		{RuleID: 4, Priority: 2},
+	}
+
+	tests := []struct {
+		name   string
+		budget int
+		want   map[uint]int
+	}{
+		{"tight budget defers the lowest priorities", 2, map[uint]int{2: 1, 3: 1}},
+		{"leftover pages go to the highest priority in turns", 9, map[uint]int{1: 1, 2: 4, 3: 3, 4: 1}},
+		{"pages are capped per rule", 100, map[uint]int{1: 10, 2: 10, 3: 10, 4: 10}},
+		{"no budget defers everything", 0, map[uint]int{}},
+	}
+	for _, tt := range tests {
+		got := allocatePages(tt.budget, demands)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: allocated %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for ruleID, pages := range tt.want {
+			if got[ruleID] != pages {
+				t.Errorf("%s: allocated %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSearchBudgetForgetsOldRequests(t *testing.T) {
+	pool := &TokenPool{tokens: []*TokenInfo{{}, {}}}
+	budget := NewSearchBudget(pool, 5)
+	now := time.Now()
+
+	budget.Spend(now.Add(-2 * time.Hour))
+	budget.Spend(now.Add(-30 * time.Minute))
+	budget.Spend(now)
+	if remaining := budget.Remaining(now); remaining != 8 {
+		t.Errorf("remaining %d searches, want 8", remaining)
+	}
+
+	// A 30 minute cycle gets half of the hourly quota of 10
+	if pages := budget.Allocate(now, 30*time.Minute, []PageDemand{{RuleID: 1}}); pages[1] != 5 {
+		t.Errorf("allocated %d pages, want 5", pages[1])
+	}
+}
@@ -7,9 +7,15 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github-monitor/cache"
+	"github-monitor/events"
+
 	"github.com/google/go-github/v57/github"
 	"golang.org/x/net/proxy"
 	"golang.org/x/oauth2"
@@ -26,10 +32,25 @@ type ProxyConfig struct {
 
 // TokenPool manages multiple GitHub tokens with automatic rotation
 type TokenPool struct {
+	tokens             []*TokenInfo
+	currentIndex       int
+	proxyConfig        *ProxyConfig
+	httpConfig         HTTPConfig
+	rateLimitThreshold int // calls kept in reserve on each token
+	responses          cache.Cache // nil when responses aren't cached
+	responseTTL        time.Duration
+	groups             map[string]*tokenGroup // dedicated tokens, not part of the shared rotation
+	configured         []string // shared tokens of the config
+	added              []string // shared tokens added at runtime, kept when the configured ones are replaced
+	groupTokens        map[string][]string // configured tokens of each group
+	disabled           map[string]bool     // tokens pulled out of rotation, wherever they come from
+	mu                 sync.RWMutex
+}
+
+// tokenGroup is a set of tokens reserved for the rules assigned to it
+type tokenGroup struct {
 	tokens       []*TokenInfo
 	currentIndex int
-	proxyConfig  *ProxyConfig
-	mu           sync.RWMutex
 }
 
 // TokenInfo holds information about a GitHub token
@@ -50,8 +71,11 @@ func NewTokenPool(tokens []string, proxyConfig *ProxyConfig) (*TokenPool, error)
 
 	pool := &TokenPool{
 		tokens:       make([]*TokenInfo, 0, len(tokens)),
-		currentIndex: 0,
-		proxyConfig:  proxyConfig,
+		currentIndex:       0,
+		proxyConfig:        proxyConfig,
+		httpConfig:         DefaultHTTPConfig,
+		rateLimitThreshold: 10,
+		configured:         tokens,
 	}
 
 	for _, token := range tokens {
@@ -61,7 +85,7 @@ func NewTokenPool(tokens []string, proxyConfig *ProxyConfig) (*TokenPool, error)
 
 		tokenInfo := &TokenInfo{
 			Token:       token,
-			Client:      createClient(token, proxyConfig),
+			Client:      createClient(token, proxyConfig, DefaultHTTPConfig, nil, 0),
 			IsAvailable: true,
 			LastChecked: time.Now(),
 		}
@@ -80,16 +104,16 @@ func NewTokenPool(tokens []string, proxyConfig *ProxyConfig) (*TokenPool, error)
 	return pool, nil
 }
 
-// createClient creates a GitHub client with the given token and proxy config
-func createClient(token string, proxyConfig *ProxyConfig) *github.Client {
+// createClient creates a GitHub client with the given token, proxy and HTTP config.
+// GET requests are made conditional when a response cache is given.
+func createClient(token string, proxyConfig *ProxyConfig, httpConfig HTTPConfig, responses cache.Cache, responseTTL time.Duration) *github.Client {
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
 
 	// Create HTTP transport
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: false},
-	}
+	transport, dialer := newTransport(httpConfig)
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: false}
 
 	// Configure proxy if enabled
 	if proxyConfig != nil && proxyConfig.Enabled && proxyConfig.URL != "" {
@@ -105,7 +129,7 @@ func createClient(token string, proxyConfig *ProxyConfig) *github.Client {
 					}
 				}
 
-				dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, proxy.Direct)
+				dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, dialer)
 				if err == nil {
 					transport.Dial = dialer.Dial
 					log.Printf("SOCKS5 proxy configured: %s", proxyURL.Host)
@@ -130,73 +154,95 @@ func createClient(token string, proxyConfig *ProxyConfig) *github.Client {
 	}
 
 	// Create oauth2 client with custom HTTP transport
-	tc := &http.Client{
-		Transport: &oauth2.Transport{
-			Source: ts,
-			Base:   transport,
-		},
+	var roundTripper http.RoundTripper = &oauth2.Transport{
+		Source: ts,
+		Base:   &retryTransport{base: transport, maxRetries: httpConfig.MaxRetries, wait: httpConfig.RetryWait},
+	}
+	if responses != nil {
+		roundTripper = newETagTransport(roundTripper, responses, responseTTL, token)
 	}
+	tc := &http.Client{Transport: roundTripper}
 
 	return github.NewClient(tc)
 }
 
-// GetClient returns an available GitHub client
+// GetClient returns an available GitHub client from the shared pool
 func (p *TokenPool) GetClient(ctx context.Context) (*github.Client, *TokenInfo, error) {
+	return p.GetClientFor(ctx, "")
+}
+
+// GetClientFor returns an available client for a rule assigned to the token group,
+// trying the group's tokens before the shared pool. An empty group uses the shared
+// pool only.
+func (p *TokenPool) GetClientFor(ctx context.Context, group string) (*github.Client, *TokenInfo, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	startIndex := p.currentIndex
-	attempts := 0
-	maxAttempts := len(p.tokens)
+	if group != "" {
+		if g, ok := p.groups[group]; ok {
+			if tokenInfo := p.pick(ctx, g.tokens, &g.currentIndex, "token group "+group); tokenInfo != nil {
+				return tokenInfo.Client, tokenInfo, nil
+			}
+			log.Printf("Token group %s is exhausted, falling back to the shared pool", group)
+		} else {
+			log.Printf("Token group %s is not configured, using the shared pool", group)
+		}
+	}
+
+	if tokenInfo := p.pick(ctx, p.tokens, &p.currentIndex, "token"); tokenInfo != nil {
+		return tokenInfo.Client, tokenInfo, nil
+	}
+
+	// Every token was tried, tell whether waiting for a reset is worth it
+	nextReset := p.getNextResetTime()
+	events.Publish(events.TypeTokenExhausted, map[string]interface{}{"next_reset": nextReset})
+	if !nextReset.IsZero() && time.Until(nextReset) < 5*time.Minute {
+		log.Printf("All tokens exhausted, waiting until %v", nextReset)
+		return nil, nil, fmt.Errorf("all tokens rate limited, next reset at %v", nextReset)
+	}
+	return nil, nil, fmt.Errorf("no available tokens")
+}
 
-	for attempts < maxAttempts {
-		tokenInfo := p.tokens[p.currentIndex]
+// pick rotates through tokens from *index and returns the first one with calls
+// left, or nil. Tokens that failed or ran low are marked unavailable. Callers hold mu.
+func (p *TokenPool) pick(ctx context.Context, tokens []*TokenInfo, index *int, label string) *TokenInfo {
+	for attempts := 0; attempts < len(tokens); attempts++ {
+		tokenInfo := tokens[*index]
 
 		// Check if token is available
 		if tokenInfo.IsAvailable {
 			// Update rate limit info
-			err := tokenInfo.UpdateRateLimit(ctx)
+			err := tokenInfo.UpdateRateLimit(ctx, p.rateLimitThreshold)
 			if err != nil {
-				log.Printf("Failed to update rate limit for token %d: %v", p.currentIndex, err)
-				p.markTokenUnavailable(p.currentIndex)
-				p.nextToken()
-				attempts++
+				log.Printf("Failed to update rate limit for %s %d: %v", label, *index, err)
+				tokenInfo.markUnavailable()
+				*index = (*index + 1) % len(tokens)
 				continue
 			}
 
 			// Check if token has remaining calls
-			if tokenInfo.HasRemainingCalls(10) { // Keep at least 10 calls in reserve
-				log.Printf("Using token %d, remaining: %d/%d, resets at: %v",
-					p.currentIndex,
+			if tokenInfo.HasRemainingCalls(p.rateLimitThreshold) { // Keep some calls in reserve
+				log.Printf("Using %s %d, remaining: %d/%d, resets at: %v",
+					label,
+					*index,
 					tokenInfo.RateLimit.Remaining,
 					tokenInfo.RateLimit.Limit,
 					tokenInfo.RateLimit.Reset.Time)
-				return tokenInfo.Client, tokenInfo, nil
+				return tokenInfo
 			}
 
 			// Token is rate limited, mark as unavailable temporarily
-			log.Printf("Token %d is rate limited, resets at: %v", p.currentIndex, tokenInfo.RateLimit.Reset.Time)
-			p.markTokenUnavailable(p.currentIndex)
+			log.Printf("%s %d is rate limited, resets at: %v", label, *index, tokenInfo.RateLimit.Reset.Time)
+			tokenInfo.markUnavailable()
 		}
 
-		p.nextToken()
-		attempts++
-
-		// If we've cycled through all tokens, check if any will reset soon
-		if p.currentIndex == startIndex && attempts == maxAttempts {
-			nextReset := p.getNextResetTime()
-			if !nextReset.IsZero() && time.Until(nextReset) < 5*time.Minute {
-				log.Printf("All tokens exhausted, waiting until %v", nextReset)
-				return nil, nil, fmt.Errorf("all tokens rate limited, next reset at %v", nextReset)
-			}
-		}
+		*index = (*index + 1) % len(tokens)
 	}
-
-	return nil, nil, fmt.Errorf("no available tokens")
+	return nil
 }
 
 // UpdateRateLimit updates the rate limit information for a token
-func (t *TokenInfo) UpdateRateLimit(ctx context.Context) error {
+func (t *TokenInfo) UpdateRateLimit(ctx context.Context, threshold int) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -210,7 +256,7 @@ func (t *TokenInfo) UpdateRateLimit(ctx context.Context) error {
 		t.LastChecked = time.Now()
 
 		// Auto-recover if rate limit has reset
-		if t.RateLimit.Remaining > 10 {
+		if t.RateLimit.Remaining > threshold {
 			t.IsAvailable = true
 		}
 	}
@@ -235,18 +281,29 @@ func (t *TokenInfo) HasRemainingCalls(threshold int) bool {
 	return t.RateLimit.Remaining > threshold
 }
 
-// markTokenUnavailable marks a token as unavailable
-func (p *TokenPool) markTokenUnavailable(index int) {
-	if index >= 0 && index < len(p.tokens) {
-		p.tokens[index].mu.Lock()
-		p.tokens[index].IsAvailable = false
-		p.tokens[index].mu.Unlock()
+// Masked returns the token masked for logs and scan history
+func (t *TokenInfo) Masked() string {
+	return MaskToken(t.Token)
+}
+
+// MaskToken shortens a token to its prefix, such as ghp_, and its last four
+// characters, enough to tell the tokens of the pool apart without revealing them
+func MaskToken(token string) string {
+	if len(token) < 12 {
+		return "****"
+	}
+	prefix := token[:4]
+	if i := strings.LastIndex(token[:12], "_"); i > 0 {
+		prefix = token[:i+1]
 	}
+	return prefix + "..." + token[len(token)-4:]
 }
 
-// nextToken moves to the next token in the pool
-func (p *TokenPool) nextToken() {
-	p.currentIndex = (p.currentIndex + 1) % len(p.tokens)
+// markUnavailable takes a token out of rotation until its rate limit resets
+func (t *TokenInfo) markUnavailable() {
+	t.mu.Lock()
+	t.IsAvailable = false
+	t.mu.Unlock()
 }
 
 // getNextResetTime returns the earliest rate limit reset time
@@ -272,37 +329,330 @@ func (p *TokenPool) GetTokenStats() []map[string]interface{} {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	stats := make([]map[string]interface{}, len(p.tokens))
+	stats := make([]map[string]interface{}, 0, len(p.tokens))
+	add := func(group string, tokens []*TokenInfo) {
+		for i, tokenInfo := range tokens {
+			tokenInfo.mu.RLock()
+			stat := map[string]interface{}{
+				"index":        i,
+				"token":        tokenInfo.Masked(),
+				"is_available": tokenInfo.IsAvailable,
+				"last_checked": tokenInfo.LastChecked,
+			}
+			if group != "" {
+				stat["group"] = group
+			}
 
-	for i, tokenInfo := range p.tokens {
-		tokenInfo.mu.RLock()
-		stat := map[string]interface{}{
-			"index":       i,
-			"is_available": tokenInfo.IsAvailable,
-			"last_checked": tokenInfo.LastChecked,
+			if tokenInfo.RateLimit != nil {
+				stat["rate_limit"] = tokenInfo.RateLimit.Limit
+				stat["rate_remaining"] = tokenInfo.RateLimit.Remaining
+				stat["rate_reset"] = tokenInfo.RateLimit.Reset.Time
+			}
+
+			stats = append(stats, stat)
+			tokenInfo.mu.RUnlock()
 		}
+	}
 
-		if tokenInfo.RateLimit != nil {
-			stat["rate_limit"] = tokenInfo.RateLimit.Limit
-			stat["rate_remaining"] = tokenInfo.RateLimit.Remaining
-			stat["rate_reset"] = tokenInfo.RateLimit.Reset.Time
+	add("", p.tokens)
+	names := make([]string, 0, len(p.groups))
+	for name := range p.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(name, p.groups[name].tokens)
+	}
+
+	return stats
+}
+
+// SetRateLimitThreshold sets how many calls are kept in reserve on each token
+func (p *TokenPool) SetRateLimitThreshold(threshold int) {
+	p.mu.Lock()
+	p.rateLimitThreshold = threshold
+	p.mu.Unlock()
+}
+
+// SetProxyConfig rebuilds every client in the pool with the new proxy settings
+func (p *TokenPool) SetProxyConfig(proxyConfig *ProxyConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.proxyConfig = proxyConfig
+	for _, tokenInfo := range p.allTokens() {
+		tokenInfo.mu.Lock()
+		tokenInfo.Client = createClient(tokenInfo.Token, proxyConfig, p.httpConfig, p.responses, p.responseTTL)
+		tokenInfo.mu.Unlock()
+	}
+
+	if proxyConfig != nil && proxyConfig.Enabled {
+		log.Printf("Token pool now using proxy %s (%s)", proxyConfig.URL, proxyConfig.Type)
+	} else {
+		log.Println("Token pool proxy disabled")
+	}
+}
+
+// SetHTTPConfig rebuilds every client in the pool with new timeouts and retries
+func (p *TokenPool) SetHTTPConfig(httpConfig HTTPConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.httpConfig = httpConfig
+	for _, tokenInfo := range p.allTokens() {
+		tokenInfo.mu.Lock()
+		tokenInfo.Client = createClient(tokenInfo.Token, p.proxyConfig, httpConfig, p.responses, p.responseTTL)
+		tokenInfo.mu.Unlock()
+	}
+}
+
+// SetResponseCache rebuilds every client in the pool to make conditional requests
+// against responses kept in the shared cache for ttl
+func (p *TokenPool) SetResponseCache(responses cache.Cache, ttl time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.responses = responses
+	p.responseTTL = ttl
+	for _, tokenInfo := range p.allTokens() {
+		tokenInfo.mu.Lock()
+		tokenInfo.Client = createClient(tokenInfo.Token, p.proxyConfig, p.httpConfig, responses, ttl)
+		tokenInfo.mu.Unlock()
+	}
+}
+
+// SetTokens replaces the tokens in the pool. Tokens that stay keep their client and
+// rate limit state, new ones are checked on their first use.
+func (p *TokenPool) SetTokens(tokens []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !slices.ContainsFunc(tokens, func(token string) bool { return token != "" }) {
+		return fmt.Errorf("no valid tokens provided")
+	}
+
+	p.configured = tokens
+	p.tokens = p.reuse(p.shared(), p.tokens)
+	p.currentIndex = 0
+	log.Printf("Token pool now has %d tokens", len(p.tokens))
+	return nil
+}
+
+// AddTokens adds tokens to the shared rotation, such as ones stored through the
+// API. They stay when the configured tokens are replaced. It returns how many of
+// them weren't in the rotation yet.
+func (p *TokenPool) AddTokens(tokens []string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	before := len(p.tokens)
+	for _, token := range tokens {
+		if token != "" && !slices.Contains(p.added, token) {
+			p.added = append(p.added, token)
 		}
+	}
+	p.tokens = p.reuse(p.shared(), p.tokens)
+	return len(p.tokens) - before
+}
+
+// RemoveToken takes a token added at runtime out of the rotation. Configured
+// tokens stay until they are removed from the config.
+func (p *TokenPool) RemoveToken(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.added = slices.DeleteFunc(p.added, func(added string) bool { return added == token })
+	p.tokens = p.reuse(p.shared(), p.tokens)
+	if p.currentIndex >= len(p.tokens) {
+		p.currentIndex = 0
+	}
+}
+
+// SetTokenActive puts a stored token back into rotation or pulls it out, configured
+// and grouped tokens included. A disabled token is skipped until it is enabled
+// again, even when the config lists it. An enabled token the config doesn't list
+// joins the added ones, such as one that was disabled when the pool was loaded.
+func (p *TokenPool) SetTokenActive(token string, active bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	join := active && !slices.Contains(p.added, token) && !p.listed(token)
+	if p.disabled[token] == !active && !join {
+		return
+	}
+	if join {
+		p.added = append(p.added, token)
+	}
+	if active {
+		delete(p.disabled, token)
+	} else {
+		if p.disabled == nil {
+			p.disabled = make(map[string]bool)
+		}
+		p.disabled[token] = true
+	}
+
+	p.tokens = p.reuse(p.shared(), p.tokens)
+	if p.currentIndex >= len(p.tokens) {
+		p.currentIndex = 0
+	}
+	p.buildGroups()
+	state := "disabled"
+	if active {
+		state = "enabled"
+	}
+	log.Printf("Token %s %s, the shared pool now has %d tokens", MaskToken(token), state, len(p.tokens))
+}
 
-		stats[i] = stat
+// listed reports whether the config lists the token, shared or in a group. Callers
+// hold mu.
+func (p *TokenPool) listed(token string) bool {
+	if slices.Contains(p.configured, token) {
+		return true
+	}
+	for _, tokens := range p.groupTokens {
+		if slices.Contains(tokens, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// shared returns the configured tokens followed by the added ones, each once.
+// Callers hold mu.
+func (p *TokenPool) shared() []string {
+	tokens := make([]string, 0, len(p.configured)+len(p.added))
+	for _, token := range append(append([]string(nil), p.configured...), p.added...) {
+		if !slices.Contains(tokens, token) {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// CheckToken asks GitHub for the rate limit of a token outside the pool, with the
+// proxy and HTTP config of the pool. It fails for tokens GitHub rejects.
+func (p *TokenPool) CheckToken(ctx context.Context, token string) (*github.Rate, error) {
+	p.mu.RLock()
+	client := createClient(token, p.proxyConfig, p.httpConfig, nil, 0)
+	p.mu.RUnlock()
+
+	rateLimit, _, err := client.RateLimit.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rateLimit == nil || rateLimit.Core == nil {
+		return nil, fmt.Errorf("GitHub returned no rate limit")
+	}
+	return rateLimit.Core, nil
+}
+
+// SetTokenGroups replaces the dedicated token groups. Their tokens are only used
+// for rules assigned to the group, which fall back to the shared pool once the
+// group is exhausted.
+func (p *TokenPool) SetTokenGroups(groups map[string][]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.groupTokens = groups
+	p.buildGroups()
+	if len(p.groups) > 0 {
+		log.Printf("Token pool has %d dedicated token groups", len(p.groups))
+	}
+}
+
+// buildGroups rebuilds the token groups from their configured tokens. Groups left
+// without tokens are dropped, their rules use the shared pool. Callers hold mu.
+func (p *TokenPool) buildGroups() {
+	updated := make(map[string]*tokenGroup, len(p.groupTokens))
+	for name, tokens := range p.groupTokens {
+		var previous []*TokenInfo
+		if g, ok := p.groups[name]; ok {
+			previous = g.tokens
+		}
+		if reused := p.reuse(tokens, previous); len(reused) > 0 {
+			updated[name] = &tokenGroup{tokens: reused}
+		}
+	}
+	p.groups = updated
+}
+
+// reuse builds the token list without the disabled tokens, keeping the client and
+// rate limit state of tokens already in previous. Callers hold mu.
+func (p *TokenPool) reuse(tokens []string, previous []*TokenInfo) []*TokenInfo {
+	existing := make(map[string]*TokenInfo, len(previous))
+	for _, tokenInfo := range previous {
+		existing[tokenInfo.Token] = tokenInfo
+	}
+
+	updated := make([]*TokenInfo, 0, len(tokens))
+	for _, token := range tokens {
+		if token == "" || p.disabled[token] {
+			continue
+		}
+		if tokenInfo, ok := existing[token]; ok {
+			updated = append(updated, tokenInfo)
+			delete(existing, token)
+			continue
+		}
+		updated = append(updated, &TokenInfo{
+			Token:       token,
+			Client:      createClient(token, p.proxyConfig, p.httpConfig, p.responses, p.responseTTL),
+			IsAvailable: true,
+			LastChecked: time.Now(),
+		})
+	}
+	return updated
+}
+
+// allTokens returns the shared tokens followed by the grouped ones. Callers hold mu.
+func (p *TokenPool) allTokens() []*TokenInfo {
+	tokens := append([]*TokenInfo(nil), p.tokens...)
+	for _, g := range p.groups {
+		tokens = append(tokens, g.tokens...)
+	}
+	return tokens
+}
+
+// AvailableTokenCount returns how many tokens can currently serve requests
+func (p *TokenPool) AvailableTokenCount() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	count := 0
+	for _, tokenInfo := range p.allTokens() {
+		tokenInfo.mu.RLock()
+		available := tokenInfo.IsAvailable
+		if !available && tokenInfo.RateLimit != nil && time.Now().After(tokenInfo.RateLimit.Reset.Time) {
+			// Will be recovered on next use
+			available = true
+		}
 		tokenInfo.mu.RUnlock()
+
+		if available {
+			count++
+		}
 	}
 
-	return stats
+	return count
+}
+
+// TokenCount returns the number of tokens in the pool, grouped ones included
+func (p *TokenPool) TokenCount() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.allTokens())
 }
 
 // RefreshAllTokens refreshes rate limit info for all tokens
 func (p *TokenPool) RefreshAllTokens(ctx context.Context) {
 	p.mu.RLock()
-	tokens := p.tokens
+	tokens := p.allTokens()
+	threshold := p.rateLimitThreshold
 	p.mu.RUnlock()
 
 	for i, tokenInfo := range tokens {
-		err := tokenInfo.UpdateRateLimit(ctx)
+		err := tokenInfo.UpdateRateLimit(ctx, threshold)
 		if err != nil {
 			log.Printf("Failed to refresh token %d: %v", i, err)
 		}
@@ -0,0 +1,103 @@
+package github
+
+import (
+	"slices"
+	"testing"
+)
+
+func poolTokens(p *TokenPool) []string {
+	var tokens []string
+	for _, tokenInfo := range p.tokens {
+		tokens = append(tokens, tokenInfo.Token)
+	}
+	return tokens
+}
+
+func TestAddTokensSurviveConfigReload(t *testing.T) {
+	pool, err := NewTokenPool([]string{"configured1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := pool.tokens[0]
+
+	if added := pool.AddTokens([]string{"imported1", "configured1", "imported1", "imported2"}); added != 2 {
+		t.Errorf("AddTokens() = %d, want 2", added)
+	}
+	if tokens := poolTokens(pool); !slices.Equal(tokens, []string{"configured1", "imported1", "imported2"}) {
+		t.Fatalf("tokens = %v", tokens)
+	}
+
+	// Replacing the configured tokens keeps the added ones and the state of known ones
+	if err := pool.SetTokens([]string{"configured1", "configured2"}); err != nil {
+		t.Fatal(err)
+	}
+	if tokens := poolTokens(pool); !slices.Equal(tokens, []string{"configured1", "configured2", "imported1", "imported2"}) {
+		t.Errorf("tokens after SetTokens = %v", tokens)
+	}
+	if pool.tokens[0] != first {
+		t.Error("SetTokens() replaced the client of a token it kept")
+	}
+
+	// Removing a configured token only takes it out once it's gone from the config
+	pool.RemoveToken("imported1")
+	pool.RemoveToken("configured2")
+	if tokens := poolTokens(pool); !slices.Equal(tokens, []string{"configured1", "configured2", "imported2"}) {
+		t.Errorf("tokens after RemoveToken = %v", tokens)
+	}
+}
+
+func TestDisabledTokensLeaveEveryRotation(t *testing.T) {
+	pool, err := NewTokenPool([]string{"configured1", "configured2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.AddTokens([]string{"imported1"})
+	pool.SetTokenGroups(map[string][]string{"vip": {"grouped1"}})
+
+	pool.SetTokenActive("configured2", false)
+	pool.SetTokenActive("imported1", false)
+	pool.SetTokenActive("grouped1", false)
+	if tokens := poolTokens(pool); !slices.Equal(tokens, []string{"configured1"}) {
+		t.Errorf("tokens after disabling = %v", tokens)
+	}
+	if _, ok := pool.groups["vip"]; ok {
+		t.Error("a group without enabled tokens is kept")
+	}
+
+	// Disabled tokens stay out when the config is reloaded
+	if err := pool.SetTokens([]string{"configured1", "configured2"}); err != nil {
+		t.Fatal(err)
+	}
+	pool.SetTokenGroups(map[string][]string{"vip": {"grouped1"}})
+	if tokens := poolTokens(pool); !slices.Equal(tokens, []string{"configured1"}) {
+		t.Errorf("tokens after SetTokens = %v", tokens)
+	}
+
+	pool.SetTokenActive("configured2", true)
+	pool.SetTokenActive("imported1", true)
+	pool.SetTokenActive("grouped1", true)
+	if tokens := poolTokens(pool); !slices.Equal(tokens, []string{"configured1", "configured2", "imported1"}) {
+		t.Errorf("tokens after enabling = %v", tokens)
+	}
+	if g, ok := pool.groups["vip"]; !ok || len(g.tokens) != 1 {
+		t.Error("the group isn't back after enabling its token")
+	}
+}
+
+func TestEnableTokenDisabledAtLoad(t *testing.T) {
+	pool, err := NewTokenPool([]string{"configured1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A stored token that was inactive on startup is only marked disabled
+	pool.SetTokenActive("stored1", false)
+	if tokens := poolTokens(pool); !slices.Equal(tokens, []string{"configured1"}) {
+		t.Fatalf("tokens after loading = %v", tokens)
+	}
+
+	pool.SetTokenActive("stored1", true)
+	if tokens := poolTokens(pool); !slices.Equal(tokens, []string{"configured1", "stored1"}) {
+		t.Errorf("tokens after enabling = %v", tokens)
+	}
+}
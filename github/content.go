@@ -0,0 +1,504 @@
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// FileContent is a file fetched from a repository
+type FileContent struct {
+	Path    string
+	Content string
+	HTMLURL string
+}
+
+// ErrContentSkipped is returned for files whose content isn't worth fetching,
+// binary files and files over the content size limit
+var ErrContentSkipped = errors.New("content skipped")
+
+// maxContentSize is the size in bytes of the largest file fetched, 0 for no limit
+var maxContentSize atomic.Int64
+
+func init() {
+	maxContentSize.Store(512 << 10)
+}
+
+// SetMaxContentSize sets the size in bytes of the largest file fetched, 0 for no limit
+func SetMaxContentSize(n int64) {
+	if n >= 0 {
+		maxContentSize.Store(n)
+	}
+}
+
+// binarySniffLength is how far into a file a NUL byte marks it binary, as git does
+const binarySniffLength = 8000
+
+// isBinary reports whether content looks like a binary file rather than text
+func isBinary(content string) bool {
+	if len(content) > binarySniffLength {
+		content = content[:binarySniffLength]
+	}
+	return strings.IndexByte(content, 0) >= 0
+}
+
+// CheckContent returns ErrContentSkipped when content already at hand is over the
+// content size limit or binary, as GetFileContent would have skipped its file
+func CheckContent(filePath, content string) error {
+	if limit := maxContentSize.Load(); limit > 0 && int64(len(content)) > limit {
+		return fmt.Errorf("%w: %s has %d bytes, more than %d", ErrContentSkipped, filePath, len(content), limit)
+	}
+	if isBinary(content) {
+		return fmt.Errorf("%w: %s is a binary file", ErrContentSkipped, filePath)
+	}
+	return nil
+}
+
+// GetFileContent fetches a file at the given ref through the contents API.
+// GitHub serves files up to 1 MB this way, larger ones return an error. Files
+// over the content size limit and binary files return ErrContentSkipped, the size
+// is checked before a file is downloaded.
+func (s *SearchService) GetFileContent(ctx context.Context, repoFullName, filePath, ref string) (*FileContent, error) {
+	owner, repo, ok := strings.Cut(repoFullName, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository name %q", repoFullName)
+	}
+
+	client, _, err := s.tokenPool.GetClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	// Ask for the size first, the contents API sends the whole file with its metadata
+	limit := maxContentSize.Load()
+	if limit > 0 {
+		size, err := contentSize(ctx, client, owner, repo, filePath, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", filePath, err)
+		}
+		if size > limit {
+			return nil, fmt.Errorf("%w: %s has %d bytes, more than %d", ErrContentSkipped, filePath, size, limit)
+		}
+	}
+
+	file, _, _, err := client.Repositories.GetContents(ctx, owner, repo, filePath, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", filePath, err)
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%s is not a file", filePath)
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", filePath, err)
+	}
+	// The file may have changed since its size was asked for
+	if err := CheckContent(filePath, content); err != nil {
+		return nil, err
+	}
+
+	return &FileContent{
+		Path:    filePath,
+		Content: content,
+		HTMLURL: file.GetHTMLURL(),
+	}, nil
+}
+
+// contentSize returns the size in bytes of a file from a HEAD request for its raw
+// content, which leaves the content itself undownloaded. A size GitHub doesn't
+// tell is returned as 0.
+func contentSize(ctx context.Context, client *github.Client, owner, repo, filePath, ref string) (int64, error) {
+	u := fmt.Sprintf("repos/%s/%s/contents/%s", owner, repo, (&url.URL{Path: filePath}).String())
+	if ref != "" {
+		u += "?ref=" + url.QueryEscape(ref)
+	}
+	req, err := client.NewRequest(http.MethodHead, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.raw")
+	resp, err := client.Do(ctx, req, nil)
+	if err != nil {
+		return 0, err
+	}
+	return max(resp.ContentLength, 0), nil
+}
+
+// searchQualifiers are the code search qualifiers a keyword may be, they narrow down
+// the search instead of being searched for
+var searchQualifiers = map[string]bool{
+	"filename":  true,
+	"extension": true,
+	"path":      true,
+	"language":  true,
+	"repo":      true,
+	"org":       true,
+	"user":      true,
+	"size":      true,
+	"in":        true,
+	"is":        true,
+	"fork":      true,
+}
+
+// IsQualifier reports whether a keyword is a search qualifier such as filename:.env,
+// negated with - or NOT or not
+func IsQualifier(keyword string) bool {
+	qualifier, _ := cutNegation(keyword)
+	name, _, ok := strings.Cut(qualifier, ":")
+	return ok && searchQualifiers[strings.ToLower(name)]
+}
+
+// cutNegation strips the - or NOT negating a keyword
+func cutNegation(keyword string) (string, bool) {
+	if rest, ok := strings.CutPrefix(keyword, "NOT "); ok {
+		return strings.TrimSpace(rest), true
+	}
+	if rest, ok := strings.CutPrefix(keyword, "-"); ok {
+		return rest, true
+	}
+	return keyword, false
+}
+
+// Term is what a keyword searches for in content
+type Term struct {
+	Text    string         // matched ignoring case
+	Regex   *regexp.Regexp // set instead of Text for the /.../ syntax of code search
+	Negated bool           // NOT term, content must not contain it
+}
+
+// ParseTerm returns the term of a keyword in a query dialect. Legacy keywords are
+// text as they are. In the code search dialect /.../ is a regular expression,
+// content: searches for its value and NOT negates, and a regular expression that
+// doesn't compile is searched for as text. ok is false for empty keywords and
+// qualifiers, which aren't searched for in content.
+func ParseTerm(keyword, dialect string) (term Term, ok bool) {
+	if keyword == "" || IsQualifier(keyword) {
+		return Term{}, false
+	}
+	if dialect != DialectCodeSearch {
+		return Term{Text: keyword}, true
+	}
+	text := keyword
+	if rest, negated := strings.CutPrefix(keyword, "NOT "); negated {
+		text, term.Negated = strings.TrimSpace(rest), true
+	}
+	if value, ok := strings.CutPrefix(text, "content:"); ok {
+		text = strings.Trim(value, `"`)
+	}
+	if len(text) > 2 && strings.HasPrefix(text, "/") && strings.HasSuffix(text, "/") {
+		if regex, err := regexp.Compile(text[1 : len(text)-1]); err == nil {
+			term.Regex = regex
+			return term, true
+		}
+	}
+	if text == "" {
+		return Term{}, false
+	}
+	term.Text = text
+	return term, true
+}
+
+// Index returns the byte index of the first match of a term in content, or -1
+func (t Term) Index(content string) int {
+	if t.Regex != nil {
+		if loc := t.Regex.FindStringIndex(content); loc != nil {
+			return loc[0]
+		}
+		return -1
+	}
+	return indexFold(content, t.Text)
+}
+
+// Plain reports whether a term is text to be found, which sources without the code
+// search syntax can be queried with
+func (t Term) Plain() bool {
+	return t.Regex == nil && !t.Negated
+}
+
+// CheckKeywords reports the first keyword whose regular expression doesn't compile
+func CheckKeywords(keywords []string) error {
+	for _, keyword := range keywords {
+		text, _ := cutNegation(keyword)
+		text = strings.TrimPrefix(text, "content:")
+		if len(text) > 2 && strings.HasPrefix(text, "/") && strings.HasSuffix(text, "/") {
+			if _, err := regexp.Compile(text[1 : len(text)-1]); err != nil {
+				return fmt.Errorf("keyword %q is not a valid regular expression: %w", keyword, err)
+			}
+		}
+	}
+	return nil
+}
+
+// MatchPath checks a file path against the filename:, extension: and path: qualifiers
+// among the keywords of a rule, other keywords are ignored
+func MatchPath(filePath string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if !IsQualifier(keyword) {
+			continue
+		}
+		qualifier, negated := cutNegation(keyword)
+		name, value, _ := strings.Cut(qualifier, ":")
+
+		var matched bool
+		switch strings.ToLower(name) {
+		case "filename":
+			matched = strings.EqualFold(path.Base(filePath), value)
+		case "extension":
+			matched = strings.EqualFold(strings.TrimPrefix(path.Ext(filePath), "."), strings.TrimPrefix(value, "."))
+		case "path":
+			matched = indexFold(filePath, strings.Trim(value, "/")) >= 0
+		default:
+			continue
+		}
+		if matched == negated {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchContent checks file content against the keywords of a rule in its query
+// dialect the way code search does: every term has to appear, ignoring case unless
+// it's a regular expression, and no negated one may. Qualifier keywords are skipped,
+// see MatchPath. It returns nil when the file doesn't match, otherwise the keywords
+// and a snippet around the first one.
+func MatchContent(content string, keywords []string, dialect string) ([]string, string) {
+	matched := make([]string, 0, len(keywords))
+	first := -1
+
+	for _, keyword := range keywords {
+		term, ok := ParseTerm(keyword, dialect)
+		if !ok {
+			continue
+		}
+		index := term.Index(content)
+		if term.Negated {
+			if index >= 0 {
+				return nil, ""
+			}
+			continue
+		}
+		if index < 0 {
+			return nil, ""
+		}
+		matched = append(matched, keyword)
+		if first < 0 || index < first {
+			first = index
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil, ""
+	}
+	return matched, snippetAround(content, first)
+}
+
+// Fingerprint identifies the leaked line of content: the first line containing a
+// keyword, ignoring surrounding whitespace. The same secret copied into other files
+// gets the same fingerprint. It is empty when no keyword is found or the line holds
+// nothing but the keyword.
+func Fingerprint(content string, keywords []string, dialect string) string {
+	first := -1
+	for _, keyword := range keywords {
+		term, ok := ParseTerm(keyword, dialect)
+		if !ok || term.Negated {
+			continue
+		}
+		if index := term.Index(content); index >= 0 && (first < 0 || index < first) {
+			first = index
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start := strings.LastIndexByte(content[:first], '\n') + 1
+	end := len(content)
+	if newline := strings.IndexByte(content[first:], '\n'); newline >= 0 {
+		end = first + newline
+	}
+	line := strings.Join(strings.Fields(content[start:end]), " ")
+	for _, keyword := range keywords {
+		if strings.EqualFold(line, keyword) {
+			return ""
+		}
+	}
+
+	sum := sha256.Sum256([]byte(line))
+	return hex.EncodeToString(sum[:16])
+}
+
+// indexFold returns the byte index of the first case-insensitive occurrence of substr
+// in s, or -1. Unlike searching strings.ToLower(s), the index is valid in s even when
+// lowercasing changes the length of some characters.
+func indexFold(s, substr string) int {
+	if substr == "" {
+		return 0
+	}
+	for i := 0; i < len(s); {
+		if hasPrefixFold(s[i:], substr) {
+			return i
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return -1
+}
+
+// hasPrefixFold reports whether s starts with prefix under Unicode case folding
+func hasPrefixFold(s, prefix string) bool {
+	for prefix != "" {
+		if s == "" {
+			return false
+		}
+		r1, n1 := utf8.DecodeRuneInString(s)
+		r2, n2 := utf8.DecodeRuneInString(prefix)
+		if r1 != r2 && !strings.EqualFold(s[:n1], prefix[:n2]) {
+			return false
+		}
+		s, prefix = s[n1:], prefix[n2:]
+	}
+	return true
+}
+
+// snippetLength is the length of snippets and search matches in bytes
+var snippetLength atomic.Int64
+
+func init() {
+	snippetLength.Store(500)
+}
+
+// SetSnippetLength sets how many bytes of content snippets and search matches keep
+func SetSnippetLength(n int) {
+	if n > 0 {
+		snippetLength.Store(int64(n))
+	}
+}
+
+// truncate cuts s to the snippet length without splitting a character, marking cut strings with "..."
+func truncate(s string) string {
+	end := int(snippetLength.Load())
+	if len(s) <= end {
+		return s
+	}
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + "..."
+}
+
+// snippetAround returns up to the snippet length of content around index, like search snippets
+func snippetAround(content string, index int) string {
+	length := int(snippetLength.Load())
+	start := index - length/5
+	if start < 0 {
+		start = 0
+	}
+	if start > len(content) {
+		start = len(content)
+	}
+	end := start + length
+	if end > len(content) {
+		end = len(content)
+	}
+
+	// Don't cut through a multi-byte character
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end--
+	}
+
+	snippet := content[start:end]
+	if end < len(content) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+// Limits of LineContext, so minified files and keywords on every line stay readable
+const (
+	maxContextMatches   = 20
+	maxContextLineBytes = 300
+)
+
+// LineContext returns the lines within n lines of those containing a keyword, prefixed
+// with their line number. Excerpts that aren't adjacent are separated by "...".
+// Qualifier keywords are skipped, empty when no line contains a keyword.
+func LineContext(content string, keywords []string, dialect string, n int) string {
+	lines := strings.Split(content, "\n")
+	include := make([]bool, len(lines))
+	matches := 0
+	for i, line := range lines {
+		if matches == maxContextMatches {
+			break
+		}
+		for _, keyword := range keywords {
+			term, ok := ParseTerm(keyword, dialect)
+			if !ok || term.Negated || term.Index(line) < 0 {
+				continue
+			}
+			for j := max(0, i-n); j <= min(len(lines)-1, i+n); j++ {
+				include[j] = true
+			}
+			matches++
+			break
+		}
+	}
+	if matches == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	previous := -1
+	for i, ok := range include {
+		if !ok {
+			continue
+		}
+		if previous >= 0 && i > previous+1 {
+			b.WriteString("...\n")
+		}
+		line := strings.TrimRight(lines[i], "\r")
+		if len(line) > maxContextLineBytes {
+			end := maxContextLineBytes
+			for end > 0 && !utf8.RuneStart(line[end]) {
+				end--
+			}
+			line = line[:end] + "..."
+		}
+		b.WriteString(strconv.Itoa(i + 1))
+		b.WriteString(": ")
+		b.WriteString(line)
+		b.WriteString("\n")
+		previous = i
+	}
+	return b.String()
+}
+
+// IsExcluded reports whether a file has one of the excluded extensions of a rule
+func IsExcluded(filePath string, excludeExts []string) bool {
+	ext := strings.TrimPrefix(path.Ext(filePath), ".")
+	if ext == "" {
+		return false
+	}
+	for _, excluded := range excludeExts {
+		if strings.EqualFold(strings.TrimPrefix(excluded, "."), ext) {
+			return true
+		}
+	}
+	return false
+}
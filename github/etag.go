@@ -0,0 +1,122 @@
+package github
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github-monitor/cache"
+)
+
+// maxCachedBody caps the size of responses kept for conditional requests
+const maxCachedBody = 4 << 20
+
+// etagTransport makes GET requests conditional on the ETag of the last response,
+// which is kept in the shared cache. GitHub doesn't count 304 answers against the
+// rate limit, and the cached body is returned in their place.
+type etagTransport struct {
+	base      http.RoundTripper
+	responses cache.Cache
+	ttl       time.Duration
+	scope     string // responses are kept per token, they can differ by permissions
+}
+
+type cachedResponse struct {
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+func newETagTransport(base http.RoundTripper, responses cache.Cache, ttl time.Duration, token string) *etagTransport {
+	sum := sha256.Sum256([]byte(token))
+	return &etagTransport{
+		base:      base,
+		responses: responses,
+		ttl:       ttl,
+		scope:     hex.EncodeToString(sum[:8]),
+	}
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Rate limit checks are free and change with every call
+	if req.Method != http.MethodGet || req.URL.Path == "/rate_limit" {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	key := "etag:" + t.scope + ":" + req.URL.String()
+
+	var cached *cachedResponse
+	if data, err := t.responses.Get(ctx, key); err == nil {
+		if json.Unmarshal(data, &cached) != nil {
+			cached = nil
+		}
+	} else if err != cache.ErrMiss {
+		log.Printf("Failed to read cached GitHub response: %v", err)
+	}
+
+	if cached != nil {
+		req = req.Clone(ctx)
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		header := cached.Header.Clone()
+		for name, values := range resp.Header {
+			if strings.HasPrefix(name, "X-Ratelimit-") {
+				header[name] = values
+			}
+		}
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+			ContentLength: int64(len(cached.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" || resp.ContentLength > maxCachedBody {
+		return resp, nil
+	}
+
+	original := resp.Body
+	body, err := io.ReadAll(io.LimitReader(original, maxCachedBody+1))
+	if err != nil {
+		original.Close()
+		return nil, err
+	}
+	if len(body) > maxCachedBody {
+		// Too large to keep, hand out what was read followed by the rest
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), original), original}
+		return resp, nil
+	}
+	original.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	data, _ := json.Marshal(cachedResponse{ETag: etag, Header: resp.Header, Body: body})
+	if err := t.responses.Set(ctx, key, data, t.ttl); err != nil {
+		log.Printf("Failed to cache GitHub response: %v", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,160 @@
+package github
+
+import (
+	"path"
+	"strings"
+)
+
+// File types results are classified as, from their name and content
+const (
+	FileTypeEnv        = "env"
+	FileTypeKey        = "key"
+	FileTypeJSON       = "json"
+	FileTypeYAML       = "yaml"
+	FileTypeTOML       = "toml"
+	FileTypeINI        = "ini"
+	FileTypeProperties = "properties"
+	FileTypeXML        = "xml"
+	FileTypeSQL        = "sql"
+	FileTypeTerraform  = "terraform"
+	FileTypeDockerfile = "dockerfile"
+	FileTypeShell      = "shell"
+	FileTypePython     = "python"
+	FileTypeJavaScript = "javascript"
+	FileTypeTypeScript = "typescript"
+	FileTypeGo         = "go"
+	FileTypeJava       = "java"
+	FileTypeKotlin     = "kotlin"
+	FileTypeCSharp     = "csharp"
+	FileTypePHP        = "php"
+	FileTypeRuby       = "ruby"
+	FileTypeRust       = "rust"
+	FileTypeC          = "c"
+	FileTypeNotebook   = "notebook"
+	FileTypeHTML       = "html"
+	FileTypeMarkdown   = "markdown"
+	FileTypeText       = "text"
+	FileTypeOther      = "other"
+)
+
+var extensionTypes = map[string]string{
+	"env":        FileTypeEnv,
+	"pem":        FileTypeKey,
+	"key":        FileTypeKey,
+	"p12":        FileTypeKey,
+	"pfx":        FileTypeKey,
+	"jks":        FileTypeKey,
+	"keystore":   FileTypeKey,
+	"ppk":        FileTypeKey,
+	"ovpn":       FileTypeKey,
+	"json":       FileTypeJSON,
+	"yaml":       FileTypeYAML,
+	"yml":        FileTypeYAML,
+	"toml":       FileTypeTOML,
+	"ini":        FileTypeINI,
+	"cfg":        FileTypeINI,
+	"conf":       FileTypeINI,
+	"properties": FileTypeProperties,
+	"xml":        FileTypeXML,
+	"config":     FileTypeXML,
+	"sql":        FileTypeSQL,
+	"tf":         FileTypeTerraform,
+	"tfvars":     FileTypeTerraform,
+	"sh":         FileTypeShell,
+	"bash":       FileTypeShell,
+	"zsh":        FileTypeShell,
+	"ps1":        FileTypeShell,
+	"py":         FileTypePython,
+	"js":         FileTypeJavaScript,
+	"mjs":        FileTypeJavaScript,
+	"cjs":        FileTypeJavaScript,
+	"jsx":        FileTypeJavaScript,
+	"ts":         FileTypeTypeScript,
+	"tsx":        FileTypeTypeScript,
+	"go":         FileTypeGo,
+	"java":       FileTypeJava,
+	"kt":         FileTypeKotlin,
+	"kts":        FileTypeKotlin,
+	"cs":         FileTypeCSharp,
+	"php":        FileTypePHP,
+	"rb":         FileTypeRuby,
+	"rs":         FileTypeRust,
+	"c":          FileTypeC,
+	"h":          FileTypeC,
+	"cc":         FileTypeC,
+	"cpp":        FileTypeC,
+	"hpp":        FileTypeC,
+	"ipynb":      FileTypeNotebook,
+	"html":       FileTypeHTML,
+	"htm":        FileTypeHTML,
+	"md":         FileTypeMarkdown,
+	"markdown":   FileTypeMarkdown,
+	"rst":        FileTypeMarkdown,
+	"txt":        FileTypeText,
+	"log":        FileTypeText,
+}
+
+// nameTypes classifies files known by their whole name
+var nameTypes = map[string]string{
+	"dockerfile":  FileTypeDockerfile,
+	"id_rsa":      FileTypeKey,
+	"id_dsa":      FileTypeKey,
+	"id_ecdsa":    FileTypeKey,
+	"id_ed25519":  FileTypeKey,
+	".npmrc":      FileTypeINI,
+	".pypirc":     FileTypeINI,
+	".netrc":      FileTypeINI,
+	".gitconfig":  FileTypeINI,
+	".htpasswd":   FileTypeKey,
+	"credentials": FileTypeINI,
+}
+
+// ValidFileTypes lists the file types results can be filtered by
+var ValidFileTypes = func() map[string]bool {
+	valid := map[string]bool{FileTypeOther: true}
+	for _, fileType := range extensionTypes {
+		valid[fileType] = true
+	}
+	for _, fileType := range nameTypes {
+		valid[fileType] = true
+	}
+	return valid
+}()
+
+// DetectFileType classifies a file by its name, falling back to its content when
+// it's known. Empty for results without a file, such as Docker Hub repositories.
+func DetectFileType(filePath, content string) string {
+	if filePath == "" {
+		return ""
+	}
+
+	name := strings.ToLower(path.Base(filePath))
+	if name == ".env" || strings.HasPrefix(name, ".env.") {
+		return FileTypeEnv
+	}
+	if fileType, ok := nameTypes[name]; ok {
+		return fileType
+	}
+	if strings.HasPrefix(name, "dockerfile.") || strings.HasSuffix(name, ".dockerfile") {
+		return FileTypeDockerfile
+	}
+	if fileType, ok := extensionTypes[strings.TrimPrefix(path.Ext(name), ".")]; ok {
+		return fileType
+	}
+
+	// Files without a telling name, such as scripts without an extension
+	switch {
+	case strings.Contains(content, "-----BEGIN") && strings.Contains(content, "PRIVATE KEY-----"):
+		return FileTypeKey
+	case strings.HasPrefix(content, "#!"):
+		firstLine, _, _ := strings.Cut(content, "\n")
+		if strings.Contains(firstLine, "python") {
+			return FileTypePython
+		}
+		if strings.Contains(firstLine, "node") {
+			return FileTypeJavaScript
+		}
+		return FileTypeShell
+	}
+	return FileTypeOther
+}
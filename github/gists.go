@@ -0,0 +1,122 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// Gist is a public gist of a user
+type Gist struct {
+	ID          string
+	Owner       string
+	Description string
+	HTMLURL     string
+	UpdatedAt   time.Time
+}
+
+// GistRevision is the content of a gist at one of its versions
+type GistRevision struct {
+	Version string
+	Files   map[string]string // content by file name, GitHub truncates files over 1 MB
+}
+
+// ListGists returns up to limit public gists of a user
+func (s *SearchService) ListGists(ctx context.Context, user string, limit int) ([]Gist, error) {
+	client, _, err := s.tokenPool.GetClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	var gists []Gist
+	opts := &github.GistListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for len(gists) < limit {
+		page, resp, err := client.Gists.List(ctx, user, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list gists of %s: %w", user, err)
+		}
+		for _, gist := range page {
+			if len(gists) < limit {
+				gists = append(gists, Gist{
+					ID:          gist.GetID(),
+					Owner:       gist.GetOwner().GetLogin(),
+					Description: gist.GetDescription(),
+					HTMLURL:     gist.GetHTMLURL(),
+					UpdatedAt:   gist.GetUpdatedAt().Time,
+				})
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return gists, nil
+}
+
+// ListGistVersions returns up to limit versions of a gist, newest first
+func (s *SearchService) ListGistVersions(ctx context.Context, id string, limit int) ([]string, error) {
+	client, _, err := s.tokenPool.GetClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	commits, _, err := client.Gists.ListCommits(ctx, id, &github.ListOptions{PerPage: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions of gist %s: %w", id, err)
+	}
+	versions := make([]string, 0, len(commits))
+	for _, commit := range commits {
+		if len(versions) < limit {
+			versions = append(versions, commit.GetVersion())
+		}
+	}
+	return versions, nil
+}
+
+// GetGistRevision returns the files of a gist at a version
+func (s *SearchService) GetGistRevision(ctx context.Context, id, version string) (*GistRevision, error) {
+	client, _, err := s.tokenPool.GetClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	gist, _, err := client.Gists.GetRevision(ctx, id, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch revision %s of gist %s: %w", version, id, err)
+	}
+	revision := &GistRevision{Version: version, Files: make(map[string]string, len(gist.Files))}
+	for name, file := range gist.Files {
+		revision.Files[string(name)] = file.GetContent()
+	}
+	return revision, nil
+}
+
+// AddedGistLines returns the lines each file of after has that the same file of
+// before doesn't, leaving out files without new lines. before may be nil for the
+// first revision.
+func AddedGistLines(before, after *GistRevision) map[string]string {
+	added := make(map[string]string)
+	for name, content := range after.Files {
+		old := make(map[string]bool)
+		if before != nil {
+			for _, line := range strings.Split(before.Files[name], "\n") {
+				old[line] = true
+			}
+		}
+
+		var lines []string
+		for _, line := range strings.Split(content, "\n") {
+			if !old[line] && strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			added[name] = strings.Join(lines, "\n")
+		}
+	}
+	return added
+}
@@ -0,0 +1,29 @@
+package github
+
+import "testing"
+
+func TestAddedGistLines(t *testing.T) {
+	before := &GistRevision{Files: map[string]string{
+		"notes.md": "# Notes\nremember the milk",
+		"old.txt":  "gone",
+	}}
+	after := &GistRevision{Files: map[string]string{
+		"notes.md": "# Notes\nremember the milk\nDB_PASSWORD=hunter2\n",
+		"new.env":  "API_KEY=abc\n\nDEBUG=1",
+	}}
+
+	added := AddedGistLines(before, after)
+	if len(added) != 2 {
+		t.Fatalf("AddedGistLines() = %v, want notes.md and new.env", added)
+	}
+	if added["notes.md"] != "DB_PASSWORD=hunter2" {
+		t.Errorf("notes.md added %q, want only the new line", added["notes.md"])
+	}
+	if added["new.env"] != "API_KEY=abc\nDEBUG=1" {
+		t.Errorf("new.env added %q, want every non-blank line", added["new.env"])
+	}
+
+	if unchanged := AddedGistLines(after, after); len(unchanged) != 0 {
+		t.Errorf("AddedGistLines() of the same revision = %v, want nothing", unchanged)
+	}
+}
@@ -0,0 +1,113 @@
+package github
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// maxInfraFindings caps the addresses and hostnames reported for one file
+const maxInfraFindings = 20
+
+var (
+	ipv4Pattern     = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6Pattern     = regexp.MustCompile(`(?i)\b[0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7}\b`)
+	hostnamePattern = regexp.MustCompile(`(?i)\b[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?)+\b`)
+)
+
+// InfraDetector finds IP addresses inside internal networks and hostnames under
+// internal DNS zones in file content
+type InfraDetector struct {
+	networks []*net.IPNet
+	zones    []string // lowercase, without the leading dot
+}
+
+// NewInfraDetector creates a detector for the given CIDR ranges and DNS zones,
+// nil when both are empty
+func NewInfraDetector(cidrs, zones []string) (*InfraDetector, error) {
+	if len(cidrs) == 0 && len(zones) == 0 {
+		return nil, nil
+	}
+
+	d := &InfraDetector{}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		d.networks = append(d.networks, network)
+	}
+	for _, zone := range zones {
+		zone = strings.Trim(strings.ToLower(strings.TrimSpace(zone)), ".")
+		if zone == "" {
+			return nil, fmt.Errorf("empty DNS zone")
+		}
+		d.zones = append(d.zones, zone)
+	}
+	return d, nil
+}
+
+// Find returns the internal addresses and hostnames in content, in the order they
+// first appear
+func (d *InfraDetector) Find(content string) []string {
+	if d == nil || content == "" {
+		return nil
+	}
+
+	var found []string
+	seen := make(map[string]bool)
+	add := func(value string) bool {
+		if !seen[value] {
+			seen[value] = true
+			found = append(found, value)
+		}
+		return len(found) < maxInfraFindings
+	}
+
+	if len(d.networks) > 0 {
+		for _, pattern := range []*regexp.Regexp{ipv4Pattern, ipv6Pattern} {
+			for _, candidate := range pattern.FindAllString(content, -1) {
+				ip := net.ParseIP(candidate)
+				if ip == nil || !d.internalIP(ip) {
+					continue
+				}
+				if !add(ip.String()) {
+					return found
+				}
+			}
+		}
+	}
+
+	if len(d.zones) > 0 {
+		for _, candidate := range hostnamePattern.FindAllString(content, -1) {
+			hostname := strings.ToLower(candidate)
+			if !d.internalHostname(hostname) {
+				continue
+			}
+			if !add(hostname) {
+				return found
+			}
+		}
+	}
+
+	return found
+}
+
+func (d *InfraDetector) internalIP(ip net.IP) bool {
+	for _, network := range d.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *InfraDetector) internalHostname(hostname string) bool {
+	for _, zone := range d.zones {
+		if hostname == zone || strings.HasSuffix(hostname, "."+zone) {
+			return true
+		}
+	}
+	return false
+}
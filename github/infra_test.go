@@ -0,0 +1,32 @@
+package github
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInfraDetectorFind(t *testing.T) {
+	detector, err := NewInfraDetector([]string{"10.0.0.0/8", "fd00::/8"}, []string{".corp.acme.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := `DB_HOST=10.2.3.4
+CACHE=8.8.8.8
+API=https://billing.corp.acme.com/v1 and billing.CORP.acme.com again
+PUBLIC=www.acme.com
+NOT_OURS=corp.acme.com.evil.io
+V6=fd12:3456::1
+VERSION=1.2.3.4.5`
+	want := []string{"10.2.3.4", "fd12:3456::1", "billing.corp.acme.com"}
+	if got := detector.Find(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() = %v, want %v", got, want)
+	}
+
+	if detector, err := NewInfraDetector(nil, nil); detector != nil || err != nil {
+		t.Errorf("NewInfraDetector(nil, nil) = %v, %v, want nil, nil", detector, err)
+	}
+	if _, err := NewInfraDetector([]string{"10.0.0.0"}, nil); err == nil {
+		t.Error("NewInfraDetector accepted an address without prefix length")
+	}
+}
@@ -0,0 +1,92 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// Repository is a repository found by repository search
+type Repository struct {
+	FullName      string
+	Owner         string
+	Name          string
+	Description   string
+	Topics        []string
+	HTMLURL       string
+	Stars         int
+	Fork          bool
+	Archived      bool
+	DefaultBranch string
+	CreatedAt     time.Time
+	PushedAt      time.Time
+}
+
+// SearchRepositories returns the first 100 repositories matching query, most
+// recently updated first
+func (s *SearchService) SearchRepositories(ctx context.Context, query string) ([]Repository, error) {
+	log.Printf("Executing repository search query: %s", query)
+
+	client, _, err := s.tokenPool.GetClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	found, resp, err := client.Search.Repositories(ctx, query, &github.SearchOptions{
+		Sort:        "updated",
+		Order:       "desc",
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		if resp != nil && resp.StatusCode == 403 {
+			return nil, fmt.Errorf("rate limit exceeded: %w", err)
+		}
+		return nil, fmt.Errorf("repository search failed: %w", err)
+	}
+
+	repos := make([]Repository, 0, len(found.Repositories))
+	for _, repo := range found.Repositories {
+		repos = append(repos, toRepository(repo))
+	}
+	return repos, nil
+}
+
+func toRepository(repo *github.Repository) Repository {
+	return Repository{
+		FullName:      repo.GetFullName(),
+		Owner:         repo.GetOwner().GetLogin(),
+		Name:          repo.GetName(),
+		Description:   repo.GetDescription(),
+		Topics:        repo.Topics,
+		HTMLURL:       repo.GetHTMLURL(),
+		Stars:         repo.GetStargazersCount(),
+		Fork:          repo.GetFork(),
+		Archived:      repo.GetArchived(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		CreatedAt:     repo.GetCreatedAt().Time,
+		PushedAt:      repo.GetPushedAt().Time,
+	}
+}
+
+// GetRepository returns a repository by its owner/name
+func (s *SearchService) GetRepository(ctx context.Context, repoFullName string) (Repository, error) {
+	owner, name, ok := strings.Cut(repoFullName, "/")
+	if !ok {
+		return Repository{}, fmt.Errorf("invalid repository name %q", repoFullName)
+	}
+
+	client, _, err := s.tokenPool.GetClient(ctx)
+	if err != nil {
+		return Repository{}, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	repo, _, err := client.Repositories.Get(ctx, owner, name)
+	if err != nil {
+		return Repository{}, fmt.Errorf("failed to fetch %s: %w", repoFullName, err)
+	}
+	return toRepository(repo), nil
+}
@@ -3,22 +3,50 @@ package github
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/go-github/v57/github"
 )
 
+// maxQueryLength is the longest code search query GitHub accepts
+const maxQueryLength = 256
+
+// Query dialects rules are searched with
+const (
+	DialectLegacy     = "legacy"      // keywords are searched for as text, the default
+	DialectCodeSearch = "code_search" // keywords may use regular expressions, content: and NOT
+)
+
 // SearchOptions represents search options
 type SearchOptions struct {
-	Keywords    []string
-	MatchType   string   // "precise" or "fuzzy"
-	ExcludeExts []string
-	Language    string
-	Sort        string // "indexed", "stars", "forks", etc.
-	Order       string // "asc" or "desc"
+	Keywords     []string
+	MatchType    string // "precise" or "fuzzy"
+	ExcludeExts  []string
+	ExcludeRepos []string // owner/name, left out of the query as far as it stays short enough
+	Dialect      string   // DialectLegacy when empty
+	RawQuery     string   // sent verbatim instead of the query built from the options above
+	FileSize     string   // size: qualifier value in bytes, empty for any size
+	Language     string
+	Sort         string        // "indexed", "stars", "forks", etc.
+	Order        string        // "asc" or "desc"
+	TokenGroup   string        // dedicated token group tried before the shared pool
+	MaxPages     int           // pages of 100 results read, 0 for all 10
+	Pages        []int         // pages read instead, to re-run the pages of an incomplete search
+	PageDelay    time.Duration // wait between pages
+}
+
+// SearchResponse is what a code search found
+type SearchResponse struct {
+	Items           []*SearchResultItem
+	Total           int    // results GitHub counted for the query
+	IncompletePages []int  // pages GitHub answered with incomplete_results, its search timed out on them
+	Token           string // masked token that ran the search
 }
 
 // SearchResultItem represents a single search result
@@ -32,11 +60,79 @@ type SearchResultItem struct {
 	ContentSnippet  string    `json:"content_snippet"`
 	Score           float64   `json:"score"`
 	CreatedAt       time.Time `json:"created_at"`
+	Source          string    `json:"source"` // empty for GitHub code search
+	Content         string    `json:"-"`      // whole file when it was fetched, kept as evidence
+	Fragments       []string  `json:"-"`      // every text match of code search results
+	SHA             string    `json:"-"`      // blob SHA of code search results
+	Verified        bool      `json:"-"`      // the content was checked to contain every keyword
+	Severity        string    `json:"-"`      // set by sources that rate their findings themselves, overrides the rule severity
+	Token           string    `json:"-"`      // masked token of the code search that found it
+}
+
+// TokenError is a search failure with the masked token that ran the search, so
+// rate limit problems can be traced to a token
+type TokenError struct {
+	Token string
+	Err   error
+}
+
+func (e *TokenError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// TokenOf returns the masked token a search failed on, empty when the search
+// failed before a token was picked
+func TokenOf(err error) string {
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) {
+		return tokenErr.Token
+	}
+	return ""
+}
+
+// InvalidQueryError is returned when GitHub rejects a search query, such as one that
+// is too long, has too many operators or uses a qualifier it doesn't allow. The
+// query fails the same way until it is changed.
+type InvalidQueryError struct {
+	Query   string
+	Details string // the reasons GitHub gave
+}
+
+func (e *InvalidQueryError) Error() string {
+	return fmt.Sprintf("invalid query: %s", e.Details)
+}
+
+// invalidQuery returns the InvalidQueryError of a 422 answer, nil for other errors
+func invalidQuery(query string, resp *github.Response, err error) *InvalidQueryError {
+	if resp == nil || resp.StatusCode != http.StatusUnprocessableEntity {
+		return nil
+	}
+	details := []string{}
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) {
+		if errResp.Message != "" {
+			details = append(details, errResp.Message)
+		}
+		for _, e := range errResp.Errors {
+			if e.Message != "" {
+				details = append(details, e.Message)
+			}
+		}
+	}
+	if len(details) == 0 {
+		details = append(details, err.Error())
+	}
+	return &InvalidQueryError{Query: query, Details: strings.Join(details, "; ")}
 }
 
 // SearchService handles GitHub code search
 type SearchService struct {
 	tokenPool *TokenPool
+	budget    atomic.Pointer[SearchBudget] // nil when searches aren't budgeted
 }
 
 // NewSearchService creates a new search service
@@ -46,12 +142,23 @@ func NewSearchService(tokenPool *TokenPool) *SearchService {
 	}
 }
 
-// SearchCode performs a GitHub code search
-func (s *SearchService) SearchCode(ctx context.Context, opts SearchOptions) ([]*SearchResultItem, error) {
+// SetBudget records the code searches made on budget, nil stops budgeting them
+func (s *SearchService) SetBudget(budget *SearchBudget) {
+	s.budget.Store(budget)
+}
+
+// Budget returns the budget code searches are recorded on, nil when there is none
+func (s *SearchService) Budget() *SearchBudget {
+	return s.budget.Load()
+}
+
+// SearchCode performs a GitHub code search. Pages GitHub couldn't search in time
+// are reported, their results are only those found before it gave up.
+func (s *SearchService) SearchCode(ctx context.Context, opts SearchOptions) (*SearchResponse, error) {
 	query := s.buildQuery(opts)
 	log.Printf("Executing search query: %s", query)
 
-	client, tokenInfo, err := s.tokenPool.GetClient(ctx)
+	client, tokenInfo, err := s.tokenPool.GetClientFor(ctx, opts.TokenGroup)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client: %w", err)
 	}
@@ -64,51 +171,98 @@ func (s *SearchService) SearchCode(ctx context.Context, opts SearchOptions) ([]*
 		},
 	}
 
-	results := make([]*SearchResultItem, 0)
-	page := 1
+	pages := opts.Pages
+	if len(pages) == 0 {
+		maxPages := opts.MaxPages
+		if maxPages <= 0 || maxPages > maxSearchPages {
+			maxPages = maxSearchPages
+		}
+		for page := 1; page <= maxPages; page++ {
+			pages = append(pages, page)
+		}
+	}
+
+	token := tokenInfo.Masked()
+	response := &SearchResponse{Items: make([]*SearchResultItem, 0), Token: token}
 
-	for {
+	for i, page := range pages {
 		searchOpts.Page = page
 
 		// Perform search
+		if budget := s.Budget(); budget != nil {
+			budget.Spend(time.Now())
+		}
 		codeResults, resp, err := client.Search.Code(ctx, query, searchOpts)
 		if err != nil {
 			// Check if it's a rate limit error
 			if resp != nil && resp.StatusCode == 403 {
-				log.Printf("Rate limit hit, token stats: %+v", tokenInfo)
-				return nil, fmt.Errorf("rate limit exceeded: %w", err)
+				log.Printf("Rate limit hit on token %s", token)
+				return nil, &TokenError{Token: token, Err: fmt.Errorf("rate limit exceeded: %w", err)}
+			}
+			if invalid := invalidQuery(query, resp, err); invalid != nil {
+				return nil, &TokenError{Token: token, Err: invalid}
 			}
-			return nil, fmt.Errorf("search failed: %w", err)
+			return nil, &TokenError{Token: token, Err: fmt.Errorf("search failed: %w", err)}
 		}
 
 		// Process results
 		for _, result := range codeResults.CodeResults {
-			item := s.convertToSearchResultItem(result, opts.Keywords)
+			item := s.convertToSearchResultItem(result, opts.Keywords, opts.Dialect)
 			if item != nil {
-				results = append(results, item)
+				item.Token = token
+				response.Items = append(response.Items, item)
 			}
 		}
+		response.Total = codeResults.GetTotal()
+		if codeResults.GetIncompleteResults() {
+			response.IncompletePages = append(response.IncompletePages, page)
+		}
 
-		log.Printf("Page %d: Found %d results, Total: %d", page, len(codeResults.CodeResults), codeResults.GetTotal())
+		log.Printf("Page %d: Found %d results, Total: %d, Incomplete: %t", page, len(codeResults.CodeResults), codeResults.GetTotal(), codeResults.GetIncompleteResults())
 
 		// Check if there are more pages
-		if page >= 10 || len(codeResults.CodeResults) == 0 {
+		if i == len(pages)-1 || len(codeResults.CodeResults) == 0 {
 			// GitHub API limits to 1000 results (10 pages * 100 per page)
 			break
 		}
 
-		page++
-
 		// Rate limiting: wait between requests
-		time.Sleep(2 * time.Second)
+		if err := Sleep(ctx, opts.PageDelay); err != nil {
+			return nil, err
+		}
 	}
 
-	log.Printf("Search completed: %d total results", len(results))
-	return results, nil
+	log.Printf("Search completed: %d total results", len(response.Items))
+	return response, nil
+}
+
+// Query returns the query a code search with the options sends
+func (s *SearchService) Query(opts SearchOptions) string {
+	return s.buildQuery(opts)
+}
+
+// CheckRawQuery reports why GitHub can't be sent a raw query
+func CheckRawQuery(query string) error {
+	switch {
+	case strings.TrimSpace(query) == "":
+		return errors.New("must not be blank")
+	case len(query) > maxQueryLength:
+		return fmt.Errorf("must be at most %d characters, GitHub rejects longer queries", maxQueryLength)
+	case strings.ContainsAny(query, "\r\n\t"):
+		return errors.New("must be a single line")
+	}
+	return nil
 }
 
 // buildQuery builds a GitHub search query from options
 func (s *SearchService) buildQuery(opts SearchOptions) string {
+	if opts.RawQuery != "" {
+		return opts.RawQuery
+	}
+	if opts.Dialect == DialectCodeSearch {
+		return buildCodeSearchQuery(opts)
+	}
+
 	var queryParts []string
 
 	if opts.MatchType == "precise" {
@@ -140,12 +294,64 @@ func (s *SearchService) buildQuery(opts SearchOptions) string {
 	if opts.Language != "" {
 		query += fmt.Sprintf(" language:%s", opts.Language)
 	}
+	if opts.FileSize != "" {
+		query += " size:" + opts.FileSize
+	}
+
+	// Exclude repositories while the query stays short enough, the results of the
+	// others are filtered out by the monitor
+	for _, repo := range opts.ExcludeRepos {
+		qualifier := " -repo:" + repo
+		if repo == "" || len(query)+len(qualifier) > maxQueryLength {
+			continue
+		}
+		query += qualifier
+	}
+
+	return query
+}
+
+// buildCodeSearchQuery builds a query in the code search syntax. Keywords are
+// passed as written, so they may be regular expressions, content: terms or NOT
+// terms, and precise rules only have their plain terms quoted.
+func buildCodeSearchQuery(opts SearchOptions) string {
+	var queryParts []string
+	for _, keyword := range opts.Keywords {
+		if keyword == "" {
+			continue
+		}
+		term, ok := ParseTerm(keyword, DialectCodeSearch)
+		if opts.MatchType == "precise" && ok && term.Plain() && term.Text == keyword && !strings.HasPrefix(keyword, `"`) {
+			keyword = fmt.Sprintf(`"%s"`, keyword)
+		}
+		queryParts = append(queryParts, keyword)
+	}
+
+	for _, ext := range opts.ExcludeExts {
+		if ext != "" {
+			queryParts = append(queryParts, "NOT path:*."+strings.TrimPrefix(ext, "."))
+		}
+	}
+	if opts.Language != "" {
+		queryParts = append(queryParts, "language:"+opts.Language)
+	}
+	if opts.FileSize != "" {
+		queryParts = append(queryParts, "size:"+opts.FileSize)
+	}
+	query := strings.Join(queryParts, " ")
 
+	for _, repo := range opts.ExcludeRepos {
+		qualifier := " NOT repo:" + repo
+		if repo == "" || len(query)+len(qualifier) > maxQueryLength {
+			continue
+		}
+		query += qualifier
+	}
 	return query
 }
 
 // convertToSearchResultItem converts a GitHub code result to our format
-func (s *SearchService) convertToSearchResultItem(result *github.CodeResult, keywords []string) *SearchResultItem {
+func (s *SearchService) convertToSearchResultItem(result *github.CodeResult, keywords []string, dialect string) *SearchResultItem {
 	if result == nil || result.Repository == nil {
 		return nil
 	}
@@ -156,28 +362,30 @@ func (s *SearchService) convertToSearchResultItem(result *github.CodeResult, key
 		FilePath:        result.GetPath(),
 		FileURL:         result.GetHTMLURL(), // Use HTML URL as file URL
 		HTMLURL:         result.GetHTMLURL(),
-		MatchedKeywords: s.findMatchedKeywords(result, keywords),
+		MatchedKeywords: s.findMatchedKeywords(result, keywords, dialect),
 		ContentSnippet:  s.extractSnippet(result),
+		Fragments:       s.extractFragments(result),
 		Score:           1.0, // Default score, can be enhanced later
 		CreatedAt:       time.Now(),
+		SHA:             result.GetSHA(),
 	}
 
 	return item
 }
 
 // findMatchedKeywords finds which keywords were matched in the result
-func (s *SearchService) findMatchedKeywords(result *github.CodeResult, keywords []string) []string {
+func (s *SearchService) findMatchedKeywords(result *github.CodeResult, keywords []string, dialect string) []string {
 	matched := make([]string, 0)
-	content := strings.ToLower(result.GetName() + " " + result.GetPath())
+	content := result.GetName() + " " + result.GetPath()
 
 	if result.TextMatches != nil {
 		for _, match := range result.TextMatches {
-			content += " " + strings.ToLower(match.GetFragment())
+			content += "\n" + match.GetFragment()
 		}
 	}
 
 	for _, keyword := range keywords {
-		if keyword != "" && strings.Contains(content, strings.ToLower(keyword)) {
+		if term, ok := ParseTerm(keyword, dialect); ok && !term.Negated && term.Index(content) >= 0 {
 			matched = append(matched, keyword)
 		}
 	}
@@ -189,31 +397,52 @@ func (s *SearchService) findMatchedKeywords(result *github.CodeResult, keywords
 func (s *SearchService) extractSnippet(result *github.CodeResult) string {
 	if result.TextMatches != nil && len(result.TextMatches) > 0 {
 		// Use the first text match as snippet
-		snippet := result.TextMatches[0].GetFragment()
-		if len(snippet) > 500 {
-			snippet = snippet[:500] + "..."
-		}
-		return snippet
+		return truncate(result.TextMatches[0].GetFragment())
 	}
 
 	return ""
 }
 
+// extractFragments returns every text match of the search result
+func (s *SearchService) extractFragments(result *github.CodeResult) []string {
+	fragments := make([]string, 0, len(result.TextMatches))
+	for _, match := range result.TextMatches {
+		if fragment := match.GetFragment(); fragment != "" {
+			fragments = append(fragments, truncate(fragment))
+		}
+	}
+	return fragments
+}
+
+// Sleep waits for d, returning early with the error of ctx when it is canceled
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // SearchWithRetry performs a search with automatic retry on rate limit
-func (s *SearchService) SearchWithRetry(ctx context.Context, opts SearchOptions, maxRetries int) ([]*SearchResultItem, error) {
+func (s *SearchService) SearchWithRetry(ctx context.Context, opts SearchOptions, maxRetries int) (*SearchResponse, error) {
 	var lastErr error
 
 	for i := 0; i < maxRetries; i++ {
-		results, err := s.SearchCode(ctx, opts)
+		response, err := s.SearchCode(ctx, opts)
 		if err == nil {
-			return results, nil
+			return response, nil
 		}
 
 		lastErr = err
 
 		if strings.Contains(err.Error(), "rate limit") {
 			log.Printf("Rate limit hit, attempt %d/%d, waiting before retry...", i+1, maxRetries)
-			time.Sleep(time.Duration(i+1) * 10 * time.Second)
+			if err := Sleep(ctx, time.Duration(i+1)*10*time.Second); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
@@ -234,6 +463,54 @@ func ParseKeywords(keywordsJSON string) ([]string, error) {
 	return keywords, nil
 }
 
+// ParseKeywordWeights parses the keyword weights of a rule from a JSON object.
+// Keys are lowercased, since keywords match regardless of case.
+func ParseKeywordWeights(weightsJSON string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	if weightsJSON == "" {
+		return weights, nil
+	}
+
+	var parsed map[string]float64
+	if err := json.Unmarshal([]byte(weightsJSON), &parsed); err != nil {
+		return nil, err
+	}
+	for keyword, weight := range parsed {
+		if weight < 0 {
+			return nil, fmt.Errorf("weight of %q is negative", keyword)
+		}
+		weights[strings.ToLower(keyword)] = weight
+	}
+	return weights, nil
+}
+
+// ScoreKeywords sums the weights of the matched keywords, keywords without a
+// weight count 1
+func ScoreKeywords(matched []string, weights map[string]float64) float64 {
+	score := 0.0
+	for _, keyword := range matched {
+		if weight, ok := weights[strings.ToLower(keyword)]; ok {
+			score += weight
+		} else {
+			score++
+		}
+	}
+	return score
+}
+
+// ParseExcludeRepos parses excluded repositories from JSON string
+func ParseExcludeRepos(reposJSON string) ([]string, error) {
+	if reposJSON == "" {
+		return []string{}, nil
+	}
+
+	var repos []string
+	if err := json.Unmarshal([]byte(reposJSON), &repos); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
 // ParseExcludeExts parses exclude extensions from JSON string
 func ParseExcludeExts(extsJSON string) ([]string, error) {
 	if extsJSON == "" {
@@ -0,0 +1,94 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPConfig holds the timeouts, connection pooling and retries of the clients
+type HTTPConfig struct {
+	ConnectTimeout time.Duration // dialing, proxy included, and the TLS handshake
+	ReadTimeout    time.Duration // waiting for the response headers once the request is sent
+	MaxIdleConns   int           // kept open by each client of the pool
+	MaxRetries     int           // retries of GET requests that failed on the network or with a 502, 503 or 504
+	RetryWait      time.Duration // before the first retry, doubled for each next one with jitter
+}
+
+// DefaultHTTPConfig is used until SetHTTPConfig is called
+var DefaultHTTPConfig = HTTPConfig{
+	ConnectTimeout: 10 * time.Second,
+	ReadTimeout:    60 * time.Second,
+	MaxIdleConns:   100,
+	MaxRetries:     2,
+	RetryWait:      time.Second,
+}
+
+// newTransport creates the base transport of a client with the timeouts of httpConfig
+func newTransport(httpConfig HTTPConfig) (*http.Transport, *net.Dialer) {
+	dialer := &net.Dialer{Timeout: httpConfig.ConnectTimeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   httpConfig.ConnectTimeout,
+		ResponseHeaderTimeout: httpConfig.ReadTimeout,
+		MaxIdleConns:          httpConfig.MaxIdleConns,
+		MaxIdleConnsPerHost:   httpConfig.MaxIdleConns,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return transport, dialer
+}
+
+// retryTransport retries GET requests that failed on the way, such as on a proxy
+// that dropped the connection or a GitHub frontend that timed out. Rate limit
+// answers aren't retried, the token pool moves to another token for them.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	wait       time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.base.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.maxRetries || !retryable(req.Context(), resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(backoff(t.wait, attempt))
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether a failed request may succeed when sent again
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff doubles wait with every attempt and spreads it by up to half either way,
+// so the clients of the pool don't retry in lockstep
+func backoff(wait time.Duration, attempt int) time.Duration {
+	d := wait << attempt
+	return d/2 + time.Duration(rand.Int63n(int64(d)+1))
+}
@@ -0,0 +1,40 @@
+package github
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		want     int
+		requests int
+	}{
+		{"retries bad gateway", []int{http.StatusBadGateway, http.StatusOK}, http.StatusOK, 2},
+		{"gives up after max retries", []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}, http.StatusServiceUnavailable, 3},
+		{"leaves rate limits to the pool", []int{http.StatusForbidden, http.StatusOK}, http.StatusForbidden, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[requests])
+				requests++
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &retryTransport{base: http.DefaultTransport, maxRetries: 2}}
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want || requests != tt.requests {
+				t.Errorf("got %d after %d requests, want %d after %d", resp.StatusCode, requests, tt.want, tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package github
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github-monitor/redact"
+)
+
+// maxCIFindings caps the findings kept per file
+const maxCIFindings = 20
+
+// Kinds of CI findings
+const (
+	CIHardcodedEnv    = "hardcoded_env"    // credential-named env variable with a literal value
+	CIHardcodedSecret = "hardcoded_secret" // value that looks like a secret anywhere else in the file
+	CISecretPrinted   = "secret_printed"   // a step prints a secret, which can get around log masking
+	CISecretsDumped   = "secrets_dumped"   // toJSON(secrets) hands every secret of the repository to a step
+	CIUntrustedCode   = "untrusted_code"   // pull_request_target checks out the pull request with secrets available
+)
+
+// CIFinding is a credential problem found in a CI config
+type CIFinding struct {
+	Line   int    `json:"line"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+var (
+	ciFiles = map[string]bool{
+		".gitlab-ci.yml":          true,
+		".travis.yml":             true,
+		"jenkinsfile":             true,
+		"azure-pipelines.yml":     true,
+		"bitbucket-pipelines.yml": true,
+		".drone.yml":              true,
+	}
+	credentialName = regexp.MustCompile(`(?i)(token|secret|passw(or)?d|api_?key|access_?key|private_?key|credentials?)`)
+	envEntry       = regexp.MustCompile(`^\s*-?\s*([A-Za-z_][A-Za-z0-9_]*)\s*[:=]\s*(.*)$`)
+	secretPrinted  = regexp.MustCompile(`(?i)\b(echo|printf|print|write-host|console\.log)\b.*\$\{\{\s*secrets\.`)
+	secretsDumped  = regexp.MustCompile(`(?i)tojson\(\s*secrets\s*\)`)
+	prHeadCheckout = regexp.MustCompile(`github\.event\.pull_request\.head\.(ref|sha)`)
+)
+
+// IsCIConfig reports whether a file configures a CI pipeline, such as a GitHub
+// Actions workflow or a .gitlab-ci.yml
+func IsCIConfig(filePath string) bool {
+	dir, name := path.Split(strings.ToLower(filePath))
+	if ciFiles[name] {
+		return true
+	}
+	if strings.HasSuffix(dir, ".github/workflows/") {
+		return strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")
+	}
+	return strings.HasSuffix(dir, ".circleci/") && name == "config.yml"
+}
+
+// FindCIIssues returns the hardcoded credentials and misused secrets in a CI config,
+// at most one per line
+func FindCIIssues(content string) []CIFinding {
+	var findings []CIFinding
+	untrustedTrigger := strings.Contains(content, "pull_request_target")
+	envIndent := -1 // indentation of the env: key whose block the line is in, -1 outside
+
+	for i, line := range strings.Split(content, "\n") {
+		if len(findings) == maxCIFindings {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t-"))
+		if envIndent >= 0 && indent <= envIndent {
+			envIndent = -1
+		}
+
+		add := func(kind, detail string) {
+			findings = append(findings, CIFinding{Line: i + 1, Kind: kind, Detail: detail})
+		}
+		switch {
+		case envIndent >= 0 && hardcodedEnv(trimmed):
+			name := envEntry.FindStringSubmatch(trimmed)[1]
+			add(CIHardcodedEnv, name+" is set to a literal value")
+		case secretsDumped.MatchString(line):
+			add(CISecretsDumped, "every secret is passed to the step")
+		case secretPrinted.MatchString(line):
+			add(CISecretPrinted, "a secret is printed")
+		case untrustedTrigger && prHeadCheckout.MatchString(line):
+			add(CIUntrustedCode, "pull_request_target runs the code of the pull request with access to secrets")
+		case !strings.Contains(line, "${{") && redact.Secrets(line) != line:
+			add(CIHardcodedSecret, "value that looks like a secret")
+		}
+
+		if key, _, _ := strings.Cut(trimmed, ":"); strings.TrimPrefix(key, "- ") == "env" || key == "variables" {
+			envIndent = indent
+		}
+	}
+	return findings
+}
+
+// hardcodedEnv reports whether an env entry sets a credential-named variable to a
+// literal rather than a secret or variable reference
+func hardcodedEnv(entry string) bool {
+	match := envEntry.FindStringSubmatch(entry)
+	if match == nil || !credentialName.MatchString(match[1]) {
+		return false
+	}
+	value := strings.Trim(strings.TrimSpace(match[2]), `"'`)
+	if len(value) < 8 || strings.ContainsAny(value, "$%") || strings.HasPrefix(value, "${{") {
+		return false
+	}
+	switch strings.ToLower(value) {
+	case "required", "optional", "changeme", "password", "disabled":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,231 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github-monitor/auth"
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/grpcapi/monitorpb"
+	"github-monitor/repository"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodRole describes who may call a method and whether it changes state
+type methodRole struct {
+	roles    []string
+	resource string
+	mutating bool
+}
+
+var (
+	anyRole     = []string{auth.RoleAdmin, auth.RoleAnalyst, auth.RoleViewer}
+	analystRole = []string{auth.RoleAdmin, auth.RoleAnalyst}
+	adminRole   = []string{auth.RoleAdmin}
+)
+
+// methodRoles mirrors the role guards of the REST routes. Methods missing here are denied.
+var methodRoles = map[string]methodRole{
+	monitorpb.MonitorService_ListRules_FullMethodName:          {anyRole, "rules", false},
+	monitorpb.MonitorService_GetRule_FullMethodName:            {anyRole, "rules", false},
+	monitorpb.MonitorService_CreateRule_FullMethodName:         {analystRole, "rules", true},
+	monitorpb.MonitorService_UpdateRule_FullMethodName:         {analystRole, "rules", true},
+	monitorpb.MonitorService_DeleteRule_FullMethodName:         {analystRole, "rules", true},
+	monitorpb.MonitorService_ListResults_FullMethodName:        {anyRole, "results", false},
+	monitorpb.MonitorService_UpdateResultStatus_FullMethodName: {analystRole, "results", true},
+	monitorpb.MonitorService_GetMonitorStatus_FullMethodName:   {anyRole, "monitor", false},
+	monitorpb.MonitorService_StartMonitor_FullMethodName:       {adminRole, "monitor", true},
+	monitorpb.MonitorService_StopMonitor_FullMethodName:        {adminRole, "monitor", true},
+	monitorpb.MonitorService_GetStats_FullMethodName:           {anyRole, "dashboard", false},
+	monitorpb.MonitorService_WatchEvents_FullMethodName:        {anyRole, "events", false},
+}
+
+type claimsKey struct{}
+type projectKey struct{}
+
+// projectMetadata selects the project of a call like the X-Project-ID header of the REST API
+const projectMetadata = "x-project-id"
+
+// authenticate validates the bearer token in the call metadata, resolves the selected
+// project and checks the caller's role. Monitor control checks the login role, every
+// other method the role in the project.
+func authenticate(ctx context.Context, repos *repository.Repositories, fullMethod string) (context.Context, error) {
+	role, ok := methodRoles[fullMethod]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "method is not allowed")
+	}
+	// Starting and stopping the monitor is admin only, kept to server.admin_allowlist
+	// like the REST routes
+	if role.resource == "monitor" && role.mutating && !auth.AdminAddressAllowed(peerIP(ctx)) {
+		return nil, status.Error(codes.PermissionDenied, "admin methods can't be called from this address")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var requested uint
+	if values := md.Get(projectMetadata); len(values) > 0 {
+		id, err := strconv.ParseUint(values[0], 10, 0)
+		if err != nil || id == 0 {
+			return nil, status.Error(codes.InvalidArgument, "x-project-id must be a positive integer")
+		}
+		requested = uint(id)
+	}
+
+	if !config.AppConfig.Auth.Enabled {
+		return withProject(ctx, repos.Projects, nil, requested)
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+
+	tokenString, found := strings.CutPrefix(values[0], "Bearer ")
+	if !found {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+
+	claims, err := auth.ValidateToken(tokenString)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	if !auth.IsSessionActive(ctx, repos.Sessions, claims.ID) {
+		return nil, status.Error(codes.Unauthenticated, "session has been revoked")
+	}
+
+	ctx, err = withProject(ctx, repos.Projects, claims, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	callerRole := projectFromContext(ctx).Role
+	if role.resource == "monitor" {
+		callerRole = claims.Role
+	}
+	for _, allowed := range role.roles {
+		if callerRole == allowed {
+			ctx = repository.WithActor(ctx, claims.Subject)
+			return context.WithValue(ctx, claimsKey{}, claims), nil
+		}
+	}
+
+	return nil, status.Error(codes.PermissionDenied, "insufficient permissions")
+}
+
+// peerIP returns the address a call came from, nil when it is unknown
+func peerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if addr, ok := p.Addr.(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	return nil
+}
+
+// withProject resolves the project of a call and restricts ctx to it
+func withProject(ctx context.Context, projects repository.ProjectRepo, claims *auth.Claims, requested uint) (context.Context, error) {
+	access, err := auth.ResolveProject(ctx, projects, claims, requested)
+	switch {
+	case errors.Is(err, auth.ErrProjectNotFound):
+		return nil, status.Error(codes.NotFound, "project not found")
+	case errors.Is(err, auth.ErrProjectForbidden):
+		return nil, status.Error(codes.PermissionDenied, "no access to the project")
+	case err != nil:
+		return nil, dbError(err)
+	}
+	return context.WithValue(access.Scope(ctx), projectKey{}, access), nil
+}
+
+// projectFromContext returns the project of the call set by authenticate
+func projectFromContext(ctx context.Context) *auth.ProjectAccess {
+	access, _ := ctx.Value(projectKey{}).(*auth.ProjectAccess)
+	if access == nil {
+		return &auth.ProjectAccess{ProjectID: models.DefaultProjectID, Role: auth.RoleAdmin, All: true}
+	}
+	return access
+}
+
+// claimsFromContext returns the caller's claims, or nil when auth is disabled
+func claimsFromContext(ctx context.Context) *auth.Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*auth.Claims)
+	return claims
+}
+
+// unaryInterceptor authenticates unary calls and audits the mutating ones
+func unaryInterceptor(repos *repository.Repositories) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, repos, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := handler(ctx, req)
+
+		if role := methodRoles[info.FullMethod]; role.mutating {
+			recordAudit(ctx, repos.Audit, info.FullMethod, role.resource, req, err)
+		}
+
+		return resp, err
+	}
+}
+
+// streamInterceptor authenticates streaming calls
+func streamInterceptor(repos *repository.Repositories) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), repos, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// recordAudit writes a mutating gRPC call to the same audit log as the REST API
+func recordAudit(ctx context.Context, audit repository.AuditRepo, fullMethod, resource string, req interface{}, callErr error) {
+	entry := models.AuditLog{
+		Method:     "GRPC",
+		Path:       fullMethod,
+		Resource:   resource,
+		StatusCode: int(status.Code(callErr)),
+	}
+
+	if data, err := json.Marshal(req); err == nil {
+		entry.Request = string(data)
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		entry.IPAddress = p.Addr.String()
+	}
+
+	if claims := claimsFromContext(ctx); claims != nil {
+		entry.Actor = claims.Subject
+		entry.Role = claims.Role
+	}
+
+	if err := audit.Create(ctx, &entry); err != nil {
+		log.Printf("Failed to write audit log: %v", err)
+	}
+}
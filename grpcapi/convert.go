@@ -0,0 +1,79 @@
+package grpcapi
+
+import (
+	"encoding/json"
+
+	"github-monitor/db/models"
+	"github-monitor/grpcapi/monitorpb"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// decodeList reads a JSON array column, tolerating empty or malformed values
+func decodeList(value string) []string {
+	var list []string
+	if value != "" {
+		json.Unmarshal([]byte(value), &list)
+	}
+	return list
+}
+
+// encodeList writes a JSON array column, leaving it empty for an empty list
+func encodeList(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+func ruleToProto(rule *models.MonitorRule) *monitorpb.Rule {
+	return &monitorpb.Rule{
+		Id:          uint64(rule.ID),
+		Name:        rule.Name,
+		Description: rule.Description,
+		Keywords:    decodeList(rule.Keywords),
+		MatchType:   rule.MatchType,
+		IsActive:    rule.IsActive,
+		ExcludeExts: decodeList(rule.ExcludeExts),
+		Severity:    rule.Severity,
+		TokenGroup:  rule.TokenGroup,
+		CreatedAt:   timestamppb.New(rule.CreatedAt),
+		UpdatedAt:   timestamppb.New(rule.UpdatedAt),
+	}
+}
+
+// applyRule copies the writable fields of a protobuf rule onto the model
+func applyRule(rule *models.MonitorRule, pb *monitorpb.Rule) {
+	rule.Name = pb.GetName()
+	rule.Description = pb.GetDescription()
+	rule.Keywords = encodeList(pb.GetKeywords())
+	rule.MatchType = pb.GetMatchType()
+	rule.IsActive = pb.GetIsActive()
+	rule.ExcludeExts = encodeList(pb.GetExcludeExts())
+	rule.Severity = pb.GetSeverity()
+	rule.TokenGroup = pb.GetTokenGroup()
+}
+
+func resultToProto(result *models.SearchResult) *monitorpb.Result {
+	return &monitorpb.Result{
+		Id:                uint64(result.ID),
+		RuleId:            uint64(result.RuleID),
+		RuleName:          result.Rule.Name,
+		RepoFullName:      result.RepoFullName,
+		RepoUrl:           result.RepoURL,
+		FilePath:          result.FilePath,
+		FileUrl:           result.FileURL,
+		HtmlUrl:           result.HTMLURL,
+		MatchedKeywords:   decodeList(result.MatchedKeywords),
+		ContentSnippet:    result.ContentSnippet,
+		Score:             result.Score,
+		Status:            result.Status,
+		Severity:          result.Severity,
+		Source:            result.Source,
+		Verdict:           result.Verdict,
+		VerdictConfidence: result.VerdictConfidence,
+		CreatedAt:         timestamppb.New(result.CreatedAt),
+		UpdatedAt:         timestamppb.New(result.UpdatedAt),
+	}
+}
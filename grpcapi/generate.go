@@ -0,0 +1,4 @@
+// Package grpcapi serves the core monitor operations over gRPC for internal services.
+package grpcapi
+
+//go:generate protoc -I ../proto --go_out=.. --go_opt=module=github-monitor --go-grpc_out=.. --go-grpc_opt=module=github-monitor monitor/v1/monitor.proto
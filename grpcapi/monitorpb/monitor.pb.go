@@ -0,0 +1,1460 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: monitor/v1/monitor.proto
+
+package monitorpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Rule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Keywords      []string               `protobuf:"bytes,4,rep,name=keywords,proto3" json:"keywords,omitempty"`
+	MatchType     string                 `protobuf:"bytes,5,opt,name=match_type,json=matchType,proto3" json:"match_type,omitempty"`
+	IsActive      bool                   `protobuf:"varint,6,opt,name=is_active,json=isActive,proto3" json:"is_active,omitempty"`
+	ExcludeExts   []string               `protobuf:"bytes,7,rep,name=exclude_exts,json=excludeExts,proto3" json:"exclude_exts,omitempty"`
+	Severity      string                 `protobuf:"bytes,8,opt,name=severity,proto3" json:"severity,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	TokenGroup    string                 `protobuf:"bytes,11,opt,name=token_group,json=tokenGroup,proto3" json:"token_group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rule) Reset() {
+	*x = Rule{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rule) ProtoMessage() {}
+
+func (x *Rule) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rule.ProtoReflect.Descriptor instead.
+func (*Rule) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Rule) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Rule) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Rule) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Rule) GetKeywords() []string {
+	if x != nil {
+		return x.Keywords
+	}
+	return nil
+}
+
+func (x *Rule) GetMatchType() string {
+	if x != nil {
+		return x.MatchType
+	}
+	return ""
+}
+
+func (x *Rule) GetIsActive() bool {
+	if x != nil {
+		return x.IsActive
+	}
+	return false
+}
+
+func (x *Rule) GetExcludeExts() []string {
+	if x != nil {
+		return x.ExcludeExts
+	}
+	return nil
+}
+
+func (x *Rule) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Rule) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Rule) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Rule) GetTokenGroup() string {
+	if x != nil {
+		return x.TokenGroup
+	}
+	return ""
+}
+
+type ListRulesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRulesRequest) Reset() {
+	*x = ListRulesRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRulesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRulesRequest) ProtoMessage() {}
+
+func (x *ListRulesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRulesRequest.ProtoReflect.Descriptor instead.
+func (*ListRulesRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{1}
+}
+
+type ListRulesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rules         []*Rule                `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRulesResponse) Reset() {
+	*x = ListRulesResponse{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRulesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRulesResponse) ProtoMessage() {}
+
+func (x *ListRulesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRulesResponse.ProtoReflect.Descriptor instead.
+func (*ListRulesResponse) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListRulesResponse) GetRules() []*Rule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type GetRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRuleRequest) Reset() {
+	*x = GetRuleRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRuleRequest) ProtoMessage() {}
+
+func (x *GetRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRuleRequest.ProtoReflect.Descriptor instead.
+func (*GetRuleRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRuleRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type CreateRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rule          *Rule                  `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateRuleRequest) Reset() {
+	*x = CreateRuleRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRuleRequest) ProtoMessage() {}
+
+func (x *CreateRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRuleRequest.ProtoReflect.Descriptor instead.
+func (*CreateRuleRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateRuleRequest) GetRule() *Rule {
+	if x != nil {
+		return x.Rule
+	}
+	return nil
+}
+
+type UpdateRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rule          *Rule                  `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRuleRequest) Reset() {
+	*x = UpdateRuleRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRuleRequest) ProtoMessage() {}
+
+func (x *UpdateRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRuleRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRuleRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateRuleRequest) GetRule() *Rule {
+	if x != nil {
+		return x.Rule
+	}
+	return nil
+}
+
+type DeleteRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRuleRequest) Reset() {
+	*x = DeleteRuleRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRuleRequest) ProtoMessage() {}
+
+func (x *DeleteRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRuleRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRuleRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteRuleRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteRuleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRuleResponse) Reset() {
+	*x = DeleteRuleResponse{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRuleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRuleResponse) ProtoMessage() {}
+
+func (x *DeleteRuleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRuleResponse.ProtoReflect.Descriptor instead.
+func (*DeleteRuleResponse) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{7}
+}
+
+type Result struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	RuleId            uint64                 `protobuf:"varint,2,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	RuleName          string                 `protobuf:"bytes,3,opt,name=rule_name,json=ruleName,proto3" json:"rule_name,omitempty"`
+	RepoFullName      string                 `protobuf:"bytes,4,opt,name=repo_full_name,json=repoFullName,proto3" json:"repo_full_name,omitempty"`
+	RepoUrl           string                 `protobuf:"bytes,5,opt,name=repo_url,json=repoUrl,proto3" json:"repo_url,omitempty"`
+	FilePath          string                 `protobuf:"bytes,6,opt,name=file_path,json=filePath,proto3" json:"file_path,omitempty"`
+	FileUrl           string                 `protobuf:"bytes,7,opt,name=file_url,json=fileUrl,proto3" json:"file_url,omitempty"`
+	HtmlUrl           string                 `protobuf:"bytes,8,opt,name=html_url,json=htmlUrl,proto3" json:"html_url,omitempty"`
+	MatchedKeywords   []string               `protobuf:"bytes,9,rep,name=matched_keywords,json=matchedKeywords,proto3" json:"matched_keywords,omitempty"`
+	ContentSnippet    string                 `protobuf:"bytes,10,opt,name=content_snippet,json=contentSnippet,proto3" json:"content_snippet,omitempty"`
+	Score             float64                `protobuf:"fixed64,11,opt,name=score,proto3" json:"score,omitempty"`
+	Status            string                 `protobuf:"bytes,12,opt,name=status,proto3" json:"status,omitempty"`
+	Severity          string                 `protobuf:"bytes,13,opt,name=severity,proto3" json:"severity,omitempty"`
+	CreatedAt         *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt         *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Source            string                 `protobuf:"bytes,16,opt,name=source,proto3" json:"source,omitempty"`   // github, dockerhub, postman, npm or pypi
+	Verdict           string                 `protobuf:"bytes,17,opt,name=verdict,proto3" json:"verdict,omitempty"` // suggested by the classifier: secret, noise or unknown, empty until classified
+	VerdictConfidence float64                `protobuf:"fixed64,18,opt,name=verdict_confidence,json=verdictConfidence,proto3" json:"verdict_confidence,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Result) Reset() {
+	*x = Result{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Result) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result) ProtoMessage() {}
+
+func (x *Result) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result.ProtoReflect.Descriptor instead.
+func (*Result) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Result) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Result) GetRuleId() uint64 {
+	if x != nil {
+		return x.RuleId
+	}
+	return 0
+}
+
+func (x *Result) GetRuleName() string {
+	if x != nil {
+		return x.RuleName
+	}
+	return ""
+}
+
+func (x *Result) GetRepoFullName() string {
+	if x != nil {
+		return x.RepoFullName
+	}
+	return ""
+}
+
+func (x *Result) GetRepoUrl() string {
+	if x != nil {
+		return x.RepoUrl
+	}
+	return ""
+}
+
+func (x *Result) GetFilePath() string {
+	if x != nil {
+		return x.FilePath
+	}
+	return ""
+}
+
+func (x *Result) GetFileUrl() string {
+	if x != nil {
+		return x.FileUrl
+	}
+	return ""
+}
+
+func (x *Result) GetHtmlUrl() string {
+	if x != nil {
+		return x.HtmlUrl
+	}
+	return ""
+}
+
+func (x *Result) GetMatchedKeywords() []string {
+	if x != nil {
+		return x.MatchedKeywords
+	}
+	return nil
+}
+
+func (x *Result) GetContentSnippet() string {
+	if x != nil {
+		return x.ContentSnippet
+	}
+	return ""
+}
+
+func (x *Result) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Result) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Result) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Result) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Result) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Result) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Result) GetVerdict() string {
+	if x != nil {
+		return x.Verdict
+	}
+	return ""
+}
+
+func (x *Result) GetVerdictConfidence() float64 {
+	if x != nil {
+		return x.VerdictConfidence
+	}
+	return 0
+}
+
+// ListResultsRequest pages newest first using the id of the last result seen as the cursor
+type ListResultsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RuleId        uint64                 `protobuf:"varint,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Severity      string                 `protobuf:"bytes,3,opt,name=severity,proto3" json:"severity,omitempty"`
+	After         uint64                 `protobuf:"varint,4,opt,name=after,proto3" json:"after,omitempty"`
+	PageSize      int32                  `protobuf:"varint,5,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	Source        string                 `protobuf:"bytes,6,opt,name=source,proto3" json:"source,omitempty"`
+	Verdict       string                 `protobuf:"bytes,7,opt,name=verdict,proto3" json:"verdict,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResultsRequest) Reset() {
+	*x = ListResultsRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResultsRequest) ProtoMessage() {}
+
+func (x *ListResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResultsRequest.ProtoReflect.Descriptor instead.
+func (*ListResultsRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListResultsRequest) GetRuleId() uint64 {
+	if x != nil {
+		return x.RuleId
+	}
+	return 0
+}
+
+func (x *ListResultsRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ListResultsRequest) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *ListResultsRequest) GetAfter() uint64 {
+	if x != nil {
+		return x.After
+	}
+	return 0
+}
+
+func (x *ListResultsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListResultsRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *ListResultsRequest) GetVerdict() string {
+	if x != nil {
+		return x.Verdict
+	}
+	return ""
+}
+
+type ListResultsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*Result              `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	NextCursor    uint64                 `protobuf:"varint,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // 0 when there are no more results
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResultsResponse) Reset() {
+	*x = ListResultsResponse{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResultsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResultsResponse) ProtoMessage() {}
+
+func (x *ListResultsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResultsResponse.ProtoReflect.Descriptor instead.
+func (*ListResultsResponse) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListResultsResponse) GetResults() []*Result {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *ListResultsResponse) GetNextCursor() uint64 {
+	if x != nil {
+		return x.NextCursor
+	}
+	return 0
+}
+
+type UpdateResultStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []uint64               `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateResultStatusRequest) Reset() {
+	*x = UpdateResultStatusRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateResultStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateResultStatusRequest) ProtoMessage() {}
+
+func (x *UpdateResultStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateResultStatusRequest.ProtoReflect.Descriptor instead.
+func (*UpdateResultStatusRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *UpdateResultStatusRequest) GetIds() []uint64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *UpdateResultStatusRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type UpdateResultStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Updated       int64                  `protobuf:"varint,1,opt,name=updated,proto3" json:"updated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateResultStatusResponse) Reset() {
+	*x = UpdateResultStatusResponse{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateResultStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateResultStatusResponse) ProtoMessage() {}
+
+func (x *UpdateResultStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateResultStatusResponse.ProtoReflect.Descriptor instead.
+func (*UpdateResultStatusResponse) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *UpdateResultStatusResponse) GetUpdated() int64 {
+	if x != nil {
+		return x.Updated
+	}
+	return 0
+}
+
+type GetMonitorStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMonitorStatusRequest) Reset() {
+	*x = GetMonitorStatusRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMonitorStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMonitorStatusRequest) ProtoMessage() {}
+
+func (x *GetMonitorStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMonitorStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetMonitorStatusRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{13}
+}
+
+type StartMonitorRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartMonitorRequest) Reset() {
+	*x = StartMonitorRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartMonitorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartMonitorRequest) ProtoMessage() {}
+
+func (x *StartMonitorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartMonitorRequest.ProtoReflect.Descriptor instead.
+func (*StartMonitorRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{14}
+}
+
+type StopMonitorRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopMonitorRequest) Reset() {
+	*x = StopMonitorRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopMonitorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopMonitorRequest) ProtoMessage() {}
+
+func (x *StopMonitorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopMonitorRequest.ProtoReflect.Descriptor instead.
+func (*StopMonitorRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{15}
+}
+
+type MonitorStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IsRunning     bool                   `protobuf:"varint,1,opt,name=is_running,json=isRunning,proto3" json:"is_running,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MonitorStatus) Reset() {
+	*x = MonitorStatus{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MonitorStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MonitorStatus) ProtoMessage() {}
+
+func (x *MonitorStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MonitorStatus.ProtoReflect.Descriptor instead.
+func (*MonitorStatus) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *MonitorStatus) GetIsRunning() bool {
+	if x != nil {
+		return x.IsRunning
+	}
+	return false
+}
+
+type GetStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsRequest) Reset() {
+	*x = GetStatsRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsRequest) ProtoMessage() {}
+
+func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{17}
+}
+
+type Stats struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	TotalRules       int64                  `protobuf:"varint,1,opt,name=total_rules,json=totalRules,proto3" json:"total_rules,omitempty"`
+	ActiveRules      int64                  `protobuf:"varint,2,opt,name=active_rules,json=activeRules,proto3" json:"active_rules,omitempty"`
+	TotalResults     int64                  `protobuf:"varint,3,opt,name=total_results,json=totalResults,proto3" json:"total_results,omitempty"`
+	PendingResults   int64                  `protobuf:"varint,4,opt,name=pending_results,json=pendingResults,proto3" json:"pending_results,omitempty"`
+	ConfirmedResults int64                  `protobuf:"varint,5,opt,name=confirmed_results,json=confirmedResults,proto3" json:"confirmed_results,omitempty"`
+	TotalTokens      int64                  `protobuf:"varint,6,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	ActiveTokens     int64                  `protobuf:"varint,7,opt,name=active_tokens,json=activeTokens,proto3" json:"active_tokens,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Stats) Reset() {
+	*x = Stats{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stats) ProtoMessage() {}
+
+func (x *Stats) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stats.ProtoReflect.Descriptor instead.
+func (*Stats) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Stats) GetTotalRules() int64 {
+	if x != nil {
+		return x.TotalRules
+	}
+	return 0
+}
+
+func (x *Stats) GetActiveRules() int64 {
+	if x != nil {
+		return x.ActiveRules
+	}
+	return 0
+}
+
+func (x *Stats) GetTotalResults() int64 {
+	if x != nil {
+		return x.TotalResults
+	}
+	return 0
+}
+
+func (x *Stats) GetPendingResults() int64 {
+	if x != nil {
+		return x.PendingResults
+	}
+	return 0
+}
+
+func (x *Stats) GetConfirmedResults() int64 {
+	if x != nil {
+		return x.ConfirmedResults
+	}
+	return 0
+}
+
+func (x *Stats) GetTotalTokens() int64 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+func (x *Stats) GetActiveTokens() int64 {
+	if x != nil {
+		return x.ActiveTokens
+	}
+	return 0
+}
+
+// WatchEventsRequest optionally limits the stream to the given event types
+type WatchEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Types         []string               `protobuf:"bytes,1,rep,name=types,proto3" json:"types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *WatchEventsRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	DataJson      string                 `protobuf:"bytes,2,opt,name=data_json,json=dataJson,proto3" json:"data_json,omitempty"` // event payload, same JSON as the WebSocket feed
+	Time          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_monitor_v1_monitor_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_v1_monitor_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_monitor_v1_monitor_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetDataJson() string {
+	if x != nil {
+		return x.DataJson
+	}
+	return ""
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+var File_monitor_v1_monitor_proto protoreflect.FileDescriptor
+
+const file_monitor_v1_monitor_proto_rawDesc = "" +
+	"\n" +
+	"\x18monitor/v1/monitor.proto\x12\n" +
+	"monitor.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xfa\x02\n" +
+	"\x04Rule\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1a\n" +
+	"\bkeywords\x18\x04 \x03(\tR\bkeywords\x12\x1d\n" +
+	"\n" +
+	"match_type\x18\x05 \x01(\tR\tmatchType\x12\x1b\n" +
+	"\tis_active\x18\x06 \x01(\bR\bisActive\x12!\n" +
+	"\fexclude_exts\x18\a \x03(\tR\vexcludeExts\x12\x1a\n" +
+	"\bseverity\x18\b \x01(\tR\bseverity\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x1f\n" +
+	"\vtoken_group\x18\v \x01(\tR\n" +
+	"tokenGroup\"\x12\n" +
+	"\x10ListRulesRequest\";\n" +
+	"\x11ListRulesResponse\x12&\n" +
+	"\x05rules\x18\x01 \x03(\v2\x10.monitor.v1.RuleR\x05rules\" \n" +
+	"\x0eGetRuleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"9\n" +
+	"\x11CreateRuleRequest\x12$\n" +
+	"\x04rule\x18\x01 \x01(\v2\x10.monitor.v1.RuleR\x04rule\"9\n" +
+	"\x11UpdateRuleRequest\x12$\n" +
+	"\x04rule\x18\x01 \x01(\v2\x10.monitor.v1.RuleR\x04rule\"#\n" +
+	"\x11DeleteRuleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"\x14\n" +
+	"\x12DeleteRuleResponse\"\xd7\x04\n" +
+	"\x06Result\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x17\n" +
+	"\arule_id\x18\x02 \x01(\x04R\x06ruleId\x12\x1b\n" +
+	"\trule_name\x18\x03 \x01(\tR\bruleName\x12$\n" +
+	"\x0erepo_full_name\x18\x04 \x01(\tR\frepoFullName\x12\x19\n" +
+	"\brepo_url\x18\x05 \x01(\tR\arepoUrl\x12\x1b\n" +
+	"\tfile_path\x18\x06 \x01(\tR\bfilePath\x12\x19\n" +
+	"\bfile_url\x18\a \x01(\tR\afileUrl\x12\x19\n" +
+	"\bhtml_url\x18\b \x01(\tR\ahtmlUrl\x12)\n" +
+	"\x10matched_keywords\x18\t \x03(\tR\x0fmatchedKeywords\x12'\n" +
+	"\x0fcontent_snippet\x18\n" +
+	" \x01(\tR\x0econtentSnippet\x12\x14\n" +
+	"\x05score\x18\v \x01(\x01R\x05score\x12\x16\n" +
+	"\x06status\x18\f \x01(\tR\x06status\x12\x1a\n" +
+	"\bseverity\x18\r \x01(\tR\bseverity\x129\n" +
+	"\n" +
+	"created_at\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x16\n" +
+	"\x06source\x18\x10 \x01(\tR\x06source\x12\x18\n" +
+	"\averdict\x18\x11 \x01(\tR\averdict\x12-\n" +
+	"\x12verdict_confidence\x18\x12 \x01(\x01R\x11verdictConfidence\"\xc6\x01\n" +
+	"\x12ListResultsRequest\x12\x17\n" +
+	"\arule_id\x18\x01 \x01(\x04R\x06ruleId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1a\n" +
+	"\bseverity\x18\x03 \x01(\tR\bseverity\x12\x14\n" +
+	"\x05after\x18\x04 \x01(\x04R\x05after\x12\x1b\n" +
+	"\tpage_size\x18\x05 \x01(\x05R\bpageSize\x12\x16\n" +
+	"\x06source\x18\x06 \x01(\tR\x06source\x12\x18\n" +
+	"\averdict\x18\a \x01(\tR\averdict\"d\n" +
+	"\x13ListResultsResponse\x12,\n" +
+	"\aresults\x18\x01 \x03(\v2\x12.monitor.v1.ResultR\aresults\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\x04R\n" +
+	"nextCursor\"E\n" +
+	"\x19UpdateResultStatusRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\x04R\x03ids\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\"6\n" +
+	"\x1aUpdateResultStatusResponse\x12\x18\n" +
+	"\aupdated\x18\x01 \x01(\x03R\aupdated\"\x19\n" +
+	"\x17GetMonitorStatusRequest\"\x15\n" +
+	"\x13StartMonitorRequest\"\x14\n" +
+	"\x12StopMonitorRequest\".\n" +
+	"\rMonitorStatus\x12\x1d\n" +
+	"\n" +
+	"is_running\x18\x01 \x01(\bR\tisRunning\"\x11\n" +
+	"\x0fGetStatsRequest\"\x8e\x02\n" +
+	"\x05Stats\x12\x1f\n" +
+	"\vtotal_rules\x18\x01 \x01(\x03R\n" +
+	"totalRules\x12!\n" +
+	"\factive_rules\x18\x02 \x01(\x03R\vactiveRules\x12#\n" +
+	"\rtotal_results\x18\x03 \x01(\x03R\ftotalResults\x12'\n" +
+	"\x0fpending_results\x18\x04 \x01(\x03R\x0ependingResults\x12+\n" +
+	"\x11confirmed_results\x18\x05 \x01(\x03R\x10confirmedResults\x12!\n" +
+	"\ftotal_tokens\x18\x06 \x01(\x03R\vtotalTokens\x12#\n" +
+	"\ractive_tokens\x18\a \x01(\x03R\factiveTokens\"*\n" +
+	"\x12WatchEventsRequest\x12\x14\n" +
+	"\x05types\x18\x01 \x03(\tR\x05types\"h\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x1b\n" +
+	"\tdata_json\x18\x02 \x01(\tR\bdataJson\x12.\n" +
+	"\x04time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04time2\xfd\x06\n" +
+	"\x0eMonitorService\x12H\n" +
+	"\tListRules\x12\x1c.monitor.v1.ListRulesRequest\x1a\x1d.monitor.v1.ListRulesResponse\x127\n" +
+	"\aGetRule\x12\x1a.monitor.v1.GetRuleRequest\x1a\x10.monitor.v1.Rule\x12=\n" +
+	"\n" +
+	"CreateRule\x12\x1d.monitor.v1.CreateRuleRequest\x1a\x10.monitor.v1.Rule\x12=\n" +
+	"\n" +
+	"UpdateRule\x12\x1d.monitor.v1.UpdateRuleRequest\x1a\x10.monitor.v1.Rule\x12K\n" +
+	"\n" +
+	"DeleteRule\x12\x1d.monitor.v1.DeleteRuleRequest\x1a\x1e.monitor.v1.DeleteRuleResponse\x12N\n" +
+	"\vListResults\x12\x1e.monitor.v1.ListResultsRequest\x1a\x1f.monitor.v1.ListResultsResponse\x12c\n" +
+	"\x12UpdateResultStatus\x12%.monitor.v1.UpdateResultStatusRequest\x1a&.monitor.v1.UpdateResultStatusResponse\x12R\n" +
+	"\x10GetMonitorStatus\x12#.monitor.v1.GetMonitorStatusRequest\x1a\x19.monitor.v1.MonitorStatus\x12J\n" +
+	"\fStartMonitor\x12\x1f.monitor.v1.StartMonitorRequest\x1a\x19.monitor.v1.MonitorStatus\x12H\n" +
+	"\vStopMonitor\x12\x1e.monitor.v1.StopMonitorRequest\x1a\x19.monitor.v1.MonitorStatus\x12:\n" +
+	"\bGetStats\x12\x1b.monitor.v1.GetStatsRequest\x1a\x11.monitor.v1.Stats\x12B\n" +
+	"\vWatchEvents\x12\x1e.monitor.v1.WatchEventsRequest\x1a\x11.monitor.v1.Event0\x01B,Z*github-monitor/grpcapi/monitorpb;monitorpbb\x06proto3"
+
+var (
+	file_monitor_v1_monitor_proto_rawDescOnce sync.Once
+	file_monitor_v1_monitor_proto_rawDescData []byte
+)
+
+func file_monitor_v1_monitor_proto_rawDescGZIP() []byte {
+	file_monitor_v1_monitor_proto_rawDescOnce.Do(func() {
+		file_monitor_v1_monitor_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_monitor_v1_monitor_proto_rawDesc), len(file_monitor_v1_monitor_proto_rawDesc)))
+	})
+	return file_monitor_v1_monitor_proto_rawDescData
+}
+
+var file_monitor_v1_monitor_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_monitor_v1_monitor_proto_goTypes = []any{
+	(*Rule)(nil),                       // 0: monitor.v1.Rule
+	(*ListRulesRequest)(nil),           // 1: monitor.v1.ListRulesRequest
+	(*ListRulesResponse)(nil),          // 2: monitor.v1.ListRulesResponse
+	(*GetRuleRequest)(nil),             // 3: monitor.v1.GetRuleRequest
+	(*CreateRuleRequest)(nil),          // 4: monitor.v1.CreateRuleRequest
+	(*UpdateRuleRequest)(nil),          // 5: monitor.v1.UpdateRuleRequest
+	(*DeleteRuleRequest)(nil),          // 6: monitor.v1.DeleteRuleRequest
+	(*DeleteRuleResponse)(nil),         // 7: monitor.v1.DeleteRuleResponse
+	(*Result)(nil),                     // 8: monitor.v1.Result
+	(*ListResultsRequest)(nil),         // 9: monitor.v1.ListResultsRequest
+	(*ListResultsResponse)(nil),        // 10: monitor.v1.ListResultsResponse
+	(*UpdateResultStatusRequest)(nil),  // 11: monitor.v1.UpdateResultStatusRequest
+	(*UpdateResultStatusResponse)(nil), // 12: monitor.v1.UpdateResultStatusResponse
+	(*GetMonitorStatusRequest)(nil),    // 13: monitor.v1.GetMonitorStatusRequest
+	(*StartMonitorRequest)(nil),        // 14: monitor.v1.StartMonitorRequest
+	(*StopMonitorRequest)(nil),         // 15: monitor.v1.StopMonitorRequest
+	(*MonitorStatus)(nil),              // 16: monitor.v1.MonitorStatus
+	(*GetStatsRequest)(nil),            // 17: monitor.v1.GetStatsRequest
+	(*Stats)(nil),                      // 18: monitor.v1.Stats
+	(*WatchEventsRequest)(nil),         // 19: monitor.v1.WatchEventsRequest
+	(*Event)(nil),                      // 20: monitor.v1.Event
+	(*timestamppb.Timestamp)(nil),      // 21: google.protobuf.Timestamp
+}
+var file_monitor_v1_monitor_proto_depIdxs = []int32{
+	21, // 0: monitor.v1.Rule.created_at:type_name -> google.protobuf.Timestamp
+	21, // 1: monitor.v1.Rule.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: monitor.v1.ListRulesResponse.rules:type_name -> monitor.v1.Rule
+	0,  // 3: monitor.v1.CreateRuleRequest.rule:type_name -> monitor.v1.Rule
+	0,  // 4: monitor.v1.UpdateRuleRequest.rule:type_name -> monitor.v1.Rule
+	21, // 5: monitor.v1.Result.created_at:type_name -> google.protobuf.Timestamp
+	21, // 6: monitor.v1.Result.updated_at:type_name -> google.protobuf.Timestamp
+	8,  // 7: monitor.v1.ListResultsResponse.results:type_name -> monitor.v1.Result
+	21, // 8: monitor.v1.Event.time:type_name -> google.protobuf.Timestamp
+	1,  // 9: monitor.v1.MonitorService.ListRules:input_type -> monitor.v1.ListRulesRequest
+	3,  // 10: monitor.v1.MonitorService.GetRule:input_type -> monitor.v1.GetRuleRequest
+	4,  // 11: monitor.v1.MonitorService.CreateRule:input_type -> monitor.v1.CreateRuleRequest
+	5,  // 12: monitor.v1.MonitorService.UpdateRule:input_type -> monitor.v1.UpdateRuleRequest
+	6,  // 13: monitor.v1.MonitorService.DeleteRule:input_type -> monitor.v1.DeleteRuleRequest
+	9,  // 14: monitor.v1.MonitorService.ListResults:input_type -> monitor.v1.ListResultsRequest
+	11, // 15: monitor.v1.MonitorService.UpdateResultStatus:input_type -> monitor.v1.UpdateResultStatusRequest
+	13, // 16: monitor.v1.MonitorService.GetMonitorStatus:input_type -> monitor.v1.GetMonitorStatusRequest
+	14, // 17: monitor.v1.MonitorService.StartMonitor:input_type -> monitor.v1.StartMonitorRequest
+	15, // 18: monitor.v1.MonitorService.StopMonitor:input_type -> monitor.v1.StopMonitorRequest
+	17, // 19: monitor.v1.MonitorService.GetStats:input_type -> monitor.v1.GetStatsRequest
+	19, // 20: monitor.v1.MonitorService.WatchEvents:input_type -> monitor.v1.WatchEventsRequest
+	2,  // 21: monitor.v1.MonitorService.ListRules:output_type -> monitor.v1.ListRulesResponse
+	0,  // 22: monitor.v1.MonitorService.GetRule:output_type -> monitor.v1.Rule
+	0,  // 23: monitor.v1.MonitorService.CreateRule:output_type -> monitor.v1.Rule
+	0,  // 24: monitor.v1.MonitorService.UpdateRule:output_type -> monitor.v1.Rule
+	7,  // 25: monitor.v1.MonitorService.DeleteRule:output_type -> monitor.v1.DeleteRuleResponse
+	10, // 26: monitor.v1.MonitorService.ListResults:output_type -> monitor.v1.ListResultsResponse
+	12, // 27: monitor.v1.MonitorService.UpdateResultStatus:output_type -> monitor.v1.UpdateResultStatusResponse
+	16, // 28: monitor.v1.MonitorService.GetMonitorStatus:output_type -> monitor.v1.MonitorStatus
+	16, // 29: monitor.v1.MonitorService.StartMonitor:output_type -> monitor.v1.MonitorStatus
+	16, // 30: monitor.v1.MonitorService.StopMonitor:output_type -> monitor.v1.MonitorStatus
+	18, // 31: monitor.v1.MonitorService.GetStats:output_type -> monitor.v1.Stats
+	20, // 32: monitor.v1.MonitorService.WatchEvents:output_type -> monitor.v1.Event
+	21, // [21:33] is the sub-list for method output_type
+	9,  // [9:21] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_monitor_v1_monitor_proto_init() }
+func file_monitor_v1_monitor_proto_init() {
+	if File_monitor_v1_monitor_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_monitor_v1_monitor_proto_rawDesc), len(file_monitor_v1_monitor_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   21,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_monitor_v1_monitor_proto_goTypes,
+		DependencyIndexes: file_monitor_v1_monitor_proto_depIdxs,
+		MessageInfos:      file_monitor_v1_monitor_proto_msgTypes,
+	}.Build()
+	File_monitor_v1_monitor_proto = out.File
+	file_monitor_v1_monitor_proto_goTypes = nil
+	file_monitor_v1_monitor_proto_depIdxs = nil
+}
@@ -0,0 +1,295 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/events"
+	"github-monitor/grpcapi/monitorpb"
+	"github-monitor/monitor"
+	"github-monitor/redact"
+	"github-monitor/repository"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 500
+)
+
+// Server implements the gRPC MonitorService on top of the same database and services as the REST API
+type Server struct {
+	monitorpb.UnimplementedMonitorServiceServer
+	repos          *repository.Repositories
+	monitorService *monitor.MonitorService
+}
+
+// NewServer creates a gRPC server with authentication and the monitor service registered.
+// creds may be nil to serve without TLS.
+func NewServer(repos *repository.Repositories, monitorService *monitor.MonitorService, creds credentials.TransportCredentials) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(unaryInterceptor(repos)),
+		grpc.StreamInterceptor(streamInterceptor(repos)),
+	}
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	srv := grpc.NewServer(opts...)
+	monitorpb.RegisterMonitorServiceServer(srv, &Server{repos: repos, monitorService: monitorService})
+	return srv
+}
+
+// dbError maps a database error to a gRPC status
+func dbError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "record not found")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return status.Error(codes.AlreadyExists, "a record with the same unique value already exists")
+	default:
+		log.Printf("gRPC database error: %v", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+// validateRule checks the fields the REST API validates for rules
+func validateRule(rule *models.MonitorRule) error {
+	if rule.Name == "" {
+		return status.Error(codes.InvalidArgument, "name is required")
+	}
+	if rule.Keywords == "" {
+		return status.Error(codes.InvalidArgument, "keywords is required")
+	}
+	if !models.ValidSeverities[rule.Severity] {
+		return status.Error(codes.InvalidArgument, "severity must be one of: critical high medium low info")
+	}
+	if rule.TokenGroup != "" && !config.HasTokenGroup(rule.TokenGroup) {
+		return status.Error(codes.InvalidArgument, "token_group must be one of github.token_groups")
+	}
+	return nil
+}
+
+// ListRules returns all monitor rules
+func (s *Server) ListRules(ctx context.Context, req *monitorpb.ListRulesRequest) (*monitorpb.ListRulesResponse, error) {
+	rules, err := s.repos.Rules.List(ctx)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	resp := &monitorpb.ListRulesResponse{Rules: make([]*monitorpb.Rule, 0, len(rules))}
+	for i := range rules {
+		resp.Rules = append(resp.Rules, ruleToProto(&rules[i]))
+	}
+	return resp, nil
+}
+
+// GetRule returns a single monitor rule
+func (s *Server) GetRule(ctx context.Context, req *monitorpb.GetRuleRequest) (*monitorpb.Rule, error) {
+	rule, err := s.repos.Rules.Get(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, dbError(err)
+	}
+	return ruleToProto(rule), nil
+}
+
+// CreateRule creates a new monitor rule
+func (s *Server) CreateRule(ctx context.Context, req *monitorpb.CreateRuleRequest) (*monitorpb.Rule, error) {
+	if req.GetRule() == nil {
+		return nil, status.Error(codes.InvalidArgument, "rule is required")
+	}
+
+	var rule models.MonitorRule
+	applyRule(&rule, req.GetRule())
+	if rule.MatchType == "" {
+		rule.MatchType = "fuzzy"
+	}
+	if rule.Severity == "" {
+		rule.Severity = "medium"
+	}
+	if err := validateRule(&rule); err != nil {
+		return nil, err
+	}
+	rule.ProjectID = projectFromContext(ctx).ProjectID
+
+	if err := s.repos.Rules.Create(ctx, &rule); err != nil {
+		return nil, dbError(err)
+	}
+	return ruleToProto(&rule), nil
+}
+
+// UpdateRule replaces the writable fields of a monitor rule
+func (s *Server) UpdateRule(ctx context.Context, req *monitorpb.UpdateRuleRequest) (*monitorpb.Rule, error) {
+	if req.GetRule() == nil {
+		return nil, status.Error(codes.InvalidArgument, "rule is required")
+	}
+
+	rule, err := s.repos.Rules.Get(ctx, uint(req.GetRule().GetId()))
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	applyRule(rule, req.GetRule())
+	if err := validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repos.Rules.Save(ctx, rule); err != nil {
+		return nil, dbError(err)
+	}
+	return ruleToProto(rule), nil
+}
+
+// DeleteRule deletes a monitor rule
+func (s *Server) DeleteRule(ctx context.Context, req *monitorpb.DeleteRuleRequest) (*monitorpb.DeleteRuleResponse, error) {
+	if err := s.repos.Rules.Delete(ctx, uint(req.GetId())); err != nil {
+		return nil, dbError(err)
+	}
+	return &monitorpb.DeleteRuleResponse{}, nil
+}
+
+// ListResults returns search results newest first using keyset pagination
+func (s *Server) ListResults(ctx context.Context, req *monitorpb.ListResultsRequest) (*monitorpb.ListResultsResponse, error) {
+	pageSize := int(req.GetPageSize())
+	if pageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	}
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	filter := repository.ResultFilter{
+		RuleID:   uint(req.GetRuleId()),
+		Status:   req.GetStatus(),
+		Severity: req.GetSeverity(),
+		Source:   req.GetSource(),
+		Verdict:  req.GetVerdict(),
+	}
+
+	results, err := s.repos.Results.ListAfter(ctx, filter, req.GetAfter(), pageSize)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	resp := &monitorpb.ListResultsResponse{Results: make([]*monitorpb.Result, 0, len(results))}
+	for i := range results {
+		if config.AppConfig.Redaction.Enabled {
+			results[i].ContentSnippet = redact.Secrets(results[i].ContentSnippet)
+		}
+		resp.Results = append(resp.Results, resultToProto(&results[i]))
+	}
+	if len(results) == pageSize {
+		resp.NextCursor = uint64(results[len(results)-1].ID)
+	}
+	return resp, nil
+}
+
+// UpdateResultStatus sets the triage status of one or more results
+func (s *Server) UpdateResultStatus(ctx context.Context, req *monitorpb.UpdateResultStatusRequest) (*monitorpb.UpdateResultStatusResponse, error) {
+	if len(req.GetIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "ids is required")
+	}
+	if !models.ValidResultStatuses[req.GetStatus()] {
+		return nil, status.Error(codes.InvalidArgument, "status must be one of: pending confirmed false_positive resolved")
+	}
+
+	ids := make([]uint, 0, len(req.GetIds()))
+	for _, id := range req.GetIds() {
+		ids = append(ids, uint(id))
+	}
+
+	updated, err := s.repos.Results.UpdateStatus(ctx, ids, req.GetStatus())
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	events.PublishTo(projectFromContext(ctx).ProjectID, events.TypeResultStatus, events.StatusChange{IDs: ids, Status: req.GetStatus()})
+
+	return &monitorpb.UpdateResultStatusResponse{Updated: updated}, nil
+}
+
+// GetMonitorStatus reports whether the monitor is running
+func (s *Server) GetMonitorStatus(ctx context.Context, req *monitorpb.GetMonitorStatusRequest) (*monitorpb.MonitorStatus, error) {
+	return &monitorpb.MonitorStatus{IsRunning: s.monitorService.IsRunning()}, nil
+}
+
+// StartMonitor starts the monitor service, a running monitor is left as it is
+func (s *Server) StartMonitor(ctx context.Context, req *monitorpb.StartMonitorRequest) (*monitorpb.MonitorStatus, error) {
+	s.monitorService.Start()
+	return &monitorpb.MonitorStatus{IsRunning: true}, nil
+}
+
+// StopMonitor stops the monitor service, a stopped monitor is left as it is
+func (s *Server) StopMonitor(ctx context.Context, req *monitorpb.StopMonitorRequest) (*monitorpb.MonitorStatus, error) {
+	s.monitorService.Stop()
+	return &monitorpb.MonitorStatus{IsRunning: false}, nil
+}
+
+// GetStats returns the dashboard statistics
+func (s *Server) GetStats(ctx context.Context, req *monitorpb.GetStatsRequest) (*monitorpb.Stats, error) {
+	stats := &monitorpb.Stats{}
+	stats.TotalRules, _ = s.repos.Rules.Count(ctx, false)
+	stats.ActiveRules, _ = s.repos.Rules.Count(ctx, true)
+	stats.TotalResults, _ = s.repos.Results.Count(ctx, repository.ResultFilter{})
+	stats.PendingResults, _ = s.repos.Results.Count(ctx, repository.ResultFilter{Status: "pending"})
+	stats.ConfirmedResults, _ = s.repos.Results.Count(ctx, repository.ResultFilter{Status: "confirmed"})
+	stats.TotalTokens, _ = s.repos.Tokens.Count(ctx, false)
+	stats.ActiveTokens, _ = s.repos.Tokens.Count(ctx, true)
+
+	return stats, nil
+}
+
+// WatchEvents streams monitor events to the client until it disconnects
+func (s *Server) WatchEvents(req *monitorpb.WatchEventsRequest, stream monitorpb.MonitorService_WatchEventsServer) error {
+	wanted := make(map[string]bool, len(req.GetTypes()))
+	for _, t := range req.GetTypes() {
+		wanted[t] = true
+	}
+
+	access := projectFromContext(stream.Context())
+	ch, unsubscribe := events.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if len(wanted) > 0 && !wanted[event.Type] {
+				continue
+			}
+			if !access.Covers(event.ProjectID) {
+				continue
+			}
+
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				continue
+			}
+
+			if err := stream.Send(&monitorpb.Event{
+				Type:     event.Type,
+				DataJson: string(data),
+				Time:     timestamppb.New(event.Time),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package honeytoken generates canary credentials. They look real but grant
+// nothing, and carry a random marker that code search can find, so a result of
+// their rule means the place they were planted in leaked.
+package honeytoken
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github-monitor/db/models"
+)
+
+// Kinds of canary credentials
+const (
+	KindAWSKey      = "aws_key"      // access key ID and secret in the AWS credentials file format
+	KindDatabaseDSN = "database_dsn" // PostgreSQL connection string
+	KindAPIKey      = "api_key"      // opaque bearer token
+)
+
+// ValidKinds lists the kinds Generate supports
+var ValidKinds = map[string]bool{
+	KindAWSKey:      true,
+	KindDatabaseDSN: true,
+	KindAPIKey:      true,
+}
+
+// defaultHost is the database host of DSNs generated without one
+const defaultHost = "db.internal"
+
+const (
+	upperBase32  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	secretChars  = alphanumeric + "+/"
+)
+
+// Generate creates a canary credential of the given kind and returns it with its
+// marker. Markers are alphanumeric so code search indexes them as a single token.
+// host is the database host of DSNs, empty for a generic internal name.
+func Generate(kind, host string) (value, marker string, err error) {
+	switch kind {
+	case KindAWSKey:
+		id, err := random(upperBase32, 16)
+		if err != nil {
+			return "", "", err
+		}
+		secret, err := random(secretChars, 40)
+		if err != nil {
+			return "", "", err
+		}
+		marker = "AKIA" + id
+		value = fmt.Sprintf("[default]\naws_access_key_id = %s\naws_secret_access_key = %s\n", marker, secret)
+		return value, marker, nil
+
+	case KindDatabaseDSN:
+		if host == "" {
+			host = defaultHost
+		}
+		user, err := random("abcdefghijklmnopqrstuvwxyz0123456789", 6)
+		if err != nil {
+			return "", "", err
+		}
+		marker, err = random(alphanumeric, 24)
+		if err != nil {
+			return "", "", err
+		}
+		value = fmt.Sprintf("postgres://svc_%s:%s@%s:5432/production?sslmode=require", user, marker, host)
+		return value, marker, nil
+
+	case KindAPIKey:
+		token, err := random(alphanumeric, 40)
+		if err != nil {
+			return "", "", err
+		}
+		return "sk_live_" + token, token, nil
+
+	default:
+		return "", "", fmt.Errorf("unknown honeytoken kind %q", kind)
+	}
+}
+
+// Rule returns the critical rule that searches for a honeytoken. It stays with
+// the token, deleting the honeytoken deletes it as well.
+func Rule(token *models.Honeytoken) (*models.MonitorRule, error) {
+	keywords, err := json.Marshal([]string{token.Marker})
+	if err != nil {
+		return nil, err
+	}
+	return &models.MonitorRule{
+		Name:        "Honeytoken: " + token.Name,
+		Description: fmt.Sprintf("Canary %s credential, any result means it leaked. Managed with the honeytoken.", token.Kind),
+		Keywords:    string(keywords),
+		MatchType:   "precise",
+		IsActive:    true,
+		Severity:    "critical",
+		ProjectID:   token.ProjectID,
+	}, nil
+}
+
+func random(alphabet string, length int) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	out := make([]byte, length)
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out[i] = alphabet[n.Int64()]
+	}
+	return string(out), nil
+}
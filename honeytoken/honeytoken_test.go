@@ -0,0 +1,30 @@
+package honeytoken
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	alphanumeric := regexp.MustCompile(`^[A-Za-z0-9]+$`)
+	for kind := range ValidKinds {
+		value, marker, err := Generate(kind, "pg.acme.internal")
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if !strings.Contains(value, marker) || !alphanumeric.MatchString(marker) || len(marker) < 20 {
+			t.Errorf("%s: marker %q doesn't identify %q", kind, marker, value)
+		}
+		if _, again, _ := Generate(kind, ""); again == marker {
+			t.Errorf("%s: generated the marker %q twice", kind, marker)
+		}
+	}
+
+	if value, _, _ := Generate(KindDatabaseDSN, "pg.acme.internal"); !strings.Contains(value, "@pg.acme.internal:5432/") {
+		t.Errorf("DSN %q doesn't use the host", value)
+	}
+	if _, _, err := Generate("ssh_key", ""); err == nil {
+		t.Error("generated an unknown kind")
+	}
+}
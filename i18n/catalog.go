@@ -0,0 +1,115 @@
+package i18n
+
+// catalog maps the English texts to their translations, by locale
+var catalog = map[string]map[string]string{
+	Chinese: {
+		// Notification templates
+		"View details":    "查看详情",
+		"...and %d more":  "……另有 %d 条",
+		"Translation: %s": "译文：%s",
+		"Confirm":         "确认泄露",
+		"False positive":  "误报",
+		"Whitelist repo":  "加白仓库",
+
+		// Notifications
+		"GitHub leak alert: %s":                       "GitHub 泄露告警：%s",
+		"Rule **%s** found %d new potential leaks:\n": "规则 **%s** 发现 %d 条新的疑似泄露：\n",
+
+		"GitHub leak reminder: %s": "GitHub 泄露提醒：%s",
+		"The snooze of %d results of rule **%s** ended, they are back in the pending queue:\n": "规则 **%[2]s** 的 %[1]d 条结果暂缓期已结束，已回到待处理队列：\n",
+
+		"GitHub leak changed: %s": "GitHub 泄露内容变更：%s",
+		"The files of %d closed results of rule **%s** changed, they are back in the pending queue:\n": "规则 **%[2]s** 的 %[1]d 条已关闭结果的文件发生变化，已回到待处理队列：\n",
+
+		"Rule scan failed: %s":               "规则扫描失败：%s",
+		"The scan of rule **%s** failed: %s": "规则 **%s** 扫描失败：%s",
+
+		"Rule needs review: %s": "规则需要复核：%s",
+
+		"A scan of rule **%s** found %d new results, more than the limit of %d. None of them were recorded.": "规则 **%s** 的一次扫描发现 %d 条新结果，超过上限 %d，均未记录。",
+
+		"A scan of rule **%s** found %d new results, more than the limit of %d. None of them were recorded. The rule was deactivated, narrow its keywords or raise its max_new_results, then re-activate it.": "规则 **%s** 的一次扫描发现 %d 条新结果，超过上限 %d，均未记录。规则已停用，请收窄关键词或调高 max_new_results 后重新启用。",
+
+		"Rule paused: %s": "规则已暂停：%s",
+		"Rule **%s** was deactivated because %s. Re-activate it once it is fixed or still needed.": "规则 **%s** 已停用，原因：%s。修复后或仍需要时请重新启用。",
+
+		"Honeytoken triggered: %s": "蜜罐凭据被触发：%s",
+		"The canary %s credential **%s** was found in %d places. It was only planted to detect leaks, so the place it was planted in (%s) has leaked:\n": "诱饵 %s 凭据 **%s** 在 %d 处被发现。它仅用于检测泄露，说明其投放位置（%s）已经泄露：\n",
+
+		"GitHub tokens running out": "GitHub 令牌即将耗尽",
+		"At the current pace of %.0f API calls a minute, the GitHub tokens run out in %d minutes, before their limit resets at %s. Raising the scan interval from %s to %s makes the %d calls left last until then.\n": "按当前每分钟 %.0f 次 API 调用的速度，GitHub 令牌将在 %d 分钟后耗尽，早于 %s 的额度重置。将扫描间隔从 %s 调高到 %s，剩余的 %d 次调用即可维持到重置。\n",
+
+		"GitHub monitor stalled": "GitHub 监控已停滞",
+		"The monitoring loop has not reported a heartbeat since %s, over two scan intervals of %s. No new leaks are found until it is restarted.\n": "监控循环自 %s 起未上报心跳，已超过两个扫描间隔（%s）。重启之前不会发现新的泄露。\n",
+
+		"GitHub monitor recovered":                                "GitHub 监控已恢复",
+		"The monitoring loop reported a heartbeat again at %s.\n": "监控循环已于 %s 重新上报心跳。\n",
+
+		"GitHub leak SLA breached": "GitHub 泄露处置超出 SLA",
+		"%d results missed their SLA. Open results now at risk: %d, overdue: %d\n": "%d 条结果超出 SLA。当前面临风险的未关闭结果：%d，已逾期：%d\n",
+
+		// API errors
+		"Validation failed":     "校验失败",
+		"Internal server error": "服务器内部错误",
+		"Record not found":      "记录不存在",
+		"A record with the same unique value already exists": "已存在唯一值相同的记录",
+		"Malformed JSON body":                                "JSON 请求体格式错误",
+		"Request body is larger than %d bytes":               "请求体超过 %d 字节",
+		"Invalid request body":                               "请求体无效",
+		"Unreadable request body":                            "无法读取请求体",
+		"is required":                                        "不能为空",
+		"must be at least %s":                                "不能小于 %s",
+		"must be at most %s":                                 "不能大于 %s",
+		"must be one of: %s":                                 "必须是以下之一：%s",
+		"must be a valid URL":                                "必须是有效的 URL",
+		"must be a valid email address":                      "必须是有效的邮箱地址",
+		"failed the %q check":                                "未通过 %q 校验",
+		"must be of type %s":                                 "必须是 %s 类型",
+		"must not be negative":                               "不能为负数",
+		"must be one of github.token_groups":                 "必须是 github.token_groups 中的一项",
+		"must be a JSON array of strings":                    "必须是字符串 JSON 数组",
+		"must be legacy or code_search":                      "必须是 legacy 或 code_search",
+		"must be en or zh":                                   "必须是 en 或 zh",
+		"Invalid ID":                                         "ID 无效",
+		"Invalid revision ID":                                "修订 ID 无效",
+		"No IDs provided":                                    "未提供 ID",
+		"No tokens provided":                                 "未提供令牌",
+		"No project selected":                                "未选择项目",
+		"Result not found":                                   "结果不存在",
+		"Rule not found":                                     "规则不存在",
+		"Project not found":                                  "项目不存在",
+		"View not found":                                     "视图不存在",
+		"Report not found":                                   "报告不存在",
+		"Notification not found":                             "通知渠道不存在",
+		"Template not found":                                 "模板不存在",
+		"Webhook not found":                                  "Webhook 不存在",
+		"Session not found":                                  "会话不存在",
+		"Revision not found":                                 "修订不存在",
+		"Share link not found":                               "分享链接不存在",
+		"Incident not found":                                 "事件不存在",
+		"Honeytoken not found":                               "蜜罐凭据不存在",
+		"Token not found":                                    "令牌不存在",
+		"Delivery not found":                                 "投递记录不存在",
+		"API endpoint not found":                             "API 接口不存在",
+		"No access to this project":                          "无权访问该项目",
+		"Insufficient permissions":                           "权限不足",
+		"Insufficient permissions in this project":           "在该项目中权限不足",
+		"Authentication required":                            "需要登录",
+		"Authorization header required":                      "缺少 Authorization 请求头",
+		"Invalid authorization header format":                "Authorization 请求头格式无效",
+		"Invalid or expired token":                           "令牌无效或已过期",
+		"Invalid password":                                   "密码错误",
+		"Password is required":                               "请输入密码",
+		"Refresh token is required":                          "缺少刷新令牌",
+		"Session has been revoked":                           "会话已被撤销",
+		"Invalid API key":                                    "API 密钥无效",
+		"Scraping is not enabled":                            "未启用监控抓取",
+		"Admin endpoints can't be reached from this address": "无法从该地址访问管理接口",
+		"SSO login is not enabled":                           "未启用 SSO 登录",
+		"SSO login failed":                                   "SSO 登录失败",
+		"Invalid SSO state":                                  "SSO 状态无效",
+		"The default project can't be deleted":               "默认项目不能删除",
+		"The project still has rules, delete them first":     "该项目仍有规则，请先删除",
+		"Only failed deliveries can be retried":              "只能重试失败的投递",
+	},
+}
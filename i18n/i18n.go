@@ -0,0 +1,84 @@
+// Package i18n renders the texts of notifications and API errors in English or
+// Chinese. Texts are written in English in the code and looked up in the catalog
+// of the locale by that English text, so untranslated ones stay English.
+package i18n
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Supported locales
+const (
+	English = "en"
+	Chinese = "zh"
+)
+
+var defaultLocale atomic.Value
+
+func init() {
+	defaultLocale.Store(English)
+}
+
+// Valid reports whether a locale is supported, or empty for the default locale
+func Valid(locale string) bool {
+	return locale == "" || locale == English || locale == Chinese
+}
+
+// SetDefault sets the locale used where none is chosen, unsupported ones are ignored
+func SetDefault(locale string) {
+	if locale != "" && Valid(locale) {
+		defaultLocale.Store(locale)
+	}
+}
+
+// Default returns the locale used where none is chosen
+func Default() string {
+	return defaultLocale.Load().(string)
+}
+
+// Translate returns text in a locale, empty for the default one. Texts without a
+// translation are returned as they are.
+func Translate(locale, text string) string {
+	if locale == "" {
+		locale = Default()
+	}
+	if translated, ok := catalog[locale][text]; ok {
+		return translated
+	}
+	return text
+}
+
+// Sprintf formats the translation of format in a locale, empty for the default one
+func Sprintf(locale, format string, args ...interface{}) string {
+	rendered := make([]interface{}, len(args))
+	for i, arg := range args {
+		if text, ok := arg.(Text); ok {
+			arg = text.In(locale)
+		}
+		rendered[i] = arg
+	}
+	return fmt.Sprintf(Translate(locale, format), rendered...)
+}
+
+// Text is a text formatted only once the locale it is shown in is known, such as
+// a notification sent to channels of different locales
+type Text struct {
+	Format string
+	Args   []interface{} // a Text among them is rendered in the same locale
+}
+
+// Format returns a Text formatted in the locale it is rendered in
+func Format(format string, args ...interface{}) Text {
+	return Text{Format: format, Args: args}
+}
+
+// In renders the text in a locale, empty for the default one
+func (t Text) In(locale string) string {
+	return Sprintf(locale, t.Format, t.Args...)
+}
+
+// IsZero reports whether the text is unset
+func (t Text) IsZero() bool {
+	return t.Format == ""
+}
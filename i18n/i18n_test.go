@@ -0,0 +1,57 @@
+package i18n
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	defer SetDefault(English)
+
+	if got := Translate("", "Rule not found"); got != "Rule not found" {
+		t.Errorf("Translate() = %q in English", got)
+	}
+	SetDefault(Chinese)
+	if got := Translate("", "Rule not found"); got != "规则不存在" {
+		t.Errorf("Translate() = %q in the default locale zh", got)
+	}
+	if got := Translate(English, "Rule not found"); got != "Rule not found" {
+		t.Errorf("Translate() = %q, want the locale given to win", got)
+	}
+	if got := Translate(Chinese, "Something new"); got != "Something new" {
+		t.Errorf("Translate() = %q, want untranslated texts as they are", got)
+	}
+
+	// Arguments are taken by position where the translation reorders them
+	text := Format("The snooze of %d results of rule **%s** ended, they are back in the pending queue:\n", 3, "aws")
+	if got := text.In(Chinese); !strings.HasPrefix(got, "规则 **aws** 的 3 条结果") {
+		t.Errorf("In(zh) = %q", got)
+	}
+	if got := Sprintf(Chinese, "Rule paused: %s", Format("Rule not found")); got != "规则已暂停：规则不存在" {
+		t.Errorf("Sprintf() = %q, want nested texts in the same locale", got)
+	}
+}
+
+var verbPattern = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*[\d.]*[a-zA-Z]`)
+
+func TestCatalogKeepsVerbs(t *testing.T) {
+	for locale, texts := range catalog {
+		for english := range texts {
+			var args []interface{}
+			for _, verb := range verbPattern.FindAllString(english, -1) {
+				switch verb[len(verb)-1] {
+				case 'd':
+					args = append(args, 1)
+				case 'f':
+					args = append(args, 1.0)
+				default:
+					args = append(args, "x")
+				}
+			}
+			if got := Sprintf(locale, english, args...); strings.Contains(got, "%!") {
+				t.Errorf("%s translation of %q doesn't take its arguments: %q", locale, english, got)
+			}
+		}
+	}
+}
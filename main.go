@@ -2,52 +2,124 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github-monitor/api"
+	"github-monitor/auth"
+	"github-monitor/cache"
+	"github-monitor/certreload"
+	"github-monitor/classifier"
+	"github-monitor/cluster"
 	"github-monitor/config"
 	"github-monitor/db"
+	"github-monitor/defectdojo"
+	"github-monitor/dockerhub"
+	"github-monitor/elastic"
 	"github-monitor/github"
+	"github-monitor/grpcapi"
+	"github-monitor/i18n"
 	"github-monitor/monitor"
+	"github-monitor/notify"
+	"github-monitor/postman"
+	"github-monitor/purge"
+	"github-monitor/registry"
+	"github-monitor/report"
+	"github-monitor/reporting"
+	"github-monitor/repository"
+	"github-monitor/rulesync"
+	"github-monitor/settings"
+	"github-monitor/sla"
+	"github-monitor/storage"
+	"github-monitor/webhooks"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// serve runs the HTTP API, the gRPC API and the monitor until a termination signal arrives
+func serve(configPath string) {
 	// Load configuration
-	if err := config.LoadConfig("config.yaml"); err != nil {
+	if err := config.LoadConfig(configPath); err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	if config.AppConfig.Sentry.Enabled {
+		if err := reporting.Init(&config.AppConfig.Sentry); err != nil {
+			log.Fatalf("Failed to initialize Sentry: %v", err)
+		}
+	}
+
 	// Initialize database
 	if err := db.InitDB(&config.AppConfig.Database); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Keep an eye on the connection pool so dead connections are replaced
+	stopDBHealthCheck := db.StartHealthCheck(&config.AppConfig.Database)
+
 	// Run migrations
 	if err := db.AutoMigrate(); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
-	// Initialize GitHub token pool with proxy config
-	proxyConfig := &github.ProxyConfig{
-		Enabled:  config.AppConfig.GitHub.ProxyEnabled,
-		URL:      config.AppConfig.GitHub.ProxyURL,
-		Type:     config.AppConfig.GitHub.ProxyType,
-		Username: config.AppConfig.GitHub.ProxyUsername,
-		Password: config.AppConfig.GitHub.ProxyPassword,
+	repos := repository.NewGormRepositories(db.GetDB())
+
+	// Apply runtime settings stored in the database on top of config.yaml
+	if err := settings.Load(repos.Settings); err != nil {
+		log.Fatalf("Failed to load runtime settings: %v", err)
 	}
-	tokenPool, err := github.NewTokenPool(config.AppConfig.GitHub.Tokens, proxyConfig)
+
+	// Initialize GitHub token pool with proxy config
+	tokenPool, err := newTokenPool()
 	if err != nil {
 		log.Fatalf("Failed to initialize token pool: %v", err)
 	}
 
-	// Refresh token information
+	// Tokens stored through the API join the configured ones. Disabled ones stay
+	// out of rotation, even when the config lists them too.
 	ctx := context.Background()
+	if stored, err := repos.Tokens.List(ctx); err != nil {
+		log.Printf("Failed to load stored tokens: %v", err)
+	} else {
+		var active []string
+		for _, token := range stored {
+			if token.IsActive {
+				active = append(active, token.Token)
+			} else {
+				tokenPool.SetTokenActive(token.Token, false)
+			}
+		}
+		if added := tokenPool.AddTokens(active); added > 0 {
+			log.Printf("Added %d stored tokens to the pool", added)
+		}
+	}
+
+	// Refresh token information
 	tokenPool.RefreshAllTokens(ctx)
 
 	// Initialize search service
 	searchService := github.NewSearchService(tokenPool)
+	if budget := config.AppConfig.Monitor.SearchBudget; budget.Enabled {
+		searchService.SetBudget(github.NewSearchBudget(tokenPool, budget.RequestsPerTokenHour))
+	}
 
 	// Parse scan interval
 	scanInterval, err := time.ParseDuration(config.AppConfig.Monitor.ScanInterval)
@@ -57,22 +129,598 @@ func main() {
 	}
 
 	// Initialize monitor service
-	monitorService := monitor.NewMonitorService(searchService, scanInterval)
+	monitorService := monitor.NewMonitorService(repos, searchService, scanInterval)
+	monitorService.SetConcurrency(config.AppConfig.Monitor.Concurrency)
+	monitorService.SetKnownCacheSize(config.AppConfig.Monitor.KnownCacheSize)
+	monitorService.SetContextLines(config.AppConfig.Monitor.ContextLines)
+	monitorService.SetIncidentGrouping(config.AppConfig.Monitor.IncidentGroupBy)
+	monitorService.SetRerunIncomplete(config.AppConfig.Monitor.RerunIncomplete)
+	monitorService.SetScanOnCreate(config.AppConfig.Monitor.ScanOnCreate)
+	monitorService.SetAutoPause(autoPause(config.AppConfig.Monitor.AutoPause))
+	monitorService.SetDelays(monitorDelays(config.AppConfig.Monitor))
+	monitorService.SetResultChanges(config.AppConfig.Monitor.ResultChanges)
+	monitorService.SetMaxNewResults(config.AppConfig.Monitor.MaxNewResults)
+	monitorService.SetDryRun(config.AppConfig.Monitor.DryRun)
+	monitorService.SetUsageTracker(github.NewUsageTracker(tokenPool))
+	monitorService.SetExhaustionWarning(exhaustionWarning(config.AppConfig.Monitor))
+	github.SetSnippetLength(config.AppConfig.Monitor.SnippetLength)
+	github.SetMaxContentSize(int64(config.AppConfig.Monitor.MaxContentKB) << 10)
+	if detector, err := github.NewInfraDetector(config.AppConfig.Monitor.InternalCIDRs, config.AppConfig.Monitor.InternalDomains); err != nil {
+		log.Printf("Internal infrastructure detection disabled: %v", err)
+	} else {
+		monitorService.SetInfraDetector(detector)
+	}
+	if config.AppConfig.DockerHub.Enabled {
+		monitorService.SetDockerHub(dockerhub.NewClient(config.AppConfig.DockerHub.MaxPages))
+	}
+	if config.AppConfig.Postman.Enabled {
+		monitorService.SetPostman(postman.NewClient(config.AppConfig.Postman.MaxPages))
+	}
+	if config.AppConfig.Registry.Enabled {
+		monitorService.SetRegistryWatch(registry.NewClient(), registryWatch(config.AppConfig.Registry))
+	}
+	if config.AppConfig.Typosquat.Enabled {
+		monitorService.SetTyposquatWatch(typosquatWatch(config.AppConfig.Typosquat))
+	}
+	if config.AppConfig.ForkWatch.Enabled {
+		monitorService.SetForkWatch(forkWatch(config.AppConfig.ForkWatch))
+	}
+	if config.AppConfig.GistWatch.Enabled {
+		monitorService.SetGistWatch(gistWatch(config.AppConfig.GistWatch))
+	}
+
+	// Keep evidence and reports in object storage if configured
+	var store storage.Store
+	if config.AppConfig.Storage.Enabled {
+		store, err = storage.New(&config.AppConfig.Storage)
+		if err != nil {
+			log.Fatalf("Failed to initialize object storage: %v", err)
+		}
+		monitorService.SetStore(store)
+	}
 
-	// Start monitor if enabled
-	if config.AppConfig.Monitor.Enabled {
-		monitorService.Start()
+	// Share cached GitHub responses, scan claims and notification dedup with other
+	// instances through Redis if configured
+	var sharedCache cache.Cache
+	if config.AppConfig.Redis.Enabled {
+		sharedCache, err = cache.NewRedis(&config.AppConfig.Redis)
+		if err != nil {
+			log.Fatalf("Failed to initialize Redis: %v", err)
+		}
+		etagTTL, _ := time.ParseDuration(config.AppConfig.Redis.ETagTTL)
+		dedupWindow, _ := time.ParseDuration(config.AppConfig.Redis.NotifyDedupWindow)
+		tokenPool.SetResponseCache(sharedCache, etagTTL)
+		monitorService.SetCache(sharedCache, dedupWindow)
 	}
 
+	// Offer triage actions in chat notifications if configured
+	if actions := config.AppConfig.Notify.Actions; actions.Enabled {
+		expiry, _ := time.ParseDuration(actions.LinkExpiry)
+		notify.ConfigureActions(actions.BaseURL, actions.Secret, expiry)
+		notify.SetSlackInteractive(actions.SlackSigningSecret != "")
+	}
+	// Email rule owners the notifications of their rules
+	if owner := config.AppConfig.Notify.OwnerEmail; owner.SMTPHost != "" {
+		notify.ConfigureOwnerMail(notify.MailServer{
+			Host:     owner.SMTPHost,
+			Port:     owner.SMTPPort,
+			Username: owner.Username,
+			Password: owner.Password,
+			From:     owner.From,
+		})
+	}
+	// Translate foreign-language snippets in notifications
+	if translation := config.AppConfig.Notify.Translation; translation.Provider != "" {
+		timeout, _ := time.ParseDuration(translation.Timeout)
+		notify.ConfigureTranslation(notify.Translation{
+			Provider:       translation.Provider,
+			URL:            translation.URL,
+			APIKey:         translation.APIKey,
+			TargetLanguage: translation.TargetLanguage,
+			Timeout:        timeout,
+		})
+	}
+
+	// Apply runtime setting changes made through the API
+	settings.OnChange(func(s settings.Runtime) {
+		if interval, err := time.ParseDuration(s.ScanInterval); err == nil {
+			monitorService.SetScanInterval(interval)
+		}
+		monitorService.SetConcurrency(s.Concurrency)
+		tokenPool.SetRateLimitThreshold(s.RateLimitThreshold)
+		tokenPool.SetProxyConfig(&github.ProxyConfig{
+			Enabled:  s.ProxyEnabled,
+			URL:      s.ProxyURL,
+			Type:     s.ProxyType,
+			Username: s.ProxyUsername,
+			Password: s.ProxyPassword,
+		})
+	})
+
+	// Pick up config.yaml edits and SIGHUP without a restart. Runtime settings go
+	// through the settings package, changed token lists rebuild the token pool.
+	config.Watch(func(cfg *config.Config) {
+		if err := settings.Reload(cfg); err != nil {
+			log.Printf("Ignoring reloaded runtime settings: %v", err)
+		}
+
+		tokens, groups := config.GitHubTokens()
+		if !slices.Equal(cfg.GitHub.Tokens, tokens) {
+			if err := tokenPool.SetTokens(cfg.GitHub.Tokens); err != nil {
+				log.Printf("Ignoring reloaded token list: %v", err)
+			} else {
+				tokens = cfg.GitHub.Tokens
+				go tokenPool.RefreshAllTokens(context.Background())
+			}
+		}
+		if !maps.EqualFunc(cfg.GitHub.TokenGroups, groups, slices.Equal[[]string]) {
+			tokenPool.SetTokenGroups(cfg.GitHub.TokenGroups)
+			groups = cfg.GitHub.TokenGroups
+			go tokenPool.RefreshAllTokens(context.Background())
+		}
+		config.SetGitHubTokens(tokens, groups)
+		config.SetTimezone(cfg.Timezone)
+		i18n.SetDefault(cfg.Locale)
+		tokenPool.SetHTTPConfig(httpConfig(cfg.GitHub.HTTP))
+
+		monitorService.SetContextLines(cfg.Monitor.ContextLines)
+		monitorService.SetIncidentGrouping(cfg.Monitor.IncidentGroupBy)
+		monitorService.SetRerunIncomplete(cfg.Monitor.RerunIncomplete)
+		monitorService.SetScanOnCreate(cfg.Monitor.ScanOnCreate)
+		monitorService.SetAutoPause(autoPause(cfg.Monitor.AutoPause))
+		monitorService.SetDelays(monitorDelays(cfg.Monitor))
+		monitorService.SetResultChanges(cfg.Monitor.ResultChanges)
+		monitorService.SetMaxNewResults(cfg.Monitor.MaxNewResults)
+		monitorService.SetDryRun(cfg.Monitor.DryRun)
+		monitorService.SetExhaustionWarning(exhaustionWarning(cfg.Monitor))
+		github.SetSnippetLength(cfg.Monitor.SnippetLength)
+		github.SetMaxContentSize(int64(cfg.Monitor.MaxContentKB) << 10)
+		switch budget := searchService.Budget(); {
+		case !cfg.Monitor.SearchBudget.Enabled:
+			searchService.SetBudget(nil)
+		case budget == nil:
+			searchService.SetBudget(github.NewSearchBudget(tokenPool, cfg.Monitor.SearchBudget.RequestsPerTokenHour))
+		default:
+			budget.SetRequestsPerHour(cfg.Monitor.SearchBudget.RequestsPerTokenHour)
+		}
+		if detector, err := github.NewInfraDetector(cfg.Monitor.InternalCIDRs, cfg.Monitor.InternalDomains); err != nil {
+			log.Printf("Keeping the internal infrastructure detection: %v", err)
+		} else {
+			monitorService.SetInfraDetector(detector)
+		}
+
+		if cfg.Registry.Enabled {
+			monitorService.SetRegistryWatch(registry.NewClient(), registryWatch(cfg.Registry))
+		} else {
+			monitorService.SetRegistryWatch(nil, monitor.RegistryWatch{})
+		}
+		if cfg.Typosquat.Enabled {
+			monitorService.SetTyposquatWatch(typosquatWatch(cfg.Typosquat))
+		} else {
+			monitorService.SetTyposquatWatch(monitor.TyposquatWatch{})
+		}
+		if cfg.ForkWatch.Enabled {
+			monitorService.SetForkWatch(forkWatch(cfg.ForkWatch))
+		} else {
+			monitorService.SetForkWatch(monitor.ForkWatch{})
+		}
+		if cfg.GistWatch.Enabled {
+			monitorService.SetGistWatch(gistWatch(cfg.GistWatch))
+		} else {
+			monitorService.SetGistWatch(monitor.GistWatch{})
+		}
+	})
+
 	// Initialize API
-	apiService := api.NewAPI(tokenPool, searchService, monitorService)
+	apiService := api.NewAPI(repos, tokenPool, searchService, monitorService)
+	if store != nil {
+		apiService.SetStore(store)
+	}
+	if sharedCache != nil {
+		apiService.SetCache(sharedCache)
+	}
+
+	// Initialize summary reports, sent weekly when enabled and on demand through the API
+	reportScheduler := report.NewScheduler(repos.Reports, tokenPool, &config.AppConfig.Report)
+	apiService.SetReportScheduler(reportScheduler)
+	if store != nil {
+		reportScheduler.SetStore(store)
+	}
+
+	// Exchange findings with DefectDojo if configured
+	var defectDojoSyncer *defectdojo.Syncer
+	if config.AppConfig.DefectDojo.Enabled {
+		defectDojoSyncer, err = defectdojo.NewSyncer(&config.AppConfig.DefectDojo, db.GetDB())
+		if err != nil {
+			log.Fatalf("Failed to initialize DefectDojo sync: %v", err)
+		}
+	}
+
+	// Suggest verdicts for new results with an LLM if configured
+	var resultClassifier *classifier.Classifier
+	if config.AppConfig.Classifier.Enabled {
+		resultClassifier, err = classifier.NewClassifier(&config.AppConfig.Classifier, db.GetDB())
+		if err != nil {
+			log.Fatalf("Failed to initialize result classifier: %v", err)
+		}
+	}
+
+	// Track triage and remediation deadlines if configured
+	var slaChecker *sla.Checker
+	if config.AppConfig.SLA.Enabled {
+		policy, err := sla.NewPolicy(&config.AppConfig.SLA)
+		if err != nil {
+			log.Fatalf("Failed to initialize SLA tracking: %v", err)
+		}
+		apiService.SetSLA(policy)
+		slaChecker, err = sla.NewChecker(&config.AppConfig.SLA, policy, repos.Results, repos.Notifications)
+		if err != nil {
+			log.Fatalf("Failed to initialize SLA tracking: %v", err)
+		}
+	}
+
+	// Deliver new results to the outbound webhooks of their rules
+	webhookDispatcher, err := webhooks.NewDispatcher(&config.AppConfig.RuleWebhooks, repos.Webhooks)
+	if err != nil {
+		log.Fatalf("Failed to initialize rule webhooks: %v", err)
+	}
+
+	// Remove records deleted long ago if configured
+	var purger *purge.Purger
+	if config.AppConfig.Purge.Enabled {
+		purger, err = purge.NewPurger(&config.AppConfig.Purge, repos.Purge)
+		if err != nil {
+			log.Fatalf("Failed to initialize the purge of deleted records: %v", err)
+		}
+	}
+
+	// Manage rules and whitelist entries from a Git repository if configured
+	var ruleSyncer *rulesync.Syncer
+	if config.AppConfig.RuleSync.Enabled {
+		ruleSyncer, err = rulesync.NewSyncer(&config.AppConfig.RuleSync, db.GetDB())
+		if err != nil {
+			log.Fatalf("Failed to initialize rule sync: %v", err)
+		}
+		apiService.SetRuleSync(ruleSyncer)
+	}
+
+	// Scheduled scans and jobs run on one instance, every instance serves the API
+	startScheduled := func() {
+		if config.AppConfig.Monitor.Enabled {
+			monitorService.Start()
+		}
+		if config.AppConfig.Report.Enabled {
+			reportScheduler.Start()
+		}
+		if defectDojoSyncer != nil {
+			defectDojoSyncer.Start()
+		}
+		if resultClassifier != nil {
+			resultClassifier.Start()
+		}
+		if ruleSyncer != nil {
+			ruleSyncer.Start()
+		}
+		if slaChecker != nil {
+			slaChecker.Start()
+		}
+		webhookDispatcher.Start()
+		if purger != nil {
+			purger.Start()
+		}
+	}
+	stopScheduled := func() {
+		monitorService.Stop()
+		reportScheduler.Stop()
+		if defectDojoSyncer != nil {
+			defectDojoSyncer.Stop()
+		}
+		if resultClassifier != nil {
+			resultClassifier.Stop()
+		}
+		if ruleSyncer != nil {
+			ruleSyncer.Stop()
+		}
+		if slaChecker != nil {
+			slaChecker.Stop()
+		}
+		webhookDispatcher.Stop()
+		if purger != nil {
+			purger.Stop()
+		}
+	}
+
+	var elector *cluster.Elector
+	if config.AppConfig.Cluster.Enabled {
+		elector, err = cluster.NewElector(db.GetDB(), &config.AppConfig.Cluster, startScheduled, stopScheduled)
+		if err != nil {
+			log.Fatalf("Failed to initialize cluster membership: %v", err)
+		}
+		apiService.SetElector(elector)
+		elector.Start()
+
+		// Pick up runtime settings changed through the API of another instance
+		go func() {
+			for range time.Tick(30 * time.Second) {
+				if err := settings.Refresh(); err != nil {
+					log.Printf("Failed to refresh runtime settings: %v", err)
+				}
+			}
+		}()
+	} else {
+		startScheduled()
+	}
+
+	// Index results into Elasticsearch if configured
+	var elasticSink *elastic.Sink
+	if config.AppConfig.Elasticsearch.Enabled {
+		elasticSink, err = elastic.NewSink(&config.AppConfig.Elasticsearch, repos)
+		if err != nil {
+			log.Fatalf("Failed to initialize Elasticsearch export: %v", err)
+		}
+		elasticSink.Start()
+	}
+
+	// Initialize SSO login if configured
+	if config.AppConfig.Auth.OIDC.Enabled {
+		oidcProvider, err := auth.NewOIDCProvider(ctx, &config.AppConfig.Auth.OIDC)
+		if err != nil {
+			log.Fatalf("Failed to initialize OIDC provider: %v", err)
+		}
+		apiService.SetOIDCProvider(oidcProvider)
+		log.Printf("OIDC login enabled with issuer %s", config.AppConfig.Auth.OIDC.IssuerURL)
+	}
+
 	router := api.SetupRouter(apiService)
 
 	// Start server
 	addr := fmt.Sprintf(":%d", config.AppConfig.Server.Port)
-	log.Printf("Starting server on %s", addr)
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: router,
+	}
+
+	tlsConfig := config.AppConfig.Server.TLS
+	var redirectSrv *http.Server
+
+	if tlsConfig.Enabled {
+		reloader, err := certreload.New(tlsConfig.CertFile, tlsConfig.KeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+		srv.TLSConfig = &tls.Config{
+			GetCertificate: reloader.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+
+		// Optionally redirect plain HTTP to HTTPS
+		if tlsConfig.RedirectHTTPPort > 0 {
+			redirectSrv = &http.Server{
+				Addr:    fmt.Sprintf(":%d", tlsConfig.RedirectHTTPPort),
+				Handler: httpsRedirect(config.AppConfig.Server.Port),
+			}
+			go func() {
+				log.Printf("Redirecting HTTP on %s to HTTPS", redirectSrv.Addr)
+				if err := redirectSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Fatalf("Failed to start HTTP redirect server: %v", err)
+				}
+			}()
+		}
+	}
+
+	go func() {
+		var err error
+		if tlsConfig.Enabled {
+			log.Printf("Starting HTTPS server on %s", addr)
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Starting server on %s", addr)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Optional gRPC API for internal services
+	var grpcSrv *grpc.Server
+	if config.AppConfig.Server.GRPC.Enabled {
+		var creds credentials.TransportCredentials
+		if srv.TLSConfig != nil {
+			creds = credentials.NewTLS(srv.TLSConfig.Clone())
+		}
+		grpcSrv = grpcapi.NewServer(repos, monitorService, creds)
+
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", config.AppConfig.Server.GRPC.Port))
+		if err != nil {
+			log.Fatalf("Failed to listen for gRPC: %v", err)
+		}
+		go func() {
+			log.Printf("Starting gRPC server on %s", listener.Addr())
+			if err := grpcSrv.Serve(listener); err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+	}
+
+	// Wait for a termination signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	log.Printf("Received %s, shutting down...", sig)
+
+	shutdownTimeout, err := time.ParseDuration(config.AppConfig.Server.ShutdownTimeout)
+	if err != nil {
+		shutdownTimeout = 15 * time.Second
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Stop accepting connections and drain in-flight requests
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown did not complete cleanly: %v", err)
+	}
+	if redirectSrv != nil {
+		redirectSrv.Shutdown(shutdownCtx)
+	}
+	if grpcSrv != nil {
+		// Event streams never finish on their own, so force the stop once the timeout is up
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcSrv.Stop()
+		}
+	}
+
+	// Stop scheduled work and hand over leadership, a scan in progress may hold it
+	// up so bound the wait
+	stopped := make(chan struct{})
+	go func() {
+		if elector != nil {
+			elector.Stop()
+		} else {
+			stopScheduled()
+		}
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		log.Println("Timed out waiting for the monitor to stop")
+	}
+
+	if elasticSink != nil {
+		elasticSink.Stop()
+	}
+	if sharedCache != nil {
+		sharedCache.Close()
+	}
+
+	stopDBHealthCheck()
+	if err := db.Close(); err != nil {
+		log.Printf("Failed to close database: %v", err)
+	}
+
+	reporting.Flush(5 * time.Second)
+	log.Println("Server exited")
+}
+
+// newTokenPool creates the GitHub token pool from the loaded config
+func newTokenPool() (*github.TokenPool, error) {
+	proxyConfig := &github.ProxyConfig{
+		Enabled:  config.AppConfig.GitHub.ProxyEnabled,
+		URL:      config.AppConfig.GitHub.ProxyURL,
+		Type:     config.AppConfig.GitHub.ProxyType,
+		Username: config.AppConfig.GitHub.ProxyUsername,
+		Password: config.AppConfig.GitHub.ProxyPassword,
+	}
+	tokenPool, err := github.NewTokenPool(config.AppConfig.GitHub.Tokens, proxyConfig)
+	if err != nil {
+		return nil, err
+	}
+	tokenPool.SetRateLimitThreshold(config.AppConfig.GitHub.RateLimitThreshold)
+	tokenPool.SetTokenGroups(config.AppConfig.GitHub.TokenGroups)
+	tokenPool.SetHTTPConfig(httpConfig(config.AppConfig.GitHub.HTTP))
+	return tokenPool, nil
+}
+
+// httpConfig converts the GitHub HTTP config for the token pool, durations are
+// validated on load
+func httpConfig(cfg config.GitHubHTTPConfig) github.HTTPConfig {
+	connectTimeout, _ := time.ParseDuration(cfg.ConnectTimeout)
+	readTimeout, _ := time.ParseDuration(cfg.ReadTimeout)
+	retryWait, _ := time.ParseDuration(cfg.RetryWait)
+	return github.HTTPConfig{
+		ConnectTimeout: connectTimeout,
+		ReadTimeout:    readTimeout,
+		MaxIdleConns:   cfg.MaxIdleConns,
+		MaxRetries:     cfg.MaxRetries,
+		RetryWait:      retryWait,
+	}
+}
+
+// registryWatch converts the registry config for the monitor service
+func registryWatch(cfg config.RegistryConfig) monitor.RegistryWatch {
+	return monitor.RegistryWatch{
+		Packages:   cfg.Packages,
+		Registries: cfg.Registries,
+		Severity:   cfg.Severity,
+	}
+}
 
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+// typosquatWatch converts the typosquat config for the monitor service
+func typosquatWatch(cfg config.TyposquatConfig) monitor.TyposquatWatch {
+	interval, _ := time.ParseDuration(cfg.Interval)
+	return monitor.TyposquatWatch{
+		Names:       cfg.Names,
+		Owners:      cfg.Owners,
+		MaxVariants: cfg.MaxVariants,
+		Severity:    cfg.Severity,
+		Interval:    interval,
 	}
 }
+
+// forkWatch converts the fork_watch config for the monitor service
+func forkWatch(cfg config.ForkWatchConfig) monitor.ForkWatch {
+	interval, _ := time.ParseDuration(cfg.Interval)
+	return monitor.ForkWatch{
+		Repositories: cfg.Repositories,
+		MaxForks:     cfg.MaxForks,
+		Severity:     cfg.Severity,
+		Interval:     interval,
+	}
+}
+
+// gistWatch converts the gist_watch config for the monitor service
+func gistWatch(cfg config.GistWatchConfig) monitor.GistWatch {
+	interval, _ := time.ParseDuration(cfg.Interval)
+	return monitor.GistWatch{
+		Users:    cfg.Users,
+		MaxGists: cfg.MaxGists,
+		Severity: cfg.Severity,
+		Interval: interval,
+	}
+}
+
+// autoPause converts the monitor.auto_pause config for the monitor service
+func autoPause(cfg config.AutoPauseConfig) monitor.AutoPause {
+	return monitor.AutoPause{
+		MaxFailures: cfg.MaxFailures,
+		IdleFor:     time.Duration(cfg.IdleDays) * 24 * time.Hour,
+	}
+}
+
+// monitorDelays parses the page and rule delays of the monitor config
+func monitorDelays(cfg config.MonitorConfig) (page, rule time.Duration) {
+	page, _ = time.ParseDuration(cfg.PageDelay)
+	rule, _ = time.ParseDuration(cfg.RuleDelay)
+	return page, rule
+}
+
+// exhaustionWarning parses how long before running out of calls the tokens are
+// warned about
+func exhaustionWarning(cfg config.MonitorConfig) time.Duration {
+	within, _ := time.ParseDuration(cfg.ExhaustionWarning)
+	return within
+}
+
+// httpsRedirect redirects every request to the same URL on the HTTPS port
+func httpsRedirect(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
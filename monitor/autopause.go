@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/i18n"
+	"github-monitor/notify"
+	"github-monitor/repository"
+
+	"gorm.io/gorm"
+)
+
+// AutoPause deactivates rules that keep failing or stopped finding anything, a
+// zero value disables the check
+type AutoPause struct {
+	MaxFailures int           // consecutive failed scans
+	IdleFor     time.Duration // without new results
+}
+
+// SetAutoPause sets when rules are deactivated after their scan
+func (m *MonitorService) SetAutoPause(pause AutoPause) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.autoPause = pause
+}
+
+func (m *MonitorService) getAutoPause() AutoPause {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.autoPause
+}
+
+// checkAutoPause deactivates a rule after its scan if its last scans all failed or
+// it found nothing new for too long, and tells its project. Only scans and results
+// since the rule was last activated count, so a re-activated rule gets a fresh start.
+func (m *MonitorService) checkAutoPause(ctx context.Context, rule models.MonitorRule) {
+	pause := m.getAutoPause()
+	if (pause.MaxFailures == 0 && pause.IdleFor == 0) || m.DryRun() {
+		return
+	}
+	activeSince := rule.CreatedAt
+	if rule.ActiveSince != nil {
+		activeSince = *rule.ActiveSince
+	}
+
+	reason, err := m.pauseReason(ctx, rule, pause, activeSince, time.Now())
+	if err != nil {
+		log.Printf("Failed to check whether rule %d should be paused: %v", rule.ID, err)
+		return
+	}
+	if reason != "" {
+		m.pauseRule(ctx, rule.ID, reason)
+	}
+}
+
+// pauseReason returns why a rule should be deactivated, empty when it shouldn't
+func (m *MonitorService) pauseReason(ctx context.Context, rule models.MonitorRule, pause AutoPause, activeSince, now time.Time) (string, error) {
+	if pause.MaxFailures > 0 {
+		history, err := m.repos.History.ListAfter(ctx, repository.HistoryFilter{RuleID: rule.ID, Since: activeSince}, 0, pause.MaxFailures)
+		if err != nil {
+			return "", err
+		}
+		failed := len(history) == pause.MaxFailures
+		for _, scan := range history {
+			failed = failed && scan.Status == "failed"
+		}
+		if failed {
+			return fmt.Sprintf("its last %d scans failed, the last one with: %s", pause.MaxFailures, history[0].ErrorMessage), nil
+		}
+	}
+
+	idleSince := now.Add(-pause.IdleFor)
+	if pause.IdleFor > 0 && activeSince.Before(idleSince) {
+		// Sample only rules record samples instead of results
+		if rule.SampleOnly {
+			return "", nil
+		}
+		// Honeytoken rules are meant to find nothing
+		if m.repos.Honeytokens != nil {
+			_, err := m.repos.Honeytokens.GetByRule(ctx, rule.ID)
+			if err == nil {
+				return "", nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", err
+			}
+		}
+		found, err := m.repos.Results.Count(ctx, repository.ResultFilter{RuleID: rule.ID, CreatedAfter: idleSince})
+		if err != nil {
+			return "", err
+		}
+		if found == 0 {
+			return fmt.Sprintf("it found no new results in %d days", int(pause.IdleFor.Hours()/24)), nil
+		}
+	}
+	return "", nil
+}
+
+// pauseRule deactivates a rule, recording the reason in the rule and its revisions
+func (m *MonitorService) pauseRule(ctx context.Context, id uint, reason string) {
+	// The rule may have been edited or deactivated during the scan
+	rule, err := m.repos.Rules.Get(ctx, id)
+	if err != nil || !rule.IsActive {
+		return
+	}
+	rule.IsActive = false
+	rule.PausedReason = reason
+	ctx = repository.WithNote(repository.WithActor(ctx, "monitor"), "paused automatically, "+reason)
+	if err := m.repos.Rules.Save(ctx, rule); err != nil {
+		log.Printf("Failed to pause rule %d: %v", rule.ID, err)
+		return
+	}
+	log.Printf("Paused rule %d (%s): %s", rule.ID, rule.Name, reason)
+	if !m.notificationsEnabled() {
+		return
+	}
+
+	message := notify.Message{
+		TitleText:   i18n.Format("Rule paused: %s", rule.Name),
+		ContentText: i18n.Format("Rule **%s** was deactivated because %s. Re-activate it once it is fixed or still needed.", rule.Name, reason),
+	}
+	m.broadcast(*rule, message, nil, func(config *models.NotificationConfig) bool {
+		return config.ProjectID == rule.ProjectID
+	})
+}
@@ -0,0 +1,61 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+)
+
+type pagesKey struct{}
+
+// withPages limits the code search pages of the rule scanned with ctx
+func withPages(ctx context.Context, pages int) context.Context {
+	return context.WithValue(ctx, pagesKey{}, pages)
+}
+
+// pagesOf returns the code search pages the scan of ctx may read, 0 for all
+func pagesOf(ctx context.Context) int {
+	pages, _ := ctx.Value(pagesKey{}).(int)
+	return pages
+}
+
+// budgetRules orders the rules of a scheduled cycle by priority and returns the
+// ones the search budget allows this cycle with their pages, leaving out the ones
+// deferred to a later cycle. Rules are prioritized by severity. Without a budget
+// the rules are returned as they are, with nil pages.
+func (m *MonitorService) budgetRules(rules []models.MonitorRule) ([]models.MonitorRule, map[uint]int) {
+	var budget *github.SearchBudget
+	if m.searchService != nil {
+		budget = m.searchService.Budget()
+	}
+	if budget == nil {
+		return rules, nil
+	}
+
+	demands := make([]github.PageDemand, len(rules))
+	for i, rule := range rules {
+		demands[i] = github.PageDemand{RuleID: rule.ID, Priority: models.SeverityRank(rule.Severity)}
+	}
+	pages := budget.Allocate(time.Now(), m.ScanInterval(), demands)
+
+	scanned := make([]models.MonitorRule, 0, len(pages))
+	for _, rule := range rules {
+		if pages[rule.ID] > 0 {
+			scanned = append(scanned, rule)
+		} else {
+			log.Printf("Deferring rule %d (%s), the search budget is spent", rule.ID, rule.Name)
+		}
+	}
+	sort.SliceStable(scanned, func(i, j int) bool {
+		return models.SeverityRank(scanned[i].Severity) > models.SeverityRank(scanned[j].Severity)
+	})
+
+	if deferred := len(rules) - len(scanned); deferred > 0 {
+		log.Printf("Search budget: %d of %d rules deferred, %d searches left this hour", deferred, len(rules), budget.Remaining(time.Now()))
+	}
+	return scanned, pages
+}
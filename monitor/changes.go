@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github-monitor/db/models"
+	"github-monitor/events"
+	"github-monitor/github"
+	"github-monitor/i18n"
+	"github-monitor/notify"
+	"github-monitor/repository"
+)
+
+// What happens when a scan finds the file of a result again with other content
+const (
+	ChangesOff    = "off"    // nothing, the file is skipped as known
+	ChangesRecord = "record" // a revision is recorded and the result shows the new content
+	ChangesReopen = "reopen" // as record, and a resolved or false positive result goes back to pending
+)
+
+// SetResultChanges sets what a changed file of a known result leads to
+func (m *MonitorService) SetResultChanges(mode string) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.resultChanges = mode
+}
+
+func (m *MonitorService) getResultChanges() string {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.resultChanges
+}
+
+// contentHash identifies the matched content of a result: the blob SHA of code
+// search results, otherwise a hash of the snippet and matches
+func contentHash(item *github.SearchResultItem) string {
+	if item.SHA != "" {
+		return item.SHA
+	}
+	if item.ContentSnippet == "" && len(item.Fragments) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(item.ContentSnippet + "\n" + strings.Join(item.Fragments, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordChanges compares the files a scan found again with their results. A file
+// whose content changed gets a revision, since it may hold a new secret although
+// it was seen before, and with ChangesReopen its closed result is reopened.
+// Results from before content hashes were kept only have theirs filled in.
+func (m *MonitorService) recordChanges(ctx context.Context, rule models.MonitorRule, items []*github.SearchResultItem) {
+	mode := m.getResultChanges()
+	if mode == ChangesOff || mode == "" || len(items) == 0 {
+		return
+	}
+
+	byKey := make(map[repository.FileKey]*github.SearchResultItem, len(items))
+	keys := make([]repository.FileKey, 0, len(items))
+	for _, item := range items {
+		key := repository.FileKey{RepoFullName: item.RepoFullName, FilePath: item.FilePath}
+		byKey[key] = item
+		keys = append(keys, key)
+	}
+	results, err := m.repos.Results.FindFiles(ctx, rule.ID, keys)
+	if err != nil {
+		log.Printf("Failed to look up the known results of rule %d: %v", rule.ID, err)
+		return
+	}
+
+	var reopened []models.SearchResult
+	for i := range results {
+		result := &results[i]
+		item := byKey[repository.FileKey{RepoFullName: result.RepoFullName, FilePath: result.FilePath}]
+		hash := contentHash(item)
+		if hash == "" || hash == result.ContentHash {
+			continue
+		}
+		if result.ContentHash == "" {
+			result.ContentHash = hash
+			if err := m.repos.Results.Save(ctx, result); err != nil {
+				log.Printf("Failed to record the content hash of result %d: %v", result.ID, err)
+			}
+			continue
+		}
+
+		revision := models.ResultRevision{
+			PreviousHash:    result.ContentHash,
+			ContentHash:     hash,
+			PreviousSnippet: result.ContentSnippet,
+			ContentSnippet:  item.ContentSnippet,
+			HTMLURL:         item.HTMLURL,
+		}
+		result.ContentHash = hash
+		result.ContentSnippet = item.ContentSnippet
+		result.HTMLURL = item.HTMLURL
+		if len(item.Fragments) > 0 {
+			encoded, _ := json.Marshal(item.Fragments)
+			result.Matches = string(encoded)
+		}
+		if mode == ChangesReopen && (result.Status == "resolved" || result.Status == "false_positive") {
+			result.Status = "pending"
+			revision.Reopened = true
+		}
+		if err := m.repos.Results.AddRevision(ctx, result, &revision); err != nil {
+			log.Printf("Failed to record the change of result %d: %v", result.ID, err)
+			continue
+		}
+
+		log.Printf("Content of result %d (%s/%s) changed", result.ID, result.RepoFullName, result.FilePath)
+		events.PublishTo(result.ProjectID, events.TypeResultChanged, result)
+		if revision.Reopened {
+			events.PublishTo(result.ProjectID, events.TypeResultStatus, events.StatusChange{IDs: []uint{result.ID}, Status: result.Status})
+			reopened = append(reopened, *result)
+		}
+	}
+
+	if len(reopened) > 0 && m.notificationsEnabled() {
+		message := notify.Message{
+			TitleText:   i18n.Format("GitHub leak changed: %s", rule.Name),
+			ContentText: i18n.Format("The files of %d closed results of rule **%s** changed, they are back in the pending queue:\n", len(reopened), rule.Name),
+			URL:         reopened[0].HTMLURL,
+		}
+		m.broadcast(rule, message, reopened, notifiesNew(rule))
+	}
+}
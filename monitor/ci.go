@@ -0,0 +1,45 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+)
+
+const (
+	// ciSeverity is the least severity of CI configs that misuse secrets
+	ciSeverity = "high"
+	// ciHardcodedSeverity is the severity of CI configs with hardcoded credentials,
+	// which usually are real ones used by a pipeline
+	ciHardcodedSeverity = "critical"
+)
+
+// detectCI returns the JSON array of hardcoded credentials and misused secrets in a
+// new result that is a CI config, such as a GitHub Actions workflow, and the
+// severity the result gets
+func (m *MonitorService) detectCI(ctx context.Context, severity string, item *github.SearchResultItem) (string, string) {
+	if (item.Source != "" && item.Source != models.SourceGitHub && item.Source != models.SourceForkWatch) || !github.IsCIConfig(item.FilePath) {
+		return "", severity
+	}
+	if !m.fetchContent(ctx, item) {
+		return "", severity
+	}
+
+	findings := github.FindCIIssues(item.Content)
+	if len(findings) == 0 {
+		return "", severity
+	}
+	least := ciSeverity
+	for _, finding := range findings {
+		if finding.Kind == github.CIHardcodedEnv || finding.Kind == github.CIHardcodedSecret {
+			least = ciHardcodedSeverity
+		}
+	}
+	if models.SeverityRank(severity) < models.SeverityRank(least) {
+		severity = least
+	}
+	encoded, _ := json.Marshal(findings)
+	return string(encoded), severity
+}
@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"context"
+	"log"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+)
+
+// SetContextLines fetches the file of every new code search result and keeps the
+// given number of lines around each match, 0 disables it
+func (m *MonitorService) SetContextLines(lines int) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.contextLines = lines
+}
+
+func (m *MonitorService) getContextLines() int {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.contextLines
+}
+
+// lineContext returns the lines around the matches of a new result, fetching its
+// file when the content isn't known yet. The content is kept on the item for the
+// evidence. Empty when disabled or the file can't be fetched.
+func (m *MonitorService) lineContext(ctx context.Context, keywords []string, dialect string, item *github.SearchResultItem) string {
+	lines := m.getContextLines()
+	if lines <= 0 || (item.Source != "" && item.Source != models.SourceGitHub) || item.FilePath == "" {
+		return ""
+	}
+
+	if !m.fetchContent(ctx, item) {
+		return ""
+	}
+	return github.LineContext(item.Content, keywords, dialect, lines)
+}
+
+// fetchContent makes sure the content of a code search result is on the item,
+// fetching the file if needed. False when it can't be fetched.
+func (m *MonitorService) fetchContent(ctx context.Context, item *github.SearchResultItem) bool {
+	if item.Content != "" {
+		return true
+	}
+	if m.searchService == nil {
+		return false
+	}
+	file, err := m.searchService.GetFileContent(ctx, item.RepoFullName, item.FilePath, "")
+	if err != nil {
+		log.Printf("Failed to fetch %s/%s: %v", item.RepoFullName, item.FilePath, err)
+		return false
+	}
+	item.Content = file.Content
+	return true
+}
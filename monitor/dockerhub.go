@@ -0,0 +1,53 @@
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/dockerhub"
+	"github-monitor/github"
+)
+
+// SetDockerHub enables Docker Hub searches for every rule, nil disables them
+func (m *MonitorService) SetDockerHub(client *dockerhub.Client) {
+	m.dockerHub = client
+}
+
+// searchDockerHub finds Docker Hub repositories whose name or description contains
+// every keyword of a rule. The longest keyword that isn't a search qualifier is sent
+// as query since Docker Hub matches loosely, the others are checked locally.
+func (m *MonitorService) searchDockerHub(ctx context.Context, keywords []string, dialect string) ([]*github.SearchResultItem, error) {
+	query := queryKeyword(keywords, dialect)
+	if query == "" {
+		return nil, nil
+	}
+
+	repos, err := m.dockerHub.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*github.SearchResultItem, 0)
+	for i := range repos {
+		repo := &repos[i]
+		matched, _ := github.MatchContent(repo.Name+"\n"+repo.Description, keywords, dialect)
+		if matched == nil {
+			continue
+		}
+
+		items = append(items, &github.SearchResultItem{
+			RepoFullName:    repo.FullName(),
+			RepoURL:         repo.URL(),
+			FileURL:         repo.URL(),
+			HTMLURL:         repo.URL(),
+			MatchedKeywords: matched,
+			ContentSnippet:  repo.Description,
+			Score:           1.0,
+			CreatedAt:       time.Now(),
+			Source:          models.SourceDockerHub,
+		})
+	}
+
+	return items, nil
+}
@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"context"
+	"log"
+
+	"github-monitor/db/models"
+	"github-monitor/repository"
+	"github-monitor/settings"
+)
+
+// SetDryRun switches dry run on or off. In dry run scans run as usual, but the new
+// results they find are staged instead of recorded, no notification is sent and
+// rules aren't paused or held for review.
+func (m *MonitorService) SetDryRun(enabled bool) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.dryRun = enabled
+}
+
+// DryRun reports whether the monitor runs in dry run
+func (m *MonitorService) DryRun() bool {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.dryRun
+}
+
+// notificationsEnabled reports whether the monitor sends notifications
+func (m *MonitorService) notificationsEnabled() bool {
+	return settings.Current().NotificationsEnabled && !m.DryRun()
+}
+
+// stageResults records the new results of a dry run scan in the staging table
+// and returns the ones that weren't staged before
+func (m *MonitorService) stageResults(ctx context.Context, rule models.MonitorRule, results []models.SearchResult) []models.SearchResult {
+	if len(results) == 0 || m.repos.Staging == nil {
+		return nil
+	}
+
+	entries := make([]models.StagedResult, 0, len(results))
+	for _, result := range results {
+		entries = append(entries, models.StagedResult{
+			RuleID:          result.RuleID,
+			ProjectID:       result.ProjectID,
+			Source:          result.Source,
+			RepoFullName:    result.RepoFullName,
+			FilePath:        result.FilePath,
+			HTMLURL:         result.HTMLURL,
+			MatchedKeywords: result.MatchedKeywords,
+			ContentSnippet:  result.ContentSnippet,
+			Score:           result.Score,
+			Severity:        result.Severity,
+			Fingerprint:     result.Fingerprint,
+			TokenUsed:       result.TokenUsed,
+		})
+	}
+	staged, err := m.repos.Staging.Stage(ctx, entries)
+	if err != nil {
+		log.Printf("Failed to stage the results of rule %d: %v", rule.ID, err)
+		return nil
+	}
+
+	fresh := make(map[repository.FileKey]bool, len(staged))
+	for _, entry := range staged {
+		fresh[repository.FileKey{RepoFullName: entry.RepoFullName, FilePath: entry.FilePath}] = true
+	}
+	stagedResults := make([]models.SearchResult, 0, len(staged))
+	for _, result := range results {
+		if fresh[repository.FileKey{RepoFullName: result.RepoFullName, FilePath: result.FilePath}] {
+			stagedResults = append(stagedResults, result)
+		}
+	}
+	log.Printf("Dry run: staged %d new results of rule %d", len(stagedResults), rule.ID)
+	return stagedResults
+}
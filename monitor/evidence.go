@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/storage"
+)
+
+// SetStore keeps evidence of every new result in object storage, nil disables it
+func (m *MonitorService) SetStore(store storage.Store) {
+	m.store = store
+}
+
+// evidence is what is stored for a result, so a finding can still be reviewed after
+// the file was deleted or the repository taken down
+type evidence struct {
+	RuleID          uint      `json:"rule_id"`
+	RuleName        string    `json:"rule_name"`
+	Source          string    `json:"source"`
+	RepoFullName    string    `json:"repo_full_name"`
+	FilePath        string    `json:"file_path"`
+	HTMLURL         string    `json:"html_url"`
+	MatchedKeywords []string  `json:"matched_keywords"`
+	ContentSnippet  string    `json:"content_snippet"`
+	Content         string    `json:"content,omitempty"` // whole file, when it could be fetched
+	CapturedAt      time.Time `json:"captured_at"`
+}
+
+// storeEvidence uploads the evidence of a new result and returns its key, or an
+// empty key when it couldn't be stored. GitHub code search only returns fragments,
+// so the file is fetched for those results. Oversized and binary files are left
+// out, whether fetched here or brought along by the result.
+func (m *MonitorService) storeEvidence(ctx context.Context, rule models.MonitorRule, source string, item *github.SearchResultItem) string {
+	content := item.Content
+	if content != "" {
+		if err := github.CheckContent(item.FilePath, content); err != nil {
+			log.Printf("Storing evidence of %s/%s without the file: %v", item.RepoFullName, item.FilePath, err)
+			content = ""
+		}
+	} else if source == models.SourceGitHub && item.FilePath != "" && m.searchService != nil {
+		file, err := m.searchService.GetFileContent(ctx, item.RepoFullName, item.FilePath, "")
+		if err != nil {
+			log.Printf("Storing evidence of %s/%s without the file: %v", item.RepoFullName, item.FilePath, err)
+		} else {
+			content = file.Content
+		}
+	}
+
+	data, err := json.Marshal(evidence{
+		RuleID:          rule.ID,
+		RuleName:        rule.Name,
+		Source:          source,
+		RepoFullName:    item.RepoFullName,
+		FilePath:        item.FilePath,
+		HTMLURL:         item.HTMLURL,
+		MatchedKeywords: item.MatchedKeywords,
+		ContentSnippet:  item.ContentSnippet,
+		Content:         content,
+		CapturedAt:      time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to encode evidence: %v", err)
+		return ""
+	}
+
+	// A rule records a file once, so the file identifies the evidence
+	sum := sha256.Sum256([]byte(source + "\x00" + item.RepoFullName + "\x00" + item.FilePath))
+	key := fmt.Sprintf("evidence/%d/%s.json", rule.ID, hex.EncodeToString(sum[:16]))
+
+	if err := m.store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "application/json"); err != nil {
+		log.Printf("Failed to store evidence: %v", err)
+		return ""
+	}
+	return key
+}
@@ -0,0 +1,318 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/repository"
+	"github-monitor/storage"
+
+	"gorm.io/gorm"
+)
+
+// memoryResults is an in-memory ResultRepo covering what scans use. The embedded
+// interface is nil, so other methods panic if a test reaches them.
+type memoryResults struct {
+	repository.ResultRepo
+
+	mu        sync.Mutex
+	results   []models.SearchResult
+	revisions []models.ResultRevision
+	lookups   int // calls to KnownFiles and FileKeys
+}
+
+func (r *memoryResults) KnownFiles(ctx context.Context, ruleID uint, repoFullNames []string) (map[repository.FileKey]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+
+	repos := make(map[string]bool, len(repoFullNames))
+	for _, name := range repoFullNames {
+		repos[name] = true
+	}
+	known := make(map[repository.FileKey]bool)
+	for _, result := range r.results {
+		if result.RuleID == ruleID && repos[result.RepoFullName] {
+			known[repository.FileKey{RepoFullName: result.RepoFullName, FilePath: result.FilePath}] = true
+		}
+	}
+	return known, nil
+}
+
+func (r *memoryResults) FileKeys(ctx context.Context, ruleID uint) ([]repository.FileKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+
+	var keys []repository.FileKey
+	for _, result := range r.results {
+		if result.RuleID == ruleID {
+			keys = append(keys, repository.FileKey{RepoFullName: result.RepoFullName, FilePath: result.FilePath})
+		}
+	}
+	return keys, nil
+}
+
+func (r *memoryResults) Create(ctx context.Context, result *models.SearchResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result.ID = uint(len(r.results) + 1)
+	r.results = append(r.results, *result)
+	return nil
+}
+
+func (r *memoryResults) FindFiles(ctx context.Context, ruleID uint, keys []repository.FileKey) ([]models.SearchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := make(map[repository.FileKey]bool, len(keys))
+	for _, key := range keys {
+		wanted[key] = true
+	}
+	var found []models.SearchResult
+	for _, result := range r.results {
+		if result.RuleID == ruleID && wanted[repository.FileKey{RepoFullName: result.RepoFullName, FilePath: result.FilePath}] {
+			found = append(found, result)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryResults) Save(ctx context.Context, result *models.SearchResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.results {
+		if r.results[i].ID == result.ID {
+			r.results[i] = *result
+		}
+	}
+	return nil
+}
+
+func (r *memoryResults) AddRevision(ctx context.Context, result *models.SearchResult, revision *models.ResultRevision) error {
+	if err := r.Save(ctx, result); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	revision.ResultID = result.ID
+	r.revisions = append(r.revisions, *revision)
+	return nil
+}
+
+func (r *memoryResults) ListSnoozeExpired(ctx context.Context, now time.Time, limit int) ([]models.SearchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []models.SearchResult
+	for _, result := range r.results {
+		if result.SnoozedUntil != nil && !result.SnoozedUntil.After(now) && len(expired) < limit {
+			expired = append(expired, result)
+		}
+	}
+	return expired, nil
+}
+
+func (r *memoryResults) EndSnooze(ctx context.Context, id uint, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.results {
+		if result := &r.results[i]; result.ID == id && result.SnoozedUntil != nil && result.SnoozedUntil.Equal(until) {
+			result.SnoozedUntil = nil
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryResults) Count(ctx context.Context, filter repository.ResultFilter) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, result := range r.results {
+		if result.RuleID == filter.RuleID && result.CreatedAt.After(filter.CreatedAfter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// memoryHistory is an in-memory HistoryRepo covering what scans use
+type memoryHistory struct {
+	repository.HistoryRepo
+
+	entries []models.ScanHistory // oldest first
+}
+
+func (h *memoryHistory) ListAfter(ctx context.Context, filter repository.HistoryFilter, after uint64, limit int) ([]models.ScanHistory, error) {
+	var entries []models.ScanHistory
+	for i := len(h.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		if entry := h.entries[i]; entry.RuleID == filter.RuleID && !entry.CreatedAt.Before(filter.Since) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// memoryWhitelist is an in-memory WhitelistRepo that honours the project scope of ctx
+type memoryWhitelist struct {
+	entries []models.Whitelist
+}
+
+func (w *memoryWhitelist) List(ctx context.Context) ([]models.Whitelist, error) {
+	ids, scoped := repository.ProjectsOf(ctx)
+	var entries []models.Whitelist
+	for _, entry := range w.entries {
+		if !scoped || containsID(ids, entry.ProjectID) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (w *memoryWhitelist) Get(ctx context.Context, id uint) (*models.Whitelist, error) {
+	for i := range w.entries {
+		if w.entries[i].ID == id {
+			return &w.entries[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (w *memoryWhitelist) Create(ctx context.Context, entry *models.Whitelist) error {
+	w.entries = append(w.entries, *entry)
+	return nil
+}
+
+func (w *memoryWhitelist) Delete(ctx context.Context, id uint) error {
+	return nil
+}
+
+// memoryHoneytokens is an in-memory HoneytokenRepo covering what notifications use
+type memoryHoneytokens struct {
+	repository.HoneytokenRepo
+
+	tokens []models.Honeytoken
+}
+
+func (h *memoryHoneytokens) GetByRule(ctx context.Context, ruleID uint) (*models.Honeytoken, error) {
+	for i := range h.tokens {
+		if h.tokens[i].RuleID == ruleID {
+			token := h.tokens[i]
+			return &token, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (h *memoryHoneytokens) Save(ctx context.Context, token *models.Honeytoken) error {
+	for i := range h.tokens {
+		if h.tokens[i].ID == token.ID {
+			h.tokens[i] = *token
+		}
+	}
+	return nil
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryRules is an in-memory RuleRepo covering what holding a rule uses
+type memoryRules struct {
+	repository.RuleRepo
+
+	rules map[uint]models.MonitorRule
+}
+
+func (r *memoryRules) Get(ctx context.Context, id uint) (*models.MonitorRule, error) {
+	rule, ok := r.rules[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rule, nil
+}
+
+func (r *memoryRules) Save(ctx context.Context, rule *models.MonitorRule) error {
+	r.rules[rule.ID] = *rule
+	return nil
+}
+
+// blockingRules holds every ListActive call until its context is canceled, like a
+// scan in flight
+type blockingRules struct {
+	repository.RuleRepo
+
+	listing chan struct{}
+}
+
+func (r *blockingRules) ListActive(ctx context.Context) ([]models.MonitorRule, error) {
+	r.listing <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// memoryStaging is an in-memory StagingRepo covering what dry runs use
+type memoryStaging struct {
+	repository.StagingRepo
+
+	staged []models.StagedResult
+}
+
+func (s *memoryStaging) Stage(ctx context.Context, results []models.StagedResult) ([]models.StagedResult, error) {
+	var fresh []models.StagedResult
+	for _, result := range results {
+		known := false
+		for _, staged := range s.staged {
+			known = known || (staged.RuleID == result.RuleID && staged.RepoFullName == result.RepoFullName && staged.FilePath == result.FilePath)
+		}
+		if !known {
+			result.ID = uint(len(s.staged) + 1)
+			s.staged = append(s.staged, result)
+			fresh = append(fresh, result)
+		}
+	}
+	return fresh, nil
+}
+
+// memorySamples is an in-memory SampleRepo covering what sample_only rules use
+type memorySamples struct {
+	repository.SampleRepo
+
+	samples []models.RuleSample
+}
+
+func (s *memorySamples) Add(ctx context.Context, sample *models.RuleSample) error {
+	sample.ID = uint(len(s.samples) + 1)
+	s.samples = append(s.samples, *sample)
+	return nil
+}
+
+// memoryStore is an in-memory Store covering what evidence uses
+type memoryStore struct {
+	storage.Store
+
+	objects map[string][]byte
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if s.objects == nil {
+		s.objects = make(map[string][]byte)
+	}
+	s.objects[key] = data
+	return nil
+}
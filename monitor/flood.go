@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github-monitor/db/models"
+	"github-monitor/i18n"
+	"github-monitor/notify"
+	"github-monitor/reporting"
+	"github-monitor/repository"
+)
+
+// SetMaxNewResults sets how many new results a scan of a rule may record, rules
+// with a limit of their own keep it. 0 disables the limit.
+func (m *MonitorService) SetMaxNewResults(max int) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.maxNewResults = max
+}
+
+// newResultsLimit returns the new results a scan of a rule may record, 0 for no limit
+func (m *MonitorService) newResultsLimit(rule models.MonitorRule) int {
+	if rule.MaxNewResults > 0 {
+		return rule.MaxNewResults
+	}
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.maxNewResults
+}
+
+// holdForReview deactivates a rule whose scan found more new results than it may
+// record and tells its project. Such a flood usually comes from a keyword matching
+// far more than intended, so none of the results are recorded.
+func (m *MonitorService) holdForReview(ctx context.Context, rule models.MonitorRule, found, limit int) {
+	reason := fmt.Sprintf("a scan found %d new results, more than the limit of %d", found, limit)
+	log.Printf("Rule %d (%s) needs review: %s", rule.ID, rule.Name, reason)
+	if m.DryRun() {
+		return
+	}
+	reporting.CaptureError(fmt.Errorf("rule %d needs review: %s", rule.ID, reason), reporting.Tags{
+		"component": "monitor",
+		"rule_id":   fmt.Sprint(rule.ID),
+		"rule":      rule.Name,
+	})
+
+	// The rule may have been edited or deactivated during the scan
+	current, err := m.repos.Rules.Get(ctx, rule.ID)
+	if err != nil {
+		log.Printf("Failed to hold rule %d for review: %v", rule.ID, err)
+		return
+	}
+	if current.NeedsReview {
+		return
+	}
+	// Built-in rules stay inactive while they run, they only have the flood dropped
+	if current.IsActive {
+		current.IsActive = false
+		current.PausedReason = reason
+		current.NeedsReview = true
+		ctx = repository.WithNote(repository.WithActor(ctx, "monitor"), "held for review, "+reason)
+		if err := m.repos.Rules.Save(ctx, current); err != nil {
+			log.Printf("Failed to hold rule %d for review: %v", rule.ID, err)
+			return
+		}
+	}
+	if !m.notificationsEnabled() {
+		return
+	}
+
+	message := notify.Message{
+		TitleText:   i18n.Format("Rule needs review: %s", rule.Name),
+		ContentText: i18n.Format("A scan of rule **%s** found %d new results, more than the limit of %d. None of them were recorded.", rule.Name, found, limit),
+	}
+	if current.NeedsReview {
+		message.ContentText = i18n.Format("A scan of rule **%s** found %d new results, more than the limit of %d. None of them were recorded. The rule was deactivated, narrow its keywords or raise its max_new_results, then re-activate it.", rule.Name, found, limit)
+	}
+	m.broadcast(*current, message, nil, func(config *models.NotificationConfig) bool {
+		return config.ProjectID == rule.ProjectID
+	})
+}
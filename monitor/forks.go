@@ -0,0 +1,269 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/repository"
+
+	"gorm.io/gorm"
+)
+
+// forkRuleName is the built-in rule suspicious changes of forks are recorded under
+const forkRuleName = "Forks of our repositories"
+
+// ForkWatch lists our public repositories whose forks are checked
+type ForkWatch struct {
+	Repositories []string // owner/name
+	MaxForks     int      // newest forks checked per repository
+	Severity     string
+	Interval     time.Duration // between checks, they run with the scan cycle
+}
+
+// SetForkWatch enables the fork check, a watch without repositories disables it
+func (m *MonitorService) SetForkWatch(watch ForkWatch) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.forkWatch = watch
+}
+
+func (m *MonitorService) getForkWatch() ForkWatch {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.forkWatch
+}
+
+// forkPushed reports whether a fork was pushed to since it was forked and since
+// this instance last compared it
+func (m *MonitorService) forkPushed(fork github.Repository) bool {
+	if !fork.PushedAt.After(fork.CreatedAt) {
+		return false
+	}
+	m.forkMu.Lock()
+	defer m.forkMu.Unlock()
+	return fork.PushedAt.After(m.forkPushes[fork.FullName])
+}
+
+func (m *MonitorService) rememberForkPush(fork github.Repository) {
+	m.forkMu.Lock()
+	defer m.forkMu.Unlock()
+	if m.forkPushes == nil {
+		m.forkPushes = make(map[string]time.Time)
+	}
+	m.forkPushes[fork.FullName] = fork.PushedAt
+}
+
+// scanForks compares the forks of our repositories that were pushed to with their
+// upstream. Lines the forks added are matched against the active rules, to find
+// secrets committed to a fork, and changes of CI workflows, build files or
+// download-and-run code are recorded under the fork rule: such forks can pass off
+// malicious builds as ours. Unless forced, the check waits for the interval since
+// the previous one.
+func (m *MonitorService) scanForks(ctx context.Context, force bool) error {
+	watch := m.getForkWatch()
+	if len(watch.Repositories) == 0 || m.searchService == nil {
+		return nil
+	}
+
+	forkRule, err := m.forkRule(ctx, watch)
+	if err != nil {
+		log.Printf("Failed to prepare the fork rule: %v", err)
+		return err
+	}
+	if !force {
+		last, err := m.repos.History.ListAfter(ctx, repository.HistoryFilter{RuleID: forkRule.ID}, 0, 1)
+		if err != nil {
+			return err
+		}
+		if len(last) > 0 && time.Since(last[0].CreatedAt) < watch.Interval {
+			return nil
+		}
+	}
+	rules, err := m.repos.Rules.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	var (
+		suspicious []*github.SearchResultItem
+		matches    = make(map[uint][]*github.SearchResultItem)
+		failed     []string
+		compared   int
+	)
+	for _, name := range watch.Repositories {
+		upstream, err := m.searchService.GetRepository(ctx, name)
+		if err != nil {
+			log.Printf("Fork check of %s failed: %v", name, err)
+			failed = append(failed, err.Error())
+			continue
+		}
+		forks, err := m.searchService.ListForks(ctx, name, watch.MaxForks)
+		if err != nil {
+			log.Printf("Fork check of %s failed: %v", name, err)
+			failed = append(failed, err.Error())
+			continue
+		}
+
+		for _, fork := range forks {
+			if !m.forkPushed(fork) {
+				continue
+			}
+			changes, err := m.searchService.CompareFork(ctx, upstream, fork)
+			if err != nil {
+				log.Printf("Fork check of %s: %v", fork.FullName, err)
+				continue
+			}
+			m.rememberForkPush(fork)
+			compared++
+			if changes.AheadBy == 0 {
+				continue
+			}
+
+			for _, file := range changes.Files {
+				if reason, snippet := github.SuspiciousChange(file); reason != "" {
+					suspicious = append(suspicious, forkItem(fork, file, reason, snippet))
+				}
+			}
+			for _, rule := range rules {
+				matches[rule.ID] = append(matches[rule.ID], forkMatches(rule, fork, changes)...)
+			}
+		}
+	}
+
+	total := 0
+	for _, rule := range rules {
+		if len(matches[rule.ID]) == 0 {
+			continue
+		}
+		newResults := m.saveResults(ctx, rule, matches[rule.ID])
+		if len(newResults) > 0 {
+			m.notifyNewResults(rule, newResults)
+		}
+		total += len(newResults)
+	}
+
+	newResults := m.saveResults(ctx, *forkRule, suspicious)
+	if len(newResults) > 0 {
+		m.notifyNewResults(*forkRule, newResults)
+	}
+
+	duration := int(time.Since(startTime).Seconds())
+	log.Printf("Fork check completed: %d forks compared, %d suspicious changes, %d new results, took %d seconds",
+		compared, len(suspicious), total+len(newResults), duration)
+
+	if len(failed) == len(watch.Repositories) {
+		err := errors.New(strings.Join(failed, "; "))
+		m.recordScanHistory(ctx, *forkRule, 0, 0, "", "failed", err.Error(), duration)
+		return err
+	}
+	m.recordScanHistory(ctx, *forkRule, len(suspicious), len(newResults), "", "success", strings.Join(failed, "; "), duration)
+	return nil
+}
+
+// forkMatches matches the lines a fork added against the keywords of a rule
+func forkMatches(rule models.MonitorRule, fork github.Repository, changes *github.ForkChanges) []*github.SearchResultItem {
+	keywords, err := github.ParseKeywords(rule.Keywords)
+	if err != nil {
+		log.Printf("Failed to parse keywords for rule %d: %v", rule.ID, err)
+		return nil
+	}
+	excludeExts, err := github.ParseExcludeExts(rule.ExcludeExts)
+	if err != nil {
+		excludeExts = []string{}
+	}
+
+	var items []*github.SearchResultItem
+	for _, file := range changes.Files {
+		if file.Added == "" || github.IsExcluded(file.Path, excludeExts) || !github.MatchPath(file.Path, keywords) {
+			continue
+		}
+		matched, snippet := github.MatchContent(file.Added, keywords, rule.QueryDialect)
+		if matched == nil {
+			continue
+		}
+		items = append(items, &github.SearchResultItem{
+			RepoFullName:    fork.FullName,
+			RepoURL:         fork.HTMLURL,
+			FilePath:        file.Path,
+			FileURL:         file.HTMLURL,
+			HTMLURL:         file.HTMLURL,
+			MatchedKeywords: matched,
+			ContentSnippet:  snippet,
+			Content:         file.Added,
+			Score:           1.0,
+			CreatedAt:       time.Now(),
+			Source:          models.SourceForkWatch,
+			Verified:        true,
+		})
+	}
+	return items
+}
+
+// forkRule returns the rule suspicious fork changes belong to, creating it on
+// first use. It stays inactive so the regular scan doesn't search code with it, and
+// follows the configured repositories and severity.
+func (m *MonitorService) forkRule(ctx context.Context, watch ForkWatch) (*models.MonitorRule, error) {
+	keywords, err := json.Marshal(watch.Repositories)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := m.repos.Rules.GetByName(ctx, forkRuleName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		rule = &models.MonitorRule{
+			Name:        forkRuleName,
+			Description: "Forks of our public repositories that changed CI workflows or build files, or added code that downloads and runs something. Managed through the fork_watch section of config.yaml.",
+			Keywords:    string(keywords),
+			MatchType:   "fuzzy",
+			Severity:    watch.Severity,
+		}
+		if err := m.repos.Rules.Create(ctx, rule); err != nil {
+			return nil, err
+		}
+		// is_active has a database default of true, which Create applies to false
+		rule.IsActive = false
+		return rule, m.repos.Rules.Save(ctx, rule)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.Keywords != string(keywords) || rule.Severity != watch.Severity {
+		rule.Keywords = string(keywords)
+		rule.Severity = watch.Severity
+		if err := m.repos.Rules.Save(ctx, rule); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
+
+// forkItem turns a suspicious change of a fork into a result, keyed by fork and file
+func forkItem(fork github.Repository, file github.ChangedFile, reason, added string) *github.SearchResultItem {
+	snippet := fmt.Sprintf("Fork pushed %s: %s", fork.PushedAt.UTC().Format("2006-01-02"), reason)
+	if added != "" {
+		snippet += "\n" + added
+	}
+
+	return &github.SearchResultItem{
+		RepoFullName:    fork.FullName,
+		RepoURL:         fork.HTMLURL,
+		FilePath:        file.Path,
+		FileURL:         file.HTMLURL,
+		HTMLURL:         file.HTMLURL,
+		MatchedKeywords: []string{reason},
+		ContentSnippet:  snippet,
+		Content:         file.Added,
+		Score:           1.0,
+		CreatedAt:       time.Now(),
+		Source:          models.SourceForkWatch,
+	}
+}
@@ -0,0 +1,329 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/redact"
+	"github-monitor/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// gistRuleName is the built-in rule secrets pasted into gists are recorded under
+	gistRuleName = "Gists of tracked users"
+	// maxGistRevisions caps the revisions of a gist read per check
+	maxGistRevisions = 10
+	// maxSecretLines caps the lines quoted in the snippet of a pasted secret
+	maxSecretLines = 5
+)
+
+// GistWatch lists the users whose public gists are checked
+type GistWatch struct {
+	Users    []string
+	MaxGists int // most recently created gists checked per user
+	Severity string
+	Interval time.Duration // between checks, they run with the scan cycle
+}
+
+// gistSeen is how far a gist was checked
+type gistSeen struct {
+	updatedAt time.Time
+	version   string // newest revision checked
+}
+
+// SetGistWatch enables the gist check, a watch without users disables it
+func (m *MonitorService) SetGistWatch(watch GistWatch) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.gistWatch = watch
+}
+
+func (m *MonitorService) getGistWatch() GistWatch {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.gistWatch
+}
+
+func (m *MonitorService) gistChecked(gist github.Gist) (gistSeen, bool) {
+	m.gistMu.Lock()
+	defer m.gistMu.Unlock()
+	seen, ok := m.gists[gist.ID]
+	return seen, ok
+}
+
+func (m *MonitorService) rememberGist(gist github.Gist, version string) {
+	m.gistMu.Lock()
+	defer m.gistMu.Unlock()
+	if m.gists == nil {
+		m.gists = make(map[string]gistSeen)
+	}
+	m.gists[gist.ID] = gistSeen{updatedAt: gist.UpdatedAt, version: version}
+}
+
+// scanGists reads the revisions of the public gists of the tracked users that
+// changed since the previous check. Lines each revision added are matched against
+// the active rules, and lines that look like secrets are recorded under the gist
+// rule: people paste configs into gists "temporarily" and forget them. Unless
+// forced, the check waits for the interval since the previous one.
+func (m *MonitorService) scanGists(ctx context.Context, force bool) error {
+	watch := m.getGistWatch()
+	if len(watch.Users) == 0 || m.searchService == nil {
+		return nil
+	}
+
+	gistRule, err := m.gistRule(ctx, watch)
+	if err != nil {
+		log.Printf("Failed to prepare the gist rule: %v", err)
+		return err
+	}
+	if !force {
+		last, err := m.repos.History.ListAfter(ctx, repository.HistoryFilter{RuleID: gistRule.ID}, 0, 1)
+		if err != nil {
+			return err
+		}
+		if len(last) > 0 && time.Since(last[0].CreatedAt) < watch.Interval {
+			return nil
+		}
+	}
+	rules, err := m.repos.Rules.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	var (
+		secrets   []*github.SearchResultItem
+		matches   = make(map[uint][]*github.SearchResultItem)
+		failed    []string
+		revisions int
+	)
+	for _, user := range watch.Users {
+		gists, err := m.searchService.ListGists(ctx, user, watch.MaxGists)
+		if err != nil {
+			log.Printf("Gist check of %s failed: %v", user, err)
+			failed = append(failed, err.Error())
+			continue
+		}
+
+		for _, gist := range gists {
+			seen, checked := m.gistChecked(gist)
+			if checked && !gist.UpdatedAt.After(seen.updatedAt) {
+				continue
+			}
+			changes, newest, err := m.gistChanges(ctx, gist, seen.version)
+			if err != nil {
+				log.Printf("Gist check of %s: %v", gist.HTMLURL, err)
+				continue
+			}
+			m.rememberGist(gist, newest)
+			revisions += len(changes)
+
+			for _, change := range changes {
+				secrets = append(secrets, gistSecrets(gist, change)...)
+				for _, rule := range rules {
+					matches[rule.ID] = append(matches[rule.ID], gistMatches(rule, gist, change)...)
+				}
+			}
+		}
+	}
+
+	total := 0
+	for _, rule := range rules {
+		if len(matches[rule.ID]) == 0 {
+			continue
+		}
+		newResults := m.saveResults(ctx, rule, matches[rule.ID])
+		if len(newResults) > 0 {
+			m.notifyNewResults(rule, newResults)
+		}
+		total += len(newResults)
+	}
+
+	newResults := m.saveResults(ctx, *gistRule, secrets)
+	if len(newResults) > 0 {
+		m.notifyNewResults(*gistRule, newResults)
+	}
+
+	duration := int(time.Since(startTime).Seconds())
+	log.Printf("Gist check completed: %d revisions read, %d pasted secrets, %d new results, took %d seconds",
+		revisions, len(secrets), total+len(newResults), duration)
+
+	if len(failed) == len(watch.Users) {
+		err := errors.New(strings.Join(failed, "; "))
+		m.recordScanHistory(ctx, *gistRule, 0, 0, "", "failed", err.Error(), duration)
+		return err
+	}
+	m.recordScanHistory(ctx, *gistRule, len(secrets), len(newResults), "", "success", strings.Join(failed, "; "), duration)
+	return nil
+}
+
+// gistChange is what a revision of a gist added, by file
+type gistChange struct {
+	version string
+	added   map[string]string
+}
+
+// gistChanges returns what the revisions after the checked version added, oldest
+// first, together with the newest version. A gist seen for the first time has its
+// last maxGistRevisions revisions read.
+func (m *MonitorService) gistChanges(ctx context.Context, gist github.Gist, checked string) ([]gistChange, string, error) {
+	versions, err := m.searchService.ListGistVersions(ctx, gist.ID, maxGistRevisions+1)
+	if err != nil || len(versions) == 0 {
+		return nil, checked, err
+	}
+
+	// versions is newest first, the ones before the checked version are new
+	fresh := versions
+	for i, version := range versions {
+		if version == checked {
+			fresh = versions[:i]
+			break
+		}
+	}
+	if len(fresh) > maxGistRevisions {
+		fresh = fresh[:maxGistRevisions]
+	}
+	if len(fresh) == 0 {
+		return nil, versions[0], nil
+	}
+
+	var before *github.GistRevision
+	if len(fresh) < len(versions) {
+		if before, err = m.searchService.GetGistRevision(ctx, gist.ID, versions[len(fresh)]); err != nil {
+			return nil, checked, err
+		}
+	}
+	changes := make([]gistChange, 0, len(fresh))
+	for i := len(fresh) - 1; i >= 0; i-- {
+		after, err := m.searchService.GetGistRevision(ctx, gist.ID, fresh[i])
+		if err != nil {
+			return nil, checked, err
+		}
+		if added := github.AddedGistLines(before, after); len(added) > 0 {
+			changes = append(changes, gistChange{version: fresh[i], added: added})
+		}
+		before = after
+	}
+	return changes, versions[0], nil
+}
+
+// gistMatches matches the lines a gist revision added against the keywords of a rule
+func gistMatches(rule models.MonitorRule, gist github.Gist, change gistChange) []*github.SearchResultItem {
+	keywords, err := github.ParseKeywords(rule.Keywords)
+	if err != nil {
+		log.Printf("Failed to parse keywords for rule %d: %v", rule.ID, err)
+		return nil
+	}
+	excludeExts, err := github.ParseExcludeExts(rule.ExcludeExts)
+	if err != nil {
+		excludeExts = []string{}
+	}
+
+	var items []*github.SearchResultItem
+	for file, added := range change.added {
+		if github.IsExcluded(file, excludeExts) || !github.MatchPath(file, keywords) {
+			continue
+		}
+		matched, snippet := github.MatchContent(added, keywords, rule.QueryDialect)
+		if matched == nil {
+			continue
+		}
+		item := gistItem(gist, change.version, file, added)
+		item.MatchedKeywords = matched
+		item.ContentSnippet = snippet
+		item.Verified = true
+		items = append(items, item)
+	}
+	return items
+}
+
+// gistSecrets turns the files of a gist revision that added secret-looking lines
+// into results of the gist rule
+func gistSecrets(gist github.Gist, change gistChange) []*github.SearchResultItem {
+	var items []*github.SearchResultItem
+	for file, added := range change.added {
+		var lines []string
+		for _, line := range strings.Split(added, "\n") {
+			if len(lines) < maxSecretLines && redact.Secrets(line) != line {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		item := gistItem(gist, change.version, file, added)
+		item.MatchedKeywords = []string{"secret"}
+		item.ContentSnippet = strings.Join(lines, "\n")
+		items = append(items, item)
+	}
+	return items
+}
+
+// gistItem turns a file of a gist revision into a result, keyed by gist, file and
+// revision so later pastes into the same file are new results
+func gistItem(gist github.Gist, version, file, added string) *github.SearchResultItem {
+	revisionURL := gist.HTMLURL + "/" + version
+	short := version
+	if len(short) > 7 {
+		short = short[:7]
+	}
+
+	return &github.SearchResultItem{
+		RepoFullName: gist.Owner + "/" + gist.ID,
+		RepoURL:      gist.HTMLURL,
+		FilePath:     file + "@" + short,
+		FileURL:      revisionURL,
+		HTMLURL:      revisionURL,
+		Content:      added,
+		Score:        1.0,
+		CreatedAt:    time.Now(),
+		Source:       models.SourceGist,
+	}
+}
+
+// gistRule returns the rule pasted secrets belong to, creating it on first use. It
+// stays inactive so the regular scan doesn't search code with it, and follows the
+// configured users and severity.
+func (m *MonitorService) gistRule(ctx context.Context, watch GistWatch) (*models.MonitorRule, error) {
+	keywords, err := json.Marshal(watch.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := m.repos.Rules.GetByName(ctx, gistRuleName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		rule = &models.MonitorRule{
+			Name:        gistRuleName,
+			Description: "Secret-looking lines added to the public gists of tracked users. Managed through the gist_watch section of config.yaml.",
+			Keywords:    string(keywords),
+			MatchType:   "fuzzy",
+			Severity:    watch.Severity,
+		}
+		if err := m.repos.Rules.Create(ctx, rule); err != nil {
+			return nil, err
+		}
+		// is_active has a database default of true, which Create applies to false
+		rule.IsActive = false
+		return rule, m.repos.Rules.Save(ctx, rule)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.Keywords != string(keywords) || rule.Severity != watch.Severity {
+		rule.Keywords = string(keywords)
+		rule.Severity = watch.Severity
+		if err := m.repos.Rules.Save(ctx, rule); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/events"
+	"github-monitor/i18n"
+	"github-monitor/notify"
+
+	"gorm.io/gorm"
+)
+
+// honeytokenTriggered records new results of a honeytoken rule as triggers of the
+// honeytoken and alerts every channel of the project about them, reporting
+// whether the rule belongs to a honeytoken
+func (m *MonitorService) honeytokenTriggered(rule models.MonitorRule, results []models.SearchResult) bool {
+	if m.repos.Honeytokens == nil {
+		return false
+	}
+
+	ctx := context.Background()
+	token, err := m.repos.Honeytokens.GetByRule(ctx, rule.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to look up the honeytoken of rule %d: %v", rule.ID, err)
+		return false
+	}
+
+	now := time.Now()
+	if token.TriggeredAt == nil {
+		token.TriggeredAt = &now
+	}
+	token.Triggers += len(results)
+	if err := m.repos.Honeytokens.Save(ctx, token); err != nil {
+		log.Printf("Failed to record the trigger of honeytoken %d: %v", token.ID, err)
+	}
+	log.Printf("Honeytoken %q triggered by %d results", token.Name, len(results))
+	events.PublishTo(token.ProjectID, events.TypeHoneytokenTriggered, token)
+
+	if !m.notificationsEnabled() {
+		return true
+	}
+	// Another instance may have saved and notified the same files
+	results = m.dedupNotifications(ctx, rule, results)
+	if len(results) == 0 {
+		return true
+	}
+
+	message := notify.Message{
+		TitleText: i18n.Format("Honeytoken triggered: %s", token.Name),
+		ContentText: i18n.Format("The canary %s credential **%s** was found in %d places. It was only planted to detect leaks, so the place it was planted in (%s) has leaked:\n",
+			token.Kind, token.Name, len(results), plantedAt(token)),
+		URL: results[0].HTMLURL,
+	}
+	// Every channel of the project, also the ones that don't notify about new results
+	m.broadcast(rule, message, results, func(config *models.NotificationConfig) bool {
+		return config.ProjectID == rule.ProjectID
+	})
+	return true
+}
+
+func plantedAt(token *models.Honeytoken) string {
+	if token.Note == "" {
+		return "no note"
+	}
+	return token.Note
+}
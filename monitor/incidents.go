@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+
+	"gorm.io/gorm"
+)
+
+// SetIncidentGrouping sets what new results are grouped into incidents by, in order
+// of preference: models.GroupFingerprint, GroupRepository or GroupOwner. Nil stops
+// grouping.
+func (m *MonitorService) SetIncidentGrouping(groupBy []string) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.groupBy = groupBy
+}
+
+func (m *MonitorService) getIncidentGrouping() []string {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.groupBy
+}
+
+// fingerprintSource is the text the fingerprint of a result is taken from, the
+// whole file when it was fetched
+func fingerprintSource(result *github.SearchResultItem) string {
+	if result.Content != "" {
+		return result.Content
+	}
+	return result.ContentSnippet
+}
+
+// groupIncident adds a new result to the open incident of its project that has a
+// result in common with it, by the first grouping that finds one, or opens an
+// incident for it. Results that none of the groupings apply to stay on their own.
+func (m *MonitorService) groupIncident(ctx context.Context, result *models.SearchResult) {
+	groupBy := m.getIncidentGrouping()
+	if len(groupBy) == 0 || m.repos.Incidents == nil {
+		return
+	}
+
+	var (
+		firstBy, firstValue string
+		incident            *models.Incident
+	)
+	for _, by := range groupBy {
+		value := groupValue(by, result)
+		if value == "" {
+			continue
+		}
+		if firstBy == "" {
+			firstBy, firstValue = by, value
+		}
+		found, err := m.repos.Incidents.FindOpen(ctx, result.ProjectID, by, value)
+		if err == nil {
+			incident = found
+			break
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to look up the incident of result %d: %v", result.ID, err)
+			return
+		}
+	}
+	if firstBy == "" {
+		return
+	}
+
+	if incident == nil {
+		incident = &models.Incident{
+			Title:      incidentTitle(firstBy, firstValue, result),
+			Status:     "pending",
+			GroupBy:    firstBy,
+			GroupValue: firstValue,
+			Severity:   result.Severity,
+			ProjectID:  result.ProjectID,
+		}
+		if err := m.repos.Incidents.Create(ctx, incident); err != nil {
+			log.Printf("Failed to open an incident for result %d: %v", result.ID, err)
+			return
+		}
+		m.incidentEvent(ctx, incident.ID, models.IncidentCreated, fmt.Sprintf("Grouped by %s %s", firstBy, firstValue))
+	}
+
+	if err := m.repos.Incidents.MoveResults(ctx, incident.ID, []uint{result.ID}); err != nil {
+		log.Printf("Failed to add result %d to incident %d: %v", result.ID, incident.ID, err)
+		return
+	}
+	result.IncidentID = &incident.ID
+	m.incidentEvent(ctx, incident.ID, models.IncidentResultsAdded,
+		fmt.Sprintf("Result #%d: %s/%s", result.ID, result.RepoFullName, result.FilePath))
+}
+
+func (m *MonitorService) incidentEvent(ctx context.Context, incidentID uint, eventType, detail string) {
+	event := models.IncidentEvent{IncidentID: incidentID, Type: eventType, Detail: detail}
+	if err := m.repos.Incidents.AddEvent(ctx, &event); err != nil {
+		log.Printf("Failed to record the timeline of incident %d: %v", incidentID, err)
+	}
+}
+
+// groupValue returns what a result shares with the others of its incident under a
+// grouping, empty when the grouping doesn't apply to it
+func groupValue(groupBy string, result *models.SearchResult) string {
+	switch groupBy {
+	case models.GroupFingerprint:
+		return result.Fingerprint
+	case models.GroupRepository:
+		return result.RepoFullName
+	case models.GroupOwner:
+		if owner, _, ok := strings.Cut(result.RepoFullName, "/"); ok {
+			return owner
+		}
+	}
+	return ""
+}
+
+func incidentTitle(groupBy, value string, result *models.SearchResult) string {
+	switch groupBy {
+	case models.GroupFingerprint:
+		return fmt.Sprintf("Leaked line of %s/%s", result.RepoFullName, result.FilePath)
+	case models.GroupOwner:
+		return "Leaks by " + value
+	default:
+		return "Leaks in " + value
+	}
+}
@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+)
+
+// maxIncompleteReruns caps how often the pages of an incomplete search are re-run
+const maxIncompleteReruns = 3
+
+// incompleteSearch are the code search pages of a rule GitHub answered with
+// incomplete results
+type incompleteSearch struct {
+	pages    []int
+	attempts int // re-runs so far
+}
+
+// SetRerunIncomplete enables re-running the code search pages GitHub answered with
+// incomplete results in the next scan cycles
+func (m *MonitorService) SetRerunIncomplete(rerun bool) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.rerunIncomplete = rerun
+}
+
+func (m *MonitorService) getRerunIncomplete() bool {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.rerunIncomplete
+}
+
+// queueIncomplete remembers incomplete pages of a rule for the next scan cycle. A
+// later scan of the rule replaces them.
+func (m *MonitorService) queueIncomplete(ruleID uint, search incompleteSearch) {
+	if !m.getRerunIncomplete() {
+		return
+	}
+	m.incompleteMu.Lock()
+	defer m.incompleteMu.Unlock()
+	if m.incomplete == nil {
+		m.incomplete = make(map[uint]incompleteSearch)
+	}
+	m.incomplete[ruleID] = search
+}
+
+func (m *MonitorService) takeIncomplete() map[uint]incompleteSearch {
+	m.incompleteMu.Lock()
+	defer m.incompleteMu.Unlock()
+	queued := m.incomplete
+	m.incomplete = nil
+	return queued
+}
+
+// rerunIncompleteSearches searches the pages queued by the previous scans again, so
+// results GitHub skipped under load are picked up. Pages that stay incomplete are
+// queued again up to maxIncompleteReruns times.
+func (m *MonitorService) rerunIncompleteSearches(ctx context.Context) {
+	for ruleID, search := range m.takeIncomplete() {
+		rule, err := m.repos.Rules.Get(ctx, ruleID)
+		if err != nil || !rule.IsActive {
+			continue
+		}
+		if err := m.rerunPages(ctx, *rule, search); err != nil {
+			log.Printf("Re-run of incomplete pages of rule %d failed: %v", ruleID, err)
+		}
+	}
+}
+
+func (m *MonitorService) rerunPages(ctx context.Context, rule models.MonitorRule, search incompleteSearch) error {
+	startTime := time.Now()
+	keywords, err := github.ParseKeywords(rule.Keywords)
+	if err != nil {
+		return err
+	}
+
+	searchOpts := m.ruleSearchOptions(rule, keywords)
+	searchOpts.Pages = search.pages
+	note := fmt.Sprintf("Re-run of incomplete pages %s", joinPages(search.pages))
+	response, err := m.searchService.SearchWithRetry(ctx, searchOpts, 3)
+	if err != nil {
+		duration := int(time.Since(startTime).Seconds())
+		m.recordScanHistory(ctx, rule, 0, 0, github.TokenOf(err), "failed", note+": "+err.Error(), duration)
+		return err
+	}
+
+	if len(response.IncompletePages) > 0 {
+		if search.attempts+1 < maxIncompleteReruns {
+			m.queueIncomplete(rule.ID, incompleteSearch{pages: response.IncompletePages, attempts: search.attempts + 1})
+		} else {
+			log.Printf("Giving up on incomplete pages %v of rule %d", response.IncompletePages, rule.ID)
+		}
+	}
+
+	newResults := m.saveResults(ctx, rule, response.Items)
+	if len(newResults) > 0 {
+		m.notifyNewResults(rule, newResults)
+	}
+
+	m.recordScanHistoryEntry(ctx, rule, models.ScanHistory{
+		ResultsCount:    len(response.Items),
+		NewResults:      len(newResults),
+		TokenUsed:       response.Token,
+		Status:          "success",
+		ErrorMessage:    note,
+		Duration:        int(time.Since(startTime).Seconds()),
+		IncompletePages: joinPages(response.IncompletePages),
+	})
+	return nil
+}
+
+// joinPages lists pages for the scan history, such as "2,5"
+func joinPages(pages []int) string {
+	parts := make([]string, 0, len(pages))
+	for _, page := range pages {
+		parts = append(parts, strconv.Itoa(page))
+	}
+	return strings.Join(parts, ",")
+}
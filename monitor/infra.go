@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+)
+
+// infraSeverity is the least severity of results leaking internal infrastructure
+const infraSeverity = "high"
+
+// SetInfraDetector fetches the file of every new code search result and looks for
+// internal addresses and hostnames in it, nil disables it
+func (m *MonitorService) SetInfraDetector(detector *github.InfraDetector) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.infra = detector
+}
+
+func (m *MonitorService) getInfraDetector() *github.InfraDetector {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.infra
+}
+
+// detectInfra returns the JSON array of internal addresses and hostnames in the file
+// of a new result and the severity the result gets, at least high when some are
+// found since infrastructure details matter without any credential
+func (m *MonitorService) detectInfra(ctx context.Context, severity string, item *github.SearchResultItem) (string, string) {
+	detector := m.getInfraDetector()
+	if detector == nil || (item.Source != "" && item.Source != models.SourceGitHub) || item.FilePath == "" {
+		return "", severity
+	}
+	if !m.fetchContent(ctx, item) {
+		return "", severity
+	}
+
+	found := detector.Find(item.Content)
+	if len(found) == 0 {
+		return "", severity
+	}
+	encoded, _ := json.Marshal(found)
+	if models.SeverityRank(severity) < models.SeverityRank(infraSeverity) {
+		severity = infraSeverity
+	}
+	return string(encoded), severity
+}
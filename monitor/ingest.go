@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/repository"
+
+	"gorm.io/gorm"
+)
+
+// ingestRulePrefix names the built-in rules findings of external tools are recorded
+// under, one per source and project
+const ingestRulePrefix = "External findings: "
+
+// Ingest records findings submitted by an external tool in a project, under the
+// rule of their source. They go through the same whitelist, dedup and
+// notifications as scan results. It returns the findings that were new.
+func (m *MonitorService) Ingest(ctx context.Context, projectID uint, source string, items []*github.SearchResultItem) ([]models.SearchResult, error) {
+	ctx = repository.WithProjects(ctx, []uint{projectID})
+	rule, err := m.ingestRule(ctx, projectID, source)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		item.Source = source
+	}
+	newResults := m.saveResults(ctx, *rule, items)
+	if len(newResults) > 0 {
+		m.notifyNewResults(*rule, newResults)
+	}
+	return newResults, nil
+}
+
+// ingestRule returns the rule findings of a source are recorded under, creating it
+// on first use. It stays inactive and without keywords so scans never run it.
+func (m *MonitorService) ingestRule(ctx context.Context, projectID uint, source string) (*models.MonitorRule, error) {
+	name := ingestRulePrefix + source
+	rule, err := m.repos.Rules.GetByName(ctx, name)
+	if err == nil {
+		return rule, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	rule = &models.MonitorRule{
+		Name:        name,
+		Description: fmt.Sprintf("Findings submitted by %s through the ingest API.", source),
+		Keywords:    "[]",
+		MatchType:   "fuzzy",
+		Severity:    "medium",
+		ProjectID:   projectID,
+	}
+	if err := m.repos.Rules.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	// is_active has a database default of true, which Create applies to false
+	rule.IsActive = false
+	return rule, m.repos.Rules.Save(ctx, rule)
+}
@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github-monitor/repository"
+)
+
+// knownFiles remembers the files each rule has recorded, so scans only ask the
+// database about files that look new. A rule's files are loaded on its first scan
+// and whole rules are dropped when the cache grows past its limit.
+type knownFiles struct {
+	mu    sync.Mutex
+	rules map[uint]map[repository.FileKey]struct{}
+	size  int // files held across all rules
+	limit int
+}
+
+func newKnownFiles(limit int) *knownFiles {
+	return &knownFiles{
+		rules: make(map[uint]map[repository.FileKey]struct{}),
+		limit: limit,
+	}
+}
+
+// SetKnownCacheSize keeps up to size recorded files in memory for dedup, 0 makes
+// every scan ask the database
+func (m *MonitorService) SetKnownCacheSize(size int) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	if size <= 0 {
+		m.known = nil
+		return
+	}
+	m.known = newKnownFiles(size)
+}
+
+func (m *MonitorService) getKnown() *knownFiles {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.known
+}
+
+// unknown returns the keys the rule hasn't recorded as far as the cache knows,
+// loading the rule's files first if needed
+func (k *knownFiles) unknown(ctx context.Context, results repository.ResultRepo, ruleID uint, keys []repository.FileKey) ([]repository.FileKey, error) {
+	k.mu.Lock()
+	files, ok := k.rules[ruleID]
+	k.mu.Unlock()
+
+	if !ok {
+		loaded, err := results.FileKeys(ctx, ruleID)
+		if err != nil {
+			return nil, err
+		}
+		files = make(map[repository.FileKey]struct{}, len(loaded))
+		for _, key := range loaded {
+			files[key] = struct{}{}
+		}
+
+		k.mu.Lock()
+		if _, raced := k.rules[ruleID]; !raced {
+			k.rules[ruleID] = files
+			k.size += len(files)
+			k.evict(ruleID)
+		}
+		files = k.rules[ruleID]
+		k.mu.Unlock()
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var unknown []repository.FileKey
+	for _, key := range keys {
+		if _, ok := files[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	return unknown, nil
+}
+
+// add records a file for a rule whose files are cached
+func (k *knownFiles) add(ruleID uint, key repository.FileKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	files, ok := k.rules[ruleID]
+	if !ok {
+		return
+	}
+	if _, ok := files[key]; !ok {
+		files[key] = struct{}{}
+		k.size++
+		k.evict(ruleID)
+	}
+}
+
+// evict drops other rules until the cache fits its limit, or the kept rule alone
+// when it's larger than the limit. Callers hold mu.
+func (k *knownFiles) evict(keep uint) {
+	for ruleID, files := range k.rules {
+		if k.size <= k.limit {
+			return
+		}
+		if ruleID == keep {
+			continue
+		}
+		delete(k.rules, ruleID)
+		k.size -= len(files)
+	}
+	if k.size > k.limit {
+		log.Printf("Rule %d has more results than monitor.known_cache_size, checking them in the database", keep)
+		k.size -= len(k.rules[keep])
+		delete(k.rules, keep)
+	}
+}
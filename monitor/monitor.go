@@ -3,105 +3,408 @@ package monitor
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github-monitor/db"
+	"github-monitor/cache"
 	"github-monitor/db/models"
+	"github-monitor/dockerhub"
+	"github-monitor/events"
 	"github-monitor/github"
+	"github-monitor/postman"
+	"github-monitor/registry"
+	"github-monitor/reporting"
+	"github-monitor/repository"
+	"github-monitor/storage"
 )
 
 // MonitorService handles the monitoring logic
 type MonitorService struct {
-	searchService *github.SearchService
-	scanInterval  time.Duration
-	isRunning     bool
-	stopChan      chan bool
+	repos             *repository.Repositories
+	searchService     *github.SearchService
+	scanInterval      time.Duration
+	concurrency       int
+	lifecycleMu       sync.Mutex // serializes Start and Stop, called by the API and the cluster elector
+	running           atomic.Bool
+	cancel            context.CancelFunc // stops the loop and the watchdog, nil while stopped
+	loops             sync.WaitGroup     // the loop and the watchdog while they run
+	intervalChan      chan time.Duration
+	lastHeartbeat     time.Time
+	heartbeatMu       sync.RWMutex
+	settingsMu        sync.RWMutex
+	notifying         sync.WaitGroup    // notifications still being delivered
+	dockerHub         *dockerhub.Client // nil when Docker Hub isn't searched
+	postman           *postman.Client   // nil when Postman isn't searched
+	registry          *registry.Client  // nil when package registries aren't checked
+	registryWatch     RegistryWatch
+	typosquatWatch    TyposquatWatch
+	forkWatch         ForkWatch
+	forkMu            sync.Mutex
+	forkPushes        map[string]time.Time // when forks were pushed to as of their last comparison
+	gistWatch         GistWatch
+	gistMu            sync.Mutex
+	gists             map[string]gistSeen   // how far gists were checked, by ID
+	store             storage.Store         // nil when evidence isn't kept
+	shared            cache.Cache           // nil when running as a single instance
+	known             *knownFiles           // nil when dedup always asks the database
+	rejected          rejectedFiles         // files of precise rules that didn't contain every keyword
+	contextLines      int                   // lines kept around matches of new results, 0 disables
+	infra             *github.InfraDetector // nil when content isn't checked for internal infrastructure
+	dedupWindow       time.Duration
+	groupBy           []string // what results are grouped into incidents by, in order of preference
+	rerunIncomplete   bool
+	scanOnCreate      bool
+	autoPause         AutoPause
+	pageDelay         time.Duration
+	ruleDelay         time.Duration
+	resultChanges     string
+	maxNewResults     int
+	dryRun            bool                 // new results are staged and nothing is notified
+	usage             *github.UsageTracker // nil when token usage isn't forecast
+	exhaustionWarning time.Duration
+	repoInfoMu        sync.Mutex
+	repoInfos         map[string]repoInfo
+	incompleteMu      sync.Mutex
+	incomplete        map[uint]incompleteSearch // code search pages to re-run, by rule
+	progressMu        sync.Mutex
+	progress          scanProgress
 }
 
 // NewMonitorService creates a new monitor service
-func NewMonitorService(searchService *github.SearchService, scanInterval time.Duration) *MonitorService {
+func NewMonitorService(repos *repository.Repositories, searchService *github.SearchService, scanInterval time.Duration) *MonitorService {
 	return &MonitorService{
+		repos:         repos,
 		searchService: searchService,
 		scanInterval:  scanInterval,
-		isRunning:     false,
-		stopChan:      make(chan bool),
+		concurrency:   1,
+		pageDelay:     2 * time.Second,
+		ruleDelay:     5 * time.Second,
+		intervalChan:  make(chan time.Duration, 1),
 	}
 }
 
-// Start starts the monitoring service
-func (m *MonitorService) Start() {
-	if m.isRunning {
+// Start starts the monitoring loop and its watchdog, and reports whether it did:
+// starting a running monitor does nothing
+func (m *MonitorService) Start() bool {
+	m.lifecycleMu.Lock()
+	defer m.lifecycleMu.Unlock()
+	if m.running.Load() {
 		log.Println("Monitor service is already running")
-		return
+		return false
 	}
 
-	m.isRunning = true
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.running.Store(true)
 	log.Println("Monitor service started")
 
-	go m.run()
+	m.loops.Add(2)
+	go func() {
+		defer m.loops.Done()
+		m.watch(ctx)
+	}()
+	go func() {
+		defer m.loops.Done()
+		m.run(ctx)
+	}()
+	return true
 }
 
-// Stop stops the monitoring service
-func (m *MonitorService) Stop() {
-	if !m.isRunning {
-		return
+// Stop cancels the scan in flight and waits for the loop and its watchdog to
+// exit, so a following Start never runs two loops. It reports whether the
+// monitor was running.
+func (m *MonitorService) Stop() bool {
+	m.lifecycleMu.Lock()
+	defer m.lifecycleMu.Unlock()
+	if !m.running.Load() {
+		return false
 	}
 
 	log.Println("Stopping monitor service...")
-	m.stopChan <- true
-	m.isRunning = false
+	m.cancel()
+	m.cancel = nil
+	m.running.Store(false)
+	m.loops.Wait()
 	log.Println("Monitor service stopped")
+	return true
 }
 
 // IsRunning returns whether the monitor is running
 func (m *MonitorService) IsRunning() bool {
-	return m.isRunning
+	return m.running.Load()
+}
+
+// ScanInterval returns the interval between scan cycles
+func (m *MonitorService) ScanInterval() time.Duration {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.scanInterval
+}
+
+// SetScanInterval changes the interval between scan cycles, taking effect immediately
+func (m *MonitorService) SetScanInterval(interval time.Duration) {
+	m.settingsMu.Lock()
+	changed := m.scanInterval != interval
+	m.scanInterval = interval
+	m.settingsMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	// Replace any pending change the loop hasn't picked up yet
+	select {
+	case <-m.intervalChan:
+	default:
+	}
+	m.intervalChan <- interval
+	log.Printf("Scan interval changed to %v", interval)
+}
+
+// SetConcurrency changes how many rules are scanned in parallel, from the next cycle on
+func (m *MonitorService) SetConcurrency(concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	m.settingsMu.Lock()
+	m.concurrency = concurrency
+	m.settingsMu.Unlock()
+}
+
+func (m *MonitorService) getConcurrency() int {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.concurrency
+}
+
+// LastHeartbeat returns when the monitoring loop last showed signs of life
+func (m *MonitorService) LastHeartbeat() time.Time {
+	m.heartbeatMu.RLock()
+	defer m.heartbeatMu.RUnlock()
+	return m.lastHeartbeat
+}
+
+// heartbeat records that the monitoring loop is alive
+func (m *MonitorService) heartbeat() {
+	m.heartbeatMu.Lock()
+	m.lastHeartbeat = time.Now()
+	m.heartbeatMu.Unlock()
 }
 
 // run is the main monitoring loop
-func (m *MonitorService) run() {
-	ticker := time.NewTicker(m.scanInterval)
+func (m *MonitorService) run(ctx context.Context) {
+	interval := m.ScanInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
+	snoozeTicker := time.NewTicker(snoozeCheckInterval)
+	defer snoozeTicker.Stop()
+	pendingTicker := time.NewTicker(pendingScanInterval)
+	defer pendingTicker.Stop()
+
+	m.heartbeat()
+	m.setNextCycle(time.Now().Add(interval))
 
 	// Run initial scan
-	m.scan()
+	m.scan(ctx)
 
 	for {
 		select {
-		case <-ticker.C:
-			m.scan()
-		case <-m.stopChan:
+		case now := <-ticker.C:
+			m.setNextCycle(now.Add(m.ScanInterval()))
+			m.scan(ctx)
+		case <-snoozeTicker.C:
+			m.endSnoozes(ctx)
+		case <-pendingTicker.C:
+			m.scanPendingRules(ctx)
+		case interval := <-m.intervalChan:
+			ticker.Reset(interval)
+			m.setNextCycle(time.Now().Add(interval))
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-// scan performs a single scan of all active rules
-func (m *MonitorService) scan() {
+// scan performs a single scan of all active rules, cut short when ctx is canceled
+func (m *MonitorService) scan(ctx context.Context) {
+	defer reporting.Recover(reporting.Tags{"component": "monitor"})
 	log.Println("Starting monitoring scan...")
-	ctx := context.Background()
+	defer m.startCycle()()
 
 	// Get all active rules
-	var rules []models.MonitorRule
-	if err := db.GetDB().Where("is_active = ?", true).Find(&rules).Error; err != nil {
+	rules, err := m.repos.Rules.ListActive(ctx)
+	if err != nil {
 		log.Printf("Failed to fetch monitor rules: %v", err)
 		return
 	}
 
 	log.Printf("Found %d active monitoring rules", len(rules))
-
-	for _, rule := range rules {
-		m.scanRule(ctx, rule)
-		// Wait between rules to avoid overwhelming the API
-		time.Sleep(5 * time.Second)
+	m.scanRules(ctx, rules, true)
+	m.rerunIncompleteSearches(ctx)
+	if release, ok := m.claimScan(ctx, "registries"); ok {
+		if err := m.scanRegistries(ctx); err != nil {
+			release()
+		}
+	}
+	if release, ok := m.claimScan(ctx, "typosquats"); ok {
+		if err := m.scanTyposquats(ctx, false); err != nil {
+			release()
+		}
+	}
+	if release, ok := m.claimScan(ctx, "forks"); ok {
+		if err := m.scanForks(ctx, false); err != nil {
+			release()
+		}
+	}
+	if release, ok := m.claimScan(ctx, "gists"); ok {
+		if err := m.scanGists(ctx, false); err != nil {
+			release()
+		}
 	}
 
+	m.heartbeat()
 	log.Println("Monitoring scan completed")
 }
 
+// ScanOnce scans a single rule, or every active rule, the package registries, the
+// lookalike repositories, the forks of our repositories and the gists of tracked
+// users when ruleID is 0, outside the monitoring loop. It returns
+// once the scans and their notifications are done and reports the rules that failed.
+func (m *MonitorService) ScanOnce(ctx context.Context, ruleID uint) error {
+	var rules []models.MonitorRule
+	if ruleID > 0 {
+		rule, err := m.repos.Rules.Get(ctx, ruleID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch rule %d: %w", ruleID, err)
+		}
+		rules = append(rules, *rule)
+	} else {
+		active, err := m.repos.Rules.ListActive(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to fetch monitor rules: %w", err)
+		}
+		rules = active
+	}
+
+	errs := m.scanRules(ctx, rules, false)
+	if ruleID == 0 {
+		if err := m.scanRegistries(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("package registries: %w", err))
+		}
+		if err := m.scanTyposquats(ctx, true); err != nil {
+			errs = append(errs, fmt.Errorf("lookalike repositories: %w", err))
+		}
+		if err := m.scanForks(ctx, true); err != nil {
+			errs = append(errs, fmt.Errorf("forks: %w", err))
+		}
+		if err := m.scanGists(ctx, true); err != nil {
+			errs = append(errs, fmt.Errorf("gists: %w", err))
+		}
+	}
+	m.notifying.Wait()
+	return errors.Join(errs...)
+}
+
+// scanRules scans the rules with a bounded number of workers and returns the failures.
+// Scheduled scans skip rules another instance scanned this cycle and follow the
+// search budget.
+func (m *MonitorService) scanRules(ctx context.Context, rules []models.MonitorRule, scheduled bool) []error {
+	var pages map[uint]int
+	if scheduled {
+		rules, pages = m.budgetRules(rules)
+	}
+
+	sem := make(chan struct{}, m.getConcurrency())
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	m.queueRules(len(rules))
+	for i, rule := range rules {
+		if ctx.Err() != nil {
+			// Stopped, the rules left wait for the next scan
+			m.queueRules(-(len(rules) - i))
+			mu.Lock()
+			errs = append(errs, ctx.Err())
+			mu.Unlock()
+			break
+		}
+		sem <- struct{}{}
+		m.queueRules(-1)
+		wg.Add(1)
+
+		ruleCtx := ctx
+		if pages != nil {
+			ruleCtx = withPages(ctx, pages[rule.ID])
+		}
+
+		go func(ctx context.Context, rule models.MonitorRule, last bool) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			m.heartbeat()
+			release := func() {}
+			if scheduled {
+				var ok bool
+				if release, ok = m.claimScan(ctx, fmt.Sprintf("rule:%d", rule.ID)); !ok {
+					log.Printf("Skipping rule %d, another instance scanned it this cycle", rule.ID)
+					return
+				}
+			}
+			done := m.startRule(rule)
+			err := m.safeScanRule(ctx, rule)
+			done()
+			m.checkAutoPause(ctx, rule)
+			if err != nil {
+				release()
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("rule %d (%s): %w", rule.ID, rule.Name, err))
+				mu.Unlock()
+			}
+			// Wait between rules to avoid overwhelming the API
+			if !last {
+				github.Sleep(ctx, m.ruleDelays(rule).next)
+			}
+		}(ruleCtx, rule, i == len(rules)-1)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+// safeScanRule scans a rule, turning a panic into a failed scan so the other rules
+// and later scans carry on
+func (m *MonitorService) safeScanRule(ctx context.Context, rule models.MonitorRule) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = reporting.Panic(value, reporting.Tags{
+				"component": "monitor",
+				"rule_id":   fmt.Sprint(rule.ID),
+				"rule":      rule.Name,
+			})
+			m.recordScanHistory(ctx, rule, 0, 0, "", "failed", err.Error(), 0)
+		}
+	}()
+	return m.scanRule(ctx, rule)
+}
+
 // scanRule scans a single monitoring rule
-func (m *MonitorService) scanRule(ctx context.Context, rule models.MonitorRule) {
+func (m *MonitorService) scanRule(ctx context.Context, rule models.MonitorRule) error {
+	if rule.ScanPending {
+		m.clearScanPending(ctx, rule.ID)
+	}
+	if rule.QueryError != "" {
+		log.Printf("Skipping rule %d until its query changes, GitHub rejected it: %s", rule.ID, rule.QueryError)
+		return nil
+	}
+
 	startTime := time.Now()
 	log.Printf("Scanning rule: %s (ID: %d)", rule.Name, rule.ID)
 
@@ -109,56 +412,148 @@ func (m *MonitorService) scanRule(ctx context.Context, rule models.MonitorRule)
 	keywords, err := github.ParseKeywords(rule.Keywords)
 	if err != nil {
 		log.Printf("Failed to parse keywords for rule %d: %v", rule.ID, err)
-		m.recordScanHistory(rule.ID, 0, 0, "", "failed", err.Error(), 0)
-		return
-	}
-
-	// Parse exclude extensions
-	excludeExts, err := github.ParseExcludeExts(rule.ExcludeExts)
-	if err != nil {
-		log.Printf("Failed to parse exclude extensions for rule %d: %v", rule.ID, err)
-		excludeExts = []string{}
+		m.recordScanHistory(ctx, rule, 0, 0, "", "failed", err.Error(), 0)
+		return err
 	}
 
 	// Build search options
-	searchOpts := github.SearchOptions{
-		Keywords:    keywords,
-		MatchType:   rule.MatchType,
-		ExcludeExts: excludeExts,
-		Sort:        "indexed",
-		Order:       "desc",
-	}
+	searchOpts := m.ruleSearchOptions(rule, keywords)
+	searchOpts.MaxPages = pagesOf(ctx)
 
 	// Perform search
-	results, err := m.searchService.SearchWithRetry(ctx, searchOpts, 3)
+	response, err := m.searchService.SearchWithRetry(ctx, searchOpts, 3)
 	if err != nil {
 		log.Printf("Search failed for rule %d: %v", rule.ID, err)
 		status := "failed"
 		if err.Error() == "rate limit exceeded" {
 			status = "rate_limited"
 		}
+		// A rejected query fails the same way on every scan, the rule waits for an edit
+		var invalid *github.InvalidQueryError
+		if errors.As(err, &invalid) {
+			status = "invalid_query"
+			if err := m.repos.Rules.SetQueryError(ctx, rule.ID, invalid.Details); err != nil {
+				log.Printf("Failed to record the query error of rule %d: %v", rule.ID, err)
+			}
+		}
 		duration := int(time.Since(startTime).Seconds())
-		m.recordScanHistory(rule.ID, 0, 0, "", status, err.Error(), duration)
-		return
+		m.recordScanHistory(ctx, rule, 0, 0, github.TokenOf(err), status, err.Error(), duration)
+		return err
 	}
 
-	// Filter results against whitelist
-	filteredResults := m.filterWhitelist(results)
+	if len(response.IncompletePages) > 0 {
+		log.Printf("Search of rule %d was incomplete on pages %v", rule.ID, response.IncompletePages)
+		m.queueIncomplete(rule.ID, incompleteSearch{pages: response.IncompletePages})
+	}
 
 	// Save new results
-	newResultsCount := m.saveResults(rule.ID, filteredResults)
+	newResults := m.saveResults(ctx, rule, response.Items)
+	newResultsCount := len(newResults)
+
+	if newResultsCount > 0 {
+		m.notifyNewResults(rule, newResults)
+	}
+
+	// Docker Hub, Postman and repository metadata run once the code results are
+	// safe, a failure there doesn't fail the scan
+	resultsCount := len(response.Items)
+	if m.dockerHub != nil {
+		found, added := m.scanSource(ctx, rule, "Docker Hub", keywords, m.searchDockerHub)
+		resultsCount += found
+		newResultsCount += added
+	}
+	if m.postman != nil {
+		found, added := m.scanSource(ctx, rule, "Postman", keywords, m.searchPostman)
+		resultsCount += found
+		newResultsCount += added
+	}
+	if rule.SearchRepoMetadata {
+		found, added := m.scanSource(ctx, rule, "Repository metadata", keywords, m.searchRepoMetadata)
+		resultsCount += found
+		newResultsCount += added
+	}
 
 	duration := int(time.Since(startTime).Seconds())
 	log.Printf("Rule %d scan completed: %d results found, %d new results, took %d seconds",
-		rule.ID, len(filteredResults), newResultsCount, duration)
+		rule.ID, resultsCount, newResultsCount, duration)
+
+	m.recordScanHistoryEntry(ctx, rule, models.ScanHistory{
+		ResultsCount:    resultsCount,
+		NewResults:      newResultsCount,
+		TokenUsed:       response.Token,
+		Status:          "success",
+		Duration:        duration,
+		IncompletePages: joinPages(response.IncompletePages),
+	})
+	return nil
+}
 
-	m.recordScanHistory(rule.ID, len(filteredResults), newResultsCount, "", "success", "", duration)
+// ruleSearchOptions returns the code search options of a rule
+func (m *MonitorService) ruleSearchOptions(rule models.MonitorRule, keywords []string) github.SearchOptions {
+	// Parse exclude extensions
+	excludeExts, err := github.ParseExcludeExts(rule.ExcludeExts)
+	if err != nil {
+		log.Printf("Failed to parse exclude extensions for rule %d: %v", rule.ID, err)
+		excludeExts = []string{}
+	}
+	excludeRepos, err := github.ParseExcludeRepos(rule.ExcludeRepos)
+	if err != nil {
+		log.Printf("Failed to parse excluded repositories for rule %d: %v", rule.ID, err)
+		excludeRepos = []string{}
+	}
+
+	return github.SearchOptions{
+		Keywords:     keywords,
+		MatchType:    rule.MatchType,
+		ExcludeExts:  excludeExts,
+		ExcludeRepos: excludeRepos,
+		Dialect:      rule.QueryDialect,
+		RawQuery:     rule.RawQuery,
+		FileSize:     rule.FileSize,
+		Sort:         "indexed",
+		Order:        "desc",
+		TokenGroup:   rule.TokenGroup,
+		PageDelay:    m.ruleDelays(rule).page,
+	}
+}
+
+// scanSource searches one of the other sources for a rule and records its hits like
+// code results. Their content is published by anyone, so a panic matching it only
+// loses that source. It returns the number of hits and of new results.
+func (m *MonitorService) scanSource(ctx context.Context, rule models.MonitorRule, name string, keywords []string,
+	search func(ctx context.Context, keywords []string, dialect string) ([]*github.SearchResultItem, error)) (found, added int) {
+	defer reporting.Recover(reporting.Tags{"component": "monitor", "source": name, "rule_id": fmt.Sprint(rule.ID)})
+
+	items, err := search(ctx, keywords, rule.QueryDialect)
+	if err != nil {
+		log.Printf("%s search failed for rule %d: %v", name, rule.ID, err)
+		return 0, 0
+	}
+
+	newResults := m.saveResults(ctx, rule, items)
+	if len(newResults) > 0 {
+		m.notifyNewResults(rule, newResults)
+	}
+	return len(items), len(newResults)
 }
 
-// filterWhitelist filters results against the whitelist
-func (m *MonitorService) filterWhitelist(results []*github.SearchResultItem) []*github.SearchResultItem {
-	var whitelist []models.Whitelist
-	if err := db.GetDB().Find(&whitelist).Error; err != nil {
+// queryKeyword picks the term sent as query to sources that match loosely: the
+// longest plain one, not a qualifier, regular expression or negated term. Empty
+// when there is none.
+func queryKeyword(keywords []string, dialect string) string {
+	query := ""
+	for _, keyword := range keywords {
+		if term, ok := github.ParseTerm(keyword, dialect); ok && term.Plain() && len(term.Text) > len(query) {
+			query = term.Text
+		}
+	}
+	return query
+}
+
+// filterWhitelist filters results against the whitelist of a project
+func (m *MonitorService) filterWhitelist(ctx context.Context, projectID uint, results []*github.SearchResultItem) []*github.SearchResultItem {
+	whitelist, err := m.repos.Whitelist.List(repository.WithProjects(ctx, []uint{projectID}))
+	if err != nil {
 		log.Printf("Failed to fetch whitelist: %v", err)
 		return results
 	}
@@ -212,57 +607,216 @@ func splitRepoName(fullName string) []string {
 	return parts
 }
 
-// saveResults saves search results to database
-func (m *MonitorService) saveResults(ruleID uint, results []*github.SearchResultItem) int {
-	newCount := 0
+// scoreResults scores results by the weights of their matched keywords and drops
+// the ones below the min score of the rule
+func scoreResults(rule models.MonitorRule, results []*github.SearchResultItem) []*github.SearchResultItem {
+	weights, err := github.ParseKeywordWeights(rule.KeywordWeights)
+	if err != nil {
+		log.Printf("Ignoring invalid keyword weights of rule %d: %v", rule.ID, err)
+		weights = nil
+	}
 
+	kept := make([]*github.SearchResultItem, 0, len(results))
 	for _, result := range results {
-		// Check if result already exists
-		var existingResult models.SearchResult
-		err := db.GetDB().Where("rule_id = ? AND repo_full_name = ? AND file_path = ?",
-			ruleID, result.RepoFullName, result.FilePath).First(&existingResult).Error
+		result.Score = github.ScoreKeywords(result.MatchedKeywords, weights)
+		if result.Score >= rule.MinScore {
+			kept = append(kept, result)
+		}
+	}
 
-		if err != nil {
-			// Result doesn't exist, create new one
-			matchedKeywordsJSON, _ := json.Marshal(result.MatchedKeywords)
-
-			newResult := models.SearchResult{
-				RuleID:          ruleID,
-				RepoFullName:    result.RepoFullName,
-				RepoURL:         result.RepoURL,
-				FilePath:        result.FilePath,
-				FileURL:         result.FileURL,
-				MatchedKeywords: string(matchedKeywordsJSON),
-				ContentSnippet:  result.ContentSnippet,
-				HTMLURL:         result.HTMLURL,
-				Score:           result.Score,
-				Status:          "pending",
-			}
+	if len(kept) < len(results) {
+		log.Printf("Min score %g of rule %d: %d -> %d results", rule.MinScore, rule.ID, len(results), len(kept))
+	}
+	return kept
+}
+
+// saveResults runs the search results through the pipeline of the rule, saves the
+// ones it hasn't recorded yet to database and returns them. The filter stage drops
+// whitelisted, excluded, known and low scoring results, the enrich stage fills in
+// the rest once the new results limit passed.
+func (m *MonitorService) saveResults(ctx context.Context, rule models.MonitorRule, results []*github.SearchResultItem) []models.SearchResult {
+	newResults := make([]models.SearchResult, 0)
+	if len(results) == 0 {
+		return newResults
+	}
+	dryRun := m.DryRun()
+
+	keywords, err := github.ParseKeywords(rule.Keywords)
+	if err != nil {
+		log.Printf("Failed to parse keywords for rule %d: %v", rule.ID, err)
+	}
+	batch := &Batch{Rule: rule, Keywords: keywords, DryRun: dryRun, Items: results}
+	filters, enrichers := pipeline(rule)
+	for _, filter := range filters {
+		if err := filter(ctx, m, batch); err != nil {
+			log.Printf("Dropping the results of rule %d: %v", rule.ID, err)
+			return newResults
+		}
+		if len(batch.Items) == 0 {
+			break
+		}
+	}
+	if rule.SampleOnly {
+		m.recordSample(ctx, rule, batch.Items)
+		return newResults
+	}
+	if len(batch.Items) == 0 {
+		return newResults
+	}
+	if limit := m.newResultsLimit(rule); limit > 0 && len(batch.Items) > limit {
+		m.holdForReview(ctx, rule, len(batch.Items), limit)
+		return newResults
+	}
+
+	batch.Results = make([]models.SearchResult, 0, len(batch.Items))
+	for _, result := range batch.Items {
+		matchedKeywordsJSON, _ := json.Marshal(result.MatchedKeywords)
+		var matches string
+		if len(result.Fragments) > 0 {
+			encoded, _ := json.Marshal(result.Fragments)
+			matches = string(encoded)
+		}
+		severity := rule.Severity
+		if result.Severity != "" {
+			severity = result.Severity
+		}
+		source := result.Source
+		if source == "" {
+			source = models.SourceGitHub
+		}
 
-			if err := db.GetDB().Create(&newResult).Error; err != nil {
-				log.Printf("Failed to save result: %v", err)
-			} else {
-				newCount++
+		batch.Results = append(batch.Results, models.SearchResult{
+			RuleID:          rule.ID,
+			ProjectID:       rule.ProjectID,
+			Source:          source,
+			RepoFullName:    result.RepoFullName,
+			RepoURL:         result.RepoURL,
+			FilePath:        result.FilePath,
+			FileURL:         result.FileURL,
+			MatchedKeywords: string(matchedKeywordsJSON),
+			ContentSnippet:  result.ContentSnippet,
+			Matches:         matches,
+			HTMLURL:         result.HTMLURL,
+			Score:           result.Score,
+			VerifiedMatch:   result.Verified,
+			TokenUsed:       result.Token,
+			Status:          "pending",
+			Severity:        severity,
+			Fingerprint:     github.Fingerprint(fingerprintSource(result), keywords, rule.QueryDialect),
+			ContentHash:     contentHash(result),
+		})
+	}
+	for _, enricher := range enrichers {
+		if err := enricher(ctx, m, batch); err != nil {
+			log.Printf("Dropping the results of rule %d: %v", rule.ID, err)
+			return newResults
+		}
+	}
+
+	knownCache := m.getKnown()
+	for i, result := range batch.Items {
+		newResult := batch.Results[i]
+		// After the enrichers, which may have fetched the file
+		newResult.FileType = github.DetectFileType(result.FilePath, result.Content)
+		if dryRun {
+			newResults = append(newResults, newResult)
+			continue
+		}
+		if m.store != nil {
+			newResult.EvidenceKey = m.storeEvidence(ctx, rule, newResult.Source, result)
+		}
+
+		if err := m.repos.Results.Create(ctx, &newResult); err != nil {
+			log.Printf("Failed to save result: %v", err)
+		} else {
+			m.groupIncident(ctx, &newResult)
+			newResults = append(newResults, newResult)
+			events.PublishTo(rule.ProjectID, events.TypeNewResult, newResult)
+			if knownCache != nil {
+				knownCache.add(rule.ID, repository.FileKey{RepoFullName: result.RepoFullName, FilePath: result.FilePath})
 			}
 		}
 	}
 
-	return newCount
+	if dryRun {
+		return m.stageResults(ctx, rule, newResults)
+	}
+	m.queueWebhooks(ctx, rule, newResults)
+	return newResults
+}
+
+// knownFiles returns which of the files in results the rule has already recorded.
+// With the known files cache only files missing from it are looked up, since
+// another instance or a restored backup may have recorded them meanwhile.
+func (m *MonitorService) knownFiles(ctx context.Context, ruleID uint, results []*github.SearchResultItem) (map[repository.FileKey]bool, error) {
+	keys := make([]repository.FileKey, 0, len(results))
+	for _, result := range results {
+		keys = append(keys, repository.FileKey{RepoFullName: result.RepoFullName, FilePath: result.FilePath})
+	}
+
+	known := make(map[repository.FileKey]bool, len(keys))
+	lookup := keys
+	if knownCache := m.getKnown(); knownCache != nil {
+		unknown, err := knownCache.unknown(ctx, m.repos.Results, ruleID, keys)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			known[key] = true
+		}
+		for _, key := range unknown {
+			delete(known, key)
+		}
+		lookup = unknown
+	}
+	if len(lookup) == 0 {
+		return known, nil
+	}
+
+	// Look up every remaining file in one query instead of one per result
+	repoSet := make(map[string]bool)
+	repoNames := make([]string, 0, len(lookup))
+	for _, key := range lookup {
+		if !repoSet[key.RepoFullName] {
+			repoSet[key.RepoFullName] = true
+			repoNames = append(repoNames, key.RepoFullName)
+		}
+	}
+
+	recorded, err := m.repos.Results.KnownFiles(ctx, ruleID, repoNames)
+	if err != nil {
+		return nil, err
+	}
+	knownCache := m.getKnown()
+	for key := range recorded {
+		known[key] = true
+		if knownCache != nil {
+			knownCache.add(ruleID, key)
+		}
+	}
+	return known, nil
 }
 
 // recordScanHistory records a scan history entry
-func (m *MonitorService) recordScanHistory(ruleID uint, resultsCount, newResults int, tokenUsed, status, errorMsg string, duration int) {
-	history := models.ScanHistory{
-		RuleID:       ruleID,
+func (m *MonitorService) recordScanHistory(ctx context.Context, rule models.MonitorRule, resultsCount, newResults int, tokenUsed, status, errorMsg string, duration int) {
+	m.recordScanHistoryEntry(ctx, rule, models.ScanHistory{
 		ResultsCount: resultsCount,
 		NewResults:   newResults,
 		TokenUsed:    tokenUsed,
 		Status:       status,
 		ErrorMessage: errorMsg,
 		Duration:     duration,
-	}
+	})
+}
 
-	if err := db.GetDB().Create(&history).Error; err != nil {
+// recordScanHistoryEntry records a scan history entry of a rule
+func (m *MonitorService) recordScanHistoryEntry(ctx context.Context, rule models.MonitorRule, history models.ScanHistory) {
+	history.RuleID = rule.ID
+	if err := m.repos.History.Create(ctx, &history); err != nil {
 		log.Printf("Failed to record scan history: %v", err)
+	} else if scanFailed(history.Status) {
+		m.notifyScanFailed(ctx, rule, history)
 	}
+
+	events.PublishTo(rule.ProjectID, events.TypeScanCompleted, history)
 }
@@ -0,0 +1,608 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/repository"
+)
+
+func newTestService(results *memoryResults, whitelist *memoryWhitelist) *MonitorService {
+	return NewMonitorService(&repository.Repositories{Results: results, Whitelist: whitelist}, nil, 0)
+}
+
+func item(repo, path string) *github.SearchResultItem {
+	return &github.SearchResultItem{RepoFullName: repo, FilePath: path}
+}
+
+func TestSaveResultsSkipsKnownAndRepeatedFiles(t *testing.T) {
+	results := &memoryResults{results: []models.SearchResult{
+		{RuleID: 1, RepoFullName: "acme/api", FilePath: ".env"},
+		{RuleID: 2, RepoFullName: "acme/web", FilePath: "config.yml"},
+	}}
+	m := newTestService(results, &memoryWhitelist{})
+	rule := models.MonitorRule{ID: 1, ProjectID: 3, Severity: "high"}
+
+	saved := m.saveResults(context.Background(), rule, []*github.SearchResultItem{
+		item("acme/api", ".env"),       // recorded by this rule before
+		item("acme/web", "config.yml"), // only recorded by another rule
+		item("acme/web", "config.yml"), // repeated within the scan
+		item("acme/api", "main.go"),
+	})
+
+	if len(saved) != 2 {
+		t.Fatalf("saved %d results, want 2: %+v", len(saved), saved)
+	}
+	for _, result := range saved {
+		if result.RuleID != 1 || result.ProjectID != 3 || result.Status != "pending" || result.Severity != "high" {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if result.Source != models.SourceGitHub {
+			t.Errorf("source = %q, want %q", result.Source, models.SourceGitHub)
+		}
+	}
+	if len(results.results) != 4 {
+		t.Errorf("stored %d results, want 4", len(results.results))
+	}
+
+	// A second scan finding the same files records nothing
+	if again := m.saveResults(context.Background(), rule, []*github.SearchResultItem{item("acme/web", "config.yml"), item("acme/api", "main.go")}); len(again) != 0 {
+		t.Errorf("second scan saved %+v, want nothing", again)
+	}
+}
+
+func TestSaveResultsWithKnownFilesCache(t *testing.T) {
+	results := &memoryResults{results: []models.SearchResult{
+		{RuleID: 1, RepoFullName: "acme/api", FilePath: ".env"},
+	}}
+	m := newTestService(results, &memoryWhitelist{})
+	m.SetKnownCacheSize(100)
+	rule := models.MonitorRule{ID: 1}
+
+	if saved := m.saveResults(context.Background(), rule, []*github.SearchResultItem{item("acme/api", ".env"), item("acme/api", "main.go")}); len(saved) != 1 {
+		t.Fatalf("first scan saved %d results, want 1", len(saved))
+	}
+
+	// Files the cache knows about don't need the database
+	lookups := results.lookups
+	if saved := m.saveResults(context.Background(), rule, []*github.SearchResultItem{item("acme/api", ".env"), item("acme/api", "main.go")}); len(saved) != 0 {
+		t.Errorf("second scan saved %+v, want nothing", saved)
+	}
+	if results.lookups != lookups {
+		t.Errorf("second scan looked up the database %d times, want none", results.lookups-lookups)
+	}
+}
+
+func TestFilterWhitelistUsesTheRuleProject(t *testing.T) {
+	whitelist := &memoryWhitelist{entries: []models.Whitelist{
+		{ProjectID: 1, Type: "user", Value: "acme"},
+		{ProjectID: 2, Type: "repo", Value: "other/tool"},
+	}}
+	m := newTestService(&memoryResults{}, whitelist)
+
+	items := []*github.SearchResultItem{item("acme/api", ".env"), item("other/tool", ".env"), item("someone/else", ".env")}
+
+	filtered := m.filterWhitelist(context.Background(), 1, items)
+	if len(filtered) != 2 || filtered[0].RepoFullName != "other/tool" || filtered[1].RepoFullName != "someone/else" {
+		t.Errorf("project 1 kept %v", repoNames(filtered))
+	}
+
+	filtered = m.filterWhitelist(context.Background(), 2, items)
+	if len(filtered) != 2 || filtered[0].RepoFullName != "acme/api" || filtered[1].RepoFullName != "someone/else" {
+		t.Errorf("project 2 kept %v", repoNames(filtered))
+	}
+}
+
+func repoNames(items []*github.SearchResultItem) []string {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.RepoFullName)
+	}
+	return names
+}
+
+func TestSaveResultsVerifiesPreciseRules(t *testing.T) {
+	results := &memoryResults{}
+	m := newTestService(results, &memoryWhitelist{})
+	rule := models.MonitorRule{ID: 1, MatchType: "precise", Keywords: `["acme.internal", "password"]`}
+
+	both := item("acme/api", ".env")
+	both.Content = "DB_HOST=db.acme.internal\nDB_PASSWORD=hunter2"
+	both.SHA = "a"
+	one := item("acme/api", "README.md")
+	one.Content = "See acme.internal for details"
+	one.SHA = "b"
+
+	saved := m.saveResults(context.Background(), rule, []*github.SearchResultItem{both, one})
+	if len(saved) != 1 || saved[0].FilePath != ".env" || !saved[0].VerifiedMatch {
+		t.Fatalf("saved %+v, want only the verified .env", saved)
+	}
+	if !m.rejected.has(rule.ID, repository.FileKey{RepoFullName: "acme/api", FilePath: "README.md"}, "b") {
+		t.Error("the file missing a keyword wasn't remembered")
+	}
+}
+
+func TestSaveResultsFlagsInternalInfrastructure(t *testing.T) {
+	m := newTestService(&memoryResults{}, &memoryWhitelist{})
+	detector, err := github.NewInfraDetector([]string{"10.0.0.0/8"}, []string{"corp.acme.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetInfraDetector(detector)
+	rule := models.MonitorRule{ID: 1, Severity: "low"}
+
+	leak := item("acme/api", "deploy.sh")
+	leak.Content = "ssh deploy@10.1.2.3 && curl https://vault.corp.acme.com"
+	clean := item("acme/api", "main.go")
+	clean.Content = "package main"
+
+	saved := m.saveResults(context.Background(), rule, []*github.SearchResultItem{leak, clean})
+	if len(saved) != 2 {
+		t.Fatalf("saved %d results, want 2", len(saved))
+	}
+	if saved[0].Severity != "high" || saved[0].InfraMatches != `["10.1.2.3","vault.corp.acme.com"]` {
+		t.Errorf("leaking result has severity %q and infra matches %s", saved[0].Severity, saved[0].InfraMatches)
+	}
+	if saved[1].Severity != "low" || saved[1].InfraMatches != "" {
+		t.Errorf("clean result has severity %q and infra matches %s", saved[1].Severity, saved[1].InfraMatches)
+	}
+}
+
+func TestSaveResultsKeepsSeverityOfSource(t *testing.T) {
+	m := newTestService(&memoryResults{}, &memoryWhitelist{})
+	rule := models.MonitorRule{ID: 1, Severity: "medium"}
+
+	rated := item("acme/api", "config.yml")
+	rated.Severity = "critical"
+	unrated := item("acme/api", "main.go")
+
+	saved := m.saveResults(context.Background(), rule, []*github.SearchResultItem{rated, unrated})
+	if len(saved) != 2 {
+		t.Fatalf("saved %d results, want 2", len(saved))
+	}
+	if saved[0].Severity != "critical" || saved[1].Severity != "medium" {
+		t.Errorf("severities are %q and %q, want critical and medium", saved[0].Severity, saved[1].Severity)
+	}
+}
+
+func TestHoneytokenResultsRecordTrigger(t *testing.T) {
+	m := newTestService(&memoryResults{}, &memoryWhitelist{})
+	honeytokens := &memoryHoneytokens{tokens: []models.Honeytoken{{ID: 1, Name: "billing", RuleID: 7}}}
+	m.repos.Honeytokens = honeytokens
+
+	m.notifyNewResults(models.MonitorRule{ID: 2}, []models.SearchResult{{ID: 1, RuleID: 2}})
+	if honeytokens.tokens[0].TriggeredAt != nil {
+		t.Fatal("a result of another rule triggered the honeytoken")
+	}
+
+	m.notifyNewResults(models.MonitorRule{ID: 7}, []models.SearchResult{{ID: 2, RuleID: 7}, {ID: 3, RuleID: 7}})
+	token := honeytokens.tokens[0]
+	if token.TriggeredAt == nil || token.Triggers != 2 {
+		t.Errorf("honeytoken triggered at %v with %d triggers, want 2", token.TriggeredAt, token.Triggers)
+	}
+}
+
+func TestEndSnoozes(t *testing.T) {
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	results := &memoryResults{results: []models.SearchResult{
+		{ID: 1, RuleID: 1, SnoozedUntil: &past},
+		{ID: 2, RuleID: 1, SnoozedUntil: &future},
+		{ID: 3, RuleID: 1},
+	}}
+	m := newTestService(results, &memoryWhitelist{})
+
+	m.endSnoozes(context.Background())
+
+	if results.results[0].SnoozedUntil != nil {
+		t.Error("the expired snooze didn't end")
+	}
+	if results.results[1].SnoozedUntil == nil {
+		t.Error("the running snooze ended")
+	}
+}
+
+func TestRepoMetadataQuery(t *testing.T) {
+	got := repoMetadataQuery([]string{"acme pay", "filename:.env", "internal"}, github.DialectLegacy)
+	if want := `"acme pay" "internal" in:description,topics`; got != want {
+		t.Errorf("repoMetadataQuery() = %q, want %q", got, want)
+	}
+	if got := repoMetadataQuery([]string{"extension:pem"}, github.DialectLegacy); got != "" {
+		t.Errorf("repoMetadataQuery() of qualifiers = %q, want empty", got)
+	}
+}
+
+func TestQueueIncomplete(t *testing.T) {
+	m := &MonitorService{}
+	m.queueIncomplete(1, incompleteSearch{pages: []int{2}})
+	if queued := m.takeIncomplete(); len(queued) != 0 {
+		t.Fatalf("queued %v with re-runs disabled", queued)
+	}
+
+	m.SetRerunIncomplete(true)
+	m.queueIncomplete(1, incompleteSearch{pages: []int{2}})
+	m.queueIncomplete(1, incompleteSearch{pages: []int{3, 5}})
+	queued := m.takeIncomplete()
+	if got := joinPages(queued[1].pages); got != "3,5" {
+		t.Errorf("queued pages = %q, want the latest scan's 3,5", got)
+	}
+	if len(m.takeIncomplete()) != 0 {
+		t.Error("pages still queued after they were taken")
+	}
+}
+
+func TestCheckStall(t *testing.T) {
+	m := &MonitorService{scanInterval: time.Minute}
+	m.running.Store(true)
+	m.heartbeat()
+	now := m.LastHeartbeat()
+
+	if m.checkStall(false, now.Add(90*time.Second)) {
+		t.Error("stalled after one and a half scan intervals")
+	}
+	if !m.checkStall(false, now.Add(3*time.Minute)) {
+		t.Error("not stalled after three scan intervals")
+	}
+
+	m.running.Store(false)
+	if m.stalledAt(now.Add(time.Hour)) {
+		t.Error("a stopped monitor is reported as stalled")
+	}
+}
+
+func TestScanStatus(t *testing.T) {
+	m := &MonitorService{}
+	m.queueRules(2)
+	m.queueRules(-1)
+	done := m.startRule(models.MonitorRule{ID: 3, Name: "keys"})
+	end := m.startCycle()
+	m.setNextCycle(time.Now().Add(time.Hour))
+
+	status := m.ScanStatus()
+	if status.Queued != 1 || len(status.Scanning) != 1 || status.Scanning[0].ID != 3 || status.CycleStartedAt == nil {
+		t.Errorf("ScanStatus() = %+v, want rule 3 scanning and one queued in a cycle", status)
+	}
+	if status.NextCycleAt != nil {
+		t.Error("next cycle reported while the monitor is stopped")
+	}
+
+	done()
+	end()
+	status = m.ScanStatus()
+	if len(status.Scanning) != 0 || status.CycleStartedAt != nil {
+		t.Errorf("ScanStatus() = %+v after the scan ended", status)
+	}
+}
+
+func TestPauseReason(t *testing.T) {
+	now := time.Now()
+	history := &memoryHistory{}
+	for i, status := range []string{"failed", "success", "failed", "failed"} {
+		history.entries = append(history.entries, models.ScanHistory{RuleID: 1, Status: status, ErrorMessage: "boom", CreatedAt: now.Add(time.Duration(i-10) * time.Hour)})
+	}
+	results := &memoryResults{results: []models.SearchResult{{RuleID: 1, CreatedAt: now.Add(-20 * 24 * time.Hour)}}}
+	honeytokens := &memoryHoneytokens{tokens: []models.Honeytoken{{RuleID: 2}}}
+	m := NewMonitorService(&repository.Repositories{Results: results, History: history, Honeytokens: honeytokens}, nil, 0)
+	ctx := context.Background()
+	monthAgo := now.Add(-30 * 24 * time.Hour)
+
+	tests := []struct {
+		name        string
+		rule        uint
+		pause       AutoPause
+		activeSince time.Time
+		sampleOnly  bool
+		paused      bool
+	}{
+		{"two failures in a row", 1, AutoPause{MaxFailures: 2}, monthAgo, false, true},
+		{"a success among the last three", 1, AutoPause{MaxFailures: 3}, monthAgo, false, false},
+		{"failures before re-activation", 1, AutoPause{MaxFailures: 2}, now.Add(-7*time.Hour - time.Minute), false, false},
+		{"no new results for a week", 1, AutoPause{IdleFor: 7 * 24 * time.Hour}, monthAgo, false, true},
+		{"a result within the window", 1, AutoPause{IdleFor: 21 * 24 * time.Hour}, monthAgo, false, false},
+		{"active for less than the window", 1, AutoPause{IdleFor: 7 * 24 * time.Hour}, now.Add(-24 * time.Hour), false, false},
+		{"honeytoken rule", 2, AutoPause{IdleFor: 7 * 24 * time.Hour}, monthAgo, false, false},
+		{"sample only rule", 3, AutoPause{IdleFor: 7 * 24 * time.Hour}, monthAgo, true, false},
+	}
+	for _, tt := range tests {
+		reason, err := m.pauseReason(ctx, models.MonitorRule{ID: tt.rule, SampleOnly: tt.sampleOnly}, tt.pause, tt.activeSince, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (reason != "") != tt.paused {
+			t.Errorf("%s: reason %q, want paused %v", tt.name, reason, tt.paused)
+		}
+	}
+}
+
+func TestRuleDelays(t *testing.T) {
+	m := newTestService(&memoryResults{}, &memoryWhitelist{})
+	m.SetDelays(time.Second, 3*time.Second)
+
+	if got := m.ruleDelays(models.MonitorRule{}); got.page != time.Second || got.next != 3*time.Second {
+		t.Errorf("rule without delays got %+v, want the configured ones", got)
+	}
+	if got := m.ruleDelays(models.MonitorRule{PageDelay: "0s", RuleDelay: "10s"}); got.page != 0 || got.next != 10*time.Second {
+		t.Errorf("rule with delays got %+v, want its own", got)
+	}
+}
+
+func TestSaveResultsRecordsChangedFiles(t *testing.T) {
+	results := &memoryResults{results: []models.SearchResult{
+		{ID: 1, RuleID: 1, RepoFullName: "acme/api", FilePath: ".env", ContentHash: "aaa", Status: "resolved"},
+		{ID: 2, RuleID: 1, RepoFullName: "acme/api", FilePath: "old.env", Status: "pending"},
+		{ID: 3, RuleID: 1, RepoFullName: "acme/web", FilePath: ".env", ContentHash: "ccc", Status: "false_positive"},
+	}}
+	m := newTestService(results, &memoryWhitelist{})
+	m.SetResultChanges(ChangesReopen)
+	rule := models.MonitorRule{ID: 1}
+
+	changed := item("acme/api", ".env")
+	changed.SHA = "bbb"
+	changed.ContentSnippet = "AWS_SECRET=new"
+	untracked := item("acme/api", "old.env")
+	untracked.SHA = "ddd"
+	same := item("acme/web", ".env")
+	same.SHA = "ccc"
+	if saved := m.saveResults(context.Background(), rule, []*github.SearchResultItem{changed, untracked, same}); len(saved) != 0 {
+		t.Fatalf("saved %+v, want nothing new", saved)
+	}
+
+	if len(results.revisions) != 1 {
+		t.Fatalf("recorded %d revisions, want 1: %+v", len(results.revisions), results.revisions)
+	}
+	if revision := results.revisions[0]; revision.ResultID != 1 || revision.PreviousHash != "aaa" || revision.ContentHash != "bbb" || !revision.Reopened {
+		t.Errorf("unexpected revision %+v", revision)
+	}
+	if result := results.results[0]; result.Status != "pending" || result.ContentHash != "bbb" || result.ContentSnippet != "AWS_SECRET=new" {
+		t.Errorf("changed result is %+v, want it reopened with the new content", result)
+	}
+	if result := results.results[1]; result.ContentHash != "ddd" {
+		t.Errorf("result without a hash got %q, want it filled in", result.ContentHash)
+	}
+	if result := results.results[2]; result.Status != "false_positive" {
+		t.Errorf("unchanged result is %q, want it left closed", result.Status)
+	}
+}
+
+func TestSkipsRepo(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	fresh := repoInfo{createdAt: now.Add(-2 * day)}
+	old := repoInfo{createdAt: now.Add(-400 * day)}
+	archived := repoInfo{archived: true, createdAt: now.Add(-2 * day)}
+
+	tests := []struct {
+		name string
+		rule models.MonitorRule
+		info repoInfo
+		skip bool
+	}{
+		{"archived", models.MonitorRule{SkipArchived: true}, archived, true},
+		{"archived allowed", models.MonitorRule{MaxRepoAgeDays: 30}, archived, false},
+		{"older than the maximum", models.MonitorRule{MaxRepoAgeDays: 365}, old, true},
+		{"within the maximum", models.MonitorRule{MaxRepoAgeDays: 365}, fresh, false},
+		{"newer than the minimum", models.MonitorRule{MinRepoAgeDays: 7}, fresh, true},
+		{"older than the minimum", models.MonitorRule{MinRepoAgeDays: 7}, old, false},
+	}
+	for _, tt := range tests {
+		if got := skipsRepo(tt.rule, tt.info, now); got != tt.skip {
+			t.Errorf("%s: skipsRepo = %v, want %v", tt.name, got, tt.skip)
+		}
+	}
+}
+
+func TestExcludeRepos(t *testing.T) {
+	rule := models.MonitorRule{ExcludeRepos: `["octo/sdk"]`}
+	items := []*github.SearchResultItem{
+		{RepoFullName: "octo/sdk", FilePath: "test/fixtures.env"},
+		{RepoFullName: "Octo/SDK", FilePath: "README.md"},
+		{RepoFullName: "octo/app", FilePath: ".env"},
+	}
+
+	kept := excludeRepos(rule, items)
+	if len(kept) != 1 || kept[0].RepoFullName != "octo/app" {
+		t.Errorf("excludeRepos() kept %d results, want only octo/app", len(kept))
+	}
+	if kept := excludeRepos(models.MonitorRule{}, items); len(kept) != len(items) {
+		t.Errorf("rule without exclusions kept %d of %d results", len(kept), len(items))
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	rules := &blockingRules{listing: make(chan struct{}, 1)}
+	m := NewMonitorService(&repository.Repositories{Rules: rules}, nil, time.Hour)
+
+	if !m.Start() {
+		t.Fatal("Start() = false on a stopped monitor")
+	}
+	if m.Start() {
+		t.Error("Start() = true on a running monitor")
+	}
+	<-rules.listing
+
+	// Stopping cancels the scan in flight and waits for the loop
+	stopped := make(chan bool)
+	go func() { stopped <- m.Stop() }()
+	select {
+	case ok := <-stopped:
+		if !ok {
+			t.Error("Stop() = false on a running monitor")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() blocked on the scan in flight")
+	}
+	if m.IsRunning() || m.Stop() {
+		t.Error("monitor still running after Stop()")
+	}
+
+	if !m.Start() {
+		t.Fatal("Start() = false after Stop()")
+	}
+	<-rules.listing
+	m.Stop()
+}
+
+func TestSaveResultsHoldsFloodingRules(t *testing.T) {
+	results := &memoryResults{}
+	m := newTestService(results, &memoryWhitelist{})
+	rules := &memoryRules{rules: map[uint]models.MonitorRule{1: {ID: 1, IsActive: true}}}
+	m.repos.Rules = rules
+	m.SetMaxNewResults(2)
+	flood := []*github.SearchResultItem{item("acme/api", ".env"), item("acme/api", "main.go"), item("acme/web", ".env")}
+
+	if saved := m.saveResults(context.Background(), rules.rules[1], flood); len(saved) != 0 || len(results.results) != 0 {
+		t.Fatalf("saved %d results over the limit, want none", len(results.results))
+	}
+	if held := rules.rules[1]; held.IsActive || !held.NeedsReview || held.PausedReason == "" {
+		t.Errorf("rule after a flood = %+v, want it inactive and needing review", held)
+	}
+
+	// A rule with a higher limit of its own records them
+	rule := models.MonitorRule{ID: 2, MaxNewResults: 5}
+	if saved := m.saveResults(context.Background(), rule, flood); len(saved) != 3 {
+		t.Errorf("saved %d results under the rule's limit, want 3", len(saved))
+	}
+}
+
+func TestSaveResultsStagesInDryRun(t *testing.T) {
+	results := &memoryResults{}
+	m := newTestService(results, &memoryWhitelist{})
+	staging := &memoryStaging{}
+	rules := &memoryRules{rules: map[uint]models.MonitorRule{1: {ID: 1, IsActive: true}}}
+	m.repos.Staging = staging
+	m.repos.Rules = rules
+	m.SetDryRun(true)
+	found := []*github.SearchResultItem{item("acme/api", ".env"), item("acme/api", "main.go")}
+
+	if staged := m.saveResults(context.Background(), rules.rules[1], found); len(staged) != 2 {
+		t.Fatalf("staged %d results, want 2", len(staged))
+	}
+	if len(results.results) != 0 || len(staging.staged) != 2 {
+		t.Fatalf("recorded %d and staged %d results, want 0 and 2", len(results.results), len(staging.staged))
+	}
+	// The next cycle finds the same files, they are staged once
+	if staged := m.saveResults(context.Background(), rules.rules[1], found); len(staged) != 0 || len(staging.staged) != 2 {
+		t.Errorf("staged %d results again, want none", len(staged))
+	}
+
+	// A flood leaves the rule alone
+	m.SetMaxNewResults(1)
+	m.saveResults(context.Background(), rules.rules[1], []*github.SearchResultItem{item("acme/web", ".env"), item("acme/web", "main.go")})
+	if rule := rules.rules[1]; !rule.IsActive || rule.NeedsReview {
+		t.Errorf("rule after a dry run flood = %+v, want it untouched", rule)
+	}
+}
+
+func TestSaveResultsSamplesSampleOnlyRules(t *testing.T) {
+	results := &memoryResults{}
+	m := newTestService(results, &memoryWhitelist{})
+	samples := &memorySamples{}
+	m.repos.Samples = samples
+	rule := models.MonitorRule{ID: 1, ProjectID: 3, SampleOnly: true}
+
+	var found []*github.SearchResultItem
+	for i := 0; i < 30; i++ {
+		hit := item(fmt.Sprintf("acme/repo%d", i%10), fmt.Sprintf("file%d.env", i))
+		hit.MatchedKeywords = []string{"acme.internal"}
+		found = append(found, hit, hit)
+	}
+
+	if saved := m.saveResults(context.Background(), rule, found); len(saved) != 0 {
+		t.Fatalf("saved %d results of a sample_only rule, want none", len(saved))
+	}
+	if len(results.results) != 0 || len(samples.samples) != 1 {
+		t.Fatalf("recorded %d results and %d samples, want 0 and 1", len(results.results), len(samples.samples))
+	}
+	sample := samples.samples[0]
+	if sample.Hits != 30 || sample.Repos != 10 || sample.ProjectID != 3 || sample.KeywordHits != `{"acme.internal":30}` {
+		t.Errorf("sample = %+v, want 30 hits in 10 repositories", sample)
+	}
+	var hits []models.SampleHit
+	if err := json.Unmarshal([]byte(sample.Sample), &hits); err != nil || len(hits) != sampleSize {
+		t.Errorf("sampled %d hits (%v), want %d", len(hits), err, sampleSize)
+	}
+}
+
+var registerTestProcessors sync.Once
+
+func TestSaveResultsRunsRuleProcessors(t *testing.T) {
+	registerTestProcessors.Do(func() {
+		RegisterProcessor("test_skip_vendor", StageFilter, func(ctx context.Context, m *MonitorService, batch *Batch) error {
+			kept := batch.Items[:0]
+			for _, item := range batch.Items {
+				if !strings.HasPrefix(item.FilePath, "vendor/") {
+					kept = append(kept, item)
+				}
+			}
+			batch.Items = kept
+			return nil
+		})
+		RegisterProcessor("test_escalate", StageEnrich, func(ctx context.Context, m *MonitorService, batch *Batch) error {
+			for i := range batch.Results {
+				batch.Results[i].Severity = "critical"
+			}
+			return nil
+		})
+	})
+
+	for processors, want := range map[string]string{
+		`["dedup", "test_skip_vendor", "test_escalate"]`: "",
+		`["test_skip_vendor"]`:                           "must include dedup",
+		`["dedup", "dedup"]`:                             `processor "dedup" is listed twice`,
+		`["dedup", "test_missing"]`:                      `unknown processor "test_missing"`,
+		`"dedup"`:                                        "must be a JSON array of processor names",
+	} {
+		if err := ValidProcessors(processors); (err == nil) != (want == "") || (err != nil && !strings.Contains(err.Error(), want)) {
+			t.Errorf("ValidProcessors(%s) = %v, want %q", processors, err, want)
+		}
+	}
+
+	// The whitelist isn't listed, so the rule records whitelisted repositories too
+	results := &memoryResults{}
+	m := newTestService(results, &memoryWhitelist{entries: []models.Whitelist{{Type: "repo", Value: "acme/api", ProjectID: 1}}})
+	rule := models.MonitorRule{ID: 1, ProjectID: 1, Severity: "low", Processors: `["dedup", "test_skip_vendor", "test_escalate"]`}
+	saved := m.saveResults(context.Background(), rule, []*github.SearchResultItem{
+		item("acme/api", ".env"), item("acme/api", "vendor/lib/.env"), item("acme/web", ".env"), item("acme/web", ".env"),
+	})
+	if len(saved) != 2 || saved[0].FilePath != ".env" || saved[1].RepoFullName != "acme/web" {
+		t.Fatalf("saved %+v, want the .env files of acme/api and acme/web", saved)
+	}
+	if saved[0].Severity != "critical" {
+		t.Errorf("severity = %q, want critical from the enrich processor", saved[0].Severity)
+	}
+	// The default pipeline drops them
+	rule = models.MonitorRule{ID: 2, ProjectID: 1, Severity: "low"}
+	if saved := m.saveResults(context.Background(), rule, []*github.SearchResultItem{item("acme/api", "main.go")}); len(saved) != 0 {
+		t.Errorf("saved %d whitelisted results with the default processors", len(saved))
+	}
+}
+
+func TestStoreEvidenceLeavesOutBinaryContent(t *testing.T) {
+	store := &memoryStore{}
+	m := &MonitorService{store: store}
+	rule := models.MonitorRule{ID: 1, Name: "gists"}
+
+	for content, want := range map[string]bool{
+		"AWS_SECRET_ACCESS_KEY=abc123\n":         true,
+		"AWS_SECRET_ACCESS_KEY=abc123\n\x00\x01": false,
+	} {
+		gist := &github.SearchResultItem{RepoFullName: "octo/abc", FilePath: fmt.Sprintf("notes-%t.txt@1234567", want), Content: content}
+		key := m.storeEvidence(context.Background(), rule, models.SourceGist, gist)
+		if key == "" {
+			t.Fatalf("no evidence stored for %q", content)
+		}
+		var stored evidence
+		if err := json.Unmarshal(store.objects[key], &stored); err != nil {
+			t.Fatal(err)
+		}
+		if (stored.Content != "") != want {
+			t.Errorf("stored content %q for %q, want it kept: %t", stored.Content, content, want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github-monitor/db/models"
+	"github-monitor/i18n"
+	"github-monitor/notify"
+	"github-monitor/reporting"
+	"github-monitor/repository"
+	"github-monitor/settings"
+)
+
+// maxListedResults caps how many results are listed in a single notification
+const maxListedResults = 10
+
+// notifyNewResults sends one summary notification for the new results of a rule scan
+func (m *MonitorService) notifyNewResults(rule models.MonitorRule, results []models.SearchResult) {
+	// A dry run triggers nothing, not even honeytokens
+	if m.DryRun() {
+		return
+	}
+	// Honeytokens alert on their own path, whatever the thresholds and channel settings
+	if m.honeytokenTriggered(rule, results) {
+		return
+	}
+
+	current := settings.Current()
+	if !current.NotificationsEnabled {
+		return
+	}
+
+	// Results below the threshold of the rule wait in the dashboard queue
+	notified := make([]models.SearchResult, 0, len(results))
+	for _, result := range results {
+		if models.MeetsNotifyThreshold(rule.NotifyThreshold, result) {
+			notified = append(notified, result)
+		}
+	}
+	results = notified
+	if len(results) == 0 {
+		return
+	}
+
+	// Another instance may have saved and notified the same files
+	results = m.dedupNotifications(context.Background(), rule, results)
+	if len(results) == 0 {
+		return
+	}
+
+	message := notify.Message{
+		TitleText:   i18n.Format("GitHub leak alert: %s", rule.Name),
+		ContentText: i18n.Format("Rule **%s** found %d new potential leaks:\n", rule.Name, len(results)),
+		URL:         results[0].HTMLURL,
+	}
+	m.broadcast(rule, message, results, notifiesNew(rule))
+}
+
+// notifySnoozeEnded reminds about the results of a rule whose snooze ended
+func (m *MonitorService) notifySnoozeEnded(rule models.MonitorRule, results []models.SearchResult) {
+	if !m.notificationsEnabled() {
+		return
+	}
+
+	message := notify.Message{
+		TitleText:   i18n.Format("GitHub leak reminder: %s", rule.Name),
+		ContentText: i18n.Format("The snooze of %d results of rule **%s** ended, they are back in the pending queue:\n", len(results), rule.Name),
+		URL:         results[0].HTMLURL,
+	}
+	m.broadcast(rule, message, results, notifiesNew(rule))
+}
+
+// notifiesNew selects the channels of the rule's project that notify about new results
+func notifiesNew(rule models.MonitorRule) func(*models.NotificationConfig) bool {
+	return func(config *models.NotificationConfig) bool {
+		return config.NotifyOnNew && config.ProjectID == rule.ProjectID
+	}
+}
+
+// notifyScanFailed emails the owner of a rule when its scans start failing. Later
+// failures in a row aren't repeated, monitor.auto_pause deals with rules that keep
+// failing.
+func (m *MonitorService) notifyScanFailed(ctx context.Context, rule models.MonitorRule, failure models.ScanHistory) {
+	if rule.Owner == "" || !m.notificationsEnabled() {
+		return
+	}
+	// The failure is recorded already, the entry before it tells whether it is the first
+	history, err := m.repos.History.ListAfter(ctx, repository.HistoryFilter{RuleID: rule.ID}, 0, 2)
+	if err != nil {
+		log.Printf("Failed to load the scan history of rule %d: %v", rule.ID, err)
+		return
+	}
+	if len(history) == 2 && scanFailed(history[1].Status) {
+		return
+	}
+
+	message := notify.Message{
+		TitleText:   i18n.Format("Rule scan failed: %s", rule.Name),
+		ContentText: i18n.Format("The scan of rule **%s** failed: %s", rule.Name, failure.ErrorMessage),
+	}
+	m.notifying.Add(1)
+	go func() {
+		defer m.notifying.Done()
+		defer reporting.Recover(reporting.Tags{"component": "notify", "rule_id": fmt.Sprint(rule.ID)})
+		if err := notify.NotifyOwner(rule.Owner, message); err != nil {
+			log.Printf("Failed to notify the owner of rule %d: %v", rule.ID, err)
+		}
+	}()
+}
+
+// scanFailed reports whether a scan status is a failure of the rule, rate limits
+// are a failure of the tokens
+func scanFailed(status string) bool {
+	return status == "failed" || status == "invalid_query"
+}
+
+// broadcast lists the results in the message and sends it in the background to the
+// channels filter selects and to the owner of the rule
+func (m *MonitorService) broadcast(rule models.MonitorRule, message notify.Message, results []models.SearchResult, filter func(*models.NotificationConfig) bool) {
+	for i, result := range results {
+		if i == maxListedResults {
+			message.More = len(results) - maxListedResults
+			break
+		}
+		message.Results = append(message.Results, notify.ResultRef{
+			ID:      result.ID,
+			Label:   strings.TrimSuffix(result.RepoFullName+"/"+result.FilePath, "/"),
+			HTMLURL: result.HTMLURL,
+			Snippet: result.ContentSnippet,
+		})
+	}
+
+	if dashboardURL := settings.Current().DashboardURL; dashboardURL != "" {
+		message.URL = fmt.Sprintf("%s/results?rule_id=%d", strings.TrimSuffix(dashboardURL, "/"), rule.ID)
+	}
+
+	m.notifying.Add(1)
+	go func() {
+		defer m.notifying.Done()
+		defer reporting.Recover(reporting.Tags{"component": "notify", "rule_id": fmt.Sprint(rule.ID)})
+		if err := notify.TranslateSnippets(&message); err != nil {
+			log.Printf("Sending the notification of rule %d untranslated: %v", rule.ID, err)
+		}
+		notify.Broadcast(context.Background(), m.repos.Notifications, message, filter)
+		if rule.Owner != "" {
+			if err := notify.NotifyOwner(rule.Owner, message); err != nil {
+				log.Printf("Failed to notify the owner of rule %d: %v", rule.ID, err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,38 @@
+package monitor
+
+import (
+	"time"
+
+	"github-monitor/db/models"
+)
+
+// delays are the waits around the searches of a rule
+type delays struct {
+	page time.Duration // between result pages
+	next time.Duration // after the scan, before the worker takes the next rule
+}
+
+// SetDelays changes the waits between result pages and between rules, rules with
+// delays of their own keep them
+func (m *MonitorService) SetDelays(page, rule time.Duration) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.pageDelay = page
+	m.ruleDelay = rule
+}
+
+// ruleDelays returns the delays of a rule, the configured ones where the rule
+// doesn't set its own
+func (m *MonitorService) ruleDelays(rule models.MonitorRule) delays {
+	m.settingsMu.RLock()
+	d := delays{page: m.pageDelay, next: m.ruleDelay}
+	m.settingsMu.RUnlock()
+
+	if page, err := time.ParseDuration(rule.PageDelay); err == nil {
+		d.page = page
+	}
+	if next, err := time.ParseDuration(rule.RuleDelay); err == nil {
+		d.next = next
+	}
+	return d
+}
@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github-monitor/reporting"
+)
+
+// pendingScanInterval is how often the loop looks for new and re-activated rules
+// to scan before the next cycle
+const pendingScanInterval = 15 * time.Second
+
+// SetScanOnCreate enables scanning rules right after they are created or
+// re-activated, rather than in the next scan cycle
+func (m *MonitorService) SetScanOnCreate(scan bool) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.scanOnCreate = scan
+}
+
+func (m *MonitorService) getScanOnCreate() bool {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.scanOnCreate
+}
+
+// scanPendingRules scans the rules waiting for their first scan. The rules are
+// stored as pending, so rules created through any instance are picked up by the
+// one running the loop.
+func (m *MonitorService) scanPendingRules(ctx context.Context) {
+	defer reporting.Recover(reporting.Tags{"component": "monitor"})
+	if !m.getScanOnCreate() {
+		return
+	}
+
+	rules, err := m.repos.Rules.ListScanPending(ctx)
+	if err != nil {
+		log.Printf("Failed to fetch rules waiting for their first scan: %v", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	log.Printf("Scanning %d new or re-activated rules", len(rules))
+	for _, err := range m.scanRules(ctx, rules, false) {
+		log.Printf("First scan failed: %v", err)
+	}
+	m.heartbeat()
+}
+
+// clearScanPending records that a rule got its first scan, whatever its outcome,
+// so a failing rule waits for the next cycle like the others
+func (m *MonitorService) clearScanPending(ctx context.Context, id uint) {
+	if err := m.repos.Rules.ClearScanPending(ctx, id); err != nil {
+		log.Printf("Failed to clear the pending scan of rule %d: %v", id, err)
+	}
+}
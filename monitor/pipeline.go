@@ -0,0 +1,225 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/repository"
+)
+
+// Stage is where a processor runs in the result pipeline
+type Stage int
+
+const (
+	// StageFilter processors run on the hits of a scan before the new results limit
+	// is checked. They may drop hits, so they should be cheap.
+	StageFilter Stage = iota
+	// StageEnrich processors run on the results about to be recorded, once the
+	// limit passed. They fill in results but don't drop them.
+	StageEnrich
+)
+
+// Batch is what a scan of a rule found in one source, on its way through the
+// result pipeline
+type Batch struct {
+	Rule     models.MonitorRule
+	Keywords []string // the rule's keywords, empty for raw query rules without any
+	DryRun   bool     // results are staged instead of recorded
+
+	Items []*github.SearchResultItem
+	// Results are built from Items once the filter stage is done, Results[i] from
+	// Items[i]
+	Results []models.SearchResult
+}
+
+// Processor is a step of the result pipeline. An error drops the batch, nothing
+// of it is recorded.
+type Processor func(ctx context.Context, m *MonitorService, batch *Batch) error
+
+type registeredProcessor struct {
+	stage   Stage
+	process Processor
+}
+
+var processors = struct {
+	mu     sync.RWMutex
+	byName map[string]registeredProcessor
+}{byName: make(map[string]registeredProcessor)}
+
+// Built-in processors
+const (
+	ProcessorWhitelist    = "whitelist"
+	ProcessorRepoFilters  = "repo_filters"
+	ProcessorExcludeRepos = "exclude_repos"
+	ProcessorDedup        = "dedup"
+	ProcessorVerify       = "verify"
+	ProcessorScore        = "score"
+	ProcessorContext      = "context"
+	ProcessorInfra        = "infra"
+	ProcessorCI           = "ci"
+)
+
+// DefaultProcessors is the pipeline of rules that don't list their processors
+var DefaultProcessors = []string{
+	ProcessorWhitelist, ProcessorRepoFilters, ProcessorExcludeRepos, ProcessorDedup, ProcessorVerify, ProcessorScore,
+	ProcessorContext, ProcessorInfra, ProcessorCI,
+}
+
+func init() {
+	RegisterProcessor(ProcessorWhitelist, StageFilter, func(ctx context.Context, m *MonitorService, batch *Batch) error {
+		batch.Items = m.filterWhitelist(ctx, batch.Rule.ProjectID, batch.Items)
+		return nil
+	})
+	RegisterProcessor(ProcessorRepoFilters, StageFilter, func(ctx context.Context, m *MonitorService, batch *Batch) error {
+		batch.Items = m.filterRepos(ctx, batch.Rule, batch.Items)
+		return nil
+	})
+	RegisterProcessor(ProcessorExcludeRepos, StageFilter, func(ctx context.Context, m *MonitorService, batch *Batch) error {
+		batch.Items = excludeRepos(batch.Rule, batch.Items)
+		return nil
+	})
+	RegisterProcessor(ProcessorDedup, StageFilter, dedupItems)
+	RegisterProcessor(ProcessorVerify, StageFilter, func(ctx context.Context, m *MonitorService, batch *Batch) error {
+		// Raw query rules may leave the keywords out, there is nothing to verify then
+		if batch.Rule.MatchType == "precise" && len(batch.Keywords) > 0 {
+			batch.Items = m.verifyMatches(ctx, batch.Rule, batch.Keywords, batch.Items)
+		}
+		return nil
+	})
+	RegisterProcessor(ProcessorScore, StageFilter, func(ctx context.Context, m *MonitorService, batch *Batch) error {
+		batch.Items = scoreResults(batch.Rule, batch.Items)
+		return nil
+	})
+
+	// May fetch the file, which the infrastructure, CI and file type detection use too
+	RegisterProcessor(ProcessorContext, StageEnrich, func(ctx context.Context, m *MonitorService, batch *Batch) error {
+		for i, item := range batch.Items {
+			batch.Results[i].MatchContext = m.lineContext(ctx, batch.Keywords, batch.Rule.QueryDialect, item)
+		}
+		return nil
+	})
+	RegisterProcessor(ProcessorInfra, StageEnrich, func(ctx context.Context, m *MonitorService, batch *Batch) error {
+		for i, item := range batch.Items {
+			result := &batch.Results[i]
+			result.InfraMatches, result.Severity = m.detectInfra(ctx, result.Severity, item)
+		}
+		return nil
+	})
+	RegisterProcessor(ProcessorCI, StageEnrich, func(ctx context.Context, m *MonitorService, batch *Batch) error {
+		for i, item := range batch.Items {
+			result := &batch.Results[i]
+			result.CIFindings, result.Severity = m.detectCI(ctx, result.Severity, item)
+		}
+		return nil
+	})
+}
+
+// RegisterProcessor adds a processor that rules can list in their processors. It
+// panics when the name is taken, so it is meant to be called from init functions.
+func RegisterProcessor(name string, stage Stage, process Processor) {
+	processors.mu.Lock()
+	defer processors.mu.Unlock()
+	if _, taken := processors.byName[name]; taken {
+		panic(fmt.Sprintf("monitor: processor %q registered twice", name))
+	}
+	processors.byName[name] = registeredProcessor{stage: stage, process: process}
+}
+
+// ValidProcessors checks the processors of a rule: empty for DefaultProcessors or
+// a JSON array of registered processors, each listed once. dedup can't be left
+// out, scans would record the same files again.
+func ValidProcessors(processorsJSON string) error {
+	if processorsJSON == "" {
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal([]byte(processorsJSON), &names); err != nil {
+		return fmt.Errorf("must be a JSON array of processor names")
+	}
+
+	processors.mu.RLock()
+	defer processors.mu.RUnlock()
+	listed := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := processors.byName[name]; !ok {
+			registered := make([]string, 0, len(processors.byName))
+			for known := range processors.byName {
+				registered = append(registered, known)
+			}
+			sort.Strings(registered)
+			return fmt.Errorf("unknown processor %q, registered ones are %s", name, strings.Join(registered, ", "))
+		}
+		if listed[name] {
+			return fmt.Errorf("processor %q is listed twice", name)
+		}
+		listed[name] = true
+	}
+	if !listed[ProcessorDedup] {
+		return fmt.Errorf("must include %s", ProcessorDedup)
+	}
+	return nil
+}
+
+// pipeline returns the processors of a rule by stage, in order
+func pipeline(rule models.MonitorRule) (filters, enrichers []Processor) {
+	var names []string
+	if rule.Processors != "" {
+		if err := ValidProcessors(rule.Processors); err != nil {
+			log.Printf("Using the default processors for rule %d: %v", rule.ID, err)
+		} else {
+			json.Unmarshal([]byte(rule.Processors), &names)
+		}
+	}
+	if len(names) == 0 {
+		names = DefaultProcessors
+	}
+
+	processors.mu.RLock()
+	defer processors.mu.RUnlock()
+	for _, name := range names {
+		registered := processors.byName[name]
+		if registered.stage == StageEnrich {
+			enrichers = append(enrichers, registered.process)
+		} else {
+			filters = append(filters, registered.process)
+		}
+	}
+	return filters, enrichers
+}
+
+// dedupItems drops the repeated files of a batch and the ones the rule already
+// recorded. Those are checked for changes instead.
+func dedupItems(ctx context.Context, m *MonitorService, batch *Batch) error {
+	known, err := m.knownFiles(ctx, batch.Rule.ID, batch.Items)
+	if err != nil {
+		return fmt.Errorf("failed to check existing results: %w", err)
+	}
+
+	fresh := make([]*github.SearchResultItem, 0, len(batch.Items))
+	var seen []*github.SearchResultItem
+	found := make(map[repository.FileKey]bool, len(batch.Items))
+	for _, item := range batch.Items {
+		key := repository.FileKey{RepoFullName: item.RepoFullName, FilePath: item.FilePath}
+		if found[key] {
+			continue
+		}
+		found[key] = true
+		if known[key] {
+			seen = append(seen, item)
+			continue
+		}
+		fresh = append(fresh, item)
+	}
+	if !batch.DryRun {
+		m.recordChanges(ctx, batch.Rule, seen)
+	}
+	batch.Items = fresh
+	return nil
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/postman"
+)
+
+// SetPostman enables Postman public workspace searches for every rule, nil disables them
+func (m *MonitorService) SetPostman(client *postman.Client) {
+	m.postman = client
+}
+
+// searchPostman finds public Postman collections and requests mentioning every
+// keyword of a rule. Like Docker Hub the longest keyword is the query, the others
+// are checked against the name, description and request URL.
+func (m *MonitorService) searchPostman(ctx context.Context, keywords []string, dialect string) ([]*github.SearchResultItem, error) {
+	query := queryKeyword(keywords, dialect)
+	if query == "" {
+		return nil, nil
+	}
+
+	entities, err := m.postman.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*github.SearchResultItem, 0)
+	for i := range entities {
+		entity := &entities[i]
+		matched, snippet := github.MatchContent(entity.Name+"\n"+entity.RequestURL+"\n"+entity.Description, keywords, dialect)
+		if matched == nil {
+			continue
+		}
+
+		items = append(items, &github.SearchResultItem{
+			RepoFullName:    entity.Publisher + "/" + entity.Workspace,
+			RepoURL:         entity.WorkspaceURL(),
+			FilePath:        entity.Type + "/" + entity.ID,
+			FileURL:         entity.URL(),
+			HTMLURL:         entity.URL(),
+			MatchedKeywords: matched,
+			ContentSnippet:  snippet,
+			Score:           1.0,
+			CreatedAt:       time.Now(),
+			Source:          models.SourcePostman,
+		})
+	}
+
+	return items, nil
+}
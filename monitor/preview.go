@@ -0,0 +1,48 @@
+package monitor
+
+import (
+	"context"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+)
+
+// previewItems is how many results a preview returns
+const previewItems = 10
+
+// QueryPreview is the code search query of a rule, with what it finds when the
+// search was run
+type QueryPreview struct {
+	Query   string                     `json:"query"`
+	Total   *int                       `json:"total,omitempty"`   // results GitHub counted, nil when not searched
+	Results []*github.SearchResultItem `json:"results,omitempty"` // the newest ones, before any filter of the rule
+}
+
+// PreviewQuery returns the code search query of a rule, raw or built from its
+// keywords. With search the query is run for one page, which costs a search API
+// call, and the newest results are returned. Nothing is recorded.
+func (m *MonitorService) PreviewQuery(ctx context.Context, rule models.MonitorRule, search bool) (*QueryPreview, error) {
+	keywords, err := github.ParseKeywords(rule.Keywords)
+	if err != nil && rule.RawQuery == "" {
+		return nil, err
+	}
+	opts := m.ruleSearchOptions(rule, keywords)
+	preview := &QueryPreview{Query: m.searchService.Query(opts)}
+	if !search {
+		return preview, nil
+	}
+
+	opts.MaxPages = 1
+	opts.PageDelay = 0
+	response, err := m.searchService.SearchCode(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	preview.Total = &response.Total
+	items := response.Items
+	if len(items) > previewItems {
+		items = items[:previewItems]
+	}
+	preview.Results = items
+	return preview, nil
+}
@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+
+	"github-monitor/db/models"
+)
+
+// ScanStatus is the work of the monitor in flight
+type ScanStatus struct {
+	Scanning       []ScanningRule `json:"scanning"`                   // rules being scanned, manual scans included
+	Queued         int            `json:"queued"`                     // rules waiting for a free worker
+	CycleStartedAt *time.Time     `json:"cycle_started_at,omitempty"` // nil between scan cycles
+	NextCycleAt    *time.Time     `json:"next_cycle_at,omitempty"`    // nil while the monitor is stopped
+}
+
+// ScanningRule is a rule being scanned
+type ScanningRule struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	ProjectID uint      `json:"project_id"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+type scanProgress struct {
+	scanning     map[uint]ScanningRule
+	queued       int
+	cycleStarted time.Time
+	nextCycle    time.Time
+}
+
+// ScanStatus returns the rules being scanned and the timing of the scan cycles
+func (m *MonitorService) ScanStatus() ScanStatus {
+	running := m.IsRunning()
+
+	m.progressMu.Lock()
+	defer m.progressMu.Unlock()
+	status := ScanStatus{
+		Scanning: make([]ScanningRule, 0, len(m.progress.scanning)),
+		Queued:   m.progress.queued,
+	}
+	for _, rule := range m.progress.scanning {
+		status.Scanning = append(status.Scanning, rule)
+	}
+	sort.Slice(status.Scanning, func(i, j int) bool {
+		return status.Scanning[i].StartedAt.Before(status.Scanning[j].StartedAt)
+	})
+	if !m.progress.cycleStarted.IsZero() {
+		started := m.progress.cycleStarted
+		status.CycleStartedAt = &started
+	}
+	if running && !m.progress.nextCycle.IsZero() {
+		next := m.progress.nextCycle
+		status.NextCycleAt = &next
+	}
+	return status
+}
+
+// startCycle records the start of a scan cycle and returns the function ending it
+func (m *MonitorService) startCycle() func() {
+	m.progressMu.Lock()
+	m.progress.cycleStarted = time.Now()
+	m.progressMu.Unlock()
+
+	return func() {
+		m.progressMu.Lock()
+		m.progress.cycleStarted = time.Time{}
+		m.progressMu.Unlock()
+	}
+}
+
+func (m *MonitorService) setNextCycle(next time.Time) {
+	m.progressMu.Lock()
+	m.progress.nextCycle = next
+	m.progressMu.Unlock()
+}
+
+// queueRules adds n rules to the ones waiting for a worker, or takes them off when
+// n is negative
+func (m *MonitorService) queueRules(n int) {
+	m.progressMu.Lock()
+	m.progress.queued += n
+	m.progressMu.Unlock()
+}
+
+// startRule records that a rule is being scanned and returns the function recording
+// that it is done
+func (m *MonitorService) startRule(rule models.MonitorRule) func() {
+	m.progressMu.Lock()
+	if m.progress.scanning == nil {
+		m.progress.scanning = make(map[uint]ScanningRule)
+	}
+	m.progress.scanning[rule.ID] = ScanningRule{ID: rule.ID, Name: rule.Name, ProjectID: rule.ProjectID, StartedAt: time.Now()}
+	m.progressMu.Unlock()
+
+	return func() {
+		m.progressMu.Lock()
+		delete(m.progress.scanning, rule.ID)
+		m.progressMu.Unlock()
+	}
+}
@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github-monitor/github"
+)
+
+// maxPushFiles caps how many changed files of a single push are fetched and scanned
+const maxPushFiles = 100
+
+// Push describes the files changed by a push to a repository
+type Push struct {
+	RepoFullName string
+	RepoURL      string
+	Ref          string // commit the files are read at
+	Paths        []string
+}
+
+// ScanPush matches the active rules against the files changed by a push, for
+// repositories that report pushes through the webhook. New results are saved and
+// notified like scan results, without waiting for the search index.
+func (m *MonitorService) ScanPush(ctx context.Context, push Push) error {
+	startTime := time.Now()
+
+	rules, err := m.repos.Rules.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Rules of projects that whitelist the repository are skipped before any file is fetched
+	probe := []*github.SearchResultItem{{RepoFullName: push.RepoFullName}}
+	whitelisted := make(map[uint]bool)
+	scanned := rules[:0]
+	for _, rule := range rules {
+		skip, seen := whitelisted[rule.ProjectID]
+		if !seen {
+			skip = len(m.filterWhitelist(ctx, rule.ProjectID, probe)) == 0
+			whitelisted[rule.ProjectID] = skip
+		}
+		if !skip {
+			scanned = append(scanned, rule)
+		}
+	}
+	rules = scanned
+	if len(rules) == 0 {
+		log.Printf("Skipping push to %s, no rule of a project that doesn't whitelist it is active", push.RepoFullName)
+		return nil
+	}
+
+	paths := push.Paths
+	if len(paths) > maxPushFiles {
+		log.Printf("Push to %s changed %d files, scanning the first %d", push.RepoFullName, len(paths), maxPushFiles)
+		paths = paths[:maxPushFiles]
+	}
+
+	files := make([]*github.FileContent, 0, len(paths))
+	for _, path := range paths {
+		file, err := m.searchService.GetFileContent(ctx, push.RepoFullName, path, push.Ref)
+		if err != nil {
+			log.Printf("Push scan of %s: %v", push.RepoFullName, err)
+			continue
+		}
+		files = append(files, file)
+	}
+
+	total := 0
+	for _, rule := range rules {
+		keywords, err := github.ParseKeywords(rule.Keywords)
+		if err != nil {
+			log.Printf("Failed to parse keywords for rule %d: %v", rule.ID, err)
+			continue
+		}
+		excludeExts, err := github.ParseExcludeExts(rule.ExcludeExts)
+		if err != nil {
+			excludeExts = []string{}
+		}
+
+		var matches []*github.SearchResultItem
+		for _, file := range files {
+			if github.IsExcluded(file.Path, excludeExts) || !github.MatchPath(file.Path, keywords) {
+				continue
+			}
+			matched, snippet := github.MatchContent(file.Content, keywords, rule.QueryDialect)
+			if matched == nil {
+				continue
+			}
+			matches = append(matches, &github.SearchResultItem{
+				RepoFullName:    push.RepoFullName,
+				RepoURL:         push.RepoURL,
+				FilePath:        file.Path,
+				FileURL:         file.HTMLURL,
+				HTMLURL:         file.HTMLURL,
+				MatchedKeywords: matched,
+				ContentSnippet:  snippet,
+				Content:         file.Content,
+				Score:           1.0,
+				CreatedAt:       time.Now(),
+			})
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		newResults := m.saveResults(ctx, rule, matches)
+		if len(newResults) > 0 {
+			m.notifyNewResults(rule, newResults)
+		}
+		total += len(newResults)
+	}
+
+	log.Printf("Push scan of %s@%s completed: %d files, %d new results, took %s",
+		push.RepoFullName, push.Ref, len(files), total, time.Since(startTime).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,169 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/registry"
+
+	"gorm.io/gorm"
+)
+
+// registryRuleName is the built-in rule registry results are recorded under
+const registryRuleName = "Public package registries"
+
+// RegistryWatch lists the internal package names to look for on public registries
+type RegistryWatch struct {
+	Packages   []string
+	Registries []string // registry.NPM, registry.PyPI
+	Severity   string
+}
+
+// SetRegistryWatch enables the package registry check, a nil client disables it
+func (m *MonitorService) SetRegistryWatch(client *registry.Client, watch RegistryWatch) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.registry = client
+	m.registryWatch = watch
+}
+
+func (m *MonitorService) getRegistryWatch() (*registry.Client, RegistryWatch) {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.registry, m.registryWatch
+}
+
+// scanRegistries looks up every internal package name on the public registries. A
+// package that shows up there is a dependency confusion risk: installers may pick
+// the public package over the internal one. Each package is reported once per
+// registry, so only newly published names create results and notifications.
+func (m *MonitorService) scanRegistries(ctx context.Context) error {
+	client, watch := m.getRegistryWatch()
+	if client == nil || len(watch.Packages) == 0 {
+		return nil
+	}
+
+	startTime := time.Now()
+	rule, err := m.registryRule(ctx, watch)
+	if err != nil {
+		log.Printf("Failed to prepare the package registry rule: %v", err)
+		return err
+	}
+
+	var (
+		items  []*github.SearchResultItem
+		failed []string
+	)
+	for _, name := range watch.Registries {
+		for _, pkgName := range watch.Packages {
+			pkg, err := client.Lookup(ctx, name, pkgName)
+			if err != nil {
+				log.Printf("Registry lookup of %s on %s failed: %v", pkgName, name, err)
+				failed = append(failed, fmt.Sprintf("%s/%s: %v", name, pkgName, err))
+				continue
+			}
+			if pkg == nil {
+				continue
+			}
+			items = append(items, registryItem(pkg, pkgName))
+		}
+	}
+
+	newResults := m.saveResults(ctx, *rule, items)
+	if len(newResults) > 0 {
+		m.notifyNewResults(*rule, newResults)
+	}
+
+	duration := int(time.Since(startTime).Seconds())
+	log.Printf("Package registry check completed: %d published packages, %d new results, took %d seconds",
+		len(items), len(newResults), duration)
+
+	if len(failed) > 0 && len(failed) == len(watch.Packages)*len(watch.Registries) {
+		err := errors.New(strings.Join(failed, "; "))
+		m.recordScanHistory(ctx, *rule, 0, 0, "", "failed", err.Error(), duration)
+		return err
+	}
+	m.recordScanHistory(ctx, *rule, len(items), len(newResults), "", "success", strings.Join(failed, "; "), duration)
+	return nil
+}
+
+// registryRule returns the rule registry results belong to, creating it on first
+// use. It stays inactive so the regular scan doesn't search GitHub with it, and
+// follows the configured package list and severity.
+func (m *MonitorService) registryRule(ctx context.Context, watch RegistryWatch) (*models.MonitorRule, error) {
+	keywords, err := json.Marshal(watch.Packages)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := m.repos.Rules.GetByName(ctx, registryRuleName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		rule = &models.MonitorRule{
+			Name:        registryRuleName,
+			Description: "Internal package names found on public npm or PyPI (dependency confusion). Managed through the registry section of config.yaml.",
+			Keywords:    string(keywords),
+			MatchType:   "precise",
+			Severity:    watch.Severity,
+		}
+		if err := m.repos.Rules.Create(ctx, rule); err != nil {
+			return nil, err
+		}
+		// is_active has a database default of true, which Create applies to false
+		rule.IsActive = false
+		return rule, m.repos.Rules.Save(ctx, rule)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.Keywords != string(keywords) || rule.Severity != watch.Severity {
+		rule.Keywords = string(keywords)
+		rule.Severity = watch.Severity
+		if err := m.repos.Rules.Save(ctx, rule); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
+
+// registryItem turns a published package into a result, keyed by registry and name
+func registryItem(pkg *registry.Package, internalName string) *github.SearchResultItem {
+	details := []string{pkg.Registry + " package " + pkg.Name}
+	if pkg.Version != "" {
+		details = append(details, "version "+pkg.Version)
+	}
+	if !pkg.Published.IsZero() {
+		details = append(details, "first published "+pkg.Published.UTC().Format("2006-01-02"))
+	}
+	if pkg.Author != "" {
+		details = append(details, "by "+pkg.Author)
+	}
+	snippet := strings.Join(details, ", ")
+	if pkg.Description != "" {
+		snippet += "\n" + pkg.Description
+	}
+
+	source := models.SourceNPM
+	if pkg.Registry == registry.PyPI {
+		source = models.SourcePyPI
+	}
+
+	return &github.SearchResultItem{
+		RepoFullName:    pkg.Registry + "/" + pkg.Name,
+		RepoURL:         pkg.URL,
+		FileURL:         pkg.URL,
+		HTMLURL:         pkg.URL,
+		MatchedKeywords: []string{internalName},
+		ContentSnippet:  snippet,
+		Score:           1.0,
+		CreatedAt:       time.Now(),
+		Source:          source,
+	}
+}
@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+)
+
+// repoInfoTTL is how long the archived state and age of a repository are reused
+const repoInfoTTL = 24 * time.Hour
+
+// repoInfo is what the repository filters of rules need to know about a repository
+type repoInfo struct {
+	archived  bool
+	createdAt time.Time
+	fetchedAt time.Time
+}
+
+// filterRepos drops the code search results in repositories a rule skips: archived
+// ones, or ones created too recently or too long ago. Code search doesn't return
+// either, so each repository is looked up once a day. A repository that can't be
+// looked up keeps its results.
+func (m *MonitorService) filterRepos(ctx context.Context, rule models.MonitorRule, items []*github.SearchResultItem) []*github.SearchResultItem {
+	if !rule.SkipArchived && rule.MinRepoAgeDays == 0 && rule.MaxRepoAgeDays == 0 {
+		return items
+	}
+
+	now := time.Now()
+	kept := make([]*github.SearchResultItem, 0, len(items))
+	for _, item := range items {
+		if item.Source != "" {
+			kept = append(kept, item)
+			continue
+		}
+		info, err := m.repoInfo(ctx, item.RepoFullName, now)
+		if err != nil {
+			log.Printf("Failed to look up %s for the repository filters of rule %d: %v", item.RepoFullName, rule.ID, err)
+			kept = append(kept, item)
+			continue
+		}
+		if !skipsRepo(rule, info, now) {
+			kept = append(kept, item)
+		}
+	}
+
+	if len(kept) != len(items) {
+		log.Printf("Repository filters of rule %d: %d -> %d results", rule.ID, len(items), len(kept))
+	}
+	return kept
+}
+
+// excludeRepos drops the results in the repositories a rule excludes, whichever
+// source found them. Code search leaves out what fits in its query, the rest and
+// the other sources are filtered here.
+func excludeRepos(rule models.MonitorRule, items []*github.SearchResultItem) []*github.SearchResultItem {
+	repos, err := github.ParseExcludeRepos(rule.ExcludeRepos)
+	if err != nil {
+		log.Printf("Failed to parse excluded repositories for rule %d: %v", rule.ID, err)
+		return items
+	}
+	if len(repos) == 0 {
+		return items
+	}
+
+	kept := make([]*github.SearchResultItem, 0, len(items))
+	for _, item := range items {
+		excluded := false
+		for _, repo := range repos {
+			// GitHub names are case-insensitive
+			if strings.EqualFold(item.RepoFullName, repo) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, item)
+		}
+	}
+
+	if len(kept) != len(items) {
+		log.Printf("Excluded repositories of rule %d: %d -> %d results", rule.ID, len(items), len(kept))
+	}
+	return kept
+}
+
+// skipsRepo reports whether a rule skips a repository
+func skipsRepo(rule models.MonitorRule, info repoInfo, now time.Time) bool {
+	if rule.SkipArchived && info.archived {
+		return true
+	}
+	age := now.Sub(info.createdAt)
+	day := 24 * time.Hour
+	if rule.MinRepoAgeDays > 0 && age < time.Duration(rule.MinRepoAgeDays)*day {
+		return true
+	}
+	return rule.MaxRepoAgeDays > 0 && age > time.Duration(rule.MaxRepoAgeDays)*day
+}
+
+// repoInfo returns the archived state and creation time of a repository, from the
+// cache while it is fresh
+func (m *MonitorService) repoInfo(ctx context.Context, repoFullName string, now time.Time) (repoInfo, error) {
+	m.repoInfoMu.Lock()
+	info, ok := m.repoInfos[repoFullName]
+	m.repoInfoMu.Unlock()
+	if ok && now.Sub(info.fetchedAt) < repoInfoTTL {
+		return info, nil
+	}
+
+	repo, err := m.searchService.GetRepository(ctx, repoFullName)
+	if err != nil {
+		return repoInfo{}, err
+	}
+	info = repoInfo{archived: repo.Archived, createdAt: repo.CreatedAt, fetchedAt: now}
+
+	m.repoInfoMu.Lock()
+	defer m.repoInfoMu.Unlock()
+	if m.repoInfos == nil {
+		m.repoInfos = make(map[string]repoInfo)
+	}
+	for name, cached := range m.repoInfos {
+		if now.Sub(cached.fetchedAt) >= repoInfoTTL {
+			delete(m.repoInfos, name)
+		}
+	}
+	m.repoInfos[repoFullName] = info
+	return info, nil
+}
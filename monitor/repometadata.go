@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+)
+
+// searchRepoMetadata finds repositories whose description or topics contain every
+// keyword of a rule. Brand and project names often show up there on repositories
+// whose files code search doesn't index, e.g. forks, archives or large files.
+func (m *MonitorService) searchRepoMetadata(ctx context.Context, keywords []string, dialect string) ([]*github.SearchResultItem, error) {
+	query := repoMetadataQuery(keywords, dialect)
+	if query == "" {
+		return nil, nil
+	}
+
+	repos, err := m.searchService.SearchRepositories(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*github.SearchResultItem, 0)
+	for _, repo := range repos {
+		metadata := repo.Description
+		if len(repo.Topics) > 0 {
+			metadata += "\nTopics: " + strings.Join(repo.Topics, ", ")
+		}
+		matched, _ := github.MatchContent(metadata, keywords, dialect)
+		if matched == nil {
+			continue
+		}
+
+		items = append(items, &github.SearchResultItem{
+			RepoFullName:    repo.FullName,
+			RepoURL:         repo.HTMLURL,
+			FileURL:         repo.HTMLURL,
+			HTMLURL:         repo.HTMLURL,
+			MatchedKeywords: matched,
+			ContentSnippet:  metadata,
+			Score:           1.0,
+			CreatedAt:       time.Now(),
+			Source:          models.SourceRepoMetadata,
+		})
+	}
+
+	return items, nil
+}
+
+// repoMetadataQuery searches descriptions and topics for every plain term of a
+// rule, not qualifiers, regular expressions or negated terms, empty when there is none
+func repoMetadataQuery(keywords []string, dialect string) string {
+	var terms []string
+	for _, keyword := range keywords {
+		if term, ok := github.ParseTerm(keyword, dialect); ok && term.Plain() {
+			terms = append(terms, fmt.Sprintf("%q", term.Text))
+		}
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+	return strings.Join(terms, " ") + " in:description,topics"
+}
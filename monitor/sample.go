@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/redact"
+	"github-monitor/repository"
+)
+
+// sampleSize is how many hits a sample keeps
+const sampleSize = 20
+
+// recordSample counts the hits a sample_only rule found in one source and keeps
+// a random sample of them, instead of recording them as results. Nothing is
+// notified, so a candidate keyword can be tried on live data.
+func (m *MonitorService) recordSample(ctx context.Context, rule models.MonitorRule, items []*github.SearchResultItem) {
+	if len(items) == 0 || m.repos.Samples == nil {
+		return
+	}
+
+	unique := make([]*github.SearchResultItem, 0, len(items))
+	found := make(map[repository.FileKey]bool, len(items))
+	repos := make(map[string]bool)
+	keywordHits := make(map[string]int)
+	for _, item := range items {
+		key := repository.FileKey{RepoFullName: item.RepoFullName, FilePath: item.FilePath}
+		if found[key] {
+			continue
+		}
+		found[key] = true
+		unique = append(unique, item)
+		repos[item.RepoFullName] = true
+		for _, keyword := range item.MatchedKeywords {
+			keywordHits[keyword]++
+		}
+	}
+
+	rand.Shuffle(len(unique), func(i, j int) { unique[i], unique[j] = unique[j], unique[i] })
+	hits := make([]models.SampleHit, 0, min(len(unique), sampleSize))
+	for _, item := range unique[:min(len(unique), sampleSize)] {
+		hits = append(hits, models.SampleHit{
+			RepoFullName:    item.RepoFullName,
+			FilePath:        item.FilePath,
+			HTMLURL:         item.HTMLURL,
+			MatchedKeywords: item.MatchedKeywords,
+			// Samples are kept whatever happens to the rule, the secrets in them aren't
+			ContentSnippet: redact.Secrets(item.ContentSnippet),
+		})
+	}
+	sample, _ := json.Marshal(hits)
+	counts := ""
+	if len(keywordHits) > 0 {
+		encoded, _ := json.Marshal(keywordHits)
+		counts = string(encoded)
+	}
+	source := unique[0].Source
+	if source == "" {
+		source = models.SourceGitHub
+	}
+
+	err := m.repos.Samples.Add(ctx, &models.RuleSample{
+		RuleID:      rule.ID,
+		ProjectID:   rule.ProjectID,
+		Source:      source,
+		Hits:        len(unique),
+		Repos:       len(repos),
+		KeywordHits: counts,
+		Sample:      string(sample),
+	})
+	if err != nil {
+		log.Printf("Failed to record the sample of rule %d: %v", rule.ID, err)
+		return
+	}
+	log.Printf("Sampled %d hits in %d repositories of rule %d", len(unique), len(repos), rule.ID)
+}
@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github-monitor/cache"
+	"github-monitor/db/models"
+)
+
+// SetCache shares scan claims and notification dedup windows with other instances
+// through the cache. A file is notified at most once per rule within dedupWindow.
+func (m *MonitorService) SetCache(shared cache.Cache, dedupWindow time.Duration) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.shared = shared
+	m.dedupWindow = dedupWindow
+}
+
+func (m *MonitorService) getCache() (cache.Cache, time.Duration) {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.shared, m.dedupWindow
+}
+
+// claimScan reports whether this instance should run a scheduled scan of name. The
+// claim is kept for most of the scan interval, so an instance that scanned it lets
+// the others skip it until the next cycle. It's given up when the scan fails, and a
+// cache outage lets every instance scan rather than none.
+func (m *MonitorService) claimScan(ctx context.Context, name string) (release func(), ok bool) {
+	shared, _ := m.getCache()
+	if shared == nil {
+		return func() {}, true
+	}
+
+	release, ok, err := shared.Claim(ctx, "scan:"+name, m.ScanInterval()*9/10)
+	if err != nil {
+		log.Printf("Failed to claim scan of %s, scanning anyway: %v", name, err)
+		return func() {}, true
+	}
+	return release, ok
+}
+
+// dedupNotifications drops results another instance notified within the dedup window
+func (m *MonitorService) dedupNotifications(ctx context.Context, rule models.MonitorRule, results []models.SearchResult) []models.SearchResult {
+	shared, window := m.getCache()
+	if shared == nil || window == 0 {
+		return results
+	}
+
+	fresh := results[:0:0]
+	for _, result := range results {
+		key := fmt.Sprintf("notified:%d:%s/%s", rule.ID, result.RepoFullName, result.FilePath)
+		_, ok, err := shared.Claim(ctx, key, window)
+		if err != nil {
+			log.Printf("Failed to check notification dedup window: %v", err)
+		}
+		if ok || err != nil {
+			fresh = append(fresh, result)
+		}
+	}
+	return fresh
+}
@@ -0,0 +1,53 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/events"
+	"github-monitor/reporting"
+)
+
+const (
+	// snoozeCheckInterval is how often results whose snooze ended are resurfaced
+	snoozeCheckInterval = time.Minute
+	// snoozeBatchSize caps the results resurfaced per check
+	snoozeBatchSize = 500
+)
+
+// endSnoozes puts results whose snooze ended back in the pending queue and sends
+// one reminder per rule
+func (m *MonitorService) endSnoozes(ctx context.Context) {
+	defer reporting.Recover(reporting.Tags{"component": "snooze"})
+
+	expired, err := m.repos.Results.ListSnoozeExpired(ctx, time.Now(), snoozeBatchSize)
+	if err != nil {
+		log.Printf("Failed to fetch snoozed results: %v", err)
+		return
+	}
+
+	rules := make(map[uint]models.MonitorRule)
+	ended := make(map[uint][]models.SearchResult)
+	for _, result := range expired {
+		ok, err := m.repos.Results.EndSnooze(ctx, result.ID, *result.SnoozedUntil)
+		if err != nil {
+			log.Printf("Failed to end the snooze of result %d: %v", result.ID, err)
+			continue
+		}
+		if !ok {
+			continue // snoozed again or ended meanwhile
+		}
+
+		result.SnoozedUntil = nil
+		events.PublishTo(result.ProjectID, events.TypeResultSnoozed, result)
+		rules[result.RuleID] = result.Rule
+		ended[result.RuleID] = append(ended[result.RuleID], result)
+	}
+
+	for ruleID, results := range ended {
+		log.Printf("Snooze of %d results of rule %d ended", len(results), ruleID)
+		m.notifySnoozeEnded(rules[ruleID], results)
+	}
+}
@@ -0,0 +1,197 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/repository"
+	"github-monitor/typosquat"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// typosquatRuleName is the built-in rule lookalike repositories are recorded under
+	typosquatRuleName = "Lookalike repositories"
+	// typosquatTermsPerQuery stays within the five OR operators repository search allows
+	typosquatTermsPerQuery = 5
+)
+
+// TyposquatWatch lists the organization and product names to look for lookalike
+// repositories of
+type TyposquatWatch struct {
+	Names       []string
+	Owners      []string // our own GitHub owners, their repositories are never flagged
+	MaxVariants int      // lookalikes searched per name
+	Severity    string
+	Interval    time.Duration // between searches, they run with the scan cycle
+}
+
+// SetTyposquatWatch enables the lookalike repository search, a watch without names
+// disables it
+func (m *MonitorService) SetTyposquatWatch(watch TyposquatWatch) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.typosquatWatch = watch
+}
+
+func (m *MonitorService) getTyposquatWatch() TyposquatWatch {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.typosquatWatch
+}
+
+// scanTyposquats searches repository names for our names and lookalikes of them,
+// recording the repositories of other owners that use one: they could be phishing
+// or distribute malware under our brand. Each repository is reported once. Unless
+// forced, the search waits for the interval since the previous one.
+func (m *MonitorService) scanTyposquats(ctx context.Context, force bool) error {
+	watch := m.getTyposquatWatch()
+	if len(watch.Names) == 0 || m.searchService == nil {
+		return nil
+	}
+
+	rule, err := m.typosquatRule(ctx, watch)
+	if err != nil {
+		log.Printf("Failed to prepare the lookalike repository rule: %v", err)
+		return err
+	}
+	if !force {
+		last, err := m.repos.History.ListAfter(ctx, repository.HistoryFilter{RuleID: rule.ID}, 0, 1)
+		if err != nil {
+			return err
+		}
+		if len(last) > 0 && time.Since(last[0].CreatedAt) < watch.Interval {
+			return nil
+		}
+	}
+
+	startTime := time.Now()
+	var variants []string
+	for _, name := range watch.Names {
+		variants = append(variants, typosquat.Variants(name, watch.MaxVariants)...)
+	}
+	owners := make(map[string]bool, len(watch.Owners))
+	for _, owner := range watch.Owners {
+		owners[strings.ToLower(owner)] = true
+	}
+
+	var (
+		items   []*github.SearchResultItem
+		failed  []string
+		queries int
+		seen    = make(map[string]bool)
+	)
+	terms := append(append([]string(nil), watch.Names...), variants...)
+	for start := 0; start < len(terms); start += typosquatTermsPerQuery {
+		end := min(start+typosquatTermsPerQuery, len(terms))
+		query := strings.Join(terms[start:end], " OR ") + " in:name"
+		if queries > 0 {
+			// Repository search allows 30 requests a minute
+			if err := github.Sleep(ctx, 2*time.Second); err != nil {
+				return err
+			}
+		}
+		queries++
+
+		repos, err := m.searchService.SearchRepositories(ctx, query)
+		if err != nil {
+			log.Printf("Lookalike repository search %q failed: %v", query, err)
+			failed = append(failed, err.Error())
+			continue
+		}
+		for _, repo := range repos {
+			if seen[repo.FullName] || owners[strings.ToLower(repo.Owner)] {
+				continue
+			}
+			if match := typosquat.Match(repo.Name, watch.Names, variants); match != "" {
+				seen[repo.FullName] = true
+				items = append(items, typosquatItem(repo, match))
+			}
+		}
+	}
+
+	newResults := m.saveResults(ctx, *rule, items)
+	if len(newResults) > 0 {
+		m.notifyNewResults(*rule, newResults)
+	}
+
+	duration := int(time.Since(startTime).Seconds())
+	log.Printf("Lookalike repository search completed: %d lookalikes, %d new results, took %d seconds",
+		len(items), len(newResults), duration)
+
+	if len(failed) == queries {
+		err := errors.New(strings.Join(failed, "; "))
+		m.recordScanHistory(ctx, *rule, 0, 0, "", "failed", err.Error(), duration)
+		return err
+	}
+	m.recordScanHistory(ctx, *rule, len(items), len(newResults), "", "success", strings.Join(failed, "; "), duration)
+	return nil
+}
+
+// typosquatRule returns the rule lookalike repositories belong to, creating it on
+// first use. It stays inactive so the regular scan doesn't search code with it, and
+// follows the configured names and severity.
+func (m *MonitorService) typosquatRule(ctx context.Context, watch TyposquatWatch) (*models.MonitorRule, error) {
+	keywords, err := json.Marshal(watch.Names)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := m.repos.Rules.GetByName(ctx, typosquatRuleName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		rule = &models.MonitorRule{
+			Name:        typosquatRuleName,
+			Description: "Repositories of other owners named like our organizations and products, or typos of them (phishing, malware). Managed through the typosquat section of config.yaml.",
+			Keywords:    string(keywords),
+			MatchType:   "fuzzy",
+			Severity:    watch.Severity,
+		}
+		if err := m.repos.Rules.Create(ctx, rule); err != nil {
+			return nil, err
+		}
+		// is_active has a database default of true, which Create applies to false
+		rule.IsActive = false
+		return rule, m.repos.Rules.Save(ctx, rule)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.Keywords != string(keywords) || rule.Severity != watch.Severity {
+		rule.Keywords = string(keywords)
+		rule.Severity = watch.Severity
+		if err := m.repos.Rules.Save(ctx, rule); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
+
+// typosquatItem turns a lookalike repository into a result, keyed by its name
+func typosquatItem(repo github.Repository, match string) *github.SearchResultItem {
+	snippet := fmt.Sprintf("Repository named like %q, created %s, %d stars",
+		match, repo.CreatedAt.UTC().Format("2006-01-02"), repo.Stars)
+	if repo.Description != "" {
+		snippet += "\n" + repo.Description
+	}
+
+	return &github.SearchResultItem{
+		RepoFullName:    repo.FullName,
+		RepoURL:         repo.HTMLURL,
+		FileURL:         repo.HTMLURL,
+		HTMLURL:         repo.HTMLURL,
+		MatchedKeywords: []string{match},
+		ContentSnippet:  snippet,
+		Score:           1.0,
+		CreatedAt:       time.Now(),
+		Source:          models.SourceTyposquat,
+	}
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github-monitor/github"
+	"github-monitor/i18n"
+	"github-monitor/notify"
+	"github-monitor/reporting"
+	"github-monitor/settings"
+)
+
+// SetUsageTracker samples the rate limits of the tokens on each watchdog check,
+// nil stops forecasting
+func (m *MonitorService) SetUsageTracker(tracker *github.UsageTracker) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.usage = tracker
+}
+
+// SetExhaustionWarning warns when the tokens are forecast to run out within the
+// given time before their limit resets, 0 disables the warning
+func (m *MonitorService) SetExhaustionWarning(within time.Duration) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.exhaustionWarning = within
+}
+
+func (m *MonitorService) getUsage() (*github.UsageTracker, time.Duration) {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.usage, m.exhaustionWarning
+}
+
+// UsageForecast is the forecast of the token pool with the scan interval that
+// would make the calls last until the reset
+type UsageForecast struct {
+	github.UsageForecast
+	ScanInterval      string `json:"scan_interval"`
+	SuggestedInterval string `json:"suggested_interval,omitempty"` // set when the tokens run out before the reset
+}
+
+// UsageForecast returns when the tokens run out at the current pace, false when
+// the usage isn't tracked
+func (m *MonitorService) UsageForecast(now time.Time) (UsageForecast, bool) {
+	tracker, _ := m.getUsage()
+	if tracker == nil {
+		return UsageForecast{}, false
+	}
+
+	interval := m.ScanInterval()
+	forecast := UsageForecast{UsageForecast: tracker.Forecast(now), ScanInterval: interval.String()}
+	if forecast.ExhaustsAt != nil {
+		forecast.SuggestedInterval = forecast.SustainableInterval(interval, now).String()
+	}
+	return forecast, true
+}
+
+// checkUsage samples the token usage and warns once per reset window when the
+// tokens are forecast to run out soon, returning the reset warned about
+func (m *MonitorService) checkUsage(warned time.Time, now time.Time) time.Time {
+	tracker, within := m.getUsage()
+	if tracker == nil {
+		return warned
+	}
+	tracker.Sample(now)
+	if within <= 0 {
+		return warned
+	}
+
+	forecast, _ := m.UsageForecast(now)
+	if forecast.ExhaustsAt == nil || forecast.ExhaustsAt.Sub(now) > within || forecast.ResetAt.Equal(warned) {
+		return warned
+	}
+
+	minutes := int(forecast.ExhaustsAt.Sub(now).Minutes())
+	log.Printf("GitHub tokens forecast to run out in %d minutes, %.0f calls a minute with %d left", minutes, forecast.PerMinute, forecast.Remaining)
+	message := notify.Message{
+		TitleText: i18n.Format("GitHub tokens running out"),
+		ContentText: i18n.Format("At the current pace of %.0f API calls a minute, the GitHub tokens run out in %d minutes, before their limit resets at %s. Raising the scan interval from %s to %s makes the %d calls left last until then.\n",
+			forecast.PerMinute, minutes, forecast.ResetAt.UTC().Format("15:04 UTC"), forecast.ScanInterval, forecast.SuggestedInterval, forecast.Remaining),
+	}
+	if dashboardURL := settings.Current().DashboardURL; dashboardURL != "" {
+		message.URL = strings.TrimSuffix(dashboardURL, "/")
+	}
+
+	if m.notificationsEnabled() {
+		m.notifying.Add(1)
+		go func() {
+			defer m.notifying.Done()
+			defer reporting.Recover(reporting.Tags{"component": "notify"})
+			// An operational alert, every channel gets it whatever its project
+			notify.Broadcast(context.Background(), m.repos.Notifications, message, nil)
+		}()
+	}
+	return *forecast.ResetAt
+}
@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/repository"
+)
+
+// maxRejectedPerRule caps the files remembered as not matching for each rule
+const maxRejectedPerRule = 10000
+
+// rejectedFiles remembers the files of precise rules whose content didn't contain
+// every keyword, by blob SHA, so unchanged files aren't fetched again every scan
+type rejectedFiles struct {
+	mu    sync.Mutex
+	rules map[uint]map[repository.FileKey]string
+}
+
+func (r *rejectedFiles) has(ruleID uint, key repository.FileKey, sha string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sha != "" && r.rules[ruleID][key] == sha
+}
+
+func (r *rejectedFiles) add(ruleID uint, key repository.FileKey, sha string) {
+	if sha == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rules == nil {
+		r.rules = make(map[uint]map[repository.FileKey]string)
+	}
+	files := r.rules[ruleID]
+	if files == nil || len(files) >= maxRejectedPerRule {
+		files = make(map[repository.FileKey]string)
+		r.rules[ruleID] = files
+	}
+	files[key] = sha
+}
+
+// verifyMatches checks that the files code search found for a precise rule contain
+// every keyword, since multi-keyword search is approximate. Files that don't are
+// dropped, verified ones are marked and carry their content on as evidence. Files
+// that can't be fetched are kept unverified rather than lost.
+func (m *MonitorService) verifyMatches(ctx context.Context, rule models.MonitorRule, keywords []string, items []*github.SearchResultItem) []*github.SearchResultItem {
+	verified := make([]*github.SearchResultItem, 0, len(items))
+	for _, item := range items {
+		if item.Source != "" && item.Source != models.SourceGitHub {
+			verified = append(verified, item)
+			continue
+		}
+
+		key := repository.FileKey{RepoFullName: item.RepoFullName, FilePath: item.FilePath}
+		if m.rejected.has(rule.ID, key, item.SHA) {
+			continue
+		}
+
+		content := item.Content
+		if content == "" {
+			if m.searchService == nil {
+				verified = append(verified, item)
+				continue
+			}
+			file, err := m.searchService.GetFileContent(ctx, item.RepoFullName, item.FilePath, "")
+			if err != nil {
+				log.Printf("Keeping %s/%s of rule %d unverified: %v", item.RepoFullName, item.FilePath, rule.ID, err)
+				verified = append(verified, item)
+				continue
+			}
+			content = file.Content
+		}
+
+		matched, snippet := github.MatchContent(content, keywords, rule.QueryDialect)
+		if matched == nil {
+			m.rejected.add(rule.ID, key, item.SHA)
+			continue
+		}
+		item.MatchedKeywords = matched
+		item.ContentSnippet = snippet
+		item.Content = content
+		item.Verified = true
+		verified = append(verified, item)
+	}
+
+	if len(verified) < len(items) {
+		log.Printf("Keyword verification of rule %d: %d -> %d results", rule.ID, len(items), len(verified))
+	}
+	return verified
+}
@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github-monitor/i18n"
+	"github-monitor/notify"
+	"github-monitor/reporting"
+	"github-monitor/settings"
+)
+
+// watchdogInterval is how often the watchdog checks the heartbeat of the monitoring loop
+const watchdogInterval = time.Minute
+
+// Stalled reports whether the monitor is running but its loop hasn't reported a
+// heartbeat in over two scan intervals, such as after it died on a panic. A scan
+// cycle can legitimately take longer than the interval, so two of them are allowed.
+func (m *MonitorService) Stalled() bool {
+	return m.stalledAt(time.Now())
+}
+
+func (m *MonitorService) stalledAt(now time.Time) bool {
+	return m.IsRunning() && now.Sub(m.LastHeartbeat()) > 2*m.ScanInterval()
+}
+
+// watch runs next to the monitoring loop, so it notices when the loop stops
+// reporting heartbeats
+func (m *MonitorService) watch(ctx context.Context) {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	stalled := false
+	var warned time.Time // reset of the tokens whose exhaustion was warned about
+	for {
+		select {
+		case now := <-ticker.C:
+			stalled = m.checkStall(stalled, now)
+			warned = m.checkUsage(warned, now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkStall alerts once when the loop stalls and once when it recovers, and
+// returns whether it is stalled now
+func (m *MonitorService) checkStall(wasStalled bool, now time.Time) bool {
+	stalled := m.stalledAt(now)
+	if stalled == wasStalled {
+		return stalled
+	}
+
+	lastHeartbeat := m.LastHeartbeat().UTC().Format("2006-01-02 15:04 UTC")
+	var message notify.Message
+	if stalled {
+		err := fmt.Errorf("monitor loop has not reported a heartbeat since %s", lastHeartbeat)
+		log.Printf("Monitor stalled: %v", err)
+		reporting.CaptureError(err, reporting.Tags{"component": "monitor"})
+		message = notify.Message{
+			TitleText: i18n.Format("GitHub monitor stalled"),
+			ContentText: i18n.Format("The monitoring loop has not reported a heartbeat since %s, over two scan intervals of %s. No new leaks are found until it is restarted.\n",
+				lastHeartbeat, m.ScanInterval()),
+		}
+	} else {
+		log.Println("Monitor recovered from a stall")
+		message = notify.Message{
+			TitleText:   i18n.Format("GitHub monitor recovered"),
+			ContentText: i18n.Format("The monitoring loop reported a heartbeat again at %s.\n", lastHeartbeat),
+		}
+	}
+	if dashboardURL := settings.Current().DashboardURL; dashboardURL != "" {
+		message.URL = strings.TrimSuffix(dashboardURL, "/")
+	}
+
+	if m.notificationsEnabled() {
+		m.notifying.Add(1)
+		go func() {
+			defer m.notifying.Done()
+			defer reporting.Recover(reporting.Tags{"component": "notify"})
+			// An operational alert, every channel gets it whatever its project
+			notify.Broadcast(context.Background(), m.repos.Notifications, message, nil)
+		}()
+	}
+	return stalled
+}
@@ -0,0 +1,40 @@
+package monitor
+
+import (
+	"context"
+	"log"
+
+	"github-monitor/db/models"
+	"github-monitor/webhooks"
+)
+
+// queueWebhooks queues the new results of a rule for each of its enabled webhooks.
+// The dispatcher sends them, so a slow receiver doesn't hold up the scan.
+func (m *MonitorService) queueWebhooks(ctx context.Context, rule models.MonitorRule, results []models.SearchResult) {
+	if len(results) == 0 || m.repos.Webhooks == nil {
+		return
+	}
+	hooks, err := m.repos.Webhooks.ListByRule(ctx, rule.ID)
+	if err != nil {
+		log.Printf("Failed to fetch the webhooks of rule %d: %v", rule.ID, err)
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, hook := range hooks {
+		if !hook.Enabled {
+			continue
+		}
+		for _, result := range results {
+			delivery, err := webhooks.NewDelivery(hook, rule, result)
+			if err != nil {
+				log.Printf("Failed to encode result %d for webhook %d: %v", result.ID, hook.ID, err)
+				continue
+			}
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	if err := m.repos.Webhooks.Enqueue(ctx, deliveries); err != nil {
+		log.Printf("Failed to queue %d webhook deliveries of rule %d: %v", len(deliveries), rule.ID, err)
+	}
+}
@@ -0,0 +1,147 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github-monitor/i18n"
+	"github-monitor/redact"
+)
+
+// Triage actions offered next to each result in a notification
+const (
+	ActionConfirm       = "confirm"
+	ActionFalsePositive = "false_positive"
+	ActionWhitelist     = "whitelist"
+)
+
+// Actions lists the triage actions with their button labels, in display order
+var Actions = []struct {
+	Name  string
+	Label string
+}{
+	{ActionConfirm, "Confirm"},
+	{ActionFalsePositive, "False positive"},
+	{ActionWhitelist, "Whitelist repo"},
+}
+
+// ResultRef is a result listed in a notification
+type ResultRef struct {
+	ID      uint
+	Label   string // repo/path
+	HTMLURL string
+	Snippet string // matched content, sent with its secrets masked
+
+	Translation string // the masked snippet in the notification language, empty when it needs none
+}
+
+// maxSnippetLength caps the snippet shown with a result in a notification
+const maxSnippetLength = 200
+
+// MaskedSnippet returns the snippet on a single line with secret-looking values
+// masked, so the channel the alert goes to doesn't hold the leaked credentials
+func (r ResultRef) MaskedSnippet() string {
+	return singleLine(redact.Secrets(r.Snippet))
+}
+
+// singleLine returns text on a single line of at most maxSnippetLength bytes,
+// without backticks that would end the code span it is shown in
+func singleLine(text string) string {
+	snippet := strings.Join(strings.Fields(text), " ")
+	if len(snippet) > maxSnippetLength {
+		cut := maxSnippetLength
+		for cut > 0 && !utf8.RuneStart(snippet[cut]) {
+			cut--
+		}
+		snippet = snippet[:cut] + "..."
+	}
+	return strings.ReplaceAll(snippet, "`", "'")
+}
+
+var actionLinks struct {
+	mu      sync.RWMutex
+	baseURL string
+	secret  []byte
+	expiry  time.Duration
+}
+
+// ConfigureActions enables triage links in notifications. Links point at baseURL,
+// carry an HMAC signature made with secret and stop working after expiry. An empty
+// secret disables them.
+func ConfigureActions(baseURL, secret string, expiry time.Duration) {
+	actionLinks.mu.Lock()
+	defer actionLinks.mu.Unlock()
+	actionLinks.baseURL = strings.TrimSuffix(baseURL, "/")
+	actionLinks.secret = []byte(secret)
+	actionLinks.expiry = expiry
+}
+
+// ActionsEnabled reports whether notifications carry triage actions
+func ActionsEnabled() bool {
+	actionLinks.mu.RLock()
+	defer actionLinks.mu.RUnlock()
+	return len(actionLinks.secret) > 0
+}
+
+// ActionURL returns the signed link performing an action on a result, or "" when
+// actions are disabled
+func ActionURL(resultID uint, action string) string {
+	actionLinks.mu.RLock()
+	defer actionLinks.mu.RUnlock()
+	if len(actionLinks.secret) == 0 {
+		return ""
+	}
+
+	expires := time.Now().Add(actionLinks.expiry).Unix()
+	params := url.Values{
+		"result":  {strconv.FormatUint(uint64(resultID), 10)},
+		"action":  {action},
+		"expires": {strconv.FormatInt(expires, 10)},
+		"sig":     {signAction(actionLinks.secret, resultID, action, expires)},
+	}
+	return actionLinks.baseURL + "/api/v1/callbacks/triage?" + params.Encode()
+}
+
+// VerifyAction checks the signature and expiry of a triage link
+func VerifyAction(resultID uint, action string, expires int64, sig string) error {
+	actionLinks.mu.RLock()
+	defer actionLinks.mu.RUnlock()
+	if len(actionLinks.secret) == 0 {
+		return errors.New("triage actions are disabled")
+	}
+	if !hmac.Equal([]byte(sig), []byte(signAction(actionLinks.secret, resultID, action, expires))) {
+		return errors.New("invalid signature")
+	}
+	if time.Now().Unix() > expires {
+		return errors.New("link has expired")
+	}
+	return nil
+}
+
+func signAction(secret []byte, resultID uint, action string, expires int64) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d\n%s\n%d", resultID, action, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// actionLinksMarkdown renders the triage links of a result as markdown in a
+// locale, "" when actions are disabled
+func actionLinksMarkdown(resultID uint, locale string) string {
+	if !ActionsEnabled() {
+		return ""
+	}
+	links := make([]string, 0, len(Actions))
+	for _, action := range Actions {
+		links = append(links, fmt.Sprintf("[%s](%s)", i18n.Translate(locale, action.Label), ActionURL(resultID, action.Name)))
+	}
+	return strings.Join(links, " · ")
+}
@@ -2,16 +2,21 @@ package notify
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github-monitor/db/models"
+	"github-monitor/i18n"
+	"github-monitor/repository"
 )
 
 // Message represents a notification message
@@ -19,6 +24,60 @@ type Message struct {
 	Title   string
 	Content string
 	URL     string
+	Results []ResultRef // listed below the content, with triage actions when enabled
+	More    int         // results left out of the list
+
+	// Set instead of Title and Content, rendered in the locale of each channel
+	TitleText   i18n.Text
+	ContentText i18n.Text
+	// Locale is the one the message is rendered in, the default locale when empty
+	Locale string
+}
+
+// In returns the message rendered in a locale, empty for the default one
+func (m Message) In(locale string) Message {
+	if locale == "" {
+		locale = i18n.Default()
+	}
+	m.Locale = locale
+	if !m.TitleText.IsZero() {
+		m.Title = m.TitleText.In(locale)
+	}
+	if !m.ContentText.IsZero() {
+		m.Content = m.ContentText.In(locale)
+	}
+	return m
+}
+
+// text translates a fixed text of the templates into the locale of the message
+func (m Message) text(format string, args ...interface{}) string {
+	return i18n.Sprintf(m.Locale, format, args...)
+}
+
+// Markdown renders the content and the listed results as markdown
+func (m Message) Markdown() string {
+	if len(m.Results) == 0 {
+		return m.Content
+	}
+
+	var b strings.Builder
+	b.WriteString(m.Content)
+	for _, result := range m.Results {
+		fmt.Fprintf(&b, "\n- [%s](%s)", result.Label, result.HTMLURL)
+		if snippet := result.MaskedSnippet(); snippet != "" {
+			fmt.Fprintf(&b, "\n  `%s`", snippet)
+		}
+		if result.Translation != "" {
+			b.WriteString("\n  " + m.text("Translation: %s", result.Translation))
+		}
+		if links := actionLinksMarkdown(result.ID, m.Locale); links != "" {
+			b.WriteString("\n  " + links)
+		}
+	}
+	if m.More > 0 {
+		b.WriteString("\n\n" + m.text("...and %d more", m.More))
+	}
+	return b.String()
 }
 
 // Notifier interface for different notification types
@@ -33,7 +92,7 @@ func (w *WeCom) Send(config *models.NotificationConfig, message Message) error {
 	payload := map[string]interface{}{
 		"msgtype": "markdown",
 		"markdown": map[string]string{
-			"content": fmt.Sprintf("## %s\n\n%s\n\n[查看详情](%s)", message.Title, message.Content, message.URL),
+			"content": fmt.Sprintf("## %s\n\n%s\n\n[%s](%s)", message.Title, message.Markdown(), message.text("View details"), message.URL),
 		},
 	}
 
@@ -60,7 +119,7 @@ func (d *DingTalk) Send(config *models.NotificationConfig, message Message) erro
 		"msgtype": "markdown",
 		"markdown": map[string]interface{}{
 			"title": message.Title,
-			"text":  fmt.Sprintf("## %s\n\n%s\n\n[查看详情](%s)", message.Title, message.Content, message.URL),
+			"text":  fmt.Sprintf("## %s\n\n%s\n\n[%s](%s)", message.Title, message.Markdown(), message.text("View details"), message.URL),
 		},
 	}
 
@@ -100,7 +159,7 @@ func (f *Feishu) Send(config *models.NotificationConfig, message Message) error
 					"tag":  "div",
 					"text": map[string]string{
 						"tag":     "lark_md",
-						"content": message.Content,
+						"content": message.Markdown(),
 					},
 				},
 				map[string]interface{}{
@@ -110,7 +169,7 @@ func (f *Feishu) Send(config *models.NotificationConfig, message Message) error
 							"tag": "button",
 							"text": map[string]string{
 								"tag":     "plain_text",
-								"content": "查看详情",
+								"content": message.text("View details"),
 							},
 							"type": "primary",
 							"url":  message.URL,
@@ -140,10 +199,23 @@ func generateFeishuSign(secret string, timestamp int64) string {
 type Webhook struct{}
 
 func (wh *Webhook) Send(config *models.NotificationConfig, message Message) error {
+	results := make([]map[string]interface{}, 0, len(message.Results))
+	for _, result := range message.Results {
+		results = append(results, map[string]interface{}{
+			"id":       result.ID,
+			"label":    result.Label,
+			"html_url": result.HTMLURL,
+			"snippet":  result.MaskedSnippet(),
+
+			"translation": result.Translation,
+		})
+	}
+
 	payload := map[string]interface{}{
 		"title":   message.Title,
-		"content": message.Content,
+		"content": message.Markdown(),
 		"url":     message.URL,
+		"results": results,
 		"time":    time.Now().Format(time.RFC3339),
 	}
 
@@ -180,6 +252,8 @@ func GetNotifier(notifType string) Notifier {
 		return &DingTalk{}
 	case "feishu":
 		return &Feishu{}
+	case "slack":
+		return &Slack{}
 	case "webhook":
 		return &Webhook{}
 	default:
@@ -187,6 +261,25 @@ func GetNotifier(notifType string) Notifier {
 	}
 }
 
+// Broadcast sends a message to every enabled channel accepted by the filter
+func Broadcast(ctx context.Context, channels repository.NotificationRepo, message Message, filter func(config *models.NotificationConfig) bool) {
+	configs, err := channels.ListEnabled(ctx)
+	if err != nil {
+		log.Printf("Failed to load notification channels: %v", err)
+		return
+	}
+
+	for i := range configs {
+		if filter != nil && !filter(&configs[i]) {
+			continue
+		}
+
+		if err := SendNotification(&configs[i], message); err != nil {
+			log.Printf("Failed to send notification via %s (%s): %v", configs[i].Name, configs[i].Type, err)
+		}
+	}
+}
+
 // SendNotification sends a notification using the specified config
 func SendNotification(config *models.NotificationConfig, message Message) error {
 	if !config.Enabled {
@@ -194,5 +287,5 @@ func SendNotification(config *models.NotificationConfig, message Message) error
 	}
 
 	notifier := GetNotifier(config.Type)
-	return notifier.Send(config, message)
+	return notifier.Send(config, message.In(config.Locale))
 }
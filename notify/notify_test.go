@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+
+	"github-monitor/i18n"
+)
+
+func TestMessageIn(t *testing.T) {
+	message := Message{
+		TitleText:   i18n.Format("GitHub leak alert: %s", "aws"),
+		ContentText: i18n.Format("Rule **%s** found %d new potential leaks:\n", "aws", 12),
+		Results:     []ResultRef{{Label: "octo/app/.env", HTMLURL: "https://github.com/octo/app/blob/main/.env"}},
+		More:        11,
+	}
+
+	english := message.In(i18n.English)
+	if english.Title != "GitHub leak alert: aws" || !strings.HasSuffix(english.Markdown(), "...and 11 more") {
+		t.Errorf("In(en) = %q\n%s", english.Title, english.Markdown())
+	}
+	chinese := message.In(i18n.Chinese)
+	if chinese.Title != "GitHub 泄露告警：aws" || !strings.HasPrefix(chinese.Content, "规则 **aws** 发现 12 条") {
+		t.Errorf("In(zh) = %q, %q", chinese.Title, chinese.Content)
+	}
+	if got := chinese.text("View details"); got != "查看详情" {
+		t.Errorf("text() = %q in zh", got)
+	}
+
+	// Messages written without texts keep their title and content
+	plain := Message{Title: "Test", Content: "Test notification"}.In(i18n.Chinese)
+	if plain.Title != "Test" || plain.Content != "Test notification" {
+		t.Errorf("In(zh) changed a plain message to %q, %q", plain.Title, plain.Content)
+	}
+}
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MailServer is the SMTP server rule owners are emailed through
+type MailServer struct {
+	Host     string
+	Port     int
+	Username string // empty sends without authentication
+	Password string
+	From     string
+}
+
+var ownerMail struct {
+	mu     sync.RWMutex
+	server MailServer
+}
+
+// sendMail is swapped out in tests
+var sendMail = smtp.SendMail
+
+// ConfigureOwnerMail emails the notifications of rules to their owners through
+// server, an empty host disables it
+func ConfigureOwnerMail(server MailServer) {
+	ownerMail.mu.Lock()
+	defer ownerMail.mu.Unlock()
+	ownerMail.server = server
+}
+
+// NotifyOwner emails a message to the owner of a rule. Owners that aren't email
+// addresses can't be reached and are skipped, as are all owners while owner mail
+// isn't configured.
+func NotifyOwner(owner string, message Message) error {
+	ownerMail.mu.RLock()
+	server := ownerMail.server
+	ownerMail.mu.RUnlock()
+	if server.Host == "" || !strings.Contains(owner, "@") {
+		return nil
+	}
+
+	var auth smtp.Auth
+	if server.Username != "" {
+		auth = smtp.PlainAuth("", server.Username, server.Password, server.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", server.Host, server.Port)
+	// Owners have no locale of their own
+	message = message.In("")
+	if err := sendMail(addr, auth, server.From, []string{owner}, ownerEmail(server.From, owner, message, time.Now())); err != nil {
+		return fmt.Errorf("failed to email rule owner %s: %w", owner, err)
+	}
+	return nil
+}
+
+// ownerEmail renders a message as a plain text email
+func ownerEmail(from, to string, message Message, now time.Time) []byte {
+	// Titles carry rule names, which must not add headers
+	subject := strings.Join(strings.Fields(message.Title), " ")
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(message.Markdown(), "\n", "\r\n"))
+	if message.URL != "" {
+		msg.WriteString("\r\n\r\n" + message.URL)
+	}
+	msg.WriteString("\r\n")
+	return msg.Bytes()
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestNotifyOwner(t *testing.T) {
+	var sent []string
+	var body string
+	sendMail = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		sent = append(sent, to...)
+		body = string(msg)
+		return nil
+	}
+	defer func() { sendMail = smtp.SendMail }()
+	defer ConfigureOwnerMail(MailServer{})
+
+	message := Message{Title: "Rule scan failed: leak\r\nBcc: someone@example.com", Content: "The scan failed"}
+
+	// Nothing is sent until owner mail is configured
+	if err := NotifyOwner("alice@example.com", message); err != nil || len(sent) != 0 {
+		t.Fatalf("sent to %v (%v) while unconfigured", sent, err)
+	}
+
+	ConfigureOwnerMail(MailServer{Host: "smtp.example.com", Port: 587, From: "monitor@example.com"})
+	if err := NotifyOwner("alice", message); err != nil || len(sent) != 0 {
+		t.Fatalf("sent to %v (%v) for an owner without an address", sent, err)
+	}
+	if err := NotifyOwner("alice@example.com", message); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0] != "alice@example.com" {
+		t.Fatalf("sent to %v, want alice@example.com", sent)
+	}
+	if !strings.Contains(body, "Subject: Rule scan failed: leak Bcc: someone@example.com\r\n") {
+		t.Errorf("title added a header:\n%s", body)
+	}
+}
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github-monitor/db/models"
+	"github-monitor/i18n"
+)
+
+// slackInteractive is set when Slack interaction payloads can be verified, then
+// the buttons post back to the server instead of opening signed links
+var slackInteractive bool
+
+// SetSlackInteractive switches Slack buttons between interaction payloads and links
+func SetSlackInteractive(enabled bool) {
+	actionLinks.mu.Lock()
+	defer actionLinks.mu.Unlock()
+	slackInteractive = enabled
+}
+
+// Slack implements Slack incoming webhook notification with Block Kit
+type Slack struct{}
+
+func (s *Slack) Send(config *models.NotificationConfig, message Message) error {
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "header",
+			"text": map[string]string{"type": "plain_text", "text": message.Title},
+		},
+		slackSection(slackMarkdown(message.Content)),
+	}
+
+	for _, result := range message.Results {
+		text := fmt.Sprintf("<%s|%s>", result.HTMLURL, result.Label)
+		if snippet := result.MaskedSnippet(); snippet != "" {
+			text += "\n`" + snippet + "`"
+		}
+		if result.Translation != "" {
+			text += "\n" + message.text("Translation: %s", result.Translation)
+		}
+		blocks = append(blocks, slackSection(text))
+		if buttons := slackButtons(result.ID, message.Locale); len(buttons) > 0 {
+			blocks = append(blocks, map[string]interface{}{
+				"type":     "actions",
+				"block_id": fmt.Sprintf("result_%d", result.ID),
+				"elements": buttons,
+			})
+		}
+	}
+	if message.More > 0 {
+		blocks = append(blocks, slackSection(message.text("...and %d more", message.More)))
+	}
+	if message.URL != "" {
+		blocks = append(blocks, slackSection(fmt.Sprintf("<%s|%s>", message.URL, message.text("View details"))))
+	}
+
+	payload := map[string]interface{}{
+		"text":   message.Title, // shown in push notifications
+		"blocks": blocks,
+	}
+	return sendWebhook(config.WebhookURL, payload)
+}
+
+func slackSection(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "section",
+		"text": map[string]string{"type": "mrkdwn", "text": text},
+	}
+}
+
+// slackButtons returns the triage buttons of a result in a locale, none when
+// actions are disabled
+func slackButtons(resultID uint, locale string) []interface{} {
+	if !ActionsEnabled() {
+		return nil
+	}
+
+	actionLinks.mu.RLock()
+	interactive := slackInteractive
+	actionLinks.mu.RUnlock()
+
+	buttons := make([]interface{}, 0, len(Actions))
+	for _, action := range Actions {
+		button := map[string]interface{}{
+			"type":      "button",
+			"action_id": "triage_" + action.Name,
+			"text":      map[string]string{"type": "plain_text", "text": i18n.Translate(locale, action.Label)},
+		}
+		if interactive {
+			button["value"] = fmt.Sprintf("%s:%d", action.Name, resultID)
+		} else {
+			button["url"] = ActionURL(resultID, action.Name)
+		}
+		if action.Name == ActionConfirm {
+			button["style"] = "danger"
+		}
+		buttons = append(buttons, button)
+	}
+	return buttons
+}
+
+// slackMarkdown converts the markdown used in messages to Slack mrkdwn
+func slackMarkdown(text string) string {
+	return strings.ReplaceAll(text, "**", "*")
+}
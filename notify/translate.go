@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Translation providers
+const (
+	TranslateDeepL          = "deepl"
+	TranslateLibreTranslate = "libretranslate"
+)
+
+// deeplURL is the DeepL API used when no URL is configured, paid plans use api.deepl.com
+const deeplURL = "https://api-free.deepl.com"
+
+// Translation is the service the snippets of notifications are translated with
+type Translation struct {
+	Provider       string // deepl or libretranslate, empty disables translation
+	URL            string // base URL of the API, required for libretranslate
+	APIKey         string
+	TargetLanguage string // language code such as en or de
+	Timeout        time.Duration
+}
+
+var translation struct {
+	mu      sync.RWMutex
+	service Translation
+}
+
+// ConfigureTranslation translates the snippets of notifications through service,
+// an empty provider disables it
+func ConfigureTranslation(service Translation) {
+	translation.mu.Lock()
+	defer translation.mu.Unlock()
+	translation.service = service
+}
+
+// TranslateSnippets sets the translation of the listed results whose snippets
+// contain letters outside ASCII, so analysts can read comments and strings in a
+// language they don't speak. Only masked snippets are sent to the provider. When
+// the translation fails the message is left as it is.
+func TranslateSnippets(message *Message) error {
+	translation.mu.RLock()
+	service := translation.service
+	translation.mu.RUnlock()
+	if service.Provider == "" {
+		return nil
+	}
+
+	var texts []string
+	var indexes []int
+	for i, result := range message.Results {
+		if snippet := result.MaskedSnippet(); needsTranslation(snippet) {
+			texts = append(texts, snippet)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), service.Timeout)
+	defer cancel()
+	translated, err := service.translate(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to translate snippets through %s: %w", service.Provider, err)
+	}
+	if len(translated) != len(texts) {
+		return fmt.Errorf("%s returned %d translations for %d snippets", service.Provider, len(translated), len(texts))
+	}
+	for i, index := range indexes {
+		// Snippets already in the target language come back unchanged
+		if text := singleLine(translated[i]); text != "" && text != texts[i] {
+			message.Results[index].Translation = text
+		}
+	}
+	return nil
+}
+
+// needsTranslation reports whether a snippet has letters outside ASCII, code and
+// English text don't need translating
+func needsTranslation(snippet string) bool {
+	for _, r := range snippet {
+		if r > unicode.MaxASCII && unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// translate returns texts in the target language, in order
+func (t Translation) translate(ctx context.Context, texts []string) ([]string, error) {
+	switch t.Provider {
+	case TranslateDeepL:
+		base := t.URL
+		if base == "" {
+			base = deeplURL
+		}
+		var response struct {
+			Translations []struct {
+				Text string `json:"text"`
+			} `json:"translations"`
+		}
+		request := map[string]interface{}{
+			"text":        texts,
+			"target_lang": strings.ToUpper(t.TargetLanguage),
+		}
+		headers := map[string]string{"Authorization": "DeepL-Auth-Key " + t.APIKey}
+		if err := postJSON(ctx, strings.TrimSuffix(base, "/")+"/v2/translate", headers, request, &response); err != nil {
+			return nil, err
+		}
+		translated := make([]string, 0, len(response.Translations))
+		for _, translation := range response.Translations {
+			translated = append(translated, translation.Text)
+		}
+		return translated, nil
+
+	case TranslateLibreTranslate:
+		var response struct {
+			TranslatedText []string `json:"translatedText"`
+		}
+		request := map[string]interface{}{
+			"q":      texts,
+			"source": "auto",
+			"target": strings.ToLower(t.TargetLanguage),
+			"format": "text",
+		}
+		if t.APIKey != "" {
+			request["api_key"] = t.APIKey
+		}
+		if err := postJSON(ctx, strings.TrimSuffix(t.URL, "/")+"/translate", nil, request, &response); err != nil {
+			return nil, err
+		}
+		return response.TranslatedText, nil
+	}
+	return nil, fmt.Errorf("unsupported translation provider %q", t.Provider)
+}
+
+// postJSON posts a JSON request and decodes the JSON response into out
+func postJSON(ctx context.Context, url string, headers map[string]string, request, out interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
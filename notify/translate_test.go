@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranslateSnippets(t *testing.T) {
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Text       []string `json:"text"`
+			TargetLang string   `json:"target_lang"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		if r.URL.Path != "/v2/translate" || r.Header.Get("Authorization") != "DeepL-Auth-Key key" || request.TargetLang != "EN" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		sent = append(sent, request.Text...)
+		w.Write([]byte(`{"translations":[{"detected_source_language":"ZH","text":"production database password"}]}`))
+	}))
+	defer server.Close()
+	defer ConfigureTranslation(Translation{})
+
+	message := Message{Results: []ResultRef{
+		{Label: "octo/app/config.go", Snippet: "// 生产数据库密码\npassword = \"hunter2hunter2hunter2\""},
+		{Label: "octo/app/main.go", Snippet: "token := os.Getenv(\"TOKEN\")"},
+	}}
+
+	// Nothing is translated until a provider is configured
+	if err := TranslateSnippets(&message); err != nil || message.Results[0].Translation != "" {
+		t.Fatalf("translated %q (%v) while unconfigured", message.Results[0].Translation, err)
+	}
+
+	ConfigureTranslation(Translation{Provider: TranslateDeepL, URL: server.URL, APIKey: "key", TargetLanguage: "en", Timeout: time.Second})
+	if err := TranslateSnippets(&message); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || strings.Contains(sent[0], "hunter2") {
+		t.Fatalf("sent %q, want only the masked snippet with foreign text", sent)
+	}
+	if message.Results[0].Translation != "production database password" || message.Results[1].Translation != "" {
+		t.Errorf("translations = %q, %q", message.Results[0].Translation, message.Results[1].Translation)
+	}
+	if !strings.Contains(message.Markdown(), "\n  Translation: production database password") {
+		t.Errorf("Markdown() left out the translation:\n%s", message.Markdown())
+	}
+}
@@ -0,0 +1,166 @@
+// Package postman searches Postman's public API network. Collections shared in
+// public workspaces regularly contain internal hosts and live credentials.
+package postman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://www.postman.com"
+	pageSize       = 100
+)
+
+// Entity is a public collection or request returned by the search
+type Entity struct {
+	ID          string
+	Type        string // "collection" or "request"
+	Name        string
+	Description string
+	RequestURL  string // requests only, the URL the request is sent to
+	Publisher   string // handle of the user or team that published the workspace
+	Workspace   string // workspace slug
+}
+
+// URL returns the page of the entity on postman.com
+func (e *Entity) URL() string {
+	if e.Publisher == "" || e.Workspace == "" {
+		return defaultBaseURL + "/search?q=" + e.ID
+	}
+	return fmt.Sprintf("%s/%s/%s", e.WorkspaceURL(), e.Type, e.ID)
+}
+
+// WorkspaceURL returns the page of the public workspace holding the entity
+func (e *Entity) WorkspaceURL() string {
+	return fmt.Sprintf("%s/%s/workspace/%s", defaultBaseURL, e.Publisher, e.Workspace)
+}
+
+// Client talks to the search behind postman.com, which needs no credentials
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxPages   int
+}
+
+// NewClient creates a client reading up to maxPages pages of 100 results per search
+func NewClient(maxPages int) *Client {
+	if maxPages < 1 {
+		maxPages = 1
+	}
+	return &Client{
+		baseURL:    defaultBaseURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		maxPages:   maxPages,
+	}
+}
+
+// The website proxies search requests to its search service
+type searchRequest struct {
+	Service string     `json:"service"`
+	Method  string     `json:"method"`
+	Path    string     `json:"path"`
+	Body    searchBody `json:"body"`
+}
+
+type searchBody struct {
+	QueryIndices      []string `json:"queryIndices"`
+	QueryText         string   `json:"queryText"`
+	Size              int      `json:"size"`
+	From              int      `json:"from"`
+	RequestOrigin     string   `json:"requestOrigin"`
+	MergeEntities     bool     `json:"mergeEntities"`
+	NonNestedRequests bool     `json:"nonNestedRequests"`
+}
+
+type searchResponse struct {
+	Data []struct {
+		Document struct {
+			ID              string `json:"id"`
+			EntityType      string `json:"entityType"`
+			Name            string `json:"name"`
+			Description     string `json:"description"`
+			URL             string `json:"url"`
+			PublisherHandle string `json:"publisherHandle"`
+			Workspaces      []struct {
+				Slug string `json:"slug"`
+			} `json:"workspaces"`
+		} `json:"document"`
+	} `json:"data"`
+}
+
+// Search returns the public collections and requests Postman finds for the query
+func (c *Client) Search(ctx context.Context, query string) ([]Entity, error) {
+	var entities []Entity
+
+	for page := 0; page < c.maxPages; page++ {
+		payload, err := json.Marshal(searchRequest{
+			Service: "search",
+			Method:  http.MethodPost,
+			Path:    "/search-all",
+			Body: searchBody{
+				QueryIndices:      []string{"runtime.collection", "runtime.request"},
+				QueryText:         query,
+				Size:              pageSize,
+				From:              page * pageSize,
+				RequestOrigin:     "srp",
+				MergeEntities:     true,
+				NonNestedRequests: true,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/_api/ws/proxy", bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("postman search failed: %w", err)
+		}
+
+		var body searchResponse
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			err = fmt.Errorf("postman rate limit exceeded")
+		case resp.StatusCode != http.StatusOK:
+			err = fmt.Errorf("postman search returned %s", resp.Status)
+		default:
+			err = json.NewDecoder(resp.Body).Decode(&body)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range body.Data {
+			doc := item.Document
+			entity := Entity{
+				ID:          doc.ID,
+				Type:        doc.EntityType,
+				Name:        doc.Name,
+				Description: doc.Description,
+				RequestURL:  doc.URL,
+				Publisher:   doc.PublisherHandle,
+			}
+			if len(doc.Workspaces) > 0 {
+				entity.Workspace = doc.Workspaces[0].Slug
+			}
+			entities = append(entities, entity)
+		}
+		if len(body.Data) < pageSize {
+			break
+		}
+	}
+
+	return entities, nil
+}
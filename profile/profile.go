@@ -0,0 +1,232 @@
+// Package profile expands a company profile (domains, brand names, internal DNS
+// zones and email domains) into recommended rules. Every rule template whose only
+// variable the profile can fill is instantiated once per value, and the generated
+// rules follow the profile as it changes.
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/repository"
+	"github-monitor/ruletemplate"
+
+	"gorm.io/gorm"
+)
+
+// profileID is the primary key of the single profile row
+const profileID = 1
+
+// Profile describes the company
+type Profile struct {
+	Domains         []string  `json:"domains"`
+	BrandNames      []string  `json:"brand_names"`
+	InternalDomains []string  `json:"internal_domains"`
+	EmailSuffixes   []string  `json:"email_suffixes"` // "@" is optional
+	UpdatedBy       string    `json:"updated_by"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// FieldError reports an unusable profile value
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// fields maps template variables to the profile values that fill them
+var fields = []struct {
+	name     string
+	variable string
+	values   func(*Profile) *[]string
+}{
+	{"domains", "domain", func(p *Profile) *[]string { return &p.Domains }},
+	{"brand_names", "brand", func(p *Profile) *[]string { return &p.BrandNames }},
+	{"internal_domains", "internal_domain", func(p *Profile) *[]string { return &p.InternalDomains }},
+	{"email_suffixes", "email_domain", func(p *Profile) *[]string { return &p.EmailSuffixes }},
+}
+
+// Normalize trims the values, drops empty and duplicate ones and rejects values
+// that can't be searched for
+func (p *Profile) Normalize() error {
+	for _, field := range fields {
+		values := field.values(p)
+		seen := make(map[string]bool)
+		normalized := []string{}
+		for i, value := range *values {
+			value = strings.TrimSpace(value)
+			if field.variable == "email_domain" {
+				value = strings.TrimPrefix(value, "@")
+			}
+			if value == "" || seen[strings.ToLower(value)] {
+				continue
+			}
+			if !ruletemplate.ValidValue(value) {
+				return &FieldError{Field: fmt.Sprintf("%s[%d]", field.name, i), Message: "must not contain whitespace or quotes"}
+			}
+			seen[strings.ToLower(value)] = true
+			normalized = append(normalized, value)
+		}
+		*values = normalized
+	}
+	return nil
+}
+
+// Recommendation is a rule generated from the profile
+type Recommendation struct {
+	Key      string              `json:"key"` // stored as the rule's profile_key
+	Template string              `json:"template"`
+	Value    string              `json:"value"`
+	Rule     *models.MonitorRule `json:"rule"`
+}
+
+// Recommend expands the profile into rules, sorted by key
+func (p *Profile) Recommend() []Recommendation {
+	var recommendations []Recommendation
+	for _, template := range ruletemplate.List() {
+		if len(template.Variables) != 1 {
+			continue
+		}
+		for _, field := range fields {
+			if field.variable != template.Variables[0].Name {
+				continue
+			}
+			for _, value := range *field.values(p) {
+				rule, err := template.Instantiate(map[string]string{field.variable: value})
+				if err != nil {
+					continue
+				}
+				rule.ProfileKey = template.Name + ":" + strings.ToLower(value)
+				recommendations = append(recommendations, Recommendation{
+					Key:      rule.ProfileKey,
+					Template: template.Name,
+					Value:    value,
+					Rule:     rule,
+				})
+			}
+		}
+	}
+	sort.Slice(recommendations, func(i, j int) bool { return recommendations[i].Key < recommendations[j].Key })
+	return recommendations
+}
+
+// Load returns the stored profile, an empty one if it was never saved
+func Load(ctx context.Context, database *gorm.DB) (*Profile, error) {
+	var row models.CompanyProfile
+	err := database.WithContext(ctx).First(&row, profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Profile{Domains: []string{}, BrandNames: []string{}, InternalDomains: []string{}, EmailSuffixes: []string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Profile{UpdatedBy: row.UpdatedBy, UpdatedAt: row.UpdatedAt}
+	for _, field := range []struct {
+		stored string
+		values *[]string
+	}{
+		{row.Domains, &p.Domains},
+		{row.BrandNames, &p.BrandNames},
+		{row.InternalDomains, &p.InternalDomains},
+		{row.EmailSuffixes, &p.EmailSuffixes},
+	} {
+		*field.values = []string{}
+		if field.stored != "" {
+			if err := json.Unmarshal([]byte(field.stored), field.values); err != nil {
+				return nil, fmt.Errorf("invalid stored profile: %w", err)
+			}
+		}
+	}
+	return p, nil
+}
+
+// SyncResult counts the rules a save created and removed
+type SyncResult struct {
+	Created int `json:"created"`
+	Removed int `json:"removed"`
+}
+
+// Save stores the profile and syncs the generated rules with it in one transaction.
+// Rules for new values are created active; rules whose value was removed are
+// deleted. A generated rule deleted by hand isn't created again while its value
+// stays in the profile, while edits to generated rules are kept.
+func Save(ctx context.Context, database *gorm.DB, p *Profile, actor string) (SyncResult, error) {
+	var result SyncResult
+	if err := p.Normalize(); err != nil {
+		return result, err
+	}
+
+	encode := func(values []string) string {
+		b, _ := json.Marshal(values)
+		return string(b)
+	}
+	row := models.CompanyProfile{
+		ID:              profileID,
+		Domains:         encode(p.Domains),
+		BrandNames:      encode(p.BrandNames),
+		InternalDomains: encode(p.InternalDomains),
+		EmailSuffixes:   encode(p.EmailSuffixes),
+		UpdatedBy:       actor,
+	}
+
+	ctx = repository.WithNote(repository.WithActor(ctx, actor), "company profile")
+	err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rules := repository.NewGormRepositories(tx).Rules
+		if err := tx.Save(&row).Error; err != nil {
+			return err
+		}
+		p.UpdatedBy, p.UpdatedAt = row.UpdatedBy, row.UpdatedAt
+
+		// Deleted rules count as existing, so a rule removed by hand stays removed
+		var existing []models.MonitorRule
+		if err := tx.Unscoped().Select("id", "profile_key", "deleted_at").Where("profile_key <> ''").Find(&existing).Error; err != nil {
+			return err
+		}
+
+		wanted := make(map[string]*models.MonitorRule)
+		for _, recommendation := range p.Recommend() {
+			wanted[recommendation.Key] = recommendation.Rule
+		}
+
+		for _, rule := range existing {
+			if _, ok := wanted[rule.ProfileKey]; ok {
+				delete(wanted, rule.ProfileKey)
+				continue
+			}
+			// Clear the key so the rule is generated again if its value comes back
+			if err := tx.Unscoped().Model(&models.MonitorRule{}).Where("id = ?", rule.ID).Update("profile_key", "").Error; err != nil {
+				return err
+			}
+			if !rule.DeletedAt.Valid {
+				if err := rules.Delete(ctx, rule.ID); err != nil {
+					return err
+				}
+				result.Removed++
+			}
+		}
+
+		keys := make([]string, 0, len(wanted))
+		for key := range wanted {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := rules.Create(ctx, wanted[key]); err != nil {
+				return err
+			}
+			result.Created++
+		}
+		return nil
+	})
+	return result, err
+}
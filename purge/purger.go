@@ -0,0 +1,102 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/reporting"
+	"github-monitor/repository"
+)
+
+// Purger periodically removes the rows soft-deleted longer ago than the configured
+// age, so deleted tokens and other records don't accumulate forever
+type Purger struct {
+	repo     repository.PurgeRepo
+	maxAge   time.Duration
+	interval time.Duration
+	stopChan chan struct{} // nil while stopped
+}
+
+// NewPurger creates a purger of the soft-deleted rows
+func NewPurger(cfg *config.PurgeConfig, repo repository.PurgeRepo) (*Purger, error) {
+	maxAge, err := time.ParseDuration(cfg.MaxAge)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max age: %w", err)
+	}
+	interval, err := time.ParseDuration(cfg.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval: %w", err)
+	}
+	return &Purger{repo: repo, maxAge: maxAge, interval: interval}, nil
+}
+
+// Start runs the purger in the background, purging once right away
+func (p *Purger) Start() {
+	if p.stopChan != nil {
+		return
+	}
+	p.stopChan = make(chan struct{})
+	go p.run(p.stopChan)
+	log.Printf("Purge of deleted records started, every %s", p.interval)
+}
+
+// Stop stops the purger
+func (p *Purger) Stop() {
+	if p.stopChan == nil {
+		return
+	}
+	close(p.stopChan)
+	p.stopChan = nil
+}
+
+func (p *Purger) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	p.purgeOnce(time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			p.purgeOnce(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// purgeOnce removes the rows deleted more than the max age before now
+func (p *Purger) purgeOnce(now time.Time) {
+	defer reporting.Recover(reporting.Tags{"component": "purge"})
+
+	purged, err := p.repo.PurgeDeleted(context.Background(), now.Add(-p.maxAge))
+	if summary := summarize(purged); summary != "" {
+		log.Printf("Purged deleted records: %s", summary)
+	}
+	if err != nil {
+		log.Printf("Purge of deleted records failed: %v", err)
+		reporting.CaptureError(err, reporting.Tags{"component": "purge"})
+	}
+}
+
+// summarize lists the purged counts, such as "3 tokens, 1 whitelist", leaving out
+// the kinds nothing was purged of
+func summarize(purged map[string]int64) string {
+	kinds := make([]string, 0, len(purged))
+	for kind, count := range purged {
+		if count > 0 {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%d %s", purged[kind], kind))
+	}
+	return strings.Join(parts, ", ")
+}
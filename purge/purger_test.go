@@ -0,0 +1,34 @@
+package purge
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeRepo struct {
+	before time.Time
+}
+
+func (r *fakeRepo) PurgeDeleted(ctx context.Context, before time.Time) (map[string]int64, error) {
+	r.before = before
+	return map[string]int64{"tokens": 2}, nil
+}
+
+func TestPurgeOnce(t *testing.T) {
+	repo := &fakeRepo{}
+	p := &Purger{repo: repo, maxAge: 30 * 24 * time.Hour}
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+
+	p.purgeOnce(now)
+	if want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC); !repo.before.Equal(want) {
+		t.Errorf("purged rows deleted before %v, want %v", repo.before, want)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	got := summarize(map[string]int64{"whitelist": 1, "tokens": 3, "views": 0})
+	if want := "3 tokens, 1 whitelist"; got != want {
+		t.Errorf("summarize() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idleTimeout is how long an unused bucket is kept before being swept
+const idleTimeout = 10 * time.Minute
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Limiter is a keyed token bucket rate limiter
+type Limiter struct {
+	rate      float64 // tokens added per second
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// NewLimiter creates a limiter allowing perMinute requests per key with the given burst
+func NewLimiter(perMinute, burst int) *Limiter {
+	if burst <= 0 {
+		burst = perMinute
+	}
+
+	return &Limiter{
+		rate:      float64(perMinute) / 60,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token for the key. If none is left it returns false and how long to wait.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	// Refill for the time elapsed since the last request
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that have been idle long enough to be full again
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// KeyFunc derives the rate limiting key from a request
+type KeyFunc func(c *gin.Context) string
+
+// ByIP limits by client IP. X-Forwarded-For only counts when the request comes
+// through one of the server's trusted proxies.
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// BySession limits by the session of the validated token, falling back to the client
+// IP when auth is disabled. It must be used after auth.AuthMiddleware, keying on the
+// raw header would give every made-up token a fresh bucket.
+func BySession(c *gin.Context) string {
+	if claims := auth.GetClaims(c); claims != nil {
+		return "session:" + claims.ID
+	}
+	return ByIP(c)
+}
+
+// Middleware rejects requests over the limit with 429 Too Many Requests
+func Middleware(limiter *Limiter, keyFunc KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, wait := limiter.Allow(keyFunc(c))
+		if !allowed {
+			c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+			apierror.Respond(c, http.StatusTooManyRequests, apierror.CodeRateLimited, "Too many requests, please slow down")
+			return
+		}
+
+		c.Next()
+	}
+}
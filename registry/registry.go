@@ -0,0 +1,159 @@
+// Package registry looks up package names on public package registries, to spot
+// internal package names that someone published publicly (dependency confusion).
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Supported registries
+const (
+	NPM  = "npm"
+	PyPI = "pypi"
+)
+
+// Package is a package published on a public registry
+type Package struct {
+	Registry    string
+	Name        string
+	Version     string // latest version
+	Description string
+	Author      string
+	URL         string // human readable package page
+	Published   time.Time
+}
+
+// Client queries the public npm and PyPI registries, neither needs credentials
+type Client struct {
+	npmURL     string
+	pypiURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a registry client
+func NewClient() *Client {
+	return &Client{
+		npmURL:     "https://registry.npmjs.org",
+		pypiURL:    "https://pypi.org",
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Lookup returns the package with the given name, or nil when it isn't published
+func (c *Client) Lookup(ctx context.Context, registry, name string) (*Package, error) {
+	switch registry {
+	case NPM:
+		return c.lookupNPM(ctx, name)
+	case PyPI:
+		return c.lookupPyPI(ctx, name)
+	default:
+		return nil, fmt.Errorf("unsupported registry %q", registry)
+	}
+}
+
+type npmPackage struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	DistTags    map[string]string `json:"dist-tags"`
+	Time        map[string]string `json:"time"`
+	Maintainers []struct {
+		Name string `json:"name"`
+	} `json:"maintainers"`
+}
+
+func (c *Client) lookupNPM(ctx context.Context, name string) (*Package, error) {
+	// Scoped names like @scope/name are requested as @scope%2Fname
+	var body npmPackage
+	found, err := c.get(ctx, c.npmURL+"/"+url.PathEscape(name), &body)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	pkg := &Package{
+		Registry:    NPM,
+		Name:        body.Name,
+		Version:     body.DistTags["latest"],
+		Description: body.Description,
+		URL:         "https://www.npmjs.com/package/" + body.Name,
+	}
+	if len(body.Maintainers) > 0 {
+		pkg.Author = body.Maintainers[0].Name
+	}
+	if created, err := time.Parse(time.RFC3339, body.Time["created"]); err == nil {
+		pkg.Published = created
+	}
+	return pkg, nil
+}
+
+type pypiPackage struct {
+	Info struct {
+		Name       string `json:"name"`
+		Version    string `json:"version"`
+		Summary    string `json:"summary"`
+		Author     string `json:"author"`
+		ProjectURL string `json:"project_url"`
+	} `json:"info"`
+	Releases map[string][]struct {
+		UploadTime string `json:"upload_time_iso_8601"`
+	} `json:"releases"`
+}
+
+func (c *Client) lookupPyPI(ctx context.Context, name string) (*Package, error) {
+	var body pypiPackage
+	found, err := c.get(ctx, c.pypiURL+"/pypi/"+url.PathEscape(name)+"/json", &body)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	pkg := &Package{
+		Registry:    PyPI,
+		Name:        body.Info.Name,
+		Version:     body.Info.Version,
+		Description: body.Info.Summary,
+		Author:      body.Info.Author,
+		URL:         body.Info.ProjectURL,
+	}
+	if pkg.URL == "" {
+		pkg.URL = "https://pypi.org/project/" + body.Info.Name + "/"
+	}
+
+	// The first upload of any release is when the name was taken
+	for _, files := range body.Releases {
+		for _, file := range files {
+			uploaded, err := time.Parse(time.RFC3339, file.UploadTime)
+			if err == nil && (pkg.Published.IsZero() || uploaded.Before(pkg.Published)) {
+				pkg.Published = uploaded
+			}
+		}
+	}
+	return pkg, nil
+}
+
+// get decodes a JSON document, reporting false when the registry doesn't know it
+func (c *Client) get(ctx context.Context, address string, v interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, json.NewDecoder(resp.Body).Decode(v)
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s returned %s", address, resp.Status)
+	}
+}
@@ -0,0 +1,152 @@
+package report
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/repository"
+)
+
+// historyPageSize is how many scan history rows are read per query
+const historyPageSize = 1000
+
+// RuleScanStats aggregates the scans of one rule
+type RuleScanStats struct {
+	RuleID         uint             `json:"rule_id"`
+	RuleName       string           `json:"rule_name"`
+	Scans          int              `json:"scans"`
+	SuccessRate    float64          `json:"success_rate"`    // share of successful scans, 0-1
+	RateLimitRate  float64          `json:"rate_limit_rate"` // share of scans that ran into the rate limit, 0-1
+	FailureRate    float64          `json:"failure_rate"`    // share of scans that failed otherwise, 0-1
+	AvgDuration    float64          `json:"avg_duration"`    // seconds
+	MedianDuration float64          `json:"median_duration"` // seconds
+	LastStatus     string           `json:"last_status"`
+	Daily          []DailyScanStats `json:"daily"` // oldest day first
+}
+
+// DailyScanStats are the scans of a rule on one day of the configured timezone
+type DailyScanStats struct {
+	Date          string  `json:"date"` // YYYY-MM-DD
+	Scans         int     `json:"scans"`
+	AvgResults    float64 `json:"avg_results"`     // results returned per scan
+	AvgNewResults float64 `json:"avg_new_results"` // new results recorded per scan
+}
+
+type ruleScans struct {
+	stats     RuleScanStats
+	durations []int
+	statuses  map[string]int
+	lastAt    time.Time
+	days      map[string]*dailyScans
+}
+
+type dailyScans struct {
+	scans, results, newResults int
+}
+
+// ScanStats aggregates the scan history matching filter per rule, ordered by
+// success rate so chronically failing rules come first
+func ScanStats(ctx context.Context, history repository.HistoryRepo, filter repository.HistoryFilter) ([]RuleScanStats, error) {
+	rules := make(map[uint]*ruleScans)
+
+	var after uint64
+	for {
+		page, err := history.ListAfter(ctx, filter, after, historyPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, scan := range page {
+			add(rules, scan)
+		}
+		if len(page) < historyPageSize {
+			break
+		}
+		after = uint64(page[len(page)-1].ID)
+	}
+
+	stats := make([]RuleScanStats, 0, len(rules))
+	for _, rule := range rules {
+		stats = append(stats, rule.finish())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].SuccessRate != stats[j].SuccessRate {
+			return stats[i].SuccessRate < stats[j].SuccessRate
+		}
+		return stats[i].RuleID < stats[j].RuleID
+	})
+	return stats, nil
+}
+
+func add(rules map[uint]*ruleScans, scan models.ScanHistory) {
+	rule := rules[scan.RuleID]
+	if rule == nil {
+		rule = &ruleScans{
+			stats:    RuleScanStats{RuleID: scan.RuleID, RuleName: scan.Rule.Name},
+			statuses: make(map[string]int),
+			days:     make(map[string]*dailyScans),
+		}
+		rules[scan.RuleID] = rule
+	}
+
+	rule.stats.Scans++
+	rule.durations = append(rule.durations, scan.Duration)
+	rule.statuses[scan.Status]++
+	if scan.CreatedAt.After(rule.lastAt) {
+		rule.lastAt = scan.CreatedAt
+		rule.stats.LastStatus = scan.Status
+	}
+
+	date := scan.CreatedAt.In(config.Location()).Format("2006-01-02")
+	day := rule.days[date]
+	if day == nil {
+		day = &dailyScans{}
+		rule.days[date] = day
+	}
+	day.scans++
+	day.results += scan.ResultsCount
+	day.newResults += scan.NewResults
+}
+
+func (r *ruleScans) finish() RuleScanStats {
+	stats := r.stats
+	scans := float64(stats.Scans)
+	stats.SuccessRate = float64(r.statuses["success"]) / scans
+	stats.RateLimitRate = float64(r.statuses["rate_limited"]) / scans
+	stats.FailureRate = 1 - stats.SuccessRate - stats.RateLimitRate
+
+	total := 0
+	for _, duration := range r.durations {
+		total += duration
+	}
+	stats.AvgDuration = float64(total) / scans
+	sort.Ints(r.durations)
+	if middle := len(r.durations) / 2; len(r.durations)%2 == 1 {
+		stats.MedianDuration = float64(r.durations[middle])
+	} else {
+		stats.MedianDuration = float64(r.durations[middle-1]+r.durations[middle]) / 2
+	}
+
+	for date, day := range r.days {
+		stats.Daily = append(stats.Daily, DailyScanStats{
+			Date:          date,
+			Scans:         day.scans,
+			AvgResults:    float64(day.results) / float64(day.scans),
+			AvgNewResults: float64(day.newResults) / float64(day.scans),
+		})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool { return stats.Daily[i].Date < stats.Daily[j].Date })
+	return stats
+}
+
+// CSVCell keeps spreadsheets from evaluating a value found in someone else's
+// repository as a formula, by prefixing values that start like one with '
+func CSVCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
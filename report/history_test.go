@@ -0,0 +1,65 @@
+package report
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/repository"
+)
+
+// memoryHistory serves ListAfter from memory, newest first
+type memoryHistory struct {
+	repository.HistoryRepo
+	scans []models.ScanHistory // newest first
+}
+
+func (h *memoryHistory) ListAfter(ctx context.Context, filter repository.HistoryFilter, after uint64, limit int) ([]models.ScanHistory, error) {
+	var page []models.ScanHistory
+	for _, scan := range h.scans {
+		if (after == 0 || uint64(scan.ID) < after) && len(page) < limit {
+			page = append(page, scan)
+		}
+	}
+	return page, nil
+}
+
+func TestScanStats(t *testing.T) {
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	history := &memoryHistory{scans: []models.ScanHistory{
+		{ID: 6, RuleID: 2, Status: "failed", Duration: 1, CreatedAt: day2},
+		{ID: 5, RuleID: 1, Status: "rate_limited", Duration: 9, ResultsCount: 0, CreatedAt: day2},
+		{ID: 4, RuleID: 1, Status: "success", Duration: 3, ResultsCount: 10, NewResults: 2, CreatedAt: day2},
+		{ID: 3, RuleID: 1, Status: "success", Duration: 2, ResultsCount: 6, NewResults: 1, CreatedAt: day1},
+		{ID: 2, RuleID: 1, Status: "success", Duration: 4, ResultsCount: 4, NewResults: 0, CreatedAt: day1},
+		{ID: 1, RuleID: 2, Status: "success", Duration: 1, CreatedAt: day1},
+	}}
+
+	stats, err := ScanStats(context.Background(), history, repository.HistoryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[0].RuleID != 2 || stats[1].RuleID != 1 {
+		t.Fatalf("stats = %+v, want rule 2 (50%% success) before rule 1 (75%%)", stats)
+	}
+
+	rule := stats[1]
+	if rule.Scans != 4 || rule.SuccessRate != 0.75 || rule.RateLimitRate != 0.25 || rule.FailureRate != 0 {
+		t.Errorf("rates = %+v", rule)
+	}
+	if rule.AvgDuration != 4.5 || rule.MedianDuration != 3.5 {
+		t.Errorf("durations avg %g median %g, want 4.5 and 3.5", rule.AvgDuration, rule.MedianDuration)
+	}
+	if rule.LastStatus != "rate_limited" {
+		t.Errorf("last status %q", rule.LastStatus)
+	}
+	want := []DailyScanStats{
+		{Date: "2026-03-01", Scans: 2, AvgResults: 5, AvgNewResults: 0.5},
+		{Date: "2026-03-02", Scans: 2, AvgResults: 5, AvgNewResults: 1},
+	}
+	if len(rule.Daily) != 2 || rule.Daily[0] != want[0] || rule.Daily[1] != want[1] {
+		t.Errorf("daily = %+v, want %+v", rule.Daily, want)
+	}
+}
@@ -0,0 +1,174 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/repository"
+)
+
+// Summary holds the data shown in a summary report
+type Summary struct {
+	PeriodStart     time.Time       `json:"period_start"`
+	PeriodEnd       time.Time       `json:"period_end"`
+	NewFindings     int64           `json:"new_findings"`
+	BySeverity      []CountByKey    `json:"by_severity"`
+	ByRule          []CountByKey    `json:"by_rule"`
+	TopRepositories []CountByKey    `json:"top_repositories"`
+	Remediation     RemediationInfo `json:"remediation"`
+	Scans           ScanInfo        `json:"scans"`
+	Tokens          TokenInfo       `json:"tokens"`
+}
+
+// CountByKey is a single row of a grouped count
+type CountByKey = repository.KeyCount
+
+// RemediationInfo describes triage progress
+type RemediationInfo struct {
+	Confirmed      int64 `json:"confirmed"`        // confirmed during the period
+	FalsePositive  int64 `json:"false_positive"`   // dismissed during the period
+	PendingTotal   int64 `json:"pending_total"`    // pending at the end of the period
+	PendingOlder7d int64 `json:"pending_older_7d"` // pending for more than a week
+}
+
+// ScanInfo describes scan activity during the period
+type ScanInfo struct {
+	Total       int64 `json:"total"`
+	Failed      int64 `json:"failed"`
+	RateLimited int64 `json:"rate_limited"`
+}
+
+// TokenInfo describes the health of the token pool when the report was generated
+type TokenInfo struct {
+	Total     int `json:"total"`
+	Available int `json:"available"`
+}
+
+// Generate collects the report data for the given period
+func Generate(ctx context.Context, reports repository.ReportRepo, tokenPool *github.TokenPool, start, end time.Time) (*Summary, error) {
+	stats, err := reports.PeriodStats(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		PeriodStart:     start,
+		PeriodEnd:       end,
+		NewFindings:     stats.NewFindings,
+		BySeverity:      stats.BySeverity,
+		ByRule:          stats.ByRule,
+		TopRepositories: stats.TopRepositories,
+		Remediation: RemediationInfo{
+			Confirmed:      stats.Confirmed,
+			FalsePositive:  stats.FalsePositive,
+			PendingTotal:   stats.PendingTotal,
+			PendingOlder7d: stats.PendingOlder7d,
+		},
+		Scans: ScanInfo{
+			Total:       stats.Scans,
+			Failed:      stats.ScansFailed,
+			RateLimited: stats.ScansRateLimited,
+		},
+	}
+
+	if tokenPool != nil {
+		summary.Tokens.Total = tokenPool.TokenCount()
+		summary.Tokens.Available = tokenPool.AvailableTokenCount()
+	}
+
+	return summary, nil
+}
+
+// Save stores the report and its rendered HTML
+func Save(ctx context.Context, reports repository.ReportRepo, summary *Summary, html string) (*models.Report, error) {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.Report{
+		PeriodStart: summary.PeriodStart,
+		PeriodEnd:   summary.PeriodEnd,
+		Summary:     string(data),
+		HTML:        html,
+	}
+
+	if err := reports.Create(ctx, report); err != nil {
+		return nil, fmt.Errorf("failed to save report: %w", err)
+	}
+
+	return report, nil
+}
+
+// RenderHTML renders the summary as a self-contained HTML document suitable for email
+func RenderHTML(summary *Summary) (string, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, summary); err != nil {
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.String(), nil
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GitHub Monitor Summary</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #d0d7de; padding: 6px 12px; text-align: left; }
+th { background: #f6f8fa; }
+</style>
+</head>
+<body>
+<h1>GitHub Monitor Summary</h1>
+<p>{{.PeriodStart.Format "2006-01-02"}} &ndash; {{.PeriodEnd.Format "2006-01-02"}}</p>
+
+<h2>New findings: {{.NewFindings}}</h2>
+
+<h3>By severity</h3>
+<table>
+<tr><th>Severity</th><th>Findings</th></tr>
+{{range .BySeverity}}<tr><td>{{.Key}}</td><td>{{.Count}}</td></tr>
+{{else}}<tr><td colspan="2">No new findings</td></tr>
+{{end}}</table>
+
+<h3>By rule</h3>
+<table>
+<tr><th>Rule</th><th>Findings</th></tr>
+{{range .ByRule}}<tr><td>{{.Key}}</td><td>{{.Count}}</td></tr>
+{{else}}<tr><td colspan="2">No new findings</td></tr>
+{{end}}</table>
+
+<h3>Top repositories</h3>
+<table>
+<tr><th>Repository</th><th>Findings</th></tr>
+{{range .TopRepositories}}<tr><td><a href="https://github.com/{{.Key}}">{{.Key}}</a></td><td>{{.Count}}</td></tr>
+{{else}}<tr><td colspan="2">No new findings</td></tr>
+{{end}}</table>
+
+<h2>Remediation</h2>
+<table>
+<tr><th>Confirmed this period</th><td>{{.Remediation.Confirmed}}</td></tr>
+<tr><th>False positives this period</th><td>{{.Remediation.FalsePositive}}</td></tr>
+<tr><th>Pending</th><td>{{.Remediation.PendingTotal}}</td></tr>
+<tr><th>Pending for more than 7 days</th><td>{{.Remediation.PendingOlder7d}}</td></tr>
+</table>
+
+<h2>Operations</h2>
+<table>
+<tr><th>Scans</th><td>{{.Scans.Total}}</td></tr>
+<tr><th>Failed scans</th><td>{{.Scans.Failed}}</td></tr>
+<tr><th>Rate limited scans</th><td>{{.Scans.RateLimited}}</td></tr>
+<tr><th>Available tokens</th><td>{{.Tokens.Available}} / {{.Tokens.Total}}</td></tr>
+</table>
+</body>
+</html>
+`))
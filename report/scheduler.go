@@ -0,0 +1,237 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/reporting"
+	"github-monitor/repository"
+	"github-monitor/storage"
+)
+
+// Scheduler sends the weekly summary report
+type Scheduler struct {
+	reports   repository.ReportRepo
+	tokenPool *github.TokenPool
+	cfg       *config.ReportConfig
+	store     storage.Store // nil when reports are only kept in the database
+	stopChan  chan struct{} // nil while stopped
+}
+
+// NewScheduler creates a report scheduler
+func NewScheduler(reports repository.ReportRepo, tokenPool *github.TokenPool, cfg *config.ReportConfig) *Scheduler {
+	return &Scheduler{
+		reports:   reports,
+		tokenPool: tokenPool,
+		cfg:       cfg,
+	}
+}
+
+// SetStore keeps a copy of every generated report in object storage
+func (s *Scheduler) SetStore(store storage.Store) {
+	s.store = store
+}
+
+// Start runs the scheduler in the background, it can be started again after Stop
+func (s *Scheduler) Start() {
+	if s.stopChan != nil {
+		return
+	}
+	s.stopChan = make(chan struct{})
+	go s.run(s.stopChan)
+	log.Printf("Report scheduler started, sending weekly on %s at %02d:00 %s", s.cfg.Weekday, s.cfg.Hour, config.Location())
+}
+
+// Stop stops the scheduler
+func (s *Scheduler) Stop() {
+	if s.stopChan == nil {
+		return
+	}
+	close(s.stopChan)
+	s.stopChan = nil
+}
+
+func (s *Scheduler) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		s.sendIfDue(time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// sendIfDue sends the report if the scheduled time has passed and it wasn't sent yet this week
+func (s *Scheduler) sendIfDue(now time.Time) {
+	defer reporting.Recover(reporting.Tags{"component": "report"})
+	due := s.lastScheduledTime(now)
+
+	// Skip if this period was delivered already, or a failed attempt is less than an hour old
+	last, err := s.reports.Latest(context.Background(), due)
+	if err == nil && (last.Delivered || time.Since(last.CreatedAt) < time.Hour) {
+		return
+	}
+
+	if _, err := s.Send(due.AddDate(0, 0, -7), due); err != nil {
+		log.Printf("Failed to send weekly report: %v", err)
+		reporting.CaptureError(err, reporting.Tags{"component": "report"})
+	}
+}
+
+// lastScheduledTime returns the most recent scheduled send time at or before now,
+// in the configured timezone
+func (s *Scheduler) lastScheduledTime(now time.Time) time.Time {
+	now = now.In(config.Location())
+	weekday := parseWeekday(s.cfg.Weekday)
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), s.cfg.Hour, 0, 0, 0, now.Location())
+
+	for scheduled.Weekday() != weekday || scheduled.After(now) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+
+	return scheduled
+}
+
+// Send generates the report for the period, stores it and delivers it to the configured destinations
+func (s *Scheduler) Send(start, end time.Time) (*models.Report, error) {
+	// The dates of the report are those of the configured timezone
+	start, end = start.In(config.Location()), end.In(config.Location())
+	ctx := context.Background()
+	summary, err := Generate(ctx, s.reports, s.tokenPool, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := RenderHTML(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := Save(ctx, s.reports, summary, html)
+	if err != nil {
+		return nil, err
+	}
+	if s.store != nil {
+		s.storeHTML(report, html)
+	}
+
+	var errs []string
+	if s.cfg.WebhookURL != "" {
+		if err := sendWebhook(s.cfg.WebhookURL, summary, html); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if s.cfg.Email.SMTPHost != "" && len(s.cfg.Email.To) > 0 {
+		if err := sendEmail(&s.cfg.Email, summary, html); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	report.Delivered = len(errs) == 0
+	report.Error = strings.Join(errs, "; ")
+	if err := s.reports.SetDelivery(ctx, report.ID, report.Delivered, report.Error); err != nil {
+		log.Printf("Failed to record the delivery of report %d: %v", report.ID, err)
+	}
+
+	if !report.Delivered {
+		return report, fmt.Errorf("report delivery failed: %s", report.Error)
+	}
+
+	log.Printf("Summary report for %s - %s delivered", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	return report, nil
+}
+
+// storeHTML uploads the rendered report, a failure only costs the stored copy
+func (s *Scheduler) storeHTML(report *models.Report, html string) {
+	key := fmt.Sprintf("reports/%s-%d.html", report.PeriodEnd.Format("2006-01-02"), report.ID)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := s.store.Put(ctx, key, strings.NewReader(html), int64(len(html)), "text/html; charset=utf-8"); err != nil {
+		log.Printf("Failed to store report %d: %v", report.ID, err)
+		return
+	}
+	report.StorageKey = key
+	if err := s.reports.SetStorageKey(ctx, report.ID, key); err != nil {
+		log.Printf("Failed to record the stored copy of report %d: %v", report.ID, err)
+	}
+}
+
+func subject(summary *Summary) string {
+	return fmt.Sprintf("GitHub Monitor weekly summary %s - %s: %d new findings",
+		summary.PeriodStart.Format("2006-01-02"), summary.PeriodEnd.Format("2006-01-02"), summary.NewFindings)
+}
+
+// sendWebhook posts the report data and HTML as JSON
+func sendWebhook(url string, summary *Summary, html string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"title":   subject(summary),
+		"summary": summary,
+		"html":    html,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to send report webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("report webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// sendEmail sends the report as an HTML email
+func sendEmail(cfg *config.EmailConfig, summary *Summary, html string) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject(summary))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(html)
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.SMTPHost)
+	}
+
+	addr := fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort)
+	if err := smtp.SendMail(addr, auth, cfg.From, cfg.To, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send report email: %w", err)
+	}
+
+	return nil
+}
+
+func parseWeekday(name string) time.Weekday {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return d
+		}
+	}
+	return time.Monday
+}
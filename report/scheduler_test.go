@@ -0,0 +1,22 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github-monitor/config"
+)
+
+func TestLastScheduledTimeFollowsTimezone(t *testing.T) {
+	config.SetTimezone("Asia/Tokyo")
+	defer config.SetTimezone("")
+
+	s := NewScheduler(nil, nil, &config.ReportConfig{Weekday: "monday", Hour: 9})
+	// Sunday 23:30 UTC is Monday 08:30 in Tokyo, the Monday 09:00 send is still ahead
+	now := time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC)
+	got := s.lastScheduledTime(now)
+	want := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC) // Monday 09:00 in Tokyo a week earlier
+	if !got.Equal(want) {
+		t.Errorf("lastScheduledTime() = %s, want %s", got, want.In(config.Location()))
+	}
+}
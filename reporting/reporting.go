@@ -0,0 +1,125 @@
+// Package reporting sends errors and recovered panics to Sentry. Panics in request
+// handlers and background goroutines are recovered and logged even without Sentry,
+// so one bad scan doesn't take the monitor down.
+package reporting
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github-monitor/apierror"
+	"github-monitor/config"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+)
+
+// Tags describe where an error happened, e.g. the component and rule
+type Tags map[string]string
+
+var enabled bool
+
+// Init configures the Sentry client, errors are only logged until it's called
+func Init(cfg *config.SentryConfig) error {
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:         cfg.DSN,
+		Environment: cfg.Environment,
+		Release:     cfg.Release,
+		SampleRate:  cfg.SampleRate,
+	})
+	if err != nil {
+		return err
+	}
+	enabled = true
+	log.Printf("Reporting errors to Sentry (%s)", cfg.Environment)
+	return nil
+}
+
+// Flush waits up to timeout for queued events to be sent
+func Flush(timeout time.Duration) {
+	if enabled {
+		sentry.Flush(timeout)
+	}
+}
+
+// CaptureError reports err with tags
+func CaptureError(err error, tags Tags) {
+	if !enabled || err == nil {
+		return
+	}
+	hub := sentry.CurrentHub().Clone()
+	hub.Scope().SetTags(tags)
+	hub.CaptureException(err)
+}
+
+// Panic logs a recovered panic value with its stack, reports it and returns it as
+// an error for callers that carry on
+func Panic(value interface{}, tags Tags) error {
+	log.Printf("Recovered from panic %v: %v\n%s", tags, value, debug.Stack())
+	if enabled {
+		hub := sentry.CurrentHub().Clone()
+		hub.Scope().SetTags(tags)
+		hub.Recover(value)
+	}
+	return fmt.Errorf("panic: %v", value)
+}
+
+// Recover reports a panic of the calling goroutine and lets it return normally.
+// It must be deferred directly:
+//
+//	defer reporting.Recover(reporting.Tags{"component": "monitor"})
+func Recover(tags Tags) {
+	if value := recover(); value != nil {
+		Panic(value, tags)
+	}
+}
+
+// Middleware recovers panics in handlers with a 500 and reports them, along with
+// errors handlers attached to requests that ended in a 5xx
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			// The client went away, there is nothing to report
+			if err, ok := value.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				c.Abort()
+				return
+			}
+			if enabled {
+				hub := sentry.CurrentHub().Clone()
+				hub.Scope().SetRequest(c.Request)
+				hub.Scope().SetTags(requestTags(c))
+				hub.Recover(value)
+			}
+			log.Printf("Recovered from panic on %s %s: %v\n%s", c.Request.Method, c.Request.URL.Path, value, debug.Stack())
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Internal server error")
+		}()
+
+		c.Next()
+
+		if !enabled || c.Writer.Status() < http.StatusInternalServerError {
+			return
+		}
+		for _, err := range c.Errors {
+			hub := sentry.CurrentHub().Clone()
+			hub.Scope().SetRequest(c.Request)
+			hub.Scope().SetTags(requestTags(c))
+			hub.CaptureException(err.Err)
+		}
+	}
+}
+
+func requestTags(c *gin.Context) Tags {
+	return Tags{"component": "api", "method": c.Request.Method, "route": c.FullPath()}
+}
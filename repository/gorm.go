@@ -0,0 +1,1534 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github-monitor/db/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NewGormRepositories creates GORM backed repositories on the given connection
+func NewGormRepositories(database *gorm.DB) *Repositories {
+	return &Repositories{
+		Rules:         &gormRuleRepo{db: database},
+		Revisions:     &gormRevisionRepo{db: database},
+		Results:       &gormResultRepo{db: database},
+		Tokens:        &gormTokenRepo{db: database},
+		Whitelist:     &gormWhitelistRepo{db: database},
+		History:       &gormHistoryRepo{db: database},
+		Notifications: &gormNotificationRepo{db: database},
+		Projects:      &gormProjectRepo{db: database},
+		Checkpoints:   &gormCheckpointRepo{db: database},
+		Reports:       &gormReportRepo{db: database},
+		Sessions:      &gormSessionRepo{db: database},
+		Audit:         &gormAuditRepo{db: database},
+		Settings:      &gormSettingRepo{db: database},
+		Views:         &gormViewRepo{db: database},
+		Honeytokens:   &gormHoneytokenRepo{db: database},
+		Incidents:     &gormIncidentRepo{db: database},
+		ShareLinks:    &gormShareLinkRepo{db: database},
+		Webhooks:      &gormWebhookRepo{db: database},
+		Purge:         &gormPurgeRepo{db: database},
+		Staging:       &gormStagingRepo{db: database},
+		Samples:       &gormSampleRepo{db: database},
+		DB:            database,
+	}
+}
+
+// keyset restricts a query to the rows older than the cursor, newest first
+func keyset(query *gorm.DB, after uint64, limit int) *gorm.DB {
+	if after > 0 {
+		query = query.Where("id < ?", after)
+	}
+	return query.Order("id DESC").Limit(limit)
+}
+
+// inProjects restricts a query to the projects of ctx
+func inProjects(ctx context.Context, query *gorm.DB) *gorm.DB {
+	if ids, ok := ProjectsOf(ctx); ok {
+		return query.Where("project_id IN ?", ids)
+	}
+	return query
+}
+
+// ofProjectRules restricts a query on a table with a rule_id to the rules in the
+// projects of ctx, deleted rules included
+func ofProjectRules(ctx context.Context, database, query *gorm.DB) *gorm.DB {
+	if ids, ok := ProjectsOf(ctx); ok {
+		return query.Where("rule_id IN (?)", database.Unscoped().Model(&models.MonitorRule{}).Select("id").Where("project_id IN ?", ids))
+	}
+	return query
+}
+
+type gormRuleRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormRuleRepo) List(ctx context.Context) ([]models.MonitorRule, error) {
+	var rules []models.MonitorRule
+	err := inProjects(ctx, r.db.WithContext(ctx)).Find(&rules).Error
+	return rules, err
+}
+
+func (r *gormRuleRepo) ListActive(ctx context.Context) ([]models.MonitorRule, error) {
+	var rules []models.MonitorRule
+	err := inProjects(ctx, r.db.WithContext(ctx)).Where("is_active = ?", true).Find(&rules).Error
+	return rules, err
+}
+
+func (r *gormRuleRepo) Get(ctx context.Context, id uint) (*models.MonitorRule, error) {
+	var rule models.MonitorRule
+	if err := inProjects(ctx, r.db.WithContext(ctx)).First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *gormRuleRepo) GetByName(ctx context.Context, name string) (*models.MonitorRule, error) {
+	var rule models.MonitorRule
+	if err := inProjects(ctx, r.db.WithContext(ctx)).Where("name = ?", name).Order("id").First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *gormRuleRepo) Create(ctx context.Context, rule *models.MonitorRule) error {
+	rule.ScanPending = rule.IsActive
+	now := time.Now()
+	rule.ActiveSince = &now
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rule).Error; err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, rule.ID, "create", nil, rule)
+	})
+}
+
+func (r *gormRuleRepo) Save(ctx context.Context, rule *models.MonitorRule) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var before models.MonitorRule
+		if err := inProjects(ctx, tx).First(&before, rule.ID).Error; err != nil {
+			return err
+		}
+		if sameQuery(&before, rule) {
+			rule.QueryError = before.QueryError
+		} else {
+			rule.QueryError = ""
+		}
+		rule.ScanPending = rule.IsActive && (before.ScanPending || !before.IsActive)
+		rule.ActiveSince = before.ActiveSince
+		if rule.IsActive && !before.IsActive {
+			now := time.Now()
+			rule.ActiveSince = &now
+		}
+		if rule.IsActive {
+			rule.PausedReason = ""
+			rule.NeedsReview = false
+		}
+		if err := tx.Save(rule).Error; err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, rule.ID, "update", &before, rule)
+	})
+}
+
+// sameQuery reports whether two versions of a rule search GitHub with the same query
+func sameQuery(a, b *models.MonitorRule) bool {
+	return a.Keywords == b.Keywords && a.MatchType == b.MatchType && a.ExcludeExts == b.ExcludeExts &&
+		a.ExcludeRepos == b.ExcludeRepos && a.QueryDialect == b.QueryDialect && a.RawQuery == b.RawQuery &&
+		a.FileSize == b.FileSize
+}
+
+func (r *gormRuleRepo) SetQueryError(ctx context.Context, id uint, message string) error {
+	return r.db.WithContext(ctx).Model(&models.MonitorRule{}).Where("id = ?", id).UpdateColumn("query_error", message).Error
+}
+
+func (r *gormRuleRepo) ListScanPending(ctx context.Context) ([]models.MonitorRule, error) {
+	var rules []models.MonitorRule
+	err := inProjects(ctx, r.db.WithContext(ctx)).Where("is_active = ? AND scan_pending = ?", true, true).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func (r *gormRuleRepo) ClearScanPending(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.MonitorRule{}).Where("id = ?", id).UpdateColumn("scan_pending", false).Error
+}
+
+func (r *gormRuleRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var before models.MonitorRule
+		if err := inProjects(ctx, tx).First(&before, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.MonitorRule{}, id).Error; err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, id, "delete", &before, nil)
+	})
+}
+
+// recordRevision stores the change from before to after, either may be nil
+func recordRevision(ctx context.Context, tx *gorm.DB, ruleID uint, action string, before, after *models.MonitorRule) error {
+	revision := models.RuleRevision{RuleID: ruleID, Action: action}
+	revision.Actor, _ = ctx.Value(actorKey{}).(string)
+	revision.Note, _ = ctx.Value(noteKey{}).(string)
+
+	var beforeSnapshot, afterSnapshot RuleSnapshot
+	if before != nil {
+		beforeSnapshot = SnapshotOf(before)
+		encoded, _ := json.Marshal(beforeSnapshot)
+		revision.Before = string(encoded)
+		revision.KeywordsBefore = before.Keywords
+	}
+	if after != nil {
+		afterSnapshot = SnapshotOf(after)
+		encoded, _ := json.Marshal(afterSnapshot)
+		revision.After = string(encoded)
+		revision.KeywordsAfter = after.Keywords
+	}
+	if before != nil && after != nil && beforeSnapshot == afterSnapshot {
+		return nil
+	}
+	return tx.Create(&revision).Error
+}
+
+func (r *gormRuleRepo) Count(ctx context.Context, activeOnly bool) (int64, error) {
+	query := inProjects(ctx, r.db.WithContext(ctx).Model(&models.MonitorRule{}))
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+type gormRevisionRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormRevisionRepo) List(ctx context.Context, filter RevisionFilter, page Page) ([]models.RuleRevision, int64, error) {
+	query := ofProjectRules(ctx, r.db, r.db.WithContext(ctx).Model(&models.RuleRevision{}))
+	if filter.RuleID > 0 {
+		query = query.Where("rule_id = ?", filter.RuleID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var revisions []models.RuleRevision
+	err := query.Order("id DESC").Offset(page.Offset()).Limit(page.Size).Find(&revisions).Error
+	return revisions, total, err
+}
+
+func (r *gormRevisionRepo) Get(ctx context.Context, id uint) (*models.RuleRevision, error) {
+	var revision models.RuleRevision
+	if err := ofProjectRules(ctx, r.db, r.db.WithContext(ctx)).First(&revision, id).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+type gormResultRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormResultRepo) filtered(ctx context.Context, filter ResultFilter) *gorm.DB {
+	query := inProjects(ctx, r.db.WithContext(ctx).Model(&models.SearchResult{}))
+	if filter.RuleID > 0 {
+		query = query.Where("rule_id = ?", filter.RuleID)
+	}
+	if filter.IncidentID > 0 {
+		query = query.Where("incident_id = ?", filter.IncidentID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Verdict != "" {
+		query = query.Where("verdict = ?", filter.Verdict)
+	}
+	if len(filter.FileTypes) > 0 {
+		query = query.Where("file_type IN ?", filter.FileTypes)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		query = query.Where("created_at > ?", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		query = query.Where("created_at <= ?", filter.CreatedBefore)
+	}
+	if filter.Snoozed != nil {
+		if *filter.Snoozed {
+			query = query.Where("snoozed_until > ?", time.Now())
+		} else {
+			query = query.Where("snoozed_until IS NULL OR snoozed_until <= ?", time.Now())
+		}
+	}
+	return query
+}
+
+func (r *gormResultRepo) List(ctx context.Context, filter ResultFilter, page Page) ([]models.SearchResult, int64, error) {
+	query := r.filtered(ctx, filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Sort == SortVerdict {
+		query = query.Order("CASE WHEN verdict = 'secret' THEN verdict_confidence WHEN verdict = 'noise' THEN -verdict_confidence ELSE 0 END DESC")
+	}
+
+	var results []models.SearchResult
+	err := query.Preload("Rule").
+		Order("created_at DESC").
+		Limit(page.Size).
+		Offset(page.Offset()).
+		Find(&results).Error
+	return results, total, err
+}
+
+func (r *gormResultRepo) ListAfter(ctx context.Context, filter ResultFilter, after uint64, limit int) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+	err := keyset(r.filtered(ctx, filter).Preload("Rule"), after, limit).Find(&results).Error
+	return results, err
+}
+
+func (r *gormResultRepo) OpenStats(ctx context.Context, today, week time.Time, topRules int) (OpenResultStats, error) {
+	open := func() *gorm.DB {
+		return r.filtered(ctx, ResultFilter{}).Where("status IN ?", []string{"pending", "confirmed"})
+	}
+	stats := OpenResultStats{BySeverity: make(map[string]int64), ByRule: []RuleCount{}}
+
+	var severities []struct {
+		Severity string
+		Results  int64
+	}
+	if err := open().Select("severity, COUNT(*) AS results").Group("severity").Scan(&severities).Error; err != nil {
+		return OpenResultStats{}, err
+	}
+	for _, row := range severities {
+		stats.BySeverity[row.Severity] = row.Results
+		stats.Total += row.Results
+	}
+
+	var sinceWeek int64
+	if err := open().Where("created_at > ?", today).Count(&stats.ByAge.Today).Error; err != nil {
+		return OpenResultStats{}, err
+	}
+	if err := open().Where("created_at > ?", week).Count(&sinceWeek).Error; err != nil {
+		return OpenResultStats{}, err
+	}
+	stats.ByAge.ThisWeek = sinceWeek - stats.ByAge.Today
+	stats.ByAge.Older = stats.Total - sinceWeek
+
+	if topRules <= 0 {
+		return stats, nil
+	}
+	err := open().Select("rule_id, COUNT(*) AS results").Group("rule_id").
+		Order("results DESC, rule_id").Limit(topRules).Scan(&stats.ByRule).Error
+	if err != nil || len(stats.ByRule) == 0 {
+		return stats, err
+	}
+	ids := make([]uint, 0, len(stats.ByRule))
+	for _, rule := range stats.ByRule {
+		ids = append(ids, rule.RuleID)
+	}
+	// Deleted rules keep their results, and their names
+	var rules []models.MonitorRule
+	if err := r.db.WithContext(ctx).Unscoped().Select("id, name").Where("id IN ?", ids).Find(&rules).Error; err != nil {
+		return OpenResultStats{}, err
+	}
+	names := make(map[uint]string, len(rules))
+	for _, rule := range rules {
+		names[rule.ID] = rule.Name
+	}
+	for i := range stats.ByRule {
+		stats.ByRule[i].RuleName = names[stats.ByRule[i].RuleID]
+	}
+	return stats, nil
+}
+
+// severityByRank names the severities ranked by models.SeverityRank
+var severityByRank = []string{"info", "low", "medium", "high", "critical"}
+
+func (r *gormResultRepo) ByRepo(ctx context.Context, filter ResultFilter, page Page) ([]RepoSummary, int64, error) {
+	var total int64
+	if err := r.filtered(ctx, filter).Distinct("repo_full_name").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	rank := "CASE severity"
+	for i, severity := range severityByRank {
+		rank += fmt.Sprintf(" WHEN '%s' THEN %d", severity, i)
+	}
+	rank += " ELSE -1 END"
+
+	var rows []struct {
+		RepoFullName  string
+		Results       int64
+		OpenResults   int64
+		Pending       int64
+		Confirmed     int64
+		FalsePositive int64
+		Resolved      int64
+		SeverityRank  int
+		FirstID       uint
+		LastID        uint
+	}
+	err := r.filtered(ctx, filter).
+		Select(`repo_full_name, COUNT(*) AS results,
+			SUM(CASE WHEN status IN ('pending', 'confirmed') THEN 1 ELSE 0 END) AS open_results,
+			SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END) AS pending,
+			SUM(CASE WHEN status = 'confirmed' THEN 1 ELSE 0 END) AS confirmed,
+			SUM(CASE WHEN status = 'false_positive' THEN 1 ELSE 0 END) AS false_positive,
+			SUM(CASE WHEN status = 'resolved' THEN 1 ELSE 0 END) AS resolved,
+			MAX(CASE WHEN status IN ('pending', 'confirmed') THEN ` + rank + ` ELSE -1 END) AS severity_rank,
+			MIN(id) AS first_id, MAX(id) AS last_id`).
+		Group("repo_full_name").
+		Order("open_results DESC, severity_rank DESC, last_id DESC").
+		Limit(page.Size).
+		Offset(page.Offset()).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Ids grow with creation, the first and last result of each repository date it.
+	// Reading their times separately keeps aggregated timestamps out of the scan,
+	// which SQLite returns as text.
+	ids := make([]uint, 0, 2*len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.FirstID, row.LastID)
+	}
+	var dates []struct {
+		ID        uint
+		CreatedAt time.Time
+	}
+	if len(ids) > 0 {
+		if err := r.db.WithContext(ctx).Model(&models.SearchResult{}).Select("id, created_at").Where("id IN ?", ids).Scan(&dates).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+	found := make(map[uint]time.Time, len(dates))
+	for _, date := range dates {
+		found[date.ID] = date.CreatedAt
+	}
+
+	summaries := make([]RepoSummary, 0, len(rows))
+	for _, row := range rows {
+		summary := RepoSummary{
+			RepoFullName: row.RepoFullName,
+			Results:      row.Results,
+			OpenResults:  row.OpenResults,
+			Statuses: map[string]int64{
+				"pending":        row.Pending,
+				"confirmed":      row.Confirmed,
+				"false_positive": row.FalsePositive,
+				"resolved":       row.Resolved,
+			},
+			FirstSeen: found[row.FirstID],
+			LastSeen:  found[row.LastID],
+		}
+		if row.SeverityRank >= 0 && row.SeverityRank < len(severityByRank) {
+			summary.HighestSeverity = severityByRank[row.SeverityRank]
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, total, nil
+}
+
+func (r *gormResultRepo) Get(ctx context.Context, id uint) (*models.SearchResult, error) {
+	var result models.SearchResult
+	if err := inProjects(ctx, r.db.WithContext(ctx)).First(&result, id).Error; err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *gormResultRepo) GetMany(ctx context.Context, ids []uint) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+	err := inProjects(ctx, r.db.WithContext(ctx)).Preload("Rule").Where("id IN ?", ids).Find(&results).Error
+	return results, err
+}
+
+func (r *gormResultRepo) KnownFiles(ctx context.Context, ruleID uint, repoFullNames []string) (map[FileKey]bool, error) {
+	known := make(map[FileKey]bool)
+	if len(repoFullNames) == 0 {
+		return known, nil
+	}
+
+	// Served by idx_search_results_dedup in a single round trip
+	var keys []FileKey
+	err := r.db.WithContext(ctx).Model(&models.SearchResult{}).
+		Select("repo_full_name, file_path").
+		Where("rule_id = ? AND repo_full_name IN ?", ruleID, repoFullNames).
+		Scan(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		known[key] = true
+	}
+	return known, nil
+}
+
+func (r *gormResultRepo) FindFiles(ctx context.Context, ruleID uint, keys []FileKey) ([]models.SearchResult, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	wanted := make(map[FileKey]bool, len(keys))
+	repoNames := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !wanted[key] {
+			wanted[key] = true
+			repoNames = append(repoNames, key.RepoFullName)
+		}
+	}
+
+	var candidates []models.SearchResult
+	err := r.db.WithContext(ctx).Where("rule_id = ? AND repo_full_name IN ?", ruleID, repoNames).Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	results := make([]models.SearchResult, 0, len(keys))
+	for _, result := range candidates {
+		if wanted[FileKey{RepoFullName: result.RepoFullName, FilePath: result.FilePath}] {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func (r *gormResultRepo) AddRevision(ctx context.Context, result *models.SearchResult, revision *models.ResultRevision) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(result).Error; err != nil {
+			return err
+		}
+		revision.ResultID = result.ID
+		revision.ProjectID = result.ProjectID
+		return tx.Create(revision).Error
+	})
+}
+
+func (r *gormResultRepo) Revisions(ctx context.Context, resultID uint) ([]models.ResultRevision, error) {
+	var revisions []models.ResultRevision
+	err := inProjects(ctx, r.db.WithContext(ctx)).Where("result_id = ?", resultID).Order("id DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *gormResultRepo) FileKeys(ctx context.Context, ruleID uint) ([]FileKey, error) {
+	var keys []FileKey
+	err := r.db.WithContext(ctx).Model(&models.SearchResult{}).
+		Select("repo_full_name, file_path").
+		Where("rule_id = ?", ruleID).
+		Scan(&keys).Error
+	return keys, err
+}
+
+func (r *gormResultRepo) Create(ctx context.Context, result *models.SearchResult) error {
+	return r.db.WithContext(ctx).Create(result).Error
+}
+
+func (r *gormResultRepo) Save(ctx context.Context, result *models.SearchResult) error {
+	return r.db.WithContext(ctx).Save(result).Error
+}
+
+func (r *gormResultRepo) UpdateStatus(ctx context.Context, ids []uint, status string) (int64, error) {
+	tx := inProjects(ctx, r.db.WithContext(ctx).Model(&models.SearchResult{})).
+		Where("id IN ?", ids).
+		Update("status", status)
+	return tx.RowsAffected, tx.Error
+}
+
+func (r *gormResultRepo) ListSnoozeExpired(ctx context.Context, now time.Time, limit int) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+	err := r.db.WithContext(ctx).Preload("Rule").
+		Where("snoozed_until <= ?", now).
+		Order("snoozed_until").
+		Limit(limit).
+		Find(&results).Error
+	return results, err
+}
+
+func (r *gormResultRepo) EndSnooze(ctx context.Context, id uint, until time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.SearchResult{}).
+		Where("id = ? AND snoozed_until = ?", id, until).
+		Update("snoozed_until", nil)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormResultRepo) ListChanged(ctx context.Context, after ChangePosition, until time.Time, limit int) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+	err := inProjects(ctx, r.db.WithContext(ctx)).Preload("Rule").
+		Where("updated_at > ? OR (updated_at = ? AND id > ?)", after.UpdatedAt, after.UpdatedAt, after.ID).
+		Where("updated_at < ?", until).
+		Order("updated_at, id").
+		Limit(limit).
+		Find(&results).Error
+	return results, err
+}
+
+func (r *gormResultRepo) Count(ctx context.Context, filter ResultFilter) (int64, error) {
+	var count int64
+	err := r.filtered(ctx, filter).Count(&count).Error
+	return count, err
+}
+
+type gormTokenRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormTokenRepo) List(ctx context.Context) ([]models.GitHubToken, error) {
+	var tokens []models.GitHubToken
+	err := r.db.WithContext(ctx).Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *gormTokenRepo) Get(ctx context.Context, id uint) (*models.GitHubToken, error) {
+	var token models.GitHubToken
+	if err := r.db.WithContext(ctx).First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *gormTokenRepo) Create(ctx context.Context, token *models.GitHubToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The unique index covers deleted tokens too, adding one again replaces them
+		if err := tx.Unscoped().Where("token = ? AND deleted_at IS NOT NULL", token.Token).Delete(&models.GitHubToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+func (r *gormTokenRepo) Save(ctx context.Context, token *models.GitHubToken) error {
+	return r.db.WithContext(ctx).Save(token).Error
+}
+
+func (r *gormTokenRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.GitHubToken{}, id).Error
+}
+
+func (r *gormTokenRepo) Count(ctx context.Context, activeOnly bool) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.GitHubToken{})
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+type gormWhitelistRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormWhitelistRepo) List(ctx context.Context) ([]models.Whitelist, error) {
+	var entries []models.Whitelist
+	err := inProjects(ctx, r.db.WithContext(ctx)).Find(&entries).Error
+	return entries, err
+}
+
+func (r *gormWhitelistRepo) Get(ctx context.Context, id uint) (*models.Whitelist, error) {
+	var entry models.Whitelist
+	if err := inProjects(ctx, r.db.WithContext(ctx)).First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *gormWhitelistRepo) Create(ctx context.Context, entry *models.Whitelist) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The unique index covers deleted entries too, adding one again replaces them
+		err := tx.Unscoped().Where("project_id = ? AND value = ? AND deleted_at IS NOT NULL", entry.ProjectID, entry.Value).
+			Delete(&models.Whitelist{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+func (r *gormWhitelistRepo) Delete(ctx context.Context, id uint) error {
+	return inProjects(ctx, r.db.WithContext(ctx)).Delete(&models.Whitelist{}, id).Error
+}
+
+type gormHistoryRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormHistoryRepo) filtered(ctx context.Context, filter HistoryFilter) *gorm.DB {
+	query := ofProjectRules(ctx, r.db, r.db.WithContext(ctx).Model(&models.ScanHistory{}))
+	if filter.RuleID > 0 {
+		query = query.Where("rule_id = ?", filter.RuleID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	return query
+}
+
+func (r *gormHistoryRepo) List(ctx context.Context, filter HistoryFilter, page Page) ([]models.ScanHistory, int64, error) {
+	query := r.filtered(ctx, filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var history []models.ScanHistory
+	err := query.Preload("Rule").
+		Order("created_at DESC").
+		Limit(page.Size).
+		Offset(page.Offset()).
+		Find(&history).Error
+	return history, total, err
+}
+
+func (r *gormHistoryRepo) ListAfter(ctx context.Context, filter HistoryFilter, after uint64, limit int) ([]models.ScanHistory, error) {
+	var history []models.ScanHistory
+	err := keyset(r.filtered(ctx, filter).Preload("Rule"), after, limit).Find(&history).Error
+	return history, err
+}
+
+func (r *gormHistoryRepo) Create(ctx context.Context, history *models.ScanHistory) error {
+	return r.db.WithContext(ctx).Create(history).Error
+}
+
+type gormNotificationRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormNotificationRepo) List(ctx context.Context) ([]models.NotificationConfig, error) {
+	var configs []models.NotificationConfig
+	err := inProjects(ctx, r.db.WithContext(ctx)).Find(&configs).Error
+	return configs, err
+}
+
+func (r *gormNotificationRepo) ListEnabled(ctx context.Context) ([]models.NotificationConfig, error) {
+	var configs []models.NotificationConfig
+	err := r.db.WithContext(ctx).Where("enabled = ?", true).Find(&configs).Error
+	return configs, err
+}
+
+func (r *gormNotificationRepo) Get(ctx context.Context, id uint) (*models.NotificationConfig, error) {
+	var config models.NotificationConfig
+	if err := inProjects(ctx, r.db.WithContext(ctx)).First(&config, id).Error; err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (r *gormNotificationRepo) Create(ctx context.Context, config *models.NotificationConfig) error {
+	return r.db.WithContext(ctx).Create(config).Error
+}
+
+func (r *gormNotificationRepo) Save(ctx context.Context, config *models.NotificationConfig) error {
+	return r.db.WithContext(ctx).Save(config).Error
+}
+
+func (r *gormNotificationRepo) Delete(ctx context.Context, id uint) error {
+	return inProjects(ctx, r.db.WithContext(ctx)).Delete(&models.NotificationConfig{}, id).Error
+}
+
+type gormProjectRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormProjectRepo) List(ctx context.Context, ids []uint) ([]models.Project, error) {
+	var projects []models.Project
+	query := r.db.WithContext(ctx).Order("id")
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+	err := query.Find(&projects).Error
+	return projects, err
+}
+
+func (r *gormProjectRepo) Get(ctx context.Context, id uint) (*models.Project, error) {
+	var project models.Project
+	if err := r.db.WithContext(ctx).First(&project, id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *gormProjectRepo) Create(ctx context.Context, project *models.Project) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The unique index covers deleted projects too, which are kept for the rows
+		// that point to them, so a deleted project with the name gives it up
+		var deleted []models.Project
+		if err := tx.Unscoped().Where("name = ? AND deleted_at IS NOT NULL", project.Name).Find(&deleted).Error; err != nil {
+			return err
+		}
+		for _, old := range deleted {
+			suffix := fmt.Sprintf(" (deleted #%d)", old.ID)
+			name := old.Name
+			if len(name)+len(suffix) > 255 {
+				name = name[:255-len(suffix)]
+			}
+			if err := tx.Unscoped().Model(&models.Project{}).Where("id = ?", old.ID).UpdateColumn("name", name+suffix).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(project).Error
+	})
+}
+
+func (r *gormProjectRepo) Save(ctx context.Context, project *models.Project) error {
+	return r.db.WithContext(ctx).Save(project).Error
+}
+
+func (r *gormProjectRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ?", id).Delete(&models.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Project{}, id).Error
+	})
+}
+
+func (r *gormProjectRepo) Members(ctx context.Context, projectID uint) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Order("subject").Find(&members).Error
+	return members, err
+}
+
+func (r *gormProjectRepo) SetMember(ctx context.Context, member *models.ProjectMember) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "subject"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(member).Error
+}
+
+func (r *gormProjectRepo) RemoveMember(ctx context.Context, projectID uint, subject string) error {
+	tx := r.db.WithContext(ctx).Where("project_id = ? AND subject = ?", projectID, subject).Delete(&models.ProjectMember{})
+	if tx.Error == nil && tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return tx.Error
+}
+
+func (r *gormProjectRepo) MembershipsOf(ctx context.Context, subject string) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	err := r.db.WithContext(ctx).
+		Where("subject = ? AND project_id IN (?)", subject, r.db.Model(&models.Project{}).Select("id")).
+		Order("project_id").Find(&members).Error
+	return members, err
+}
+
+type gormCheckpointRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormCheckpointRepo) Get(ctx context.Context, name string, v interface{}) (bool, error) {
+	var checkpoint models.Checkpoint
+	err := r.db.WithContext(ctx).Where("name = ?", name).Take(&checkpoint).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(checkpoint.Value), v)
+}
+
+func (r *gormCheckpointRepo) Set(ctx context.Context, name string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Save(&models.Checkpoint{Name: name, Value: string(value)}).Error
+}
+
+type gormReportRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormReportRepo) List(ctx context.Context, limit int) ([]models.Report, error) {
+	var reports []models.Report
+	err := r.db.WithContext(ctx).Order("created_at DESC").Limit(limit).Find(&reports).Error
+	return reports, err
+}
+
+func (r *gormReportRepo) Get(ctx context.Context, id uint) (*models.Report, error) {
+	var report models.Report
+	if err := r.db.WithContext(ctx).First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *gormReportRepo) Latest(ctx context.Context, end time.Time) (*models.Report, error) {
+	var report models.Report
+	if err := r.db.WithContext(ctx).Where("period_end >= ?", end).Order("created_at DESC").First(&report).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *gormReportRepo) Create(ctx context.Context, report *models.Report) error {
+	return r.db.WithContext(ctx).Create(report).Error
+}
+
+func (r *gormReportRepo) SetDelivery(ctx context.Context, id uint, delivered bool, deliveryError string) error {
+	return r.db.WithContext(ctx).Model(&models.Report{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"delivered": delivered,
+		"error":     deliveryError,
+	}).Error
+}
+
+func (r *gormReportRepo) SetStorageKey(ctx context.Context, id uint, key string) error {
+	return r.db.WithContext(ctx).Model(&models.Report{}).Where("id = ?", id).Update("storage_key", key).Error
+}
+
+func (r *gormReportRepo) PeriodStats(ctx context.Context, start, end time.Time) (*PeriodStats, error) {
+	database := r.db.WithContext(ctx)
+	stats := &PeriodStats{}
+
+	inPeriod := database.Model(&models.SearchResult{}).Where("search_results.created_at >= ? AND search_results.created_at < ?", start, end)
+
+	if err := inPeriod.Session(&gorm.Session{}).Count(&stats.NewFindings).Error; err != nil {
+		return nil, fmt.Errorf("failed to count findings: %w", err)
+	}
+
+	if err := inPeriod.Session(&gorm.Session{}).
+		Select("severity AS group_key, COUNT(*) AS count").
+		Group("severity").
+		Order("count DESC").
+		Scan(&stats.BySeverity).Error; err != nil {
+		return nil, fmt.Errorf("failed to group findings by severity: %w", err)
+	}
+
+	if err := inPeriod.Session(&gorm.Session{}).
+		Select("monitor_rules.name AS group_key, COUNT(*) AS count").
+		Joins("JOIN monitor_rules ON monitor_rules.id = search_results.rule_id").
+		Group("monitor_rules.name").
+		Order("count DESC").
+		Scan(&stats.ByRule).Error; err != nil {
+		return nil, fmt.Errorf("failed to group findings by rule: %w", err)
+	}
+
+	if err := inPeriod.Session(&gorm.Session{}).
+		Select("repo_full_name AS group_key, COUNT(*) AS count").
+		Group("repo_full_name").
+		Order("count DESC").
+		Limit(10).
+		Scan(&stats.TopRepositories).Error; err != nil {
+		return nil, fmt.Errorf("failed to find top repositories: %w", err)
+	}
+
+	updatedInPeriod := database.Model(&models.SearchResult{}).Where("updated_at >= ? AND updated_at < ?", start, end)
+	updatedInPeriod.Session(&gorm.Session{}).Where("status = ?", "confirmed").Count(&stats.Confirmed)
+	updatedInPeriod.Session(&gorm.Session{}).Where("status = ?", "false_positive").Count(&stats.FalsePositive)
+	database.Model(&models.SearchResult{}).Where("status = ?", "pending").Count(&stats.PendingTotal)
+	database.Model(&models.SearchResult{}).Where("status = ? AND created_at < ?", "pending", end.Add(-7*24*time.Hour)).Count(&stats.PendingOlder7d)
+
+	scans := database.Model(&models.ScanHistory{}).Where("created_at >= ? AND created_at < ?", start, end)
+	scans.Session(&gorm.Session{}).Count(&stats.Scans)
+	scans.Session(&gorm.Session{}).Where("status = ?", "failed").Count(&stats.ScansFailed)
+	scans.Session(&gorm.Session{}).Where("status = ?", "rate_limited").Count(&stats.ScansRateLimited)
+
+	return stats, nil
+}
+
+type gormSessionRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormSessionRepo) List(ctx context.Context, activeOnly bool) ([]models.Session, error) {
+	query := r.db.WithContext(ctx).Model(&models.Session{})
+	if activeOnly {
+		query = query.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+
+	var sessions []models.Session
+	err := query.Order("created_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *gormSessionRepo) GetBySessionID(ctx context.Context, sessionID string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).Where("session_id = ?", sessionID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepo) GetByRefreshTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepo) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *gormSessionRepo) RotateRefreshToken(ctx context.Context, id uint, oldHash, newHash string, usedAt time.Time) (bool, error) {
+	// Conditional on the old hash so a refresh token can only be redeemed once
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", id, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": newHash,
+			"last_used_at":       usedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormSessionRepo) Revoke(ctx context.Context, sessionID string) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormSessionRepo) Active(ctx context.Context, sessionID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+type gormAuditRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormAuditRepo) List(ctx context.Context, filter AuditFilter, page Page) ([]models.AuditLog, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.AuditLog{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Resource != "" {
+		query = query.Where("resource = ?", filter.Resource)
+	}
+	if filter.ResourceID != "" {
+		query = query.Where("resource_id = ?", filter.ResourceID)
+	}
+	if filter.Method != "" {
+		query = query.Where("method = ?", filter.Method)
+	}
+	if filter.Since != "" {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if filter.Until != "" {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []models.AuditLog
+	err := query.Order("created_at DESC").Limit(page.Size).Offset(page.Offset()).Find(&logs).Error
+	return logs, total, err
+}
+
+func (r *gormAuditRepo) Create(ctx context.Context, entry *models.AuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+type gormSettingRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormSettingRepo) List(ctx context.Context) ([]models.Setting, error) {
+	var settings []models.Setting
+	err := r.db.WithContext(ctx).Find(&settings).Error
+	return settings, err
+}
+
+func (r *gormSettingRepo) Set(ctx context.Context, setting *models.Setting) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(setting).Error
+}
+
+type gormViewRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormViewRepo) List(ctx context.Context, owner string) ([]models.SavedView, error) {
+	var views []models.SavedView
+	err := inProjects(ctx, r.db.WithContext(ctx)).
+		Where("owner = ? OR shared = ?", owner, true).
+		Order("name").
+		Find(&views).Error
+	return views, err
+}
+
+func (r *gormViewRepo) Get(ctx context.Context, id uint) (*models.SavedView, error) {
+	var view models.SavedView
+	if err := inProjects(ctx, r.db.WithContext(ctx)).First(&view, id).Error; err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+func (r *gormViewRepo) Create(ctx context.Context, view *models.SavedView) error {
+	return r.db.WithContext(ctx).Create(view).Error
+}
+
+func (r *gormViewRepo) Save(ctx context.Context, view *models.SavedView) error {
+	return r.db.WithContext(ctx).Save(view).Error
+}
+
+func (r *gormViewRepo) Delete(ctx context.Context, id uint) error {
+	return inProjects(ctx, r.db.WithContext(ctx)).Delete(&models.SavedView{}, id).Error
+}
+
+type gormHoneytokenRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormHoneytokenRepo) List(ctx context.Context) ([]models.Honeytoken, error) {
+	var tokens []models.Honeytoken
+	err := inProjects(ctx, r.db.WithContext(ctx)).Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *gormHoneytokenRepo) Get(ctx context.Context, id uint) (*models.Honeytoken, error) {
+	var token models.Honeytoken
+	if err := inProjects(ctx, r.db.WithContext(ctx)).First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *gormHoneytokenRepo) GetByRule(ctx context.Context, ruleID uint) (*models.Honeytoken, error) {
+	var token models.Honeytoken
+	if err := inProjects(ctx, r.db.WithContext(ctx)).Where("rule_id = ?", ruleID).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *gormHoneytokenRepo) Create(ctx context.Context, token *models.Honeytoken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *gormHoneytokenRepo) Save(ctx context.Context, token *models.Honeytoken) error {
+	return r.db.WithContext(ctx).Save(token).Error
+}
+
+func (r *gormHoneytokenRepo) Delete(ctx context.Context, id uint) error {
+	return inProjects(ctx, r.db.WithContext(ctx)).Delete(&models.Honeytoken{}, id).Error
+}
+
+type gormIncidentRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormIncidentRepo) List(ctx context.Context, filter IncidentFilter, page Page) ([]models.Incident, int64, error) {
+	query := inProjects(ctx, r.db.WithContext(ctx).Model(&models.Incident{}))
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+	if filter.Assignee != "" {
+		query = query.Where("assignee = ?", filter.Assignee)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var incidents []models.Incident
+	err := query.Order("id DESC").Limit(page.Size).Offset(page.Offset()).Find(&incidents).Error
+	return incidents, total, err
+}
+
+func (r *gormIncidentRepo) CountOpen(ctx context.Context) (int64, error) {
+	var count int64
+	err := inProjects(ctx, r.db.WithContext(ctx).Model(&models.Incident{})).
+		Where("status IN ?", []string{"pending", "confirmed"}).Count(&count).Error
+	return count, err
+}
+
+func (r *gormIncidentRepo) Get(ctx context.Context, id uint) (*models.Incident, error) {
+	var incident models.Incident
+	if err := inProjects(ctx, r.db.WithContext(ctx)).First(&incident, id).Error; err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+func (r *gormIncidentRepo) FindOpen(ctx context.Context, projectID uint, groupBy, value string) (*models.Incident, error) {
+	results := r.db.WithContext(ctx).Model(&models.SearchResult{}).
+		Select("incident_id").
+		Where("project_id = ? AND incident_id IS NOT NULL", projectID)
+	switch groupBy {
+	case models.GroupFingerprint:
+		results = results.Where("fingerprint = ?", value)
+	case models.GroupRepository:
+		results = results.Where("repo_full_name = ?", value)
+	case models.GroupOwner:
+		results = results.Where("repo_full_name LIKE ?", value+"/%")
+	default:
+		return nil, fmt.Errorf("unknown incident grouping %q", groupBy)
+	}
+
+	var incident models.Incident
+	err := r.db.WithContext(ctx).
+		Where("id IN (?) AND status IN ?", results, []string{"pending", "confirmed"}).
+		Order("id DESC").
+		First(&incident).Error
+	if err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+func (r *gormIncidentRepo) Create(ctx context.Context, incident *models.Incident) error {
+	return r.db.WithContext(ctx).Create(incident).Error
+}
+
+func (r *gormIncidentRepo) Save(ctx context.Context, incident *models.Incident) error {
+	return r.db.WithContext(ctx).Save(incident).Error
+}
+
+func (r *gormIncidentRepo) Delete(ctx context.Context, id uint) error {
+	return inProjects(ctx, r.db.WithContext(ctx)).Delete(&models.Incident{}, id).Error
+}
+
+func (r *gormIncidentRepo) ResultIDs(ctx context.Context, id uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.SearchResult{}).
+		Where("incident_id = ?", id).
+		Order("id").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *gormIncidentRepo) MoveResults(ctx context.Context, id uint, resultIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var left []uint
+		if err := tx.Model(&models.SearchResult{}).
+			Where("id IN ? AND incident_id IS NOT NULL AND incident_id <> ?", resultIDs, id).
+			Distinct().
+			Pluck("incident_id", &left).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SearchResult{}).Where("id IN ?", resultIDs).Update("incident_id", id).Error; err != nil {
+			return err
+		}
+		for _, incidentID := range append(left, id) {
+			if err := recountIncident(tx, incidentID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// recountIncident updates the result count and severity of an incident from its results
+func recountIncident(tx *gorm.DB, id uint) error {
+	var severities []string
+	if err := tx.Model(&models.SearchResult{}).Where("incident_id = ?", id).Pluck("severity", &severities).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"result_count": len(severities)}
+	highest := ""
+	for _, severity := range severities {
+		if highest == "" || models.SeverityRank(severity) > models.SeverityRank(highest) {
+			highest = severity
+		}
+	}
+	if highest != "" {
+		updates["severity"] = highest
+	}
+	return tx.Model(&models.Incident{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *gormIncidentRepo) Timeline(ctx context.Context, id uint) ([]models.IncidentEvent, error) {
+	var events []models.IncidentEvent
+	err := r.db.WithContext(ctx).Where("incident_id = ?", id).Order("id").Find(&events).Error
+	return events, err
+}
+
+func (r *gormIncidentRepo) AddEvent(ctx context.Context, event *models.IncidentEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+type gormShareLinkRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormShareLinkRepo) ListByResult(ctx context.Context, resultID uint) ([]models.ShareLink, error) {
+	var links []models.ShareLink
+	err := inProjects(ctx, r.db.WithContext(ctx)).Where("result_id = ?", resultID).Order("id DESC").Find(&links).Error
+	return links, err
+}
+
+func (r *gormShareLinkRepo) Get(ctx context.Context, id uint) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := inProjects(ctx, r.db.WithContext(ctx)).First(&link, id).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *gormShareLinkRepo) GetByTokenHash(ctx context.Context, hash string) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *gormShareLinkRepo) Create(ctx context.Context, link *models.ShareLink) error {
+	return r.db.WithContext(ctx).Create(link).Error
+}
+
+func (r *gormShareLinkRepo) Save(ctx context.Context, link *models.ShareLink) error {
+	return r.db.WithContext(ctx).Save(link).Error
+}
+
+type gormWebhookRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormWebhookRepo) ListByRule(ctx context.Context, ruleID uint) ([]models.RuleWebhook, error) {
+	var webhooks []models.RuleWebhook
+	err := inProjects(ctx, r.db.WithContext(ctx)).Where("rule_id = ?", ruleID).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *gormWebhookRepo) Get(ctx context.Context, id uint) (*models.RuleWebhook, error) {
+	var webhook models.RuleWebhook
+	if err := inProjects(ctx, r.db.WithContext(ctx)).First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *gormWebhookRepo) Create(ctx context.Context, webhook *models.RuleWebhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *gormWebhookRepo) Save(ctx context.Context, webhook *models.RuleWebhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+func (r *gormWebhookRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := inProjects(ctx, tx).Delete(&models.RuleWebhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error
+	})
+}
+
+func (r *gormWebhookRepo) Enqueue(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *gormWebhookRepo) ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := inProjects(ctx, r.db.WithContext(ctx)).Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *gormWebhookRepo) GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := inProjects(ctx, r.db.WithContext(ctx)).First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *gormWebhookRepo) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", "pending", now).
+		Order("next_attempt_at, id").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *gormWebhookRepo) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+type gormPurgeRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormPurgeRepo) PurgeDeleted(ctx context.Context, before time.Time) (map[string]int64, error) {
+	purges := []struct {
+		name  string
+		model interface{}
+		scope func(*gorm.DB) *gorm.DB
+	}{
+		{"tokens", &models.GitHubToken{}, nil},
+		{"whitelist", &models.Whitelist{}, func(query *gorm.DB) *gorm.DB {
+			// A rule sync restores the entries it manages by ID
+			return query.Where("id NOT IN (?)", r.db.Model(&models.SyncedDefinition{}).Select("target_id").Where("kind = ?", "whitelist"))
+		}},
+		{"views", &models.SavedView{}, nil},
+		{"notifications", &models.NotificationConfig{}, nil},
+		{"honeytokens", &models.Honeytoken{}, nil},
+		{"incidents", &models.Incident{}, func(query *gorm.DB) *gorm.DB {
+			return query.Where("id NOT IN (?)", r.db.Unscoped().Model(&models.SearchResult{}).Select("incident_id").Where("incident_id IS NOT NULL"))
+		}},
+	}
+
+	purged := make(map[string]int64, len(purges))
+	for _, purge := range purges {
+		query := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before)
+		if purge.scope != nil {
+			query = purge.scope(query)
+		}
+		result := query.Delete(purge.model)
+		if result.Error != nil {
+			return purged, fmt.Errorf("failed to purge %s: %w", purge.name, result.Error)
+		}
+		purged[purge.name] = result.RowsAffected
+	}
+	return purged, nil
+}
+
+type gormStagingRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormStagingRepo) Stage(ctx context.Context, results []models.StagedResult) ([]models.StagedResult, error) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	// Dry runs scan the same files every cycle, each is staged once per rule
+	repos := make([]string, 0, len(results))
+	for _, result := range results {
+		repos = append(repos, result.RepoFullName)
+	}
+	var existing []models.StagedResult
+	err := r.db.WithContext(ctx).Select("rule_id, repo_full_name, file_path").
+		Where("rule_id = ? AND repo_full_name IN ?", results[0].RuleID, repos).
+		Find(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+	staged := make(map[FileKey]bool, len(existing))
+	for _, result := range existing {
+		staged[FileKey{RepoFullName: result.RepoFullName, FilePath: result.FilePath}] = true
+	}
+
+	fresh := make([]models.StagedResult, 0, len(results))
+	for _, result := range results {
+		key := FileKey{RepoFullName: result.RepoFullName, FilePath: result.FilePath}
+		if !staged[key] {
+			staged[key] = true
+			fresh = append(fresh, result)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+	if err := r.db.WithContext(ctx).Create(&fresh).Error; err != nil {
+		return nil, err
+	}
+	return fresh, nil
+}
+
+func (r *gormStagingRepo) List(ctx context.Context, ruleID uint, page Page) ([]models.StagedResult, int64, error) {
+	query := inProjects(ctx, r.db.WithContext(ctx).Model(&models.StagedResult{}))
+	if ruleID > 0 {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var results []models.StagedResult
+	err := query.Order("id DESC").Limit(page.Size).Offset(page.Offset()).Find(&results).Error
+	return results, total, err
+}
+
+func (r *gormStagingRepo) Clear(ctx context.Context) (int64, error) {
+	result := inProjects(ctx, r.db.WithContext(ctx)).Where("1 = 1").Delete(&models.StagedResult{})
+	return result.RowsAffected, result.Error
+}
+
+type gormSampleRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormSampleRepo) Add(ctx context.Context, sample *models.RuleSample) error {
+	return r.db.WithContext(ctx).Create(sample).Error
+}
+
+func (r *gormSampleRepo) List(ctx context.Context, ruleID uint, page Page) ([]models.RuleSample, int64, error) {
+	query := inProjects(ctx, r.db.WithContext(ctx).Model(&models.RuleSample{})).Where("rule_id = ?", ruleID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var samples []models.RuleSample
+	err := query.Order("id DESC").Limit(page.Size).Offset(page.Offset()).Find(&samples).Error
+	return samples, total, err
+}
+
+func (r *gormSampleRepo) Summary(ctx context.Context, ruleID uint) (SampleSummary, error) {
+	var row struct {
+		Samples int64
+		Hits    int64
+		MaxHits int64
+	}
+	err := inProjects(ctx, r.db.WithContext(ctx).Model(&models.RuleSample{})).Where("rule_id = ?", ruleID).
+		Select("COUNT(*) AS samples, COALESCE(SUM(hits), 0) AS hits, COALESCE(MAX(hits), 0) AS max_hits").
+		Scan(&row).Error
+	if err != nil {
+		return SampleSummary{}, err
+	}
+	summary := SampleSummary{Samples: row.Samples, Hits: row.Hits, MaxHits: row.MaxHits}
+	if row.Samples > 0 {
+		var first models.RuleSample
+		err := inProjects(ctx, r.db.WithContext(ctx)).Where("rule_id = ?", ruleID).Order("id").First(&first).Error
+		if err != nil {
+			return SampleSummary{}, err
+		}
+		summary.Since = &first.CreatedAt
+	}
+	return summary, nil
+}
+
+func (r *gormSampleRepo) Clear(ctx context.Context, ruleID uint) (int64, error) {
+	result := inProjects(ctx, r.db.WithContext(ctx)).Where("rule_id = ?", ruleID).Delete(&models.RuleSample{})
+	return result.RowsAffected, result.Error
+}
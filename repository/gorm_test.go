@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github-monitor/db/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	database, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestCreateProjectNamedLikeADeletedOne(t *testing.T) {
+	database := openTestDB(t, &models.Project{}, &models.ProjectMember{})
+	projects := &gormProjectRepo{db: database}
+	ctx := context.Background()
+
+	first := &models.Project{Name: "subsidiary"}
+	if err := projects.Create(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := projects.Delete(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	second := &models.Project{Name: "subsidiary"}
+	if err := projects.Create(ctx, second); err != nil {
+		t.Fatalf("re-creating a deleted project: %v", err)
+	}
+	if second.ID == first.ID {
+		t.Error("the deleted project was reused")
+	}
+
+	// The deleted project is kept under another name
+	var deleted models.Project
+	if err := database.Unscoped().First(&deleted, first.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !deleted.DeletedAt.Valid || deleted.Name == "subsidiary" {
+		t.Errorf("deleted project = %q, deleted %v", deleted.Name, deleted.DeletedAt.Valid)
+	}
+
+	// A project that isn't deleted still holds its name
+	if err := projects.Create(ctx, &models.Project{Name: "subsidiary"}); err == nil {
+		t.Error("created a second project with the name of a live one")
+	}
+}
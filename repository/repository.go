@@ -0,0 +1,611 @@
+// Package repository hides the data access behind small interfaces so handlers
+// and the monitor can be tested with fakes instead of a database.
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github-monitor/db/models"
+
+	"gorm.io/gorm"
+)
+
+// Page selects a page with the legacy offset pagination
+type Page struct {
+	Number int
+	Size   int
+}
+
+// Offset returns the number of rows to skip
+func (p Page) Offset() int {
+	if p.Number < 1 {
+		return 0
+	}
+	return (p.Number - 1) * p.Size
+}
+
+// ResultFilter narrows down search results, zero values match everything
+type ResultFilter struct {
+	RuleID        uint
+	IncidentID    uint
+	Status        string
+	Severity      string
+	Source        string
+	Verdict       string
+	FileTypes     []string  // any of these file types
+	Snoozed       *bool     // nil matches all, false hides results snoozed until later, true only lists them
+	CreatedAfter  time.Time // zero for no lower bound, exclusive
+	CreatedBefore time.Time // zero for no upper bound, inclusive
+	Sort          string    // ordering of List, ListAfter is always newest first
+}
+
+// Result orderings of List
+const (
+	SortNewest  = ""        // newest first
+	SortVerdict = "verdict" // likely real secrets first, then unclassified results, then likely noise
+)
+
+// FileKey identifies a file a rule matched
+type FileKey struct {
+	RepoFullName string
+	FilePath     string
+}
+
+// HistoryFilter narrows down scan history, zero values match everything
+type HistoryFilter struct {
+	RuleID uint
+	Since  time.Time // zero for no lower bound
+}
+
+// RevisionFilter narrows down rule revisions, zero values match everything
+type RevisionFilter struct {
+	RuleID uint
+	Since  time.Time
+	Until  time.Time
+}
+
+// RuleSnapshot is the state of a rule a revision records
+type RuleSnapshot struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Keywords    string `json:"keywords"`
+	MatchType   string `json:"match_type"`
+	IsActive    bool   `json:"is_active"`
+	ExcludeExts string `json:"exclude_exts"`
+	Severity    string `json:"severity"`
+	TokenGroup  string `json:"token_group,omitempty"`
+
+	SearchRepoMetadata bool `json:"search_repo_metadata,omitempty"`
+
+	KeywordWeights string  `json:"keyword_weights,omitempty"`
+	MinScore       float64 `json:"min_score,omitempty"`
+
+	NotifyThreshold string `json:"notify_threshold,omitempty"`
+
+	PageDelay string `json:"page_delay,omitempty"`
+	RuleDelay string `json:"rule_delay,omitempty"`
+
+	SkipArchived   bool `json:"skip_archived,omitempty"`
+	MinRepoAgeDays int  `json:"min_repo_age_days,omitempty"`
+	MaxRepoAgeDays int  `json:"max_repo_age_days,omitempty"`
+
+	MaxNewResults int `json:"max_new_results,omitempty"`
+
+	Owner string `json:"owner,omitempty"`
+
+	ExcludeRepos string `json:"exclude_repos,omitempty"`
+
+	QueryDialect string `json:"query_dialect,omitempty"`
+	RawQuery     string `json:"raw_query,omitempty"`
+
+	FileSize string `json:"file_size,omitempty"`
+
+	SampleOnly bool `json:"sample_only,omitempty"`
+
+	Processors string `json:"processors,omitempty"`
+}
+
+// SnapshotOf captures the revisioned fields of a rule
+func SnapshotOf(rule *models.MonitorRule) RuleSnapshot {
+	return RuleSnapshot{
+		Name:        rule.Name,
+		Description: rule.Description,
+		Keywords:    rule.Keywords,
+		MatchType:   rule.MatchType,
+		IsActive:    rule.IsActive,
+		ExcludeExts: rule.ExcludeExts,
+		Severity:    rule.Severity,
+		TokenGroup:  rule.TokenGroup,
+
+		SearchRepoMetadata: rule.SearchRepoMetadata,
+
+		KeywordWeights: rule.KeywordWeights,
+		MinScore:       rule.MinScore,
+
+		NotifyThreshold: rule.NotifyThreshold,
+
+		PageDelay: rule.PageDelay,
+		RuleDelay: rule.RuleDelay,
+
+		SkipArchived:   rule.SkipArchived,
+		MinRepoAgeDays: rule.MinRepoAgeDays,
+		MaxRepoAgeDays: rule.MaxRepoAgeDays,
+
+		MaxNewResults: rule.MaxNewResults,
+
+		Owner: rule.Owner,
+
+		ExcludeRepos: rule.ExcludeRepos,
+
+		QueryDialect: rule.QueryDialect,
+		RawQuery:     rule.RawQuery,
+
+		FileSize: rule.FileSize,
+
+		SampleOnly: rule.SampleOnly,
+
+		Processors: rule.Processors,
+	}
+}
+
+// ApplyTo sets the revisioned fields of a rule
+func (s RuleSnapshot) ApplyTo(rule *models.MonitorRule) {
+	rule.Name = s.Name
+	rule.Description = s.Description
+	rule.Keywords = s.Keywords
+	rule.MatchType = s.MatchType
+	rule.IsActive = s.IsActive
+	rule.ExcludeExts = s.ExcludeExts
+	rule.Severity = s.Severity
+	rule.TokenGroup = s.TokenGroup
+	rule.SearchRepoMetadata = s.SearchRepoMetadata
+	rule.KeywordWeights = s.KeywordWeights
+	rule.MinScore = s.MinScore
+	rule.NotifyThreshold = s.NotifyThreshold
+	rule.PageDelay = s.PageDelay
+	rule.RuleDelay = s.RuleDelay
+	rule.SkipArchived = s.SkipArchived
+	rule.MinRepoAgeDays = s.MinRepoAgeDays
+	rule.MaxRepoAgeDays = s.MaxRepoAgeDays
+	rule.MaxNewResults = s.MaxNewResults
+	rule.Owner = s.Owner
+	rule.ExcludeRepos = s.ExcludeRepos
+	rule.RawQuery = s.RawQuery
+	rule.FileSize = s.FileSize
+	rule.SampleOnly = s.SampleOnly
+	rule.Processors = s.Processors
+	rule.QueryDialect = s.QueryDialect
+	if rule.QueryDialect == "" {
+		// Revisions from before dialects were all searched as text
+		rule.QueryDialect = "legacy"
+	}
+}
+
+type actorKey struct{}
+type noteKey struct{}
+type projectsKey struct{}
+
+// WithActor names who makes the changes done with ctx, for the rule history
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// WithNote attaches a note to the rule revisions recorded with ctx
+func WithNote(ctx context.Context, note string) context.Context {
+	return context.WithValue(ctx, noteKey{}, note)
+}
+
+// WithProjects restricts the rules, results, whitelist entries, scan history and
+// notification channels read and changed with ctx to the given projects
+func WithProjects(ctx context.Context, ids []uint) context.Context {
+	return context.WithValue(ctx, projectsKey{}, ids)
+}
+
+// ProjectsOf returns the projects ctx is restricted to, ok is false when it isn't
+func ProjectsOf(ctx context.Context) (ids []uint, ok bool) {
+	ids, ok = ctx.Value(projectsKey{}).([]uint)
+	return ids, ok
+}
+
+// RuleRepo stores monitor rules. Create, Save and Delete record a revision with the
+// actor and note of the context; saves that change nothing aren't recorded.
+type RuleRepo interface {
+	List(ctx context.Context) ([]models.MonitorRule, error)
+	ListActive(ctx context.Context) ([]models.MonitorRule, error)
+	Get(ctx context.Context, id uint) (*models.MonitorRule, error)
+	// GetByName returns the first rule with the name, or gorm.ErrRecordNotFound
+	GetByName(ctx context.Context, name string) (*models.MonitorRule, error)
+	// Create stores a new rule, active rules are marked as waiting for their first scan
+	Create(ctx context.Context, rule *models.MonitorRule) error
+	// Save stores a rule. The query error is kept while the keywords, match type
+	// and excluded extensions stay the same and cleared when they change. Re-activated
+	// rules are marked as waiting for their first scan.
+	Save(ctx context.Context, rule *models.MonitorRule) error
+	// SetQueryError records why GitHub rejected the query of a rule, without a revision
+	SetQueryError(ctx context.Context, id uint, message string) error
+	// ListScanPending returns the active rules waiting for their first scan
+	ListScanPending(ctx context.Context) ([]models.MonitorRule, error)
+	// ClearScanPending records that a rule was scanned, without a revision
+	ClearScanPending(ctx context.Context, id uint) error
+	Delete(ctx context.Context, id uint) error
+	Count(ctx context.Context, activeOnly bool) (int64, error)
+}
+
+// RevisionRepo reads the change history of monitor rules
+type RevisionRepo interface {
+	// List returns a page of revisions, newest first, together with the total match count
+	List(ctx context.Context, filter RevisionFilter, page Page) ([]models.RuleRevision, int64, error)
+	Get(ctx context.Context, id uint) (*models.RuleRevision, error)
+}
+
+// ResultRepo stores search results
+type ResultRepo interface {
+	// List returns a page ordered newest first together with the total match count
+	List(ctx context.Context, filter ResultFilter, page Page) ([]models.SearchResult, int64, error)
+	// ListAfter returns up to limit results with an id below after, newest first. after=0 starts at the newest.
+	ListAfter(ctx context.Context, filter ResultFilter, after uint64, limit int) ([]models.SearchResult, error)
+	Get(ctx context.Context, id uint) (*models.SearchResult, error)
+	// GetMany returns the results with the given ids that still exist, with their rule
+	GetMany(ctx context.Context, ids []uint) ([]models.SearchResult, error)
+	// KnownFiles returns the files in the given repositories a rule has already recorded
+	KnownFiles(ctx context.Context, ruleID uint, repoFullNames []string) (map[FileKey]bool, error)
+	// FileKeys returns every file a rule has recorded
+	FileKeys(ctx context.Context, ruleID uint) ([]FileKey, error)
+	// FindFiles returns the results a rule recorded for the given files
+	FindFiles(ctx context.Context, ruleID uint, keys []FileKey) ([]models.SearchResult, error)
+	// AddRevision saves a result together with the revision recording its change
+	AddRevision(ctx context.Context, result *models.SearchResult, revision *models.ResultRevision) error
+	// Revisions returns the revisions of a result, newest first
+	Revisions(ctx context.Context, resultID uint) ([]models.ResultRevision, error)
+	Create(ctx context.Context, result *models.SearchResult) error
+	Save(ctx context.Context, result *models.SearchResult) error
+	UpdateStatus(ctx context.Context, ids []uint, status string) (int64, error)
+	// ListSnoozeExpired returns up to limit results with their rule whose snooze ended before now
+	ListSnoozeExpired(ctx context.Context, now time.Time, limit int) ([]models.SearchResult, error)
+	// EndSnooze clears the snooze of a result if it's still snoozed until the given
+	// time, reporting whether it was, so a snooze ends only once
+	EndSnooze(ctx context.Context, id uint, until time.Time) (bool, error)
+	Count(ctx context.Context, filter ResultFilter) (int64, error)
+	// ListChanged returns up to limit results with their rule that were created or
+	// updated after the given position and before until, ordered by update time and id
+	ListChanged(ctx context.Context, after ChangePosition, until time.Time, limit int) ([]models.SearchResult, error)
+	// ByRepo returns a page of the repositories with matching results, those with the
+	// most open results and the highest severity first, together with the total
+	// number of repositories
+	ByRepo(ctx context.Context, filter ResultFilter, page Page) ([]RepoSummary, int64, error)
+	// OpenStats breaks the open results down by severity, by age, found since today
+	// or since week, and by rule for the topRules rules with the most, none when 0
+	OpenStats(ctx context.Context, today, week time.Time, topRules int) (OpenResultStats, error)
+}
+
+// RepoSummary aggregates the results found in a repository
+type RepoSummary struct {
+	RepoFullName    string           `json:"repo_full_name"`
+	Results         int64            `json:"results"`
+	OpenResults     int64            `json:"open_results"`               // pending or confirmed
+	HighestSeverity string           `json:"highest_severity,omitempty"` // of the open results, empty when none is open
+	Statuses        map[string]int64 `json:"statuses"`
+	FirstSeen       time.Time        `json:"first_seen"` // when the first result was found
+	LastSeen        time.Time        `json:"last_seen"`  // when the latest result was found
+}
+
+// OpenResultStats breaks the open results, pending or confirmed, down
+type OpenResultStats struct {
+	Total      int64            `json:"total"`
+	BySeverity map[string]int64 `json:"by_severity"`
+	ByAge      AgeCounts        `json:"by_age"`
+	ByRule     []RuleCount      `json:"by_rule"` // the rules with the most open results, most first
+}
+
+// AgeCounts counts results by when they were found
+type AgeCounts struct {
+	Today    int64 `json:"today"`
+	ThisWeek int64 `json:"this_week"` // earlier this week, today's not included
+	Older    int64 `json:"older"`
+}
+
+// RuleCount counts the results of a rule
+type RuleCount struct {
+	RuleID   uint   `json:"rule_id"`
+	RuleName string `json:"rule_name"`
+	Results  int64  `json:"results"`
+}
+
+// ChangePosition is a position in the results ordered by update time and id
+type ChangePosition struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint      `json:"id"`
+}
+
+// TokenRepo stores GitHub tokens
+type TokenRepo interface {
+	List(ctx context.Context) ([]models.GitHubToken, error)
+	Get(ctx context.Context, id uint) (*models.GitHubToken, error)
+	Create(ctx context.Context, token *models.GitHubToken) error
+	Save(ctx context.Context, token *models.GitHubToken) error
+	Delete(ctx context.Context, id uint) error
+	Count(ctx context.Context, activeOnly bool) (int64, error)
+}
+
+// WhitelistRepo stores whitelisted users and repositories
+type WhitelistRepo interface {
+	List(ctx context.Context) ([]models.Whitelist, error)
+	Get(ctx context.Context, id uint) (*models.Whitelist, error)
+	Create(ctx context.Context, entry *models.Whitelist) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// HistoryRepo stores scan history
+type HistoryRepo interface {
+	List(ctx context.Context, filter HistoryFilter, page Page) ([]models.ScanHistory, int64, error)
+	ListAfter(ctx context.Context, filter HistoryFilter, after uint64, limit int) ([]models.ScanHistory, error)
+	Create(ctx context.Context, history *models.ScanHistory) error
+}
+
+// NotificationRepo stores notification channel configs
+type NotificationRepo interface {
+	List(ctx context.Context) ([]models.NotificationConfig, error)
+	// ListEnabled returns the enabled channels of every project, for broadcasts
+	ListEnabled(ctx context.Context) ([]models.NotificationConfig, error)
+	Get(ctx context.Context, id uint) (*models.NotificationConfig, error)
+	Create(ctx context.Context, config *models.NotificationConfig) error
+	Save(ctx context.Context, config *models.NotificationConfig) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// ProjectRepo stores projects and the roles users hold in them
+type ProjectRepo interface {
+	// List returns the projects with the given ids, every project when ids is nil
+	List(ctx context.Context, ids []uint) ([]models.Project, error)
+	Get(ctx context.Context, id uint) (*models.Project, error)
+	Create(ctx context.Context, project *models.Project) error
+	Save(ctx context.Context, project *models.Project) error
+	Delete(ctx context.Context, id uint) error
+	Members(ctx context.Context, projectID uint) ([]models.ProjectMember, error)
+	// SetMember adds a member or changes the role of an existing one
+	SetMember(ctx context.Context, member *models.ProjectMember) error
+	RemoveMember(ctx context.Context, projectID uint, subject string) error
+	// MembershipsOf returns the memberships of a user, lowest project id first
+	MembershipsOf(ctx context.Context, subject string) ([]models.ProjectMember, error)
+}
+
+// CheckpointRepo stores the progress of background jobs
+type CheckpointRepo interface {
+	// Get decodes a checkpoint into v, found is false when there is none yet
+	Get(ctx context.Context, name string, v interface{}) (found bool, err error)
+	Set(ctx context.Context, name string, v interface{}) error
+}
+
+// KeyCount is a row of a grouped count
+type KeyCount struct {
+	Key   string `gorm:"column:group_key" json:"key"`
+	Count int64  `json:"count"`
+}
+
+// PeriodStats counts the findings, triage and scans of a period across every
+// project, for summary reports
+type PeriodStats struct {
+	NewFindings     int64
+	BySeverity      []KeyCount
+	ByRule          []KeyCount
+	TopRepositories []KeyCount // the 10 with the most findings
+
+	Confirmed      int64 // confirmed during the period
+	FalsePositive  int64 // dismissed during the period
+	PendingTotal   int64 // pending now
+	PendingOlder7d int64 // pending for more than a week before the period end
+
+	Scans            int64
+	ScansFailed      int64
+	ScansRateLimited int64
+}
+
+// ReportRepo stores generated summary reports
+type ReportRepo interface {
+	// List returns up to limit reports, newest first
+	List(ctx context.Context, limit int) ([]models.Report, error)
+	Get(ctx context.Context, id uint) (*models.Report, error)
+	// Latest returns the newest report of a period ending at or after end
+	Latest(ctx context.Context, end time.Time) (*models.Report, error)
+	Create(ctx context.Context, report *models.Report) error
+	// SetDelivery records whether the report reached its destinations
+	SetDelivery(ctx context.Context, id uint, delivered bool, deliveryError string) error
+	SetStorageKey(ctx context.Context, id uint, key string) error
+	// PeriodStats counts what happened from start until end
+	PeriodStats(ctx context.Context, start, end time.Time) (*PeriodStats, error)
+}
+
+// SessionRepo stores login sessions
+type SessionRepo interface {
+	// List returns sessions newest first, only the ones not revoked or expired when activeOnly is set
+	List(ctx context.Context, activeOnly bool) ([]models.Session, error)
+	GetBySessionID(ctx context.Context, sessionID string) (*models.Session, error)
+	GetByRefreshTokenHash(ctx context.Context, hash string) (*models.Session, error)
+	Create(ctx context.Context, session *models.Session) error
+	// RotateRefreshToken replaces the refresh token hash of a session, rotated is
+	// false when it no longer had oldHash because it was redeemed already
+	RotateRefreshToken(ctx context.Context, id uint, oldHash, newHash string, usedAt time.Time) (rotated bool, err error)
+	// Revoke revokes a session unless it was revoked already
+	Revoke(ctx context.Context, sessionID string) error
+	// Active reports whether the session exists and has not been revoked
+	Active(ctx context.Context, sessionID string) (bool, error)
+}
+
+// AuditFilter narrows down audit log entries, zero values match everything
+type AuditFilter struct {
+	Actor      string
+	Resource   string
+	ResourceID string
+	Method     string
+	Since      string // entries created at or after, as given by the caller
+	Until      string // entries created before, as given by the caller
+}
+
+// AuditRepo stores the audit log
+type AuditRepo interface {
+	// List returns a page ordered newest first together with the total match count
+	List(ctx context.Context, filter AuditFilter, page Page) ([]models.AuditLog, int64, error)
+	Create(ctx context.Context, entry *models.AuditLog) error
+}
+
+// ViewRepo stores saved result views
+type ViewRepo interface {
+	// List returns the views in the projects of ctx the owner saved or that are shared
+	List(ctx context.Context, owner string) ([]models.SavedView, error)
+	Get(ctx context.Context, id uint) (*models.SavedView, error)
+	Create(ctx context.Context, view *models.SavedView) error
+	Save(ctx context.Context, view *models.SavedView) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// HoneytokenRepo stores the canary credentials
+type HoneytokenRepo interface {
+	List(ctx context.Context) ([]models.Honeytoken, error)
+	Get(ctx context.Context, id uint) (*models.Honeytoken, error)
+	// GetByRule returns the honeytoken the rule searches for, or gorm.ErrRecordNotFound
+	GetByRule(ctx context.Context, ruleID uint) (*models.Honeytoken, error)
+	Create(ctx context.Context, token *models.Honeytoken) error
+	Save(ctx context.Context, token *models.Honeytoken) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// IncidentFilter narrows down incidents, zero values match everything
+type IncidentFilter struct {
+	Status   string
+	Severity string
+	Assignee string
+}
+
+// IncidentRepo stores incidents and their timelines
+type IncidentRepo interface {
+	// List returns a page ordered newest first together with the total match count
+	List(ctx context.Context, filter IncidentFilter, page Page) ([]models.Incident, int64, error)
+	Get(ctx context.Context, id uint) (*models.Incident, error)
+	// FindOpen returns the newest pending or confirmed incident of a project with a
+	// result that shares the value of the grouping, or gorm.ErrRecordNotFound
+	FindOpen(ctx context.Context, projectID uint, groupBy, value string) (*models.Incident, error)
+	Create(ctx context.Context, incident *models.Incident) error
+	Save(ctx context.Context, incident *models.Incident) error
+	// Delete deletes an incident without results, the timeline stays
+	Delete(ctx context.Context, id uint) error
+	ResultIDs(ctx context.Context, id uint) ([]uint, error)
+	// MoveResults moves results into an incident, updating the result count and
+	// severity of the incidents they leave and join
+	MoveResults(ctx context.Context, id uint, resultIDs []uint) error
+	// CountOpen counts the pending and confirmed incidents
+	CountOpen(ctx context.Context) (int64, error)
+	// Timeline returns the events of an incident, oldest first
+	Timeline(ctx context.Context, id uint) ([]models.IncidentEvent, error)
+	AddEvent(ctx context.Context, event *models.IncidentEvent) error
+}
+
+// ShareLinkRepo stores the share links of results
+type ShareLinkRepo interface {
+	// ListByResult returns the links of a result, newest first
+	ListByResult(ctx context.Context, resultID uint) ([]models.ShareLink, error)
+	Get(ctx context.Context, id uint) (*models.ShareLink, error)
+	// GetByTokenHash returns the link of a token in any project, links are opened
+	// without a login
+	GetByTokenHash(ctx context.Context, hash string) (*models.ShareLink, error)
+	Create(ctx context.Context, link *models.ShareLink) error
+	Save(ctx context.Context, link *models.ShareLink) error
+}
+
+// WebhookRepo stores the outbound webhooks of rules and their deliveries
+type WebhookRepo interface {
+	// ListByRule returns the webhooks of a rule, oldest first
+	ListByRule(ctx context.Context, ruleID uint) ([]models.RuleWebhook, error)
+	Get(ctx context.Context, id uint) (*models.RuleWebhook, error)
+	Create(ctx context.Context, webhook *models.RuleWebhook) error
+	Save(ctx context.Context, webhook *models.RuleWebhook) error
+	// Delete removes a webhook together with its deliveries
+	Delete(ctx context.Context, id uint) error
+
+	// Enqueue stores new deliveries
+	Enqueue(ctx context.Context, deliveries []models.WebhookDelivery) error
+	// ListDeliveries returns up to limit deliveries of a webhook, newest first
+	ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	// DueDeliveries returns up to limit pending deliveries of any project whose next
+	// attempt is due by now, oldest first
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+// PurgeRepo removes soft-deleted rows for good
+type PurgeRepo interface {
+	// PurgeDeleted hard-deletes the rows soft-deleted before the cutoff and returns
+	// how many it removed of each kind, such as tokens or whitelist. Rules and
+	// projects are kept, their revisions, scan history and results still refer to
+	// them, as are incidents results still belong to and whitelist entries a rule
+	// sync may restore.
+	PurgeDeleted(ctx context.Context, before time.Time) (map[string]int64, error)
+}
+
+// StagingRepo stores the results scans found in dry run
+type StagingRepo interface {
+	// Stage records the results whose file the rule hasn't staged yet and returns them
+	Stage(ctx context.Context, results []models.StagedResult) ([]models.StagedResult, error)
+	// List returns a page of the staged results in the projects of ctx, newest first,
+	// together with their total. A rule id of 0 lists those of every rule.
+	List(ctx context.Context, ruleID uint, page Page) ([]models.StagedResult, int64, error)
+	// Clear removes the staged results in the projects of ctx and returns how many
+	Clear(ctx context.Context) (int64, error)
+}
+
+// SampleRepo stores what the scans of sample_only rules found
+type SampleRepo interface {
+	Add(ctx context.Context, sample *models.RuleSample) error
+	// List returns a page of the samples of a rule, newest first, together with
+	// their total
+	List(ctx context.Context, ruleID uint, page Page) ([]models.RuleSample, int64, error)
+	// Summary totals the samples of a rule
+	Summary(ctx context.Context, ruleID uint) (SampleSummary, error)
+	// Clear removes the samples of a rule and returns how many
+	Clear(ctx context.Context, ruleID uint) (int64, error)
+}
+
+// SampleSummary totals the samples of a rule
+type SampleSummary struct {
+	Samples int64      `json:"samples"`  // scans and sources sampled
+	Hits    int64      `json:"hits"`     // files they found
+	MaxHits int64      `json:"max_hits"` // most files a single sample found
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// SettingRepo stores the runtime setting overrides
+type SettingRepo interface {
+	List(ctx context.Context) ([]models.Setting, error)
+	// Set creates or replaces the override of a setting
+	Set(ctx context.Context, setting *models.Setting) error
+}
+
+// Repositories bundles every repository so it can be passed to constructors as one value
+type Repositories struct {
+	Rules         RuleRepo
+	Revisions     RevisionRepo
+	Results       ResultRepo
+	Tokens        TokenRepo
+	Whitelist     WhitelistRepo
+	History       HistoryRepo
+	Notifications NotificationRepo
+	Projects      ProjectRepo
+	Checkpoints   CheckpointRepo
+	Reports       ReportRepo
+	Sessions      SessionRepo
+	Audit         AuditRepo
+	Settings      SettingRepo
+	Views         ViewRepo
+	Honeytokens   HoneytokenRepo
+	Incidents     IncidentRepo
+	ShareLinks    ShareLinkRepo
+	Webhooks      WebhookRepo
+	Purge         PurgeRepo
+	Staging       StagingRepo
+	Samples       SampleRepo
+
+	// DB is the connection behind the repositories, for backup and the company
+	// profile, which work on several tables in one transaction
+	DB *gorm.DB
+}
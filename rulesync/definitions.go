@@ -0,0 +1,323 @@
+package rulesync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/github"
+	"github-monitor/monitor"
+	"github-monitor/repository"
+
+	"gopkg.in/yaml.v3"
+)
+
+// document is the layout of a definitions file, every file may hold rules,
+// whitelist entries or both
+type document struct {
+	Rules     []ruleDefinition      `yaml:"rules"`
+	Whitelist []whitelistDefinition `yaml:"whitelist"`
+}
+
+// ruleDefinition is a monitor rule as written in the repository. Rules are
+// identified by name, renaming one replaces the rule.
+type ruleDefinition struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Keywords    []string `yaml:"keywords"`
+	MatchType   string   `yaml:"match_type"` // defaults to fuzzy
+	ExcludeExts []string `yaml:"exclude_exts"`
+	Severity    string   `yaml:"severity"` // defaults to medium
+	Active      *bool    `yaml:"active"`   // defaults to true
+	TokenGroup  string   `yaml:"token_group"`
+
+	SearchRepoMetadata bool `yaml:"search_repo_metadata"` // also search repository descriptions and topics
+
+	KeywordWeights map[string]float64 `yaml:"keyword_weights"` // keywords without a weight count 1
+	MinScore       float64            `yaml:"min_score"`
+
+	NotifyThreshold string `yaml:"notify_threshold"` // a severity or a score, empty notifies about every result
+
+	PageDelay string `yaml:"page_delay"` // empty for monitor.page_delay
+	RuleDelay string `yaml:"rule_delay"` // empty for monitor.rule_delay
+
+	SkipArchived   bool `yaml:"skip_archived"`
+	MinRepoAgeDays int  `yaml:"min_repo_age_days"` // 0 for no limit
+	MaxRepoAgeDays int  `yaml:"max_repo_age_days"` // 0 for no limit
+
+	MaxNewResults int `yaml:"max_new_results"` // 0 for monitor.max_new_results
+
+	ExcludeRepos []string `yaml:"exclude_repos"` // owner/name, still matched by other rules
+
+	QueryDialect string `yaml:"query_dialect"` // legacy (default) or code_search
+	RawQuery     string `yaml:"raw_query"`     // sent verbatim instead of the query built from the keywords
+
+	FileSize string `yaml:"file_size"` // size: qualifier in bytes, such as "<50000"
+
+	SampleOnly bool `yaml:"sample_only"` // count hits and keep a sample instead of recording results
+
+	Processors []string `yaml:"processors"` // result processors in order, empty for the default pipeline
+
+	Owner string `yaml:"owner"` // user or email address emailed the rule's notifications
+}
+
+// whitelistDefinition is a whitelist entry, identified by its value
+type whitelistDefinition struct {
+	Type        string `yaml:"type"` // user or repo
+	Value       string `yaml:"value"`
+	Description string `yaml:"description"`
+}
+
+// whitelistState is the applied state of a whitelist entry
+type whitelistState struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// definitions are the parsed files, keyed by rule name and whitelist value
+type definitions struct {
+	rules     map[string]definedRule
+	whitelist map[string]definedEntry
+}
+
+type definedRule struct {
+	file     string
+	snapshot repository.RuleSnapshot
+}
+
+type definedEntry struct {
+	file  string
+	state whitelistState
+}
+
+// load parses every .yaml and .yml file below dir. Any invalid file fails the
+// whole load, so a typo never deletes the rules of a file.
+func load(dir string) (*definitions, error) {
+	defs := &definitions{
+		rules:     make(map[string]definedRule),
+		whitelist: make(map[string]definedEntry),
+	}
+
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if ext := filepath.Ext(path); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		name, _ := filepath.Rel(dir, path)
+		name = filepath.ToSlash(name)
+		if err := defs.add(name, path); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return defs, nil
+}
+
+func (d *definitions) add(name, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc document
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil && !errors.Is(err, io.EOF) { // an empty file is fine
+		return err
+	}
+
+	for i, rule := range doc.Rules {
+		snapshot, err := rule.snapshot()
+		if err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		if other, ok := d.rules[snapshot.Name]; ok {
+			return fmt.Errorf("rules[%d]: rule %q is already defined in %s", i, snapshot.Name, other.file)
+		}
+		d.rules[snapshot.Name] = definedRule{file: name, snapshot: snapshot}
+	}
+
+	for i, entry := range doc.Whitelist {
+		value := strings.TrimSpace(entry.Value)
+		if value == "" {
+			return fmt.Errorf("whitelist[%d]: value is required", i)
+		}
+		if entry.Type != "user" && entry.Type != "repo" {
+			return fmt.Errorf("whitelist[%d]: type must be user or repo", i)
+		}
+		if other, ok := d.whitelist[value]; ok {
+			return fmt.Errorf("whitelist[%d]: %q is already defined in %s", i, value, other.file)
+		}
+		d.whitelist[value] = definedEntry{file: name, state: whitelistState{Type: entry.Type, Description: entry.Description}}
+	}
+	return nil
+}
+
+// snapshot validates the definition and returns the rule state it describes
+func (r ruleDefinition) snapshot() (repository.RuleSnapshot, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return repository.RuleSnapshot{}, fmt.Errorf("name is required")
+	}
+	if len(r.Keywords) == 0 {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: keywords is required", name)
+	}
+
+	matchType := r.MatchType
+	if matchType == "" {
+		matchType = "fuzzy"
+	}
+	if matchType != "fuzzy" && matchType != "precise" {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: match_type must be fuzzy or precise", name)
+	}
+	dialect := r.QueryDialect
+	if dialect == "" {
+		dialect = github.DialectLegacy
+	}
+	if !models.ValidQueryDialects[dialect] {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: query_dialect must be legacy or code_search", name)
+	}
+	if dialect == github.DialectCodeSearch {
+		if err := github.CheckKeywords(r.Keywords); err != nil {
+			return repository.RuleSnapshot{}, fmt.Errorf("rule %q: %w", name, err)
+		}
+	}
+	if r.RawQuery != "" {
+		if err := github.CheckRawQuery(r.RawQuery); err != nil {
+			return repository.RuleSnapshot{}, fmt.Errorf("rule %q: raw_query %w", name, err)
+		}
+	}
+	if !models.ValidFileSize(r.FileSize) {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: file_size must be a number of bytes such as <50000 or a range such as 100..5000", name)
+	}
+	severity := r.Severity
+	if severity == "" {
+		severity = "medium"
+	}
+	if !models.ValidSeverities[severity] {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: severity must be one of: critical high medium low info", name)
+	}
+	if r.TokenGroup != "" && !config.HasTokenGroup(r.TokenGroup) {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: token_group must be one of github.token_groups", name)
+	}
+
+	if r.MinScore < 0 {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: min_score must not be negative", name)
+	}
+	if !models.ValidNotifyThreshold(r.NotifyThreshold) {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: notify_threshold must be a severity or a non-negative score", name)
+	}
+	if !models.ValidDelay(r.PageDelay) || !models.ValidDelay(r.RuleDelay) {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: page_delay and rule_delay must be durations between 0s and 1m", name)
+	}
+	if !models.ValidRepoAge(r.MinRepoAgeDays, r.MaxRepoAgeDays) {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: min_repo_age_days and max_repo_age_days must not be negative, and min at most max", name)
+	}
+	if r.MaxNewResults < 0 {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: max_new_results must not be negative", name)
+	}
+	if !models.ValidOwner(r.Owner) {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: owner must be a user name or a plain email address", name)
+	}
+	excludeRepos := ""
+	if len(r.ExcludeRepos) > 0 {
+		encoded, _ := json.Marshal(r.ExcludeRepos)
+		excludeRepos = string(encoded)
+	}
+	if !models.ValidExcludeRepos(excludeRepos) {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: exclude_repos must list at most %d owner/name repositories", name, models.MaxExcludeRepos)
+	}
+	processors := ""
+	if len(r.Processors) > 0 {
+		encoded, _ := json.Marshal(r.Processors)
+		processors = string(encoded)
+	}
+	if err := monitor.ValidProcessors(processors); err != nil {
+		return repository.RuleSnapshot{}, fmt.Errorf("rule %q: processors: %v", name, err)
+	}
+	weights := ""
+	if len(r.KeywordWeights) > 0 {
+		for keyword, weight := range r.KeywordWeights {
+			if weight < 0 {
+				return repository.RuleSnapshot{}, fmt.Errorf("rule %q: weight of %q must not be negative", name, keyword)
+			}
+			known := false
+			for _, candidate := range r.Keywords {
+				known = known || strings.EqualFold(candidate, keyword)
+			}
+			if !known {
+				return repository.RuleSnapshot{}, fmt.Errorf("rule %q: keyword_weights: %q is not one of the keywords", name, keyword)
+			}
+		}
+		encoded, _ := json.Marshal(r.KeywordWeights)
+		weights = string(encoded)
+	}
+
+	excludeExts := r.ExcludeExts
+	if excludeExts == nil {
+		excludeExts = []string{}
+	}
+	keywords, _ := json.Marshal(r.Keywords)
+	excludeJSON, _ := json.Marshal(excludeExts)
+
+	return repository.RuleSnapshot{
+		Name:        name,
+		Description: r.Description,
+		Keywords:    string(keywords),
+		MatchType:   matchType,
+		IsActive:    r.Active == nil || *r.Active,
+		ExcludeExts: string(excludeJSON),
+		Severity:    severity,
+		TokenGroup:  r.TokenGroup,
+
+		SearchRepoMetadata: r.SearchRepoMetadata,
+
+		KeywordWeights: weights,
+		MinScore:       r.MinScore,
+
+		NotifyThreshold: r.NotifyThreshold,
+
+		PageDelay: r.PageDelay,
+		RuleDelay: r.RuleDelay,
+
+		SkipArchived:   r.SkipArchived,
+		MinRepoAgeDays: r.MinRepoAgeDays,
+		MaxRepoAgeDays: r.MaxRepoAgeDays,
+
+		MaxNewResults: r.MaxNewResults,
+
+		Owner: r.Owner,
+
+		ExcludeRepos: excludeRepos,
+
+		QueryDialect: dialect,
+		RawQuery:     r.RawQuery,
+
+		FileSize: r.FileSize,
+
+		SampleOnly: r.SampleOnly,
+
+		Processors: processors,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package rulesync
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// checkout is a shallow clone of one branch, updated in place
+type checkout struct {
+	url    string
+	branch string
+	token  string
+	dir    string
+}
+
+// pull brings the checkout up to date with the remote branch and returns the
+// commit. Fetching from the URL rather than origin follows changes to the config.
+func (c *checkout) pull(ctx context.Context) (string, error) {
+	if _, err := os.Stat(filepath.Join(c.dir, ".git")); err != nil {
+		if err := os.MkdirAll(filepath.Dir(c.dir), 0o755); err != nil {
+			return "", err
+		}
+		// A failed clone leaves a partial directory behind
+		if err := os.RemoveAll(c.dir); err != nil {
+			return "", err
+		}
+		if _, err := c.git(ctx, "", "clone", "--depth", "1", "--single-branch", "--branch", c.branch, c.url, c.dir); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := c.git(ctx, c.dir, "fetch", "--depth", "1", c.url, c.branch); err != nil {
+			return "", err
+		}
+		if _, err := c.git(ctx, c.dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+
+	commit, err := c.git(ctx, c.dir, "rev-parse", "HEAD")
+	return strings.TrimSpace(commit), err
+}
+
+// git runs a git command in dir. The token is passed as a header rather than in
+// the URL, so it isn't stored in the checkout's remote or echoed in errors.
+func (c *checkout) git(ctx context.Context, dir string, args ...string) (string, error) {
+	command := args[0]
+	if c.token != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + c.token))
+		args = append([]string{"-c", "http.extraHeader=Authorization: Basic " + credentials}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,488 @@
+// Package rulesync manages monitor rules and whitelist entries from YAML files in
+// a Git repository, so detection content goes through code review. The repository
+// is the source of truth: each sync creates, updates and deletes the rules it
+// defines, and changes made to them elsewhere are reported as drift and reverted
+// unless configured to only report.
+package rulesync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/events"
+	"github-monitor/github"
+	"github-monitor/reporting"
+	"github-monitor/repository"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of synced definitions
+const (
+	KindRule      = "rule"
+	KindWhitelist = "whitelist"
+)
+
+// actor is recorded on the rule revisions made by a sync
+const actor = "rule-sync"
+
+// Result counts the changes a sync made
+type Result struct {
+	Created int     `json:"created"`
+	Updated int     `json:"updated"`
+	Removed int     `json:"removed"`
+	Drift   []Drift `json:"drift"`
+}
+
+// Drift is a synced rule or whitelist entry changed outside the repository
+type Drift struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"` // rule name or whitelist value
+	File     string `json:"file"`
+	TargetID uint   `json:"target_id"`
+	Change   string `json:"change"`   // modified or deleted
+	Reverted bool   `json:"reverted"` // false when drift is only reported
+}
+
+// Status describes the last sync
+type Status struct {
+	Commit   string    `json:"commit"`
+	SyncedAt time.Time `json:"synced_at"`
+	Error    string    `json:"error,omitempty"`
+	Result   *Result   `json:"result,omitempty"`
+}
+
+// Syncer periodically reconciles the database with the rules repository
+type Syncer struct {
+	db       *gorm.DB
+	checkout *checkout
+	path     string
+	revert   bool
+	interval time.Duration
+	stopChan chan struct{} // nil while stopped
+
+	mu     sync.Mutex // one sync at a time
+	status Status
+}
+
+// NewSyncer creates a syncer for the configured repository
+func NewSyncer(cfg *config.RuleSyncConfig, database *gorm.DB) (*Syncer, error) {
+	interval, err := time.ParseDuration(cfg.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sync interval: %w", err)
+	}
+	return &Syncer{
+		db: database,
+		checkout: &checkout{
+			url:    cfg.Repository,
+			branch: cfg.Branch,
+			token:  cfg.Token,
+			dir:    cfg.WorkDir,
+		},
+		path:     cfg.Path,
+		revert:   cfg.Drift == "revert",
+		interval: interval,
+	}, nil
+}
+
+// Start polls the repository in the background, it can be started again after Stop
+func (s *Syncer) Start() {
+	if s.stopChan != nil {
+		return
+	}
+	s.stopChan = make(chan struct{})
+	go s.run(s.stopChan)
+	log.Printf("Rule sync started, every %s", s.interval)
+}
+
+// Stop stops polling
+func (s *Syncer) Stop() {
+	if s.stopChan == nil {
+		return
+	}
+	close(s.stopChan)
+	s.stopChan = nil
+}
+
+func (s *Syncer) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.syncOnce()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Syncer) syncOnce() {
+	defer reporting.Recover(reporting.Tags{"component": "rulesync"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+	defer cancel()
+	if _, err := s.Sync(ctx); err != nil {
+		log.Printf("Rule sync failed: %v", err)
+		reporting.CaptureError(err, reporting.Tags{"component": "rulesync"})
+	}
+}
+
+// Status returns the outcome of the last sync
+func (s *Syncer) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Sync pulls the repository and reconciles the database with its definitions. A
+// failed pull or an invalid file leaves the database untouched.
+func (s *Syncer) Sync(ctx context.Context) (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, err := s.sync(ctx)
+	status.SyncedAt = time.Now()
+	if err != nil {
+		status.Error = err.Error()
+	}
+	s.status = status
+	return status, err
+}
+
+func (s *Syncer) sync(ctx context.Context) (Status, error) {
+	commit, err := s.checkout.pull(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{Commit: commit}
+
+	defs, err := load(filepath.Join(s.checkout.dir, s.path))
+	if err != nil {
+		return status, fmt.Errorf("commit %.12s: %w", commit, err)
+	}
+
+	result := &Result{Drift: []Drift{}}
+	ctx = repository.WithNote(repository.WithActor(ctx, actor), fmt.Sprintf("rules repository at %.12s", commit))
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.syncRules(ctx, tx, defs.rules, result); err != nil {
+			return err
+		}
+		return s.syncWhitelist(tx, defs.whitelist, result)
+	})
+	if err != nil {
+		return status, err
+	}
+	status.Result = result
+
+	if result.Created+result.Updated+result.Removed > 0 {
+		log.Printf("Rule sync at %.12s created %d, updated %d and removed %d definitions",
+			commit, result.Created, result.Updated, result.Removed)
+	}
+	for _, drift := range result.Drift {
+		log.Printf("Rule sync: %s %q from %s was %s outside the repository (reverted: %t)",
+			drift.Kind, drift.Name, drift.File, drift.Change, drift.Reverted)
+	}
+	if len(result.Drift) > 0 {
+		events.Publish(events.TypeRuleDrift, result.Drift)
+	}
+	return status, nil
+}
+
+// links returns the synced definitions of a kind by name
+func links(tx *gorm.DB, kind string) (map[string]*models.SyncedDefinition, error) {
+	var rows []models.SyncedDefinition
+	if err := tx.Where("kind = ?", kind).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*models.SyncedDefinition, len(rows))
+	for i := range rows {
+		byName[rows[i].Name] = &rows[i]
+	}
+	return byName, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func encode(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func (s *Syncer) syncRules(ctx context.Context, tx *gorm.DB, defined map[string]definedRule, result *Result) error {
+	rules := repository.NewGormRepositories(tx).Rules
+	linked, err := links(tx, KindRule)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(defined) {
+		def := defined[name]
+		link, ok := linked[name]
+		delete(linked, name)
+
+		if !ok {
+			// Take over a rule of the same name, e.g. one created before the repository
+			rule, err := rules.GetByName(ctx, name)
+			switch {
+			case err == nil:
+				def.snapshot.ApplyTo(rule)
+				if err := rules.Save(ctx, rule); err != nil {
+					return err
+				}
+				result.Updated++
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				if rule, err = createRule(ctx, rules, def.snapshot); err != nil {
+					return err
+				}
+				result.Created++
+			default:
+				return err
+			}
+
+			link = &models.SyncedDefinition{Kind: KindRule, Name: name, File: def.file, TargetID: rule.ID, Applied: encode(def.snapshot)}
+			if err := tx.Create(link).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		rule, err := rules.Get(ctx, link.TargetID)
+		deleted := errors.Is(err, gorm.ErrRecordNotFound)
+		if err != nil && !deleted {
+			return err
+		}
+
+		// Drift is a change that matches neither the last applied state nor the
+		// definition, so fixing the definition to match the change resolves it
+		if deleted || (!sameRule(repository.SnapshotOf(rule), def.snapshot) && !sameRule(repository.SnapshotOf(rule), appliedRule(link))) {
+			drift := Drift{Kind: KindRule, Name: name, File: def.file, TargetID: link.TargetID, Change: "modified", Reverted: s.revert}
+			if deleted {
+				drift.Change = "deleted"
+			}
+			result.Drift = append(result.Drift, drift)
+			if !s.revert {
+				continue
+			}
+		}
+
+		switch {
+		case deleted:
+			if rule, err = createRule(ctx, rules, def.snapshot); err != nil {
+				return err
+			}
+			link.TargetID = rule.ID
+			result.Created++
+		case !sameRule(repository.SnapshotOf(rule), def.snapshot):
+			def.snapshot.ApplyTo(rule)
+			if err := rules.Save(ctx, rule); err != nil {
+				return err
+			}
+			result.Updated++
+		}
+
+		link.File = def.file
+		link.Applied = encode(def.snapshot)
+		if err := tx.Save(link).Error; err != nil {
+			return err
+		}
+	}
+
+	// Definitions removed from the repository
+	for _, name := range sortedKeys(linked) {
+		link := linked[name]
+		err := rules.Delete(ctx, link.TargetID)
+		if err == nil {
+			result.Removed++
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := tx.Delete(link).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createRule creates a rule in the given state. is_active has a database default
+// of true, which Create applies to false.
+func createRule(ctx context.Context, rules repository.RuleRepo, snapshot repository.RuleSnapshot) (*models.MonitorRule, error) {
+	rule := &models.MonitorRule{}
+	snapshot.ApplyTo(rule)
+	if err := rules.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	if !snapshot.IsActive && rule.IsActive {
+		rule.IsActive = false
+		if err := rules.Save(ctx, rule); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
+
+func appliedRule(link *models.SyncedDefinition) repository.RuleSnapshot {
+	var snapshot repository.RuleSnapshot
+	_ = json.Unmarshal([]byte(link.Applied), &snapshot)
+	return snapshot
+}
+
+// sameRule compares snapshots, ignoring the formatting of the JSON lists
+func sameRule(a, b repository.RuleSnapshot) bool {
+	a.Keywords, b.Keywords = canonicalList(a.Keywords), canonicalList(b.Keywords)
+	a.ExcludeExts, b.ExcludeExts = canonicalList(a.ExcludeExts), canonicalList(b.ExcludeExts)
+	a.ExcludeRepos, b.ExcludeRepos = canonicalList(a.ExcludeRepos), canonicalList(b.ExcludeRepos)
+	a.KeywordWeights, b.KeywordWeights = canonicalWeights(a.KeywordWeights), canonicalWeights(b.KeywordWeights)
+	// Snapshots from before query dialects were searched as text
+	for _, snapshot := range []*repository.RuleSnapshot{&a, &b} {
+		if snapshot.QueryDialect == "" {
+			snapshot.QueryDialect = github.DialectLegacy
+		}
+	}
+	return a == b
+}
+
+// canonicalWeights re-encodes keyword weights with sorted keys
+func canonicalWeights(weights string) string {
+	var values map[string]float64
+	if weights == "" || json.Unmarshal([]byte(weights), &values) != nil {
+		return weights
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	encoded, _ := json.Marshal(values)
+	return string(encoded)
+}
+
+func canonicalList(list string) string {
+	var values []string
+	if list == "" || json.Unmarshal([]byte(list), &values) != nil {
+		return list
+	}
+	if values == nil {
+		values = []string{}
+	}
+	return encode(values)
+}
+
+func (s *Syncer) syncWhitelist(tx *gorm.DB, defined map[string]definedEntry, result *Result) error {
+	linked, err := links(tx, KindWhitelist)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range sortedKeys(defined) {
+		def := defined[value]
+		link, ok := linked[value]
+		delete(linked, value)
+
+		if !ok {
+			// Values are unique including deleted entries, so an existing one is taken over
+			var entry models.Whitelist
+			err := tx.Unscoped().Where("value = ?", value).First(&entry).Error
+			switch {
+			case err == nil:
+				if err := restoreEntry(tx, entry.ID, def.state); err != nil {
+					return err
+				}
+				result.Updated++
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				entry = models.Whitelist{Type: def.state.Type, Value: value, Description: def.state.Description}
+				if err := tx.Create(&entry).Error; err != nil {
+					return err
+				}
+				result.Created++
+			default:
+				return err
+			}
+
+			link = &models.SyncedDefinition{Kind: KindWhitelist, Name: value, File: def.file, TargetID: entry.ID, Applied: encode(def.state)}
+			if err := tx.Create(link).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		var entry models.Whitelist
+		err := tx.First(&entry, link.TargetID).Error
+		deleted := errors.Is(err, gorm.ErrRecordNotFound)
+		if err != nil && !deleted {
+			return err
+		}
+
+		current := whitelistState{Type: entry.Type, Description: entry.Description}
+		var applied whitelistState
+		_ = json.Unmarshal([]byte(link.Applied), &applied)
+		if deleted || (current != def.state && current != applied) {
+			drift := Drift{Kind: KindWhitelist, Name: value, File: def.file, TargetID: link.TargetID, Change: "modified", Reverted: s.revert}
+			if deleted {
+				drift.Change = "deleted"
+			}
+			result.Drift = append(result.Drift, drift)
+			if !s.revert {
+				continue
+			}
+		}
+
+		if deleted || current != def.state {
+			if err := restoreEntry(tx, link.TargetID, def.state); err != nil {
+				return err
+			}
+			if deleted {
+				result.Created++
+			} else {
+				result.Updated++
+			}
+		}
+
+		link.File = def.file
+		link.Applied = encode(def.state)
+		if err := tx.Save(link).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, value := range sortedKeys(linked) {
+		link := linked[value]
+		deleted := tx.Delete(&models.Whitelist{}, link.TargetID)
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		if deleted.RowsAffected > 0 {
+			result.Removed++
+		}
+		if err := tx.Delete(link).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreEntry sets the state of a whitelist entry, undeleting it if needed
+func restoreEntry(tx *gorm.DB, id uint, state whitelistState) error {
+	return tx.Unscoped().Model(&models.Whitelist{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"type":        state.Type,
+		"description": state.Description,
+		"deleted_at":  nil,
+		"updated_at":  time.Now(),
+	}).Error
+}
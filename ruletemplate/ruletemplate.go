@@ -0,0 +1,267 @@
+// Package ruletemplate is a built-in catalog of monitor rules for common leaks, so a
+// new deployment gets useful coverage by filling in its domains instead of
+// writing search queries.
+package ruletemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github-monitor/db/models"
+)
+
+// Template is a rule whose keywords contain {{variable}} placeholders
+type Template struct {
+	Name        string     `json:"name"`
+	Title       string     `json:"title"` // rule name, may contain placeholders
+	Description string     `json:"description"`
+	Keywords    []string   `json:"keywords"`
+	MatchType   string     `json:"match_type"`
+	ExcludeExts []string   `json:"exclude_exts"`
+	Severity    string     `json:"severity"`
+	Variables   []Variable `json:"variables"`
+}
+
+// Variable is a value the caller fills in when instantiating a template
+type Variable struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Example     string `json:"example"`
+}
+
+// VariableError reports a missing or unusable variable value
+type VariableError struct {
+	Name    string
+	Message string
+}
+
+func (e *VariableError) Error() string {
+	return fmt.Sprintf("%s %s", e.Name, e.Message)
+}
+
+var (
+	placeholder = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+	// Values end up in search queries, where whitespace splits a keyword and quotes
+	// end a phrase
+	validValue = regexp.MustCompile(`^[^\s"]+$`)
+)
+
+var domain = Variable{Name: "domain", Description: "Company domain that appears in configs and emails", Example: "example.com"}
+
+// catalog is kept sorted by name
+var catalog = []Template{
+	{
+		Name:        "aliyun-access-keys",
+		Title:       "Aliyun access keys ({{domain}})",
+		Description: "Aliyun AccessKey secrets next to the company domain",
+		Keywords:    []string{"{{domain}}", "AccessKeySecret"},
+		MatchType:   "fuzzy",
+		ExcludeExts: []string{"md", "html"},
+		Severity:    "critical",
+		Variables:   []Variable{domain},
+	},
+	{
+		Name:        "aws-access-keys",
+		Title:       "AWS access keys ({{domain}})",
+		Description: "AWS secret access keys next to the company domain",
+		Keywords:    []string{"{{domain}}", "aws_secret_access_key"},
+		MatchType:   "fuzzy",
+		ExcludeExts: []string{"md", "html"},
+		Severity:    "critical",
+		Variables:   []Variable{domain},
+	},
+	{
+		Name:        "azure-storage-keys",
+		Title:       "Azure storage keys ({{domain}})",
+		Description: "Azure storage connection strings with an account key next to the company domain",
+		Keywords:    []string{"{{domain}}", "AccountKey"},
+		MatchType:   "fuzzy",
+		ExcludeExts: []string{"md", "html"},
+		Severity:    "critical",
+		Variables:   []Variable{domain},
+	},
+	{
+		Name:        "brand-api-keys",
+		Title:       "API keys ({{brand}})",
+		Description: "API keys in files mentioning the brand or product name",
+		Keywords:    []string{"{{brand}}", "api_key"},
+		MatchType:   "fuzzy",
+		ExcludeExts: []string{"md", "html"},
+		Severity:    "medium",
+		Variables: []Variable{
+			{Name: "brand", Description: "Brand or product name", Example: "acme"},
+		},
+	},
+	{
+		Name:        "domain-passwords",
+		Title:       "Passwords ({{domain}})",
+		Description: "Passwords in files mentioning the company domain",
+		Keywords:    []string{"{{domain}}", "password"},
+		MatchType:   "fuzzy",
+		ExcludeExts: []string{"md", "html"},
+		Severity:    "high",
+		Variables:   []Variable{domain},
+	},
+	{
+		Name:        "email-passwords",
+		Title:       "Mailbox passwords (@{{email_domain}})",
+		Description: "Company email addresses next to a password, e.g. mail client or CI configs",
+		Keywords:    []string{"@{{email_domain}}", "password"},
+		MatchType:   "fuzzy",
+		ExcludeExts: []string{"md", "html"},
+		Severity:    "high",
+		Variables: []Variable{
+			{Name: "email_domain", Description: "Domain of the company's email addresses", Example: "mail.example.com"},
+		},
+	},
+	{
+		Name:        "env-files",
+		Title:       ".env files ({{domain}})",
+		Description: "Committed .env files mentioning the company domain",
+		Keywords:    []string{"{{domain}}", "filename:.env"},
+		MatchType:   "fuzzy",
+		Severity:    "high",
+		Variables:   []Variable{domain},
+	},
+	{
+		Name:        "gcp-service-accounts",
+		Title:       "GCP service account keys ({{domain}})",
+		Description: "Google Cloud service account key files of the company's projects",
+		Keywords:    []string{"{{domain}}", "private_key_id", "service_account"},
+		MatchType:   "fuzzy",
+		ExcludeExts: []string{"md", "html"},
+		Severity:    "critical",
+		Variables:   []Variable{domain},
+	},
+	{
+		Name:        "github-actions-credentials",
+		Title:       "GitHub Actions credentials ({{domain}})",
+		Description: "GitHub Actions workflows mentioning the company domain, where hardcoded tokens in env blocks and misused secrets are flagged",
+		Keywords:    []string{"{{domain}}", "path:.github/workflows"},
+		MatchType:   "fuzzy",
+		Severity:    "high",
+		Variables:   []Variable{domain},
+	},
+	{
+		Name:        "gitlab-ci-credentials",
+		Title:       "GitLab CI credentials ({{domain}})",
+		Description: "GitLab CI pipelines mentioning the company domain, where hardcoded credentials in variables are flagged",
+		Keywords:    []string{"{{domain}}", "filename:.gitlab-ci.yml"},
+		MatchType:   "fuzzy",
+		Severity:    "high",
+		Variables:   []Variable{domain},
+	},
+	{
+		Name:        "internal-hostnames",
+		Title:       "Internal hostnames ({{internal_domain}})",
+		Description: "Any file mentioning an internal DNS zone, which usually means internal configs or docs",
+		Keywords:    []string{"{{internal_domain}}"},
+		MatchType:   "precise",
+		Severity:    "medium",
+		Variables: []Variable{
+			{Name: "internal_domain", Description: "Internal DNS zone", Example: "corp.example.com"},
+		},
+	},
+	{
+		Name:        "jdbc-connection-strings",
+		Title:       "JDBC connection strings ({{domain}})",
+		Description: "JDBC URLs with a password pointing at the company's database hosts",
+		Keywords:    []string{"jdbc", "{{domain}}", "password"},
+		MatchType:   "fuzzy",
+		ExcludeExts: []string{"md"},
+		Severity:    "high",
+		Variables:   []Variable{domain},
+	},
+	{
+		Name:        "smtp-credentials",
+		Title:       "SMTP credentials ({{domain}})",
+		Description: "SMTP settings with a password for the company's mail servers",
+		Keywords:    []string{"{{domain}}", "smtp", "password"},
+		MatchType:   "fuzzy",
+		ExcludeExts: []string{"md", "html"},
+		Severity:    "high",
+		Variables:   []Variable{domain},
+	},
+	{
+		Name:        "vpn-configs",
+		Title:       "OpenVPN configs ({{vpn_host}})",
+		Description: "OpenVPN client profiles connecting to the company's VPN gateway",
+		Keywords:    []string{"{{vpn_host}}", "extension:ovpn"},
+		MatchType:   "fuzzy",
+		Severity:    "critical",
+		Variables: []Variable{
+			{Name: "vpn_host", Description: "Hostname of the VPN gateway", Example: "vpn.example.com"},
+		},
+	},
+}
+
+// List returns the templates sorted by name
+func List() []Template {
+	return catalog
+}
+
+// Get returns the template with the given name
+func Get(name string) (Template, bool) {
+	i := sort.Search(len(catalog), func(i int) bool { return catalog[i].Name >= name })
+	if i < len(catalog) && catalog[i].Name == name {
+		return catalog[i], true
+	}
+	return Template{}, false
+}
+
+// ValidValue reports whether value can be used for a variable
+func ValidValue(value string) bool {
+	return validValue.MatchString(value)
+}
+
+// Instantiate fills in the variables and returns the rule, which isn't saved yet.
+// Every variable of the template is required, unknown ones are rejected.
+func (t Template) Instantiate(values map[string]string) (*models.MonitorRule, error) {
+	known := make(map[string]bool, len(t.Variables))
+	for _, v := range t.Variables {
+		known[v.Name] = true
+		value := strings.TrimSpace(values[v.Name])
+		if value == "" {
+			return nil, &VariableError{Name: v.Name, Message: "is required"}
+		}
+		if !ValidValue(value) {
+			return nil, &VariableError{Name: v.Name, Message: "must not contain whitespace or quotes"}
+		}
+	}
+	for name := range values {
+		if !known[name] {
+			return nil, &VariableError{Name: name, Message: "is not a variable of this template"}
+		}
+	}
+
+	fill := func(s string) string {
+		return placeholder.ReplaceAllStringFunc(s, func(match string) string {
+			return strings.TrimSpace(values[placeholder.FindStringSubmatch(match)[1]])
+		})
+	}
+
+	keywords := make([]string, len(t.Keywords))
+	for i, keyword := range t.Keywords {
+		keywords[i] = fill(keyword)
+	}
+	keywordsJSON, _ := json.Marshal(keywords)
+
+	excludeExts := t.ExcludeExts
+	if excludeExts == nil {
+		excludeExts = []string{}
+	}
+	excludeJSON, _ := json.Marshal(excludeExts)
+
+	return &models.MonitorRule{
+		Name:        fill(t.Title),
+		Description: t.Description,
+		Keywords:    string(keywordsJSON),
+		MatchType:   t.MatchType,
+		IsActive:    true,
+		ExcludeExts: string(excludeJSON),
+		Severity:    t.Severity,
+	}, nil
+}
@@ -0,0 +1,316 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/repository"
+)
+
+// Runtime holds the settings that can be changed while the service is running.
+// Values stored in the settings table override config.yaml.
+type Runtime struct {
+	ScanInterval         string `json:"scan_interval"`
+	Concurrency          int    `json:"concurrency"`
+	RateLimitThreshold   int    `json:"rate_limit_threshold"`
+	ProxyEnabled         bool   `json:"proxy_enabled"`
+	ProxyURL             string `json:"proxy_url"`
+	ProxyType            string `json:"proxy_type"`
+	ProxyUsername        string `json:"proxy_username"`
+	ProxyPassword        string `json:"proxy_password"`
+	NotificationsEnabled bool   `json:"notifications_enabled"`
+	DashboardURL         string `json:"dashboard_url"`
+}
+
+// ErrInvalid is wrapped by the errors of Update caused by the changes themselves,
+// as opposed to failures storing them
+var ErrInvalid = errors.New("invalid settings")
+
+var (
+	current      Runtime
+	fileSettings Runtime // values from the config file, stored overrides apply on top
+	listeners    []func(Runtime)
+	store        repository.SettingRepo
+	mu           sync.RWMutex
+)
+
+// Load initializes the runtime settings from config.yaml and the overrides in
+// overrides, which later updates are stored in
+func Load(overrides repository.SettingRepo) error {
+	mu.Lock()
+	store = overrides
+	mu.Unlock()
+
+	runtime, err := resolve(config.AppConfig)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	current = runtime
+	fileSettings = fromConfig(config.AppConfig)
+	toConfig(runtime, config.AppConfig)
+	mu.Unlock()
+
+	return nil
+}
+
+// Reload applies the values of a re-read config file. Overrides stored in the
+// settings table still win, listeners are only called when something changed.
+func Reload(cfg *config.Config) error {
+	runtime, err := resolve(cfg)
+	if err != nil {
+		return err
+	}
+	if err := runtime.Validate(); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	fileSettings = fromConfig(cfg)
+	mu.Unlock()
+
+	apply(runtime, "Runtime settings updated from config file")
+	return nil
+}
+
+// Refresh picks up overrides that other instances sharing the database stored
+func Refresh() error {
+	mu.RLock()
+	base := fileSettings
+	mu.RUnlock()
+
+	runtime, _, err := withOverrides(base)
+	if err != nil {
+		return err
+	}
+	if err := runtime.Validate(); err != nil {
+		return err
+	}
+
+	apply(runtime, "Runtime settings updated by another instance")
+	return nil
+}
+
+// apply makes runtime current and calls the listeners if anything changed
+func apply(runtime Runtime, reason string) {
+	mu.Lock()
+	if runtime == current {
+		mu.Unlock()
+		return
+	}
+	current = runtime
+	toConfig(runtime, config.AppConfig)
+	fns := append([]func(Runtime){}, listeners...)
+	mu.Unlock()
+
+	log.Println(reason)
+	for _, fn := range fns {
+		fn(runtime)
+	}
+}
+
+// resolve combines the config file values with the overrides in the settings table
+func resolve(cfg *config.Config) (Runtime, error) {
+	runtime, count, err := withOverrides(fromConfig(cfg))
+	if err != nil {
+		return Runtime{}, err
+	}
+	if count > 0 {
+		log.Printf("Applied %d runtime setting overrides", count)
+	}
+	return runtime, nil
+}
+
+// withOverrides applies the overrides in the settings table to base and returns
+// how many there were
+func withOverrides(base Runtime) (Runtime, int, error) {
+	mu.RLock()
+	overridesStore := store
+	mu.RUnlock()
+
+	rows, err := overridesStore.List(context.Background())
+	if err != nil {
+		return Runtime{}, 0, fmt.Errorf("failed to load settings: %w", err)
+	}
+	if len(rows) == 0 {
+		return base, 0, nil
+	}
+
+	overrides := make(map[string]json.RawMessage, len(rows))
+	for _, row := range rows {
+		overrides[row.Key] = json.RawMessage(row.Value)
+	}
+
+	merged, err := merge(base, overrides)
+	if err != nil {
+		return Runtime{}, 0, fmt.Errorf("invalid stored settings: %w", err)
+	}
+	return merged, len(rows), nil
+}
+
+// Current returns a copy of the current runtime settings
+func Current() Runtime {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// OnChange registers a function called with the new settings after every update
+func OnChange(fn func(Runtime)) {
+	mu.Lock()
+	listeners = append(listeners, fn)
+	mu.Unlock()
+}
+
+// Update validates and persists the given changes, then applies them live
+func Update(changes map[string]json.RawMessage, actor string) (Runtime, error) {
+	mu.Lock()
+	updated, err := merge(current, changes)
+	if err != nil {
+		mu.Unlock()
+		return Runtime{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	if err := updated.Validate(); err != nil {
+		mu.Unlock()
+		return Runtime{}, err
+	}
+
+	for key, value := range changes {
+		row := models.Setting{Key: key, Value: string(value), UpdatedBy: actor}
+		if err := store.Set(context.Background(), &row); err != nil {
+			mu.Unlock()
+			return Runtime{}, fmt.Errorf("failed to save setting %s: %w", key, err)
+		}
+	}
+
+	current = updated
+	toConfig(updated, config.AppConfig)
+	fns := append([]func(Runtime){}, listeners...)
+	mu.Unlock()
+
+	for _, fn := range fns {
+		fn(updated)
+	}
+
+	return updated, nil
+}
+
+// Validate checks the settings for values that can't be applied
+func (r Runtime) Validate() error {
+	var problems []string
+
+	if d, err := time.ParseDuration(r.ScanInterval); err != nil {
+		problems = append(problems, fmt.Sprintf("scan_interval: %v", err))
+	} else if d < time.Minute {
+		problems = append(problems, "scan_interval: must be at least 1m")
+	}
+
+	if r.Concurrency < 1 || r.Concurrency > 32 {
+		problems = append(problems, "concurrency: must be between 1 and 32")
+	}
+
+	if r.RateLimitThreshold < 0 {
+		problems = append(problems, "rate_limit_threshold: must not be negative")
+	}
+
+	if r.ProxyEnabled {
+		if _, err := url.Parse(r.ProxyURL); err != nil || r.ProxyURL == "" {
+			problems = append(problems, "proxy_url: must be a valid URL when the proxy is enabled")
+		}
+		switch r.ProxyType {
+		case "http", "https", "socks5":
+		default:
+			problems = append(problems, "proxy_type: must be http, https or socks5")
+		}
+	}
+
+	if r.DashboardURL != "" {
+		if u, err := url.Parse(r.DashboardURL); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, "dashboard_url: must be an absolute URL")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Masked returns a copy safe to show in the API
+func (r Runtime) Masked() Runtime {
+	if r.ProxyPassword != "" {
+		r.ProxyPassword = "******"
+	}
+	return r
+}
+
+// merge overlays JSON encoded values onto the settings, rejecting unknown keys
+func merge(base Runtime, changes map[string]json.RawMessage) (Runtime, error) {
+	data, err := json.Marshal(base)
+	if err != nil {
+		return Runtime{}, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Runtime{}, err
+	}
+
+	for key, value := range changes {
+		if _, ok := fields[key]; !ok {
+			return Runtime{}, fmt.Errorf("unknown setting %q", key)
+		}
+		fields[key] = value
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return Runtime{}, err
+	}
+
+	var merged Runtime
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return Runtime{}, fmt.Errorf("invalid setting value: %w", err)
+	}
+
+	return merged, nil
+}
+
+func fromConfig(cfg *config.Config) Runtime {
+	return Runtime{
+		ScanInterval:         cfg.Monitor.ScanInterval,
+		Concurrency:          cfg.Monitor.Concurrency,
+		RateLimitThreshold:   cfg.GitHub.RateLimitThreshold,
+		ProxyEnabled:         cfg.GitHub.ProxyEnabled,
+		ProxyURL:             cfg.GitHub.ProxyURL,
+		ProxyType:            cfg.GitHub.ProxyType,
+		ProxyUsername:        cfg.GitHub.ProxyUsername,
+		ProxyPassword:        cfg.GitHub.ProxyPassword,
+		NotificationsEnabled: cfg.Notify.Enabled,
+		DashboardURL:         cfg.Notify.DashboardURL,
+	}
+}
+
+func toConfig(r Runtime, cfg *config.Config) {
+	cfg.Monitor.ScanInterval = r.ScanInterval
+	cfg.Monitor.Concurrency = r.Concurrency
+	cfg.GitHub.RateLimitThreshold = r.RateLimitThreshold
+	cfg.GitHub.ProxyEnabled = r.ProxyEnabled
+	cfg.GitHub.ProxyURL = r.ProxyURL
+	cfg.GitHub.ProxyType = r.ProxyType
+	cfg.GitHub.ProxyUsername = r.ProxyUsername
+	cfg.GitHub.ProxyPassword = r.ProxyPassword
+	cfg.Notify.Enabled = r.NotificationsEnabled
+	cfg.Notify.DashboardURL = r.DashboardURL
+}
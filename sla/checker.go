@@ -0,0 +1,175 @@
+package sla
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/i18n"
+	"github-monitor/notify"
+	"github-monitor/reporting"
+	"github-monitor/repository"
+	"github-monitor/settings"
+)
+
+const (
+	// maxBreaches caps the newly overdue results fetched per severity and status
+	maxBreaches = 500
+	// maxListedBreaches caps how many results are listed in a single notification
+	maxListedBreaches = 10
+)
+
+// Checker periodically notifies each project about its results that missed their
+// SLA since the previous check, together with its current at-risk and overdue counts
+type Checker struct {
+	policy    *Policy
+	results   repository.ResultRepo
+	channels  repository.NotificationRepo
+	interval  time.Duration
+	lastCheck time.Time
+	stopChan  chan struct{} // nil while stopped
+}
+
+// NewChecker creates a checker of the configured SLAs
+func NewChecker(cfg *config.SLAConfig, policy *Policy, results repository.ResultRepo, channels repository.NotificationRepo) (*Checker, error) {
+	interval, err := time.ParseDuration(cfg.CheckInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid check interval: %w", err)
+	}
+	return &Checker{policy: policy, results: results, channels: channels, interval: interval}, nil
+}
+
+// Start runs the checker in the background, it can be started again after Stop.
+// Results that missed their SLA while it was stopped aren't notified.
+func (c *Checker) Start() {
+	if c.stopChan != nil {
+		return
+	}
+	c.stopChan = make(chan struct{})
+	c.lastCheck = time.Now()
+	go c.run(c.stopChan)
+	log.Printf("SLA checker started, every %s", c.interval)
+}
+
+// Stop stops the checker
+func (c *Checker) Stop() {
+	if c.stopChan == nil {
+		return
+	}
+	close(c.stopChan)
+	c.stopChan = nil
+}
+
+func (c *Checker) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			c.checkOnce(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (c *Checker) checkOnce(now time.Time) {
+	defer reporting.Recover(reporting.Tags{"component": "sla"})
+
+	if err := c.Check(context.Background(), now); err != nil {
+		log.Printf("SLA check failed: %v", err)
+		reporting.CaptureError(err, reporting.Tags{"component": "sla"})
+	}
+}
+
+// Check notifies about the results whose SLA ended between the previous check and now
+func (c *Checker) Check(ctx context.Context, now time.Time) error {
+	since := c.lastCheck
+	c.lastCheck = now
+
+	breaches, err := c.Breaches(ctx, since, now)
+	if err != nil || len(breaches) == 0 {
+		return err
+	}
+	if !settings.Current().NotificationsEnabled {
+		return nil
+	}
+
+	byProject := make(map[uint][]models.SearchResult)
+	for _, result := range breaches {
+		byProject[result.ProjectID] = append(byProject[result.ProjectID], result)
+	}
+	for projectID, results := range byProject {
+		counts, err := c.policy.Count(repository.WithProjects(ctx, []uint{projectID}), c.results, now)
+		if err != nil {
+			return err
+		}
+		log.Printf("%d results of project %d missed their SLA", len(results), projectID)
+		notify.Broadcast(ctx, c.channels, breachMessage(results, counts), func(config *models.NotificationConfig) bool {
+			return config.NotifyOnNew && config.ProjectID == projectID
+		})
+	}
+	return nil
+}
+
+// Breaches returns the open results whose SLA ended after since and by until,
+// oldest deadline first
+func (c *Checker) Breaches(ctx context.Context, since, until time.Time) ([]models.SearchResult, error) {
+	var breaches []models.SearchResult
+	for severity := range c.policy.targets {
+		for status := range stageStatuses {
+			sla := c.policy.sla(status, severity)
+			if sla == 0 {
+				continue
+			}
+
+			filter := repository.ResultFilter{
+				Status:        status,
+				Severity:      severity,
+				CreatedAfter:  since.Add(-sla),
+				CreatedBefore: until.Add(-sla),
+			}
+			results, _, err := c.results.List(ctx, filter, repository.Page{Number: 1, Size: maxBreaches})
+			if err != nil {
+				return nil, err
+			}
+			breaches = append(breaches, results...)
+		}
+	}
+
+	c.policy.Annotate(breaches, until)
+	sort.Slice(breaches, func(i, j int) bool {
+		return breaches[i].SLADeadline.Before(*breaches[j].SLADeadline)
+	})
+	return breaches, nil
+}
+
+func breachMessage(results []models.SearchResult, counts Counts) notify.Message {
+	message := notify.Message{
+		TitleText: i18n.Format("GitHub leak SLA breached"),
+		ContentText: i18n.Format("%d results missed their SLA. Open results now at risk: %d, overdue: %d\n",
+			len(results), counts.AtRisk, counts.Overdue),
+		URL: results[0].HTMLURL,
+	}
+	for i, result := range results {
+		if i == maxListedBreaches {
+			message.More = len(results) - maxListedBreaches
+			break
+		}
+		message.Results = append(message.Results, notify.ResultRef{
+			ID:      result.ID,
+			Label:   strings.TrimSuffix(result.RepoFullName+"/"+result.FilePath, "/") + " (" + result.Severity + ", " + result.Status + ")",
+			HTMLURL: result.HTMLURL,
+		})
+	}
+	if dashboardURL := settings.Current().DashboardURL; dashboardURL != "" {
+		message.URL = strings.TrimSuffix(dashboardURL, "/") + "/results"
+	}
+	return message
+}
@@ -0,0 +1,132 @@
+// Package sla tracks the triage and remediation deadlines of open results. Pending
+// results must be triaged and confirmed ones resolved within the SLA of their
+// severity, counted from when they were found.
+package sla
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/repository"
+)
+
+// States of an open result against its SLA
+const (
+	StateOK      = "ok"
+	StateAtRisk  = "at_risk"
+	StateOverdue = "overdue"
+)
+
+// stageStatuses maps the statuses with a deadline to the SLA that applies
+var stageStatuses = map[string]func(target) time.Duration{
+	"pending":   func(t target) time.Duration { return t.triage },
+	"confirmed": func(t target) time.Duration { return t.remediate },
+}
+
+type target struct {
+	triage    time.Duration // 0 for none
+	remediate time.Duration // 0 for none
+}
+
+// Policy holds the SLAs per severity
+type Policy struct {
+	targets map[string]target
+	atRisk  float64
+}
+
+// Counts are the open results at risk of missing their SLA and the ones that missed it
+type Counts struct {
+	AtRisk  int64 `json:"at_risk"`
+	Overdue int64 `json:"overdue"`
+}
+
+// NewPolicy creates the policy of the configured SLAs
+func NewPolicy(cfg *config.SLAConfig) (*Policy, error) {
+	p := &Policy{targets: make(map[string]target, len(cfg.Severities)), atRisk: cfg.AtRisk}
+	for severity, configured := range cfg.Severities {
+		var t target
+		var err error
+		if configured.Triage != "" {
+			if t.triage, err = time.ParseDuration(configured.Triage); err != nil {
+				return nil, fmt.Errorf("invalid triage SLA of %s: %w", severity, err)
+			}
+		}
+		if configured.Remediate != "" {
+			if t.remediate, err = time.ParseDuration(configured.Remediate); err != nil {
+				return nil, fmt.Errorf("invalid remediate SLA of %s: %w", severity, err)
+			}
+		}
+		p.targets[severity] = t
+	}
+	return p, nil
+}
+
+// sla returns the SLA that applies to results of the given status and severity, 0
+// for none
+func (p *Policy) sla(status, severity string) time.Duration {
+	stage, ok := stageStatuses[status]
+	if !ok {
+		return 0
+	}
+	return stage(p.targets[severity])
+}
+
+// State returns the SLA state of a result and its deadline, false for results
+// without a running SLA such as resolved ones
+func (p *Policy) State(result models.SearchResult, now time.Time) (string, time.Time, bool) {
+	sla := p.sla(result.Status, result.Severity)
+	if sla == 0 {
+		return "", time.Time{}, false
+	}
+
+	deadline := result.CreatedAt.Add(sla)
+	switch {
+	case now.After(deadline):
+		return StateOverdue, deadline, true
+	case now.After(result.CreatedAt.Add(p.atRiskAfter(sla))):
+		return StateAtRisk, deadline, true
+	}
+	return StateOK, deadline, true
+}
+
+// Annotate sets the SLA state and deadline of the results
+func (p *Policy) Annotate(results []models.SearchResult, now time.Time) {
+	for i := range results {
+		if state, deadline, ok := p.State(results[i], now); ok {
+			results[i].SLAState = state
+			results[i].SLADeadline = &deadline
+		}
+	}
+}
+
+func (p *Policy) atRiskAfter(sla time.Duration) time.Duration {
+	return time.Duration(float64(sla) * p.atRisk)
+}
+
+// Count counts the open results in the projects of ctx that are at risk or overdue
+func (p *Policy) Count(ctx context.Context, results repository.ResultRepo, now time.Time) (Counts, error) {
+	var counts Counts
+	for severity := range p.targets {
+		for status := range stageStatuses {
+			sla := p.sla(status, severity)
+			if sla == 0 {
+				continue
+			}
+
+			overdue, err := results.Count(ctx, repository.ResultFilter{Status: status, Severity: severity, CreatedBefore: now.Add(-sla)})
+			if err != nil {
+				return Counts{}, err
+			}
+			late, err := results.Count(ctx, repository.ResultFilter{Status: status, Severity: severity, CreatedBefore: now.Add(-p.atRiskAfter(sla))})
+			if err != nil {
+				return Counts{}, err
+			}
+			counts.Overdue += overdue
+			counts.AtRisk += late - overdue
+		}
+	}
+	return counts, nil
+}
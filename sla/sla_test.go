@@ -0,0 +1,43 @@
+package sla
+
+import (
+	"testing"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+)
+
+func TestPolicyState(t *testing.T) {
+	policy, err := NewPolicy(&config.SLAConfig{
+		AtRisk: 0.75,
+		Severities: map[string]config.SLATarget{
+			"critical": {Triage: "4h", Remediate: "72h"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		status, severity string
+		age              time.Duration
+		want             string
+	}{
+		{"pending", "critical", time.Hour, StateOK},
+		{"pending", "critical", 3*time.Hour + time.Minute, StateAtRisk},
+		{"pending", "critical", 5 * time.Hour, StateOverdue},
+		{"confirmed", "critical", 5 * time.Hour, StateOK},
+		{"confirmed", "critical", 73 * time.Hour, StateOverdue},
+		{"resolved", "critical", 100 * time.Hour, ""},
+		{"pending", "low", 100 * time.Hour, ""},
+	}
+	for _, tt := range tests {
+		result := models.SearchResult{Status: tt.status, Severity: tt.severity, CreatedAt: found}
+		state, _, _ := policy.State(result, found.Add(tt.age))
+		if state != tt.want {
+			t.Errorf("%s %s result after %s: state %q, want %q", tt.severity, tt.status, tt.age, state, tt.want)
+		}
+	}
+}
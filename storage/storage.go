@@ -0,0 +1,121 @@
+// Package storage keeps evidence and generated files in S3 compatible object
+// storage (AWS S3, MinIO or Aliyun OSS), so they don't grow the database and can
+// be handed out through short-lived signed URLs.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github-monitor/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Supported providers
+const (
+	ProviderS3    = "s3"
+	ProviderMinIO = "minio"
+	ProviderOSS   = "oss"
+)
+
+// Store reads and writes objects by key
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that downloads the object without credentials until it expires
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// New connects to the configured bucket
+func New(cfg *config.StorageConfig) (Store, error) {
+	endpoint := cfg.Endpoint
+	lookup := minio.BucketLookupDNS
+	switch cfg.Provider {
+	case ProviderS3:
+		if endpoint == "" {
+			endpoint = "s3.amazonaws.com"
+		}
+	case ProviderMinIO:
+		// MinIO deployments rarely have wildcard DNS for bucket subdomains
+		lookup = minio.BucketLookupPath
+	case ProviderOSS:
+		if endpoint == "" {
+			endpoint = "oss-" + cfg.Region + ".aliyuncs.com"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported storage provider %q", cfg.Provider)
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure:       cfg.UseSSL,
+		Region:       cfg.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	return &s3Store{client: client, bucket: cfg.Bucket, prefix: strings.Trim(cfg.Prefix, "/")}, nil
+}
+
+// s3Store talks the S3 API, which MinIO and OSS implement as well
+type s3Store struct {
+	client *minio.Client
+	bucket string
+	prefix string // prepended to every key, so one bucket can be shared
+}
+
+func (s *s3Store) object(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return path.Join(s.prefix, key)
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, s.object(key), r, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to store %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, s.object(key), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	// GetObject is lazy, Stat surfaces a missing object right away
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return obj, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.bucket, s.object(key), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *s3Store) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	params := url.Values{}
+	params.Set("response-content-disposition", fmt.Sprintf("inline; filename=%q", path.Base(key)))
+
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, s.object(key), expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s: %w", key, err)
+	}
+	return u.String(), nil
+}
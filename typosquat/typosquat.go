@@ -0,0 +1,82 @@
+// Package typosquat generates lookalike variants of organization and product
+// names and tells which repository names use them, to spot repositories that
+// impersonate the brand for phishing or malware distribution.
+package typosquat
+
+import (
+	"strings"
+)
+
+// homoglyphs are the characters that read alike in repository names
+var homoglyphs = []struct{ from, to string }{
+	{"o", "0"}, {"0", "o"},
+	{"l", "1"}, {"l", "i"}, {"i", "1"}, {"i", "l"}, {"1", "l"}, {"1", "i"},
+	{"e", "3"}, {"a", "4"}, {"s", "5"},
+	{"m", "rn"}, {"rn", "m"},
+	{"w", "vv"}, {"vv", "w"},
+	{"g", "q"}, {"q", "g"},
+}
+
+// affixes are added by repositories posing as official releases
+var affixes = []string{"official", "app", "desktop", "client", "wallet", "login", "installer", "crack"}
+
+// Variants returns up to limit lowercase lookalikes of name, the most convincing
+// kinds first: homoglyphs, missing, doubled and swapped characters, separators and
+// official-looking affixes. The name itself isn't included.
+func Variants(name string, limit int) []string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	seen := map[string]bool{name: true}
+	var variants []string
+	add := func(variant string) {
+		if len(variants) < limit && len(variant) >= 3 && !seen[variant] {
+			seen[variant] = true
+			variants = append(variants, variant)
+		}
+	}
+
+	for i := range name {
+		for _, glyph := range homoglyphs {
+			if strings.HasPrefix(name[i:], glyph.from) {
+				add(name[:i] + glyph.to + name[i+len(glyph.from):])
+			}
+		}
+	}
+	for i := range name {
+		add(name[:i] + name[i+1:])
+	}
+	for i := range name {
+		add(name[:i+1] + name[i:])
+	}
+	for i := 0; i+1 < len(name); i++ {
+		add(name[:i] + string(name[i+1]) + string(name[i]) + name[i+2:])
+	}
+
+	compact := strings.NewReplacer("-", "", "_", "", ".", "").Replace(name)
+	add(compact)
+	for i := 1; i < len(compact); i++ {
+		add(compact[:i] + "-" + compact[i:])
+	}
+	for _, affix := range affixes {
+		add(name + "-" + affix)
+		add(affix + "-" + name)
+	}
+	return variants
+}
+
+// Match returns the name or variant a repository name uses, empty when it uses
+// none. They have to make up the whole repository name or whole parts of it
+// separated by -, _ or ., so acme matches acme-wallet but not acmeology.
+func Match(repoName string, names, variants []string) string {
+	separators := strings.NewReplacer("_", "-", ".", "-")
+	padded := "-" + separators.Replace(strings.ToLower(repoName)) + "-"
+
+	for _, candidates := range [][]string{names, variants} {
+		for _, candidate := range candidates {
+			candidate = separators.Replace(strings.ToLower(candidate))
+			if strings.Contains(padded, "-"+candidate+"-") {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
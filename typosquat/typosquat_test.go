@@ -0,0 +1,44 @@
+package typosquat
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVariants(t *testing.T) {
+	variants := Variants("Acme-Pay", 200)
+	for _, want := range []string{"acrne-pay", "4cme-pay", "acme-py", "accme-pay", "amce-pay", "acmepay", "acme-pay-official"} {
+		if !slices.Contains(variants, want) {
+			t.Errorf("variants of acme-pay miss %q", want)
+		}
+	}
+	if slices.Contains(variants, "acme-pay") {
+		t.Error("variants contain the name itself")
+	}
+	if limited := Variants("acme-pay", 5); !slices.Equal(limited, variants[:5]) {
+		t.Errorf("limited variants %v aren't the first of %v", limited, variants[:5])
+	}
+}
+
+func TestMatch(t *testing.T) {
+	names := []string{"acme"}
+	variants := Variants("acme", 100)
+
+	tests := []struct {
+		repo string
+		want string
+	}{
+		{"acme", "acme"},
+		{"acme-wallet-desktop", "acme"},
+		{"4cme_Wallet", "4cme"},
+		{"acrne", "acrne"},
+		{"acmeology", ""},
+		{"wallet", ""},
+		{"get.acme-pay.desktop", "acme"},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.repo, names, variants); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.repo, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+	"github-monitor/reporting"
+	"github-monitor/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// EventResultCreated is the event of a delivery carrying a new result
+	EventResultCreated = "result.created"
+
+	// Delivery statuses
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+
+	// pollInterval is how often due deliveries are looked for
+	pollInterval = 10 * time.Second
+	// maxDue caps the deliveries attempted per poll
+	maxDue = 100
+	// firstRetry and maxRetry bound the backoff between attempts
+	firstRetry = time.Minute
+	maxRetry   = 6 * time.Hour
+	// maxErrorLength caps the response body kept as the error of a failed attempt
+	maxErrorLength = 500
+)
+
+// payload is the JSON body of a delivery
+type payload struct {
+	Event  string              `json:"event"`
+	Rule   payloadRule         `json:"rule"`
+	Result models.SearchResult `json:"result"`
+}
+
+type payloadRule struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+}
+
+// NewDelivery queues a new result of a rule for one of its webhooks. The result is
+// sent unmasked, the receiver is a machine that needs the full match.
+func NewDelivery(webhook models.RuleWebhook, rule models.MonitorRule, result models.SearchResult) (models.WebhookDelivery, error) {
+	body, err := json.Marshal(payload{
+		Event:  EventResultCreated,
+		Rule:   payloadRule{ID: rule.ID, Name: rule.Name, Severity: rule.Severity},
+		Result: result,
+	})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		ResultID:      result.ID,
+		ProjectID:     webhook.ProjectID,
+		Payload:       string(body),
+		Status:        StatusPending,
+		NextAttemptAt: time.Now(),
+	}, nil
+}
+
+// Sign returns the signature of a delivery body, sent as X-Monitor-Signature so
+// receivers can check it came from us
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the wait after the given number of failed attempts: a minute,
+// doubling up to six hours
+func Backoff(attempts int) time.Duration {
+	wait := firstRetry
+	for i := 1; i < attempts && wait < maxRetry; i++ {
+		wait *= 2
+	}
+	if wait > maxRetry {
+		wait = maxRetry
+	}
+	return wait
+}
+
+// Dispatcher delivers the queued results to the rule webhooks, retrying failed
+// deliveries with backoff
+type Dispatcher struct {
+	repo        repository.WebhookRepo
+	client      *http.Client
+	maxAttempts int
+	stopChan    chan struct{} // nil while stopped
+}
+
+// NewDispatcher creates a dispatcher of the queued deliveries
+func NewDispatcher(cfg *config.RuleWebhooksConfig, repo repository.WebhookRepo) (*Dispatcher, error) {
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout: %w", err)
+	}
+	return &Dispatcher{
+		repo:        repo,
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: cfg.MaxAttempts,
+	}, nil
+}
+
+// Start runs the dispatcher in the background, it can be started again after Stop.
+// Deliveries queued while it was stopped are sent once it runs.
+func (d *Dispatcher) Start() {
+	if d.stopChan != nil {
+		return
+	}
+	d.stopChan = make(chan struct{})
+	go d.run(d.stopChan)
+	log.Printf("Rule webhook dispatcher started")
+}
+
+// Stop stops the dispatcher
+func (d *Dispatcher) Stop() {
+	if d.stopChan == nil {
+		return
+	}
+	close(d.stopChan)
+	d.stopChan = nil
+}
+
+func (d *Dispatcher) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			d.deliverOnce(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliverOnce(now time.Time) {
+	defer reporting.Recover(reporting.Tags{"component": "webhooks"})
+
+	if err := d.DeliverDue(context.Background(), now); err != nil {
+		log.Printf("Rule webhook delivery failed: %v", err)
+		reporting.CaptureError(err, reporting.Tags{"component": "webhooks"})
+	}
+}
+
+// DeliverDue attempts the deliveries whose next attempt is due by now
+func (d *Dispatcher) DeliverDue(ctx context.Context, now time.Time) error {
+	deliveries, err := d.repo.DueDeliveries(ctx, now, maxDue)
+	if err != nil {
+		return err
+	}
+	webhooks := make(map[uint]*models.RuleWebhook)
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		webhook, seen := webhooks[delivery.WebhookID]
+		if !seen {
+			webhook, err = d.repo.Get(ctx, delivery.WebhookID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		if webhook == nil || !webhook.Enabled {
+			delivery.Status = StatusFailed
+			delivery.LastError = "webhook deleted or disabled"
+		} else {
+			d.attempt(ctx, webhook, delivery, now)
+		}
+		if err := d.repo.SaveDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt sends a delivery once and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, webhook *models.RuleWebhook, delivery *models.WebhookDelivery, now time.Time) {
+	delivery.Attempts++
+	code, err := d.send(ctx, webhook, delivery)
+	delivery.ResponseCode = code
+	if err == nil {
+		delivered := now
+		delivery.Status = StatusDelivered
+		delivery.DeliveredAt = &delivered
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = StatusFailed
+		log.Printf("Delivery %d to rule webhook %d failed for good after %d attempts: %v", delivery.ID, webhook.ID, delivery.Attempts, err)
+		return
+	}
+	delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+}
+
+// send posts a delivery, any status but 2xx is an error
+func (d *Dispatcher) send(ctx context.Context, webhook *models.RuleWebhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GitHub-Monitor-Webhook")
+	req.Header.Set("X-Monitor-Event", EventResultCreated)
+	req.Header.Set("X-Monitor-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	if webhook.Secret != "" {
+		req.Header.Set("X-Monitor-Signature", Sign(webhook.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return resp.StatusCode, fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github-monitor/config"
+	"github-monitor/db/models"
+
+	"gorm.io/gorm"
+)
+
+// memoryWebhooks is a WebhookRepo for the dispatcher, the methods it doesn't use
+// aren't implemented
+type memoryWebhooks struct {
+	hooks      map[uint]*models.RuleWebhook
+	deliveries []models.WebhookDelivery
+}
+
+func (r *memoryWebhooks) ListByRule(ctx context.Context, ruleID uint) ([]models.RuleWebhook, error) {
+	return nil, nil
+}
+
+func (r *memoryWebhooks) Get(ctx context.Context, id uint) (*models.RuleWebhook, error) {
+	if hook, ok := r.hooks[id]; ok {
+		return hook, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryWebhooks) Create(ctx context.Context, webhook *models.RuleWebhook) error { return nil }
+func (r *memoryWebhooks) Save(ctx context.Context, webhook *models.RuleWebhook) error   { return nil }
+func (r *memoryWebhooks) Delete(ctx context.Context, id uint) error                     { return nil }
+
+func (r *memoryWebhooks) Enqueue(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	r.deliveries = append(r.deliveries, deliveries...)
+	return nil
+}
+
+func (r *memoryWebhooks) ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (r *memoryWebhooks) GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryWebhooks) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var due []models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == StatusPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryWebhooks) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	for i := range r.deliveries {
+		if r.deliveries[i].ID == delivery.ID {
+			r.deliveries[i] = *delivery
+		}
+	}
+	return nil
+}
+
+func TestDeliverDue(t *testing.T) {
+	failing := true
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Monitor-Signature")
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	repo := &memoryWebhooks{hooks: map[uint]*models.RuleWebhook{
+		1: {ID: 1, URL: server.URL, Secret: "s3cret", Enabled: true},
+		2: {ID: 2, URL: server.URL, Enabled: false},
+	}}
+	rule := models.MonitorRule{ID: 7, Name: "AWS keys", Severity: "critical"}
+	for i, hook := range []uint{1, 2} {
+		delivery, err := NewDelivery(*repo.hooks[hook], rule, models.SearchResult{ID: 42, RuleID: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		delivery.ID = uint(i + 1)
+		repo.Enqueue(context.Background(), []models.WebhookDelivery{delivery})
+	}
+	d, err := NewDispatcher(&config.RuleWebhooksConfig{Timeout: "5s", MaxAttempts: 2}, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := d.DeliverDue(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+	first, disabled := repo.deliveries[0], repo.deliveries[1]
+	if first.Status != StatusPending || first.Attempts != 1 || first.ResponseCode != http.StatusServiceUnavailable {
+		t.Errorf("failed attempt left %+v, want a pending retry", first)
+	}
+	if want := now.Add(time.Minute); !first.NextAttemptAt.Equal(want) {
+		t.Errorf("next attempt at %v, want %v", first.NextAttemptAt, want)
+	}
+	if disabled.Status != StatusFailed || disabled.Attempts != 0 {
+		t.Errorf("delivery to a disabled webhook is %+v, want failed without an attempt", disabled)
+	}
+	if signature != Sign("s3cret", body) {
+		t.Errorf("signature %q doesn't match the body", signature)
+	}
+
+	// Not due yet
+	if err := d.DeliverDue(context.Background(), now.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if repo.deliveries[0].Attempts != 1 {
+		t.Errorf("attempted %d times before the retry was due", repo.deliveries[0].Attempts)
+	}
+
+	failing = false
+	if err := d.DeliverDue(context.Background(), now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if delivered := repo.deliveries[0]; delivered.Status != StatusDelivered || delivered.DeliveredAt == nil || delivered.LastError != "" {
+		t.Errorf("retry left %+v, want delivered", delivered)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		5:  16 * time.Minute,
+		20: 6 * time.Hour,
+	} {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}